@@ -17,6 +17,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/alecthomas/kong"
 	"github.com/common-nighthawk/go-figure"
@@ -36,17 +37,43 @@ func main() {
 	ctx := context.Background()
 	flags := &parca.Flags{}
 
-	kong.Parse(flags)
+	kctx := kong.Parse(flags)
 
 	if flags.Version {
 		fmt.Printf("parca, version %s (commit: %s)\n", version, commit)
 		return
 	}
 
+	switch strings.Fields(kctx.Command())[0] {
+	case "query":
+		if err := parca.RunQuery(ctx, flags, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "query failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "gate":
+		if err := parca.RunGate(ctx, flags, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "gate failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "tsdb":
+		if err := parca.RunInspect(ctx, flags, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "inspect failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	serverStr := figure.NewColorFigure("Parca", "roman", "cyan", true)
 	serverStr.Print()
 
-	logger := parca.NewLogger(flags.Logs.Level, flags.Logs.Format, "parca")
+	logLevels, err := parca.NewLogLevels(flags.Logs.Level, flags.Logs.ComponentLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid log level: %v\n", err)
+		os.Exit(1)
+	}
+	logger := parca.NewLeveledLogger(logLevels, flags.Logs.Format, "parca")
 	level.Debug(logger).Log("msg", "parca initialized",
 		"version", version,
 		"commit", commit,
@@ -61,7 +88,7 @@ func main() {
 
 	registry := prometheus.NewRegistry()
 
-	err := parca.Run(ctx, logger, registry, flags, version)
+	err = parca.Run(ctx, logger, registry, flags, version, parca.WithLogLevels(logLevels))
 	if err != nil {
 		level.Error(logger).Log("msg", "Program exited with error", "err", err)
 		os.Exit(1)
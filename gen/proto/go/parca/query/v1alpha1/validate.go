@@ -30,6 +30,55 @@ func (r *QueryRangeRequest) Validate() error {
 	)
 }
 
+// Validate the QueryRangeWindowsRequest.
+func (r *QueryRangeWindowsRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Query, validation.Required),
+		validation.Field(&r.Windows, validation.Required),
+	)
+}
+
+// Validate the TimeWindow.
+func (w *TimeWindow) Validate() error {
+	return validation.ValidateStruct(w,
+		validation.Field(&w.Start, validation.Required),
+		validation.Field(&w.End, validation.Required, isAfter(w.Start)),
+	)
+}
+
+// Validate the CompareToBaselineRequest.
+func (r *CompareToBaselineRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.BaselineQuery, validation.Required),
+		validation.Field(&r.Start, validation.Required),
+		validation.Field(&r.End, validation.Required, isAfter(r.Start)),
+		validation.Field(&r.Profile, validation.Required),
+		validation.Field(&r.Rules, validation.Required),
+	)
+}
+
+// Validate the RegressionRule.
+func (r *RegressionRule) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Function, validation.Required),
+		validation.Field(&r.MaxIncrease, validation.Min(0.0)),
+	)
+}
+
+// Validate the QueryUnitsRequest.
+func (r *QueryUnitsRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Query, validation.Required),
+	)
+}
+
+// Validate the QueryRangeUnitsRequest.
+func (r *QueryRangeUnitsRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Query, validation.Required),
+	)
+}
+
 // Validate the QueryRequest.
 func (r *QueryRequest) Validate() error {
 	err := validation.ValidateStruct(r,
@@ -0,0 +1,296 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client provides a Go client for the Parca APIs.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	pprofprofile "github.com/google/pprof/profile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	querypb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+// ErrUnexpectedReport is returned when the server responds with a different
+// report than the one requested.
+var ErrUnexpectedReport = errors.New("unexpected report type in response")
+
+type options struct {
+	insecure           bool
+	insecureSkipVerify bool
+	bearerToken        string
+	dialOpts           []grpc.DialOption
+}
+
+type Option func(*options)
+
+// WithInsecure disables transport security.
+func WithInsecure() Option {
+	return func(o *options) {
+		o.insecure = true
+	}
+}
+
+// WithInsecureSkipVerify disables verification of the server certificate.
+func WithInsecureSkipVerify() Option {
+	return func(o *options) {
+		o.insecureSkipVerify = true
+	}
+}
+
+// WithBearerToken authenticates every request with the given bearer token.
+func WithBearerToken(token string) Option {
+	return func(o *options) {
+		o.bearerToken = token
+	}
+}
+
+// WithDialOptions appends additional options used to create the gRPC
+// connection.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOpts = append(o.dialOpts, opts...)
+	}
+}
+
+// Client is a client for the query, profile store and debuginfo APIs of a
+// Parca server.
+type Client struct {
+	conn *grpc.ClientConn
+
+	Query        querypb.QueryServiceClient
+	ProfileStore profilestorepb.ProfileStoreServiceClient
+	Debuginfo    debuginfopb.DebuginfoServiceClient
+}
+
+// New creates a client connected to the Parca server at the given address.
+func New(address string, opts ...Option) (*Client, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	dialOpts := []grpc.DialOption{}
+	if o.insecure {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: o.insecureSkipVerify,
+		})))
+	}
+	if o.bearerToken != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(&bearerToken{
+			token:    o.bearerToken,
+			insecure: o.insecure,
+		}))
+	}
+	dialOpts = append(dialOpts, o.dialOpts...)
+
+	conn, err := grpc.NewClient(address, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("create gRPC connection: %w", err)
+	}
+
+	c := NewFromConn(conn)
+	c.conn = conn
+	return c, nil
+}
+
+// NewFromConn creates a client using an existing connection. Closing the
+// client does not close the connection.
+func NewFromConn(conn grpc.ClientConnInterface) *Client {
+	return &Client{
+		Query:        querypb.NewQueryServiceClient(conn),
+		ProfileStore: profilestorepb.NewProfileStoreServiceClient(conn),
+		Debuginfo:    debuginfopb.NewDebuginfoServiceClient(conn),
+	}
+}
+
+// Close closes the underlying connection if it was created by New.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// WriteRaw pushes a serialized pprof profile with the given labels. The
+// labels must contain the profile name in the "__name__" label.
+func (c *Client) WriteRaw(ctx context.Context, labels map[string]string, pprof []byte) error {
+	_, err := c.ProfileStore.WriteRaw(ctx, &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{{
+			Labels:  labelSet(labels),
+			Samples: []*profilestorepb.RawSample{{RawProfile: pprof}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("write raw: %w", err)
+	}
+	return nil
+}
+
+// WriteProfile serializes and pushes a pprof profile with the given labels.
+func (c *Client) WriteProfile(ctx context.Context, labels map[string]string, p *pprofprofile.Profile) error {
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		return fmt.Errorf("serialize profile: %w", err)
+	}
+	return c.WriteRaw(ctx, labels, buf.Bytes())
+}
+
+// QueryMerge merges all profiles matching the query in the given time range
+// and returns the result as a pprof profile.
+func (c *Client) QueryMerge(ctx context.Context, query string, start, end time.Time) (*pprofprofile.Profile, error) {
+	return c.QueryPprof(ctx, &querypb.QueryRequest{
+		Mode: querypb.QueryRequest_MODE_MERGE,
+		Options: &querypb.QueryRequest_Merge{
+			Merge: &querypb.MergeProfile{
+				Query: query,
+				Start: timestamppb.New(start),
+				End:   timestamppb.New(end),
+			},
+		},
+	})
+}
+
+// QuerySingle returns the profile matching the query at the given time as a
+// pprof profile.
+func (c *Client) QuerySingle(ctx context.Context, query string, t time.Time) (*pprofprofile.Profile, error) {
+	return c.QueryPprof(ctx, &querypb.QueryRequest{
+		Mode: querypb.QueryRequest_MODE_SINGLE_UNSPECIFIED,
+		Options: &querypb.QueryRequest_Single{
+			Single: &querypb.SingleProfile{
+				Query: query,
+				Time:  timestamppb.New(t),
+			},
+		},
+	})
+}
+
+// QueryPprof runs the query and returns the result as a pprof profile. The
+// report type of the request is overwritten.
+func (c *Client) QueryPprof(ctx context.Context, req *querypb.QueryRequest) (*pprofprofile.Profile, error) {
+	req.ReportType = querypb.QueryRequest_REPORT_TYPE_PPROF
+
+	resp, err := c.Query.Query(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	pprof, ok := resp.Report.(*querypb.QueryResponse_Pprof)
+	if !ok {
+		return nil, ErrUnexpectedReport
+	}
+
+	p, err := pprofprofile.ParseData(pprof.Pprof)
+	if err != nil {
+		return nil, fmt.Errorf("parse pprof: %w", err)
+	}
+	return p, nil
+}
+
+// LabelValues returns the values of the label for the series matching the
+// matchers in the given time range.
+func (c *Client) LabelValues(ctx context.Context, name string, match []string, start, end time.Time) ([]string, error) {
+	resp, err := c.Query.Values(ctx, &querypb.ValuesRequest{
+		LabelName: name,
+		Match:     match,
+		Start:     timestamppb.New(start),
+		End:       timestamppb.New(end),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("values: %w", err)
+	}
+	return resp.LabelValues, nil
+}
+
+// EachLabelValue calls fn for every label name and value pair of the series
+// matching the matchers in the given time range. Iteration stops at the first
+// error returned by fn.
+func (c *Client) EachLabelValue(ctx context.Context, match []string, start, end time.Time, fn func(name, value string) error) error {
+	resp, err := c.Query.Labels(ctx, &querypb.LabelsRequest{
+		Match: match,
+		Start: timestamppb.New(start),
+		End:   timestamppb.New(end),
+	})
+	if err != nil {
+		return fmt.Errorf("labels: %w", err)
+	}
+
+	for _, name := range resp.LabelNames {
+		values, err := c.LabelValues(ctx, name, match, start, end)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if err := fn(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ShouldUploadDebuginfo reports whether the server wants debuginfo for the
+// given build ID to be uploaded, and the reason.
+func (c *Client) ShouldUploadDebuginfo(ctx context.Context, buildID string) (bool, string, error) {
+	resp, err := c.Debuginfo.ShouldInitiateUpload(ctx, &debuginfopb.ShouldInitiateUploadRequest{
+		BuildId: buildID,
+		Type:    debuginfopb.DebuginfoType_DEBUGINFO_TYPE_DEBUGINFO_UNSPECIFIED,
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("should initiate upload: %w", err)
+	}
+	return resp.ShouldInitiateUpload, resp.Reason, nil
+}
+
+func labelSet(labels map[string]string) *profilestorepb.LabelSet {
+	ls := &profilestorepb.LabelSet{
+		Labels: make([]*profilestorepb.Label, 0, len(labels)),
+	}
+	for name, value := range labels {
+		ls.Labels = append(ls.Labels, &profilestorepb.Label{Name: name, Value: value})
+	}
+	sort.Slice(ls.Labels, func(i, j int) bool {
+		return ls.Labels[i].Name < ls.Labels[j].Name
+	})
+	return ls
+}
+
+type bearerToken struct {
+	token    string
+	insecure bool
+}
+
+func (t *bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + t.token,
+	}, nil
+}
+
+func (t *bearerToken) RequireTransportSecurity() bool {
+	return !t.insecure
+}
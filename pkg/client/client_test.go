@@ -0,0 +1,141 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	querypb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+type fakeQueryServer struct {
+	querypb.UnimplementedQueryServiceServer
+
+	pprof      []byte
+	req        *querypb.QueryRequest
+	compareReq *querypb.CompareToBaselineRequest
+}
+
+func (s *fakeQueryServer) Query(_ context.Context, req *querypb.QueryRequest) (*querypb.QueryResponse, error) {
+	s.req = req
+	return &querypb.QueryResponse{
+		Report: &querypb.QueryResponse_Pprof{Pprof: s.pprof},
+	}, nil
+}
+
+func (s *fakeQueryServer) CompareToBaseline(_ context.Context, req *querypb.CompareToBaselineRequest) (*querypb.CompareToBaselineResponse, error) {
+	s.compareReq = req
+	return &querypb.CompareToBaselineResponse{
+		Functions: []*querypb.FunctionComparison{{Function: "main", Increase: 0.2, MaxIncrease: 0.1, Regressed: true}},
+		Regressed: true,
+	}, nil
+}
+
+func (s *fakeQueryServer) Labels(context.Context, *querypb.LabelsRequest) (*querypb.LabelsResponse, error) {
+	return &querypb.LabelsResponse{LabelNames: []string{"job"}}, nil
+}
+
+func (s *fakeQueryServer) Values(_ context.Context, req *querypb.ValuesRequest) (*querypb.ValuesResponse, error) {
+	return &querypb.ValuesResponse{LabelValues: []string{req.LabelName + "-a", req.LabelName + "-b"}}, nil
+}
+
+type fakeProfileStoreServer struct {
+	profilestorepb.UnimplementedProfileStoreServiceServer
+
+	req *profilestorepb.WriteRawRequest
+}
+
+func (s *fakeProfileStoreServer) WriteRaw(_ context.Context, req *profilestorepb.WriteRawRequest) (*profilestorepb.WriteRawResponse, error) {
+	s.req = req
+	return &profilestorepb.WriteRawResponse{}, nil
+}
+
+func testProfile() *pprofprofile.Profile {
+	fn := &pprofprofile.Function{ID: 1, Name: "main"}
+	loc := &pprofprofile.Location{ID: 1, Address: 0x1000, Line: []pprofprofile.Line{{Function: fn, Line: 1}}}
+	return &pprofprofile.Profile{
+		SampleType: []*pprofprofile.ValueType{{Type: "samples", Unit: "count"}},
+		Sample:     []*pprofprofile.Sample{{Location: []*pprofprofile.Location{loc}, Value: []int64{10}}},
+		Location:   []*pprofprofile.Location{loc},
+		Function:   []*pprofprofile.Function{fn},
+	}
+}
+
+func TestClient(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testProfile().Write(&buf))
+
+	qs := &fakeQueryServer{pprof: buf.Bytes()}
+	ps := &fakeProfileStoreServer{}
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	querypb.RegisterQueryServiceServer(srv, qs)
+	profilestorepb.RegisterProfileStoreServiceServer(srv, ps)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	c, err := New(
+		"passthrough:///bufnet",
+		WithInsecure(),
+		WithDialOptions(
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	ctx := context.Background()
+
+	require.NoError(t, c.WriteProfile(ctx, map[string]string{"job": "test", "__name__": "cpu"}, testProfile()))
+	require.Len(t, ps.req.Series, 1)
+	require.Equal(t, "__name__", ps.req.Series[0].Labels.Labels[0].Name)
+	require.Equal(t, "job", ps.req.Series[0].Labels.Labels[1].Name)
+
+	now := time.Now()
+	p, err := c.QueryMerge(ctx, `cpu:samples:count::{job="test"}`, now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Equal(t, querypb.QueryRequest_MODE_MERGE, qs.req.Mode)
+	require.Equal(t, querypb.QueryRequest_REPORT_TYPE_PPROF, qs.req.ReportType)
+	require.Len(t, p.Sample, 1)
+	require.Equal(t, int64(10), p.Sample[0].Value[0])
+
+	report, err := c.CompareToBaseline(ctx, `cpu:samples:count::{job="test"}`, now.Add(-time.Hour), now, buf.Bytes(), "samples", []RegressionRule{{Function: "^main$", MaxIncrease: 0.1}})
+	require.NoError(t, err)
+	require.True(t, report.Regressed)
+	require.Equal(t, "samples", qs.compareReq.SampleType)
+	require.Equal(t, buf.Bytes(), qs.compareReq.Profile)
+	require.Equal(t, "^main$", qs.compareReq.Rules[0].Function)
+
+	values := map[string][]string{}
+	require.NoError(t, c.EachLabelValue(ctx, nil, now.Add(-time.Hour), now, func(name, value string) error {
+		values[name] = append(values[name], value)
+		return nil
+	}))
+	require.Equal(t, map[string][]string{"job": {"job-a", "job-b"}}, values)
+}
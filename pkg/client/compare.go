@@ -0,0 +1,59 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	querypb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+// RegressionRule limits how much the functions matching a regular expression
+// may regress compared to a baseline.
+type RegressionRule struct {
+	// Function is a regular expression matching the names of the functions.
+	Function string
+	// MaxIncrease is the maximum allowed relative increase of the share of
+	// the total that is spent in the function, eg. 0.05 allows the
+	// function's share to grow by 5%.
+	MaxIncrease float64
+}
+
+// CompareToBaseline compares the pprof profile against the merge of the
+// profiles matching the baseline query in the given range. The values of the
+// profile's sample type of the given type, eg. cpu, are compared. An empty
+// sample type selects the one of the baseline query.
+func (c *Client) CompareToBaseline(ctx context.Context, baselineQuery string, start, end time.Time, pprof []byte, sampleType string, rules []RegressionRule) (*querypb.CompareToBaselineResponse, error) {
+	req := &querypb.CompareToBaselineRequest{
+		BaselineQuery: baselineQuery,
+		Start:         timestamppb.New(start),
+		End:           timestamppb.New(end),
+		Profile:       pprof,
+		SampleType:    sampleType,
+		Rules:         make([]*querypb.RegressionRule, 0, len(rules)),
+	}
+	for _, r := range rules {
+		req.Rules = append(req.Rules, &querypb.RegressionRule{Function: r.Function, MaxIncrease: r.MaxIncrease})
+	}
+
+	resp, err := c.Query.CompareToBaseline(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("compare to baseline: %w", err)
+	}
+	return resp, nil
+}
@@ -44,8 +44,13 @@ const (
 
 // Config holds all the configuration information for Parca.
 type Config struct {
-	ObjectStorage *ObjectStorage  `yaml:"object_storage,omitempty"`
-	ScrapeConfigs []*ScrapeConfig `yaml:"scrape_configs,omitempty"`
+	ObjectStorage *ObjectStorage    `yaml:"object_storage,omitempty"`
+	ScrapeConfigs []*ScrapeConfig   `yaml:"scrape_configs,omitempty"`
+	Symbolizer    *SymbolizerConfig `yaml:"symbolizer,omitempty"`
+	Storage       *StorageConfig    `yaml:"storage,omitempty"`
+	Ingest        *IngestConfig     `yaml:"ingest,omitempty"`
+
+	ScheduledQueries []*ScheduledQueryConfig `yaml:"scheduled_queries,omitempty"`
 }
 
 type ObjectStorage struct {
@@ -57,6 +62,10 @@ func (c *Config) Validate() error {
 	if err := validation.ValidateStruct(c,
 		validation.Field(&c.ObjectStorage, validation.Required, ObjectStorageValid),
 		validation.Field(&c.ScrapeConfigs, ScrapeConfigsValid),
+		validation.Field(&c.Symbolizer),
+		validation.Field(&c.Storage),
+		validation.Field(&c.Ingest),
+		validation.Field(&c.ScheduledQueries, validation.Each(validation.NotNil)),
 	); err != nil {
 		return err
 	}
@@ -163,6 +172,14 @@ type ScrapeConfig struct {
 
 	ProfilingConfig *ProfilingConfig `yaml:"profiling_config,omitempty"`
 
+	// ProfileSizeLimit limits the size of the profiles scraped from the
+	// targets, overriding the limit of the server.
+	ProfileSizeLimit *ProfileSizeLimitConfig `yaml:"profile_size_limit,omitempty"`
+
+	// AdaptiveInterval lengthens the scrape interval of targets whose
+	// profiles are stable and shortens it again when they change.
+	AdaptiveInterval *AdaptiveIntervalConfig `yaml:"adaptive_interval,omitempty"`
+
 	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
 	// We cannot do proper Go type embedding below as the parser will then parse
 	// values arbitrarily into the overflow maps of further-down types.
@@ -182,6 +199,51 @@ type ServiceDiscoveryConfig struct {
 	StaticConfigs []*targetgroup.Group `yaml:"static_configs,omitempty"`
 }
 
+// Modes of ProfileSizeLimitConfig.
+const (
+	ProfileSizeLimitModeReject = "reject"
+	ProfileSizeLimitModeTrim   = "trim"
+)
+
+// ProfileSizeLimitConfig limits the uncompressed size of scraped profiles.
+type ProfileSizeLimitConfig struct {
+	// MaxBytes is the maximum uncompressed size of a profile. Zero disables
+	// the limit.
+	MaxBytes int64 `yaml:"max_bytes"`
+	// Mode is what happens to profiles exceeding the limit: "reject" drops
+	// them and "trim" keeps their top_k heaviest samples.
+	Mode string `yaml:"mode,omitempty"`
+	TopK int    `yaml:"top_k,omitempty"`
+}
+
+// DefaultProfileSizeLimitTopK is the number of samples trimmed profiles keep
+// unless configured.
+const DefaultProfileSizeLimitTopK = 1000
+
+// AdaptiveIntervalConfig adapts the scrape interval of a target between the
+// scrape_interval and max_interval by the similarity of its consecutive
+// profiles. The similarity is one minus the total variation distance of the
+// shares of the stacks in the profiles.
+type AdaptiveIntervalConfig struct {
+	MaxInterval model.Duration `yaml:"max_interval"`
+	// StableSimilarity is the similarity from which on the interval is
+	// doubled.
+	StableSimilarity float64 `yaml:"stable_similarity,omitempty"`
+	// ChangeSimilarity is the similarity below which the interval is halved.
+	ChangeSimilarity float64 `yaml:"change_similarity,omitempty"`
+	// SpikeFactor is the factor by which the total of a profile has to
+	// differ from the previous one to reset the interval to the
+	// scrape_interval.
+	SpikeFactor float64 `yaml:"spike_factor,omitempty"`
+}
+
+// Defaults of the adaptive scrape interval.
+const (
+	DefaultAdaptiveStableSimilarity = 0.95
+	DefaultAdaptiveChangeSimilarity = 0.8
+	DefaultAdaptiveSpikeFactor      = 2
+)
+
 type ProfilingConfig struct {
 	PprofConfig PprofConfig `yaml:"pprof_config,omitempty"`
 	PprofPrefix string      `yaml:"path_prefix,omitempty"`
@@ -235,6 +297,12 @@ func (c *ScrapeConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return errors.New("job_name is empty")
 	}
 
+	// An empty scrape_interval keeps the default, so that everything relative
+	// to the interval is validated against the one actually used.
+	if c.ScrapeInterval == 0 {
+		c.ScrapeInterval = defaults.ScrapeInterval
+	}
+
 	// The UnmarshalYAML method of HTTPClientConfig is not being called because it's not a pointer.
 	// We cannot make it a pointer as the parser panics for inlined pointer structs.
 	// Thus we just do its validation here.
@@ -255,6 +323,46 @@ func (c *ScrapeConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		}
 	}
 
+	if l := c.ProfileSizeLimit; l != nil {
+		switch l.Mode {
+		case "":
+			l.Mode = ProfileSizeLimitModeReject
+		case ProfileSizeLimitModeReject, ProfileSizeLimitModeTrim:
+		default:
+			return fmt.Errorf("invalid profile size limit mode %q, must be %q or %q", l.Mode, ProfileSizeLimitModeReject, ProfileSizeLimitModeTrim)
+		}
+		if l.MaxBytes < 0 {
+			return errors.New("profile size limit max_bytes must not be negative")
+		}
+		if l.TopK == 0 {
+			l.TopK = DefaultProfileSizeLimitTopK
+		}
+		if l.TopK < 0 {
+			return errors.New("profile size limit top_k must be positive")
+		}
+	}
+
+	if a := c.AdaptiveInterval; a != nil {
+		if a.MaxInterval < c.ScrapeInterval {
+			return fmt.Errorf("adaptive max_interval must not be less than the scrape interval: %v", c.JobName)
+		}
+		if a.StableSimilarity == 0 {
+			a.StableSimilarity = DefaultAdaptiveStableSimilarity
+		}
+		if a.ChangeSimilarity == 0 {
+			a.ChangeSimilarity = DefaultAdaptiveChangeSimilarity
+		}
+		if a.SpikeFactor == 0 {
+			a.SpikeFactor = DefaultAdaptiveSpikeFactor
+		}
+		if a.ChangeSimilarity > a.StableSimilarity || a.StableSimilarity > 1 {
+			return fmt.Errorf("adaptive similarities must satisfy change_similarity <= stable_similarity <= 1: %v", c.JobName)
+		}
+		if a.SpikeFactor <= 1 {
+			return fmt.Errorf("adaptive spike_factor must be greater than 1: %v", c.JobName)
+		}
+	}
+
 	// Validate the scrape and timeout internal configuration. When /debug/pprof/profile scraping
 	// is enabled we need to make sure there is enough time to complete the scrape.
 	if c.ScrapeTimeout == 0 {
@@ -334,3 +334,37 @@ object_storage:
 	require.NoError(t, err)
 	require.Equal(t, expected, c)
 }
+
+func TestLoadAdaptiveInterval(t *testing.T) {
+	t.Parallel()
+
+	adaptiveYAML := func(scrapeInterval, maxInterval string) string {
+		return `
+object_storage:
+  bucket:
+    type: "FILESYSTEM"
+    config:
+      directory: "./data"
+scrape_configs:
+  - job_name: 'parca'
+    scrape_interval: ` + scrapeInterval + `
+    adaptive_interval:
+      max_interval: ` + maxInterval + `
+    static_configs:
+      - targets: [ 'localhost:10902' ]
+`
+	}
+
+	// The max interval is checked against the default scrape interval.
+	c, err := Load(adaptiveYAML("0s", "30s"))
+	require.NoError(t, err)
+	require.Equal(t, model.Duration(10*time.Second), c.ScrapeConfigs[0].ScrapeInterval)
+	require.Equal(t, model.Duration(13*time.Second), c.ScrapeConfigs[0].ScrapeTimeout)
+	require.Equal(t, DefaultAdaptiveStableSimilarity, c.ScrapeConfigs[0].AdaptiveInterval.StableSimilarity)
+
+	_, err = Load(adaptiveYAML("0s", "5s"))
+	require.ErrorContains(t, err, "adaptive max_interval must not be less than the scrape interval")
+
+	_, err = Load(adaptiveYAML("1s", "5s"))
+	require.NoError(t, err)
+}
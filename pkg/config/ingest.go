@@ -0,0 +1,77 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// IngestConfig configures how written profiles are processed before they
+// are stored.
+type IngestConfig struct {
+	// Sampling rules are evaluated in order, the first rule matching a series
+	// applies to it.
+	Sampling []*SamplingConfig `yaml:"sampling,omitempty"`
+}
+
+// Sampling modes.
+const (
+	SamplingModeEveryNth      = "every_nth"
+	SamplingModeProbabilistic = "probabilistic"
+)
+
+// SamplingConfig keeps only a fraction of the profiles of the matching
+// series. The values of kept profiles are scaled up accordingly, so that
+// merged results remain statistically correct.
+type SamplingConfig struct {
+	// Matchers is a series selector, eg. {namespace=~"dev-.*"}.
+	Matchers string `yaml:"matchers"`
+	// Keep is N of keeping one of every N profiles.
+	Keep int `yaml:"keep"`
+	// Mode is either "every_nth", keeping exactly every Nth profile of a
+	// series, or "probabilistic", keeping each profile with a probability of
+	// 1/N. Defaults to "every_nth".
+	Mode string `yaml:"mode,omitempty"`
+}
+
+// Validate returns an error if the ingest config is not valid.
+func (c *IngestConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Sampling, validation.Each(validation.NotNil)),
+	)
+}
+
+// Validate returns an error if the sampling config is not valid.
+func (c *SamplingConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Matchers, validation.Required, validation.By(validSelector)),
+		validation.Field(&c.Keep, validation.Required, validation.Min(1)),
+		validation.Field(&c.Mode, validation.In(SamplingModeEveryNth, SamplingModeProbabilistic)),
+	)
+}
+
+func validSelector(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+	if _, err := parser.ParseMetricSelector(s); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	return nil
+}
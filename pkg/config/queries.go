@@ -0,0 +1,73 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/prometheus/common/model"
+)
+
+// ScheduledQueryConfig periodically queries the top functions of the
+// profiles of the last range and pushes them to the sinks.
+type ScheduledQueryConfig struct {
+	Name string `yaml:"name"`
+	// Query selects the profiles, eg.
+	// parca_agent:samples:count:cpu:nanoseconds:delta{namespace="prod"}.
+	Query    string         `yaml:"query"`
+	Range    model.Duration `yaml:"range"`
+	Interval model.Duration `yaml:"interval"`
+	// Limit is the number of functions pushed, by their flat value. Zero
+	// pushes all functions.
+	Limit int `yaml:"limit,omitempty"`
+	// Diff compares the range against the preceding range of the same
+	// length.
+	Diff  bool               `yaml:"diff,omitempty"`
+	Sinks []*QuerySinkConfig `yaml:"sinks"`
+}
+
+// Types of the sinks of scheduled queries.
+const (
+	QuerySinkWebhook = "webhook"
+	QuerySinkBucket  = "bucket"
+)
+
+// QuerySinkConfig is where the results of a scheduled query are pushed to:
+// posted as JSON to a webhook, or written to the object storage as JSON or
+// Parquet.
+type QuerySinkConfig struct {
+	Type   string `yaml:"type"`
+	URL    string `yaml:"url,omitempty"`
+	Format string `yaml:"format,omitempty"`
+}
+
+// Validate returns an error if the scheduled query is not valid.
+func (c *ScheduledQueryConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Name, validation.Required),
+		validation.Field(&c.Query, validation.Required, validation.By(validSelector)),
+		validation.Field(&c.Range, validation.Required, validation.Min(model.Duration(0))),
+		validation.Field(&c.Interval, validation.Required, validation.Min(model.Duration(0))),
+		validation.Field(&c.Limit, validation.Min(0)),
+		validation.Field(&c.Sinks, validation.Required, validation.Each(validation.NotNil)),
+	)
+}
+
+// Validate returns an error if the sink is not valid.
+func (c *QuerySinkConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Type, validation.Required, validation.In(QuerySinkWebhook, QuerySinkBucket)),
+		validation.Field(&c.URL, validation.When(c.Type == QuerySinkWebhook, validation.Required)),
+		validation.Field(&c.Format, validation.In("json", "parquet")),
+	)
+}
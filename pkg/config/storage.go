@@ -0,0 +1,139 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/prometheus/common/model"
+)
+
+// StorageConfig configures the retention tiers of the profile storage.
+type StorageConfig struct {
+	// RawRetention is how far back queries are served from the raw profile
+	// data, and how long its persisted blocks are kept. Zero means forever.
+	RawRetention model.Duration `yaml:"raw_retention,omitempty"`
+	// Tiers are downsampled copies of the profile data, ordered from the
+	// finest to the coarsest resolution.
+	Tiers []*StorageTier `yaml:"tiers,omitempty"`
+	// QueryPolicy is how range queries are served from the tiers: "start"
+	// serves the whole range from the finest tier covering its start,
+	// "stitch" serves each part of the range from the finest tier covering
+	// it. Defaults to "start".
+	QueryPolicy string `yaml:"query_policy,omitempty"`
+	// Rollups are recording rules continuously merging series into
+	// synthetic series written back to the storage, eg. of all pods into one
+	// series per deployment.
+	Rollups []*StorageRollup `yaml:"rollups,omitempty"`
+}
+
+// Query policies of the storage tiers.
+const (
+	StorageQueryPolicyStart  = "start"
+	StorageQueryPolicyStitch = "stitch"
+)
+
+// StorageTier is a downsampled copy of the profile data, in which the delta
+// profiles of each series are merged into one profile per resolution window.
+type StorageTier struct {
+	Resolution model.Duration `yaml:"resolution"`
+	// Retention is how far back queries are served from the tier, and how
+	// long its persisted blocks are kept. Zero means forever.
+	Retention model.Duration `yaml:"retention,omitempty"`
+}
+
+// StorageRollup merges the delta profiles of the series matching the
+// matchers into one series per value of the By labels and interval. The
+// synthetic series get the __rollup__ label set to the name of the rollup,
+// and only queries selecting that label read them.
+type StorageRollup struct {
+	Name string `yaml:"name"`
+	// Query restricts the rollup to a delta profile type, eg.
+	// parca_agent:samples:count:cpu:nanoseconds:delta{namespace="prod"}.
+	// It can't be combined with matchers.
+	Query string `yaml:"query,omitempty"`
+	// Matchers is a series selector, eg. {namespace="prod"}. All series are
+	// rolled up if neither it nor the query is set.
+	Matchers string   `yaml:"matchers,omitempty"`
+	By       []string `yaml:"by"`
+	// Labels are added to the synthetic series.
+	Labels   map[string]string `yaml:"labels,omitempty"`
+	Interval model.Duration    `yaml:"interval"`
+}
+
+// Validate returns an error if the storage config is not valid.
+func (c *StorageConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.RawRetention, validation.Min(model.Duration(0))),
+		validation.Field(&c.Tiers, validation.Each(validation.NotNil), validation.By(validTiers)),
+		validation.Field(&c.QueryPolicy, validation.In(StorageQueryPolicyStart, StorageQueryPolicyStitch)),
+		validation.Field(&c.Rollups, validation.Each(validation.NotNil)),
+	)
+}
+
+// Validate returns an error if the rollup is not valid.
+func (r *StorageRollup) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Name, validation.Required),
+		validation.Field(&r.Query, validation.When(r.Query != "", validation.By(validSelector))),
+		validation.Field(&r.Matchers,
+			validation.When(r.Query != "", validation.Empty.Error("can't be combined with a query")),
+			validation.When(r.Matchers != "", validation.By(validSelector)),
+		),
+		validation.Field(&r.By, validation.Required, validation.Each(validation.By(validLabelName))),
+		validation.Field(&r.Interval, validation.Required, validation.Min(model.Duration(0))),
+	)
+}
+
+func validLabelName(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+	if !model.LabelName(s).IsValid() {
+		return fmt.Errorf("invalid label name %q", s)
+	}
+	return nil
+}
+
+// Validate returns an error if the tier is not valid.
+func (t *StorageTier) Validate() error {
+	return validation.ValidateStruct(t,
+		validation.Field(&t.Resolution, validation.Required, validation.Min(model.Duration(0))),
+		validation.Field(&t.Retention, validation.Min(model.Duration(0))),
+	)
+}
+
+// validTiers checks that every tier's resolution is a multiple of the
+// previous tier's, so that each tier can be produced from the previous one.
+func validTiers(value interface{}) error {
+	tiers, ok := value.([]*StorageTier)
+	if !ok {
+		return errors.New("must be a list of tiers")
+	}
+
+	var prev model.Duration
+	for i, t := range tiers {
+		if t == nil || t.Resolution <= 0 {
+			continue
+		}
+		if prev != 0 && (t.Resolution <= prev || t.Resolution%prev != 0) {
+			return fmt.Errorf("tier %d: resolution %s must be a multiple of the previous tier's resolution %s", i, t.Resolution, prev)
+		}
+		prev = t.Resolution
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	// FrameTransformStripTemplateArgs removes template and generic arguments
+	// from function names, eg. "foo<int>::bar" becomes "foo::bar".
+	FrameTransformStripTemplateArgs = "strip_template_args"
+	// FrameTransformCollapseStdInternals collapses consecutive inlined frames
+	// of the standard library into the frame that was called from user code.
+	FrameTransformCollapseStdInternals = "collapse_std_internals"
+	// FrameTransformRename rewrites the function name (or filename) of frames
+	// matching a regular expression.
+	FrameTransformRename = "rename"
+	// FrameTransformFoldAsync folds compiler-generated async state machine
+	// frames (Rust async fn closures, C++ coroutine resume/destroy clones)
+	// into their logical parent function while the stacks of a queried
+	// profile are built, across the locations of a stack.
+	FrameTransformFoldAsync = "fold_async"
+	// FrameTransformLabelRuntime renames frames internal to well-known
+	// runtimes (Go scheduler and garbage collector, JVM garbage collector and
+	// JIT compiler, CPython eval loop) to a display name prefixed with
+	// "[runtime] " and merges consecutive frames of the same component.
+	FrameTransformLabelRuntime = "label_runtime"
+)
+
+const (
+	FrameTransformFieldFunctionName = "function_name"
+	FrameTransformFieldFilename     = "filename"
+)
+
+// FrameLanguages are the languages a frame transform rule can be restricted to.
+var FrameLanguages = []interface{}{"c", "cpp", "go", "rust"}
+
+// SymbolizerConfig configures the post-processing of symbolized frames.
+type SymbolizerConfig struct {
+	FrameTransforms []*FrameTransformRule `yaml:"frame_transforms,omitempty"`
+}
+
+// FrameTransformRule applies a list of transforms to the frames of all
+// locations whose mapping and source language match.
+type FrameTransformRule struct {
+	// Mapping is a regular expression matched against the file of the
+	// location's mapping. An empty expression matches all mappings.
+	Mapping string `yaml:"mapping,omitempty"`
+	// Language restricts the rule to locations whose source file belongs to
+	// the given language. An empty language matches all locations.
+	Language   string            `yaml:"language,omitempty"`
+	Transforms []*FrameTransform `yaml:"transforms"`
+}
+
+// FrameTransform is a single transformation applied to symbolized frames.
+type FrameTransform struct {
+	Type string `yaml:"type"`
+
+	// Field, Pattern and Replacement configure the rename transform. Field
+	// defaults to the function name.
+	Field       string `yaml:"field,omitempty"`
+	Pattern     string `yaml:"pattern,omitempty"`
+	Replacement string `yaml:"replacement,omitempty"`
+}
+
+// Validate returns an error if the symbolizer config is not valid.
+func (c *SymbolizerConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.FrameTransforms, validation.Each(validation.NotNil)),
+	)
+}
+
+// Validate returns an error if the rule is not valid.
+func (r *FrameTransformRule) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Mapping, validation.By(validRegexp)),
+		validation.Field(&r.Language, validation.In(FrameLanguages...)),
+		validation.Field(&r.Transforms, validation.Required, validation.Each(validation.NotNil)),
+	)
+}
+
+// Validate returns an error if the transform is not valid.
+func (t *FrameTransform) Validate() error {
+	return validation.ValidateStruct(t,
+		validation.Field(&t.Type, validation.Required, validation.In(
+			FrameTransformStripTemplateArgs,
+			FrameTransformCollapseStdInternals,
+			FrameTransformRename,
+			FrameTransformFoldAsync,
+			FrameTransformLabelRuntime,
+		)),
+		validation.Field(&t.Field, validation.In(
+			FrameTransformFieldFunctionName,
+			FrameTransformFieldFilename,
+		)),
+		validation.Field(&t.Pattern,
+			validation.When(t.Type == FrameTransformRename, validation.Required),
+			validation.By(validRegexp),
+		),
+	)
+}
+
+func validRegexp(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+	if _, err := regexp.Compile(s); err != nil {
+		return fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/thanos-io/objstore"
 
@@ -31,15 +32,18 @@ var (
 type Fetcher struct {
 	debuginfodClients DebuginfodClients
 	bucket            objstore.Bucket
+	usage             *Usage
 }
 
 func NewFetcher(
 	debuginfodClients DebuginfodClients,
 	bucket objstore.Bucket,
+	usage *Usage,
 ) *Fetcher {
 	return &Fetcher{
 		debuginfodClients: debuginfodClients,
 		bucket:            bucket,
+		usage:             usage,
 	}
 }
 
@@ -55,6 +59,7 @@ func (f *Fetcher) FetchDebuginfo(ctx context.Context, dbginfo *debuginfopb.Debug
 }
 
 func (f *Fetcher) fetchFromBucket(ctx context.Context, dbginfo *debuginfopb.Debuginfo) (io.ReadCloser, error) {
+	f.usage.touch(dbginfo.BuildId, dbginfo.Type, time.Now())
 	return f.bucket.Get(ctx, objectPath(dbginfo.BuildId, dbginfo.Type))
 }
 
@@ -0,0 +1,271 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debuginfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+)
+
+// GCPath is the HTTP path reporting what the debuginfo garbage collection
+// would delete, relative to the API root.
+const GCPath = "/debuginfo/gc"
+
+// BuildIDReferences returns the build IDs referenced by stored profiles,
+// with the last time each was seen.
+type BuildIDReferences interface {
+	ReferencedBuildIDs(ctx context.Context) (map[string]time.Time, error)
+}
+
+// GCCandidate is an uploaded debuginfo that isn't referenced by any stored
+// profile anymore.
+type GCCandidate struct {
+	BuildID  string    `json:"buildId"`
+	Type     string    `json:"type"`
+	Size     int64     `json:"size"`
+	Uploaded time.Time `json:"uploaded"`
+
+	typ debuginfopb.DebuginfoType
+}
+
+var debuginfoTypes = []debuginfopb.DebuginfoType{
+	debuginfopb.DebuginfoType_DEBUGINFO_TYPE_DEBUGINFO_UNSPECIFIED,
+	debuginfopb.DebuginfoType_DEBUGINFO_TYPE_EXECUTABLE,
+	debuginfopb.DebuginfoType_DEBUGINFO_TYPE_SOURCES,
+}
+
+// GC deletes uploaded debuginfos whose build IDs have not been referenced by
+// any stored profile for a grace period. Uploads younger than the grace
+// period are kept, as their profiles may not be written yet.
+type GC struct {
+	logger     log.Logger
+	bucket     objstore.Bucket
+	metadata   MetadataManager
+	usage      *Usage
+	references BuildIDReferences
+	grace      time.Duration
+
+	// unreferencedSince holds when each build ID was first found not to be
+	// referenced anymore. It is only kept in memory, so after a restart the
+	// grace period starts over.
+	mtx               sync.Mutex
+	unreferencedSince map[string]time.Time
+
+	deleted      prometheus.Counter
+	deletedBytes prometheus.Counter
+
+	timeNow func() time.Time
+}
+
+func NewGC(
+	logger log.Logger,
+	reg prometheus.Registerer,
+	bucket objstore.Bucket,
+	metadata MetadataManager,
+	usage *Usage,
+	references BuildIDReferences,
+	grace time.Duration,
+) *GC {
+	return &GC{
+		logger:     log.With(logger, "component", "debuginfo-gc"),
+		bucket:     bucket,
+		metadata:   metadata,
+		usage:      usage,
+		references: references,
+		grace:      grace,
+
+		unreferencedSince: map[string]time.Time{},
+		deleted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_debuginfo_gc_deleted_total",
+			Help: "Number of unreferenced debuginfos deleted.",
+		}),
+		deletedBytes: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_debuginfo_gc_deleted_bytes_total",
+			Help: "Bytes of unreferenced debuginfos deleted.",
+		}),
+		timeNow: time.Now,
+	}
+}
+
+// Plan returns the debuginfos a collection would delete, largest first.
+func (g *GC) Plan(ctx context.Context) ([]GCCandidate, error) {
+	now := g.timeNow()
+	referenced, err := g.references.ReferencedBuildIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get referenced build IDs: %w", err)
+	}
+
+	var buildIDs []string
+	if err := g.bucket.Iter(ctx, "", func(name string) error {
+		if strings.HasSuffix(name, objstore.DirDelim) {
+			buildIDs = append(buildIDs, strings.TrimSuffix(name, objstore.DirDelim))
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list debuginfos: %w", err)
+	}
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	var res []GCCandidate
+	for _, buildID := range buildIDs {
+		if _, ok := referenced[buildID]; ok {
+			delete(g.unreferencedSince, buildID)
+			continue
+		}
+		since, ok := g.unreferencedSince[buildID]
+		if !ok {
+			since = now
+			g.unreferencedSince[buildID] = since
+		}
+		if since.Add(g.grace).After(now) {
+			continue
+		}
+
+		for _, typ := range debuginfoTypes {
+			dbginfo, err := g.metadata.Fetch(ctx, buildID, typ)
+			if errors.Is(err, ErrMetadataNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			// Debuginfos from debuginfod servers are not stored.
+			if dbginfo.Source != debuginfopb.Debuginfo_SOURCE_UPLOAD || dbginfo.Upload == nil {
+				continue
+			}
+
+			uploaded := dbginfo.Upload.FinishedAt
+			if uploaded == nil {
+				uploaded = dbginfo.Upload.StartedAt
+			}
+			if uploaded.AsTime().Add(g.grace).After(now) {
+				continue
+			}
+
+			c := GCCandidate{
+				BuildID:  buildID,
+				Type:     typ.String(),
+				Uploaded: uploaded.AsTime(),
+				typ:      typ,
+			}
+			attrs, err := g.bucket.Attributes(ctx, objectPath(buildID, typ))
+			if err == nil {
+				c.Size = attrs.Size
+			} else if !g.bucket.IsObjNotFoundErr(err) {
+				return nil, err
+			}
+			res = append(res, c)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Size != res[j].Size {
+			return res[i].Size > res[j].Size
+		}
+		return res[i].BuildID < res[j].BuildID
+	})
+	return res, nil
+}
+
+// Collect deletes the debuginfos returned by Plan along with their metadata.
+func (g *GC) Collect(ctx context.Context) ([]GCCandidate, error) {
+	candidates, err := g.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range candidates {
+		if err := g.bucket.Delete(ctx, objectPath(c.BuildID, c.typ)); err != nil && !g.bucket.IsObjNotFoundErr(err) {
+			return candidates[:i], fmt.Errorf("delete debuginfo %s: %w", c.BuildID, err)
+		}
+		if err := g.bucket.Delete(ctx, metadataObjectPath(c.BuildID, c.typ)); err != nil && !g.bucket.IsObjNotFoundErr(err) {
+			return candidates[:i], fmt.Errorf("delete debuginfo metadata %s: %w", c.BuildID, err)
+		}
+		g.usage.forget(c.BuildID, c.typ)
+		g.mtx.Lock()
+		delete(g.unreferencedSince, c.BuildID)
+		g.mtx.Unlock()
+		g.deleted.Inc()
+		g.deletedBytes.Add(float64(c.Size))
+	}
+	return candidates, nil
+}
+
+// Run collects unreferenced debuginfos in the interval until the context is
+// canceled.
+func (g *GC) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			deleted, err := g.Collect(ctx)
+			if err != nil {
+				level.Warn(g.logger).Log("msg", "failed to collect unreferenced debuginfos", "err", err)
+			}
+			if len(deleted) > 0 {
+				level.Info(g.logger).Log("msg", "deleted unreferenced debuginfos", "count", len(deleted))
+			}
+		}
+	}
+}
+
+// Handler serves a dry run of the collection, the debuginfos it would
+// delete.
+func (g *GC) Handler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		candidates, err := g.Plan(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var total int64
+		for _, c := range candidates {
+			total += c.Size
+		}
+		if candidates == nil {
+			candidates = []GCCandidate{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(struct {
+			Candidates []GCCandidate `json:"candidates"`
+			Bytes      int64         `json:"bytes"`
+		}{Candidates: candidates, Bytes: total}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debuginfo
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+)
+
+type fakeReferences map[string]time.Time
+
+func (r fakeReferences) ReferencedBuildIDs(context.Context) (map[string]time.Time, error) {
+	return r, nil
+}
+
+func TestGC(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	bucket := objstore.NewInMemBucket()
+	metadata := NewObjectStoreMetadata(logger, bucket)
+	typ := debuginfopb.DebuginfoType_DEBUGINFO_TYPE_DEBUGINFO_UNSPECIFIED
+	now := time.Unix(10000, 0)
+
+	upload := func(buildID string, size int, at time.Time) {
+		require.NoError(t, metadata.MarkAsUploading(ctx, buildID, "upload", "hash", typ, timestamppb.New(at)))
+		require.NoError(t, bucket.Upload(ctx, objectPath(buildID, typ), bytes.NewReader(make([]byte, size))))
+		require.NoError(t, metadata.MarkAsUploaded(ctx, buildID, "upload", typ, timestamppb.New(at)))
+	}
+	upload("referenced", 10, now.Add(-time.Hour))
+	upload("unreferenced", 20, now.Add(-time.Hour))
+	upload("recent", 30, now.Add(9*time.Minute))
+	upload("expiring", 40, now.Add(-time.Hour))
+	require.NoError(t, metadata.MarkAsDebuginfodSource(ctx, []string{"debuginfod"}, "debuginfod", typ))
+
+	references := fakeReferences{"referenced": now, "expiring": now}
+
+	g := NewGC(logger, prometheus.NewRegistry(), bucket, metadata, nil, references, 10*time.Minute)
+	g.timeNow = func() time.Time { return now }
+
+	// Build IDs are kept for the grace period after they were first found
+	// unreferenced.
+	candidates, err := g.Plan(ctx)
+	require.NoError(t, err)
+	require.Empty(t, candidates)
+
+	// The profiles referencing a build ID were deleted by the retention.
+	delete(references, "expiring")
+	now = now.Add(10 * time.Minute)
+	candidates, err = g.Plan(ctx)
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	require.Equal(t, "unreferenced", candidates[0].BuildID)
+	require.Equal(t, int64(20), candidates[0].Size)
+
+	// Planning is a dry run.
+	exists, err := bucket.Exists(ctx, objectPath("unreferenced", typ))
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	_, err = g.Collect(ctx)
+	require.NoError(t, err)
+	exists, err = bucket.Exists(ctx, objectPath("unreferenced", typ))
+	require.NoError(t, err)
+	require.False(t, exists)
+	_, err = metadata.Fetch(ctx, "unreferenced", typ)
+	require.ErrorIs(t, err, ErrMetadataNotFound)
+
+	candidates, err = g.Plan(ctx)
+	require.NoError(t, err)
+	require.Empty(t, candidates)
+
+	now = now.Add(10 * time.Minute)
+	candidates, err = g.Plan(ctx)
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	require.Equal(t, "expiring", candidates[0].BuildID)
+	require.Equal(t, "recent", candidates[1].BuildID)
+}
@@ -27,6 +27,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+	"github.com/parca-dev/parca/pkg/faults"
 )
 
 var (
@@ -106,6 +107,10 @@ func (m *ObjectStoreMetadata) MarkAsUploaded(ctx context.Context, buildID, uploa
 }
 
 func (m *ObjectStoreMetadata) Fetch(ctx context.Context, buildID string, typ debuginfopb.DebuginfoType) (*debuginfopb.Debuginfo, error) {
+	if err := faults.Inject(ctx, faults.DebuginfoMetadataFetch); err != nil {
+		return nil, err
+	}
+
 	path := metadataObjectPath(buildID, typ)
 	r, err := m.bucket.Get(ctx, path)
 	if err != nil {
@@ -132,6 +137,9 @@ func (m *ObjectStoreMetadata) write(ctx context.Context, dbginfo *debuginfopb.De
 	if dbginfo.BuildId == "" {
 		return errors.New("build id is required to wirte debuginfo metadata")
 	}
+	if err := faults.Inject(ctx, faults.DebuginfoMetadataWrite); err != nil {
+		return err
+	}
 
 	// Writing in multiline mode to make it easier to read for humans.
 	debuginfoJSON, err := (protojson.MarshalOptions{Multiline: true}).Marshal(dbginfo)
@@ -65,6 +65,7 @@ func TestMetadata(t *testing.T) {
 		},
 		time.Minute*15,
 		1024*1024*1024,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -0,0 +1,439 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debuginfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+)
+
+const (
+	// LargestDebuginfosPath is the HTTP path listing the largest stored
+	// debuginfos, relative to the API root.
+	LargestDebuginfosPath = "/debuginfo/largest"
+
+	usageObjectPath = "usage.json"
+)
+
+// Quotas limits the bytes of uploaded debuginfos stored per tenant. A limit
+// of 0 means no limit.
+type Quotas struct {
+	Default int64
+	Tenants map[string]int64
+	// Tokens are the bearer tokens uploads are authenticated with, mapped
+	// to the tenant they are accounted to. Without tokens all uploads
+	// belong to the default tenant "".
+	Tokens map[string]string
+}
+
+func (q Quotas) limit(tenant string) int64 {
+	if l, ok := q.Tenants[tenant]; ok {
+		return l
+	}
+	return q.Default
+}
+
+// UsageEntry is an uploaded debuginfo accounted to a tenant.
+type UsageEntry struct {
+	BuildID  string    `json:"buildId"`
+	Type     string    `json:"type"`
+	Tenant   string    `json:"tenant,omitempty"`
+	Size     int64     `json:"size"`
+	Uploaded time.Time `json:"uploaded"`
+	LastUsed time.Time `json:"lastUsed,omitempty"`
+}
+
+// Usage tracks the bytes of uploaded debuginfos stored per tenant and when
+// they were last used for symbolization, and enforces the quotas on new
+// uploads. It is persisted to the bucket, so it survives restarts. A nil
+// Usage doesn't track anything.
+type Usage struct {
+	logger log.Logger
+	bucket objstore.Bucket
+	quotas Quotas
+
+	stored *prometheus.GaugeVec
+
+	mtx     sync.Mutex
+	entries map[string]*UsageEntry
+	tenants map[string]int64
+	// reservations are the sizes declared by the uploads in progress, by
+	// their upload ID. They count against the quotas until the uploads
+	// are finished, fail or become stale.
+	reservations map[string]*reservation
+	dirty        bool
+}
+
+type reservation struct {
+	tenant  string
+	key     string
+	size    int64
+	expires time.Time
+}
+
+// NewUsage returns a usage tracker persisted to the bucket. Load has to be
+// called to read the previously persisted usage.
+func NewUsage(logger log.Logger, reg prometheus.Registerer, bucket objstore.Bucket, quotas Quotas) *Usage {
+	return &Usage{
+		logger: log.With(logger, "component", "debuginfo-usage"),
+		bucket: bucket,
+		quotas: quotas,
+		stored: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "parca_debuginfo_stored_bytes",
+			Help: "Bytes of uploaded debuginfos stored per tenant.",
+		}, []string{"tenant"}),
+		entries:      map[string]*UsageEntry{},
+		tenants:      map[string]int64{},
+		reservations: map[string]*reservation{},
+	}
+}
+
+// tenant returns the tenant the bearer token of the request is mapped to.
+func (u *Usage) tenant(ctx context.Context) (string, error) {
+	if u == nil || len(u.quotas.Tokens) == 0 {
+		return "", nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(v, "Bearer ")
+		if !ok {
+			continue
+		}
+		if tenant, ok := u.quotas.Tokens[token]; ok {
+			return tenant, nil
+		}
+	}
+	return "", status.Error(codes.Unauthenticated, "a valid bearer token is required to upload debuginfos")
+}
+
+// Load reads the persisted usage from the bucket.
+func (u *Usage) Load(ctx context.Context) error {
+	if u == nil {
+		return nil
+	}
+
+	r, err := u.bucket.Get(ctx, usageObjectPath)
+	if err != nil {
+		if u.bucket.IsObjNotFoundErr(err) {
+			return nil
+		}
+		return fmt.Errorf("fetch debuginfo usage: %w", err)
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read debuginfo usage: %w", err)
+	}
+	var entries []*UsageEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return fmt.Errorf("unmarshal debuginfo usage: %w", err)
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.entries = make(map[string]*UsageEntry, len(entries))
+	u.tenants = map[string]int64{}
+	for _, e := range entries {
+		u.entries[usageKey(e.BuildID, e.Type)] = e
+		u.tenants[e.Tenant] += e.Size
+	}
+	for tenant, size := range u.tenants {
+		u.stored.WithLabelValues(tenant).Set(float64(size))
+	}
+	return nil
+}
+
+func usageKey(buildID, typ string) string {
+	return buildID + "/" + typ
+}
+
+// reserve reserves the declared size of an upload until it expires. It
+// returns a ResourceExhausted error if the size would exceed the quota of the
+// tenant with the stored debuginfos and the other reservations. A debuginfo
+// replacing one of the same tenant only counts with the difference in size.
+func (u *Usage) reserve(uploadID, tenant, buildID string, typ debuginfopb.DebuginfoType, size int64, expires, now time.Time) error {
+	if u == nil {
+		return nil
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	key := usageKey(buildID, typ.String())
+	if err := u.admit(tenant, key, size, now); err != nil {
+		return err
+	}
+	u.reservations[uploadID] = &reservation{tenant: tenant, key: key, size: size, expires: expires}
+	return nil
+}
+
+// admit returns a ResourceExhausted error if storing a debuginfo of the size
+// would exceed the quota of the tenant. Expired reservations are dropped.
+func (u *Usage) admit(tenant, key string, size int64, now time.Time) error {
+	used := u.tenants[tenant]
+	for id, r := range u.reservations {
+		switch {
+		case now.After(r.expires):
+			delete(u.reservations, id)
+		case r.tenant == tenant:
+			used += r.size
+		}
+	}
+
+	limit := u.quotas.limit(tenant)
+	if limit <= 0 {
+		return nil
+	}
+	if e, ok := u.entries[key]; ok && e.Tenant == tenant {
+		used -= e.Size
+	}
+	if used+size > limit {
+		return status.Errorf(codes.ResourceExhausted, "debuginfo quota of tenant %q exceeded: %d bytes stored or reserved, %d bytes uploaded, %d bytes allowed", tenant, used, size, limit)
+	}
+	return nil
+}
+
+// finish accounts a finished upload of the size of the uploaded object to
+// the tenant it was reserved for and releases its reservation. Uploads
+// larger than their declared size are rejected. The tenant is only used if
+// the reservation was lost, eg. by a restart, in which case the quota is
+// checked again.
+func (u *Usage) finish(uploadID, tenant, buildID string, typ debuginfopb.DebuginfoType, size int64, now time.Time) error {
+	if u == nil {
+		return nil
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	key := usageKey(buildID, typ.String())
+	r, ok := u.reservations[uploadID]
+	delete(u.reservations, uploadID)
+	if ok {
+		if size > r.size {
+			return status.Errorf(codes.InvalidArgument, "uploaded debuginfo has %d bytes, but %d bytes were declared", size, r.size)
+		}
+		tenant = r.tenant
+	} else if err := u.admit(tenant, key, size, now); err != nil {
+		return err
+	}
+
+	u.record(tenant, key, buildID, typ, size, now)
+	return nil
+}
+
+// release drops the reservation of a failed upload.
+func (u *Usage) release(uploadID string) {
+	if u == nil {
+		return
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	delete(u.reservations, uploadID)
+}
+
+// record accounts a finished upload to the tenant. The mutex must be held.
+func (u *Usage) record(tenant, key, buildID string, typ debuginfopb.DebuginfoType, size int64, now time.Time) {
+	if e, ok := u.entries[key]; ok {
+		u.add(e.Tenant, -e.Size)
+	}
+	u.entries[key] = &UsageEntry{
+		BuildID:  buildID,
+		Type:     typ.String(),
+		Tenant:   tenant,
+		Size:     size,
+		Uploaded: now,
+	}
+	u.add(tenant, size)
+	u.dirty = true
+}
+
+func (u *Usage) add(tenant string, size int64) {
+	u.tenants[tenant] += size
+	u.stored.WithLabelValues(tenant).Set(float64(u.tenants[tenant]))
+}
+
+// touch marks the debuginfo as used.
+func (u *Usage) touch(buildID string, typ debuginfopb.DebuginfoType, now time.Time) {
+	if u == nil {
+		return
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	if e, ok := u.entries[usageKey(buildID, typ.String())]; ok {
+		e.LastUsed = now
+		u.dirty = true
+	}
+}
+
+// Largest returns up to n of the largest debuginfos, largest first.
+func (u *Usage) Largest(n int) []UsageEntry {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	res := make([]UsageEntry, 0, len(u.entries))
+	for _, e := range u.entries {
+		res = append(res, *e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Size != res[j].Size {
+			return res[i].Size > res[j].Size
+		}
+		return usageKey(res[i].BuildID, res[i].Type) < usageKey(res[j].BuildID, res[j].Type)
+	})
+	if n > 0 && len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
+
+// Flush persists the usage to the bucket if it changed.
+func (u *Usage) Flush(ctx context.Context) error {
+	if u == nil {
+		return nil
+	}
+
+	u.mtx.Lock()
+	if !u.dirty {
+		u.mtx.Unlock()
+		return nil
+	}
+	entries := make([]UsageEntry, 0, len(u.entries))
+	for _, e := range u.entries {
+		entries = append(entries, *e)
+	}
+	u.dirty = false
+	u.mtx.Unlock()
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := u.bucket.Upload(ctx, usageObjectPath, bytes.NewReader(b)); err != nil {
+		u.mtx.Lock()
+		u.dirty = true
+		u.mtx.Unlock()
+		return fmt.Errorf("write debuginfo usage: %w", err)
+	}
+	return nil
+}
+
+// Run persists the usage in the interval until the context is canceled, and
+// a last time before returning.
+func (u *Usage) Run(ctx context.Context, interval time.Duration) error {
+	if u == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return u.Flush(ctx)
+		case <-ticker.C:
+			if err := u.Flush(ctx); err != nil {
+				level.Warn(u.logger).Log("msg", "failed to persist debuginfo usage", "err", err)
+			}
+		}
+	}
+}
+
+// LargestHandler serves the largest stored debuginfos with the time they
+// were last used, and the bytes stored and quota per tenant. The "limit"
+// query parameter limits the number of debuginfos, 100 by default.
+func (u *Usage) LargestHandler() runtime.HandlerFunc {
+	type tenantUsage struct {
+		Tenant string `json:"tenant"`
+		Stored int64  `json:"stored"`
+		Quota  int64  `json:"quota,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		limit := 100
+		if v := r.URL.Query().Get("limit"); v != "" {
+			l, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+				return
+			}
+			limit = l
+		}
+
+		u.mtx.Lock()
+		tenants := make([]tenantUsage, 0, len(u.tenants))
+		for tenant, stored := range u.tenants {
+			tenants = append(tenants, tenantUsage{Tenant: tenant, Stored: stored, Quota: u.quotas.limit(tenant)})
+		}
+		u.mtx.Unlock()
+		sort.Slice(tenants, func(i, j int) bool { return tenants[i].Tenant < tenants[j].Tenant })
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(struct {
+			Tenants    []tenantUsage `json:"tenants"`
+			Debuginfos []UsageEntry  `json:"debuginfos"`
+		}{Tenants: tenants, Debuginfos: u.Largest(limit)}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// forget removes a deleted debuginfo from the usage.
+func (u *Usage) forget(buildID string, typ debuginfopb.DebuginfoType) {
+	if u == nil {
+		return
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	key := usageKey(buildID, typ.String())
+	if e, ok := u.entries[key]; ok {
+		u.add(e.Tenant, -e.Size)
+		delete(u.entries, key)
+		u.dirty = true
+	}
+}
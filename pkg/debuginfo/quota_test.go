@@ -0,0 +1,110 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debuginfo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+)
+
+func TestUsage(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	typ := debuginfopb.DebuginfoType_DEBUGINFO_TYPE_DEBUGINFO_UNSPECIFIED
+	now := time.Unix(100, 0).UTC()
+
+	u := NewUsage(log.NewNopLogger(), prometheus.NewRegistry(), bucket, Quotas{
+		Default: 100,
+		Tenants: map[string]int64{"big": 1000},
+	})
+	require.NoError(t, u.Load(ctx))
+
+	expires := now.Add(time.Hour)
+	require.NoError(t, u.reserve("1", "", "aaaa", typ, 60, expires, now))
+	require.NoError(t, u.finish("1", "", "aaaa", typ, 60, now))
+	// Replacing a debuginfo only counts the difference.
+	require.NoError(t, u.reserve("2", "", "aaaa", typ, 100, expires, now))
+	u.release("2")
+	require.NoError(t, u.reserve("3", "big", "bbbb", typ, 500, expires, now))
+	require.NoError(t, u.finish("3", "big", "bbbb", typ, 500, now))
+	u.touch("aaaa", typ, now.Add(time.Minute))
+	require.NoError(t, u.Flush(ctx))
+
+	// The usage survives a restart.
+	u = NewUsage(log.NewNopLogger(), prometheus.NewRegistry(), bucket, Quotas{})
+	require.NoError(t, u.Load(ctx))
+	require.Equal(t, []UsageEntry{
+		{BuildID: "bbbb", Type: typ.String(), Tenant: "big", Size: 500, Uploaded: now},
+		{BuildID: "aaaa", Type: typ.String(), Size: 60, Uploaded: now, LastUsed: now.Add(time.Minute)},
+	}, u.Largest(10))
+	require.Len(t, u.Largest(1), 1)
+}
+
+func TestUsageReservations(t *testing.T) {
+	typ := debuginfopb.DebuginfoType_DEBUGINFO_TYPE_DEBUGINFO_UNSPECIFIED
+	now := time.Unix(100, 0)
+	expires := now.Add(time.Hour)
+
+	u := NewUsage(log.NewNopLogger(), prometheus.NewRegistry(), objstore.NewInMemBucket(), Quotas{Default: 100})
+
+	// Uploads in progress count against the quota.
+	require.NoError(t, u.reserve("1", "", "aaaa", typ, 60, expires, now))
+	require.Equal(t, codes.ResourceExhausted, status.Code(u.reserve("2", "", "bbbb", typ, 41, expires, now)))
+	require.NoError(t, u.reserve("2", "", "bbbb", typ, 40, expires, now))
+
+	// Uploads larger than declared are rejected and release their
+	// reservation.
+	require.Equal(t, codes.InvalidArgument, status.Code(u.finish("1", "", "aaaa", typ, 61, now)))
+	require.NoError(t, u.reserve("3", "", "aaaa", typ, 60, expires, now))
+	require.NoError(t, u.finish("3", "", "aaaa", typ, 50, now))
+	require.Equal(t, int64(50), u.tenants[""])
+
+	// Failed and stale uploads release their reservation.
+	u.release("2")
+	require.NoError(t, u.reserve("4", "", "bbbb", typ, 50, expires, now))
+	require.NoError(t, u.reserve("5", "", "cccc", typ, 50, expires.Add(time.Second), expires.Add(time.Second)))
+
+	// Uploads whose reservation was lost are checked against the quota.
+	require.Equal(t, codes.ResourceExhausted, status.Code(u.finish("6", "", "dddd", typ, 1, now)))
+}
+
+func TestUsageTenant(t *testing.T) {
+	u := NewUsage(log.NewNopLogger(), prometheus.NewRegistry(), objstore.NewInMemBucket(), Quotas{})
+	tenant, err := u.tenant(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "", tenant)
+
+	u = NewUsage(log.NewNopLogger(), prometheus.NewRegistry(), objstore.NewInMemBucket(), Quotas{
+		Tokens: map[string]string{"secret": "a"},
+	})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	tenant, err = u.tenant(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a", tenant)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer other", "parca-tenant", "a"))
+	_, err = u.tenant(ctx)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,222 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debuginfo
+
+import (
+	"context"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thanos-io/objstore"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+)
+
+// sectionsReadAhead is the minimum number of bytes fetched by a ranged read,
+// so that reading the ELF headers one by one doesn't issue a request each,
+// and so that sections close to each other are fetched together.
+const sectionsReadAhead = 64 << 10
+
+// FetchDebuginfoSections writes the debuginfo to dst, fetching only the ELF
+// headers and the sections needed for symbolization with ranged reads when
+// the debuginfo was uploaded. The other sections are left as holes in dst,
+// so the offsets of the fetched ones are unchanged.
+func (f *Fetcher) FetchDebuginfoSections(ctx context.Context, dbginfo *debuginfopb.Debuginfo, dst *os.File) error {
+	if dbginfo.Source != debuginfopb.Debuginfo_SOURCE_UPLOAD {
+		rc, err := f.FetchDebuginfo(ctx, dbginfo)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		_, err = io.Copy(dst, rc)
+		return err
+	}
+
+	f.usage.touch(dbginfo.BuildId, dbginfo.Type, time.Now())
+	return copySections(ctx, f.bucket, objectPath(dbginfo.BuildId, dbginfo.Type), dst)
+}
+
+// copySections copies the ELF headers and the sections of the object needed
+// for symbolization to the same offsets in dst.
+func copySections(ctx context.Context, bucket objstore.BucketReader, name string, dst *os.File) error {
+	attrs, err := bucket.Attributes(ctx, name)
+	if err != nil {
+		return fmt.Errorf("attributes of %s: %w", name, err)
+	}
+
+	r := &objectReaderAt{ctx: ctx, bucket: bucket, name: name, size: attrs.Size}
+	e, err := elf.NewFile(r)
+	if err != nil {
+		return fmt.Errorf("read ELF headers: %w", err)
+	}
+	if len(e.Sections) == 0 {
+		// Without section headers we can't tell what's needed.
+		return copyRanges(ctx, bucket, name, dst, []objectRange{{off: 0, length: attrs.Size}})
+	}
+
+	ranges, err := headerRanges(r, e)
+	if err != nil {
+		return err
+	}
+	for _, s := range e.Sections {
+		if s.Type != elf.SHT_NOBITS && s.FileSize > 0 && symbolizationSection(s) {
+			ranges = append(ranges, objectRange{off: int64(s.Offset), length: int64(s.FileSize)})
+		}
+	}
+
+	if err := dst.Truncate(attrs.Size); err != nil {
+		return fmt.Errorf("truncate: %w", err)
+	}
+	return copyRanges(ctx, bucket, name, dst, ranges)
+}
+
+// symbolizationSection returns whether the section is read by any of the
+// liners or when checking the quality of the debuginfo.
+func symbolizationSection(s *elf.Section) bool {
+	switch s.Type {
+	case elf.SHT_SYMTAB, elf.SHT_DYNSYM, elf.SHT_STRTAB, elf.SHT_NOTE,
+		elf.SHT_GNU_VERSYM, elf.SHT_GNU_VERNEED, elf.SHT_GNU_VERDEF:
+		return true
+	}
+	switch s.Name {
+	case ".gosymtab", ".gopclntab", ".data.rel.ro.gosymtab", ".data.rel.ro.gopclntab", ".rela.plt":
+		return true
+	}
+	return strings.HasPrefix(s.Name, ".debug_") || strings.HasPrefix(s.Name, ".zdebug_")
+}
+
+type objectRange struct {
+	off    int64
+	length int64
+}
+
+// headerRanges returns the ranges of the ELF file header, the program header
+// table and the section header table.
+func headerRanges(r io.ReaderAt, e *elf.File) ([]objectRange, error) {
+	var (
+		header                      [64]byte
+		headerSize                  int64
+		phoff, shoff                int64
+		phentsize, shentsize        int64
+		phoffPos, shoffPos, sizePos int
+	)
+	switch e.Class {
+	case elf.ELFCLASS64:
+		headerSize, phoffPos, shoffPos, sizePos = 64, 0x20, 0x28, 0x36
+	case elf.ELFCLASS32:
+		headerSize, phoffPos, shoffPos, sizePos = 52, 0x1c, 0x20, 0x2a
+	default:
+		return nil, fmt.Errorf("unsupported ELF class %s", e.Class)
+	}
+	if _, err := r.ReadAt(header[:headerSize], 0); err != nil {
+		return nil, fmt.Errorf("read ELF header: %w", err)
+	}
+
+	bo := e.ByteOrder
+	if e.Class == elf.ELFCLASS64 {
+		phoff = int64(bo.Uint64(header[phoffPos:]))
+		shoff = int64(bo.Uint64(header[shoffPos:]))
+	} else {
+		phoff = int64(bo.Uint32(header[phoffPos:]))
+		shoff = int64(bo.Uint32(header[shoffPos:]))
+	}
+	// e_phentsize is followed by e_phnum and e_shentsize.
+	phentsize = int64(bo.Uint16(header[sizePos:]))
+	shentsize = int64(bo.Uint16(header[sizePos+4:]))
+
+	return []objectRange{
+		{off: 0, length: headerSize},
+		{off: phoff, length: phentsize * int64(len(e.Progs))},
+		{off: shoff, length: shentsize * int64(len(e.Sections))},
+	}, nil
+}
+
+// copyRanges copies the ranges of the object to the same offsets in dst,
+// merging the ranges closer than sectionsReadAhead to each other.
+func copyRanges(ctx context.Context, bucket objstore.BucketReader, name string, dst io.WriterAt, ranges []objectRange) error {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].off < ranges[j].off
+	})
+
+	merged := make([]objectRange, 0, len(ranges))
+	for _, rng := range ranges {
+		if rng.length <= 0 {
+			continue
+		}
+		if n := len(merged); n > 0 && rng.off <= merged[n-1].off+merged[n-1].length+sectionsReadAhead {
+			last := &merged[n-1]
+			last.length = max(last.length, rng.off+rng.length-last.off)
+			continue
+		}
+		merged = append(merged, rng)
+	}
+
+	for _, rng := range merged {
+		rc, err := bucket.GetRange(ctx, name, rng.off, rng.length)
+		if err != nil {
+			return fmt.Errorf("get range %d-%d of %s: %w", rng.off, rng.off+rng.length, name, err)
+		}
+		_, err = io.Copy(io.NewOffsetWriter(dst, rng.off), rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("copy range %d-%d of %s: %w", rng.off, rng.off+rng.length, name, err)
+		}
+	}
+	return nil
+}
+
+// objectReaderAt reads an object in the bucket with ranged reads of at least
+// sectionsReadAhead bytes, keeping the last one.
+type objectReaderAt struct {
+	ctx    context.Context
+	bucket objstore.BucketReader
+	name   string
+	size   int64
+
+	buf []byte
+	off int64
+}
+
+func (r *objectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	if off < r.off || off+int64(len(p)) > r.off+int64(len(r.buf)) {
+		length := min(max(int64(len(p)), sectionsReadAhead), r.size-off)
+		rc, err := r.bucket.GetRange(r.ctx, r.name, off, length)
+		if err != nil {
+			return 0, err
+		}
+		defer rc.Close()
+
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(rc, buf); err != nil {
+			return 0, err
+		}
+		r.buf, r.off = buf, off
+	}
+
+	n := copy(p, r.buf[off-r.off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
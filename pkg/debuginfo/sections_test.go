@@ -0,0 +1,71 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debuginfo
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+)
+
+func TestFetchDebuginfoSections(t *testing.T) {
+	ctx := context.Background()
+	buildID := "2d6912fd3dd64542f6f6294f4bf9cb6c265b3085"
+
+	content, err := os.ReadFile("../symbolizer/testdata/" + buildID + "/debuginfo")
+	require.NoError(t, err)
+
+	bucket := objstore.NewInMemBucket()
+	require.NoError(t, bucket.Upload(ctx, objectPath(buildID, debuginfopb.DebuginfoType_DEBUGINFO_TYPE_DEBUGINFO_UNSPECIFIED), bytes.NewReader(content)))
+
+	dst, err := os.CreateTemp(t.TempDir(), "debuginfo-*")
+	require.NoError(t, err)
+	defer dst.Close()
+
+	f := NewFetcher(NopDebuginfodClients{}, bucket, nil)
+	require.NoError(t, f.FetchDebuginfoSections(ctx, &debuginfopb.Debuginfo{
+		BuildId: buildID,
+		Source:  debuginfopb.Debuginfo_SOURCE_UPLOAD,
+	}, dst))
+
+	orig, err := elf.NewFile(bytes.NewReader(content))
+	require.NoError(t, err)
+	fetched, err := elf.Open(dst.Name())
+	require.NoError(t, err)
+	defer fetched.Close()
+
+	require.Equal(t, orig.Progs[0].ProgHeader, fetched.Progs[0].ProgHeader)
+	for _, name := range []string{".gopclntab", ".zdebug_info", ".zdebug_line", ".symtab", ".strtab"} {
+		want, err := orig.Section(name).Data()
+		require.NoError(t, err)
+		got, err := fetched.Section(name).Data()
+		require.NoError(t, err)
+		require.Equal(t, want, got, name)
+	}
+
+	// The code isn't needed for symbolization.
+	text, err := fetched.Section(".text").Data()
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, len(text)), text)
+
+	_, err = fetched.DWARF()
+	require.NoError(t, err)
+}
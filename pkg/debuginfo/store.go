@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/google/uuid"
 	"github.com/thanos-io/objstore"
 	"github.com/thanos-io/objstore/client"
@@ -79,6 +80,8 @@ type Store struct {
 	maxUploadDuration time.Duration
 	maxUploadSize     int64
 
+	usage *Usage
+
 	timeNow func() time.Time
 }
 
@@ -101,6 +104,7 @@ func NewStore(
 	signedUpload SignedUpload,
 	maxUploadDuration time.Duration,
 	maxUploadSize int64,
+	usage *Usage,
 ) (*Store, error) {
 	return &Store{
 		tracer:            tracer,
@@ -111,6 +115,7 @@ func NewStore(
 		signedUpload:      signedUpload,
 		maxUploadDuration: maxUploadDuration,
 		maxUploadSize:     maxUploadSize,
+		usage:             usage,
 		timeNow:           time.Now,
 	}, nil
 }
@@ -286,12 +291,24 @@ func (s *Store) InitiateUpload(ctx context.Context, req *debuginfopb.InitiateUpl
 		return nil, status.Errorf(codes.InvalidArgument, "upload size %d exceeds maximum allowed size %d", req.Size, s.maxUploadSize)
 	}
 
+	tenant, err := s.usage.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	uploadID := uuid.New().String()
 	uploadStarted := s.timeNow()
 	uploadExpiry := uploadStarted.Add(s.maxUploadDuration)
 
+	// The declared size is reserved until the upload is finished or is
+	// stale, so that concurrent uploads can't exceed the quota together.
+	if err := s.usage.reserve(uploadID, tenant, req.BuildId, req.Type, req.Size, uploadExpiry.Add(2*time.Minute), uploadStarted); err != nil {
+		return nil, err
+	}
+
 	if !s.signedUpload.Enabled {
 		if err := s.metadata.MarkAsUploading(ctx, req.BuildId, uploadID, req.Hash, req.Type, timestamppb.New(uploadStarted)); err != nil {
+			s.usage.release(uploadID)
 			return nil, fmt.Errorf("mark debuginfo upload as uploading via gRPC: %w", err)
 		}
 
@@ -307,10 +324,12 @@ func (s *Store) InitiateUpload(ctx context.Context, req *debuginfopb.InitiateUpl
 
 	signedURL, err := s.signedUpload.Client.SignedPUT(ctx, objectPath(req.BuildId, req.Type), req.Size, uploadExpiry)
 	if err != nil {
+		s.usage.release(uploadID)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	if err := s.metadata.MarkAsUploading(ctx, req.BuildId, uploadID, req.Hash, req.Type, timestamppb.New(uploadStarted)); err != nil {
+		s.usage.release(uploadID)
 		return nil, fmt.Errorf("mark debuginfo upload as uploading via signed URL: %w", err)
 	}
 
@@ -335,6 +354,29 @@ func (s *Store) MarkUploadFinished(ctx context.Context, req *debuginfopb.MarkUpl
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	// The size of the uploaded object is accounted instead of the declared
+	// one, uploads exceeding it are deleted so they can be retried.
+	if s.usage != nil {
+		tenant, err := s.usage.tenant(ctx)
+		if err != nil {
+			return nil, err
+		}
+		attrs, err := s.bucket.Attributes(ctx, objectPath(buildID, req.Type))
+		if err != nil {
+			s.usage.release(req.UploadId)
+			if s.bucket.IsObjNotFoundErr(err) {
+				return nil, status.Error(codes.FailedPrecondition, "no debuginfo was uploaded for build id")
+			}
+			return nil, status.Errorf(codes.Internal, "get size of uploaded debuginfo: %v", err)
+		}
+		if err := s.usage.finish(req.UploadId, tenant, buildID, req.Type, attrs.Size, s.timeNow()); err != nil {
+			if err := s.bucket.Delete(ctx, objectPath(buildID, req.Type)); err != nil {
+				level.Warn(s.logger).Log("msg", "failed to delete rejected debuginfo", "build_id", buildID, "err", err)
+			}
+			return nil, err
+		}
+	}
+
 	err := s.metadata.MarkAsUploaded(ctx, buildID, req.UploadId, req.Type, timestamppb.New(s.timeNow()))
 	if errors.Is(err, ErrDebuginfoNotFound) {
 		return nil, status.Error(codes.NotFound, "no debuginfo metadata found for build id")
@@ -375,6 +417,7 @@ func (s *Store) Upload(stream debuginfopb.DebuginfoService_UploadServer) error {
 	span.SetAttributes(attribute.String("upload_id", uploadID))
 
 	if err := s.upload(ctx, buildID, uploadID, typ, r); err != nil {
+		s.usage.release(uploadID)
 		return err
 	}
 
@@ -92,6 +92,7 @@ func TestStore(t *testing.T) {
 		},
 		time.Minute*15,
 		1024*1024*1024,
+		nil,
 	)
 	require.NoError(t, err)
 
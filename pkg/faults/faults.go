@@ -0,0 +1,36 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package faults provides fault injection hooks for testing error and
+// recovery paths of the storage. The hooks are only active when built with
+// the faultinjection build tag, otherwise Inject is a no-op.
+package faults
+
+import "errors"
+
+// Point identifies a place in the code where faults can be injected.
+type Point string
+
+const (
+	// Ingest is checked before a record is inserted into a table.
+	Ingest Point = "ingest"
+	// DebuginfoMetadataFetch is checked before debuginfo metadata is read
+	// from object storage.
+	DebuginfoMetadataFetch Point = "debuginfo_metadata_fetch"
+	// DebuginfoMetadataWrite is checked before debuginfo metadata is written
+	// to object storage.
+	DebuginfoMetadataWrite Point = "debuginfo_metadata_write"
+)
+
+// ErrInjected is the default error returned by injected failures.
+var ErrInjected = errors.New("injected fault")
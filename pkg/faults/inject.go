@@ -0,0 +1,107 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build faultinjection
+
+package faults
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Enabled reports whether fault injection is compiled in.
+const Enabled = true
+
+type fault struct {
+	err   error
+	delay time.Duration
+	// times is the number of remaining injections, negative means forever.
+	times int
+}
+
+var (
+	mtx    sync.Mutex
+	faults = map[Point][]*fault{}
+	hits   = map[Point]int{}
+)
+
+// Inject is called at a fault injection point. It applies the next fault
+// registered for the point, which may delay and fail the operation.
+func Inject(ctx context.Context, p Point) error {
+	mtx.Lock()
+	hits[p]++
+	var f *fault
+	if fs := faults[p]; len(fs) > 0 {
+		f = fs[0]
+		if f.times > 0 {
+			f.times--
+			if f.times == 0 {
+				faults[p] = fs[1:]
+			}
+		}
+	}
+	mtx.Unlock()
+
+	if f == nil {
+		return nil
+	}
+	if f.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(f.delay):
+		}
+	}
+	return f.err
+}
+
+// FailNext makes the next n operations at the point fail with err, or
+// ErrInjected if err is nil. A negative n fails all operations until Reset.
+func FailNext(p Point, n int, err error) {
+	if err == nil {
+		err = ErrInjected
+	}
+	add(p, &fault{err: err, times: n})
+}
+
+// Delay delays the next n operations at the point. A negative n delays all
+// operations until Reset.
+func Delay(p Point, n int, d time.Duration) {
+	add(p, &fault{delay: d, times: n})
+}
+
+func add(p Point, f *fault) {
+	if f.times == 0 {
+		return
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	faults[p] = append(faults[p], f)
+}
+
+// Hits returns how often the point was reached since the last Reset.
+func Hits(p Point) int {
+	mtx.Lock()
+	defer mtx.Unlock()
+	return hits[p]
+}
+
+// Reset removes all registered faults and hit counts.
+func Reset() {
+	mtx.Lock()
+	defer mtx.Unlock()
+	faults = map[Point][]*fault{}
+	hits = map[Point]int{}
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build faultinjection
+
+package faults
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInject(t *testing.T) {
+	Setup(t)
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	FailNext(Ingest, 2, errBoom)
+	FailNext(Ingest, 1, nil)
+
+	require.ErrorIs(t, Inject(ctx, Ingest), errBoom)
+	require.ErrorIs(t, Inject(ctx, Ingest), errBoom)
+	require.ErrorIs(t, Inject(ctx, Ingest), ErrInjected)
+	require.NoError(t, Inject(ctx, Ingest))
+	require.NoError(t, Inject(ctx, DebuginfoMetadataFetch))
+	require.Equal(t, 4, Hits(Ingest))
+}
+
+func TestInjectDelay(t *testing.T) {
+	Setup(t)
+
+	Delay(DebuginfoMetadataFetch, -1, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, Inject(ctx, DebuginfoMetadataFetch), context.DeadlineExceeded)
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build faultinjection
+
+package faults
+
+import "testing"
+
+// Setup resets all faults before and after the test. Tests using fault
+// injection must not run in parallel, as the faults are global.
+func Setup(t testing.TB) {
+	t.Helper()
+	Reset()
+	t.Cleanup(Reset)
+}
+
+// Invariant is a check of the state of a component that has to hold no
+// matter which faults were injected.
+type Invariant func() error
+
+// CheckInvariants fails the test if any of the invariants does not hold.
+func CheckInvariants(t testing.TB, invariants ...Invariant) {
+	t.Helper()
+	for _, inv := range invariants {
+		if err := inv(); err != nil {
+			t.Fatalf("invariant violated: %v", err)
+		}
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/array"
 	"github.com/go-kit/log"
+
+	"github.com/parca-dev/parca/pkg/faults"
 )
 
 type Ingester interface {
@@ -49,6 +51,10 @@ func (ing TableIngester) Ingest(ctx context.Context, record arrow.Record) error
 		return nil
 	}
 
+	if err := faults.Inject(ctx, faults.Ingest); err != nil {
+		return err
+	}
+
 	for _, col := range record.Columns() {
 		switch col := col.(type) {
 		case *array.Dictionary:
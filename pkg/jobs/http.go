@@ -0,0 +1,92 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+)
+
+// Path is the HTTP path of the jobs endpoints, relative to the API root.
+const Path = "/jobs"
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+}
+
+// SubmitHandler starts a job. The request body is a JSON object with the
+// kind of the job and its kind specific spec.
+func (m *Manager) SubmitHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var req struct {
+			Kind string          `json:"kind"`
+			Spec json.RawMessage `json:"spec"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		j, err := m.Submit(r.Context(), req.Kind, req.Spec)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, j)
+	}
+}
+
+// ListHandler lists the jobs, of a single kind if the "kind" parameter is
+// given.
+func (m *Manager) ListHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		writeJSON(w, http.StatusOK, struct {
+			Jobs []*Job `json:"jobs"`
+		}{m.List(r.URL.Query().Get("kind"))})
+	}
+}
+
+// GetHandler returns the state and progress of the job given by the "id"
+// path parameter.
+func (m *Manager) GetHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+		j, ok := m.Get(params["id"])
+		if !ok {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, j)
+	}
+}
+
+// CancelHandler cancels the job given by the "id" path parameter.
+func (m *Manager) CancelHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+		j, err := m.Cancel(params["id"])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, j)
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jobs runs asynchronous server-side operations, like exports or
+// compactions, independently of the requests that started them. Jobs are
+// persisted in the object storage and resumed from their last checkpoint when
+// the server restarts.
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const manifestName = "job.json"
+
+type State string
+
+const (
+	StateRunning  State = "running"
+	StateDone     State = "done"
+	StateFailed   State = "failed"
+	StateCanceled State = "canceled"
+)
+
+// Job is the state of a job. It is persisted after every checkpoint.
+type Job struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Spec is the kind specific description of the work.
+	Spec  json.RawMessage `json:"spec"`
+	State State           `json:"state"`
+	Error string          `json:"error,omitempty"`
+	// Completed is the amount of work done out of Total, in units defined by
+	// the kind of the job.
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+	// Result is the kind specific result of the work done so far.
+	Result  json.RawMessage `json:"result,omitempty"`
+	Created time.Time       `json:"created"`
+	Updated time.Time       `json:"updated"`
+}
+
+// Checkpoint records the progress of a job. A resumed job starts at the last
+// recorded checkpoint.
+type Checkpoint func(completed int, result json.RawMessage) error
+
+// Kind implements one kind of job.
+type Kind interface {
+	// Plan validates the spec and returns the total amount of work.
+	Plan(spec json.RawMessage) (int, error)
+	// Run does the work of the job, starting after the completed amount of
+	// work, with the result of the last checkpoint.
+	Run(ctx context.Context, id string, spec json.RawMessage, completed int, result json.RawMessage, checkpoint Checkpoint) error
+}
+
+type job struct {
+	mtx    sync.Mutex
+	job    Job
+	cancel context.CancelFunc
+}
+
+func (j *job) snapshot() *Job {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	c := j.job
+	return &c
+}
+
+// Manager runs jobs and keeps track of their state.
+type Manager struct {
+	logger log.Logger
+	bucket objstore.Bucket
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mtx   sync.Mutex
+	kinds map[string]Kind
+	jobs  map[string]*job
+
+	jobsTotal *prometheus.CounterVec
+	running   *prometheus.GaugeVec
+}
+
+func NewManager(logger log.Logger, reg prometheus.Registerer, bucket objstore.Bucket) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		logger: logger,
+		bucket: bucket,
+		ctx:    ctx,
+		cancel: cancel,
+		kinds:  map[string]Kind{},
+		jobs:   map[string]*job{},
+		jobsTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_jobs_finished_total",
+			Help: "Total number of finished jobs by kind and final state.",
+		}, []string{"kind", "state"}),
+		running: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "parca_jobs_running",
+			Help: "Number of running jobs by kind.",
+		}, []string{"kind"}),
+	}
+}
+
+// Register makes a kind of job available. Kinds must be registered before
+// the manager is run.
+func (m *Manager) Register(name string, k Kind) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.kinds[name] = k
+}
+
+// Run resumes the jobs that were interrupted by a restart and blocks until
+// the context is canceled. Running jobs are interrupted before Run returns,
+// and resumed on the next start.
+func (m *Manager) Run(ctx context.Context) error {
+	if err := m.resume(ctx); err != nil {
+		level.Warn(m.logger).Log("msg", "failed to resume jobs", "err", err)
+	}
+
+	<-ctx.Done()
+	m.cancel()
+	m.wg.Wait()
+	return nil
+}
+
+func (m *Manager) resume(ctx context.Context) error {
+	return m.bucket.Iter(ctx, "", func(dir string) error {
+		id := strings.TrimSuffix(dir, objstore.DirDelim)
+		j, err := m.readManifest(ctx, id)
+		if err != nil {
+			level.Warn(m.logger).Log("msg", "failed to read job manifest", "id", id, "err", err)
+			return nil
+		}
+
+		m.mtx.Lock()
+		m.jobs[j.ID] = &job{job: *j}
+		_, known := m.kinds[j.Kind]
+		m.mtx.Unlock()
+
+		if j.State != StateRunning {
+			return nil
+		}
+		if !known {
+			level.Warn(m.logger).Log("msg", "not resuming job of unknown kind", "id", j.ID, "kind", j.Kind)
+			return nil
+		}
+
+		level.Info(m.logger).Log("msg", "resuming job", "id", j.ID, "kind", j.Kind, "completed", j.Completed, "total", j.Total)
+		m.start(j.ID)
+		return nil
+	})
+}
+
+func (m *Manager) readManifest(ctx context.Context, id string) (*Job, error) {
+	r, err := m.bucket.Get(ctx, path.Join(id, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	j := &Job{}
+	if err := json.NewDecoder(r).Decode(j); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	return j, nil
+}
+
+func (m *Manager) writeManifest(ctx context.Context, j *Job) error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return m.bucket.Upload(ctx, path.Join(j.ID, manifestName), bytes.NewReader(b))
+}
+
+// Submit validates the spec with the kind of the job and starts it.
+func (m *Manager) Submit(ctx context.Context, kind string, spec json.RawMessage) (*Job, error) {
+	m.mtx.Lock()
+	k, ok := m.kinds[kind]
+	m.mtx.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown job kind %q", kind)
+	}
+
+	total, err := k.Plan(spec)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s job: %v", kind, err)
+	}
+
+	now := time.Now()
+	j := Job{
+		ID:      uuid.New().String(),
+		Kind:    kind,
+		Spec:    spec,
+		State:   StateRunning,
+		Total:   total,
+		Created: now,
+		Updated: now,
+	}
+	if err := m.writeManifest(ctx, &j); err != nil {
+		return nil, fmt.Errorf("write job manifest: %w", err)
+	}
+
+	m.mtx.Lock()
+	m.jobs[j.ID] = &job{job: j}
+	m.mtx.Unlock()
+
+	m.start(j.ID)
+	return &j, nil
+}
+
+func (m *Manager) start(id string) {
+	m.mtx.Lock()
+	j := m.jobs[id]
+	k := m.kinds[j.job.Kind]
+	m.mtx.Unlock()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	j.mtx.Lock()
+	j.cancel = cancel
+	snapshot := j.job
+	j.mtx.Unlock()
+
+	running := m.running.WithLabelValues(snapshot.Kind)
+	running.Inc()
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer running.Dec()
+		defer cancel()
+
+		checkpoint := func(completed int, result json.RawMessage) error {
+			j.mtx.Lock()
+			j.job.Completed = completed
+			j.job.Result = result
+			j.job.Updated = time.Now()
+			c := j.job
+			j.mtx.Unlock()
+
+			return m.writeManifest(ctx, &c)
+		}
+
+		err := k.Run(ctx, snapshot.ID, snapshot.Spec, snapshot.Completed, snapshot.Result, checkpoint)
+
+		j.mtx.Lock()
+		switch {
+		case j.job.State == StateCanceled:
+			// Canceled by a user.
+		case err == nil:
+			j.job.State = StateDone
+		case errors.Is(err, context.Canceled):
+			// Shutting down, the job is resumed on the next start.
+			j.mtx.Unlock()
+			return
+		default:
+			level.Error(m.logger).Log("msg", "job failed", "id", snapshot.ID, "kind", snapshot.Kind, "err", err)
+			j.job.State = StateFailed
+			j.job.Error = err.Error()
+		}
+		j.job.Updated = time.Now()
+		c := j.job
+		j.mtx.Unlock()
+
+		m.jobsTotal.WithLabelValues(c.Kind, string(c.State)).Inc()
+		// The job context may be canceled already, the final state is written
+		// as long as the manager is running.
+		if err := m.writeManifest(m.ctx, &c); err != nil {
+			level.Error(m.logger).Log("msg", "failed to write job manifest", "id", c.ID, "err", err)
+		}
+	}()
+}
+
+// Cancel stops a running job. Canceled jobs are not resumed.
+func (m *Manager) Cancel(id string) (*Job, error) {
+	m.mtx.Lock()
+	j, ok := m.jobs[id]
+	m.mtx.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "job %q not found", id)
+	}
+
+	j.mtx.Lock()
+	if j.job.State != StateRunning {
+		j.mtx.Unlock()
+		return nil, status.Errorf(codes.FailedPrecondition, "job %q is %s", id, j.job.State)
+	}
+	j.job.State = StateCanceled
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.mtx.Unlock()
+
+	return j.snapshot(), nil
+}
+
+// Get returns the job with the given ID.
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mtx.Lock()
+	j, ok := m.jobs[id]
+	m.mtx.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return j.snapshot(), true
+}
+
+// List returns the jobs of the given kind, or all jobs if the kind is empty,
+// the most recent first.
+func (m *Manager) List(kind string) []*Job {
+	m.mtx.Lock()
+	jobs := make([]*job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	m.mtx.Unlock()
+
+	res := make([]*Job, 0, len(jobs))
+	for _, j := range jobs {
+		if s := j.snapshot(); kind == "" || s.Kind == kind {
+			res = append(res, s)
+		}
+	}
+	sort.Slice(res, func(i, k int) bool {
+		return res[i].Created.After(res[k].Created)
+	})
+	return res
+}
+
+type oneShot func(ctx context.Context) error
+
+// OneShot makes a job kind of a function that does all its work at once and
+// takes no spec. An interrupted job runs the function again.
+func OneShot(fn func(ctx context.Context) error) Kind {
+	return oneShot(fn)
+}
+
+func (f oneShot) Plan(json.RawMessage) (int, error) {
+	return 1, nil
+}
+
+func (f oneShot) Run(ctx context.Context, _ string, _ json.RawMessage, _ int, _ json.RawMessage, checkpoint Checkpoint) error {
+	if err := f(ctx); err != nil {
+		return err
+	}
+	return checkpoint(1, nil)
+}
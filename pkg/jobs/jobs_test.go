@@ -0,0 +1,131 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// countKind counts up to the number given as spec, one step at a time.
+type countKind struct {
+	// block makes every step wait until the context is canceled.
+	block bool
+}
+
+func (k countKind) Plan(spec json.RawMessage) (int, error) {
+	var n int
+	return n, json.Unmarshal(spec, &n)
+}
+
+func (k countKind) Run(ctx context.Context, _ string, spec json.RawMessage, completed int, _ json.RawMessage, checkpoint Checkpoint) error {
+	n, _ := k.Plan(spec)
+	for i := completed; i < n; i++ {
+		if k.block {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if err := checkpoint(i+1, json.RawMessage(`{}`)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitForState(t *testing.T, m *Manager, id string, state State) *Job {
+	t.Helper()
+
+	var j *Job
+	require.Eventually(t, func() bool {
+		var ok bool
+		j, ok = m.Get(id)
+		return ok && j.State == state
+	}, 5*time.Second, 10*time.Millisecond)
+	return j
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(log.NewNopLogger(), prometheus.NewRegistry(), objstore.NewInMemBucket())
+	m.Register("count", countKind{})
+	m.Register("block", countKind{block: true})
+
+	_, err := m.Submit(ctx, "unknown", nil)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = m.Submit(ctx, "count", json.RawMessage(`"three"`))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	j, err := m.Submit(ctx, "count", json.RawMessage(`3`))
+	require.NoError(t, err)
+	require.Equal(t, 3, j.Total)
+	j = waitForState(t, m, j.ID, StateDone)
+	require.Equal(t, 3, j.Completed)
+
+	blocked, err := m.Submit(ctx, "block", json.RawMessage(`1`))
+	require.NoError(t, err)
+	_, err = m.Cancel(blocked.ID)
+	require.NoError(t, err)
+	waitForState(t, m, blocked.ID, StateCanceled)
+	_, err = m.Cancel(blocked.ID)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	require.Len(t, m.List(""), 2)
+	require.Len(t, m.List("count"), 1)
+}
+
+func TestManagerResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bucket := objstore.NewInMemBucket()
+
+	for _, j := range []Job{
+		{ID: "interrupted", Kind: "count", Spec: json.RawMessage(`3`), State: StateRunning, Completed: 1, Total: 3},
+		{ID: "failed", Kind: "count", Spec: json.RawMessage(`3`), State: StateFailed, Total: 3},
+	} {
+		b, err := json.Marshal(j)
+		require.NoError(t, err)
+		require.NoError(t, bucket.Upload(ctx, j.ID+"/"+manifestName, bytes.NewReader(b)))
+	}
+
+	m := NewManager(log.NewNopLogger(), prometheus.NewRegistry(), bucket)
+	m.Register("count", countKind{})
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	j := waitForState(t, m, "interrupted", StateDone)
+	require.Equal(t, 3, j.Completed)
+
+	j, ok := m.Get("failed")
+	require.True(t, ok)
+	require.Equal(t, StateFailed, j.State)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	// The final state is persisted.
+	m = NewManager(log.NewNopLogger(), prometheus.NewRegistry(), bucket)
+	require.NoError(t, m.resume(context.Background()))
+	j, ok = m.Get("interrupted")
+	require.True(t, ok)
+	require.Equal(t, StateDone, j.State)
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prometheus/common/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+const (
+	// MaxLabelNameLength is the maximum length of a label name in bytes.
+	MaxLabelNameLength = 1024
+	// MaxLabelValueLength is the maximum length of a label value in bytes.
+	MaxLabelValueLength = 4096
+
+	// reservedLabelPrefix is reserved for labels used internally, like the
+	// profile name. Relabeling drops all other labels with this prefix
+	// before a scraped profile is written.
+	reservedLabelPrefix = "__"
+)
+
+var (
+	ErrInvalidLabelName   = errors.New("invalid label name")
+	ErrDuplicateLabelName = errors.New("duplicate label name")
+	ErrReservedLabelName  = errors.New("label name uses the reserved prefix " + reservedLabelPrefix)
+	ErrLabelNameTooLong   = errors.New("label name is too long")
+	ErrInvalidLabelValue  = errors.New("label value is not valid UTF-8")
+	ErrLabelValueTooLong  = errors.New("label value is too long")
+)
+
+// LabelError is returned when the labels of a written series are invalid.
+type LabelError struct {
+	// Name of the offending label.
+	Name string
+	// Err is one of the ErrInvalidLabelName, ErrDuplicateLabelName,
+	// ErrReservedLabelName, ErrLabelNameTooLong, ErrInvalidLabelValue or
+	// ErrLabelValueTooLong errors.
+	Err error
+}
+
+func (e *LabelError) Error() string {
+	return fmt.Sprintf("%v: %q", e.Err, truncate(e.Name, 128))
+}
+
+func (e *LabelError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus makes the error an InvalidArgument error when returned from a
+// gRPC handler.
+func (e *LabelError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// ValidateLabelSet validates the labels of a written series. The labels are
+// stored as label columns, which makes their order irrelevant, so series
+// that only differ in the order of their labels are the same series.
+func ValidateLabelSet(ls *profilestorepb.LabelSet) error {
+	seen := make(map[string]struct{}, len(ls.GetLabels()))
+	for _, l := range ls.GetLabels() {
+		if len(l.Name) > MaxLabelNameLength {
+			return &LabelError{Name: l.Name, Err: ErrLabelNameTooLong}
+		}
+		if l.Name != model.MetricNameLabel {
+			if !model.LabelName(l.Name).IsValid() {
+				return &LabelError{Name: l.Name, Err: ErrInvalidLabelName}
+			}
+			if strings.HasPrefix(l.Name, reservedLabelPrefix) {
+				return &LabelError{Name: l.Name, Err: ErrReservedLabelName}
+			}
+		}
+		if _, ok := seen[l.Name]; ok {
+			return &LabelError{Name: l.Name, Err: ErrDuplicateLabelName}
+		}
+		seen[l.Name] = struct{}{}
+
+		if len(l.Value) > MaxLabelValueLength {
+			return &LabelError{Name: l.Name, Err: ErrLabelValueTooLong}
+		}
+		if !utf8.ValidString(l.Value) {
+			return &LabelError{Name: l.Name, Err: ErrInvalidLabelValue}
+		}
+	}
+
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -19,7 +19,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"slices"
 	"sort"
 	"strings"
@@ -127,6 +126,18 @@ func WriteRawRequestToArrowRecord(
 		return nil, err
 	}
 
+	return NormalizedWriteRawRequestToArrowRecord(ctx, mem, normalizedRequest, schema)
+}
+
+// NormalizedWriteRawRequestToArrowRecord converts an already normalized
+// request to a sorted arrow record. It returns nil if the request contains no
+// samples.
+func NormalizedWriteRawRequestToArrowRecord(
+	ctx context.Context,
+	mem memory.Allocator,
+	normalizedRequest NormalizedWriteRawRequest,
+	schema *dynparquet.Schema,
+) (arrow.Record, error) {
 	ps, err := schema.GetDynamicParquetSchema(map[string][]string{
 		profile.ColumnLabels: normalizedRequest.AllLabelNames,
 	})
@@ -491,22 +502,18 @@ func NormalizeWriteRawRequest(ctx context.Context, req *profilestorepb.WriteRawR
 
 	series := make([]Series, 0, len(req.Series))
 	for _, rawSeries := range req.Series {
-		ls := make(map[string]string, len(rawSeries.Labels.Labels))
+		if err := ValidateLabelSet(rawSeries.Labels); err != nil {
+			return NormalizedWriteRawRequest{}, err
+		}
+
+		ls := make(map[string]string, len(rawSeries.Labels.GetLabels()))
 		name := ""
-		for _, l := range rawSeries.Labels.Labels {
+		for _, l := range rawSeries.Labels.GetLabels() {
 			if l.Name == model.MetricNameLabel {
 				name = l.Value
 				continue
 			}
 
-			if valid := model.LabelName(l.Name).IsValid(); !valid {
-				return NormalizedWriteRawRequest{}, status.Errorf(codes.InvalidArgument, "invalid label name: %v", l.Name)
-			}
-
-			if _, ok := ls[l.Name]; ok {
-				return NormalizedWriteRawRequest{}, status.Errorf(codes.InvalidArgument, "duplicate label name: %v", l.Name)
-			}
-
 			ls[l.Name] = l.Value
 			allLabelNames[l.Name] = struct{}{}
 		}
@@ -520,7 +527,10 @@ func NormalizeWriteRawRequest(ctx context.Context, req *profilestorepb.WriteRawR
 			if len(sample.RawProfile) >= 2 && sample.RawProfile[0] == 0x1f && sample.RawProfile[1] == 0x8b {
 				gz, err := gzip.NewReader(bytes.NewBuffer(sample.RawProfile))
 				if err == nil {
-					sample.RawProfile, err = io.ReadAll(gz)
+					sample.RawProfile, err = readAllLimited(gz, MaxDecompressedProfileSize)
+				}
+				if errors.Is(err, ErrProfileTooLarge) {
+					return NormalizedWriteRawRequest{}, status.Errorf(codes.InvalidArgument, "decompressing profile: %v", err)
 				}
 				if err != nil {
 					return NormalizedWriteRawRequest{}, fmt.Errorf("decompressing profile: %v", err)
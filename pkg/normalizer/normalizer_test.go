@@ -14,6 +14,7 @@
 package normalizer
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"io"
@@ -21,8 +22,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pprofpb "github.com/parca-dev/parca/gen/proto/go/google/pprof"
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
 )
 
 func MustReadAllGzip(t testing.TB, filename string) []byte {
@@ -50,3 +54,83 @@ func Test_Normalizer(t *testing.T) {
 	_, err := NormalizePprof(ctx, t.Name(), nil, p, true, nil)
 	require.NoError(t, err)
 }
+
+func writeRawRequest(rawProfile []byte) *profilestorepb.WriteRawRequest {
+	return &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{{
+			Labels: &profilestorepb.LabelSet{
+				Labels: []*profilestorepb.Label{{Name: "__name__", Value: "test"}},
+			},
+			Samples: []*profilestorepb.RawSample{{RawProfile: rawProfile}},
+		}},
+	}
+}
+
+func Test_ValidatePprofProfile_StringIndexes(t *testing.T) {
+	for name, p := range map[string]*pprofpb.Profile{
+		"period type past string table": {
+			StringTable: []string{"", "cpu"},
+			PeriodType:  &pprofpb.ValueType{Type: 1, Unit: 2},
+		},
+		"sample type with empty string table": {
+			SampleType: []*pprofpb.ValueType{{Type: 1}},
+		},
+		"mapping filename at string table length": {
+			StringTable: []string{"", "a"},
+			Mapping:     []*pprofpb.Mapping{{Id: 1, Filename: 2}},
+		},
+		"negative function name": {
+			StringTable: []string{""},
+			Function:    []*pprofpb.Function{{Id: 1, Name: -1}},
+		},
+		"nil line": {
+			Location: []*pprofpb.Location{{Id: 1, Line: []*pprofpb.Line{nil}}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Error(t, ValidatePprofProfile(p, nil))
+		})
+	}
+}
+
+func Test_NormalizeWriteRawRequest_Limits(t *testing.T) {
+	ctx := context.Background()
+
+	p := &pprofpb.Profile{
+		StringTable: []string{"", "samples", "count"},
+		SampleType:  make([]*pprofpb.ValueType, MaxSampleTypes+1),
+	}
+	for i := range p.SampleType {
+		p.SampleType[i] = &pprofpb.ValueType{Type: 1, Unit: 2}
+	}
+	b, err := p.MarshalVT()
+	require.NoError(t, err)
+
+	_, err = NormalizeWriteRawRequest(ctx, writeRawRequest(b))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	_, err = gz.Write(make([]byte, 1025))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	r, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	_, err = readAllLimited(r, 1024)
+	require.ErrorIs(t, err, ErrProfileTooLarge)
+}
+
+func FuzzNormalizeWriteRawRequest(f *testing.F) {
+	for _, filename := range []string{"./profile.pb.gz", "./node-profile.pb.gz"} {
+		compressed, err := os.ReadFile(filename)
+		require.NoError(f, err)
+		f.Add(compressed)
+		f.Add(MustReadAllGzip(f, filename))
+	}
+
+	f.Fuzz(func(t *testing.T, rawProfile []byte) {
+		// Malformed profiles must be rejected with an error, never panic.
+		_, _ = NormalizeWriteRawRequest(context.Background(), writeRawRequest(rawProfile))
+	})
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package normalizer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// RecordToNormalizedWriteRawRequest converts a record in the storage schema,
+// like the records converted from Arrow and OTLP writes, to a normalized
+// write request, eg. to pass them to ingest middlewares. The rows of each
+// series and profile become the samples of one normalized profile, their
+// locations are kept encoded as they are.
+func RecordToNormalizedWriteRawRequest(r arrow.Record) (NormalizedWriteRawRequest, error) {
+	var (
+		name, sampleType, sampleUnit, periodType, periodUnit arrow.Array
+		period, timestamp, duration, values                  *array.Int64
+		stacktraces                                          *array.List
+		labelNames                                           []string
+		labelColumns                                         []arrow.Array
+	)
+	for i, field := range r.Schema().Fields() {
+		switch {
+		case field.Name == profile.ColumnName:
+			name = r.Column(i)
+		case field.Name == profile.ColumnSampleType:
+			sampleType = r.Column(i)
+		case field.Name == profile.ColumnSampleUnit:
+			sampleUnit = r.Column(i)
+		case field.Name == profile.ColumnPeriodType:
+			periodType = r.Column(i)
+		case field.Name == profile.ColumnPeriodUnit:
+			periodUnit = r.Column(i)
+		case field.Name == profile.ColumnPeriod:
+			period, _ = r.Column(i).(*array.Int64)
+		case field.Name == profile.ColumnTimestamp:
+			timestamp, _ = r.Column(i).(*array.Int64)
+		case field.Name == profile.ColumnDuration:
+			duration, _ = r.Column(i).(*array.Int64)
+		case field.Name == profile.ColumnValue:
+			values, _ = r.Column(i).(*array.Int64)
+		case field.Name == profile.ColumnStacktrace:
+			stacktraces, _ = r.Column(i).(*array.List)
+		case strings.HasPrefix(field.Name, profile.ColumnLabelsPrefix):
+			labelNames = append(labelNames, strings.TrimPrefix(field.Name, profile.ColumnLabelsPrefix))
+			labelColumns = append(labelColumns, r.Column(i))
+		}
+	}
+	if name == nil || sampleType == nil || sampleUnit == nil || periodType == nil || periodUnit == nil ||
+		period == nil || timestamp == nil || duration == nil || values == nil || stacktraces == nil {
+		return NormalizedWriteRawRequest{}, fmt.Errorf("unexpected record schema: %s", r.Schema())
+	}
+
+	var (
+		series   []Series
+		bySeries = map[string]int{}
+		profiles = map[recordProfileKey]*NormalizedProfile{}
+	)
+	for row := 0; row < int(r.NumRows()); row++ {
+		ls := make(map[string]string, len(labelColumns))
+		for i, col := range labelColumns {
+			if col.IsValid(row) {
+				ls[labelNames[i]] = recordString(col, row)
+			}
+		}
+
+		key := labelsKey(ls)
+		idx, ok := bySeries[key]
+		if !ok {
+			idx = len(series)
+			bySeries[key] = idx
+			series = append(series, Series{Labels: ls})
+		}
+
+		meta := profile.Meta{
+			Name:       recordString(name, row),
+			Timestamp:  timestamp.Value(row),
+			Duration:   duration.Value(row),
+			Period:     period.Value(row),
+			PeriodType: profile.ValueType{Type: recordString(periodType, row), Unit: recordString(periodUnit, row)},
+			SampleType: profile.ValueType{Type: recordString(sampleType, row), Unit: recordString(sampleUnit, row)},
+		}
+		p, ok := profiles[recordProfileKey{series: idx, meta: meta}]
+		if !ok {
+			p = &NormalizedProfile{Meta: meta}
+			profiles[recordProfileKey{series: idx, meta: meta}] = p
+			series[idx].Samples = append(series[idx].Samples, []*NormalizedProfile{p})
+		}
+
+		var locs [][]byte
+		if stacktraces.IsValid(row) {
+			start, end := stacktraces.ValueOffsets(row)
+			locations := stacktraces.ListValues()
+			locs = make([][]byte, 0, end-start)
+			for i := int(start); i < int(end); i++ {
+				// Null locations are kept, they are written as nulls again.
+				var loc []byte
+				if locations.IsValid(i) {
+					loc = recordBytes(locations, i)
+				}
+				locs = append(locs, loc)
+			}
+		}
+		p.Samples = append(p.Samples, &NormalizedSample{
+			Locations: locs,
+			Value:     values.Value(row),
+		})
+	}
+
+	sort.Strings(labelNames)
+	return NormalizedWriteRawRequest{
+		Series:        series,
+		AllLabelNames: labelNames,
+	}, nil
+}
+
+type recordProfileKey struct {
+	series int
+	meta   profile.Meta
+}
+
+func labelsKey(ls map[string]string) string {
+	names := make([]string, 0, len(ls))
+	for name := range ls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(0xff)
+		b.WriteString(ls[name])
+		b.WriteByte(0xfe)
+	}
+	return b.String()
+}
+
+func recordBytes(arr arrow.Array, i int) []byte {
+	switch a := arr.(type) {
+	case *array.Dictionary:
+		return recordBytes(a.Dictionary(), a.GetValueIndex(i))
+	case *array.Binary:
+		return a.Value(i)
+	case *array.String:
+		return []byte(a.Value(i))
+	default:
+		return nil
+	}
+}
+
+func recordString(arr arrow.Array, i int) string {
+	if !arr.IsValid(i) {
+		return ""
+	}
+	return string(recordBytes(arr, i))
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalizer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func TestRecordToNormalizedWriteRawRequest(t *testing.T) {
+	ctx := context.Background()
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+
+	req := writeRawRequest(MustReadAllGzip(t, "./profile.pb.gz"))
+	req.Series[0].Labels.Labels = append(req.Series[0].Labels.Labels, &profilestorepb.Label{Name: "job", Value: "test"})
+	normalized, err := NormalizeWriteRawRequest(ctx, req)
+	require.NoError(t, err)
+
+	r, err := NormalizedWriteRawRequestToArrowRecord(ctx, mem, normalized, schema)
+	require.NoError(t, err)
+	defer r.Release()
+
+	roundtrip, err := RecordToNormalizedWriteRawRequest(r)
+	require.NoError(t, err)
+	require.Equal(t, normalized.AllLabelNames, roundtrip.AllLabelNames)
+	require.Len(t, roundtrip.Series, 1)
+	require.Equal(t, map[string]string{"job": "test"}, roundtrip.Series[0].Labels)
+
+	rr, err := NormalizedWriteRawRequestToArrowRecord(ctx, mem, roundtrip, schema)
+	require.NoError(t, err)
+	defer rr.Release()
+
+	require.Equal(t, recordRows(r), recordRows(rr))
+}
+
+// recordRows sums the values of the rows of a record in the storage schema
+// by all other columns.
+func recordRows(r arrow.Record) map[string]int64 {
+	rows := map[string]int64{}
+	for row := 0; row < int(r.NumRows()); row++ {
+		var (
+			b     strings.Builder
+			value int64
+		)
+		for i, field := range r.Schema().Fields() {
+			col := r.Column(i)
+			switch c := col.(type) {
+			case *array.Int64:
+				if field.Name == profile.ColumnValue {
+					value = c.Value(row)
+					continue
+				}
+				fmt.Fprintf(&b, "%s=%d;", field.Name, c.Value(row))
+			case *array.List:
+				start, end := c.ValueOffsets(row)
+				fmt.Fprintf(&b, "%s=", field.Name)
+				for j := int(start); j < int(end); j++ {
+					fmt.Fprintf(&b, "%x,", recordBytes(c.ListValues(), j))
+				}
+				b.WriteByte(';')
+			default:
+				if col.IsValid(row) {
+					fmt.Fprintf(&b, "%s=%s;", field.Name, recordString(col, row))
+				}
+			}
+		}
+		rows[b.String()] += value
+	}
+	return rows
+}
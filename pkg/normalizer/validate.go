@@ -14,19 +14,60 @@
 package normalizer
 
 import (
+	"errors"
 	"fmt"
+	"io"
 
 	pprofpb "github.com/parca-dev/parca/gen/proto/go/google/pprof"
 	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
 )
 
-func ValidatePprofProfile(p *pprofpb.Profile, ei []*profilestorepb.ExecutableInfo) error {
-	stringTableLen := int64(len(p.StringTable))
+const (
+	// MaxDecompressedProfileSize is the maximum size of a pprof profile after
+	// decompression. It protects the server from decompression bombs.
+	MaxDecompressedProfileSize = 512 << 20 // 512MiB
+
+	// MaxSampleTypes is the maximum number of sample types of a pprof
+	// profile. Each sample type is normalized into its own profile, so the
+	// work done for a profile grows with the number of sample types times the
+	// number of samples.
+	MaxSampleTypes = 64
+)
 
-	if stringTableLen > 0 && p.StringTable[0] != "" {
+var ErrProfileTooLarge = errors.New("profile exceeds the maximum decompressed size")
+
+// readAllLimited reads r until EOF, but at most limit bytes.
+func readAllLimited(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, ErrProfileTooLarge
+	}
+	return b, nil
+}
+
+func ValidatePprofProfile(p *pprofpb.Profile, ei []*profilestorepb.ExecutableInfo) error {
+	if len(p.StringTable) > 0 && p.StringTable[0] != "" {
 		return fmt.Errorf("first item in string table is expected to be empty string, but it is %q", p.StringTable[0])
 	}
 
+	if len(p.SampleType) > MaxSampleTypes {
+		return fmt.Errorf("profile has %d sample types, at most %d are allowed", len(p.SampleType), MaxSampleTypes)
+	}
+	for i, st := range p.SampleType {
+		if st == nil {
+			return fmt.Errorf("profile has nil sample type")
+		}
+		if !existsInStringTable(st.Type, p.StringTable) || !existsInStringTable(st.Unit, p.StringTable) {
+			return fmt.Errorf("sample type %d has invalid string index", i)
+		}
+	}
+	if p.PeriodType != nil && (!existsInStringTable(p.PeriodType.Type, p.StringTable) || !existsInStringTable(p.PeriodType.Unit, p.StringTable)) {
+		return fmt.Errorf("period type has invalid string index")
+	}
+
 	// Check that all mappings/locations/functions are in the tables
 	// Check that there are no duplicate ids
 	mappingsNum := uint64(len(p.Mapping))
@@ -37,11 +78,11 @@ func ValidatePprofProfile(p *pprofpb.Profile, ei []*profilestorepb.ExecutableInf
 		if m.Id != uint64(i+1) {
 			return fmt.Errorf("mapping id is not sequential")
 		}
-		if m.Filename != 0 && m.Filename > stringTableLen {
+		if !existsInStringTable(m.Filename, p.StringTable) {
 			return fmt.Errorf("mapping (id: %d) has invalid filename index %d", m.Id, m.Filename)
 		}
-		if m.BuildId != 0 && m.BuildId > stringTableLen {
-			return fmt.Errorf("mapping (id: %d) has invalid buildid index %d", m.Id, m.Filename)
+		if !existsInStringTable(m.BuildId, p.StringTable) {
+			return fmt.Errorf("mapping (id: %d) has invalid buildid index %d", m.Id, m.BuildId)
 		}
 	}
 
@@ -57,13 +98,13 @@ func ValidatePprofProfile(p *pprofpb.Profile, ei []*profilestorepb.ExecutableInf
 		if f.Id != uint64(i+1) {
 			return fmt.Errorf("function id is not sequential")
 		}
-		if f.Name != 0 && f.Name > stringTableLen {
+		if !existsInStringTable(f.Name, p.StringTable) {
 			return fmt.Errorf("function (id: %d) has invalid name index %d", f.Id, f.Name)
 		}
-		if f.SystemName != 0 && f.SystemName > stringTableLen {
+		if !existsInStringTable(f.SystemName, p.StringTable) {
 			return fmt.Errorf("function (id: %d) has invalid systemname index %d", f.Id, f.SystemName)
 		}
-		if f.Filename != 0 && f.Filename > stringTableLen {
+		if !existsInStringTable(f.Filename, p.StringTable) {
 			return fmt.Errorf("function (id: %d) has invalid filename index %d", f.Id, f.Filename)
 		}
 	}
@@ -80,6 +121,9 @@ func ValidatePprofProfile(p *pprofpb.Profile, ei []*profilestorepb.ExecutableInf
 			return fmt.Errorf("location has invalid mapping id: %d", l.MappingId)
 		}
 		for _, ln := range l.Line {
+			if ln == nil {
+				return fmt.Errorf("location %d has nil line", l.Id)
+			}
 			if ln.FunctionId != 0 && ln.FunctionId > functionsNum {
 				return fmt.Errorf("location %d has invalid function id: %d", l.Id, ln.FunctionId)
 			}
@@ -108,13 +152,16 @@ func ValidatePprofProfile(p *pprofpb.Profile, ei []*profilestorepb.ExecutableInf
 			}
 		}
 		for j, label := range s.Label {
+			if label == nil {
+				return fmt.Errorf("sample %d label %d is nil", i, j)
+			}
 			if label.Key == 0 {
 				return fmt.Errorf("sample %d label %d has no key", i, j)
 			}
-			if label.Key != 0 && label.Key > stringTableLen {
+			if !existsInStringTable(label.Key, p.StringTable) {
 				return fmt.Errorf("sample %d label %d has invalid key index %d", i, j, label.Key)
 			}
-			if label.Str != 0 && label.Str > stringTableLen {
+			if !existsInStringTable(label.Str, p.StringTable) {
 				return fmt.Errorf("sample %d label %d has invalid str index %d", i, j, label.Str)
 			}
 		}
@@ -0,0 +1,104 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	querypb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/client"
+)
+
+// ErrRegression is returned by the gate command if a function regressed
+// beyond its threshold.
+var ErrRegression = errors.New("performance regression detected")
+
+// GateCmd compares a profile, eg. of a benchmark run in CI, against a
+// baseline stored in Parca and fails if functions regress.
+type GateCmd struct {
+	Profile string `arg:"" type:"existingfile" help:"Path to the pprof profile to compare against the baseline."`
+
+	Address  string        `default:"localhost:7070" help:"gRPC address of the Parca server. The --bearer-token, --bearer-token-file, --insecure and --insecure-skip-verify flags apply to it."`
+	Baseline string        `required:"" help:"Selector of the baseline profiles, eg. 'parca_agent:samples:count:cpu:nanoseconds:delta{job=\"bench\",branch=\"main\"}'."`
+	Since    time.Duration `default:"168h" help:"Merge the baseline profiles of this duration before now."`
+
+	SampleType string `help:"Type of the sample values of the profile to compare, eg. cpu for a Go CPU profile. Defaults to the sample type of the baseline selector."`
+
+	Function    []string `required:"" sep:"none" help:"Regular expression of the functions to check. Can be repeated."`
+	MaxIncrease float64  `default:"0.05" help:"Maximum allowed relative increase of a function's share of the total, eg. 0.05 for 5%."`
+
+	Upload map[string]string `help:"Upload the profile with these labels before comparing, eg. --upload=__name__=bench --upload=branch=feature."`
+}
+
+// RunGate runs the gate command. It returns ErrRegression if any of the
+// functions regressed.
+func RunGate(ctx context.Context, flags *Flags, stdout io.Writer) error {
+	cmd := &flags.Gate
+
+	rules := make([]client.RegressionRule, 0, len(cmd.Function))
+	for _, f := range cmd.Function {
+		rules = append(rules, client.RegressionRule{Function: f, MaxIncrease: cmd.MaxIncrease})
+	}
+
+	data, err := os.ReadFile(cmd.Profile)
+	if err != nil {
+		return fmt.Errorf("read profile: %w", err)
+	}
+
+	c, err := newClientFromFlags(flags, cmd.Address)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if len(cmd.Upload) > 0 {
+		if err := c.WriteRaw(ctx, cmd.Upload, data); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	report, err := c.CompareToBaseline(ctx, cmd.Baseline, now.Add(-cmd.Since), now, data, cmd.SampleType, rules)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteComparisonReport(stdout, report); err != nil {
+		return err
+	}
+	if report.Regressed {
+		return ErrRegression
+	}
+	return nil
+}
+
+// WriteComparisonReport writes a table of the compared functions.
+func WriteComparisonReport(w io.Writer, report *querypb.CompareToBaselineResponse) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "STATUS\tBASELINE\tCANDIDATE\tCHANGE\tFUNCTION\n")
+	for _, f := range report.Functions {
+		status := "ok"
+		if f.Regressed {
+			status = "REGRESSED"
+		}
+		fmt.Fprintf(tw, "%s\t%.2f%%\t%.2f%%\t%+.2f%%\t%s\n", status, f.Baseline*100, f.Candidate*100, f.Increase*100, f.Function)
+	}
+	return tw.Flush()
+}
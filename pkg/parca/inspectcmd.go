@@ -0,0 +1,149 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parca
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/thanos-io/objstore/providers/filesystem"
+
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+// TSDBCmd groups the commands working on the storage offline.
+type TSDBCmd struct {
+	Inspect InspectCmd `cmd:"" help:"Print the blocks and write-ahead logs in a storage directory without starting the server, eg. to debug corruption."`
+}
+
+// InspectCmd prints the stats of the blocks and write-ahead logs in a
+// directory.
+type InspectCmd struct {
+	Dir string `arg:"" type:"existingdir" help:"Directory to inspect, eg. the storage path or a local copy of the bucket the blocks are persisted to."`
+
+	Columns bool `help:"Also print how the columns of every block are stored."`
+}
+
+// RunInspect runs the tsdb inspect command.
+func RunInspect(ctx context.Context, flags *Flags, stdout io.Writer) error {
+	cmd := &flags.TSDB.Inspect
+
+	bucket, err := filesystem.NewBucket(cmd.Dir)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", cmd.Dir, err)
+	}
+	blocks, err := parcacol.InspectBlocks(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if err := WriteBlockStats(stdout, blocks, cmd.Columns); err != nil {
+		return err
+	}
+
+	wals, err := inspectWALs(cmd.Dir)
+	if err != nil {
+		return err
+	}
+	if len(wals) == 0 {
+		return nil
+	}
+	fmt.Fprintln(stdout)
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "WAL\tSEGMENTS\tBYTES\n")
+	for _, w := range wals {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", w.dir, w.segments, w.bytes)
+	}
+	return tw.Flush()
+}
+
+// WriteBlockStats writes a table of the blocks, followed by a table of the
+// columns of every block if columns is set.
+func WriteBlockStats(w io.Writer, blocks []*parcacol.BlockStats, columns bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "BLOCK\tTABLE\tROWS\tSERIES\tROW GROUPS\tPAGES\tMIN TIME\tMAX TIME\tBYTES\n")
+	for _, b := range blocks {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\t%d\n",
+			b.ID, b.Table, b.Rows, b.Series, b.RowGroups, b.Pages,
+			b.MinTime.UTC().Format(time.RFC3339), b.MaxTime.UTC().Format(time.RFC3339), b.Bytes,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if !columns {
+		return nil
+	}
+
+	for _, b := range blocks {
+		fmt.Fprintf(w, "\n%s\n", b.ID)
+		fmt.Fprintf(tw, "COLUMN\tENCODINGS\tCOMPRESSION\tCOMPRESSED\tUNCOMPRESSED\n")
+		for _, c := range b.Columns {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", c.Name, strings.Join(c.Encodings, ","), c.Compression, c.CompressedBytes, c.UncompressedBytes)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type walStats struct {
+	dir      string
+	segments int
+	bytes    int64
+}
+
+// inspectWALs returns the number and size of the segments of the
+// write-ahead logs in the directory, which are kept in directories named
+// wal.
+func inspectWALs(dir string) ([]walStats, error) {
+	var wals []walStats
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || d.Name() != "wal" {
+			return nil
+		}
+
+		w := walStats{dir: path}
+		err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			w.segments++
+			w.bytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		wals = append(wals, w)
+		return fs.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("inspect write-ahead logs: %w", err)
+	}
+	return wals, nil
+}
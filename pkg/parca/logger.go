@@ -14,49 +14,185 @@
 package parca
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
 	"os"
+	"sync"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 )
 
 const (
 	LogFormatJSON = "json"
 )
 
+// Components of Parca that log with their own level, see LogLevels. Any
+// other value of the component key of a logger can be used as well.
+const (
+	LogComponentScrape     = "scrape"
+	LogComponentStorage    = "storage"
+	LogComponentSymbolizer = "symbolizer"
+	LogComponentQuery      = "query"
+)
+
+// LogLevelsPath is the HTTP path of the endpoint listing and adjusting the
+// log levels of the components, relative to the API root.
+const LogLevelsPath = "/log_levels"
+
+var logLevelRanks = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+}
+
+// LogLevels are the log levels of the components of Parca. A component is
+// named by the last "component" key of a logger's context, components
+// without a level of their own log at the default level. Levels can be
+// adjusted at runtime.
+type LogLevels struct {
+	mtx          sync.RWMutex
+	defaultLevel string
+	components   map[string]string
+}
+
+// NewLogLevels returns the log levels with the default level and the levels
+// of the given components.
+func NewLogLevels(defaultLevel string, components map[string]string) (*LogLevels, error) {
+	l := &LogLevels{components: map[string]string{}}
+	if err := l.Set("", defaultLevel); err != nil {
+		return nil, err
+	}
+	for component, lvl := range components {
+		if err := l.Set(component, lvl); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Set sets the log level of a component, or the default level if the
+// component is empty. An empty level resets a component to the default
+// level.
+func (l *LogLevels) Set(component, lvl string) error {
+	if _, ok := logLevelRanks[lvl]; !ok && (component == "" || lvl != "") {
+		return fmt.Errorf("invalid log level %q, must be one of error, warn, info or debug", lvl)
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	switch {
+	case component == "":
+		l.defaultLevel = lvl
+	case lvl == "":
+		delete(l.components, component)
+	default:
+		l.components[component] = lvl
+	}
+	return nil
+}
+
+// Levels returns the default level and the levels of the components that
+// have their own.
+func (l *LogLevels) Levels() (string, map[string]string) {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	return l.defaultLevel, maps.Clone(l.components)
+}
+
+func (l *LogLevels) allowed(component string, lvl level.Value) bool {
+	l.mtx.RLock()
+	threshold, ok := l.components[component]
+	if !ok {
+		threshold = l.defaultLevel
+	}
+	l.mtx.RUnlock()
+
+	return logLevelRanks[lvl.String()] >= logLevelRanks[threshold]
+}
+
+// filter drops the log lines below the level of the component they are
+// logged by. Lines without a level are always logged.
+func (l *LogLevels) filter(next log.Logger) log.Logger {
+	return log.LoggerFunc(func(keyvals ...any) error {
+		var (
+			component string
+			lvl       level.Value
+		)
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			if v, ok := keyvals[i+1].(level.Value); ok {
+				lvl = v
+				continue
+			}
+			if keyvals[i] == "component" {
+				component, _ = keyvals[i+1].(string)
+			}
+		}
+		if lvl != nil && !l.allowed(component, lvl) {
+			return nil
+		}
+		return next.Log(keyvals...)
+	})
+}
+
+// LogLevelsHandler lists the log levels. PUT and POST requests set the level
+// of the "component" parameter, or the default level if it is empty, to the
+// "level" parameter.
+func (l *LogLevels) LogLevelsHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			q := r.URL.Query()
+			if err := l.Set(q.Get("component"), q.Get("level")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		defaultLevel, components := l.Levels()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(struct {
+			Default    string            `json:"default"`
+			Components map[string]string `json:"components"`
+		}{defaultLevel, components})
+	}
+}
+
 // NewLogger returns a log.Logger that prints in the provided format at the
 // provided level with a UTC timestamp and the caller of the log entry. If non
 // empty, the debug name is also appended as a field to all log lines. Panics
 // if the log level is not error, warn, info or debug. Log level is expected to
 // be validated before passed to this function.
 func NewLogger(logLevel, logFormat, debugName string) log.Logger {
-	var (
-		logger log.Logger
-		lvl    level.Option
-	)
-
-	switch logLevel {
-	case "error":
-		lvl = level.AllowError()
-	case "warn":
-		lvl = level.AllowWarn()
-	case "info":
-		lvl = level.AllowInfo()
-	case "debug":
-		lvl = level.AllowDebug()
-	default:
+	levels, err := NewLogLevels(logLevel, nil)
+	if err != nil {
 		// This enum is already checked and enforced by flag validations, so
 		// this should never happen.
 		panic("unexpected log level")
 	}
+	return NewLeveledLogger(levels, logFormat, debugName)
+}
 
+// NewLeveledLogger returns a log.Logger like NewLogger that logs at the
+// levels of the components instead of a single level.
+func NewLeveledLogger(levels *LogLevels, logFormat, debugName string) log.Logger {
 	writer := log.NewSyncWriter(os.Stderr)
-	logger = log.NewLogfmtLogger(writer)
+	logger := log.NewLogfmtLogger(writer)
 	if logFormat == LogFormatJSON {
 		logger = log.NewJSONLogger(writer)
 	}
 
-	logger = level.NewFilter(logger, lvl)
+	logger = levels.filter(logger)
 
 	if debugName != "" {
 		logger = log.With(logger, "name", debugName)
@@ -0,0 +1,73 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parca
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevels(t *testing.T) {
+	levels, err := NewLogLevels("info", map[string]string{LogComponentScrape: "debug"})
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	logger := levels.filter(log.NewLogfmtLogger(buf))
+	scrape := log.With(logger, "component", LogComponentScrape)
+	query := log.With(logger, "component", LogComponentQuery)
+
+	level.Debug(logger).Log("msg", "root debug")
+	level.Debug(scrape).Log("msg", "scrape debug")
+	level.Debug(query).Log("msg", "query debug")
+	level.Info(query).Log("msg", "query info")
+	logger.Log("msg", "no level")
+	require.Equal(t, ""+
+		"level=debug component=scrape msg=\"scrape debug\"\n"+
+		"level=info component=query msg=\"query info\"\n"+
+		"msg=\"no level\"\n",
+		buf.String(),
+	)
+
+	// Adjusting the levels at runtime applies to existing loggers.
+	buf.Reset()
+	rec := httptest.NewRecorder()
+	levels.LogLevelsHandler()(rec, httptest.NewRequest("PUT", LogLevelsPath+"?component=query&level=error", nil), nil)
+	require.Equal(t, 200, rec.Code)
+	rec = httptest.NewRecorder()
+	levels.LogLevelsHandler()(rec, httptest.NewRequest("PUT", LogLevelsPath+"?component=scrape", nil), nil)
+	require.Equal(t, 200, rec.Code)
+
+	level.Debug(scrape).Log("msg", "scrape debug")
+	level.Warn(query).Log("msg", "query warn")
+	level.Error(query).Log("msg", "query error")
+	require.Equal(t, "level=error component=query msg=\"query error\"\n", buf.String())
+
+	var res struct {
+		Default    string            `json:"default"`
+		Components map[string]string `json:"components"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Equal(t, "info", res.Default)
+	require.Equal(t, map[string]string{LogComponentQuery: "error"}, res.Components)
+
+	rec = httptest.NewRecorder()
+	levels.LogLevelsHandler()(rec, httptest.NewRequest("PUT", LogLevelsPath+"?component=query&level=verbose", nil), nil)
+	require.Equal(t, 400, rec.Code)
+}
@@ -20,9 +20,11 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	goruntime "runtime"
+	"runtime/debug"
 	"runtime/pprof"
 	"strings"
 	"syscall"
@@ -46,6 +48,7 @@ import (
 	promconfig "github.com/prometheus/common/config"
 	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/thanos-io/objstore"
 	"github.com/thanos-io/objstore/client"
 	objstoretracing "github.com/thanos-io/objstore/tracing/opentelemetry"
@@ -70,10 +73,13 @@ import (
 	"github.com/parca-dev/parca/pkg/config"
 	"github.com/parca-dev/parca/pkg/debuginfo"
 	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/jobs"
 	"github.com/parca-dev/parca/pkg/kv"
 	"github.com/parca-dev/parca/pkg/parcacol"
 	"github.com/parca-dev/parca/pkg/profile"
 	"github.com/parca-dev/parca/pkg/profilestore"
+	"github.com/parca-dev/parca/pkg/profilestore/kafka"
+	"github.com/parca-dev/parca/pkg/profilestore/nats"
 	queryservice "github.com/parca-dev/parca/pkg/query"
 	"github.com/parca-dev/parca/pkg/scrape"
 	"github.com/parca-dev/parca/pkg/server"
@@ -110,16 +116,22 @@ type Flags struct {
 	BlockProfileRate     int `default:"0" help:"Sample rate for block profile."`
 
 	EnablePersistence bool `default:"false" help:"Turn on persistent storage for the metastore and profile storage."`
+	EnableAdminAPI    bool `default:"false" help:"Serve the admin endpoints that delete or modify stored profiles and blocks, take storage snapshots and change log levels at runtime. They are not authenticated, only enable them if the HTTP address is reachable by trusted clients alone."`
 
 	Storage FlagsStorage `embed:"" prefix:"storage-"`
+	Ingest  FlagsIngest  `embed:"" prefix:"ingest-"`
 
 	Symbolizer FlagsSymbolizer `embed:"" prefix:"symbolizer-"`
 
 	Debuginfo  FlagsDebuginfo  `embed:"" prefix:"debuginfo-"`
 	Debuginfod FlagsDebuginfod `embed:"" prefix:"debuginfod-"`
 
+	Cost FlagsCost `embed:"" prefix:"cost-"`
+
 	ProfileShareServer string `default:"api.pprof.me:443" help:"gRPC address to send share profile requests to."`
 
+	QueryNormalizationURL string `help:"URL of a Prometheus compatible API to read scalar series from, eg. request rates, when normalizing queries at /api/profiles/query_normalized."`
+
 	StoreAddress       string            `kong:"help='gRPC address to send profiles and symbols to.'"`
 	BearerToken        string            `kong:"help='Bearer token to authenticate with store.'"`
 	BearerTokenFile    string            `kong:"help='File to read bearer token from to authenticate with store.'"`
@@ -128,11 +140,22 @@ type Flags struct {
 	ExternalLabel      map[string]string `kong:"help='Label(s) to attach to all profiles in scraper-only mode.'"`
 
 	Hidden FlagsHidden `embed:"" prefix:""`
+
+	Serve struct{} `cmd:"" default:"1" hidden:"" help:"Run the Parca server (default)."`
+	Query QueryCmd `cmd:"" help:"Query a Parca server and print or write the resulting profile."`
+	Gate  GateCmd  `cmd:"" help:"Compare a profile against a baseline and fail if functions regress."`
+	TSDB  TSDBCmd  `cmd:"" name:"tsdb" help:"Work with the storage offline."`
 }
 
 type FlagsLogs struct {
 	Level  string `enum:"error,warn,info,debug" default:"info" help:"Log level."`
 	Format string `enum:"logfmt,json" default:"logfmt" help:"Configure if structured logging as JSON or as logfmt"`
+	Access bool   `default:"false" help:"Log query and ingest requests with query fingerprints. Can be toggled at runtime at /debug/access-log."`
+
+	ComponentLevel map[string]string `help:"Log level of a component, eg. scrape=debug. Components are scrape, storage, symbolizer and query, others log at --log-level. Can be adjusted at runtime at /api/log_levels with enable-admin-api."`
+
+	SlowQueryThreshold time.Duration `default:"10s" help:"Queries taking longer than this are logged along with their selection statistics. Listed at /api/slow_queries."`
+	SlowQueryLogSize   int           `default:"100" help:"Number of most recent slow queries to keep."`
 }
 
 // FlagsOTLP provides OTLP configuration flags.
@@ -143,16 +166,72 @@ type FlagsOTLP struct {
 
 type FlagsStorage struct {
 	ActiveMemory        int64  `default:"536870912" help:"Amount of memory to use for active storage. Defaults to 512MB."`
+	MaxMemory           int64  `default:"0" help:"Soft limit of the memory used by Parca in bytes. Sets the Go runtime memory limit unless GOMEMLIMIT is set, frees memory when approaching it and rejects writes close to it. Zero disables the limit."`
 	Path                string `default:"data" help:"Path to storage directory."`
 	EnableWAL           bool   `default:"false" help:"Enables write ahead log for profile storage."`
 	SnapshotTriggerSize int64  `default:"134217728" help:"Number of bytes to trigger a snapshot. Defaults to 1/4 of active memory. This is only used if enable-wal is set."`
 	RowGroupSize        int    `default:"8192" help:"Number of rows in each row group during compaction and persistence. Setting to <= 0 results in a single row group per file."`
 	IndexOnDisk         bool   `default:"false" help:"Whether to store the index on disk instead of in memory. Useful to reduce the memory footprint of the store."`
+	Compression         string `default:"lz4" enum:"lz4,zstd" help:"Compression of the stacktrace, timestamp and value columns of stored profiles. zstd makes persisted blocks smaller at the cost of more CPU when they are written and read. Blocks written with either compression can be read."`
+
+	RetentionSize     int64         `default:"0" help:"Maximum number of bytes of the blocks persisted to object storage. The oldest blocks are deleted when it's exceeded. Requires enable-persistence. Zero disables the limit."`
+	RetentionInterval time.Duration `default:"5m" help:"Interval in which the retention size and the retentions of the storage tiers in the config file are enforced, and the rows of deleted profiles are reclaimed."`
+
+	BlockCacheSize int64 `default:"0" help:"Maximum number of bytes of the blocks read from object storage that are cached in the storage path. The least recently read blocks are evicted. Requires enable-persistence. Zero disables the cache."`
+
+	SnapshotDir     string `default:"" help:"Directory snapshots of the stored profiles are taken in, at /api/storage/snapshots with enable-admin-api. Empty disables snapshots."`
+	RestoreSnapshot string `default:"" help:"Path of a snapshot directory to restore the stored profiles from at startup."`
+
+	DownsampleDelay time.Duration `default:"1m" help:"How long after the end of a window it is downsampled into the storage tiers configured in the config file, to include late profiles."`
+	WatermarksDir   string        `default:"" help:"Directory the progress of downsampling and rollups is recorded in, so the windows that completed while Parca was not running are processed after a restart. Empty keeps it in memory only."`
+
+	TombstonesPath      string        `default:"" help:"File deletions of profiles made at /api/profiles/delete with enable-admin-api are recorded in, so they survive restarts. Empty keeps them in memory only."`
+	UndeleteGracePeriod time.Duration `default:"24h" help:"How long after a deletion of profiles it can be undone at /api/profiles/undelete. After that the rows of the deleted profiles are reclaimed from the persisted blocks."`
+}
+
+// FlagsIngest configures how written profiles are ingested.
+type FlagsIngest struct {
+	DedupWindow     time.Duration `default:"5m" help:"Drop profiles identical to one ingested for the same series within this window, eg. when an agent retries a write. Zero disables deduplication."`
+	DedupMaxEntries int           `default:"100000" help:"Maximum number of ingested profiles remembered for deduplication."`
+	TimestampPolicy string        `default:"agent" enum:"agent,receive,scrape-start,capture-start,capture-midpoint" help:"Timestamp stored profiles get: the one reported in the profile (agent), the time the server received it (receive), the start of the scrape (scrape-start), or the start (capture-start) or midpoint (capture-midpoint) of the capture window lasting the duration of the profile and starting at the reported timestamp, or ending when the server received it if the profile has none. Pushed profiles keep their reported timestamp with scrape-start."`
+	MaxClockSkew    time.Duration `default:"10m" help:"Clamp profile timestamps more than this far ahead of the server's time. Zero disables clamping."`
+	RejectFuture    time.Duration `default:"0" help:"Reject profiles with timestamps more than this far ahead of the server's time, eg. 10m. Applies after clamping. Zero disables the bound."`
+	RejectPast      time.Duration `default:"0" help:"Reject profiles with timestamps more than this far behind the server's time, eg. 168h. Zero disables the bound."`
+
+	SeriesCreationRate  float64 `default:"0" help:"Maximum number of new series created per second. Writes creating new series wait for the limit. Zero disables the limit."`
+	SeriesCreationBurst int     `default:"1000" help:"Number of new series that may be created at once before the series creation rate applies."`
+	SeriesCreationQueue int     `default:"10000" help:"Maximum number of new series waiting to be created. Writes exceeding it are rejected."`
+	MaxSeries           int     `default:"0" help:"Maximum number of series written to within the last hour. Writes creating new series beyond it are rejected. Zero disables the limit."`
+
+	MaxProfileSize  int64  `default:"0" help:"Maximum uncompressed size of a written pprof profile in bytes. Scrape configs can override it. Zero disables the limit."`
+	ProfileSizeMode string `default:"reject" enum:"reject,trim" help:"What happens to profiles exceeding the maximum size: reject them or trim them to their heaviest samples."`
+	ProfileSizeTopK int    `default:"1000" help:"Number of heaviest samples kept of profiles trimmed for exceeding the maximum size."`
+
+	EmptyProfiles string `default:"store" enum:"store,skip,marker" help:"What is stored of profiles without any non-zero sample, eg. of idle processes: the profile (store), nothing (skip) or a single sample without a stack (marker). The series of skipped profiles are still listed in the series stats."`
+
+	MaxStacks int `default:"0" help:"Maximum number of distinct stacks stored per profile. The least significant samples of profiles exceeding it are aggregated into a single \"other\" stack. Zero disables the limit."`
+
+	Middlewares []string `help:"Names of the registered ingest middlewares to run on written profiles, in order."`
+
+	RawProfileWindow time.Duration `default:"0" help:"Keep the raw pprof of the first profile written for every series in each window of this length in object storage, eg. 1h, so the original can be downloaded. Zero disables keeping raw profiles."`
+
+	LastProfiles int `default:"0" help:"Number of the most recent raw profiles kept for every series in the storage path, eg. to retrieve the last heap profiles of a target that ran out of memory at /api/profiles/last. They survive restarts. Zero disables keeping them."`
+
+	KafkaBrokers       []string `help:"Kafka brokers to consume profiles from the topics of --ingest-kafka-topics. Empty disables consuming from Kafka."`
+	KafkaTopics        []string `help:"Kafka topics to consume profiles from. The value of a record is a pprof profile, its headers are the labels of the series, including __name__."`
+	KafkaConsumerGroup string   `default:"parca" help:"Kafka consumer group the offsets of the consumed records are committed for, once their profile is written."`
+
+	NATSURL      string `default:"" help:"URL of the NATS server to consume profiles from the JetStream stream of --ingest-nats-stream. Empty disables consuming from NATS."`
+	NATSStream   string `default:"" help:"JetStream stream to consume profiles from. The data of a message is a pprof profile, its headers are the labels of the series, including __name__."`
+	NATSConsumer string `default:"parca" help:"Name of the durable JetStream consumer the messages are acknowledged for, once their profile is written."`
 }
 
 type FlagsSymbolizer struct {
 	DemangleMode  string `default:"simple" help:"Mode to demangle C++ symbols. Default mode is simplified: no parameters, no templates, no return type" enum:"simple,full,none,templates"`
 	NumberOfTries int    `default:"3" help:"Number of tries to attempt to symbolize an unsybolized location"`
+	Concurrency   int    `default:"4" help:"Number of build IDs whose locations are symbolized concurrently per query."`
+
+	DebuginfoSections bool `default:"false" help:"Fetch only the sections of uploaded debuginfos needed for symbolization with ranged reads, instead of whole files."`
 }
 
 // FlagsDebuginfo configures the Parca Debuginfo client.
@@ -161,6 +240,13 @@ type FlagsDebuginfo struct {
 	UploadMaxSize     int64         `default:"1000000000" help:"Maximum size of debuginfo upload in bytes."`
 	UploadMaxDuration time.Duration `default:"15m" help:"Maximum duration of debuginfo upload."`
 	UploadsSignedURL  bool          `default:"false" help:"Whether to use signed URLs for debuginfo uploads."`
+
+	TenantQuota  int64             `default:"0" help:"Maximum bytes of uploaded debuginfos stored per tenant, 0 for no limit."`
+	TenantQuotas map[string]int64  `help:"Maximum bytes of uploaded debuginfos stored for specific tenants, overriding the default quota."`
+	TenantTokens map[string]string `help:"Bearer tokens authenticating debuginfo uploads, mapped to the tenant the uploads are accounted to. Without tokens all uploads are accounted to the default tenant."`
+
+	GCInterval    time.Duration `default:"0" help:"Interval in which uploaded debuginfos not referenced by any stored profile are deleted, 0 to disable."`
+	GCGracePeriod time.Duration `default:"168h" help:"Time debuginfos are kept after the last stored profile referencing them was deleted, and after they were uploaded."`
 }
 
 // FlagsDebuginfod configures the Parca Debuginfo daemon / server.
@@ -169,6 +255,13 @@ type FlagsDebuginfod struct {
 	HTTPRequestTimeout time.Duration `default:"5m" help:"Timeout duration for HTTP request to upstream debuginfod server. Defaults to 5m"`
 }
 
+// FlagsCost configures the rates of the cost reports.
+type FlagsCost struct {
+	CoreHour float64 `default:"0" help:"Price of using one CPU core for an hour in cost reports."`
+	GiBHour  float64 `default:"0" help:"Price of using a GiB of memory for an hour in cost reports."`
+	Currency string  `default:"USD" help:"Currency of the prices in cost reports."`
+}
+
 // FlagsHidden contains hidden flags intended only for debugging or experimental features.
 type FlagsHidden struct {
 	DebugNormalizeAddresses bool `kong:"help='Normalize sampled addresses.',default='true',hidden=''"`
@@ -177,11 +270,38 @@ type FlagsHidden struct {
 	IcebergStorage bool `kong:"help='Use iceberg storage for profile storage. Requires enable-persistence flag.',default='false',hidden=''"`
 }
 
+type runOptions struct {
+	logLevels *LogLevels
+}
+
+type RunOption func(*runOptions)
+
+// WithLogLevels serves the log levels the logger was created with, see
+// NewLeveledLogger, so they can be adjusted at runtime.
+func WithLogLevels(levels *LogLevels) RunOption {
+	return func(o *runOptions) {
+		o.logLevels = levels
+	}
+}
+
 // Run the parca server.
-func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags *Flags, version string) error {
+func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags *Flags, version string, runOpts ...RunOption) error {
+	options := &runOptions{}
+	for _, opt := range runOpts {
+		opt(options)
+	}
+
 	goruntime.SetBlockProfileRate(flags.BlockProfileRate)
 	goruntime.SetMutexProfileFraction(flags.MutexProfileFraction)
 
+	if flags.Storage.MaxMemory > 0 {
+		if _, ok := os.LookupEnv("GOMEMLIMIT"); ok {
+			level.Warn(logger).Log("msg", "GOMEMLIMIT is set and takes precedence over --storage-max-memory for the Go runtime")
+		} else {
+			debug.SetMemoryLimit(flags.Storage.MaxMemory)
+		}
+	}
+
 	// Initialize tracing.
 	var (
 		exporter       tracer.Exporter
@@ -270,7 +390,8 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 		badgerOptions = badger.DefaultOptions("").WithInMemory(true)
 	}
 
-	badgerOptions = badgerOptions.WithLogger(&badgerlogger.BadgerLogger{Logger: logger})
+	storageLogger := log.With(logger, "component", LogComponentStorage)
+	badgerOptions = badgerOptions.WithLogger(&badgerlogger.BadgerLogger{Logger: storageLogger})
 	db, err := badger.Open(badgerOptions)
 	if err != nil {
 		level.Error(logger).Log("msg", "failed to open badger database for metastore", "err", err)
@@ -279,11 +400,14 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 
 	frostdbOptions := []frostdb.Option{
 		frostdb.WithActiveMemorySize(flags.Storage.ActiveMemory),
-		frostdb.WithLogger(logger),
+		frostdb.WithLogger(storageLogger),
 		frostdb.WithRegistry(reg),
 		frostdb.WithTracer(tracerProvider.Tracer("frostdb")),
 	}
 
+	// blocksBucket is the bucket frostdb persists blocks to, through the
+	// block cache if enabled.
+	var blocksBucket objstore.Bucket
 	if flags.EnablePersistence {
 		blocksDirectory := "blocks"
 		prefixedBucket := objstore.NewPrefixedBucket(bucket, blocksDirectory)
@@ -310,7 +434,16 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 				return err
 			}
 		} else {
-			store = frostdb.NewDefaultObjstoreBucket(prefixedBucket)
+			var blocks objstore.Bucket = prefixedBucket
+			if flags.Storage.BlockCacheSize > 0 {
+				blocks, err = parcacol.NewBlockCache(storageLogger, reg, prefixedBucket, filepath.Join(flags.Storage.Path, "block-cache"), flags.Storage.BlockCacheSize)
+				if err != nil {
+					level.Error(logger).Log("msg", "failed to initialize block cache", "err", err)
+					return err
+				}
+			}
+			store = frostdb.NewDefaultObjstoreBucket(blocks)
+			blocksBucket = blocks
 		}
 		frostdbOptions = append(
 			frostdbOptions,
@@ -349,6 +482,10 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 	}
 
 	def := profile.SchemaDefinition()
+	if err := profile.SetCompression(def, flags.Storage.Compression); err != nil {
+		level.Error(logger).Log("msg", "set storage compression", "err", err)
+		return err
+	}
 	table, err := colDB.Table("stacktraces",
 		frostdb.NewTableConfig(
 			def,
@@ -365,6 +502,24 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 		return err
 	}
 
+	metadataDef := profile.MetadataSchemaDefinition()
+	if err := profile.SetCompression(metadataDef, flags.Storage.Compression); err != nil {
+		level.Error(logger).Log("msg", "set storage compression", "err", err)
+		return err
+	}
+	metadataTable, err := colDB.Table(profile.MetadataTableName,
+		frostdb.NewTableConfig(metadataDef),
+	)
+	if err != nil {
+		level.Error(logger).Log("msg", "create metadata table", "err", err)
+		return err
+	}
+	metadataSchema, err := dynparquet.SchemaFromDefinition(metadataDef)
+	if err != nil {
+		level.Error(logger).Log("msg", "metadata schema from definition", "err", err)
+		return err
+	}
+
 	var debuginfodClients debuginfo.DebuginfodClients = debuginfo.NopDebuginfodClients{}
 	if len(flags.Debuginfod.UpstreamServers) > 0 {
 		debuginfodClients = debuginfo.NewDebuginfodClients(
@@ -381,6 +536,15 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 	debuginfoBucket := objstore.NewPrefixedBucket(bucket, "debuginfo")
 	prefixedSignedRequestsClient := signedrequests.NewPrefixedClient(signedRequestsClient, "debuginfo")
 	debuginfoMetadata := debuginfo.NewObjectStoreMetadata(logger, debuginfoBucket)
+	debuginfoUsage := debuginfo.NewUsage(logger, reg, debuginfoBucket, debuginfo.Quotas{
+		Default: flags.Debuginfo.TenantQuota,
+		Tenants: flags.Debuginfo.TenantQuotas,
+		Tokens:  flags.Debuginfo.TenantTokens,
+	})
+	if err := debuginfoUsage.Load(ctx); err != nil {
+		level.Error(logger).Log("msg", "failed to load debuginfo usage", "err", err)
+		return err
+	}
 	dbginfo, err := debuginfo.NewStore(
 		tracerProvider.Tracer("debuginfo"),
 		logger,
@@ -393,40 +557,201 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 		},
 		flags.Debuginfo.UploadMaxDuration,
 		flags.Debuginfo.UploadMaxSize,
+		debuginfoUsage,
 	)
 	if err != nil {
 		level.Error(logger).Log("msg", "failed to initialize debug info store", "err", err)
 		return err
 	}
 
-	ingester := ingester.NewIngester(logger, table)
+	var frameRules []*config.FrameTransformRule
+	if cfg.Symbolizer != nil {
+		frameRules = cfg.Symbolizer.FrameTransforms
+	}
+	framePipeline, err := symbolizer.NewFramePipeline(frameRules)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to initialize frame transform pipeline", "err", err)
+		return err
+	}
+
+	engine := query.NewEngine(
+		memory.DefaultAllocator,
+		colDB.TableProvider(),
+		query.WithTracer(tracerProvider.Tracer("query-engine")),
+	)
+
+	tombstones, err := parcacol.NewTombstones(flags.Storage.TombstonesPath, flags.Storage.UndeleteGracePeriod)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to load tombstones", "err", err)
+		return err
+	}
+
+	var (
+		rawRetention time.Duration
+		tiers        []parcacol.Tier
+		tierPolicy   = parcacol.TierPolicyStart
+		downsamplers []*parcacol.Downsampler
+		rollups      []*parcacol.Rollup
+		// snapshotTables are the tables included in storage snapshots.
+		snapshotTables = map[string]ingester.Ingester{}
+	)
+	if cfg.Storage != nil {
+		rawRetention = time.Duration(cfg.Storage.RawRetention)
+		if cfg.Storage.QueryPolicy != "" {
+			tierPolicy = parcacol.TierPolicy(cfg.Storage.QueryPolicy)
+		}
+
+		source := "stacktraces"
+		for _, tc := range cfg.Storage.Tiers {
+			tier := parcacol.Tier{
+				Table:      parcacol.TierTableName("stacktraces", time.Duration(tc.Resolution)),
+				Resolution: time.Duration(tc.Resolution),
+				Retention:  time.Duration(tc.Retention),
+			}
+			tierTable, err := colDB.Table(tier.Table,
+				frostdb.NewTableConfig(
+					def,
+					frostdb.WithRowGroupSize(flags.Storage.RowGroupSize),
+				),
+			)
+			if err != nil {
+				level.Error(logger).Log("msg", "create tier table", "tier", tier.Table, "err", err)
+				return err
+			}
+
+			tierIngester := ingester.NewIngester(storageLogger, tierTable)
+			snapshotTables[tier.Table] = tierIngester
+			tiers = append(tiers, tier)
+			downsamplers = append(downsamplers, parcacol.NewDownsampler(
+				storageLogger,
+				reg,
+				tracerProvider.Tracer("downsampler"),
+				engine,
+				source,
+				tier,
+				tierIngester,
+				schema,
+				memory.DefaultAllocator,
+				tombstones,
+				watermark(flags.Storage.WatermarksDir, "downsample_"+tier.Table),
+				flags.Storage.DownsampleDelay,
+			))
+			// Every tier is produced from the previous, finer one.
+			source = tier.Table
+		}
+	}
+
+	rollupRules, err := getRollupRules(cfg)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to configure rollups", "err", err)
+		return err
+	}
+	for _, rule := range rollupRules {
+		rollups = append(rollups, parcacol.NewRollup(
+			storageLogger,
+			reg,
+			tracerProvider.Tracer("rollup"),
+			engine,
+			"stacktraces",
+			rule,
+			ingester.NewIngester(storageLogger, table),
+			schema,
+			memory.DefaultAllocator,
+			tombstones,
+			watermark(flags.Storage.WatermarksDir, "rollup_"+rule.Name),
+			flags.Storage.DownsampleDelay,
+		))
+	}
+
+	ingestMiddlewares, err := profilestore.IngestMiddlewares(flags.Ingest.Middlewares...)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to configure ingest middlewares", "err", err)
+		return err
+	}
+
+	samplingRules, err := getSamplingRules(cfg)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to configure sampling", "err", err)
+		return err
+	}
+
+	metadataIngester := ingester.NewIngester(storageLogger, metadataTable)
+	ingester := ingester.NewIngester(storageLogger, table)
+	snapshotTables["stacktraces"] = ingester
+	snapshotTables[profile.MetadataTableName] = metadataIngester
+
+	// Queries pin the blocks they may read until they are done, so that
+	// neither the retentions nor the block admin delete them.
+	snapshotPins := parcacol.NewSnapshotPins()
+
+	// Blocks are only described when they are persisted by frostdb itself.
+	// They are rewritten through the block cache, so it never serves a
+	// stale copy.
+	var blocks *parcacol.Blocks
+	if blocksBucket != nil {
+		blocks = parcacol.NewBlocks(storageLogger, blocksBucket, objstore.NewPrefixedBucket(bucket, "blocks-meta"), snapshotPins)
+	}
+
+	snapshots := parcacol.NewSnapshots(storageLogger, engine, memory.DefaultAllocator, flags.Storage.SnapshotDir, snapshotTables)
+	if flags.Storage.RestoreSnapshot != "" {
+		if _, err := snapshots.Restore(ctx, flags.Storage.RestoreSnapshot); err != nil {
+			level.Error(logger).Log("msg", "failed to restore storage snapshot", "err", err)
+			return err
+		}
+	}
+	queryLogger := log.With(logger, "component", LogComponentQuery)
+	symbolizerLogger := log.With(logger, "component", LogComponentSymbolizer)
 	querier := parcacol.NewQuerier(
-		logger,
+		queryLogger,
 		tracerProvider.Tracer("querier"),
-		query.NewEngine(
-			memory.DefaultAllocator,
-			colDB.TableProvider(),
-			query.WithTracer(tracerProvider.Tracer("query-engine")),
-		),
+		engine,
 		"stacktraces",
 		symbolizer.New(
-			logger,
+			symbolizerLogger,
 			debuginfoMetadata,
 			symbolizer.NewBadgerCache(db),
-			debuginfo.NewFetcher(debuginfodClients, debuginfoBucket),
+			debuginfo.NewFetcher(debuginfodClients, debuginfoBucket, debuginfoUsage),
 			flags.Debuginfo.CacheDir,
 			symbolizer.WithDemangleMode(flags.Symbolizer.DemangleMode),
+			symbolizer.WithFramePipeline(framePipeline),
+			symbolizer.WithDebuginfoSections(flags.Symbolizer.DebuginfoSections),
 		),
 		memory.DefaultAllocator,
+		parcacol.WithRawRetention(rawRetention),
+		parcacol.WithTiers(tiers),
+		parcacol.WithTierPolicy(tierPolicy),
+		parcacol.WithTombstones(tombstones),
+		parcacol.WithSnapshotPins(snapshotPins),
+		parcacol.WithSymbolizationConcurrency(flags.Symbolizer.Concurrency),
+		parcacol.WithFramePipeline(framePipeline),
 	)
 
 	s := profilestore.NewProfileColumnStore(
 		reg,
-		logger,
+		storageLogger,
 		tracerProvider.Tracer("profilestore"),
 		ingester,
 		schema,
 		memory.DefaultAllocator,
+		profilestore.WithDeduplication(flags.Ingest.DedupWindow, flags.Ingest.DedupMaxEntries),
+		profilestore.WithTimestampPolicy(profilestore.TimestampPolicy(flags.Ingest.TimestampPolicy), flags.Ingest.MaxClockSkew),
+		profilestore.WithTimeBounds(flags.Ingest.RejectFuture, flags.Ingest.RejectPast),
+		profilestore.WithSeriesCreationLimit(flags.Ingest.SeriesCreationRate, flags.Ingest.SeriesCreationBurst, flags.Ingest.SeriesCreationQueue),
+		profilestore.WithSeriesLimit(flags.Ingest.MaxSeries),
+		profilestore.WithMemoryLimit(flags.Storage.MaxMemory, table.EnsureCompaction),
+		profilestore.WithProfileSizeLimit(profilestore.ProfileSizeLimit{
+			MaxBytes: flags.Ingest.MaxProfileSize,
+			Mode:     profilestore.ProfileSizeMode(flags.Ingest.ProfileSizeMode),
+			TopK:     flags.Ingest.ProfileSizeTopK,
+		}),
+		profilestore.WithIngestMiddlewares(ingestMiddlewares...),
+		profilestore.WithSampling(samplingRules...),
+		profilestore.WithProfileMetadata(metadataIngester, metadataSchema),
+		profilestore.WithStackLimit(flags.Ingest.MaxStacks),
+		profilestore.WithEmptyProfiles(profilestore.EmptyProfileMode(flags.Ingest.EmptyProfiles)),
+		profilestore.WithRawProfileExemplars(objstore.NewPrefixedBucket(bucket, "raw-profiles"), flags.Ingest.RawProfileWindow),
+		profilestore.WithLastProfiles(filepath.Join(flags.Storage.Path, "last-profiles"), flags.Ingest.LastProfiles),
+		profilestore.WithDeletedProfiles(tombstones.Deleted),
 	)
 
 	propagators := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
@@ -442,8 +767,11 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 		return fmt.Errorf("failed to create gRPC connection to ProfileShareServer: %s, %w", flags.ProfileShareServer, err)
 	}
 
+	binaryCatalog := queryservice.NewBinaryCatalog(queryLogger, querier, debuginfoMetadata)
+	debuginfoGC := debuginfo.NewGC(logger, reg, debuginfoBucket, debuginfoMetadata, debuginfoUsage, querier, flags.Debuginfo.GCGracePeriod)
+
 	q := queryservice.NewColumnQueryAPI(
-		logger,
+		queryLogger,
 		tracerProvider.Tracer("query-service"),
 		sharepb.NewShareServiceClient(conn),
 		querier,
@@ -458,6 +786,36 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 		),
 	)
 
+	var scalarSource queryservice.ScalarSource
+	if flags.QueryNormalizationURL != "" {
+		scalarSource = queryservice.NewPrometheusScalarSource(http.DefaultClient, strings.TrimSuffix(flags.QueryNormalizationURL, "/"))
+	}
+
+	jobManager := jobs.NewManager(
+		log.With(logger, "component", "jobs"),
+		reg,
+		objstore.NewPrefixedBucket(bucket, "jobs"),
+	)
+	profileExporter := queryservice.NewExporter(
+		log.With(logger, "component", "profile_exporter"),
+		objstore.NewPrefixedBucket(bucket, "exports"),
+		q,
+	)
+	jobManager.Register(queryservice.ExportJobKind, profileExporter)
+
+	var scheduler *queryservice.Scheduler
+	if len(cfg.ScheduledQueries) > 0 {
+		scheduler = queryservice.NewScheduler(
+			log.With(logger, "component", "scheduled_queries"),
+			reg,
+			q,
+			getScheduledQueries(cfg, objstore.NewPrefixedBucket(bucket, "query-results")),
+		)
+	}
+	jobManager.Register("compaction", jobs.OneShot(func(context.Context) error {
+		return table.EnsureCompaction()
+	}))
+
 	t := telemetryservice.NewTelemetry(
 		logger,
 	)
@@ -470,13 +828,14 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	discoveryManager := discovery.NewManager(ctx, logger, reg, sdMetrics)
+	scrapeLogger := log.With(logger, "component", LogComponentScrape)
+	discoveryManager := discovery.NewManager(ctx, scrapeLogger, reg, sdMetrics)
 	if err := discoveryManager.ApplyConfig(getDiscoveryConfigs(cfg.ScrapeConfigs)); err != nil {
 		level.Error(logger).Log("msg", "failed to apply discovery configs", "err", err)
 		return err
 	}
 
-	m := scrape.NewManager(logger, reg, s, cfg.ScrapeConfigs, labels.Labels{})
+	m := scrape.NewManager(scrapeLogger, reg, s, cfg.ScrapeConfigs, labels.Labels{})
 	if err := m.ApplyConfig(cfg.ScrapeConfigs); err != nil {
 		level.Error(logger).Log("msg", "failed to apply scrape configs", "err", err)
 		return err
@@ -495,6 +854,17 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 				return m.ApplyConfig(cfg.ScrapeConfigs)
 			},
 		},
+		{
+			Name: "ingest_sampling",
+			Reloader: func(cfg *config.Config) error {
+				rules, err := getSamplingRules(cfg)
+				if err != nil {
+					return err
+				}
+				s.SetSamplingRules(rules)
+				return nil
+			},
+		},
 	}
 
 	cfgReloader, err := config.NewConfigReloader(logger, reg, flags.ConfigPath, reloaders)
@@ -529,6 +899,246 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 		})
 	}
 
+	{
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				var err error
+
+				pprof.Do(ctx, pprof.Labels("parca_component", "jobs"), func(ctx context.Context) {
+					err = jobManager.Run(ctx)
+				})
+
+				return err
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "job manager exiting")
+				cancel()
+			},
+		)
+	}
+
+	{
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				var err error
+
+				pprof.Do(ctx, pprof.Labels("parca_component", "debuginfo_usage"), func(ctx context.Context) {
+					err = debuginfoUsage.Run(ctx, time.Minute)
+				})
+
+				return err
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "debuginfo usage exiting")
+				cancel()
+			},
+		)
+	}
+
+	if flags.Storage.RetentionSize > 0 {
+		switch {
+		case !flags.EnablePersistence:
+			level.Warn(logger).Log("msg", "storage retention size has no effect without persistence")
+		case flags.Hidden.IcebergStorage:
+			level.Warn(logger).Log("msg", "storage retention size is not supported with iceberg storage")
+		default:
+			sizeRetention := parcacol.NewSizeRetention(storageLogger, reg, objstore.NewPrefixedBucket(bucket, "blocks"), flags.Storage.RetentionSize, parcacol.WithRetainedBlocks(blocks), parcacol.WithPinnedBlocks(snapshotPins))
+			ctx, cancel := context.WithCancel(ctx)
+			gr.Add(
+				func() error {
+					var err error
+
+					pprof.Do(ctx, pprof.Labels("parca_component", "size_retention"), func(ctx context.Context) {
+						err = sizeRetention.Run(ctx, flags.Storage.RetentionInterval)
+					})
+
+					return err
+				},
+				func(_ error) {
+					level.Debug(logger).Log("msg", "size retention exiting")
+					cancel()
+				},
+			)
+		}
+	}
+
+	if tableRetention := parcacol.NewTableRetention(storageLogger, reg, blocks, "stacktraces", rawRetention, tiers); tableRetention.Enabled() {
+		if blocks == nil {
+			// Without persistence the tables are bounded by the active
+			// memory size only.
+			level.Warn(logger).Log("msg", "storage raw and tier retentions only select the table queries read without persistence")
+		} else {
+			ctx, cancel := context.WithCancel(ctx)
+			gr.Add(
+				func() error {
+					var err error
+
+					pprof.Do(ctx, pprof.Labels("parca_component", "table_retention"), func(ctx context.Context) {
+						err = tableRetention.Run(ctx, flags.Storage.RetentionInterval)
+					})
+
+					return err
+				},
+				func(_ error) {
+					level.Debug(logger).Log("msg", "table retention exiting")
+					cancel()
+				},
+			)
+		}
+	}
+
+	if blocks != nil {
+		reclaimer := parcacol.NewReclaimer(storageLogger, reg, engine, blocks, tombstones, "stacktraces", tiers, rollups)
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				var err error
+
+				pprof.Do(ctx, pprof.Labels("parca_component", "tombstone_reclaim"), func(ctx context.Context) {
+					err = reclaimer.Run(ctx, flags.Storage.RetentionInterval)
+				})
+
+				return err
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "tombstone reclaim exiting")
+				cancel()
+			},
+		)
+	}
+
+	if flags.Debuginfo.GCInterval > 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				var err error
+
+				pprof.Do(ctx, pprof.Labels("parca_component", "debuginfo_gc"), func(ctx context.Context) {
+					err = debuginfoGC.Run(ctx, flags.Debuginfo.GCInterval)
+				})
+
+				return err
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "debuginfo gc exiting")
+				cancel()
+			},
+		)
+	}
+
+	for _, d := range downsamplers {
+		d := d
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				var err error
+
+				pprof.Do(ctx, pprof.Labels("parca_component", "downsampler"), func(ctx context.Context) {
+					err = d.Run(ctx)
+				})
+
+				return err
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "downsampler exiting")
+				cancel()
+			},
+		)
+	}
+
+	if scheduler != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				return scheduler.Run(ctx)
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "scheduled queries exiting")
+				cancel()
+			},
+		)
+	}
+
+	for _, r := range rollups {
+		r := r
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				var err error
+
+				pprof.Do(ctx, pprof.Labels("parca_component", "rollup"), func(ctx context.Context) {
+					err = r.Run(ctx)
+				})
+
+				return err
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "rollup exiting")
+				cancel()
+			},
+		)
+	}
+
+	if len(flags.Ingest.KafkaBrokers) > 0 {
+		if len(flags.Ingest.KafkaTopics) == 0 {
+			return errors.New("consuming from Kafka requires --ingest-kafka-topics")
+		}
+		client, err := kafka.NewClient(flags.Ingest.KafkaBrokers, flags.Ingest.KafkaTopics, flags.Ingest.KafkaConsumerGroup)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create Kafka client", "err", err)
+			return err
+		}
+		src := kafka.NewSource(log.With(storageLogger, "source", "kafka"), client)
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				defer client.Close()
+
+				var err error
+				pprof.Do(ctx, pprof.Labels("parca_component", "kafka_source"), func(ctx context.Context) {
+					err = s.Consume(ctx, "kafka", src)
+				})
+
+				return err
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "kafka source exiting")
+				cancel()
+			},
+		)
+	}
+
+	if flags.Ingest.NATSURL != "" {
+		if flags.Ingest.NATSStream == "" {
+			return errors.New("consuming from NATS requires --ingest-nats-stream")
+		}
+		consumer, err := nats.NewConsumer(ctx, flags.Ingest.NATSURL, flags.Ingest.NATSStream, flags.Ingest.NATSConsumer)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create NATS consumer", "err", err)
+			return err
+		}
+		src := nats.NewSource(consumer.Messages())
+		ctx, cancel := context.WithCancel(ctx)
+		gr.Add(
+			func() error {
+				defer consumer.Close()
+
+				var err error
+				pprof.Do(ctx, pprof.Labels("parca_component", "nats_source"), func(ctx context.Context) {
+					err = s.Consume(ctx, "nats", src)
+				})
+
+				return err
+			},
+			func(_ error) {
+				level.Debug(logger).Log("msg", "nats source exiting")
+				cancel()
+			},
+		)
+	}
+
 	gr.Add(
 		func() error {
 			var err error
@@ -574,7 +1184,44 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 			cancel()
 		},
 	)
-	parcaserver := server.NewServer(reg, version)
+	slowQueryLog := queryservice.NewSlowQueryLog(
+		log.With(logger, "component", "slow_query_log"),
+		reg,
+		flags.Logs.SlowQueryThreshold,
+		flags.Logs.SlowQueryLogSize,
+	)
+	queryMetrics := queryservice.NewQueryMetrics(reg)
+	serverOpts := []server.Option{
+		server.WithAccessLog(server.NewAccessLog(log.With(logger, "component", "access_log"), flags.Logs.Access)),
+		server.WithUnaryInterceptor(slowQueryLog.UnaryServerInterceptor()),
+		server.WithUnaryInterceptor(queryMetrics.UnaryServerInterceptor()),
+	}
+	// The query endpoints served over HTTP next to the query service are
+	// instrumented the same way.
+	queryInterceptors := []queryservice.HandlerInterceptor{
+		slowQueryLog.HandlerInterceptor(),
+		queryMetrics.HandlerInterceptor(),
+	}
+	if flags.EnablePersistence {
+		// Queries in flight are tracked on disk to report them after a crash.
+		activeQueries, err := queryservice.NewActiveQueryTracker(
+			queryLogger,
+			reg,
+			flags.Storage.Path,
+			queryservice.DefaultActiveQuerySlots,
+		)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create active query tracker", "err", err)
+			return err
+		}
+		defer activeQueries.Close()
+		serverOpts = append(serverOpts, server.WithUnaryInterceptor(activeQueries.UnaryServerInterceptor()))
+		queryInterceptors = append(queryInterceptors, activeQueries.HandlerInterceptor())
+	}
+	queryHandler := func(method string, h runtime.HandlerFunc) runtime.HandlerFunc {
+		return queryservice.InterceptHandler(method, h, queryInterceptors...)
+	}
+	parcaserver := server.NewServer(reg, version, serverOpts...)
 	gr.Add(
 		func() error {
 			var err error
@@ -593,8 +1240,10 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 						debuginfopb.RegisterDebuginfoServiceServer(srv, dbginfo)
 						profilestorepb.RegisterProfileStoreServiceServer(srv, s)
 						profilestorepb.RegisterAgentsServiceServer(srv, s)
+						profilestorepb.RegisterSeriesStatsServiceServer(srv, s)
 						otelgrpcprofilingpb.RegisterProfilesServiceServer(srv, s)
 						querypb.RegisterQueryServiceServer(srv, q)
+						querypb.RegisterBinaryServiceServer(srv, binaryCatalog)
 						scrapepb.RegisterScrapeServiceServer(srv, m)
 						telemetry.RegisterTelemetryServiceServer(srv, t)
 
@@ -610,10 +1259,152 @@ func Run(ctx context.Context, logger log.Logger, reg *prometheus.Registry, flags
 							return err
 						}
 
+						if err := profilestorepb.RegisterSeriesStatsServiceHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+							return err
+						}
+
 						if err := querypb.RegisterQueryServiceHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
 							return err
 						}
 
+						if err := querypb.RegisterBinaryServiceHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodPost, jobs.Path, jobManager.SubmitHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, queryservice.SlowQueriesPath, slowQueryLog.SlowQueriesHandler()); err != nil {
+							return err
+						}
+
+						if options.logLevels != nil {
+							if err := mux.HandlePath(http.MethodGet, LogLevelsPath, options.logLevels.LogLevelsHandler()); err != nil {
+								return err
+							}
+						}
+
+						if err := mux.HandlePath(http.MethodGet, jobs.Path, jobManager.ListHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, jobs.Path+"/{id}", jobManager.GetHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodPost, jobs.Path+"/{id}/cancel", jobManager.CancelHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, queryservice.ExportsPath+"/{id}/parts/{part}", profileExporter.PartHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, queryservice.QueryFunctionsPath, queryservice.QueryFunctionsHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodPost, queryservice.QueryFunctionsPath+"/{name}", queryHandler(queryservice.QueryFunctionsPath+"/{name}", q.EvalQueryFunctionHandler())); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodPost, queryservice.QueryNormalizedPath, queryHandler(queryservice.QueryNormalizedPath, q.QueryNormalizedHandler(scalarSource))); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, queryservice.CompareMatrixPath, queryHandler(queryservice.CompareMatrixPath, q.CompareMatrixHandler())); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, queryservice.CostReportPath, queryHandler(queryservice.CostReportPath, q.CostReportHandler(queryservice.CostRates{
+							CoreHour: flags.Cost.CoreHour,
+							GiBHour:  flags.Cost.GiBHour,
+							Currency: flags.Cost.Currency,
+						}))); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, parcacol.DeletePath, tombstones.ListHandler()); err != nil {
+							return err
+						}
+
+						if blocks != nil {
+							if err := mux.HandlePath(http.MethodGet, parcacol.BlocksPath, blocks.ListHandler()); err != nil {
+								return err
+							}
+						}
+
+						if flags.Storage.SnapshotDir != "" {
+							if err := mux.HandlePath(http.MethodGet, parcacol.SnapshotsPath, snapshots.ListHandler()); err != nil {
+								return err
+							}
+						}
+
+						// The admin endpoints are unauthenticated, so they
+						// are only served if explicitly enabled.
+						if flags.EnableAdminAPI {
+							if options.logLevels != nil {
+								for _, method := range []string{http.MethodPut, http.MethodPost} {
+									if err := mux.HandlePath(method, LogLevelsPath, options.logLevels.LogLevelsHandler()); err != nil {
+										return err
+									}
+								}
+							}
+
+							if err := mux.HandlePath(http.MethodPost, parcacol.DeletePath, tombstones.DeleteHandler()); err != nil {
+								return err
+							}
+
+							if err := mux.HandlePath(http.MethodPost, parcacol.UndeletePath, tombstones.UndeleteHandler()); err != nil {
+								return err
+							}
+
+							if blocks != nil {
+								if err := mux.HandlePath(http.MethodDelete, parcacol.BlocksPath, blocks.DeleteHandler()); err != nil {
+									return err
+								}
+
+								if err := mux.HandlePath(http.MethodPost, parcacol.BlockAnnotationsPath, blocks.AnnotateHandler()); err != nil {
+									return err
+								}
+
+								if err := mux.HandlePath(http.MethodPost, parcacol.BlockRetainPath, blocks.RetainHandler()); err != nil {
+									return err
+								}
+							}
+
+							if flags.Storage.SnapshotDir != "" {
+								if err := mux.HandlePath(http.MethodPost, parcacol.SnapshotsPath, snapshots.SnapshotHandler()); err != nil {
+									return err
+								}
+							}
+						}
+
+						if err := mux.HandlePath(http.MethodGet, debuginfo.LargestDebuginfosPath, debuginfoUsage.LargestHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, debuginfo.GCPath, debuginfoGC.Handler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, queryservice.ImagesPath, queryHandler(queryservice.ImagesPath, binaryCatalog.ImagesHandler())); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, profilestore.SeriesErrorsPath, s.SeriesErrorsHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, profilestore.RawProfilesPath, s.RawProfilesHandler()); err != nil {
+							return err
+						}
+
+						if err := mux.HandlePath(http.MethodGet, profilestore.LastProfilesPath, s.LastProfilesHandler()); err != nil {
+							return err
+						}
+
 						if err := scrapepb.RegisterScrapeServiceHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
 							return err
 						}
@@ -738,7 +1529,8 @@ func runForwarder(
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	discoveryManager := discovery.NewManager(ctx, logger, reg, sdMetrics)
+	scrapeLogger := log.With(logger, "component", LogComponentScrape)
+	discoveryManager := discovery.NewManager(ctx, scrapeLogger, reg, sdMetrics)
 	if err := discoveryManager.ApplyConfig(getDiscoveryConfigs(cfg.ScrapeConfigs)); err != nil {
 		level.Error(logger).Log("msg", "failed to apply discovery configs", "err", err)
 		return err
@@ -746,7 +1538,7 @@ func runForwarder(
 
 	externalLabels := labels.FromMap(flags.ExternalLabel)
 
-	m := scrape.NewManager(logger, reg, store, cfg.ScrapeConfigs, externalLabels)
+	m := scrape.NewManager(scrapeLogger, reg, store, cfg.ScrapeConfigs, externalLabels)
 	if err := m.ApplyConfig(cfg.ScrapeConfigs); err != nil {
 		level.Error(logger).Log("msg", "failed to apply scrape configs", "err", err)
 		return err
@@ -882,6 +1674,100 @@ func (t *perRequestBearerToken) RequireTransportSecurity() bool {
 	return !t.insecure
 }
 
+func getSamplingRules(cfg *config.Config) ([]profilestore.SamplingRule, error) {
+	if cfg.Ingest == nil {
+		return nil, nil
+	}
+
+	rules := make([]profilestore.SamplingRule, 0, len(cfg.Ingest.Sampling))
+	for _, c := range cfg.Ingest.Sampling {
+		matchers, err := parser.ParseMetricSelector(c.Matchers)
+		if err != nil {
+			return nil, fmt.Errorf("parse sampling matchers %q: %w", c.Matchers, err)
+		}
+		mode := profilestore.SamplingModeEveryNth
+		if c.Mode != "" {
+			mode = profilestore.SamplingMode(c.Mode)
+		}
+		rules = append(rules, profilestore.SamplingRule{
+			Matchers: matchers,
+			Keep:     c.Keep,
+			Mode:     mode,
+		})
+	}
+	return rules, nil
+}
+
+func getRollupRules(cfg *config.Config) ([]parcacol.RollupRule, error) {
+	if cfg.Storage == nil {
+		return nil, nil
+	}
+
+	rules := make([]parcacol.RollupRule, 0, len(cfg.Storage.Rollups))
+	for _, c := range cfg.Storage.Rollups {
+		if c.Query != "" {
+			qp, err := parcacol.ParseQuery(c.Query)
+			if err != nil {
+				return nil, fmt.Errorf("parse rollup query %q: %w", c.Query, err)
+			}
+			if !qp.Delta {
+				return nil, fmt.Errorf("rollup query %q is not of a delta profile type", c.Query)
+			}
+		}
+
+		var matchers []*labels.Matcher
+		if c.Matchers != "" {
+			var err error
+			matchers, err = parser.ParseMetricSelector(c.Matchers)
+			if err != nil {
+				return nil, fmt.Errorf("parse rollup matchers %q: %w", c.Matchers, err)
+			}
+		}
+		rules = append(rules, parcacol.RollupRule{
+			Name:     c.Name,
+			Query:    c.Query,
+			Matchers: matchers,
+			By:       c.By,
+			Labels:   c.Labels,
+			Interval: time.Duration(c.Interval),
+		})
+	}
+	return rules, nil
+}
+
+// watermark returns the watermark of the named background job kept in the
+// directory, or nil if no directory is configured.
+func watermark(dir, name string) *parcacol.Watermark {
+	if dir == "" {
+		return nil
+	}
+	return parcacol.NewWatermark(filepath.Join(dir, url.PathEscape(name)+".json"))
+}
+
+func getScheduledQueries(cfg *config.Config, bucket objstore.Bucket) []queryservice.ScheduledQuery {
+	queries := make([]queryservice.ScheduledQuery, 0, len(cfg.ScheduledQueries))
+	for _, c := range cfg.ScheduledQueries {
+		q := queryservice.ScheduledQuery{
+			Name:     c.Name,
+			Query:    c.Query,
+			Range:    time.Duration(c.Range),
+			Interval: time.Duration(c.Interval),
+			Limit:    c.Limit,
+			Diff:     c.Diff,
+		}
+		for _, s := range c.Sinks {
+			switch s.Type {
+			case config.QuerySinkWebhook:
+				q.Sinks = append(q.Sinks, queryservice.NewWebhookSink(s.URL, http.DefaultClient))
+			case config.QuerySinkBucket:
+				q.Sinks = append(q.Sinks, queryservice.NewBucketSink(bucket, s.Format))
+			}
+		}
+		queries = append(queries, q)
+	}
+	return queries
+}
+
 func getDiscoveryConfigs(cfgs []*config.ScrapeConfig) map[string]discovery.Configs {
 	c := make(map[string]discovery.Configs)
 	for _, v := range cfgs {
@@ -0,0 +1,432 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parca
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	pprofprofile "github.com/google/pprof/profile"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	querypb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/client"
+)
+
+const (
+	queryOutputTop        = "top"
+	queryOutputTree       = "tree"
+	queryOutputFolded     = "folded"
+	queryOutputPprof      = "pprof"
+	queryOutputSpeedscope = "speedscope"
+)
+
+// QueryCmd queries a Parca server and prints or writes the resulting profile.
+type QueryCmd struct {
+	Selector string `arg:"" help:"Profile selector to query, eg. 'parca_agent:samples:count:cpu:nanoseconds:delta{job=\"parca\"}'."`
+
+	Address string `default:"localhost:7070" help:"gRPC address of the Parca server to query. The --bearer-token, --bearer-token-file, --insecure and --insecure-skip-verify flags apply to it."`
+
+	Single bool          `help:"Query the single profile at --time instead of merging all profiles in the range."`
+	Since  time.Duration `default:"15m" help:"Query the range ending at --end (or now) going back this duration. Ignored if --start is set."`
+	Start  time.Time     `help:"Start of the range to merge (RFC3339)."`
+	End    time.Time     `help:"End of the range to merge (RFC3339). Defaults to now."`
+	Time   time.Time     `help:"Time of the profile to query in single mode (RFC3339)."`
+	Filter string        `help:"Only keep stacks containing a function matching this filter."`
+
+	Output string `default:"top" enum:"top,tree,folded,pprof,speedscope" help:"Output format."`
+	File   string `short:"o" help:"File to write the output to. Defaults to stdout. Required for pprof."`
+	Limit  int    `default:"20" help:"Number of functions to print in the top output."`
+}
+
+// RunQuery runs the query command.
+func RunQuery(ctx context.Context, flags *Flags, stdout io.Writer) error {
+	cmd := &flags.Query
+	if cmd.Output == queryOutputPprof && cmd.File == "" {
+		return errors.New("writing a pprof profile requires --file")
+	}
+
+	c, err := newClientFromFlags(flags, cmd.Address)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	p, err := c.QueryPprof(ctx, cmd.request(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	w := stdout
+	if cmd.File != "" {
+		f, err := os.Create(cmd.File)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch cmd.Output {
+	case queryOutputPprof:
+		return p.Write(w)
+	case queryOutputTree:
+		return WriteTree(w, p)
+	case queryOutputFolded:
+		return WriteFolded(w, p)
+	case queryOutputSpeedscope:
+		return WriteSpeedscope(w, p, cmd.Selector)
+	default:
+		return WriteTop(w, p, cmd.Limit)
+	}
+}
+
+// newClientFromFlags creates a client for the address using the connection
+// flags shared with the forwarder.
+func newClientFromFlags(flags *Flags, address string) (*client.Client, error) {
+	opts := []client.Option{}
+	if flags.Insecure {
+		opts = append(opts, client.WithInsecure())
+	}
+	if flags.InsecureSkipVerify {
+		opts = append(opts, client.WithInsecureSkipVerify())
+	}
+	if flags.BearerToken != "" {
+		opts = append(opts, client.WithBearerToken(flags.BearerToken))
+	}
+	if flags.BearerTokenFile != "" {
+		b, err := os.ReadFile(flags.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bearer token from file: %w", err)
+		}
+		opts = append(opts, client.WithBearerToken(strings.TrimSpace(string(b))))
+	}
+
+	return client.New(address, opts...)
+}
+
+func (cmd *QueryCmd) request(now time.Time) *querypb.QueryRequest {
+	req := &querypb.QueryRequest{}
+	if cmd.Filter != "" {
+		req.Filter = []*querypb.Filter{{
+			Filter: &querypb.Filter_StackFilter{
+				StackFilter: &querypb.StackFilter{
+					Filter: &querypb.StackFilter_FunctionNameStackFilter{
+						FunctionNameStackFilter: &querypb.FunctionNameStackFilter{
+							FunctionToFilter: cmd.Filter,
+						},
+					},
+				},
+			},
+		}}
+	}
+
+	if cmd.Single {
+		t := cmd.Time
+		if t.IsZero() {
+			t = now
+		}
+		req.Mode = querypb.QueryRequest_MODE_SINGLE_UNSPECIFIED
+		req.Options = &querypb.QueryRequest_Single{
+			Single: &querypb.SingleProfile{
+				Query: cmd.Selector,
+				Time:  timestamppb.New(t),
+			},
+		}
+		return req
+	}
+
+	end := cmd.End
+	if end.IsZero() {
+		end = now
+	}
+	start := cmd.Start
+	if start.IsZero() {
+		start = end.Add(-cmd.Since)
+	}
+	req.Mode = querypb.QueryRequest_MODE_MERGE
+	req.Options = &querypb.QueryRequest_Merge{
+		Merge: &querypb.MergeProfile{
+			Query: cmd.Selector,
+			Start: timestamppb.New(start),
+			End:   timestamppb.New(end),
+		},
+	}
+	return req
+}
+
+// frameName returns the name of the line's function, falling back to the
+// address of the location if it is not symbolized.
+func frameName(loc *pprofprofile.Location, line pprofprofile.Line) string {
+	if line.Function != nil && line.Function.Name != "" {
+		return line.Function.Name
+	}
+	return fmt.Sprintf("0x%x", loc.Address)
+}
+
+// stackFrames returns the frames of the sample ordered from the root to the
+// leaf, expanding inlined functions.
+func stackFrames(s *pprofprofile.Sample) []string {
+	frames := []string{}
+	for i := len(s.Location) - 1; i >= 0; i-- {
+		loc := s.Location[i]
+		if len(loc.Line) == 0 {
+			frames = append(frames, frameName(loc, pprofprofile.Line{}))
+			continue
+		}
+		for j := len(loc.Line) - 1; j >= 0; j-- {
+			frames = append(frames, frameName(loc, loc.Line[j]))
+		}
+	}
+	return frames
+}
+
+func sampleValue(s *pprofprofile.Sample) int64 {
+	if len(s.Value) == 0 {
+		return 0
+	}
+	return s.Value[len(s.Value)-1]
+}
+
+func sampleUnit(p *pprofprofile.Profile) string {
+	if len(p.SampleType) == 0 {
+		return ""
+	}
+	return p.SampleType[len(p.SampleType)-1].Unit
+}
+
+// WriteTop writes the functions with the highest flat values.
+func WriteTop(w io.Writer, p *pprofprofile.Profile, limit int) error {
+	type entry struct {
+		name      string
+		flat, cum int64
+	}
+
+	var total int64
+	entries := map[string]*entry{}
+	get := func(name string) *entry {
+		e, ok := entries[name]
+		if !ok {
+			e = &entry{name: name}
+			entries[name] = e
+		}
+		return e
+	}
+
+	for _, s := range p.Sample {
+		v := sampleValue(s)
+		total += v
+
+		frames := stackFrames(s)
+		if len(frames) == 0 {
+			continue
+		}
+		get(frames[len(frames)-1]).flat += v
+
+		seen := map[string]struct{}{}
+		for _, f := range frames {
+			if _, ok := seen[f]; ok {
+				// Only count recursive functions once.
+				continue
+			}
+			seen[f] = struct{}{}
+			get(f).cum += v
+		}
+	}
+
+	list := make([]*entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].flat != list[j].flat {
+			return list[i].flat > list[j].flat
+		}
+		return list[i].name < list[j].name
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+
+	percent := func(v int64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(v) / float64(total) * 100
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Total: %d %s\n", total, sampleUnit(p))
+	fmt.Fprintf(tw, "flat\tflat%%\tcum\tcum%%\t\n")
+	for _, e := range list {
+		fmt.Fprintf(tw, "%d\t%.2f%%\t%d\t%.2f%%\t  %s\n", e.flat, percent(e.flat), e.cum, percent(e.cum), e.name)
+	}
+	return tw.Flush()
+}
+
+// WriteFolded writes the stacks in the folded format understood by
+// flamegraph.pl and other tools: one line per stack with its frames joined by
+// semicolons from the root to the leaf, followed by the value.
+func WriteFolded(w io.Writer, p *pprofprofile.Profile) error {
+	stacks := map[string]int64{}
+	for _, s := range p.Sample {
+		stacks[strings.Join(stackFrames(s), ";")] += sampleValue(s)
+	}
+
+	keys := make([]string, 0, len(stacks))
+	for k := range stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s %d\n", k, stacks[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type treeNode struct {
+	name     string
+	cum      int64
+	children map[string]*treeNode
+}
+
+// WriteTree writes the call tree with the cumulative value of every node,
+// children sorted by their value.
+func WriteTree(w io.Writer, p *pprofprofile.Profile) error {
+	root := &treeNode{name: "root", children: map[string]*treeNode{}}
+	for _, s := range p.Sample {
+		v := sampleValue(s)
+		n := root
+		n.cum += v
+		for _, f := range stackFrames(s) {
+			child, ok := n.children[f]
+			if !ok {
+				child = &treeNode{name: f, children: map[string]*treeNode{}}
+				n.children[f] = child
+			}
+			child.cum += v
+			n = child
+		}
+	}
+
+	var write func(n *treeNode, depth int) error
+	write = func(n *treeNode, depth int) error {
+		if _, err := fmt.Fprintf(w, "%s%d %s\n", strings.Repeat("  ", depth), n.cum, n.name); err != nil {
+			return err
+		}
+
+		children := make([]*treeNode, 0, len(n.children))
+		for _, c := range n.children {
+			children = append(children, c)
+		}
+		sort.Slice(children, func(i, j int) bool {
+			if children[i].cum != children[j].cum {
+				return children[i].cum > children[j].cum
+			}
+			return children[i].name < children[j].name
+		})
+		for _, c := range children {
+			if err := write(c, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return write(root, 0)
+}
+
+type speedscopeFile struct {
+	Schema   string              `json:"$schema"`
+	Shared   speedscopeShared    `json:"shared"`
+	Profiles []speedscopeProfile `json:"profiles"`
+	Name     string              `json:"name"`
+	Exporter string              `json:"exporter"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int64   `json:"startValue"`
+	EndValue   int64   `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int64 `json:"weights"`
+}
+
+func speedscopeUnit(unit string) string {
+	switch unit {
+	case "nanoseconds", "microseconds", "milliseconds", "seconds", "bytes":
+		return unit
+	default:
+		return "none"
+	}
+}
+
+// WriteSpeedscope writes the profile as a sampled profile in the speedscope
+// file format.
+func WriteSpeedscope(w io.Writer, p *pprofprofile.Profile, name string) error {
+	frameIndex := map[string]int{}
+	file := speedscopeFile{
+		Schema:   "https://www.speedscope.app/file-format-schema.json",
+		Name:     name,
+		Exporter: "parca",
+	}
+
+	prof := speedscopeProfile{
+		Type: "sampled",
+		Name: name,
+		Unit: speedscopeUnit(sampleUnit(p)),
+	}
+	for _, s := range p.Sample {
+		frames := stackFrames(s)
+		stack := make([]int, 0, len(frames))
+		for _, f := range frames {
+			i, ok := frameIndex[f]
+			if !ok {
+				i = len(file.Shared.Frames)
+				frameIndex[f] = i
+				file.Shared.Frames = append(file.Shared.Frames, speedscopeFrame{Name: f})
+			}
+			stack = append(stack, i)
+		}
+
+		v := sampleValue(s)
+		prof.Samples = append(prof.Samples, stack)
+		prof.Weights = append(prof.Weights, v)
+		prof.EndValue += v
+	}
+	file.Profiles = []speedscopeProfile{prof}
+
+	return json.NewEncoder(w).Encode(file)
+}
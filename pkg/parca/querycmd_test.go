@@ -0,0 +1,100 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parca
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+
+	querypb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+func queryCmdTestProfile() *pprofprofile.Profile {
+	mainFn := &pprofprofile.Function{ID: 1, Name: "main"}
+	fooFn := &pprofprofile.Function{ID: 2, Name: "foo"}
+	barFn := &pprofprofile.Function{ID: 3, Name: "bar"}
+
+	mainLoc := &pprofprofile.Location{ID: 1, Line: []pprofprofile.Line{{Function: mainFn}}}
+	// bar is inlined into foo.
+	fooLoc := &pprofprofile.Location{ID: 2, Line: []pprofprofile.Line{{Function: barFn}, {Function: fooFn}}}
+	unknownLoc := &pprofprofile.Location{ID: 3, Address: 0x2a}
+
+	return &pprofprofile.Profile{
+		SampleType: []*pprofprofile.ValueType{{Type: "samples", Unit: "count"}},
+		Sample: []*pprofprofile.Sample{
+			{Location: []*pprofprofile.Location{fooLoc, mainLoc}, Value: []int64{3}},
+			{Location: []*pprofprofile.Location{mainLoc}, Value: []int64{1}},
+			{Location: []*pprofprofile.Location{unknownLoc, mainLoc}, Value: []int64{2}},
+		},
+		Location: []*pprofprofile.Location{mainLoc, fooLoc, unknownLoc},
+		Function: []*pprofprofile.Function{mainFn, fooFn, barFn},
+	}
+}
+
+func TestWriteFolded(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteFolded(&buf, queryCmdTestProfile()))
+	require.Equal(t, "main 1\nmain;0x2a 2\nmain;foo;bar 3\n", buf.String())
+}
+
+func TestWriteTree(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteTree(&buf, queryCmdTestProfile()))
+	require.Equal(t, "6 root\n  6 main\n    3 foo\n      3 bar\n    2 0x2a\n", buf.String())
+}
+
+func TestWriteTop(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteTop(&buf, queryCmdTestProfile(), 2))
+	out := buf.String()
+	require.Contains(t, out, "Total: 6 count")
+	require.Contains(t, out, "bar")
+	require.Contains(t, out, "0x2a")
+	require.NotContains(t, out, "foo")
+}
+
+func TestWriteSpeedscope(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteSpeedscope(&buf, queryCmdTestProfile(), "test"))
+
+	var f speedscopeFile
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &f))
+	require.Len(t, f.Profiles, 1)
+	require.Equal(t, int64(6), f.Profiles[0].EndValue)
+	require.Equal(t, "none", f.Profiles[0].Unit)
+	require.Len(t, f.Shared.Frames, 4)
+	require.Equal(t, []int{0, 1, 2}, f.Profiles[0].Samples[0])
+}
+
+func TestQueryCmdRequest(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	cmd := &QueryCmd{Selector: "cpu{}", Since: time.Minute, Filter: "foo"}
+	req := cmd.request(now)
+	require.Equal(t, querypb.QueryRequest_MODE_MERGE, req.Mode)
+	merge := req.GetMerge()
+	require.Equal(t, int64(940), merge.Start.AsTime().Unix())
+	require.Equal(t, int64(1000), merge.End.AsTime().Unix())
+	require.Equal(t, "foo", req.Filter[0].GetStackFilter().GetFunctionNameStackFilter().GetFunctionToFilter())
+
+	cmd = &QueryCmd{Selector: "cpu{}", Single: true}
+	req = cmd.request(now)
+	require.Equal(t, querypb.QueryRequest_MODE_SINGLE_UNSPECIFIED, req.Mode)
+	require.Equal(t, int64(1000), req.GetSingle().Time.AsTime().Unix())
+}
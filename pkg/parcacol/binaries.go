@@ -0,0 +1,304 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/prometheus/model/timestamp"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// Binary is a binary observed in the mappings of stored profiles.
+type Binary struct {
+	BuildID   string
+	File      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Services are the distinct values of the service label of the profiles
+	// the binary was observed in.
+	Services []string
+	// ServiceRanges holds when the binary was first and last observed for
+	// each of the services.
+	ServiceRanges map[string]TimeRange
+}
+
+type binaryKey struct {
+	buildID string
+	file    string
+}
+
+type seenRange struct {
+	first int64
+	last  int64
+}
+
+func (r *seenRange) observe(ts int64) {
+	if ts < r.first {
+		r.first = ts
+	}
+	if ts > r.last {
+		r.last = ts
+	}
+}
+
+type binaryStats struct {
+	seen     seenRange
+	services map[string]*seenRange
+}
+
+// Binaries returns all distinct binaries found in the mappings of the profiles
+// matching the query in the given time range. An empty query matches the
+// profiles of all types. Binaries are identified by their build ID and
+// mapping file.
+func (q *Querier) Binaries(
+	ctx context.Context,
+	query string,
+	startTime, endTime time.Time,
+	serviceLabel string,
+) ([]*Binary, error) {
+	ctx, span := q.tracer.Start(ctx, "Querier/Binaries")
+	defer span.End()
+
+	var exprs []logicalplan.Expr
+	if query != "" {
+		_, selectorExprs, err := q.queryToFilterExprs(query)
+		if err != nil {
+			return nil, err
+		}
+		exprs = selectorExprs
+	} else {
+		exclusions, err := q.tombstones.exclusions()
+		if err != nil {
+			return nil, err
+		}
+		exprs = exclusions
+	}
+
+	exprs = append(exprs,
+		logicalplan.Col(profile.ColumnTimestamp).GtEq(logicalplan.Literal(timestamp.FromTime(startTime))),
+		logicalplan.Col(profile.ColumnTimestamp).LtEq(logicalplan.Literal(timestamp.FromTime(endTime))),
+	)
+
+	projection := []logicalplan.Expr{
+		logicalplan.Col(profile.ColumnStacktrace),
+		logicalplan.Col(profile.ColumnTimestamp),
+	}
+	serviceColumn := ""
+	if serviceLabel != "" {
+		serviceColumn = profile.ColumnLabelsPrefix + serviceLabel
+		projection = append(projection, logicalplan.Col(serviceColumn))
+	}
+
+	binaries := map[binaryKey]*binaryStats{}
+	err := q.engine.ScanTable(q.tableName).
+		Filter(logicalplan.And(exprs...)).
+		Project(projection...).
+		Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+			return collectBinaries(r, serviceColumn, binaries)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Binary, 0, len(binaries))
+	for k, s := range binaries {
+		services := make([]string, 0, len(s.services))
+		ranges := make(map[string]TimeRange, len(s.services))
+		for svc, r := range s.services {
+			services = append(services, svc)
+			ranges[svc] = TimeRange{Start: timestamp.Time(r.first), End: timestamp.Time(r.last)}
+		}
+		sort.Strings(services)
+
+		res = append(res, &Binary{
+			BuildID:       k.buildID,
+			File:          k.file,
+			FirstSeen:     timestamp.Time(s.seen.first),
+			LastSeen:      timestamp.Time(s.seen.last),
+			Services:      services,
+			ServiceRanges: ranges,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].File != res[j].File {
+			return res[i].File < res[j].File
+		}
+		return res[i].BuildID < res[j].BuildID
+	})
+
+	return res, nil
+}
+
+func collectBinaries(r arrow.Record, serviceColumn string, binaries map[binaryKey]*binaryStats) error {
+	var (
+		stacktraces *array.List
+		timestamps  *array.Int64
+		services    arrow.Array
+	)
+	for i, field := range r.Schema().Fields() {
+		switch field.Name {
+		case profile.ColumnStacktrace:
+			stacktraces, _ = r.Column(i).(*array.List)
+		case profile.ColumnTimestamp:
+			timestamps, _ = r.Column(i).(*array.Int64)
+		case serviceColumn:
+			services = r.Column(i)
+		}
+	}
+	if stacktraces == nil || timestamps == nil {
+		return fmt.Errorf("unexpected record schema: %s", r.Schema())
+	}
+
+	locations, ok := stacktraces.ListValues().(*array.Dictionary)
+	if !ok {
+		return fmt.Errorf("unexpected stacktrace type: %s", stacktraces.ListValues().DataType())
+	}
+	dict, ok := locations.Dictionary().(*array.Binary)
+	if !ok {
+		return fmt.Errorf("unexpected location dictionary type: %s", locations.Dictionary().DataType())
+	}
+
+	// Decode every distinct location once per record.
+	decoded := make(map[int]*binaryKey, dict.Len())
+	keyFor := func(idx int) *binaryKey {
+		k, ok := decoded[idx]
+		if ok {
+			return k
+		}
+		info, _ := profile.DecodeSymbolizationInfo(dict.Value(idx))
+		if len(info.BuildID) > 0 || info.Mapping.File != "" {
+			k = &binaryKey{buildID: string(info.BuildID), file: info.Mapping.File}
+		}
+		decoded[idx] = k
+		return k
+	}
+
+	for row := 0; row < int(r.NumRows()); row++ {
+		if stacktraces.IsNull(row) {
+			continue
+		}
+
+		ts := timestamps.Value(row)
+		service := ""
+		if services != nil && services.IsValid(row) {
+			service = labelValue(services, row)
+		}
+
+		start, end := stacktraces.ValueOffsets(row)
+		seen := map[binaryKey]struct{}{}
+		for i := int(start); i < int(end); i++ {
+			if locations.IsNull(i) {
+				continue
+			}
+			k := keyFor(locations.GetValueIndex(i))
+			if k == nil {
+				continue
+			}
+			if _, ok := seen[*k]; ok {
+				continue
+			}
+			seen[*k] = struct{}{}
+
+			s, ok := binaries[*k]
+			if !ok {
+				s = &binaryStats{seen: seenRange{first: ts, last: ts}, services: map[string]*seenRange{}}
+				binaries[*k] = s
+			}
+			s.seen.observe(ts)
+			if service != "" {
+				sr, ok := s.services[service]
+				if !ok {
+					sr = &seenRange{first: ts, last: ts}
+					s.services[service] = sr
+				}
+				sr.observe(ts)
+			}
+		}
+	}
+
+	return nil
+}
+
+func labelValue(arr arrow.Array, i int) string {
+	switch a := arr.(type) {
+	case *array.Dictionary:
+		return StringValueFromDictionary(a, i)
+	case *array.Binary:
+		return string(a.Value(i))
+	case *array.String:
+		return a.Value(i)
+	default:
+		return ""
+	}
+}
+
+// ReferencedBuildIDs returns the build IDs found in the mappings of stored
+// profiles, with the last time each was seen. The raw table and every tier
+// are scanned entirely, as queries read any profile still stored, eg. the
+// raw table serves non-delta profiles of any age, and the table retention
+// deletes the profiles past the retention of their table. Deleted profiles
+// only stop referencing build IDs once the deletion can't be undone anymore,
+// so that undeleted profiles can still be symbolized.
+func (q *Querier) ReferencedBuildIDs(ctx context.Context) (map[string]time.Time, error) {
+	ctx, span := q.tracer.Start(ctx, "Querier/ReferencedBuildIDs")
+	defer span.End()
+
+	tables := []string{q.tableName}
+	for _, t := range q.tiers {
+		tables = append(tables, t.Table)
+	}
+
+	exclusions, err := q.tombstones.finalExclusions(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	binaries := map[binaryKey]*binaryStats{}
+	for _, table := range tables {
+		plan := q.engine.ScanTable(table)
+		if len(exclusions) > 0 {
+			plan = plan.Filter(logicalplan.And(exclusions...))
+		}
+		err := plan.
+			Project(
+				logicalplan.Col(profile.ColumnStacktrace),
+				logicalplan.Col(profile.ColumnTimestamp),
+			).
+			Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+				return collectBinaries(r, "", binaries)
+			})
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %w", table, err)
+		}
+	}
+
+	res := make(map[string]time.Time, len(binaries))
+	for k, s := range binaries {
+		if k.buildID == "" {
+			continue
+		}
+		if last := timestamp.Time(s.seen.last); last.After(res[k.buildID]) {
+			res[k.buildID] = last
+		}
+	}
+	return res, nil
+}
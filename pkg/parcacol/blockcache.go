@@ -0,0 +1,277 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+	"golang.org/x/sync/singleflight"
+)
+
+// blockCacheTmpSuffix is the suffix of objects being downloaded to the
+// cache.
+const blockCacheTmpSuffix = ".tmp"
+
+// BlockCache is a bucket that keeps the objects read from the wrapped
+// bucket in a local directory, so that blocks offloaded to object storage
+// are only downloaded once while they are queried. The least recently read
+// objects are evicted once the cached objects exceed a size budget. The
+// cache survives restarts.
+type BlockCache struct {
+	objstore.Bucket
+
+	logger   log.Logger
+	dir      string
+	maxBytes int64
+
+	sfg singleflight.Group
+
+	mtx     sync.Mutex
+	entries map[string]*blockCacheEntry
+	used    int64
+
+	hits        prometheus.Counter
+	misses      prometheus.Counter
+	cachedBytes prometheus.Gauge
+}
+
+type blockCacheEntry struct {
+	size     int64
+	lastRead time.Time
+}
+
+// NewBlockCache returns a cache of the objects of the bucket in the
+// directory, taking up at most maxBytes. Objects cached before are reused.
+func NewBlockCache(logger log.Logger, reg prometheus.Registerer, bucket objstore.Bucket, dir string, maxBytes int64) (*BlockCache, error) {
+	c := &BlockCache{
+		Bucket:   bucket,
+		logger:   logger,
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  map[string]*blockCacheEntry{},
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_storage_block_cache_hits_total",
+			Help: "Total number of reads of objects served from the local block cache.",
+		}),
+		misses: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_storage_block_cache_misses_total",
+			Help: "Total number of reads of objects downloaded to the local block cache.",
+		}),
+		cachedBytes: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "parca_storage_block_cache_bytes",
+			Help: "Bytes of the objects in the local block cache.",
+		}),
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create block cache directory: %w", err)
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasSuffix(path, blockCacheTmpSuffix) {
+			return os.Remove(path)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		c.entries[filepath.ToSlash(rel)] = &blockCacheEntry{size: info.Size(), lastRead: info.ModTime()}
+		c.used += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load block cache: %w", err)
+	}
+	c.mtx.Lock()
+	c.evict("")
+	c.mtx.Unlock()
+
+	return c, nil
+}
+
+// Get returns the object from the cache, downloading it first if needed.
+func (c *BlockCache) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return c.open(ctx, name)
+}
+
+// GetRange returns a range of the object from the cache, downloading the
+// whole object first if needed. A negative length reads to the end.
+func (c *BlockCache) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	f, err := c.open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+// Upload uploads the object to the bucket, dropping a cached version.
+func (c *BlockCache) Upload(ctx context.Context, name string, r io.Reader) error {
+	c.remove(name)
+	return c.Bucket.Upload(ctx, name, r)
+}
+
+// Delete deletes the object from the bucket and the cache.
+func (c *BlockCache) Delete(ctx context.Context, name string) error {
+	c.remove(name)
+	return c.Bucket.Delete(ctx, name)
+}
+
+func (c *BlockCache) path(name string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(name))
+}
+
+func (c *BlockCache) open(ctx context.Context, name string) (*os.File, error) {
+	c.mtx.Lock()
+	e, ok := c.entries[name]
+	if ok {
+		e.lastRead = time.Now()
+	}
+	c.mtx.Unlock()
+
+	if ok {
+		f, err := os.Open(c.path(name))
+		if err == nil {
+			c.hits.Inc()
+			return f, nil
+		}
+		// The file was evicted since, it is downloaded again.
+	}
+
+	// Concurrent reads of the same object download it once.
+	_, err, _ := c.sfg.Do(name, func() (any, error) {
+		return nil, c.download(ctx, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(c.path(name))
+}
+
+func (c *BlockCache) download(ctx context.Context, name string) error {
+	c.misses.Inc()
+
+	rc, err := c.Bucket.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	path := c.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + blockCacheTmpSuffix
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	size, err := io.Copy(f, rc)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("cache %s: %w", name, err)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if old, ok := c.entries[name]; ok {
+		c.used -= old.size
+	}
+	c.entries[name] = &blockCacheEntry{size: size, lastRead: time.Now()}
+	c.used += size
+	c.evict(name)
+	return nil
+}
+
+// evict removes the least recently read objects other than keep until the
+// cache fits into the budget. The caller must hold the lock.
+func (c *BlockCache) evict(keep string) {
+	defer func() { c.cachedBytes.Set(float64(c.used)) }()
+	if c.used <= c.maxBytes {
+		return
+	}
+
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		if name != keep {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return c.entries[names[i]].lastRead.Before(c.entries[names[j]].lastRead)
+	})
+	for _, name := range names {
+		if c.used <= c.maxBytes {
+			return
+		}
+		if err := os.Remove(c.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			level.Warn(c.logger).Log("msg", "failed to evict object from block cache", "name", name, "err", err)
+			continue
+		}
+		c.used -= c.entries[name].size
+		delete(c.entries, name)
+	}
+}
+
+func (c *BlockCache) remove(name string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	if err := os.Remove(c.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		level.Warn(c.logger).Log("msg", "failed to remove object from block cache", "name", name, "err", err)
+	}
+	c.used -= e.size
+	delete(c.entries, name)
+	c.cachedBytes.Set(float64(c.used))
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestBlockCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	bucket := objstore.NewInMemBucket()
+	require.NoError(t, bucket.Upload(ctx, "parca/stacktraces/a/data.parquet", bytes.NewReader([]byte("0123456789"))))
+	require.NoError(t, bucket.Upload(ctx, "parca/stacktraces/b/data.parquet", bytes.NewReader([]byte("abcdefghij"))))
+
+	read := func(c *BlockCache, name string, off, length int64) string {
+		rc, err := c.GetRange(ctx, name, off, length)
+		require.NoError(t, err)
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	c, err := NewBlockCache(log.NewNopLogger(), prometheus.NewRegistry(), bucket, dir, 15)
+	require.NoError(t, err)
+	require.Equal(t, "234", read(c, "parca/stacktraces/a/data.parquet", 2, 3))
+	require.Equal(t, "789", read(c, "parca/stacktraces/a/data.parquet", 7, -1))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.misses))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.hits))
+
+	// Caching the second object evicts the first one to fit the budget.
+	require.Equal(t, "abc", read(c, "parca/stacktraces/b/data.parquet", 0, 3))
+	require.Equal(t, 10.0, testutil.ToFloat64(c.cachedBytes))
+
+	// The cache survives restarts.
+	c, err = NewBlockCache(log.NewNopLogger(), prometheus.NewRegistry(), bucket, dir, 15)
+	require.NoError(t, err)
+	require.Equal(t, "def", read(c, "parca/stacktraces/b/data.parquet", 3, 3))
+	require.Equal(t, 0.0, testutil.ToFloat64(c.misses))
+
+	require.NoError(t, c.Delete(ctx, "parca/stacktraces/b/data.parquet"))
+	require.Equal(t, 0.0, testutil.ToFloat64(c.cachedBytes))
+	_, err = c.Get(ctx, "parca/stacktraces/b/data.parquet")
+	require.True(t, bucket.IsObjNotFoundErr(err))
+}
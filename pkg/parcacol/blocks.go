@@ -0,0 +1,638 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+	"github.com/thanos-io/objstore"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// BlocksPath is the HTTP path of the endpoint listing the blocks persisted
+// to object storage, relative to the API root.
+const BlocksPath = "/storage/blocks"
+
+// BlockAnnotationsPath is the HTTP path of the endpoint annotating a block,
+// relative to the API root.
+const BlockAnnotationsPath = "/storage/blocks/annotations"
+
+// BlockRetainPath is the HTTP path of the endpoint marking a block to be
+// retained, relative to the API root.
+const BlockRetainPath = "/storage/blocks/retain"
+
+// ErrBlockNotFound is returned for operations on blocks that don't exist.
+var ErrBlockNotFound = errors.New("block not found")
+
+// ErrBlockPinned is returned when deleting a block a request in flight may
+// still read, see SnapshotPins.
+var ErrBlockPinned = errors.New("block pinned by a read snapshot")
+
+// BlockMeta describes a block persisted to object storage.
+type BlockMeta struct {
+	ID string `json:"id"`
+	// Table is the table the block holds rows of.
+	Table   string    `json:"table"`
+	Created time.Time `json:"created"`
+	Bytes   int64     `json:"bytes"`
+	Rows    int64     `json:"rows"`
+	// MinTime and MaxTime are the time range of the profiles in the block,
+	// if the block has statistics of the timestamp column.
+	MinTime time.Time `json:"min_time,omitempty"`
+	MaxTime time.Time `json:"max_time,omitempty"`
+	// Annotations are attached by users, eg. where the block was imported
+	// from.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Retain marks the block to never be deleted by the size retention.
+	Retain bool `json:"retain,omitempty"`
+}
+
+// Blocks describes the blocks persisted to object storage. The description
+// of a block is computed from its Parquet files the first time it is listed
+// and kept in the metadata bucket along with the annotations of the block,
+// as the blocks are written by the storage.
+type Blocks struct {
+	logger log.Logger
+	bucket objstore.Bucket
+	meta   objstore.Bucket
+	// pins are the read snapshots in flight, their blocks are neither
+	// deleted nor rewritten.
+	pins *SnapshotPins
+
+	// mtx serializes the updates of the metadata.
+	mtx sync.Mutex
+}
+
+// NewBlocks returns the descriptions of the blocks in the bucket, kept in
+// the metadata bucket. The pins must be the ones of the querier reading the
+// blocks, or nil if no query pins them.
+func NewBlocks(logger log.Logger, bucket, meta objstore.Bucket, pins *SnapshotPins) *Blocks {
+	return &Blocks{
+		logger: logger,
+		bucket: bucket,
+		meta:   meta,
+		pins:   pins,
+	}
+}
+
+// List returns the descriptions of the blocks, the oldest first.
+func (b *Blocks) List(ctx context.Context) ([]*BlockMeta, error) {
+	blocks, err := listBlocks(ctx, b.bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*BlockMeta, 0, len(blocks))
+	for _, block := range blocks {
+		meta, err := b.blockMeta(ctx, block)
+		if err != nil {
+			return nil, fmt.Errorf("describe block %s: %w", block.id, err)
+		}
+		res = append(res, meta)
+	}
+	return res, nil
+}
+
+// Annotate merges the annotations into the ones of the block. Empty values
+// remove annotations.
+func (b *Blocks) Annotate(ctx context.Context, id string, annotations map[string]string) (*BlockMeta, error) {
+	block, err := b.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	meta, err := b.blockMeta(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		if v == "" {
+			delete(meta.Annotations, k)
+			continue
+		}
+		meta.Annotations[k] = v
+	}
+	if err := b.storeMeta(ctx, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// SetRetain marks the block to be retained or not. Retained blocks are never
+// deleted by the size retention.
+func (b *Blocks) SetRetain(ctx context.Context, id string, retain bool) (*BlockMeta, error) {
+	block, err := b.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	meta, err := b.blockMeta(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	meta.Retain = retain
+	if err := b.storeMeta(ctx, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// Delete deletes the block and its description, or returns ErrBlockPinned
+// if a read snapshot in flight pins it.
+func (b *Blocks) Delete(ctx context.Context, id string) error {
+	block, err := b.find(ctx, id)
+	if err != nil {
+		return err
+	}
+	return b.deleteBlock(ctx, block)
+}
+
+func (b *Blocks) deleteBlock(ctx context.Context, block *persistedBlock) error {
+	id := block.id.String()
+	if b.pins.blockPinned(block.id) {
+		return fmt.Errorf("%w: %s", ErrBlockPinned, id)
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, name := range block.objects {
+		if err := b.bucket.Delete(ctx, name); err != nil && !b.bucket.IsObjNotFoundErr(err) {
+			return fmt.Errorf("delete block %s: %w", id, err)
+		}
+	}
+	if err := b.meta.Delete(ctx, metaKey(id)); err != nil && !b.meta.IsObjNotFoundErr(err) {
+		return fmt.Errorf("delete meta of block %s: %w", id, err)
+	}
+	level.Info(b.logger).Log("msg", "deleted block", "block", id, "bytes", block.size)
+	return nil
+}
+
+// rewrite rewrites the Parquet files of the block without the rows drop
+// returns true for, and returns the number of rows dropped. Before the
+// rewritten files are uploaded prepare is called, eg. to record what has to
+// be done about the dropped rows. The description of the block is computed
+// again, keeping its annotations. Blocks pinned by a read snapshot in flight
+// are not rewritten, as the request may be reading the files.
+func (b *Blocks) rewrite(ctx context.Context, block *persistedBlock, drop func(*parquetRow) bool, prepare func() error) (int64, error) {
+	id := block.id.String()
+	if b.pins.blockPinned(block.id) {
+		return 0, fmt.Errorf("%w: %s", ErrBlockPinned, id)
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	prev, err := b.blockMeta(ctx, block)
+	if err != nil {
+		return 0, fmt.Errorf("describe block %s: %w", id, err)
+	}
+
+	var dropped int64
+	rewritten := map[string]*bytes.Buffer{}
+	for _, name := range block.objects {
+		if !strings.HasSuffix(name, ".parquet") {
+			continue
+		}
+		buf, n, err := rewriteParquet(ctx, b.bucket, name, drop)
+		if err != nil {
+			return 0, fmt.Errorf("rewrite %s: %w", name, err)
+		}
+		if n > 0 {
+			rewritten[name] = buf
+			dropped += n
+		}
+	}
+	if dropped == 0 {
+		return 0, nil
+	}
+	if err := prepare(); err != nil {
+		return 0, err
+	}
+
+	for name, buf := range rewritten {
+		if err := b.bucket.Upload(ctx, name, buf); err != nil {
+			return 0, fmt.Errorf("upload %s: %w", name, err)
+		}
+	}
+	block.size = 0
+	for _, name := range block.objects {
+		attrs, err := b.bucket.Attributes(ctx, name)
+		if err != nil {
+			return 0, err
+		}
+		block.size += attrs.Size
+	}
+	meta, err := b.describe(ctx, block)
+	if err != nil {
+		return 0, err
+	}
+	meta.Annotations, meta.Retain = prev.Annotations, prev.Retain
+	if err := b.storeMeta(ctx, meta); err != nil {
+		return 0, err
+	}
+	level.Info(b.logger).Log("msg", "rewrote block", "block", id, "dropped_rows", dropped)
+	return dropped, nil
+}
+
+// rewriteParquet returns the Parquet file with the name without the rows drop
+// returns true for, along with the number of rows dropped. The row groups,
+// sorting columns and key/value metadata of the file are kept.
+func rewriteParquet(ctx context.Context, bucket objstore.Bucket, name string, drop func(*parquetRow) bool) (*bytes.Buffer, int64, error) {
+	attrs, err := bucket.Attributes(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := parquet.OpenFile(&bucketReaderAt{ctx: ctx, bucket: bucket, name: name}, attrs.Size,
+		parquet.SkipBloomFilters(true),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := []parquet.WriterOption{f.Schema()}
+	for _, kv := range f.Metadata().KeyValueMetadata {
+		opts = append(opts, parquet.KeyValueMetadata(kv.Key, kv.Value))
+	}
+	if rgs := f.RowGroups(); len(rgs) > 0 && len(rgs[0].SortingColumns()) > 0 {
+		opts = append(opts, parquet.SortingWriterConfig(parquet.SortingColumns(rgs[0].SortingColumns()...)))
+	}
+	buf := &bytes.Buffer{}
+	w := parquet.NewWriter(buf, opts...)
+
+	row := newParquetRow(f.Schema())
+	var dropped int64
+	for _, rg := range f.RowGroups() {
+		kept, n, err := copyRows(w, rg, row, drop)
+		if err != nil {
+			return nil, 0, err
+		}
+		dropped += n
+		if kept > 0 {
+			if err := w.Flush(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if dropped == 0 {
+		return nil, 0, nil
+	}
+	if err := w.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf, dropped, nil
+}
+
+// copyRows writes the rows of the row group drop returns false for, and
+// returns the number of rows kept and dropped.
+func copyRows(w *parquet.Writer, rg parquet.RowGroup, row *parquetRow, drop func(*parquetRow) bool) (int64, int64, error) {
+	rows := rg.Rows()
+	defer rows.Close()
+
+	var kept, dropped int64
+	buf := make([]parquet.Row, 512)
+	for {
+		n, err := rows.ReadRows(buf)
+		for _, r := range buf[:n] {
+			row.reset(r)
+			if drop(row) {
+				dropped++
+				continue
+			}
+			if _, err := w.WriteRows([]parquet.Row{r}); err != nil {
+				return 0, 0, err
+			}
+			kept++
+		}
+		if errors.Is(err, io.EOF) {
+			return kept, dropped, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// parquetRow gives access to the values of a row of a Parquet file by the
+// name of their column. Only the first value of repeated columns is kept.
+type parquetRow struct {
+	columns map[string]int
+	values  map[int]parquet.Value
+}
+
+func newParquetRow(schema *parquet.Schema) *parquetRow {
+	r := &parquetRow{columns: map[string]int{}, values: map[int]parquet.Value{}}
+	for i, p := range schema.Columns() {
+		r.columns[strings.Join(p, ".")] = i
+	}
+	return r
+}
+
+func (r *parquetRow) reset(row parquet.Row) {
+	clear(r.values)
+	for _, v := range row {
+		if _, ok := r.values[v.Column()]; !ok {
+			r.values[v.Column()] = v
+		}
+	}
+}
+
+// value returns the value of the column, or false if the row has none.
+func (r *parquetRow) value(column string) (parquet.Value, bool) {
+	i, ok := r.columns[column]
+	if !ok {
+		return parquet.Value{}, false
+	}
+	v, ok := r.values[i]
+	if !ok || v.IsNull() {
+		return parquet.Value{}, false
+	}
+	return v, true
+}
+
+// stringValue returns the value of a string column, or the empty string if
+// the row has none like for labels a series doesn't have.
+func (r *parquetRow) stringValue(column string) string {
+	v, ok := r.value(column)
+	if !ok {
+		return ""
+	}
+	return string(v.ByteArray())
+}
+
+func (r *parquetRow) int64Value(column string) int64 {
+	v, ok := r.value(column)
+	if !ok {
+		return 0
+	}
+	return v.Int64()
+}
+
+// retained returns whether the block is marked to be retained. Blocks that
+// were never described are not.
+func (b *Blocks) retained(ctx context.Context, id ulid.ULID) (bool, error) {
+	rc, err := b.meta.Get(ctx, metaKey(id.String()))
+	if b.meta.IsObjNotFoundErr(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+
+	meta := &BlockMeta{}
+	if err := json.NewDecoder(rc).Decode(meta); err != nil {
+		return false, fmt.Errorf("decode block meta: %w", err)
+	}
+	return meta.Retain, nil
+}
+
+// find returns the block with the ID, or ErrBlockNotFound.
+func (b *Blocks) find(ctx context.Context, id string) (*persistedBlock, error) {
+	blocks, err := listBlocks(ctx, b.bucket)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		if block.id.String() == id {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, id)
+}
+
+func metaKey(id string) string {
+	return id + ".json"
+}
+
+// blockMeta returns the stored description of the block, computing and
+// storing it if there is none yet.
+func (b *Blocks) blockMeta(ctx context.Context, block *persistedBlock) (*BlockMeta, error) {
+	rc, err := b.meta.Get(ctx, metaKey(block.id.String()))
+	if err == nil {
+		defer rc.Close()
+		meta := &BlockMeta{}
+		if err := json.NewDecoder(rc).Decode(meta); err != nil {
+			return nil, fmt.Errorf("decode block meta: %w", err)
+		}
+		return meta, nil
+	}
+	if !b.meta.IsObjNotFoundErr(err) {
+		return nil, err
+	}
+
+	meta, err := b.describe(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.storeMeta(ctx, meta); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to store block meta", "block", meta.ID, "err", err)
+	}
+	return meta, nil
+}
+
+func (b *Blocks) storeMeta(ctx context.Context, meta *BlockMeta) error {
+	buf, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return b.meta.Upload(ctx, metaKey(meta.ID), bytes.NewReader(buf))
+}
+
+// describe computes the description of the block from the footers of its
+// Parquet files.
+func (b *Blocks) describe(ctx context.Context, block *persistedBlock) (*BlockMeta, error) {
+	meta := &BlockMeta{
+		ID:      block.id.String(),
+		Table:   path.Base(path.Dir(block.dir)),
+		Created: time.UnixMilli(int64(block.id.Time())),
+		Bytes:   block.size,
+	}
+
+	var minTime, maxTime int64
+	found := false
+	for _, name := range block.objects {
+		if !strings.HasSuffix(name, ".parquet") {
+			continue
+		}
+		attrs, err := b.bucket.Attributes(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parquet.OpenFile(&bucketReaderAt{ctx: ctx, bucket: b.bucket, name: name}, attrs.Size,
+			parquet.SkipPageIndex(true),
+			parquet.SkipBloomFilters(true),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		meta.Rows += f.NumRows()
+
+		for _, rg := range f.Metadata().RowGroups {
+			for _, cc := range rg.Columns {
+				md := cc.MetaData
+				if len(md.PathInSchema) != 1 || md.PathInSchema[0] != profile.ColumnTimestamp {
+					continue
+				}
+				if len(md.Statistics.MinValue) != 8 || len(md.Statistics.MaxValue) != 8 {
+					continue
+				}
+				lo := int64(binary.LittleEndian.Uint64(md.Statistics.MinValue))
+				hi := int64(binary.LittleEndian.Uint64(md.Statistics.MaxValue))
+				if !found || lo < minTime {
+					minTime = lo
+				}
+				if !found || hi > maxTime {
+					maxTime = hi
+				}
+				found = true
+			}
+		}
+	}
+	if found {
+		meta.MinTime = time.UnixMilli(minTime)
+		meta.MaxTime = time.UnixMilli(maxTime)
+	}
+	return meta, nil
+}
+
+// bucketReaderAt reads an object with ranged reads, so that only the parts
+// of a Parquet file that are needed are downloaded.
+type bucketReaderAt struct {
+	ctx    context.Context
+	bucket objstore.BucketReader
+	name   string
+}
+
+func (r *bucketReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	rc, err := r.bucket.GetRange(r.ctx, r.name, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.ReadFull(rc, p)
+}
+
+// ListHandler serves the descriptions of the blocks.
+func (b *Blocks) ListHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		blocks, err := b.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, struct {
+			Blocks []*BlockMeta `json:"blocks"`
+		}{blocks})
+	}
+}
+
+// DeleteHandler deletes the block with the ID in the id parameter.
+func (b *Blocks) DeleteHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		err := b.Delete(r.Context(), r.URL.Query().Get("id"))
+		if errors.Is(err, ErrBlockNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, ErrBlockPinned) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RetainHandler marks the block with the ID in the id parameter to be
+// retained, or not if the retain parameter is false, and serves the
+// description of the block.
+func (b *Blocks) RetainHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		params := r.URL.Query()
+		retain := true
+		if v := params.Get("retain"); v != "" {
+			var err error
+			if retain, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, fmt.Sprintf("invalid retain: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		meta, err := b.SetRetain(r.Context(), params.Get("id"), retain)
+		if errors.Is(err, ErrBlockNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, meta)
+	}
+}
+
+// AnnotateHandler merges the annotations in the JSON object of the request
+// body into the ones of the block with the ID in the id parameter, and
+// serves the description of the block.
+func (b *Blocks) AnnotateHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		annotations := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&annotations); err != nil {
+			http.Error(w, fmt.Sprintf("invalid annotations: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		meta, err := b.Annotate(r.Context(), r.URL.Query().Get("id"), annotations)
+		if errors.Is(err, ErrBlockNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, meta)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestBlocks(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+
+	type row struct {
+		Timestamp int64 `parquet:"timestamp"`
+		Value     int64 `parquet:"value"`
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, parquet.Write(buf, []row{{Timestamp: 3000, Value: 1}, {Timestamp: 1000, Value: 2}, {Timestamp: 2000, Value: 3}}))
+	id := ulid.MustNew(ulid.Timestamp(time.Unix(10, 0)), bytes.NewReader(make([]byte, 16))).String()
+	require.NoError(t, bucket.Upload(ctx, "parca/stacktraces/"+id+"/data.parquet", bytes.NewReader(buf.Bytes())))
+
+	meta := objstore.NewInMemBucket()
+	b := NewBlocks(log.NewNopLogger(), bucket, meta, nil)
+	blocks, err := b.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []*BlockMeta{{
+		ID:      id,
+		Table:   "stacktraces",
+		Created: time.Unix(10, 0),
+		Bytes:   int64(buf.Len()),
+		Rows:    3,
+		MinTime: time.UnixMilli(1000),
+		MaxTime: time.UnixMilli(3000),
+	}}, blocks)
+
+	_, err = b.Annotate(ctx, id, map[string]string{"source": "cluster-a"})
+	require.NoError(t, err)
+
+	// The annotations are kept with the description.
+	blocks, err = NewBlocks(log.NewNopLogger(), bucket, meta, nil).List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"source": "cluster-a"}, blocks[0].Annotations)
+
+	_, err = b.Annotate(ctx, "missing", map[string]string{"source": "cluster-a"})
+	require.ErrorIs(t, err, ErrBlockNotFound)
+}
+
+func TestBlocksRetainAndDelete(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+
+	type row struct {
+		Timestamp int64 `parquet:"timestamp"`
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, parquet.Write(buf, []row{{Timestamp: 1000}}))
+	ids := make([]string, 3)
+	for i := range ids {
+		ids[i] = ulid.MustNew(ulid.Timestamp(time.Unix(int64(i+1), 0)), bytes.NewReader(make([]byte, 16))).String()
+		require.NoError(t, bucket.Upload(ctx, "parca/stacktraces/"+ids[i]+"/data.parquet", bytes.NewReader(buf.Bytes())))
+	}
+
+	pins := NewSnapshotPins()
+	b := NewBlocks(log.NewNopLogger(), bucket, objstore.NewInMemBucket(), pins)
+	meta, err := b.SetRetain(ctx, ids[0], true)
+	require.NoError(t, err)
+	require.True(t, meta.Retain)
+
+	// The oldest block is retained, so the next one is deleted instead.
+	r := NewSizeRetention(log.NewNopLogger(), prometheus.NewRegistry(), bucket, int64(2*buf.Len()), WithRetainedBlocks(b))
+	_, err = r.Enforce(ctx)
+	require.NoError(t, err)
+
+	blocks, err := b.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	require.Equal(t, ids[0], blocks[0].ID)
+	require.Equal(t, ids[2], blocks[1].ID)
+
+	// Blocks a request in flight may read can't be deleted.
+	_, release := pins.ContextWithReadSnapshot(ctx)
+	require.ErrorIs(t, b.Delete(ctx, ids[0]), ErrBlockPinned)
+	release()
+
+	require.NoError(t, b.Delete(ctx, ids[0]))
+	blocks, err = b.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	require.Equal(t, ids[2], blocks[0].ID)
+
+	require.ErrorIs(t, b.Delete(ctx, ids[0]), ErrBlockNotFound)
+}
@@ -0,0 +1,375 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// Tier is a copy of the profile data at a resolution. The raw tier has a
+// resolution of zero.
+type Tier struct {
+	Table      string
+	Resolution time.Duration
+	// Retention is how far back the tier is used to serve queries. Zero
+	// means forever.
+	Retention time.Duration
+}
+
+// TierTableName returns the name of the table of the tier with the given
+// resolution.
+func TierTableName(raw string, resolution time.Duration) string {
+	if resolution == 0 {
+		return raw
+	}
+	return raw + "_" + model.Duration(resolution).String()
+}
+
+// Downsampler merges the delta profiles of a source table into one profile
+// per series and resolution window, and writes them to the table of a tier.
+// The timestamp of a merged profile is the start of its window and its
+// duration is the resolution. Non-delta profiles can't be merged by summing
+// and are not downsampled.
+type Downsampler struct {
+	logger   log.Logger
+	tracer   trace.Tracer
+	engine   Engine
+	source   string
+	tier     Tier
+	ingester ingester.Ingester
+	schema   *dynparquet.Schema
+	mem      memory.Allocator
+	// tombstones exclude deleted profiles from the tier.
+	tombstones *Tombstones
+	// watermark is the next window to downsample.
+	watermark *Watermark
+
+	// delay is how long after the end of a window it is downsampled, to
+	// include profiles that are written late.
+	delay time.Duration
+
+	windows prometheus.Counter
+	errors  prometheus.Counter
+	rows    prometheus.Counter
+}
+
+// NewDownsampler creates a Downsampler that reads from the source table and
+// writes the merged profiles using the ingester of the tier's table. Deleted
+// profiles are not merged. The watermark may be nil.
+func NewDownsampler(
+	logger log.Logger,
+	reg prometheus.Registerer,
+	tracer trace.Tracer,
+	engine Engine,
+	source string,
+	tier Tier,
+	ingester ingester.Ingester,
+	schema *dynparquet.Schema,
+	mem memory.Allocator,
+	tombstones *Tombstones,
+	watermark *Watermark,
+	delay time.Duration,
+) *Downsampler {
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"tier": tier.Table}, reg)
+	return &Downsampler{
+		logger:     log.With(logger, "tier", tier.Table),
+		tracer:     tracer,
+		engine:     engine,
+		source:     source,
+		tier:       tier,
+		ingester:   ingester,
+		schema:     schema,
+		mem:        mem,
+		tombstones: tombstones,
+		watermark:  watermark,
+		delay:      delay,
+		windows: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_downsampler_windows_total",
+			Help: "Total number of windows downsampled.",
+		}),
+		errors: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_downsampler_errors_total",
+			Help: "Total number of windows that failed to be downsampled.",
+		}),
+		rows: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_downsampler_rows_written_total",
+			Help: "Total number of rows written to the tier.",
+		}),
+	}
+}
+
+// Run downsamples every window once it is complete until the context is
+// canceled. It continues at the watermark, so the windows that completed
+// while it was not running are downsampled first. Without a stored
+// watermark, windows that ended before Run was called are not downsampled.
+func (d *Downsampler) Run(ctx context.Context) error {
+	return runWindows(ctx, d.logger, d.watermark, d.tier.Resolution, d.delay, func(ctx context.Context, start int64) {
+		if err := d.Downsample(ctx, timestamp.Time(start)); err != nil {
+			d.errors.Inc()
+			level.Error(d.logger).Log("msg", "failed to downsample window", "start", timestamp.Time(start), "err", err)
+		}
+	})
+}
+
+// Downsample merges the profiles of the window starting at the given time
+// and writes them to the tier.
+func (d *Downsampler) Downsample(ctx context.Context, start time.Time) error {
+	ctx, span := d.tracer.Start(ctx, "Downsampler/Downsample")
+	span.SetAttributes(attribute.String("tier", d.tier.Table))
+	span.SetAttributes(attribute.Int64("start", start.Unix()))
+	defer span.End()
+
+	windowStart := timestamp.FromTime(start)
+	windowEnd := windowStart + d.tier.Resolution.Milliseconds()
+
+	groupBy := []logicalplan.Expr{
+		logicalplan.Col(profile.ColumnName),
+		logicalplan.Col(profile.ColumnSampleType),
+		logicalplan.Col(profile.ColumnSampleUnit),
+		logicalplan.Col(profile.ColumnPeriodType),
+		logicalplan.Col(profile.ColumnPeriodUnit),
+		logicalplan.Col(profile.ColumnPeriod),
+		logicalplan.Col(profile.ColumnStacktrace),
+		logicalplan.DynCol(profile.ColumnLabels),
+	}
+	valueSum := logicalplan.Sum(logicalplan.Col(profile.ColumnValue))
+
+	// Deletions that can still be undone are excluded when the tier is read.
+	exclusions, err := d.tombstones.finalExclusions(time.Now())
+	if err != nil {
+		return fmt.Errorf("exclude deleted profiles: %w", err)
+	}
+	filters := append([]logicalplan.Expr{
+		logicalplan.Col(profile.ColumnTimestamp).GtEq(logicalplan.Literal(windowStart)),
+		logicalplan.Col(profile.ColumnTimestamp).Lt(logicalplan.Literal(windowEnd)),
+		logicalplan.Col(profile.ColumnDuration).NotEq(logicalplan.Literal(0)),
+	}, exclusions...)
+
+	profiles := newWindowProfiles(windowStart, d.tier.Resolution.Nanoseconds())
+	err = d.engine.ScanTable(d.source).
+		Filter(logicalplan.And(filters...)).
+		Project(append(groupBy, logicalplan.Col(profile.ColumnValue))...).
+		Aggregate(
+			[]*logicalplan.AggregationFunction{valueSum},
+			groupBy,
+		).
+		Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+			return profiles.add(r, valueSum.Name())
+		})
+	if err != nil {
+		return fmt.Errorf("merge window: %w", err)
+	}
+
+	r, err := normalizer.NormalizedWriteRawRequestToArrowRecord(ctx, d.mem, profiles.request(), d.schema)
+	if err != nil {
+		return fmt.Errorf("build record: %w", err)
+	}
+	d.windows.Inc()
+	if r == nil {
+		return nil
+	}
+	defer r.Release()
+
+	if err := d.ingester.Ingest(ctx, r); err != nil {
+		return fmt.Errorf("ingest: %w", err)
+	}
+	d.rows.Add(float64(r.NumRows()))
+
+	return nil
+}
+
+type windowProfileKey struct {
+	labels string
+	meta   profile.Meta
+}
+
+// windowProfiles collects the merged samples of a window grouped into
+// profiles by series and profile type.
+type windowProfiles struct {
+	timestamp  int64
+	duration   int64
+	series     map[string]*normalizer.Series
+	profiles   map[windowProfileKey]*normalizer.NormalizedProfile
+	labelNames map[string]struct{}
+}
+
+func newWindowProfiles(ts, duration int64) *windowProfiles {
+	return &windowProfiles{
+		timestamp:  ts,
+		duration:   duration,
+		series:     map[string]*normalizer.Series{},
+		profiles:   map[windowProfileKey]*normalizer.NormalizedProfile{},
+		labelNames: map[string]struct{}{},
+	}
+}
+
+func (w *windowProfiles) add(r arrow.Record, valueColumn string) error {
+	var (
+		name, sampleType, sampleUnit, periodType, periodUnit arrow.Array
+		period                                               *array.Int64
+		stacktraces                                          *array.List
+		values                                               *array.Int64
+		labelNames                                           []string
+		labelColumns                                         []arrow.Array
+	)
+	for i, field := range r.Schema().Fields() {
+		switch {
+		case field.Name == profile.ColumnName:
+			name = r.Column(i)
+		case field.Name == profile.ColumnSampleType:
+			sampleType = r.Column(i)
+		case field.Name == profile.ColumnSampleUnit:
+			sampleUnit = r.Column(i)
+		case field.Name == profile.ColumnPeriodType:
+			periodType = r.Column(i)
+		case field.Name == profile.ColumnPeriodUnit:
+			periodUnit = r.Column(i)
+		case field.Name == profile.ColumnPeriod:
+			period, _ = r.Column(i).(*array.Int64)
+		case field.Name == profile.ColumnStacktrace:
+			stacktraces, _ = r.Column(i).(*array.List)
+		case field.Name == valueColumn:
+			values, _ = r.Column(i).(*array.Int64)
+		case strings.HasPrefix(field.Name, profile.ColumnLabelsPrefix):
+			labelNames = append(labelNames, strings.TrimPrefix(field.Name, profile.ColumnLabelsPrefix))
+			labelColumns = append(labelColumns, r.Column(i))
+		}
+	}
+	if name == nil || sampleType == nil || sampleUnit == nil || periodType == nil || periodUnit == nil || period == nil || stacktraces == nil || values == nil {
+		return fmt.Errorf("unexpected record schema: %s", r.Schema())
+	}
+
+	var (
+		locations *array.Dictionary
+		dict      *array.Binary
+	)
+	if l, ok := stacktraces.ListValues().(*array.Dictionary); ok {
+		locations = l
+		dict, _ = l.Dictionary().(*array.Binary)
+	}
+	if dict == nil {
+		return fmt.Errorf("unexpected stacktrace type: %s", stacktraces.DataType())
+	}
+
+	for row := 0; row < int(r.NumRows()); row++ {
+		ls := make(map[string]string, len(labelNames))
+		for i, col := range labelColumns {
+			if col.IsValid(row) {
+				ls[labelNames[i]] = labelValue(col, row)
+				w.labelNames[labelNames[i]] = struct{}{}
+			}
+		}
+		seriesKey := labelsKey(ls)
+
+		s, ok := w.series[seriesKey]
+		if !ok {
+			s = &normalizer.Series{Labels: ls, Samples: [][]*normalizer.NormalizedProfile{{}}}
+			w.series[seriesKey] = s
+		}
+
+		k := windowProfileKey{
+			labels: seriesKey,
+			meta: profile.Meta{
+				Name:       labelValue(name, row),
+				Timestamp:  w.timestamp,
+				Duration:   w.duration,
+				Period:     period.Value(row),
+				PeriodType: profile.ValueType{Type: labelValue(periodType, row), Unit: labelValue(periodUnit, row)},
+				SampleType: profile.ValueType{Type: labelValue(sampleType, row), Unit: labelValue(sampleUnit, row)},
+			},
+		}
+		p, ok := w.profiles[k]
+		if !ok {
+			p = &normalizer.NormalizedProfile{Meta: k.meta}
+			w.profiles[k] = p
+			s.Samples[0] = append(s.Samples[0], p)
+		}
+
+		var locs [][]byte
+		if !stacktraces.IsNull(row) {
+			start, end := stacktraces.ValueOffsets(row)
+			locs = make([][]byte, 0, end-start)
+			for i := int(start); i < int(end); i++ {
+				if locations.IsNull(i) {
+					locs = append(locs, nil)
+					continue
+				}
+				// Copy the location, the record is released after the
+				// callback returns.
+				locs = append(locs, append([]byte(nil), dict.Value(locations.GetValueIndex(i))...))
+			}
+		}
+
+		p.Samples = append(p.Samples, &normalizer.NormalizedSample{
+			Locations: locs,
+			Value:     values.Value(row),
+		})
+	}
+
+	return nil
+}
+
+func (w *windowProfiles) request() normalizer.NormalizedWriteRawRequest {
+	req := normalizer.NormalizedWriteRawRequest{
+		Series:        make([]normalizer.Series, 0, len(w.series)),
+		AllLabelNames: make([]string, 0, len(w.labelNames)),
+	}
+	for _, s := range w.series {
+		req.Series = append(req.Series, *s)
+	}
+	for name := range w.labelNames {
+		req.AllLabelNames = append(req.AllLabelNames, name)
+	}
+	sort.Strings(req.AllLabelNames)
+	return req
+}
+
+func labelsKey(ls map[string]string) string {
+	names := make([]string, 0, len(ls))
+	for name := range ls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(0xff)
+		b.WriteString(ls[name])
+		b.WriteByte(0xfe)
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func TestTierTableName(t *testing.T) {
+	require.Equal(t, "stacktraces", TierTableName("stacktraces", 0))
+	require.Equal(t, "stacktraces_5m", TierTableName("stacktraces", 5*time.Minute))
+	require.Equal(t, "stacktraces_1h", TierTableName("stacktraces", time.Hour))
+}
+
+func TestQuerierDeltaTable(t *testing.T) {
+	q := NewQuerier(nil, nil, nil, "stacktraces", nil, nil,
+		WithRawRetention(48*time.Hour),
+		WithTiers([]Tier{
+			{Table: "stacktraces_5m", Resolution: 5 * time.Minute, Retention: 30 * 24 * time.Hour},
+			{Table: "stacktraces_1h", Resolution: time.Hour, Retention: 365 * 24 * time.Hour},
+		}),
+	)
+
+	now := time.Now()
+	require.Equal(t, "stacktraces", q.deltaTable(now.Add(-time.Hour), now))
+	require.Equal(t, "stacktraces_5m", q.deltaTable(now.Add(-72*time.Hour), now))
+	require.Equal(t, "stacktraces_1h", q.deltaTable(now.Add(-60*24*time.Hour), now))
+	require.Equal(t, "stacktraces_1h", q.deltaTable(now.Add(-2*365*24*time.Hour), now))
+
+	// Without tiers the raw table is always used.
+	q = NewQuerier(nil, nil, nil, "stacktraces", nil, nil, WithRawRetention(time.Hour))
+	require.Equal(t, "stacktraces", q.deltaTable(now.Add(-72*time.Hour), now))
+}
+
+func TestWindowProfiles(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	stringColumn := func(values ...string) arrow.Array {
+		b := array.NewStringBuilder(mem)
+		defer b.Release()
+		b.AppendValues(values, nil)
+		return b.NewArray()
+	}
+	int64Column := func(values ...int64) arrow.Array {
+		b := array.NewInt64Builder(mem)
+		defer b.Release()
+		b.AppendValues(values, nil)
+		return b.NewArray()
+	}
+
+	sb := array.NewListBuilder(mem, &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.Binary})
+	defer sb.Release()
+	vb := sb.ValueBuilder().(*array.BinaryDictionaryBuilder)
+	for _, stack := range [][]string{{"a", "b"}, {"a"}, {"a"}} {
+		sb.Append(true)
+		for _, loc := range stack {
+			require.NoError(t, vb.AppendString(loc))
+		}
+	}
+	stacktraces := sb.NewArray()
+
+	fields := []arrow.Field{
+		{Name: profile.ColumnName, Type: arrow.BinaryTypes.String},
+		{Name: profile.ColumnSampleType, Type: arrow.BinaryTypes.String},
+		{Name: profile.ColumnSampleUnit, Type: arrow.BinaryTypes.String},
+		{Name: profile.ColumnPeriodType, Type: arrow.BinaryTypes.String},
+		{Name: profile.ColumnPeriodUnit, Type: arrow.BinaryTypes.String},
+		{Name: profile.ColumnPeriod, Type: arrow.PrimitiveTypes.Int64},
+		{Name: profile.ColumnStacktrace, Type: stacktraces.DataType()},
+		{Name: profile.ColumnLabelsPrefix + "job", Type: arrow.BinaryTypes.String},
+		{Name: "sum(value)", Type: arrow.PrimitiveTypes.Int64},
+	}
+	columns := []arrow.Array{
+		stringColumn("cpu", "cpu", "cpu"),
+		stringColumn("samples", "samples", "samples"),
+		stringColumn("count", "count", "count"),
+		stringColumn("cpu", "cpu", "cpu"),
+		stringColumn("nanoseconds", "nanoseconds", "nanoseconds"),
+		int64Column(100, 100, 100),
+		stacktraces,
+		stringColumn("api", "api", "db"),
+		int64Column(3, 5, 7),
+	}
+	r := array.NewRecord(arrow.NewSchema(fields, nil), columns, 3)
+	for _, c := range columns {
+		c.Release()
+	}
+	defer r.Release()
+
+	w := newWindowProfiles(60_000, time.Minute.Nanoseconds())
+	require.NoError(t, w.add(r, "sum(value)"))
+
+	req := w.request()
+	require.Equal(t, []string{"job"}, req.AllLabelNames)
+	require.Len(t, req.Series, 2)
+
+	samples := map[string]int{}
+	for _, s := range req.Series {
+		require.Len(t, s.Samples, 1)
+		require.Len(t, s.Samples[0], 1)
+		p := s.Samples[0][0]
+		require.Equal(t, int64(60_000), p.Meta.Timestamp)
+		require.Equal(t, time.Minute.Nanoseconds(), p.Meta.Duration)
+		require.Equal(t, "cpu", p.Meta.Name)
+		samples[s.Labels["job"]] = len(p.Samples)
+	}
+	require.Equal(t, map[string]int{"api": 2, "db": 1}, samples)
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/parquet-go/parquet-go"
+	"github.com/thanos-io/objstore"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// BlockStats describes the contents of a persisted block in more detail
+// than its description, eg. to debug a corrupted block.
+type BlockStats struct {
+	*BlockMeta
+
+	// Series is the number of distinct label sets and profile types.
+	Series    int
+	RowGroups int
+	// Pages is the number of data pages of all columns.
+	Pages   int
+	Columns []ColumnStats
+}
+
+// ColumnStats describes how a column of a block is stored.
+type ColumnStats struct {
+	Name              string
+	Encodings         []string
+	Compression       string
+	CompressedBytes   int64
+	UncompressedBytes int64
+}
+
+// InspectBlocks returns the stats of the blocks in the bucket. Unlike
+// listing the blocks it reads every row, so it is meant to run offline
+// against a local copy of the blocks.
+func InspectBlocks(ctx context.Context, bucket objstore.Bucket) ([]*BlockStats, error) {
+	blocks, err := listBlocks(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Blocks{bucket: bucket}
+	stats := make([]*BlockStats, 0, len(blocks))
+	for _, block := range blocks {
+		meta, err := b.describe(ctx, block)
+		if err != nil {
+			return nil, fmt.Errorf("describe block %s: %w", block.id, err)
+		}
+
+		s := &BlockStats{BlockMeta: meta}
+		series := map[uint64]struct{}{}
+		columns := map[string]*ColumnStats{}
+		for _, name := range block.objects {
+			if !strings.HasSuffix(name, ".parquet") {
+				continue
+			}
+			if err := inspectFile(ctx, bucket, name, s, columns, series); err != nil {
+				return nil, fmt.Errorf("inspect %s: %w", name, err)
+			}
+		}
+		s.Series = len(series)
+		for _, c := range columns {
+			s.Columns = append(s.Columns, *c)
+		}
+		sort.Slice(s.Columns, func(i, j int) bool {
+			return s.Columns[i].Name < s.Columns[j].Name
+		})
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+func inspectFile(ctx context.Context, bucket objstore.Bucket, name string, s *BlockStats, columns map[string]*ColumnStats, series map[uint64]struct{}) error {
+	attrs, err := bucket.Attributes(ctx, name)
+	if err != nil {
+		return err
+	}
+	f, err := parquet.OpenFile(&bucketReaderAt{ctx: ctx, bucket: bucket, name: name}, attrs.Size,
+		parquet.SkipBloomFilters(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, rg := range f.Metadata().RowGroups {
+		for _, cc := range rg.Columns {
+			md := cc.MetaData
+			path := strings.Join(md.PathInSchema, ".")
+			c, ok := columns[path]
+			if !ok {
+				c = &ColumnStats{Name: path}
+				columns[path] = c
+			}
+			c.Compression = md.Codec.String()
+			c.CompressedBytes += md.TotalCompressedSize
+			c.UncompressedBytes += md.TotalUncompressedSize
+			for _, e := range md.Encoding {
+				if !slices.Contains(c.Encodings, e.String()) {
+					c.Encodings = append(c.Encodings, e.String())
+				}
+			}
+		}
+	}
+
+	paths := f.Schema().Columns()
+	seriesColumns := make([]bool, len(paths))
+	for i, p := range paths {
+		switch name := strings.Join(p, "."); name {
+		case profile.ColumnName, profile.ColumnSampleType, profile.ColumnSampleUnit, profile.ColumnPeriodType, profile.ColumnPeriodUnit:
+			seriesColumns[i] = true
+		default:
+			seriesColumns[i] = strings.HasPrefix(name, profile.ColumnLabelsPrefix)
+		}
+	}
+
+	for _, rg := range f.RowGroups() {
+		s.RowGroups++
+		for _, cc := range rg.ColumnChunks() {
+			if idx, err := cc.OffsetIndex(); err == nil {
+				s.Pages += idx.NumPages()
+			}
+		}
+		if err := readSeries(rg, seriesColumns, series); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSeries adds the hashes of the series of the rows of the row group.
+func readSeries(rg parquet.RowGroup, seriesColumns []bool, series map[uint64]struct{}) error {
+	rows := rg.Rows()
+	defer rows.Close()
+
+	buf := make([]parquet.Row, 512)
+	h := xxhash.New()
+	var column [4]byte
+	for {
+		n, err := rows.ReadRows(buf)
+		for _, row := range buf[:n] {
+			h.Reset()
+			for _, v := range row {
+				if v.IsNull() || !seriesColumns[v.Column()] {
+					continue
+				}
+				binary.LittleEndian.PutUint32(column[:], uint32(v.Column()))
+				_, _ = h.Write(column[:])
+				_, _ = h.Write(v.ByteArray())
+				_, _ = h.Write([]byte{0})
+			}
+			series[h.Sum64()] = struct{}{}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestInspectBlocks(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+
+	type row struct {
+		Name      string `parquet:"name,dict"`
+		Job       string `parquet:"labels.job,dict"`
+		Timestamp int64  `parquet:"timestamp"`
+		Value     int64  `parquet:"value"`
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, parquet.Write(buf, []row{
+		{Name: "cpu", Job: "api", Timestamp: 1000, Value: 1},
+		{Name: "cpu", Job: "api", Timestamp: 2000, Value: 2},
+		{Name: "cpu", Job: "db", Timestamp: 2000, Value: 3},
+		{Name: "memory", Job: "api", Timestamp: 3000, Value: 4},
+	}))
+	id := ulid.MustNew(ulid.Timestamp(time.Unix(10, 0)), bytes.NewReader(make([]byte, 16))).String()
+	require.NoError(t, bucket.Upload(ctx, "parca/stacktraces/"+id+"/data.parquet", bytes.NewReader(buf.Bytes())))
+
+	stats, err := InspectBlocks(ctx, bucket)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+
+	s := stats[0]
+	require.Equal(t, id, s.ID)
+	require.Equal(t, int64(4), s.Rows)
+	require.Equal(t, time.UnixMilli(1000), s.MinTime)
+	require.Equal(t, time.UnixMilli(3000), s.MaxTime)
+	require.Equal(t, 3, s.Series)
+	require.Equal(t, 1, s.RowGroups)
+
+	names := make([]string, 0, len(s.Columns))
+	for _, c := range s.Columns {
+		names = append(names, c.Name)
+		require.NotEmpty(t, c.Encodings)
+		require.Positive(t, c.CompressedBytes)
+	}
+	require.Equal(t, []string{"labels.job", "name", "timestamp", "value"}, names)
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// ProfileMetadata is the metadata written along with a profile.
+type ProfileMetadata struct {
+	Labels    map[string]string `json:"labels"`
+	Timestamp time.Time         `json:"timestamp"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// ProfileMetadata returns the metadata of the profiles matching the query in
+// the time range, ordered by time. Only the profile name of the profile type
+// of the query is matched, so the metadata is shared by all sample types of
+// a profile. The metadata of deleted profiles is excluded.
+func (q *Querier) ProfileMetadata(ctx context.Context, query string, start, end time.Time) ([]*ProfileMetadata, error) {
+	ctx, span := q.tracer.Start(ctx, "Querier/ProfileMetadata")
+	defer span.End()
+
+	qp, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	labelExprs, err := MatchersToBooleanExpressions(qp.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	exclusions, err := q.tombstones.metadataExclusions()
+	if err != nil {
+		return nil, err
+	}
+	exprs := append([]logicalplan.Expr{
+		logicalplan.Col(profile.ColumnName).Eq(logicalplan.Literal(qp.Meta.Name)),
+		logicalplan.Col(profile.ColumnTimestamp).GtEq(logicalplan.Literal(timestamp.FromTime(start))),
+		logicalplan.Col(profile.ColumnTimestamp).LtEq(logicalplan.Literal(timestamp.FromTime(end))),
+	}, labelExprs...)
+	exprs = append(exprs, exclusions...)
+
+	var res []*ProfileMetadata
+	err = q.engine.ScanTable(profile.MetadataTableName).
+		Filter(logicalplan.And(exprs...)).
+		Project(
+			logicalplan.DynCol(profile.ColumnLabels),
+			logicalplan.DynCol(profile.ColumnMetadata),
+			logicalplan.Col(profile.ColumnTimestamp),
+		).
+		Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+			rows, err := profileMetadataFromRecord(r)
+			if err != nil {
+				return err
+			}
+			res = append(res, rows...)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if !res[i].Timestamp.Equal(res[j].Timestamp) {
+			return res[i].Timestamp.Before(res[j].Timestamp)
+		}
+		return labels.FromMap(res[i].Labels).String() < labels.FromMap(res[j].Labels).String()
+	})
+	return res, nil
+}
+
+func profileMetadataFromRecord(r arrow.Record) ([]*ProfileMetadata, error) {
+	var timestamps *array.Int64
+	for i, field := range r.Schema().Fields() {
+		if field.Name == profile.ColumnTimestamp {
+			timestamps, _ = r.Column(i).(*array.Int64)
+		}
+	}
+	if timestamps == nil {
+		return nil, fmt.Errorf("unexpected record schema: %s", r.Schema())
+	}
+
+	rows := make([]*ProfileMetadata, r.NumRows())
+	for i := range rows {
+		rows[i] = &ProfileMetadata{
+			Labels:    map[string]string{},
+			Timestamp: timestamp.Time(timestamps.Value(i)),
+			Metadata:  map[string]string{},
+		}
+	}
+	for i, field := range r.Schema().Fields() {
+		var (
+			m    func(*ProfileMetadata) map[string]string
+			name string
+		)
+		switch {
+		case strings.HasPrefix(field.Name, profile.ColumnLabelsPrefix):
+			m = func(p *ProfileMetadata) map[string]string { return p.Labels }
+			name = strings.TrimPrefix(field.Name, profile.ColumnLabelsPrefix)
+		case strings.HasPrefix(field.Name, profile.ColumnMetadataPrefix):
+			m = func(p *ProfileMetadata) map[string]string { return p.Metadata }
+			name = strings.TrimPrefix(field.Name, profile.ColumnMetadataPrefix)
+		default:
+			continue
+		}
+
+		col := r.Column(i)
+		for row, p := range rows {
+			if col.IsValid(row) {
+				m(p)[name] = labelValue(col, row)
+			}
+		}
+	}
+	return rows, nil
+}
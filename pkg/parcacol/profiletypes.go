@@ -0,0 +1,147 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// ProfileTypeCount is a profile type stored for the series of a selector,
+// with the number of samples stored.
+type ProfileTypeCount struct {
+	// ProfileType is the profile type as used in queries, eg.
+	// "parca_agent:samples:count:cpu:nanoseconds:delta".
+	ProfileType string `json:"profileType"`
+	Name        string `json:"name"`
+	SampleType  string `json:"sampleType"`
+	SampleUnit  string `json:"sampleUnit"`
+	PeriodType  string `json:"periodType"`
+	PeriodUnit  string `json:"periodUnit"`
+	Delta       bool   `json:"delta"`
+	Samples     int64  `json:"samples"`
+}
+
+// ProfileTypeCounts returns the profile types stored in the time range for
+// the series matching the selector, a set of label matchers, ordered by
+// profile type. An empty selector matches all series.
+func (q *Querier) ProfileTypeCounts(ctx context.Context, selector string, start, end time.Time) ([]*ProfileTypeCount, error) {
+	ctx, span := q.tracer.Start(ctx, "Querier/ProfileTypeCounts")
+	defer span.End()
+
+	exprs := []logicalplan.Expr{
+		logicalplan.Col(profile.ColumnTimestamp).GtEq(logicalplan.Literal(timestamp.FromTime(start))),
+		logicalplan.Col(profile.ColumnTimestamp).LtEq(logicalplan.Literal(timestamp.FromTime(end))),
+	}
+	if selector != "" {
+		matchers, err := parser.ParseMetricSelector(selector)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "parse selector: %v", err)
+		}
+		labelExprs, err := MatchersToBooleanExpressions(matchers)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "failed to build query")
+		}
+		exprs = append(exprs, labelExprs...)
+	}
+	exclusions, err := q.tombstones.exclusions()
+	if err != nil {
+		return nil, err
+	}
+	exprs = append(exprs, exclusions...)
+
+	count := logicalplan.Count(logicalplan.Col(profile.ColumnValue))
+	groupBy := []string{
+		profile.ColumnName,
+		profile.ColumnSampleType,
+		profile.ColumnSampleUnit,
+		profile.ColumnPeriodType,
+		profile.ColumnPeriodUnit,
+	}
+	groupExprs := make([]logicalplan.Expr, 0, len(groupBy)+1)
+	for _, c := range groupBy {
+		groupExprs = append(groupExprs, logicalplan.Col(c))
+	}
+	// Profiles are delta profiles if they have a duration, the durations
+	// are merged below.
+	groupExprs = append(groupExprs, logicalplan.Col(profile.ColumnDuration))
+
+	types := map[string]*ProfileTypeCount{}
+	err = q.engine.ScanTable(q.tableName).
+		Filter(logicalplan.And(exprs...)).
+		Aggregate([]*logicalplan.AggregationFunction{count}, groupExprs).
+		Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+			columns := map[string]arrow.Array{}
+			for i, field := range r.Schema().Fields() {
+				columns[field.Name] = r.Column(i)
+			}
+			durations, _ := columns[profile.ColumnDuration].(*array.Int64)
+			counts, _ := columns[count.Name()].(*array.Int64)
+			if durations == nil || counts == nil {
+				return fmt.Errorf("unexpected record schema: %s", r.Schema())
+			}
+			for _, c := range groupBy {
+				if columns[c] == nil {
+					return fmt.Errorf("unexpected record schema: %s", r.Schema())
+				}
+			}
+
+			for i := 0; i < int(r.NumRows()); i++ {
+				t := &ProfileTypeCount{
+					Name:       labelValue(columns[profile.ColumnName], i),
+					SampleType: labelValue(columns[profile.ColumnSampleType], i),
+					SampleUnit: labelValue(columns[profile.ColumnSampleUnit], i),
+					PeriodType: labelValue(columns[profile.ColumnPeriodType], i),
+					PeriodUnit: labelValue(columns[profile.ColumnPeriodUnit], i),
+					Delta:      durations.Value(i) > 0,
+				}
+				t.ProfileType = fmt.Sprintf("%s:%s:%s:%s:%s", t.Name, t.SampleType, t.SampleUnit, t.PeriodType, t.PeriodUnit)
+				if t.Delta {
+					t.ProfileType += ":delta"
+				}
+
+				if existing, ok := types[t.ProfileType]; ok {
+					t = existing
+				} else {
+					types[t.ProfileType] = t
+				}
+				t.Samples += counts.Value(i)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*ProfileTypeCount, 0, len(types))
+	for _, t := range types {
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ProfileType < res[j].ProfileType
+	})
+	return res, nil
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/prometheus/prometheus/promql/parser"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -65,8 +66,9 @@ func NewQuerier(
 	tableName string,
 	symbolizer Symbolizer,
 	pool memory.Allocator,
+	opts ...QuerierOption,
 ) *Querier {
-	return &Querier{
+	q := &Querier{
 		logger:     logger,
 		tracer:     tracer,
 		engine:     engine,
@@ -74,6 +76,20 @@ func NewQuerier(
 		symbolizer: symbolizer,
 		pool:       pool,
 	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+type QuerierOption func(*Querier)
+
+// WithTiers configures the downsampled tiers delta profiles are queried from,
+// ordered from the finest to the coarsest resolution.
+func WithTiers(tiers []Tier) QuerierOption {
+	return func(q *Querier) {
+		q.tiers = tiers
+	}
 }
 
 type Querier struct {
@@ -83,6 +99,92 @@ type Querier struct {
 	symbolizer Symbolizer
 	tracer     trace.Tracer
 	pool       memory.Allocator
+
+	// rawRetention is how far back the raw table serves queries and tiers
+	// are downsampled copies of it, see WithTiers.
+	rawRetention time.Duration
+	tiers        []Tier
+	tierPolicy   TierPolicy
+
+	tombstones *Tombstones
+	// pins are the read snapshots of the requests in flight, see
+	// WithSnapshotPins.
+	pins *SnapshotPins
+
+	// symbolizationConcurrency is the number of build IDs symbolized
+	// concurrently, see WithSymbolizationConcurrency.
+	symbolizationConcurrency int
+	// frames folds the stacks built from symbolized locations, see
+	// WithFramePipeline.
+	frames *symbolizer.FramePipeline
+}
+
+// WithSymbolizationConcurrency symbolizes the locations of up to n build IDs
+// of a query concurrently.
+func WithSymbolizationConcurrency(n int) QuerierOption {
+	return func(q *Querier) {
+		q.symbolizationConcurrency = n
+	}
+}
+
+// WithFramePipeline folds the stacks of queried profiles with the stack
+// transforms of the pipeline, eg. fold_async, while they are built from the
+// symbolized locations.
+func WithFramePipeline(p *symbolizer.FramePipeline) QuerierOption {
+	return func(q *Querier) {
+		q.frames = p
+	}
+}
+
+// WithTombstones excludes the profiles deleted by the tombstones from all
+// queries.
+func WithTombstones(t *Tombstones) QuerierOption {
+	return func(q *Querier) {
+		q.tombstones = t
+	}
+}
+
+// WithSnapshotPins pins the blocks read snapshots may read, so that the
+// size retention and the block admin sharing the pins don't delete them.
+func WithSnapshotPins(p *SnapshotPins) QuerierOption {
+	return func(q *Querier) {
+		q.pins = p
+	}
+}
+
+// ContextWithReadSnapshot returns a context whose queries read the same
+// snapshot of the storage, see SnapshotPins.ContextWithReadSnapshot.
+func (q *Querier) ContextWithReadSnapshot(ctx context.Context) (context.Context, func()) {
+	return q.pins.ContextWithReadSnapshot(ctx)
+}
+
+// WithRawRetention sets how far back delta profiles are queried from the raw
+// table before falling back to the downsampled tiers.
+func WithRawRetention(retention time.Duration) QuerierOption {
+	return func(q *Querier) {
+		q.rawRetention = retention
+	}
+}
+
+// deltaTable returns the table to query delta profiles from for a range
+// starting at the given time. It is the finest resolution table whose
+// retention, relative to now, covers the start of the range, or the
+// coarsest tier if none does. The most recent window of a tier is only
+// available once it was downsampled.
+func (q *Querier) deltaTable(start, now time.Time) string {
+	if len(q.tiers) == 0 || covers(q.rawRetention, start, now) {
+		return q.tableName
+	}
+	for _, t := range q.tiers {
+		if covers(t.Retention, start, now) {
+			return t.Table
+		}
+	}
+	return q.tiers[len(q.tiers)-1].Table
+}
+
+func covers(retention time.Duration, start, now time.Time) bool {
+	return retention == 0 || !start.Before(now.Add(-retention))
 }
 
 func (q *Querier) Labels(
@@ -97,12 +199,19 @@ func (q *Querier) Labels(
 
 	if profileType != "" {
 		matchers := strings.Join(match, ",")
-		_, selectorExprs, err := QueryToFilterExprs(profileType + "{" + matchers + "}")
+		_, selectorExprs, err := q.queryToFilterExprs(profileType + "{" + matchers + "}")
 		if err != nil {
 			return nil, err
 		}
 
 		filterExpr = append(filterExpr, selectorExprs...)
+	} else {
+		exclusions, err := q.tombstones.exclusions()
+		if err != nil {
+			return nil, err
+		}
+
+		filterExpr = append(filterExpr, exclusions...)
 	}
 
 	if startTime.Unix() != 0 && endTime.Unix() != 0 {
@@ -160,12 +269,19 @@ func (q *Querier) Values(
 	filterExpr := []logicalplan.Expr{}
 
 	if profileType != "" {
-		_, selectorExprs, err := QueryToFilterExprs(profileType + "{}")
+		_, selectorExprs, err := q.queryToFilterExprs(profileType + "{}")
 		if err != nil {
 			return nil, err
 		}
 
 		filterExpr = append(filterExpr, selectorExprs...)
+	} else {
+		exclusions, err := q.tombstones.exclusions()
+		if err != nil {
+			return nil, err
+		}
+
+		filterExpr = append(filterExpr, exclusions...)
 	}
 
 	if startTime.Unix() != 0 && endTime.Unix() != 0 {
@@ -268,22 +384,44 @@ func QueryToFilterExprs(query string) (QueryParts, []logicalplan.Expr, error) {
 		return qp, nil, status.Error(codes.InvalidArgument, "failed to build query")
 	}
 
-	exprs := append([]logicalplan.Expr{
-		logicalplan.Col(profile.ColumnName).Eq(logicalplan.Literal(qp.Meta.Name)),
-		logicalplan.Col(profile.ColumnSampleType).Eq(logicalplan.Literal(qp.Meta.SampleType.Type)),
-		logicalplan.Col(profile.ColumnSampleUnit).Eq(logicalplan.Literal(qp.Meta.SampleType.Unit)),
-		logicalplan.Col(profile.ColumnPeriodType).Eq(logicalplan.Literal(qp.Meta.PeriodType.Type)),
-		logicalplan.Col(profile.ColumnPeriodUnit).Eq(logicalplan.Literal(qp.Meta.PeriodType.Unit)),
-	}, labelFilterExpressions...)
+	return qp, append(profileTypeExprs(qp.Meta, qp.Delta), labelFilterExpressions...), nil
+}
 
+// profileTypeExprs returns the expressions selecting the rows of the profile
+// type.
+func profileTypeExprs(meta profile.Meta, delta bool) []logicalplan.Expr {
 	deltaPlan := logicalplan.Col(profile.ColumnDuration).Eq(logicalplan.Literal(0))
-	if qp.Delta {
+	if delta {
 		deltaPlan = logicalplan.Col(profile.ColumnDuration).NotEq(logicalplan.Literal(0))
 	}
 
-	exprs = append(exprs, deltaPlan)
+	return []logicalplan.Expr{
+		logicalplan.Col(profile.ColumnName).Eq(logicalplan.Literal(meta.Name)),
+		logicalplan.Col(profile.ColumnSampleType).Eq(logicalplan.Literal(meta.SampleType.Type)),
+		logicalplan.Col(profile.ColumnSampleUnit).Eq(logicalplan.Literal(meta.SampleType.Unit)),
+		logicalplan.Col(profile.ColumnPeriodType).Eq(logicalplan.Literal(meta.PeriodType.Type)),
+		logicalplan.Col(profile.ColumnPeriodUnit).Eq(logicalplan.Literal(meta.PeriodType.Unit)),
+		deltaPlan,
+	}
+}
+
+// queryToFilterExprs is QueryToFilterExprs excluding deleted profiles and,
+// unless selected, the synthetic series of rollups.
+func (q *Querier) queryToFilterExprs(query string) (QueryParts, []logicalplan.Expr, error) {
+	qp, exprs, err := QueryToFilterExprs(query)
+	if err != nil {
+		return qp, nil, err
+	}
+	if !selectsRollups(qp.Matchers) {
+		exprs = append(exprs, rollupExclusion())
+	}
 
-	return qp, exprs, nil
+	exclusions, err := q.tombstones.exclusions()
+	if err != nil {
+		return qp, nil, err
+	}
+
+	return qp, append(exprs, exclusions...), nil
 }
 
 type QueryParts struct {
@@ -312,30 +450,41 @@ func ParseQuery(query string) (QueryParts, error) {
 		return QueryParts{}, status.Error(codes.InvalidArgument, "query must contain a profile-type selection")
 	}
 
-	parts := strings.Split(nameLabel.Value, ":")
+	meta, delta, err := parseProfileType(nameLabel.Value)
+	if err != nil {
+		return QueryParts{}, err
+	}
+
+	return QueryParts{
+		Meta:     meta,
+		Delta:    delta,
+		Matchers: sel,
+	}, nil
+}
+
+// parseProfileType parses a profile-type selection of the form
+// <name>:<sample-type>:<sample-unit>:<period-type>:<period-unit>(:delta).
+func parseProfileType(s string) (profile.Meta, bool, error) {
+	parts := strings.Split(s, ":")
 	if len(parts) != 5 && len(parts) != 6 {
-		return QueryParts{}, status.Errorf(codes.InvalidArgument, "profile-type selection must be of the form <name>:<sample-type>:<sample-unit>:<period-type>:<period-unit>(:delta), got(%d): %q", len(parts), nameLabel.Value)
+		return profile.Meta{}, false, status.Errorf(codes.InvalidArgument, "profile-type selection must be of the form <name>:<sample-type>:<sample-unit>:<period-type>:<period-unit>(:delta), got(%d): %q", len(parts), s)
 	}
 	delta := false
 	if len(parts) == 6 && parts[5] == "delta" {
 		delta = true
 	}
 
-	return QueryParts{
-		Meta: profile.Meta{
-			Name: parts[0],
-			SampleType: profile.ValueType{
-				Type: parts[1],
-				Unit: parts[2],
-			},
-			PeriodType: profile.ValueType{
-				Type: parts[3],
-				Unit: parts[4],
-			},
+	return profile.Meta{
+		Name: parts[0],
+		SampleType: profile.ValueType{
+			Type: parts[1],
+			Unit: parts[2],
 		},
-		Delta:    delta,
-		Matchers: sel,
-	}, nil
+		PeriodType: profile.ValueType{
+			Type: parts[3],
+			Unit: parts[4],
+		},
+	}, delta, nil
 }
 
 func (q *Querier) QueryRange(
@@ -346,11 +495,28 @@ func (q *Querier) QueryRange(
 	limit uint32,
 	sumBy []string,
 ) ([]*pb.MetricsSeries, error) {
-	queryParts, selectorExprs, err := QueryToFilterExprs(query)
+	queryParts, selectorExprs, err := q.queryToFilterExprs(query)
 	if err != nil {
 		return nil, err
 	}
 
+	return q.queryRange(ctx, queryParts, selectorExprs, startTime, endTime, step, sumBy)
+}
+
+// TimeRange is a time window of a range query.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (q *Querier) queryRange(
+	ctx context.Context,
+	queryParts QueryParts,
+	selectorExprs []logicalplan.Expr,
+	startTime, endTime time.Time,
+	step time.Duration,
+	sumBy []string,
+) ([]*pb.MetricsSeries, error) {
 	start := timestamp.FromTime(startTime)
 	end := timestamp.FromTime(endTime)
 
@@ -359,6 +525,10 @@ func (q *Querier) QueryRange(
 		step = time.Second
 	}
 
+	if queryParts.Delta && len(q.tiers) > 0 && q.tierPolicy == TierPolicyStitch {
+		return q.queryRangeStitched(ctx, queryParts, selectorExprs, startTime, endTime, step, sumBy)
+	}
+
 	exprs := append(
 		selectorExprs,
 		logicalplan.Col(profile.ColumnTimestamp).Gt(logicalplan.Literal(start)),
@@ -367,17 +537,36 @@ func (q *Querier) QueryRange(
 
 	filterExpr := logicalplan.And(exprs...)
 
+	table := q.tableName
+	if queryParts.Delta {
+		table = q.deltaTable(startTime, readTime(ctx))
+		if len(q.tiers) > 0 {
+			reportResolutions(ctx, []Segment{q.segment(table, startTime, endTime)})
+		}
+	}
+	sel := queryStatsFromContext(ctx).selection(table, filterExpr)
+	begin := time.Now()
+
+	var (
+		series []*pb.MetricsSeries
+		err    error
+	)
 	if queryParts.Delta {
-		return q.queryRangeDelta(
+		series, err = q.queryRangeDelta(
 			ctx,
+			table,
 			filterExpr,
 			step,
 			queryParts.Meta,
 			sumBy,
+			sel,
 		)
+	} else {
+		series, err = q.queryRangeNonDelta(ctx, filterExpr, step, sumBy, sel)
 	}
+	sel.finish(begin, len(series))
 
-	return q.queryRangeNonDelta(ctx, filterExpr, step, sumBy)
+	return series, err
 }
 
 const (
@@ -387,10 +576,12 @@ const (
 
 func (q *Querier) queryRangeDelta(
 	ctx context.Context,
+	table string,
 	filterExpr logicalplan.Expr,
 	step time.Duration,
 	m profile.Meta,
 	sumBy []string,
+	sel *Selection,
 ) ([]*pb.MetricsSeries, error) {
 	resultType := m.SampleType
 
@@ -469,7 +660,7 @@ func (q *Querier) queryRangeDelta(
 		).Alias(ValuePerSecond)
 	}
 
-	err := q.engine.ScanTable(q.tableName).
+	err := q.engine.ScanTable(table).
 		Filter(filterExpr).
 		Project(preProjection...).
 		Aggregate(
@@ -499,6 +690,7 @@ func (q *Querier) queryRangeDelta(
 			r.Retain()
 			records = append(records, r)
 			rows += int(r.NumRows())
+			sel.record(r)
 			return nil
 		})
 	if err != nil {
@@ -642,7 +834,7 @@ func getSumByAggregateExprs(sumBy []string) []logicalplan.Expr {
 	return exprs
 }
 
-func (q *Querier) queryRangeNonDelta(ctx context.Context, filterExpr logicalplan.Expr, step time.Duration, sumBy []string) ([]*pb.MetricsSeries, error) {
+func (q *Querier) queryRangeNonDelta(ctx context.Context, filterExpr logicalplan.Expr, step time.Duration, sumBy []string, sel *Selection) ([]*pb.MetricsSeries, error) {
 	records := []arrow.Record{}
 	defer func() {
 		for _, r := range records {
@@ -668,6 +860,7 @@ func (q *Querier) queryRangeNonDelta(ctx context.Context, filterExpr logicalplan
 			r.Retain()
 			records = append(records, r)
 			rows += int(r.NumRows())
+			sel.record(r)
 			return nil
 		})
 	if err != nil {
@@ -793,7 +986,16 @@ func (q *Querier) ProfileTypes(
 	seen := map[string]struct{}{}
 	res := []*pb.ProfileType{}
 
-	err := q.engine.ScanTable(q.tableName).
+	exclusions, err := q.tombstones.exclusions()
+	if err != nil {
+		return nil, err
+	}
+
+	builder := q.engine.ScanTable(q.tableName)
+	if len(exclusions) > 0 {
+		builder = builder.Filter(logicalplan.And(exclusions...))
+	}
+	err = builder.
 		Distinct(
 			logicalplan.Col(profile.ColumnName),
 			logicalplan.Col(profile.ColumnSampleType),
@@ -934,6 +1136,8 @@ func (q *Querier) SymbolizeArrowRecord(
 	queryParts QueryParts,
 	invertCallStacks bool,
 ) ([]arrow.Record, error) {
+	defer ObserveStage(ctx, StageSymbolize, time.Now())
+
 	res := make([]arrow.Record, len(records))
 
 	for i, r := range records {
@@ -1022,6 +1226,11 @@ func (q *Querier) resolveStacks(
 		return nil, err
 	}
 
+	foldStacks := q.frames.FoldsStacks()
+	var (
+		stack   []*profile.Location
+		encoded [][]byte
+	)
 	for i := 0; i < stacktraceColumn.Len(); i++ {
 		if stacktraceColumn.IsNull(i) {
 			w.LocationsList.AppendNull()
@@ -1030,100 +1239,142 @@ func (q *Querier) resolveStacks(
 		w.LocationsList.Append(true)
 
 		start, end := stacktraceColumn.ValueOffsets(i)
+		if foldStacks {
+			// The stack is folded before it is written, so that no reader
+			// ever sees the unfolded frames. Locations that weren't
+			// symbolized here are kept in place and written from their
+			// encoding.
+			stack = stack[:0]
+			for j := int(start); j < int(end); j++ {
+				stack = append(stack, symbolizedLocations[values.GetValueIndex(j)])
+			}
+			folded := q.frames.FoldStack(stack)
+
+			encoded = encoded[:0]
+			j := int(start)
+			for _, loc := range folded {
+				var data []byte
+				if loc == nil {
+					for symbolizedLocations[values.GetValueIndex(j)] != nil {
+						j++
+					}
+					data = valueDict.Value(values.GetValueIndex(j))
+					j++
+				}
+				encoded = append(encoded, data)
+			}
+
+			for k := range folded {
+				kWithInversion := handleIndexInversion(invertCallStacks, 0, len(folded), k)
+				if err := appendLocation(w, folded[kWithInversion], encoded[kWithInversion]); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
 		for j := int(start); j < int(end); j++ {
 			jWithInversion := handleIndexInversion(invertCallStacks, int(start), int(end), j)
-			w.Locations.Append(true)
 			idx := values.GetValueIndex(jWithInversion)
+			if err := appendLocation(w, symbolizedLocations[idx], valueDict.Value(idx)); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-			if symbolizedLocations[idx] != nil {
-				// We symbolized the location successfully, so we'll use the symbolized location.
-				w.Addresses.Append(symbolizedLocations[idx].Address)
-				if len(symbolizedLocations[idx].Mapping.BuildId) > 0 {
-					if err := w.MappingBuildID.Append(stringToBytes(symbolizedLocations[idx].Mapping.BuildId)); err != nil {
-						return nil, fmt.Errorf("failed to append mapping build id: %w", err)
+	return w.RecordBuilder.NewRecord(), nil
+}
+
+// appendLocation appends the location to the stack being written. A nil
+// location wasn't symbolized by the querier and is written from its encoding.
+func appendLocation(w profile.LocationsWriter, loc *profile.Location, encodedLocation []byte) error {
+	w.Locations.Append(true)
+
+	if loc != nil {
+		// We symbolized the location successfully, so we'll use the symbolized location.
+		w.Addresses.Append(loc.Address)
+		if len(loc.Mapping.BuildId) > 0 {
+			if err := w.MappingBuildID.Append(stringToBytes(loc.Mapping.BuildId)); err != nil {
+				return fmt.Errorf("failed to append mapping build id: %w", err)
+			}
+		} else {
+			if err := w.MappingBuildID.Append([]byte{}); err != nil {
+				return fmt.Errorf("failed to append empty mapping build id: %w", err)
+			}
+		}
+		if len(loc.Mapping.File) > 0 {
+			if err := w.MappingFile.Append(stringToBytes(loc.Mapping.File)); err != nil {
+				return fmt.Errorf("failed to append mapping file: %w", err)
+			}
+		} else {
+			if err := w.MappingFile.Append([]byte{}); err != nil {
+				return fmt.Errorf("failed to append empty mapping file: %w", err)
+			}
+		}
+		w.MappingStart.Append(loc.Mapping.Start)
+		w.MappingLimit.Append(loc.Mapping.Limit)
+		w.MappingOffset.Append(loc.Mapping.Offset)
+
+		if len(loc.Lines) > 0 {
+			w.Lines.Append(true)
+			for _, line := range loc.Lines {
+				w.Line.Append(true)
+				w.LineNumber.Append(line.Line)
+				if len(line.Function.Name) > 0 {
+					if err := w.FunctionName.Append(stringToBytes(line.Function.Name)); err != nil {
+						return fmt.Errorf("failed to append function name: %w", err)
 					}
 				} else {
-					if err := w.MappingBuildID.Append([]byte{}); err != nil {
-						return nil, fmt.Errorf("failed to append empty mapping build id: %w", err)
+					if err := w.FunctionName.Append([]byte{}); err != nil {
+						return fmt.Errorf("failed to append empty function name: %w", err)
 					}
 				}
-				if len(symbolizedLocations[idx].Mapping.File) > 0 {
-					if err := w.MappingFile.Append(stringToBytes(symbolizedLocations[idx].Mapping.File)); err != nil {
-						return nil, fmt.Errorf("failed to append mapping file: %w", err)
+				if len(line.Function.SystemName) > 0 {
+					if err := w.FunctionSystemName.Append(stringToBytes(line.Function.SystemName)); err != nil {
+						return fmt.Errorf("failed to append function system name: %w", err)
 					}
 				} else {
-					if err := w.MappingFile.Append([]byte{}); err != nil {
-						return nil, fmt.Errorf("failed to append empty mapping file: %w", err)
+					if err := w.FunctionSystemName.Append([]byte{}); err != nil {
+						return fmt.Errorf("failed to append empty function system name: %w", err)
 					}
 				}
-				w.MappingStart.Append(symbolizedLocations[idx].Mapping.Start)
-				w.MappingLimit.Append(symbolizedLocations[idx].Mapping.Limit)
-				w.MappingOffset.Append(symbolizedLocations[idx].Mapping.Offset)
-
-				if len(symbolizedLocations[idx].Lines) > 0 {
-					w.Lines.Append(true)
-					for _, line := range symbolizedLocations[idx].Lines {
-						w.Line.Append(true)
-						w.LineNumber.Append(line.Line)
-						if len(line.Function.Name) > 0 {
-							if err := w.FunctionName.Append(stringToBytes(line.Function.Name)); err != nil {
-								return nil, fmt.Errorf("failed to append function name: %w", err)
-							}
-						} else {
-							if err := w.FunctionName.Append([]byte{}); err != nil {
-								return nil, fmt.Errorf("failed to append empty function name: %w", err)
-							}
-						}
-						if len(line.Function.SystemName) > 0 {
-							if err := w.FunctionSystemName.Append(stringToBytes(line.Function.SystemName)); err != nil {
-								return nil, fmt.Errorf("failed to append function system name: %w", err)
-							}
-						} else {
-							if err := w.FunctionSystemName.Append([]byte{}); err != nil {
-								return nil, fmt.Errorf("failed to append empty function system name: %w", err)
-							}
-						}
-						if len(line.Function.Filename) > 0 {
-							if err := w.FunctionFilename.Append(stringToBytes(line.Function.Filename)); err != nil {
-								return nil, fmt.Errorf("failed to append function filename: %w", err)
-							}
-						} else {
-							if err := w.FunctionFilename.Append([]byte{}); err != nil {
-								return nil, fmt.Errorf("failed to append empty function filename: %w", err)
-							}
-						}
-						w.FunctionStartLine.Append(line.Function.StartLine)
+				if len(line.Function.Filename) > 0 {
+					if err := w.FunctionFilename.Append(stringToBytes(line.Function.Filename)); err != nil {
+						return fmt.Errorf("failed to append function filename: %w", err)
 					}
 				} else {
-					w.Lines.Append(false)
+					if err := w.FunctionFilename.Append([]byte{}); err != nil {
+						return fmt.Errorf("failed to append empty function filename: %w", err)
+					}
 				}
-				continue
+				w.FunctionStartLine.Append(line.Function.StartLine)
 			}
-
-			encodedLocation := valueDict.Value(idx)
-			res, err := profile.DecodeInto(w, encodedLocation)
-			if err != nil {
-				return nil, err
-			}
-			if res.WroteLines {
-				w.Addresses.Append(res.Addr)
-				continue
-			}
-			if res.Addr == 0 || len(res.BuildID) == 0 {
-				w.Addresses.Append(res.Addr)
-				w.Lines.AppendNull()
-				continue
-			}
-
-			// We end up here if we tried to symbolize the location but failed,
-			// and therefore fell back to using the encoded location from the
-			// valueDict.
-			w.Addresses.Append(res.Addr)
-			w.Lines.AppendNull()
+		} else {
+			w.Lines.Append(false)
 		}
+		return nil
 	}
 
-	return w.RecordBuilder.NewRecord(), nil
+	res, err := profile.DecodeInto(w, encodedLocation)
+	if err != nil {
+		return err
+	}
+	if res.WroteLines {
+		w.Addresses.Append(res.Addr)
+		return nil
+	}
+	if res.Addr == 0 || len(res.BuildID) == 0 {
+		w.Addresses.Append(res.Addr)
+		w.Lines.AppendNull()
+		return nil
+	}
+
+	// We end up here if we tried to symbolize the location but failed,
+	// and therefore fell back to using the encoded location from the
+	// valueDict.
+	w.Addresses.Append(res.Addr)
+	w.Lines.AppendNull()
+	return nil
 }
 
 type MappingLocations struct {
@@ -1178,6 +1429,11 @@ func (q *Querier) symbolizeLocations(
 		res[i] = loc
 	}
 
+	// The locations of a build ID are symbolized in a single request, so its
+	// debuginfo is only opened once, and build IDs don't share locations, so
+	// their requests can run concurrently.
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(q.symbolizationConcurrency, 1))
 	for buildID, mappingAddrIndex := range index {
 		symReq := symbolizer.SymbolizationRequest{
 			BuildID: buildID,
@@ -1193,10 +1449,12 @@ func (q *Querier) symbolizeLocations(
 			})
 		}
 
-		err := q.symbolizer.Symbolize(ctx, symReq)
-		if err != nil {
-			return nil, err
-		}
+		g.Go(func() error {
+			return q.symbolizer.Symbolize(ctx, symReq)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
 
 	return res, nil
@@ -1274,7 +1532,7 @@ func (q *Querier) findSingle(ctx context.Context, query string, t time.Time) ([]
 	span.SetAttributes(attribute.Int64("time", t.Unix()))
 	defer span.End()
 
-	queryParts, selectorExprs, err := QueryToFilterExprs(query)
+	queryParts, selectorExprs, err := q.queryToFilterExprs(query)
 	if err != nil {
 		return nil, "", queryParts, err
 	}
@@ -1317,6 +1575,9 @@ func (q *Querier) findSingle(ctx context.Context, query string, t time.Time) ([]
 		aggrFunctions = append(aggrFunctions, durationSum)
 	}
 
+	sel := queryStatsFromContext(ctx).selection(q.tableName, filterExpr)
+	begin := time.Now()
+
 	records := []arrow.Record{}
 	err = q.engine.ScanTable(q.tableName).
 		Filter(filterExpr).
@@ -1329,11 +1590,13 @@ func (q *Querier) findSingle(ctx context.Context, query string, t time.Time) ([]
 		Execute(ctx, func(ctx context.Context, r arrow.Record) error {
 			r.Retain()
 			records = append(records, r)
+			sel.record(r)
 			return nil
 		})
 	if err != nil {
 		return nil, "", queryParts, fmt.Errorf("execute query: %w", err)
 	}
+	q.finishAggregated(sel, begin, q.tableName, filterExpr)
 
 	queryParts.Meta.Timestamp = requestedTime
 
@@ -1390,7 +1653,7 @@ func (q *Querier) selectMerge(
 	ctx, span := q.tracer.Start(ctx, "Querier/selectMerge")
 	defer span.End()
 
-	queryParts, selectorExprs, err := QueryToFilterExprs(query)
+	queryParts, selectorExprs, err := q.queryToFilterExprs(query)
 	if err != nil {
 		return nil, "", queryParts, err
 	}
@@ -1448,8 +1711,16 @@ func (q *Querier) selectMerge(
 		)
 	}
 
+	table := q.tableName
+	if queryParts.Delta {
+		table = q.deltaTable(startTime, readTime(ctx))
+	}
+
+	sel := queryStatsFromContext(ctx).selection(table, filterExpr)
+	begin := time.Now()
+
 	records := []arrow.Record{}
-	err = q.engine.ScanTable(q.tableName).
+	err = q.engine.ScanTable(table).
 		Filter(filterExpr).
 		Project(firstProject...).
 		Aggregate(
@@ -1460,11 +1731,13 @@ func (q *Querier) selectMerge(
 		Execute(ctx, func(ctx context.Context, r arrow.Record) error {
 			r.Retain()
 			records = append(records, r)
+			sel.record(r)
 			return nil
 		})
 	if err != nil {
 		return nil, "", queryParts, err
 	}
+	q.finishAggregated(sel, begin, table, filterExpr)
 
 	queryParts.Meta.SampleType = resultType
 	queryParts.Meta.Timestamp = start
@@ -1483,7 +1756,7 @@ func (q *Querier) GetProfileMetadataMappings(
 	ctx, span := q.tracer.Start(ctx, "Querier/MappingFiles")
 	defer span.End()
 
-	_, selectorExprs, err := QueryToFilterExprs(query)
+	_, selectorExprs, err := q.queryToFilterExprs(query)
 	if err != nil {
 		return nil, err
 	}
@@ -1547,7 +1820,7 @@ func (q *Querier) GetProfileMetadataLabels(
 	ctx, span := q.tracer.Start(ctx, "Querier/Labels")
 	defer span.End()
 
-	_, selectorExprs, err := QueryToFilterExprs(query)
+	_, selectorExprs, err := q.queryToFilterExprs(query)
 	if err != nil {
 		return nil, err
 	}
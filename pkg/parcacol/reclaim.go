@@ -0,0 +1,392 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// Reclaimer reclaims the rows of deleted profiles once their deletion can't
+// be undone anymore: it rewrites the blocks persisted to object storage
+// without them and then expires their tombstones, so that queries stop
+// excluding them.
+//
+// Rows of deleted profiles are written until the grace period of the
+// deletion expired, as tiers and rollups only exclude final deletions, so
+// they are reclaimed once the raw table was persisted since. A row of a tier
+// is dropped if its window overlaps the deleted range, and the synthetic
+// series of the rollup windows that overlap it are dropped and rolled up
+// again. Tombstones are kept as long as the tables still have rows of the
+// deleted profiles in memory, which are reclaimed once persisted.
+type Reclaimer struct {
+	logger     log.Logger
+	engine     Engine
+	blocks     *Blocks
+	tombstones *Tombstones
+	rawTable   string
+	// resolutions are the resolutions of the tiers by table.
+	resolutions map[string]time.Duration
+	rollups     map[string]*Rollup
+
+	rewrittenBlocks   prometheus.Counter
+	reclaimedRows     prometheus.Counter
+	expiredTombstones prometheus.Counter
+}
+
+// NewReclaimer returns the Reclaimer of the deleted profiles of the raw table,
+// the tables of its tiers and the metadata table, whose synthetic series are
+// rolled up again by the rollups.
+func NewReclaimer(
+	logger log.Logger,
+	reg prometheus.Registerer,
+	engine Engine,
+	blocks *Blocks,
+	tombstones *Tombstones,
+	rawTable string,
+	tiers []Tier,
+	rollups []*Rollup,
+) *Reclaimer {
+	r := &Reclaimer{
+		logger:      logger,
+		engine:      engine,
+		blocks:      blocks,
+		tombstones:  tombstones,
+		rawTable:    rawTable,
+		resolutions: map[string]time.Duration{},
+		rollups:     map[string]*Rollup{},
+		rewrittenBlocks: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_storage_reclaim_rewritten_blocks_total",
+			Help: "Total number of blocks rewritten without the rows of deleted profiles.",
+		}),
+		reclaimedRows: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_storage_reclaim_dropped_rows_total",
+			Help: "Total number of rows of deleted profiles dropped from blocks.",
+		}),
+		expiredTombstones: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_storage_reclaim_expired_tombstones_total",
+			Help: "Total number of tombstones expired after the rows of the deleted profiles were reclaimed.",
+		}),
+	}
+	for _, t := range tiers {
+		r.resolutions[t.Table] = t.Resolution
+	}
+	for _, rollup := range rollups {
+		r.rollups[rollup.rule.Name] = rollup
+	}
+	return r
+}
+
+// Reclaim reclaims the rows of the profiles deleted by the tombstones whose
+// grace period expired at now, and returns the number of tombstones expired.
+// Tombstones whose rows can't be reclaimed yet, eg. as a block is pinned by a
+// read snapshot in flight, are reclaimed by a later run.
+func (r *Reclaimer) Reclaim(ctx context.Context, now time.Time) (int, error) {
+	blocks, err := listBlocks(ctx, r.blocks.bucket)
+	if err != nil {
+		return 0, err
+	}
+	metas := make([]*BlockMeta, 0, len(blocks))
+	var rotated time.Time
+	for _, block := range blocks {
+		meta, err := r.blocks.blockMeta(ctx, block)
+		if err != nil {
+			return 0, fmt.Errorf("describe block %s: %w", block.id, err)
+		}
+		metas = append(metas, meta)
+		if meta.Table == r.rawTable && meta.Created.After(rotated) {
+			rotated = meta.Created
+		}
+	}
+
+	expired := 0
+	for _, ts := range r.tombstones.List() {
+		if !r.tombstones.final(ts, now) {
+			continue
+		}
+		// Blocks are named after the time they were created at, so all rows
+		// written before a block was created were persisted once it is.
+		if !rotated.After(ts.Created.Add(r.tombstones.grace)) {
+			continue
+		}
+
+		done, err := r.reclaim(ctx, ts, blocks, metas)
+		if errors.Is(err, ErrBlockPinned) {
+			level.Debug(r.logger).Log("msg", "block pinned by a read snapshot, deferring reclaiming deleted profiles", "tombstone", ts.ID, "err", err)
+			continue
+		}
+		if err != nil {
+			return expired, fmt.Errorf("reclaim tombstone %s: %w", ts.ID, err)
+		}
+		if !done {
+			continue
+		}
+		if err := r.tombstones.expire(ts.ID); err != nil {
+			return expired, err
+		}
+		r.expiredTombstones.Inc()
+		expired++
+	}
+	return expired, nil
+}
+
+// reclaim drops the rows of the tombstone from the blocks, rolls up the
+// windows whose synthetic series were dropped again, and returns whether no
+// rows of the tombstone are left in memory.
+func (r *Reclaimer) reclaim(ctx context.Context, ts *Tombstone, blocks []*persistedBlock, metas []*BlockMeta) (bool, error) {
+	for i, block := range blocks {
+		meta := metas[i]
+		resolution, metadata, ok := r.table(meta.Table)
+		if !ok || !r.overlaps(ts, meta, resolution) {
+			continue
+		}
+
+		windows := map[string][]time.Time{}
+		drop := func(row *parquetRow) bool {
+			t := row.int64Value(profile.ColumnTimestamp)
+			if name := row.stringValue(profile.ColumnLabelsPrefix + RollupLabel); name != "" && meta.Table == r.rawTable {
+				rollup, ok := r.rollups[name]
+				if !ok || !overlaps(ts, t, rollup.rule.Interval) {
+					return false
+				}
+				if w := timestamp.Time(t); !slices.ContainsFunc(windows[name], w.Equal) {
+					windows[name] = append(windows[name], w)
+				}
+				return true
+			}
+			return overlaps(ts, t, resolution) && matchesRow(ts.matchers, row, metadata)
+		}
+		// The windows whose synthetic series are dropped are recorded
+		// before the block is uploaded, so they are rolled up again even
+		// if Parca stops in between.
+		prepare := func() error {
+			if len(windows) == 0 {
+				return nil
+			}
+			return r.tombstones.update(ts.ID, func(ts *Tombstone) {
+				if ts.Recompute == nil {
+					ts.Recompute = map[string][]time.Time{}
+				}
+				for name, w := range windows {
+					ts.Recompute[name] = append(ts.Recompute[name], w...)
+				}
+			})
+		}
+
+		dropped, err := r.blocks.rewrite(ctx, block, drop, prepare)
+		if err != nil {
+			return false, err
+		}
+		if dropped > 0 {
+			r.rewrittenBlocks.Inc()
+			r.reclaimedRows.Add(float64(dropped))
+		}
+	}
+
+	if err := r.recompute(ctx, ts.ID); err != nil {
+		return false, err
+	}
+
+	tables := []string{r.rawTable, profile.MetadataTableName}
+	for table := range r.resolutions {
+		tables = append(tables, table)
+	}
+	for _, table := range tables {
+		left, err := r.inMemory(ctx, ts, table)
+		if err != nil {
+			return false, fmt.Errorf("find rows of %s in memory: %w", table, err)
+		}
+		if left {
+			level.Debug(r.logger).Log("msg", "deleted profiles not persisted yet, deferring reclaiming them", "tombstone", ts.ID, "table", table)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// recompute rolls up the windows recorded in the tombstone with the ID again.
+func (r *Reclaimer) recompute(ctx context.Context, id string) error {
+	tombstones := r.tombstones.List()
+	i := slices.IndexFunc(tombstones, func(ts *Tombstone) bool { return ts.ID == id })
+	if i < 0 || len(tombstones[i].Recompute) == 0 {
+		return nil
+	}
+	ts := tombstones[i]
+
+	for name, windows := range ts.Recompute {
+		rollup, ok := r.rollups[name]
+		if !ok {
+			// The rollup was removed from the config.
+			continue
+		}
+		for _, w := range windows {
+			if err := rollup.Rollup(ctx, w); err != nil {
+				return fmt.Errorf("roll up window %s of %s: %w", w, name, err)
+			}
+		}
+	}
+	return r.tombstones.update(id, func(ts *Tombstone) {
+		ts.Recompute = nil
+	})
+}
+
+// inMemory returns whether the table still has rows of the tombstone. The
+// persisted blocks were rewritten without them, so they are in memory.
+func (r *Reclaimer) inMemory(ctx context.Context, ts *Tombstone, table string) (bool, error) {
+	resolution, metadata, _ := r.table(table)
+	exprs := []logicalplan.Expr{
+		logicalplan.Col(profile.ColumnTimestamp).Gt(logicalplan.Literal(timestamp.FromTime(ts.Start) - max(resolution.Milliseconds(), 1))),
+		logicalplan.Col(profile.ColumnTimestamp).LtEq(logicalplan.Literal(timestamp.FromTime(ts.End))),
+	}
+	if table == r.rawTable {
+		// The synthetic series of rollups were rolled up again without
+		// the deleted profiles.
+		exprs = append(exprs, rollupExclusion())
+	}
+	for _, m := range ts.matchers {
+		if metadata && m.Name == labels.MetricName {
+			if m.Type != labels.MatchEqual {
+				continue
+			}
+			meta, _, err := parseProfileType(m.Value)
+			if err != nil {
+				return false, err
+			}
+			exprs = append(exprs, logicalplan.Col(profile.ColumnName).Eq(logicalplan.Literal(meta.Name)))
+			continue
+		}
+		expr, err := matcherExpression(m)
+		if err != nil {
+			return false, err
+		}
+		exprs = append(exprs, expr)
+	}
+
+	found := false
+	err := r.engine.ScanTable(table).
+		Filter(logicalplan.And(exprs...)).
+		Project(logicalplan.Col(profile.ColumnTimestamp)).
+		Execute(ctx, func(ctx context.Context, rec arrow.Record) error {
+			if rec.NumRows() > 0 {
+				found = true
+			}
+			return nil
+		})
+	return found, err
+}
+
+// table returns the resolution of the table and whether it is the metadata
+// table, or false if it has no profiles.
+func (r *Reclaimer) table(name string) (time.Duration, bool, bool) {
+	switch name {
+	case r.rawTable:
+		return 0, false, true
+	case profile.MetadataTableName:
+		return 0, true, true
+	}
+	resolution, ok := r.resolutions[name]
+	return resolution, false, ok
+}
+
+// overlaps returns whether the block may have rows of the tombstone, or of
+// the rollup windows overlapping it.
+func (r *Reclaimer) overlaps(ts *Tombstone, meta *BlockMeta, resolution time.Duration) bool {
+	if meta.MinTime.IsZero() {
+		return true
+	}
+	if meta.Table == r.rawTable {
+		for _, rollup := range r.rollups {
+			resolution = max(resolution, rollup.rule.Interval)
+		}
+	}
+	return !meta.MinTime.After(ts.End) && meta.MaxTime.Add(max(resolution, time.Millisecond)).After(ts.Start)
+}
+
+// overlaps returns whether the row at t, which covers the window of the
+// resolution starting at t, overlaps the time range of the tombstone.
+func overlaps(ts *Tombstone, t int64, resolution time.Duration) bool {
+	return t <= timestamp.FromTime(ts.End) && t+max(resolution.Milliseconds(), 1) > timestamp.FromTime(ts.Start)
+}
+
+// matchesRow returns whether the row of a table of profiles, or of the
+// metadata table, matches the matchers of a tombstone. Like for queries, the
+// __name__ label selects a profile type, of which the metadata only has the
+// name.
+func matchesRow(matchers []*labels.Matcher, row *parquetRow, metadata bool) bool {
+	for _, m := range matchers {
+		if m.Name != labels.MetricName {
+			if !m.Matches(row.stringValue(profile.ColumnLabelsPrefix + m.Name)) {
+				return false
+			}
+			continue
+		}
+
+		meta, delta, err := parseProfileType(m.Value)
+		if err != nil {
+			return false
+		}
+		if metadata {
+			if m.Type == labels.MatchEqual && row.stringValue(profile.ColumnName) != meta.Name {
+				return false
+			}
+			continue
+		}
+		match := row.stringValue(profile.ColumnName) == meta.Name &&
+			row.stringValue(profile.ColumnSampleType) == meta.SampleType.Type &&
+			row.stringValue(profile.ColumnSampleUnit) == meta.SampleType.Unit &&
+			row.stringValue(profile.ColumnPeriodType) == meta.PeriodType.Type &&
+			row.stringValue(profile.ColumnPeriodUnit) == meta.PeriodType.Unit &&
+			(row.int64Value(profile.ColumnDuration) != 0) == delta
+		if match != (m.Type == labels.MatchEqual) {
+			return false
+		}
+	}
+	return true
+}
+
+// Run reclaims the rows of deleted profiles in the interval until the context
+// is canceled.
+func (r *Reclaimer) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			expired, err := r.Reclaim(ctx, time.Now())
+			if err != nil {
+				level.Warn(r.logger).Log("msg", "failed to reclaim deleted profiles", "err", err)
+			}
+			if expired > 0 {
+				level.Info(r.logger).Log("msg", "reclaimed deleted profiles", "tombstones", expired)
+			}
+		}
+	}
+}
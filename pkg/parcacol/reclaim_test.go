@@ -0,0 +1,131 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func TestReclaimer(t *testing.T) {
+	ctx := context.Background()
+
+	col, err := frostdb.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { col.Close() })
+	colDB, err := col.DB(ctx, "parca")
+	require.NoError(t, err)
+	_, err = colDB.Table("stacktraces", frostdb.NewTableConfig(profile.SchemaDefinition()))
+	require.NoError(t, err)
+	_, err = colDB.Table(profile.MetadataTableName, frostdb.NewTableConfig(profile.MetadataSchemaDefinition()))
+	require.NoError(t, err)
+	engine := query.NewEngine(memory.DefaultAllocator, colDB.TableProvider())
+
+	type row struct {
+		Name      string `parquet:"name"`
+		Job       string `parquet:"labels.job"`
+		Timestamp int64  `parquet:"timestamp"`
+	}
+	bucket := objstore.NewInMemBucket()
+	upload := func(table string, created time.Time, rows ...row) string {
+		buf := &bytes.Buffer{}
+		require.NoError(t, parquet.Write(buf, rows))
+		id := ulid.MustNew(ulid.Timestamp(created), bytes.NewReader(make([]byte, 16))).String()
+		require.NoError(t, bucket.Upload(ctx, "parca/"+table+"/"+id+"/data.parquet", bytes.NewReader(buf.Bytes())))
+		return id
+	}
+	read := func(table, id string) []row {
+		rc, err := bucket.Get(ctx, "parca/"+table+"/"+id+"/data.parquet")
+		require.NoError(t, err)
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rows, err := parquet.Read[row](bytes.NewReader(b), int64(len(b)))
+		require.NoError(t, err)
+		return rows
+	}
+
+	tombstones, err := NewTombstones("", time.Hour)
+	require.NoError(t, err)
+	ts, err := tombstones.Delete(`{job="checkout"}`, time.UnixMilli(1000), time.UnixMilli(2000))
+	require.NoError(t, err)
+
+	raw := upload("stacktraces", ts.Created.Add(-time.Hour),
+		row{Name: "memory", Job: "checkout", Timestamp: 1500},
+		row{Name: "memory", Job: "checkout", Timestamp: 3000},
+		row{Name: "memory", Job: "api", Timestamp: 1500},
+	)
+	metadata := upload(profile.MetadataTableName, ts.Created.Add(-2*time.Hour),
+		row{Name: "memory", Job: "checkout", Timestamp: 1500},
+		row{Name: "memory", Job: "api", Timestamp: 1500},
+	)
+	pins := NewSnapshotPins()
+	blocks := NewBlocks(log.NewNopLogger(), bucket, objstore.NewInMemBucket(), pins)
+	_, err = blocks.Annotate(ctx, raw, map[string]string{"source": "import"})
+	require.NoError(t, err)
+
+	r := NewReclaimer(log.NewNopLogger(), prometheus.NewRegistry(), engine, blocks, tombstones, "stacktraces", nil, nil)
+	now := ts.Created.Add(2 * time.Hour)
+
+	// The raw table wasn't persisted since the deletion became final, so
+	// rows of the deleted profiles may still be written to it.
+	expired, err := r.Reclaim(ctx, now)
+	require.NoError(t, err)
+	require.Zero(t, expired)
+	require.Len(t, read("stacktraces", raw), 3)
+
+	upload("stacktraces", ts.Created.Add(90*time.Minute), row{Name: "memory", Job: "checkout", Timestamp: 5000})
+
+	// A request in flight may read the blocks, so they are rewritten once
+	// it released its snapshot.
+	_, release := pins.ContextWithReadSnapshot(ctx)
+	expired, err = r.Reclaim(ctx, now)
+	require.NoError(t, err)
+	require.Zero(t, expired)
+	require.Len(t, tombstones.List(), 1)
+	release()
+
+	expired, err = r.Reclaim(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, 1, expired)
+	require.Empty(t, tombstones.List())
+	require.Equal(t, []row{
+		{Name: "memory", Job: "checkout", Timestamp: 3000},
+		{Name: "memory", Job: "api", Timestamp: 1500},
+	}, read("stacktraces", raw))
+	require.Equal(t, []row{
+		{Name: "memory", Job: "api", Timestamp: 1500},
+	}, read(profile.MetadataTableName, metadata))
+
+	metas, err := blocks.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, raw, metas[1].ID)
+	require.Equal(t, int64(2), metas[1].Rows)
+	require.Equal(t, map[string]string{"source": "import"}, metas[1].Annotations)
+}
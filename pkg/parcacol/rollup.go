@@ -0,0 +1,288 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// RollupLabel is the label of the series written by rollups, set to the name
+// of the rollup. Queries only read these series if they select the label, eg.
+// {__rollup__="deployments",deployment="api"}, so that they don't count the
+// profiles of the rolled up series twice.
+const RollupLabel = "__rollup__"
+
+// RollupRule merges the series matching the matchers into one synthetic
+// series per value of the By labels, which get the static Labels.
+type RollupRule struct {
+	Name string
+	// Query optionally restricts the rule to the series of a delta profile
+	// type, eg. parca_agent:samples:count:cpu:nanoseconds:delta{namespace="prod"}.
+	// The matchers are ignored if it is set.
+	Query    string
+	Matchers []*labels.Matcher
+	By       []string
+	Labels   map[string]string
+	Interval time.Duration
+}
+
+// Rollup continuously merges the delta profiles of the series matching a
+// rule, eg. of all pods of a deployment, into synthetic series written back
+// to the same table, so that the most common aggregate queries read
+// precomputed data. Like a downsampler it merges the profiles of every
+// interval into one profile starting at the window and lasting the interval.
+type Rollup struct {
+	logger   log.Logger
+	tracer   trace.Tracer
+	engine   Engine
+	table    string
+	rule     RollupRule
+	ingester ingester.Ingester
+	schema   *dynparquet.Schema
+	mem      memory.Allocator
+	// tombstones exclude deleted profiles from the synthetic series.
+	tombstones *Tombstones
+	// watermark is the next window to roll up.
+	watermark *Watermark
+
+	// delay is how long after the end of a window it is rolled up, to
+	// include profiles that are written late.
+	delay time.Duration
+
+	windows     prometheus.Counter
+	errors      prometheus.Counter
+	rows        prometheus.Counter
+	lastSuccess prometheus.Gauge
+	duration    prometheus.Histogram
+}
+
+// NewRollup creates a Rollup that reads from the table and writes the
+// synthetic series back using its ingester. Deleted profiles are not rolled
+// up. The watermark may be nil.
+func NewRollup(
+	logger log.Logger,
+	reg prometheus.Registerer,
+	tracer trace.Tracer,
+	engine Engine,
+	table string,
+	rule RollupRule,
+	ingester ingester.Ingester,
+	schema *dynparquet.Schema,
+	mem memory.Allocator,
+	tombstones *Tombstones,
+	watermark *Watermark,
+	delay time.Duration,
+) *Rollup {
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"rollup": rule.Name}, reg)
+	return &Rollup{
+		logger:     log.With(logger, "rollup", rule.Name),
+		tracer:     tracer,
+		engine:     engine,
+		table:      table,
+		rule:       rule,
+		ingester:   ingester,
+		schema:     schema,
+		mem:        mem,
+		tombstones: tombstones,
+		watermark:  watermark,
+		delay:      delay,
+		windows: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_rollup_windows_total",
+			Help: "Total number of windows rolled up.",
+		}),
+		errors: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_rollup_errors_total",
+			Help: "Total number of windows that failed to be rolled up.",
+		}),
+		rows: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_rollup_rows_written_total",
+			Help: "Total number of rows of synthetic series written.",
+		}),
+		lastSuccess: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "parca_rollup_last_success_timestamp_seconds",
+			Help: "Timestamp of the last window that was rolled up successfully.",
+		}),
+		duration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "parca_rollup_duration_seconds",
+			Help:    "Duration of rolling up a window.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+		}),
+	}
+}
+
+// Run rolls up every window once it is complete until the context is
+// canceled. It continues at the watermark, so the windows that completed
+// while it was not running are rolled up first. Without a stored watermark,
+// windows that ended before Run was called are not rolled up.
+func (r *Rollup) Run(ctx context.Context) error {
+	interval := r.rule.Interval.Milliseconds()
+	return runWindows(ctx, r.logger, r.watermark, r.rule.Interval, r.delay, func(ctx context.Context, windowStart int64) {
+		start := time.Now()
+		if err := r.Rollup(ctx, timestamp.Time(windowStart)); err != nil {
+			r.errors.Inc()
+			level.Error(r.logger).Log("msg", "failed to roll up window", "start", timestamp.Time(windowStart), "err", err)
+		} else {
+			r.lastSuccess.Set(float64(timestamp.Time(windowStart + interval).Unix()))
+		}
+		r.duration.Observe(time.Since(start).Seconds())
+	})
+}
+
+// Rollup merges the profiles of the window starting at the given time and
+// writes the synthetic series.
+func (r *Rollup) Rollup(ctx context.Context, start time.Time) error {
+	ctx, span := r.tracer.Start(ctx, "Rollup/Rollup")
+	span.SetAttributes(attribute.String("rollup", r.rule.Name))
+	span.SetAttributes(attribute.Int64("start", start.Unix()))
+	defer span.End()
+
+	windowStart := timestamp.FromTime(start)
+	windowEnd := windowStart + r.rule.Interval.Milliseconds()
+
+	groupBy := []logicalplan.Expr{
+		logicalplan.Col(profile.ColumnName),
+		logicalplan.Col(profile.ColumnSampleType),
+		logicalplan.Col(profile.ColumnSampleUnit),
+		logicalplan.Col(profile.ColumnPeriodType),
+		logicalplan.Col(profile.ColumnPeriodUnit),
+		logicalplan.Col(profile.ColumnPeriod),
+		logicalplan.Col(profile.ColumnStacktrace),
+	}
+	for _, name := range r.rule.By {
+		groupBy = append(groupBy, logicalplan.Col(profile.ColumnLabelsPrefix+name))
+	}
+	valueSum := logicalplan.Sum(logicalplan.Col(profile.ColumnValue))
+
+	filters, err := r.filterExprs(windowStart, windowEnd)
+	if err != nil {
+		return err
+	}
+
+	profiles := newWindowProfiles(windowStart, r.rule.Interval.Nanoseconds())
+	err = r.engine.ScanTable(r.table).
+		Filter(logicalplan.And(filters...)).
+		Project(append(groupBy, logicalplan.Col(profile.ColumnValue))...).
+		Aggregate(
+			[]*logicalplan.AggregationFunction{valueSum},
+			groupBy,
+		).
+		Execute(ctx, func(ctx context.Context, rec arrow.Record) error {
+			return profiles.add(rec, valueSum.Name())
+		})
+	if err != nil {
+		return fmt.Errorf("merge window: %w", err)
+	}
+
+	req := profiles.request()
+	for _, s := range req.Series {
+		for name, value := range r.rule.Labels {
+			s.Labels[name] = value
+		}
+		s.Labels[RollupLabel] = r.rule.Name
+	}
+	for name := range r.rule.Labels {
+		if !slices.Contains(req.AllLabelNames, name) {
+			req.AllLabelNames = append(req.AllLabelNames, name)
+		}
+	}
+	req.AllLabelNames = append(req.AllLabelNames, RollupLabel)
+	sort.Strings(req.AllLabelNames)
+
+	rec, err := normalizer.NormalizedWriteRawRequestToArrowRecord(ctx, r.mem, req, r.schema)
+	if err != nil {
+		return fmt.Errorf("build record: %w", err)
+	}
+	r.windows.Inc()
+	if rec == nil {
+		return nil
+	}
+	defer rec.Release()
+
+	if err := r.ingester.Ingest(ctx, rec); err != nil {
+		return fmt.Errorf("ingest: %w", err)
+	}
+	r.rows.Add(float64(rec.NumRows()))
+
+	return nil
+}
+
+// filterExprs selects the delta profiles of the window of the series matching
+// the rule, except for profiles whose deletion can't be undone anymore and
+// the synthetic series of any rollup.
+func (r *Rollup) filterExprs(windowStart, windowEnd int64) ([]logicalplan.Expr, error) {
+	exclusions, err := r.tombstones.finalExclusions(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	exprs := append([]logicalplan.Expr{
+		logicalplan.Col(profile.ColumnTimestamp).GtEq(logicalplan.Literal(windowStart)),
+		logicalplan.Col(profile.ColumnTimestamp).Lt(logicalplan.Literal(windowEnd)),
+		rollupExclusion(),
+	}, exclusions...)
+
+	if r.rule.Query != "" {
+		qp, selectorExprs, err := QueryToFilterExprs(r.rule.Query)
+		if err != nil {
+			return nil, err
+		}
+		if !qp.Delta {
+			return nil, fmt.Errorf("query %q is not of a delta profile type", r.rule.Query)
+		}
+		return append(exprs, selectorExprs...), nil
+	}
+
+	matchers, err := MatchersToBooleanExpressions(r.rule.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	exprs = append(exprs, logicalplan.Col(profile.ColumnDuration).NotEq(logicalplan.Literal(0)))
+	return append(exprs, matchers...), nil
+}
+
+// rollupExclusion excludes the synthetic series written by rollups.
+func rollupExclusion() logicalplan.Expr {
+	expr, _ := MatcherToBooleanExpression(labels.MustNewMatcher(labels.MatchEqual, RollupLabel, ""))
+	return expr
+}
+
+// selectsRollups returns whether any of the matchers is on the rollup label.
+func selectsRollups(matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if m.Name == RollupLabel {
+			return true
+		}
+	}
+	return false
+}
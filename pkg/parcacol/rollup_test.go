@@ -0,0 +1,64 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"testing"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollupExclusion(t *testing.T) {
+	exprStrings := func(exprs []logicalplan.Expr) []string {
+		res := make([]string, 0, len(exprs))
+		for _, e := range exprs {
+			res = append(res, e.String())
+		}
+		return res
+	}
+	exclusion := rollupExclusion().String()
+
+	q := NewQuerier(nil, nil, nil, "stacktraces", nil, nil)
+	_, exprs, err := q.queryToFilterExprs(`parca_agent:samples:count:cpu:nanoseconds:delta{deployment="api"}`)
+	require.NoError(t, err)
+	require.Contains(t, exprStrings(exprs), exclusion)
+
+	// Queries selecting the rollup label read the synthetic series.
+	_, exprs, err = q.queryToFilterExprs(`parca_agent:samples:count:cpu:nanoseconds:delta{__rollup__="deployments",deployment="api"}`)
+	require.NoError(t, err)
+	require.NotContains(t, exprStrings(exprs), exclusion)
+
+	// Rollups never roll up synthetic series.
+	r := &Rollup{rule: RollupRule{
+		Name:     "deployments",
+		Matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "namespace", "prod")},
+		By:       []string{"deployment"},
+	}}
+	exprs, err = r.filterExprs(0, 60_000)
+	require.NoError(t, err)
+	require.Len(t, exprs, 5)
+	require.Contains(t, exprStrings(exprs), exclusion)
+
+	r.rule.Query = `parca_agent:samples:count:cpu:nanoseconds:delta{namespace="prod"}`
+	exprs, err = r.filterExprs(0, 60_000)
+	require.NoError(t, err)
+	require.Len(t, exprs, 10)
+	require.Contains(t, exprStrings(exprs), exclusion)
+
+	r.rule.Query = `memory:inuse_space:bytes:space:bytes{namespace="prod"}`
+	_, err = r.filterExprs(0, 60_000)
+	require.Error(t, err)
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+)
+
+// SizeRetention deletes the oldest blocks persisted to object storage once
+// the blocks take up more than a budget. Blocks are the directories named
+// by a ULID, which orders them by the time they were created.
+type SizeRetention struct {
+	logger   log.Logger
+	bucket   objstore.Bucket
+	maxBytes int64
+
+	// blocks knows which blocks are marked to be retained, see
+	// WithRetainedBlocks.
+	blocks *Blocks
+	// pins are the read snapshots in flight, see WithPinnedBlocks.
+	pins *SnapshotPins
+
+	storedBytes    prometheus.Gauge
+	reclaimedBytes prometheus.Counter
+	deletedBlocks  prometheus.Counter
+}
+
+type SizeRetentionOption func(*SizeRetention)
+
+// WithRetainedBlocks never deletes the blocks marked to be retained. They
+// still count towards the budget.
+func WithRetainedBlocks(blocks *Blocks) SizeRetentionOption {
+	return func(r *SizeRetention) {
+		r.blocks = blocks
+	}
+}
+
+// WithPinnedBlocks defers deleting the blocks pinned by the read snapshots
+// of the querier sharing the pins.
+func WithPinnedBlocks(p *SnapshotPins) SizeRetentionOption {
+	return func(r *SizeRetention) {
+		r.pins = p
+	}
+}
+
+// NewSizeRetention returns a size retention for the blocks in the bucket.
+func NewSizeRetention(logger log.Logger, reg prometheus.Registerer, bucket objstore.Bucket, maxBytes int64, opts ...SizeRetentionOption) *SizeRetention {
+	r := &SizeRetention{
+		logger:   logger,
+		bucket:   bucket,
+		maxBytes: maxBytes,
+		storedBytes: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "parca_storage_blocks_stored_bytes",
+			Help: "Bytes of the blocks persisted to object storage, as of the last size retention run.",
+		}),
+		reclaimedBytes: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_storage_retention_reclaimed_bytes_total",
+			Help: "Total number of bytes of blocks deleted to stay within the storage size budget.",
+		}),
+		deletedBlocks: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_storage_retention_deleted_blocks_total",
+			Help: "Total number of blocks deleted to stay within the storage size budget.",
+		}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+type persistedBlock struct {
+	id ulid.ULID
+	// dir is the directory of the block, named by its ID.
+	dir     string
+	objects []string
+	size    int64
+}
+
+// Enforce deletes the oldest blocks until the remaining ones fit into the
+// budget, and returns the number of bytes reclaimed. It stops at the first
+// block pinned by a read snapshot in flight, so that blocks are still deleted
+// oldest first, and the next run continues once the snapshot was released.
+func (r *SizeRetention) Enforce(ctx context.Context) (int64, error) {
+	blocks, err := listBlocks(ctx, r.bucket)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, b := range blocks {
+		total += b.size
+	}
+
+	var reclaimed int64
+	for _, b := range blocks {
+		if total <= r.maxBytes {
+			break
+		}
+		if r.pins.blockPinned(b.id) {
+			level.Debug(r.logger).Log("msg", "block pinned by a read snapshot, deferring size retention", "block", b.id)
+			break
+		}
+		if r.blocks != nil {
+			retained, err := r.blocks.retained(ctx, b.id)
+			if err != nil {
+				r.storedBytes.Set(float64(total))
+				return reclaimed, fmt.Errorf("check retention of block %s: %w", b.id, err)
+			}
+			if retained {
+				continue
+			}
+		}
+		for _, name := range b.objects {
+			if err := r.bucket.Delete(ctx, name); err != nil && !r.bucket.IsObjNotFoundErr(err) {
+				r.storedBytes.Set(float64(total))
+				return reclaimed, fmt.Errorf("delete block %s: %w", b.id, err)
+			}
+		}
+		level.Debug(r.logger).Log("msg", "deleted block exceeding the storage size budget", "block", b.id, "bytes", b.size)
+		total -= b.size
+		reclaimed += b.size
+		r.reclaimedBytes.Add(float64(b.size))
+		r.deletedBlocks.Inc()
+	}
+
+	r.storedBytes.Set(float64(total))
+	return reclaimed, nil
+}
+
+// listBlocks returns the blocks in the bucket, the oldest first.
+func listBlocks(ctx context.Context, bucket objstore.Bucket) ([]*persistedBlock, error) {
+	byDir := map[string]*persistedBlock{}
+	err := bucket.Iter(ctx, "", func(name string) error {
+		dir := path.Dir(name)
+		id, err := ulid.ParseStrict(path.Base(dir))
+		if err != nil {
+			return nil
+		}
+
+		attrs, err := bucket.Attributes(ctx, name)
+		if err != nil {
+			if bucket.IsObjNotFoundErr(err) {
+				return nil
+			}
+			return fmt.Errorf("attributes of %s: %w", name, err)
+		}
+
+		b, ok := byDir[dir]
+		if !ok {
+			b = &persistedBlock{id: id, dir: dir}
+			byDir[dir] = b
+		}
+		b.objects = append(b.objects, name)
+		b.size += attrs.Size
+		return nil
+	}, objstore.WithRecursiveIter)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*persistedBlock, 0, len(byDir))
+	for _, b := range byDir {
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].id.Compare(blocks[j].id) < 0
+	})
+	return blocks, nil
+}
+
+// Run enforces the budget in the interval until the context is canceled.
+func (r *SizeRetention) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			reclaimed, err := r.Enforce(ctx)
+			if err != nil {
+				level.Warn(r.logger).Log("msg", "failed to enforce storage size budget", "err", err)
+			}
+			if reclaimed > 0 {
+				level.Info(r.logger).Log("msg", "deleted blocks exceeding the storage size budget", "bytes", reclaimed)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestSizeRetention(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+
+	upload := func(name string, size int) {
+		require.NoError(t, bucket.Upload(ctx, name, bytes.NewReader(make([]byte, size))))
+	}
+	id := func(sec int64) string {
+		return ulid.MustNew(ulid.Timestamp(time.Unix(sec, 0)), bytes.NewReader(make([]byte, 16))).String()
+	}
+	oldest, middle, newest := id(1), id(2), id(3)
+	upload("parca/stacktraces/"+middle+"/data.parquet", 40)
+	upload("parca/stacktraces/"+oldest+"/data.parquet", 30)
+	upload("parca/stacktraces/"+oldest+"/index", 10)
+	upload("parca/stacktraces/"+newest+"/data.parquet", 50)
+	// Objects outside of blocks are never deleted.
+	upload("parca/metadata", 100)
+
+	r := NewSizeRetention(log.NewNopLogger(), prometheus.NewRegistry(), bucket, 100)
+	reclaimed, err := r.Enforce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), reclaimed)
+	require.Equal(t, 1.0, testutil.ToFloat64(r.deletedBlocks))
+	require.Equal(t, 90.0, testutil.ToFloat64(r.storedBytes))
+
+	exists, err := bucket.Exists(ctx, "parca/stacktraces/"+oldest+"/index")
+	require.NoError(t, err)
+	require.False(t, exists)
+	exists, err = bucket.Exists(ctx, "parca/metadata")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	// Within the budget nothing is deleted.
+	reclaimed, err = r.Enforce(ctx)
+	require.NoError(t, err)
+	require.Zero(t, reclaimed)
+}
+
+func TestSizeRetentionPinned(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+
+	oldest := ulid.MustNew(ulid.Timestamp(time.Unix(1, 0)), bytes.NewReader(make([]byte, 16))).String()
+	require.NoError(t, bucket.Upload(ctx, "parca/stacktraces/"+oldest+"/data.parquet", bytes.NewReader(make([]byte, 60))))
+	// A request in flight may read the block, so it is kept until the
+	// request released its snapshot.
+	pins := NewSnapshotPins()
+	_, release := pins.ContextWithReadSnapshot(ctx)
+	defer release()
+	newest := ulid.MustNew(ulid.Now(), bytes.NewReader(make([]byte, 16))).String()
+	require.NoError(t, bucket.Upload(ctx, "parca/stacktraces/"+newest+"/data.parquet", bytes.NewReader(make([]byte, 60))))
+
+	r := NewSizeRetention(log.NewNopLogger(), prometheus.NewRegistry(), bucket, 100, WithPinnedBlocks(pins))
+	reclaimed, err := r.Enforce(ctx)
+	require.NoError(t, err)
+	require.Zero(t, reclaimed)
+
+	release()
+	reclaimed, err = r.Enforce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), reclaimed)
+	exists, err := bucket.Exists(ctx, "parca/stacktraces/"+oldest+"/data.parquet")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type readSnapshotKey struct{}
+
+// SnapshotPins are the read snapshots of the requests in flight. A request
+// may read any block that existed when its snapshot was taken, so those
+// blocks are pinned and not deleted until the request released it. The
+// querier taking the snapshots and whatever deletes or rewrites the blocks
+// of the same storage must share the pins.
+type SnapshotPins struct {
+	mtx  sync.Mutex
+	next uint64
+	pins map[uint64]time.Time
+}
+
+// NewSnapshotPins returns pins without any snapshots in flight.
+func NewSnapshotPins() *SnapshotPins {
+	return &SnapshotPins{pins: map[uint64]time.Time{}}
+}
+
+// pin registers a snapshot taken at t, until the returned func is called.
+func (p *SnapshotPins) pin(t time.Time) func() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	id := p.next
+	p.next++
+	p.pins[id] = t
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mtx.Lock()
+			defer p.mtx.Unlock()
+			delete(p.pins, id)
+		})
+	}
+}
+
+// pinned returns whether a snapshot in flight was taken at or after t, and
+// may therefore read a block created at t. Nil pins never pin anything.
+func (p *SnapshotPins) pinned(t time.Time) bool {
+	if p == nil {
+		return false
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, pin := range p.pins {
+		if !pin.Before(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockPinned returns whether a read snapshot in flight may read the block.
+func (p *SnapshotPins) blockPinned(id ulid.ULID) bool {
+	return p.pinned(ulid.Time(id.Time()))
+}
+
+// ContextWithReadSnapshot returns a context whose queries read the same
+// snapshot of the storage tiers: the retention boundaries of the tiers are
+// resolved at the time the snapshot was taken, so that the selections of a
+// long running request, eg. both sides of a diff or the windows of a range
+// query, are served from the same tables even if a boundary moves while it
+// runs. If the context already has a snapshot it is kept, so nested calls
+// share the snapshot of the outermost one.
+//
+// The snapshot pins the blocks persisted so far: neither the size retention
+// nor deleting a block removes them until release is called, which must be
+// once the request is done reading. Nil pins take the snapshot without
+// pinning any blocks.
+//
+// Each selection is consistent on its own, as the storage reads at the
+// transaction watermark of when the selection started.
+func (p *SnapshotPins) ContextWithReadSnapshot(ctx context.Context) (_ context.Context, release func()) {
+	if _, ok := ctx.Value(readSnapshotKey{}).(time.Time); ok {
+		return ctx, func() {}
+	}
+	now := time.Now()
+	ctx = context.WithValue(ctx, readSnapshotKey{}, now)
+	if p == nil {
+		return ctx, func() {}
+	}
+	return ctx, p.pin(now)
+}
+
+// readTime returns the time of the read snapshot of the context, or the
+// current time if it has none.
+func readTime(ctx context.Context) time.Time {
+	if t, ok := ctx.Value(readSnapshotKey{}).(time.Time); ok {
+		return t
+	}
+	return time.Now()
+}
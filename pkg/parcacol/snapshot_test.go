@@ -0,0 +1,53 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSnapshot(t *testing.T) {
+	pins := NewSnapshotPins()
+	ctx, release := pins.ContextWithReadSnapshot(context.Background())
+	snapshot := readTime(ctx)
+
+	time.Sleep(time.Millisecond)
+	require.Equal(t, snapshot, readTime(ctx))
+	// Nested calls keep the outermost snapshot.
+	nested, releaseNested := pins.ContextWithReadSnapshot(ctx)
+	require.Equal(t, snapshot, readTime(nested))
+	require.True(t, readTime(context.Background()).After(snapshot))
+
+	// Only the outermost snapshot pins the blocks created until it was taken.
+	require.True(t, pins.pinned(snapshot))
+	require.False(t, pins.pinned(snapshot.Add(time.Millisecond)))
+	// Pins are not shared between storages.
+	require.False(t, NewSnapshotPins().pinned(snapshot))
+	releaseNested()
+	require.True(t, pins.pinned(snapshot))
+	release()
+	release()
+	require.False(t, pins.pinned(snapshot))
+
+	// Without pins the snapshot is still taken, but pins nothing.
+	var none *SnapshotPins
+	ctx, release = none.ContextWithReadSnapshot(context.Background())
+	defer release()
+	require.False(t, readTime(ctx).After(time.Now()))
+	require.False(t, none.pinned(readTime(ctx)))
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+type queryStatsKey struct{}
+
+// Selection describes how the data of a query was selected from storage and
+// what it cost.
+type Selection struct {
+	Table    string        `json:"table"`
+	Filter   string        `json:"filter"`
+	Records  int           `json:"records"`
+	Rows     int           `json:"rows"`
+	Duration time.Duration `json:"duration"`
+	// Series is the number of series selected. It is -1 if it was not
+	// counted, see QueryStats.CountSeries.
+	Series int `json:"series"`
+
+	stats       *QueryStats
+	countSeries func(ctx context.Context) (int, error)
+}
+
+// Stages of a query other than the selections, see ObserveStage.
+const (
+	StageSymbolize = "symbolize"
+	StageRender    = "render"
+)
+
+// QueryStats collects the selections made by the queries issued with a
+// context returned by WithQueryStats.
+type QueryStats struct {
+	mtx        sync.Mutex
+	selections []*Selection
+	stages     map[string]time.Duration
+}
+
+// WithQueryStats returns a context that collects the statistics of the
+// queries issued with it. If the context already collects them, it is
+// returned along with its statistics.
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	if s := queryStatsFromContext(ctx); s != nil {
+		return ctx, s
+	}
+	s := &QueryStats{}
+	return context.WithValue(ctx, queryStatsKey{}, s), s
+}
+
+// ObserveStage adds the time since start to the duration of the stage of
+// the query, if the context collects statistics.
+func ObserveStage(ctx context.Context, stage string, start time.Time) {
+	s := queryStatsFromContext(ctx)
+	if s == nil {
+		return
+	}
+
+	d := time.Since(start)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.stages == nil {
+		s.stages = map[string]time.Duration{}
+	}
+	s.stages[stage] += d
+}
+
+// Stages returns the durations of the stages observed so far.
+func (s *QueryStats) Stages() map[string]time.Duration {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	res := make(map[string]time.Duration, len(s.stages))
+	for stage, d := range s.stages {
+		res[stage] = d
+	}
+	return res
+}
+
+func queryStatsFromContext(ctx context.Context) *QueryStats {
+	s, _ := ctx.Value(queryStatsKey{}).(*QueryStats)
+	return s
+}
+
+// Selections returns the selections made so far.
+func (s *QueryStats) Selections() []Selection {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	res := make([]Selection, 0, len(s.selections))
+	for _, sel := range s.selections {
+		res = append(res, *sel)
+	}
+	return res
+}
+
+// CountSeries counts the series of the selections that aggregated them away.
+// It issues another query for each of them, so it is only meant to be called
+// to explain queries that turned out to be expensive.
+func (s *QueryStats) CountSeries(ctx context.Context) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, sel := range s.selections {
+		if sel.countSeries == nil {
+			continue
+		}
+		n, err := sel.countSeries(ctx)
+		if err != nil {
+			return fmt.Errorf("count series of %s: %w", sel.Table, err)
+		}
+		sel.Series = n
+		sel.countSeries = nil
+	}
+	return nil
+}
+
+// selection starts recording a selection from the table. It returns nil if
+// the context does not collect statistics.
+func (s *QueryStats) selection(table string, filter logicalplan.Expr) *Selection {
+	if s == nil {
+		return nil
+	}
+
+	sel := &Selection{
+		Table:  table,
+		Filter: filter.String(),
+		Series: -1,
+		stats:  s,
+	}
+	s.mtx.Lock()
+	s.selections = append(s.selections, sel)
+	s.mtx.Unlock()
+	return sel
+}
+
+func (sel *Selection) record(r arrow.Record) {
+	if sel == nil {
+		return
+	}
+	sel.stats.mtx.Lock()
+	sel.Records++
+	sel.Rows += int(r.NumRows())
+	sel.stats.mtx.Unlock()
+}
+
+func (sel *Selection) finish(start time.Time, series int) {
+	if sel == nil {
+		return
+	}
+	sel.stats.mtx.Lock()
+	sel.Duration = time.Since(start)
+	sel.Series = series
+	sel.stats.mtx.Unlock()
+}
+
+// finishAggregated finishes a selection whose series were aggregated away.
+// They are only counted on demand, see QueryStats.CountSeries.
+func (q *Querier) finishAggregated(sel *Selection, start time.Time, table string, filter logicalplan.Expr) {
+	if sel == nil {
+		return
+	}
+	sel.finish(start, -1)
+
+	sel.stats.mtx.Lock()
+	defer sel.stats.mtx.Unlock()
+	sel.countSeries = func(ctx context.Context) (int, error) {
+		series := 0
+		err := q.engine.ScanTable(table).
+			Filter(filter).
+			Distinct(logicalplan.DynCol(profile.ColumnLabels)).
+			Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+				series += int(r.NumRows())
+				return nil
+			})
+		return series, err
+	}
+}
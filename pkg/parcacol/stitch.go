@@ -0,0 +1,238 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// TierPolicy is how range queries of delta profiles are served when
+// downsampled tiers exist.
+type TierPolicy string
+
+const (
+	// TierPolicyStart serves the whole range from the finest table whose
+	// retention covers the start of the range.
+	TierPolicyStart TierPolicy = "start"
+	// TierPolicyStitch serves each part of the range from the finest table
+	// whose retention covers it, eg. the recent part from the raw table and
+	// older parts from coarser tiers, and stitches the series together.
+	TierPolicyStitch TierPolicy = "stitch"
+)
+
+// WithTierPolicy sets how range queries of delta profiles are served from
+// the tiers. It defaults to TierPolicyStart.
+func WithTierPolicy(policy TierPolicy) QuerierOption {
+	return func(q *Querier) {
+		q.tierPolicy = policy
+	}
+}
+
+// ResolutionsMetadataKey is the gRPC response header reporting the
+// resolutions a range query was served at. It has a value per segment of
+// the range in the form "<start>,<end>,<resolution>", with the start and
+// end in milliseconds since epoch and the resolution either "raw" or a
+// duration.
+const ResolutionsMetadataKey = "parca-resolutions"
+
+// Segment is a part of the time range of a query served from one table.
+type Segment struct {
+	Table string
+	// Resolution of the table, zero for the raw table.
+	Resolution time.Duration
+	Start      time.Time
+	End        time.Time
+}
+
+func (s Segment) String() string {
+	resolution := "raw"
+	if s.Resolution > 0 {
+		resolution = model.Duration(s.Resolution).String()
+	}
+	return fmt.Sprintf("%d,%d,%s", timestamp.FromTime(s.Start), timestamp.FromTime(s.End), resolution)
+}
+
+// segment returns the segment of the range served from the table.
+func (q *Querier) segment(table string, start, end time.Time) Segment {
+	s := Segment{Table: table, Start: start, End: end}
+	for _, t := range q.tiers {
+		if t.Table == table {
+			s.Resolution = t.Resolution
+		}
+	}
+	return s
+}
+
+// segments splits the range at the retention boundaries of the raw table and
+// the tiers, so that each segment is served from the finest table covering
+// it. Consecutive segments served from the same table are merged.
+//
+// A row of a tier covers the window of its resolution starting at its
+// timestamp, so the boundary after a segment served from a tier is aligned
+// down to the start of the window it falls into. Otherwise the window would
+// be read from both the tier and the finer table after it.
+func (q *Querier) segments(start, end, now time.Time) []Segment {
+	cuts := []time.Time{}
+	for _, retention := range append([]time.Duration{q.rawRetention}, tierRetentions(q.tiers)...) {
+		if retention == 0 {
+			continue
+		}
+		if cut := now.Add(-retention); cut.After(start) && cut.Before(end) {
+			cuts = append(cuts, cut)
+		}
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].Before(cuts[j]) })
+	cuts = append(cuts, end)
+
+	var segments []Segment
+	segStart := start
+	for _, cut := range cuts {
+		if !cut.After(segStart) {
+			continue
+		}
+		table := q.deltaTable(segStart, now)
+		if n := len(segments); n > 0 && segments[n-1].Table == table {
+			segments[n-1].End = cut
+		} else {
+			segments = append(segments, q.segment(table, segStart, cut))
+		}
+		segStart = cut
+	}
+
+	for i := 1; i < len(segments); i++ {
+		prev := &segments[i-1]
+		if prev.Resolution == 0 {
+			continue
+		}
+		step := prev.Resolution.Milliseconds()
+		aligned := time.UnixMilli(timestamp.FromTime(segments[i].Start) / step * step)
+		if !aligned.After(prev.Start) {
+			// The range starts within the window, the finer table serves
+			// all of it.
+			segments[i].Start = prev.Start
+			segments = slices.Delete(segments, i-1, i)
+			i--
+			continue
+		}
+		prev.End = aligned
+		segments[i].Start = aligned
+	}
+	return segments
+}
+
+func tierRetentions(tiers []Tier) []time.Duration {
+	res := make([]time.Duration, 0, len(tiers))
+	for _, t := range tiers {
+		res = append(res, t.Retention)
+	}
+	return res
+}
+
+// queryRangeStitched serves a range query of delta profiles from the
+// segments of its range, see TierPolicyStitch.
+func (q *Querier) queryRangeStitched(
+	ctx context.Context,
+	queryParts QueryParts,
+	selectorExprs []logicalplan.Expr,
+	startTime, endTime time.Time,
+	step time.Duration,
+	sumBy []string,
+) ([]*pb.MetricsSeries, error) {
+	segments := q.segments(startTime, endTime, readTime(ctx))
+	reportResolutions(ctx, segments)
+
+	var (
+		res   []*pb.MetricsSeries
+		index = map[string]*pb.MetricsSeries{}
+	)
+	for i, seg := range segments {
+		// The start of the range is exclusive, segments after the first
+		// include their start as it is the end of the previous one.
+		lower := logicalplan.Col(profile.ColumnTimestamp).GtEq(logicalplan.Literal(timestamp.FromTime(seg.Start)))
+		if i == 0 {
+			lower = logicalplan.Col(profile.ColumnTimestamp).Gt(logicalplan.Literal(timestamp.FromTime(seg.Start)))
+		}
+		filterExpr := logicalplan.And(append(
+			slices.Clone(selectorExprs),
+			lower,
+			logicalplan.Col(profile.ColumnTimestamp).Lt(logicalplan.Literal(timestamp.FromTime(seg.End))),
+		)...)
+
+		sel := queryStatsFromContext(ctx).selection(seg.Table, filterExpr)
+		begin := time.Now()
+		series, err := q.queryRangeDelta(ctx, seg.Table, filterExpr, step, queryParts.Meta, sumBy, sel)
+		sel.finish(begin, len(series))
+		if status.Code(err) == codes.NotFound {
+			// Other segments may have data.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("segment %s: %w", seg.Table, err)
+		}
+
+		for _, s := range series {
+			key := labelSetKey(s)
+			if existing, ok := index[key]; ok {
+				existing.Samples = append(existing.Samples, s.Samples...)
+				continue
+			}
+			index[key] = s
+			res = append(res, s)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, status.Error(
+			codes.NotFound,
+			"No data found for the query, try a different query or time range or no data has been written to be queried yet.",
+		)
+	}
+	return res, nil
+}
+
+func labelSetKey(s *pb.MetricsSeries) string {
+	ls := make([]string, 0, len(s.GetLabelset().GetLabels()))
+	for _, l := range s.GetLabelset().GetLabels() {
+		ls = append(ls, l.Name+"="+l.Value)
+	}
+	sort.Strings(ls)
+	return strings.Join(ls, ",")
+}
+
+// reportResolutions reports the segments a query was served from to the
+// client, see ResolutionsMetadataKey. Outside of a gRPC request it does
+// nothing.
+func reportResolutions(ctx context.Context, segments []Segment) {
+	values := make([]string, 0, len(segments))
+	for _, s := range segments {
+		values = append(values, s.String())
+	}
+	_ = grpc.SetHeader(ctx, metadata.MD{ResolutionsMetadataKey: values})
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerierSegments(t *testing.T) {
+	q := NewQuerier(nil, nil, nil, "stacktraces", nil, nil,
+		WithRawRetention(48*time.Hour),
+		WithTiers([]Tier{
+			{Table: "stacktraces_5m", Resolution: 5 * time.Minute, Retention: 30 * 24 * time.Hour},
+			{Table: "stacktraces_1h", Resolution: time.Hour, Retention: 365 * 24 * time.Hour},
+		}),
+		WithTierPolicy(TierPolicyStitch),
+	)
+
+	day := 24 * time.Hour
+	// The retention boundaries fall within windows of the tiers.
+	now := time.UnixMilli(0).Add(1000*day + 2*time.Hour + 7*time.Minute)
+
+	// A recent range is served from the raw table only.
+	require.Equal(t, []Segment{
+		{Table: "stacktraces", Start: now.Add(-time.Hour), End: now},
+	}, q.segments(now.Add(-time.Hour), now, now))
+
+	// A long range is served from every table covering a part of it. Each
+	// boundary is aligned down to the start of the window of the coarser
+	// tier it falls into, so that no window is read from both tables.
+	hourBoundary := now.Add(-30*day - 7*time.Minute)
+	minuteBoundary := now.Add(-2*day - 2*time.Minute)
+	require.Equal(t, []Segment{
+		{Table: "stacktraces_1h", Resolution: time.Hour, Start: now.Add(-60 * day), End: hourBoundary},
+		{Table: "stacktraces_5m", Resolution: 5 * time.Minute, Start: hourBoundary, End: minuteBoundary},
+		{Table: "stacktraces", Start: minuteBoundary, End: now},
+	}, q.segments(now.Add(-60*day), now, now))
+
+	// A range starting within the window of a boundary is served from the
+	// finer table only.
+	require.Equal(t, []Segment{
+		{Table: "stacktraces", Start: now.Add(-2*day - time.Minute), End: now},
+	}, q.segments(now.Add(-2*day-time.Minute), now, now))
+
+	// Ranges older than all retentions are served from the coarsest tier.
+	require.Equal(t, []Segment{
+		{Table: "stacktraces_1h", Resolution: time.Hour, Start: now.Add(-800 * day), End: now.Add(-700 * day)},
+	}, q.segments(now.Add(-800*day), now.Add(-700*day), now))
+
+	require.Equal(t,
+		"1000,2000,5m",
+		Segment{Resolution: 5 * time.Minute, Start: time.UnixMilli(1000), End: time.UnixMilli(2000)}.String(),
+	)
+}
@@ -0,0 +1,290 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+
+	"github.com/parca-dev/parca/pkg/ingester"
+)
+
+// SnapshotsPath is the HTTP path of the endpoints listing and taking
+// snapshots of the storage, relative to the API root.
+const SnapshotsPath = "/storage/snapshots"
+
+// snapshotMetaFile is the file in a snapshot directory describing it. It is
+// written last, a directory without it is an incomplete snapshot.
+const snapshotMetaFile = "meta.json"
+
+// SnapshotMeta describes a snapshot of the storage.
+type SnapshotMeta struct {
+	Name   string           `json:"name"`
+	Time   time.Time        `json:"time"`
+	Tables map[string]int64 `json:"tables"`
+}
+
+// Snapshots copies the rows of the storage tables to Arrow IPC files in a
+// directory and restores them from there, eg. for backups or to move the
+// data of a non-persistent storage across an upgrade. Every table is a
+// subdirectory of the snapshot holding one file per scanned record, as the
+// records of a table can have different dynamic columns.
+type Snapshots struct {
+	logger log.Logger
+	engine Engine
+	mem    memory.Allocator
+	dir    string
+	// tables are the ingesters of the tables included in snapshots, by
+	// table name.
+	tables map[string]ingester.Ingester
+
+	mtx sync.Mutex
+}
+
+// NewSnapshots returns snapshots of the tables, taken in the directory.
+func NewSnapshots(logger log.Logger, engine Engine, mem memory.Allocator, dir string, tables map[string]ingester.Ingester) *Snapshots {
+	return &Snapshots{
+		logger: logger,
+		engine: engine,
+		mem:    mem,
+		dir:    dir,
+		tables: tables,
+	}
+}
+
+// Snapshot writes all rows of the tables to a new snapshot named by the
+// current time.
+func (s *Snapshots) Snapshot(ctx context.Context) (*SnapshotMeta, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now().UTC()
+	meta := &SnapshotMeta{
+		Name:   now.Format("20060102T150405.000Z"),
+		Time:   now,
+		Tables: map[string]int64{},
+	}
+	dir := filepath.Join(s.dir, meta.Name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create snapshot directory: %w", err)
+	}
+
+	for table := range s.tables {
+		rows, err := s.snapshotTable(ctx, dir, table)
+		if err != nil {
+			return nil, err
+		}
+		meta.Tables[table] = rows
+	}
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, snapshotMetaFile), b, 0o644); err != nil {
+		return nil, fmt.Errorf("write snapshot meta: %w", err)
+	}
+	level.Info(s.logger).Log("msg", "took storage snapshot", "snapshot", meta.Name)
+	return meta, nil
+}
+
+func (s *Snapshots) snapshotTable(ctx context.Context, dir, table string) (int64, error) {
+	tableDir := filepath.Join(dir, table)
+	if err := os.MkdirAll(tableDir, 0o755); err != nil {
+		return 0, fmt.Errorf("create table directory: %w", err)
+	}
+
+	var (
+		rows  int64
+		files int
+	)
+	err := s.engine.ScanTable(table).Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+		if r.NumRows() == 0 {
+			return nil
+		}
+		if err := writeRecordFile(filepath.Join(tableDir, fmt.Sprintf("%08d.arrow", files)), r, s.mem); err != nil {
+			return err
+		}
+		files++
+		rows += r.NumRows()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("snapshot %s: %w", table, err)
+	}
+	return rows, nil
+}
+
+func writeRecordFile(path string, r arrow.Record, mem memory.Allocator) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := ipc.NewWriter(f, ipc.WithSchema(r.Schema()), ipc.WithAllocator(mem))
+	if err := w.Write(r); err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close record writer: %w", err)
+	}
+	return f.Close()
+}
+
+// Restore ingests the rows of the snapshot in the directory into the tables.
+// Tables of the snapshot that don't exist anymore are skipped.
+func (s *Snapshots) Restore(ctx context.Context, dir string) (*SnapshotMeta, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	b, err := os.ReadFile(filepath.Join(dir, snapshotMetaFile))
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot meta: %w", err)
+	}
+	meta := &SnapshotMeta{}
+	if err := json.Unmarshal(b, meta); err != nil {
+		return nil, fmt.Errorf("invalid snapshot meta: %w", err)
+	}
+
+	for table := range meta.Tables {
+		ing, ok := s.tables[table]
+		if !ok {
+			level.Warn(s.logger).Log("msg", "skipping unknown table of snapshot", "table", table)
+			continue
+		}
+		if err := s.restoreTable(ctx, filepath.Join(dir, table), ing); err != nil {
+			return nil, fmt.Errorf("restore %s: %w", table, err)
+		}
+	}
+	level.Info(s.logger).Log("msg", "restored storage snapshot", "snapshot", meta.Name)
+	return meta, nil
+}
+
+func (s *Snapshots) restoreTable(ctx context.Context, dir string, ing ingester.Ingester) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".arrow") {
+			continue
+		}
+		if err := s.restoreFile(ctx, filepath.Join(dir, e.Name()), ing); err != nil {
+			return fmt.Errorf("%s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (s *Snapshots) restoreFile(ctx context.Context, path string, ing ingester.Ingester) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := ipc.NewReader(f, ipc.WithAllocator(s.mem))
+	if err != nil {
+		return err
+	}
+	defer r.Release()
+
+	for r.Next() {
+		if err := ing.Ingest(ctx, r.Record()); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// List returns the complete snapshots, the most recent first.
+func (s *Snapshots) List() ([]*SnapshotMeta, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*SnapshotMeta{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(s.dir, e.Name(), snapshotMetaFile))
+		if err != nil {
+			continue
+		}
+		meta := &SnapshotMeta{}
+		if err := json.Unmarshal(b, meta); err != nil {
+			continue
+		}
+		res = append(res, meta)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Time.After(res[j].Time)
+	})
+	return res, nil
+}
+
+// ListHandler serves the complete snapshots.
+func (s *Snapshots) ListHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		snapshots, err := s.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, struct {
+			Snapshots []*SnapshotMeta `json:"snapshots"`
+		}{snapshots})
+	}
+}
+
+// SnapshotHandler takes a snapshot and serves its description.
+func (s *Snapshots) SnapshotHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		meta, err := s.Snapshot(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, meta)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/profile"
+	"github.com/parca-dev/parca/pkg/profilestore"
+)
+
+func newSnapshotsTestDB(t *testing.T, dir string) (*Snapshots, ingester.Ingester, Engine) {
+	t.Helper()
+
+	col, err := frostdb.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { col.Close() })
+	colDB, err := col.DB(context.Background(), "parca")
+	require.NoError(t, err)
+	table, err := colDB.Table("stacktraces", frostdb.NewTableConfig(profile.SchemaDefinition()))
+	require.NoError(t, err)
+
+	ing := ingester.NewIngester(log.NewNopLogger(), table)
+	engine := query.NewEngine(memory.DefaultAllocator, colDB.TableProvider())
+	return NewSnapshots(log.NewNopLogger(), engine, memory.DefaultAllocator, dir, map[string]ingester.Ingester{"stacktraces": ing}), ing, engine
+}
+
+func countRows(t *testing.T, engine Engine) int64 {
+	t.Helper()
+
+	var rows int64
+	require.NoError(t, engine.ScanTable("stacktraces").Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+		rows += r.NumRows()
+		return nil
+	}))
+	return rows
+}
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	snapshots, ing, engine := newSnapshotsTestDB(t, dir)
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+	store := profilestore.NewProfileColumnStore(
+		prometheus.NewRegistry(),
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		ing,
+		schema,
+		memory.DefaultAllocator,
+	)
+	raw, err := os.ReadFile("../query/testdata/profile1.pb.gz")
+	require.NoError(t, err)
+	_, err = store.WriteRaw(ctx, &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{{
+			Labels: &profilestorepb.LabelSet{Labels: []*profilestorepb.Label{
+				{Name: "__name__", Value: "memory"},
+				{Name: "job", Value: "default"},
+			}},
+			Samples: []*profilestorepb.RawSample{{RawProfile: raw}},
+		}},
+	})
+	require.NoError(t, err)
+	rows := countRows(t, engine)
+	require.NotZero(t, rows)
+
+	meta, err := snapshots.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Equal(t, rows, meta.Tables["stacktraces"])
+
+	list, err := snapshots.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, meta.Name, list[0].Name)
+
+	restored, _, restoredEngine := newSnapshotsTestDB(t, dir)
+	_, err = restored.Restore(ctx, dir+"/"+meta.Name)
+	require.NoError(t, err)
+	require.Equal(t, rows, countRows(t, restoredEngine))
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package parcacol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// TableRetention deletes the blocks persisted to object storage once all the
+// profiles in them are past the retention of their table: the raw table is
+// kept for the raw retention and every tier table for the retention of its
+// tier. Queries don't read a table past its retention, so deleting the
+// blocks doesn't change their results. Data that is not persisted yet is
+// bounded by the active memory size of the storage instead.
+type TableRetention struct {
+	logger log.Logger
+	blocks *Blocks
+	// retentions are how long the rows of each table are kept, by table.
+	// Blocks of tables without a retention are kept forever.
+	retentions map[string]time.Duration
+
+	reclaimedBytes *prometheus.CounterVec
+	deletedBlocks  *prometheus.CounterVec
+}
+
+// NewTableRetention returns the retention of the raw table and the tiers.
+// Zero retentions keep a table forever.
+func NewTableRetention(logger log.Logger, reg prometheus.Registerer, blocks *Blocks, rawTable string, rawRetention time.Duration, tiers []Tier) *TableRetention {
+	r := &TableRetention{
+		logger:     logger,
+		blocks:     blocks,
+		retentions: map[string]time.Duration{},
+		reclaimedBytes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_storage_table_retention_reclaimed_bytes_total",
+			Help: "Total number of bytes of blocks deleted as they were past the retention of their table.",
+		}, []string{"table"}),
+		deletedBlocks: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_storage_table_retention_deleted_blocks_total",
+			Help: "Total number of blocks deleted as they were past the retention of their table.",
+		}, []string{"table"}),
+	}
+	// A row of a tier covers the window of its resolution that starts at
+	// its timestamp. Stitched queries read a table from the start of the
+	// window of the next, coarser tier its retention ends in, see
+	// Querier.segments, so rows are kept for that long past the retention.
+	tables := append([]Tier{{Table: rawTable, Retention: rawRetention}}, tiers...)
+	for i, t := range tables {
+		if t.Retention == 0 {
+			continue
+		}
+		slack := t.Resolution
+		if i+1 < len(tables) && tables[i+1].Resolution > slack {
+			slack = tables[i+1].Resolution
+		}
+		r.retentions[t.Table] = t.Retention + slack
+	}
+	return r
+}
+
+// Enabled returns whether any table has a retention.
+func (r *TableRetention) Enabled() bool {
+	return len(r.retentions) > 0
+}
+
+// Enforce deletes the blocks past the retention of their table as of now, and
+// returns the number of bytes reclaimed. Blocks marked to be retained are
+// kept, and blocks pinned by a read snapshot in flight are deleted by a later
+// run once the snapshot was released.
+func (r *TableRetention) Enforce(ctx context.Context, now time.Time) (int64, error) {
+	blocks, err := listBlocks(ctx, r.blocks.bucket)
+	if err != nil {
+		return 0, err
+	}
+
+	var reclaimed int64
+	for _, block := range blocks {
+		meta, err := r.blocks.blockMeta(ctx, block)
+		if err != nil {
+			return reclaimed, fmt.Errorf("describe block %s: %w", block.id, err)
+		}
+		retention, ok := r.retentions[meta.Table]
+		if !ok || meta.Retain {
+			continue
+		}
+
+		newest := meta.MaxTime
+		if newest.IsZero() {
+			// Without statistics of the timestamps the block can't have
+			// profiles newer than when it was written.
+			newest = meta.Created
+		}
+		if !newest.Before(now.Add(-retention)) {
+			continue
+		}
+
+		err = r.blocks.deleteBlock(ctx, block)
+		if errors.Is(err, ErrBlockPinned) {
+			level.Debug(r.logger).Log("msg", "block pinned by a read snapshot, deferring table retention", "block", block.id)
+			continue
+		}
+		if err != nil {
+			return reclaimed, err
+		}
+		reclaimed += block.size
+		r.reclaimedBytes.WithLabelValues(meta.Table).Add(float64(block.size))
+		r.deletedBlocks.WithLabelValues(meta.Table).Inc()
+	}
+	return reclaimed, nil
+}
+
+// Run enforces the retentions in the interval until the context is canceled.
+func (r *TableRetention) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			reclaimed, err := r.Enforce(ctx, time.Now())
+			if err != nil {
+				level.Warn(r.logger).Log("msg", "failed to enforce table retention", "err", err)
+			}
+			if reclaimed > 0 {
+				level.Info(r.logger).Log("msg", "deleted blocks past the retention of their table", "bytes", reclaimed)
+			}
+		}
+	}
+}
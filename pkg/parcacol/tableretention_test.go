@@ -0,0 +1,115 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package parcacol
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestTableRetention(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	now := time.Unix(100_000, 0)
+
+	type row struct {
+		Timestamp int64 `parquet:"timestamp"`
+	}
+	upload := func(table string, created time.Time, timestamps ...time.Time) string {
+		rows := make([]row, 0, len(timestamps))
+		for _, ts := range timestamps {
+			rows = append(rows, row{Timestamp: ts.UnixMilli()})
+		}
+		buf := &bytes.Buffer{}
+		require.NoError(t, parquet.Write(buf, rows))
+		id := ulid.MustNew(ulid.Timestamp(created), bytes.NewReader(make([]byte, 16))).String()
+		require.NoError(t, bucket.Upload(ctx, "parca/"+table+"/"+id+"/data.parquet", bytes.NewReader(buf.Bytes())))
+		return id
+	}
+
+	tier := Tier{
+		Table:      TierTableName("stacktraces", 5*time.Minute),
+		Resolution: 5 * time.Minute,
+		Retention:  24 * time.Hour,
+	}
+	// The raw table is kept for an hour.
+	expiredRaw := upload("stacktraces", now.Add(-2*time.Hour), now.Add(-3*time.Hour), now.Add(-2*time.Hour))
+	// A block is kept as long as any of its profiles is within retention.
+	mixedRaw := upload("stacktraces", now.Add(-30*time.Minute), now.Add(-3*time.Hour), now.Add(-30*time.Minute))
+	// The newest window of the block still ends within the tier's
+	// retention.
+	lastWindow := upload(tier.Table, now.Add(-23*time.Hour), now.Add(-24*time.Hour).Add(-time.Minute))
+	expiredTier := upload(tier.Table, now.Add(-25*time.Hour), now.Add(-26*time.Hour), now.Add(-25*time.Hour))
+	// Tables without a retention are kept forever.
+	metadata := upload("metadata", now.Add(-100*time.Hour), now.Add(-100*time.Hour))
+
+	blocks := NewBlocks(log.NewNopLogger(), bucket, objstore.NewInMemBucket(), nil)
+	retained := upload("stacktraces", now.Add(-5*time.Hour), now.Add(-5*time.Hour))
+	_, err := blocks.SetRetain(ctx, retained, true)
+	require.NoError(t, err)
+
+	r := NewTableRetention(log.NewNopLogger(), prometheus.NewRegistry(), blocks, "stacktraces", time.Hour, []Tier{tier})
+	reclaimed, err := r.Enforce(ctx, now)
+	require.NoError(t, err)
+	require.Positive(t, reclaimed)
+	require.Equal(t, 1.0, testutil.ToFloat64(r.deletedBlocks.WithLabelValues("stacktraces")))
+	require.Equal(t, 1.0, testutil.ToFloat64(r.deletedBlocks.WithLabelValues(tier.Table)))
+
+	metas, err := blocks.List(ctx)
+	require.NoError(t, err)
+	ids := []string{}
+	for _, m := range metas {
+		ids = append(ids, m.ID)
+	}
+	require.ElementsMatch(t, []string{mixedRaw, lastWindow, metadata, retained}, ids)
+	require.NotContains(t, ids, expiredRaw)
+	require.NotContains(t, ids, expiredTier)
+}
+
+func TestTableRetentionPinned(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+
+	type row struct {
+		Timestamp int64 `parquet:"timestamp"`
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, parquet.Write(buf, []row{{Timestamp: 1000}}))
+	id := ulid.MustNew(ulid.Timestamp(time.Unix(1, 0)), bytes.NewReader(make([]byte, 16))).String()
+	require.NoError(t, bucket.Upload(ctx, "parca/stacktraces/"+id+"/data.parquet", bytes.NewReader(buf.Bytes())))
+	pins := NewSnapshotPins()
+	blocks := NewBlocks(log.NewNopLogger(), bucket, objstore.NewInMemBucket(), pins)
+	r := NewTableRetention(log.NewNopLogger(), prometheus.NewRegistry(), blocks, "stacktraces", time.Hour, nil)
+
+	// A request in flight may read the block, so it is kept until the
+	// request released its snapshot.
+	_, release := pins.ContextWithReadSnapshot(ctx)
+	reclaimed, err := r.Enforce(ctx, time.Now())
+	require.NoError(t, err)
+	require.Zero(t, reclaimed)
+
+	release()
+	reclaimed, err = r.Enforce(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), reclaimed)
+}
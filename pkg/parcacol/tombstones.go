@@ -0,0 +1,527 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/scalar"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/oklog/ulid/v2"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+const (
+	// DeletePath is the HTTP path of the endpoint deleting profiles,
+	// relative to the API root.
+	DeletePath = "/profiles/delete"
+	// UndeletePath is the HTTP path of the endpoint undoing a deletion,
+	// relative to the API root.
+	UndeletePath = "/profiles/undelete"
+)
+
+// ErrUndeleteExpired is returned when undoing a deletion after the grace
+// period.
+var ErrUndeleteExpired = errors.New("grace period to undo the deletion expired")
+
+// Tombstone marks the profiles of the series matching a selector in a time
+// range as deleted.
+type Tombstone struct {
+	ID       string    `json:"id"`
+	Selector string    `json:"selector"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Created  time.Time `json:"created"`
+	// Recompute are the windows of rollups, by rollup, whose synthetic
+	// series were dropped while reclaiming the rows of the deleted
+	// profiles and still have to be rolled up again.
+	Recompute map[string][]time.Time `json:"recompute,omitempty"`
+
+	matchers []*labels.Matcher
+}
+
+// Tombstones records deletions of profiles and excludes them from queries
+// as soon as they are recorded. As the profiles are only hidden at first, a
+// deletion can be undone within a grace period. After that the Reclaimer
+// rewrites the persisted blocks without the rows of the deleted profiles and
+// expires the tombstone. Tombstones are kept in a file, if configured, so
+// deletions survive restarts.
+type Tombstones struct {
+	path  string
+	grace time.Duration
+
+	mtx        sync.RWMutex
+	tombstones []*Tombstone
+}
+
+// NewTombstones returns the tombstones recorded in the file at path. An
+// empty path keeps the tombstones in memory only. Deletions can be undone
+// for the grace period after they were made.
+func NewTombstones(path string, grace time.Duration) (*Tombstones, error) {
+	t := &Tombstones{path: path, grace: grace}
+	if path == "" {
+		return t, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read tombstones: %w", err)
+	}
+	if err := json.Unmarshal(b, &t.tombstones); err != nil {
+		return nil, fmt.Errorf("decode tombstones: %w", err)
+	}
+	for _, ts := range t.tombstones {
+		if ts.matchers, err = parser.ParseMetricSelector(ts.Selector); err != nil {
+			return nil, fmt.Errorf("parse tombstone selector %q: %w", ts.Selector, err)
+		}
+	}
+	return t, nil
+}
+
+// Delete deletes the profiles of the series matching the selector, eg.
+// {job="checkout"}, between start and end inclusive. The selector matches
+// the series of all profile types, unless it selects one with the __name__
+// label like queries do, eg.
+// {__name__="parca_agent:samples:count:cpu:nanoseconds:delta",job="checkout"}.
+func (t *Tombstones) Delete(selector string, start, end time.Time) (*Tombstone, error) {
+	matchers, err := parser.ParseMetricSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("parse selector: %w", err)
+	}
+	if len(matchers) == 0 {
+		return nil, errors.New("selector must have at least one matcher")
+	}
+	for _, m := range matchers {
+		if _, err := negatedMatcherExpression(m); err != nil {
+			return nil, err
+		}
+	}
+	if end.Before(start) {
+		return nil, errors.New("end must not be before start")
+	}
+
+	now := time.Now()
+	ts := &Tombstone{
+		ID:       ulid.MustNew(ulid.Timestamp(now), rand.Reader).String(),
+		Selector: selector,
+		Start:    start,
+		End:      end,
+		Created:  now,
+		matchers: matchers,
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	tombstones := append(t.tombstones[:len(t.tombstones):len(t.tombstones)], ts)
+	if err := t.persist(tombstones); err != nil {
+		return nil, err
+	}
+	t.tombstones = tombstones
+	return ts, nil
+}
+
+// Undelete removes the tombstone with the ID, so that the profiles it
+// deleted are queried again. It fails once the grace period after the
+// deletion expired.
+func (t *Tombstones) Undelete(id string) (*Tombstone, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	i := slices.IndexFunc(t.tombstones, func(ts *Tombstone) bool { return ts.ID == id })
+	if i < 0 {
+		return nil, os.ErrNotExist
+	}
+	ts := t.tombstones[i]
+	if t.final(ts, time.Now()) {
+		return nil, ErrUndeleteExpired
+	}
+
+	tombstones := slices.Delete(slices.Clone(t.tombstones), i, i+1)
+	if err := t.persist(tombstones); err != nil {
+		return nil, err
+	}
+	t.tombstones = tombstones
+	return ts, nil
+}
+
+// update replaces the tombstone with the ID with a copy modified by fn.
+func (t *Tombstones) update(id string, fn func(*Tombstone)) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	i := slices.IndexFunc(t.tombstones, func(ts *Tombstone) bool { return ts.ID == id })
+	if i < 0 {
+		return os.ErrNotExist
+	}
+	ts := *t.tombstones[i]
+	fn(&ts)
+
+	tombstones := slices.Clone(t.tombstones)
+	tombstones[i] = &ts
+	if err := t.persist(tombstones); err != nil {
+		return err
+	}
+	t.tombstones = tombstones
+	return nil
+}
+
+// expire removes the tombstone with the ID once the rows of the profiles it
+// deleted were reclaimed, so that queries don't have to exclude them
+// anymore.
+func (t *Tombstones) expire(id string) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	i := slices.IndexFunc(t.tombstones, func(ts *Tombstone) bool { return ts.ID == id })
+	if i < 0 {
+		return nil
+	}
+	tombstones := slices.Delete(slices.Clone(t.tombstones), i, i+1)
+	if err := t.persist(tombstones); err != nil {
+		return err
+	}
+	t.tombstones = tombstones
+	return nil
+}
+
+// List returns the recorded tombstones, oldest first.
+func (t *Tombstones) List() []*Tombstone {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	return t.tombstones[:len(t.tombstones):len(t.tombstones)]
+}
+
+func (t *Tombstones) persist(tombstones []*Tombstone) error {
+	if t.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(tombstones)
+	if err != nil {
+		return fmt.Errorf("encode tombstones: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return fmt.Errorf("create tombstones directory: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a truncated
+	// file behind, which would lose all deletions.
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write tombstones: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return fmt.Errorf("write tombstones: %w", err)
+	}
+	return nil
+}
+
+// exclusions returns the filter expressions excluding the rows of deleted
+// profiles, one per tombstone. A row is kept if it doesn't match one of the
+// matchers of a tombstone or is outside of its time range.
+func (t *Tombstones) exclusions() ([]logicalplan.Expr, error) {
+	return t.exclusionsOf(negatedMatcherExpression, nil)
+}
+
+// finalExclusions returns the exclusions of the tombstones whose grace period
+// expired at now, so that their deletions can't be undone anymore. Data
+// derived from the stored profiles, like tiers and rollups, only excludes
+// those: while a deletion can still be undone the deleted profiles are
+// hidden when they are read instead, so undoing it restores the derived
+// data too.
+func (t *Tombstones) finalExclusions(now time.Time) ([]logicalplan.Expr, error) {
+	return t.exclusionsOf(negatedMatcherExpression, func(ts *Tombstone) bool {
+		return t.final(ts, now)
+	})
+}
+
+// final returns whether the grace period to undo the deletion of the
+// tombstone expired at now.
+func (t *Tombstones) final(ts *Tombstone, now time.Time) bool {
+	return now.Sub(ts.Created) > t.grace
+}
+
+// metadataExclusions returns the filter expressions excluding the metadata
+// of deleted profiles. The metadata is shared by all sample types of a
+// profile, so deleting one of its profile types excludes it.
+func (t *Tombstones) metadataExclusions() ([]logicalplan.Expr, error) {
+	return t.exclusionsOf(func(m *labels.Matcher) (logicalplan.Expr, error) {
+		if m.Name != labels.MetricName {
+			return negatedMatcherExpression(m)
+		}
+
+		meta, _, err := parseProfileType(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		if m.Type == labels.MatchNotEqual {
+			// Any profile has a profile type not equal to the one of the
+			// matcher, unless it has a single one.
+			return nil, nil
+		}
+		return logicalplan.Col(profile.ColumnName).NotEq(logicalplan.Literal(meta.Name)), nil
+	}, nil)
+}
+
+// Deleted returns whether the profile of the series at the time is deleted.
+// The labels are the ones of a series as written, its __name__ label is the
+// name of the profile. Like its metadata, the profile is deleted if any of
+// its profile types is.
+func (t *Tombstones) Deleted(ls labels.Labels, at time.Time) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	for _, ts := range t.tombstones {
+		if at.Before(ts.Start) || at.After(ts.End) {
+			continue
+		}
+		if matchesProfile(ts.matchers, ls) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesProfile(matchers []*labels.Matcher, ls labels.Labels) bool {
+	for _, m := range matchers {
+		if m.Name != labels.MetricName {
+			if !m.Matches(ls.Get(m.Name)) {
+				return false
+			}
+			continue
+		}
+
+		meta, _, err := parseProfileType(m.Value)
+		if err != nil {
+			return false
+		}
+		if m.Type == labels.MatchEqual && ls.Get(labels.MetricName) != meta.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// exclusionsOf returns the exclusions negating the matchers with negate,
+// which returns nil for a matcher that matches every row, of the tombstones
+// selected by include, or all if it is nil.
+func (t *Tombstones) exclusionsOf(negate func(*labels.Matcher) (logicalplan.Expr, error), include func(*Tombstone) bool) ([]logicalplan.Expr, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	exprs := make([]logicalplan.Expr, 0, len(t.tombstones))
+	for _, ts := range t.tombstones {
+		if include != nil && !include(ts) {
+			continue
+		}
+		or := make([]logicalplan.Expr, 0, len(ts.matchers)+2)
+		for _, m := range ts.matchers {
+			expr, err := negate(m)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				or = append(or, expr)
+			}
+		}
+		or = append(or,
+			logicalplan.Col(profile.ColumnTimestamp).Lt(logicalplan.Literal(timestamp.FromTime(ts.Start))),
+			logicalplan.Col(profile.ColumnTimestamp).Gt(logicalplan.Literal(timestamp.FromTime(ts.End))),
+		)
+		exprs = append(exprs, logicalplan.Or(or...))
+	}
+	return exprs, nil
+}
+
+// negatedMatcherExpression returns the expression matching the rows the
+// matcher doesn't match. Rows without the label have a null value, they are
+// not matched by the matcher unless it matches the empty value.
+func negatedMatcherExpression(m *labels.Matcher) (logicalplan.Expr, error) {
+	if m.Name == labels.MetricName {
+		return negatedProfileTypeExpression(m)
+	}
+
+	inverse, err := inverseMatcher(m)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := MatcherToBooleanExpression(inverse)
+	if err != nil {
+		return nil, err
+	}
+	if m.Matches("") {
+		return expr, nil
+	}
+	return logicalplan.Or(
+		expr,
+		logicalplan.Col(profile.ColumnLabelsPrefix+m.Name).Eq(&logicalplan.LiteralExpr{Value: scalar.ScalarNull}),
+	), nil
+}
+
+// matcherExpression returns the expression matching the rows the matcher
+// matches, including the rows without the label if it matches the empty
+// value.
+func matcherExpression(m *labels.Matcher) (logicalplan.Expr, error) {
+	inverse, err := inverseMatcher(m)
+	if err != nil {
+		return nil, err
+	}
+	return negatedMatcherExpression(inverse)
+}
+
+// inverseMatcher returns the matcher matching the values m doesn't match.
+func inverseMatcher(m *labels.Matcher) (*labels.Matcher, error) {
+	var inverse labels.MatchType
+	switch m.Type {
+	case labels.MatchEqual:
+		inverse = labels.MatchNotEqual
+	case labels.MatchNotEqual:
+		inverse = labels.MatchEqual
+	case labels.MatchRegexp:
+		inverse = labels.MatchNotRegexp
+	case labels.MatchNotRegexp:
+		inverse = labels.MatchRegexp
+	default:
+		return nil, fmt.Errorf("unsupported matcher type %v", m.Type.String())
+	}
+	return labels.NewMatcher(inverse, m.Name, m.Value)
+}
+
+// negatedProfileTypeExpression returns the expression matching the rows the
+// matcher of the __name__ label doesn't match. The label selects a profile
+// type, which is stored in the profile type columns, so it can only be
+// matched exactly.
+func negatedProfileTypeExpression(m *labels.Matcher) (logicalplan.Expr, error) {
+	meta, delta, err := parseProfileType(m.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m.Type {
+	case labels.MatchEqual:
+		deltaPlan := logicalplan.Col(profile.ColumnDuration).NotEq(logicalplan.Literal(0))
+		if delta {
+			deltaPlan = logicalplan.Col(profile.ColumnDuration).Eq(logicalplan.Literal(0))
+		}
+		return logicalplan.Or(
+			logicalplan.Col(profile.ColumnName).NotEq(logicalplan.Literal(meta.Name)),
+			logicalplan.Col(profile.ColumnSampleType).NotEq(logicalplan.Literal(meta.SampleType.Type)),
+			logicalplan.Col(profile.ColumnSampleUnit).NotEq(logicalplan.Literal(meta.SampleType.Unit)),
+			logicalplan.Col(profile.ColumnPeriodType).NotEq(logicalplan.Literal(meta.PeriodType.Type)),
+			logicalplan.Col(profile.ColumnPeriodUnit).NotEq(logicalplan.Literal(meta.PeriodType.Unit)),
+			deltaPlan,
+		), nil
+	case labels.MatchNotEqual:
+		return logicalplan.And(profileTypeExprs(meta, delta)...), nil
+	default:
+		return nil, fmt.Errorf("the profile type of the %s label must be matched exactly", labels.MetricName)
+	}
+}
+
+// DeleteHandler deletes the profiles of the series matching the selector
+// in the match parameter between the start and end parameters, in
+// milliseconds or RFC3339, and serves the recorded tombstone.
+func (t *Tombstones) DeleteHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		params := r.URL.Query()
+		selector := params.Get("match")
+		if selector == "" {
+			http.Error(w, "match parameter is required", http.StatusBadRequest)
+			return
+		}
+		start, err := parseDeleteTime(params.Get("start"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid start: %v", err), http.StatusBadRequest)
+			return
+		}
+		end, err := parseDeleteTime(params.Get("end"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid end: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		ts, err := t.Delete(selector, start, end)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, ts)
+	}
+}
+
+// UndeleteHandler undoes the deletion of the tombstone with the ID in the id
+// parameter and serves the removed tombstone.
+func (t *Tombstones) UndeleteHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ts, err := t.Undelete(r.URL.Query().Get("id"))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			http.Error(w, "tombstone not found", http.StatusNotFound)
+			return
+		case errors.Is(err, ErrUndeleteExpired):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, ts)
+	}
+}
+
+// ListHandler serves the recorded tombstones.
+func (t *Tombstones) ListHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		writeJSON(w, struct {
+			Tombstones []*Tombstone `json:"tombstones"`
+		}{t.List()})
+	}
+}
+
+func parseDeleteTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("missing")
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return timestamp.Time(ms), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
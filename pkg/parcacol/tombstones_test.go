@@ -0,0 +1,184 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+	"github.com/parca-dev/parca/pkg/profilestore"
+)
+
+func TestTombstones(t *testing.T) {
+	ctx := context.Background()
+
+	_, ing, engine := newSnapshotsTestDB(t, t.TempDir())
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+	store := profilestore.NewProfileColumnStore(
+		prometheus.NewRegistry(),
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		ing,
+		schema,
+		memory.DefaultAllocator,
+	)
+	raw, err := os.ReadFile("../query/testdata/profile1.pb.gz")
+	require.NoError(t, err)
+	_, err = store.WriteRaw(ctx, &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{{
+			Labels: &profilestorepb.LabelSet{Labels: []*profilestorepb.Label{
+				{Name: "__name__", Value: "memory"},
+				{Name: "job", Value: "default"},
+			}},
+			Samples: []*profilestorepb.RawSample{{RawProfile: raw}},
+		}},
+	})
+	require.NoError(t, err)
+
+	visibleRows := func(tombstones *Tombstones) int64 {
+		exprs, err := tombstones.exclusions()
+		require.NoError(t, err)
+
+		builder := engine.ScanTable("stacktraces")
+		if len(exprs) > 0 {
+			builder = builder.Filter(logicalplan.And(exprs...))
+		}
+		var rows int64
+		require.NoError(t, builder.Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			rows += r.NumRows()
+			return nil
+		}))
+		return rows
+	}
+
+	// Times after the year 9999 cannot be encoded to JSON.
+	end := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "tombstones.json")
+	tombstones, err := NewTombstones(path, time.Hour)
+	require.NoError(t, err)
+	rows := visibleRows(tombstones)
+	require.NotZero(t, rows)
+
+	// Neither the other job nor a time range before the profile delete it.
+	_, err = tombstones.Delete(`{job="other"}`, time.UnixMilli(0), end)
+	require.NoError(t, err)
+	_, err = tombstones.Delete(`{job="default"}`, time.UnixMilli(0), time.UnixMilli(1))
+	require.NoError(t, err)
+	require.Equal(t, rows, visibleRows(tombstones))
+
+	// The profile type selects the rows of one sample type only, and as
+	// delta profile it selects none of them.
+	_, err = tombstones.Delete(`memory:alloc_objects:count:space:bytes:delta{job="default"}`, time.UnixMilli(0), end)
+	require.NoError(t, err)
+	require.Equal(t, rows, visibleRows(tombstones))
+	_, err = tombstones.Delete(`memory:alloc_objects:count:space:bytes{job="default"}`, time.UnixMilli(0), end)
+	require.NoError(t, err)
+	typeRows := visibleRows(tombstones)
+	require.NotZero(t, typeRows)
+	require.Less(t, typeRows, rows)
+
+	_, err = tombstones.Delete(`{job=~"def.*"}`, time.UnixMilli(0), end)
+	require.NoError(t, err)
+	require.Zero(t, visibleRows(tombstones))
+
+	// The deletions survive a restart.
+	reloaded, err := NewTombstones(path, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, reloaded.List(), 5)
+	require.Zero(t, visibleRows(reloaded))
+
+	// Readers not selecting a profile type exclude the deleted profiles too.
+	q := NewQuerier(log.NewNopLogger(), noop.NewTracerProvider().Tracer(""), engine, "stacktraces", nil, memory.DefaultAllocator, WithTombstones(reloaded))
+	types, err := q.ProfileTypes(ctx)
+	require.NoError(t, err)
+	require.Empty(t, types)
+	names, err := q.Labels(ctx, nil, time.Unix(0, 0), time.Unix(0, 0), "")
+	require.NoError(t, err)
+	require.Empty(t, names)
+	binaries, err := q.Binaries(ctx, "", time.UnixMilli(0), end, "")
+	require.NoError(t, err)
+	require.Empty(t, binaries)
+
+	_, err = tombstones.Delete(`{}`, time.UnixMilli(0), time.UnixMilli(1))
+	require.Error(t, err)
+	_, err = tombstones.Delete(`{__name__=~"memory:.*"}`, time.UnixMilli(0), time.UnixMilli(1))
+	require.Error(t, err)
+	_, err = tombstones.Delete(`{__name__="memory"}`, time.UnixMilli(0), time.UnixMilli(1))
+	require.Error(t, err)
+}
+
+func TestTombstonesDeleted(t *testing.T) {
+	tombstones, err := NewTombstones(filepath.Join(t.TempDir(), "tombstones.json"), time.Hour)
+	require.NoError(t, err)
+
+	_, err = tombstones.Delete(`memory:alloc_objects:count:space:bytes{job="api"}`, time.UnixMilli(100), time.UnixMilli(200))
+	require.NoError(t, err)
+
+	api := labels.FromStrings("__name__", "memory", "job", "api")
+	require.True(t, tombstones.Deleted(api, time.UnixMilli(150)))
+	require.False(t, tombstones.Deleted(api, time.UnixMilli(250)))
+	require.False(t, tombstones.Deleted(labels.FromStrings("__name__", "memory", "job", "db"), time.UnixMilli(150)))
+	require.False(t, tombstones.Deleted(labels.FromStrings("__name__", "cpu", "job", "api"), time.UnixMilli(150)))
+
+	var none *Tombstones
+	require.False(t, none.Deleted(api, time.UnixMilli(150)))
+}
+
+func TestTombstonesUndelete(t *testing.T) {
+	tombstones, err := NewTombstones(filepath.Join(t.TempDir(), "tombstones.json"), time.Hour)
+	require.NoError(t, err)
+
+	ts, err := tombstones.Delete(`{job="default"}`, time.UnixMilli(0), time.UnixMilli(1))
+	require.NoError(t, err)
+	exprs, err := tombstones.exclusions()
+	require.NoError(t, err)
+	require.Len(t, exprs, 1)
+	// Derived data keeps the profiles while the deletion can be undone.
+	exprs, err = tombstones.finalExclusions(time.Now())
+	require.NoError(t, err)
+	require.Empty(t, exprs)
+
+	undeleted, err := tombstones.Undelete(ts.ID)
+	require.NoError(t, err)
+	require.Equal(t, ts.Selector, undeleted.Selector)
+	require.Empty(t, tombstones.List())
+
+	_, err = tombstones.Undelete(ts.ID)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// Deletions older than the grace period are permanent.
+	ts, err = tombstones.Delete(`{job="default"}`, time.UnixMilli(0), time.UnixMilli(1))
+	require.NoError(t, err)
+	ts.Created = time.Now().Add(-2 * time.Hour)
+	_, err = tombstones.Undelete(ts.ID)
+	require.ErrorIs(t, err, ErrUndeleteExpired)
+	exprs, err = tombstones.finalExclusions(time.Now())
+	require.NoError(t, err)
+	require.Len(t, exprs, 1)
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// DefaultTraceIDLabel is the sample label agents attach trace IDs as.
+const DefaultTraceIDLabel = "trace_id"
+
+// TraceExemplar links the samples of a profile to the distributed trace
+// they were recorded in.
+type TraceExemplar struct {
+	TraceID string `json:"traceId"`
+	// Value is the total value of the samples recorded in the trace.
+	Value int64 `json:"value"`
+	// Timestamp is the time of the last profile with samples of the trace.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TraceExemplars returns the traces the samples matching the query in the
+// time range were recorded in, by the value of the given sample label, the
+// traces with the highest total value first. At most limit traces are
+// returned, unless it is zero.
+func (q *Querier) TraceExemplars(ctx context.Context, query string, start, end time.Time, label string, limit int) ([]*TraceExemplar, error) {
+	ctx, span := q.tracer.Start(ctx, "Querier/TraceExemplars")
+	defer span.End()
+
+	_, exprs, err := q.queryToFilterExprs(query)
+	if err != nil {
+		return nil, err
+	}
+	m, err := labels.NewMatcher(labels.MatchNotEqual, label, "")
+	if err != nil {
+		return nil, err
+	}
+	labelExprs, err := MatchersToBooleanExpressions([]*labels.Matcher{m})
+	if err != nil {
+		return nil, err
+	}
+	exprs = append(exprs, labelExprs...)
+	exprs = append(exprs,
+		logicalplan.Col(profile.ColumnTimestamp).GtEq(logicalplan.Literal(timestamp.FromTime(start))),
+		logicalplan.Col(profile.ColumnTimestamp).LtEq(logicalplan.Literal(timestamp.FromTime(end))),
+	)
+
+	totalSum := logicalplan.Sum(logicalplan.Col(profile.ColumnValue))
+	latest := logicalplan.Max(logicalplan.Col(profile.ColumnTimestamp))
+	column := profile.ColumnLabelsPrefix + label
+
+	var res []*TraceExemplar
+	err = q.engine.ScanTable(q.tableName).
+		Filter(logicalplan.And(exprs...)).
+		Aggregate(
+			[]*logicalplan.AggregationFunction{totalSum, latest},
+			[]logicalplan.Expr{logicalplan.Col(column)},
+		).
+		Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+			var (
+				traceIDs   arrow.Array
+				values     *array.Int64
+				timestamps *array.Int64
+			)
+			for i, field := range r.Schema().Fields() {
+				switch field.Name {
+				case column:
+					traceIDs = r.Column(i)
+				case totalSum.Name():
+					values, _ = r.Column(i).(*array.Int64)
+				case latest.Name():
+					timestamps, _ = r.Column(i).(*array.Int64)
+				}
+			}
+			if traceIDs == nil || values == nil || timestamps == nil {
+				return fmt.Errorf("unexpected record schema: %s", r.Schema())
+			}
+
+			for i := 0; i < int(r.NumRows()); i++ {
+				if traceIDs.IsNull(i) {
+					continue
+				}
+				res = append(res, &TraceExemplar{
+					TraceID:   labelValue(traceIDs, i),
+					Value:     values.Value(i),
+					Timestamp: timestamp.Time(timestamps.Value(i)),
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Value != res[j].Value {
+			return res[i].Value > res[j].Value
+		}
+		return res[i].TraceID < res[j].TraceID
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/model/timestamp"
+)
+
+// Watermark is the start of the next window a background job, eg. a
+// downsampler, has to process. It is kept in a file, if configured, so that
+// after a restart the job catches up on the windows that completed while it
+// was not running instead of skipping them.
+type Watermark struct {
+	path string
+}
+
+// NewWatermark returns the watermark kept in the file at path. An empty path
+// keeps it in memory only.
+func NewWatermark(path string) *Watermark {
+	return &Watermark{path: path}
+}
+
+type watermarkFile struct {
+	// Next is the start of the next window in milliseconds.
+	Next int64 `json:"next"`
+}
+
+// load returns the start of the next window in milliseconds, or false if
+// none was stored.
+func (w *Watermark) load() (int64, bool, error) {
+	if w == nil || w.path == "" {
+		return 0, false, nil
+	}
+
+	b, err := os.ReadFile(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("read watermark: %w", err)
+	}
+	var f watermarkFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return 0, false, fmt.Errorf("decode watermark: %w", err)
+	}
+	return f.Next, true, nil
+}
+
+func (w *Watermark) store(next int64) error {
+	if w == nil || w.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(watermarkFile{Next: next})
+	if err != nil {
+		return fmt.Errorf("encode watermark: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return fmt.Errorf("create watermark directory: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a truncated
+	// file behind.
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write watermark: %w", err)
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return fmt.Errorf("write watermark: %w", err)
+	}
+	return nil
+}
+
+// runWindows calls process with the start of every window of the interval,
+// in milliseconds, once the window ended delay ago, until the context is
+// canceled. It starts at the watermark and advances it past every processed
+// window. Without a watermark it starts at the window that is currently
+// completing, so earlier windows are not processed.
+func runWindows(
+	ctx context.Context,
+	logger log.Logger,
+	watermark *Watermark,
+	interval, delay time.Duration,
+	process func(ctx context.Context, start int64),
+) error {
+	step := interval.Milliseconds()
+	next := timestamp.FromTime(time.Now().Add(-delay)) / step * step
+	stored, ok, err := watermark.load()
+	if err != nil {
+		level.Warn(logger).Log("msg", "failed to load watermark, starting at the current window", "err", err)
+	}
+	if ok {
+		// The interval may have been reconfigured since the watermark was
+		// stored.
+		next = stored / step * step
+		if behind := (timestamp.FromTime(time.Now().Add(-delay)) - next) / step; behind > 0 {
+			level.Info(logger).Log("msg", "catching up on windows", "from", timestamp.Time(next), "windows", behind)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done := timestamp.FromTime(time.Now().Add(-delay))
+		for next+step <= done {
+			if ctx.Err() != nil {
+				return nil
+			}
+			process(ctx, next)
+			next += step
+			if err := watermark.store(next); err != nil {
+				level.Warn(logger).Log("msg", "failed to store watermark", "err", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatermark(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watermarks", "downsample.json")
+
+	w := NewWatermark(path)
+	_, ok, err := w.load()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, w.store(3600000))
+	next, ok, err := NewWatermark(path).load()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, int64(3600000), next)
+
+	// Without a path, or a watermark at all, nothing is kept.
+	require.NoError(t, NewWatermark("").store(1))
+	_, ok, err = NewWatermark("").load()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	var none *Watermark
+	require.NoError(t, none.store(1))
+	_, ok, err = none.load()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestRunWindowsCatchUp(t *testing.T) {
+	const interval = time.Hour
+	step := interval.Milliseconds()
+	current := timestamp.FromTime(time.Now()) / step * step
+
+	// The job last ran three windows ago.
+	w := NewWatermark(filepath.Join(t.TempDir(), "rollup.json"))
+	require.NoError(t, w.store(current-3*step))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var starts []int64
+	err := runWindows(ctx, log.NewNopLogger(), w, interval, 0, func(_ context.Context, start int64) {
+		starts = append(starts, start)
+		if len(starts) == 3 {
+			cancel()
+		}
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{current - 3*step, current - 2*step, current - step}, starts)
+
+	next, ok, err := w.load()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, current, next)
+}
+
+func TestRunWindowsStopsCatchUp(t *testing.T) {
+	const interval = time.Hour
+	step := interval.Milliseconds()
+	current := timestamp.FromTime(time.Now()) / step * step
+
+	w := NewWatermark(filepath.Join(t.TempDir(), "rollup.json"))
+	require.NoError(t, w.store(current-3*step))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Canceling the context stops catching up after the current window, and
+	// the remaining windows are processed after the next start.
+	var starts []int64
+	err := runWindows(ctx, log.NewNopLogger(), w, interval, 0, func(_ context.Context, start int64) {
+		starts = append(starts, start)
+		cancel()
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{current - 3*step}, starts)
+
+	next, _, err := w.load()
+	require.NoError(t, err)
+	require.Equal(t, current-2*step, next)
+}
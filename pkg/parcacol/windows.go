@@ -0,0 +1,381 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parcacol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// QueryRangeWindows evaluates the same range query over multiple time windows.
+// The windows that read the same table share a single selection of the
+// samples in any of them, which are then bucketed by step for each window.
+// The results are returned in the order of the windows, windows without
+// samples have no series.
+func (q *Querier) QueryRangeWindows(
+	ctx context.Context,
+	query string,
+	windows []TimeRange,
+	step time.Duration,
+	limit uint32,
+	sumBy []string,
+) ([][]*pb.MetricsSeries, error) {
+	queryParts, selectorExprs, err := q.queryToFilterExprs(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// The step cannot be lower than 1s
+	if step < time.Second {
+		step = time.Second
+	}
+
+	res := make([][]*pb.MetricsSeries, len(windows))
+	g, ctx := errgroup.WithContext(ctx)
+
+	// A stitched window reads from several tables, so each is evaluated on
+	// its own.
+	if queryParts.Delta && len(q.tiers) > 0 && q.tierPolicy == TierPolicyStitch {
+		for i, w := range windows {
+			g.Go(func() error {
+				series, err := q.queryRange(ctx, queryParts, selectorExprs, w.Start, w.End, step, sumBy)
+				if err != nil {
+					return fmt.Errorf("window %d: %w", i, err)
+				}
+				res[i] = series
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	var (
+		tables   []string
+		byTable  = map[string][]int{}
+		segments = make([]Segment, 0, len(windows))
+	)
+	for i, w := range windows {
+		table := q.tableName
+		if queryParts.Delta {
+			table = q.deltaTable(w.Start, readTime(ctx))
+		}
+		if _, ok := byTable[table]; !ok {
+			tables = append(tables, table)
+		}
+		byTable[table] = append(byTable[table], i)
+		segments = append(segments, q.segment(table, w.Start, w.End))
+	}
+	if queryParts.Delta && len(q.tiers) > 0 {
+		reportResolutions(ctx, segments)
+	}
+
+	for _, table := range tables {
+		indices := byTable[table]
+		g.Go(func() error {
+			tableWindows := make([]TimeRange, 0, len(indices))
+			for _, i := range indices {
+				tableWindows = append(tableWindows, windows[i])
+			}
+			series, err := q.selectWindows(ctx, table, queryParts, selectorExprs, tableWindows, step, sumBy)
+			if err != nil {
+				return err
+			}
+			for j, i := range indices {
+				res[i] = series[j]
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if empty(res) {
+		return nil, status.Error(
+			codes.NotFound,
+			"No data found for the query, try a different query or time range or no data has been written to be queried yet.",
+		)
+	}
+
+	return res, nil
+}
+
+func empty(res [][]*pb.MetricsSeries) bool {
+	for _, series := range res {
+		if len(series) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// windowBucket is a step of a series of a window, aggregated from the
+// samples of each timestamp in it.
+type windowBucket struct {
+	value       int64
+	durationMin int64
+	timestamps  int
+}
+
+// windowSeries are the series of a window, by their label set.
+type windowSeries struct {
+	series  []*pb.MetricsSeries
+	index   map[string]int
+	buckets []map[int64]*windowBucket
+}
+
+func (w *windowSeries) get(key string, lset labels.Labels, newSeries func(labels.Labels) *pb.MetricsSeries) int {
+	i, ok := w.index[key]
+	if !ok {
+		w.series = append(w.series, newSeries(lset))
+		w.buckets = append(w.buckets, map[int64]*windowBucket{})
+		i = len(w.series) - 1
+		w.index[key] = i
+	}
+	return i
+}
+
+// selectWindows selects the samples of the table in any of the windows with
+// a single scan, summed by timestamp and the sum by labels, and buckets them
+// by step for each window. Delta profiles are bucketed like queryRangeDelta
+// does, otherwise the first sample of each bucket is kept like
+// queryRangeNonDelta does.
+func (q *Querier) selectWindows(
+	ctx context.Context,
+	table string,
+	queryParts QueryParts,
+	selectorExprs []logicalplan.Expr,
+	windows []TimeRange,
+	step time.Duration,
+	sumBy []string,
+) ([][]*pb.MetricsSeries, error) {
+	ranges := make([]logicalplan.Expr, 0, len(windows))
+	for _, w := range windows {
+		ranges = append(ranges, logicalplan.And(
+			logicalplan.Col(profile.ColumnTimestamp).Gt(logicalplan.Literal(timestamp.FromTime(w.Start))),
+			logicalplan.Col(profile.ColumnTimestamp).Lt(logicalplan.Literal(timestamp.FromTime(w.End))),
+		))
+	}
+	exprs := make([]logicalplan.Expr, 0, len(selectorExprs)+1)
+	exprs = append(exprs, selectorExprs...)
+	exprs = append(exprs, logicalplan.Or(ranges...))
+	filterExpr := logicalplan.And(exprs...)
+
+	m := queryParts.Meta
+	resultType := m.SampleType
+	var valueExpr logicalplan.Expr = logicalplan.Col(profile.ColumnValue)
+	if queryParts.Delta && isSamplesCount(m.SampleType) {
+		// 1 CPU sample is equivalent to whatever the period is.
+		valueExpr = logicalplan.Mul(
+			logicalplan.Col(profile.ColumnValue),
+			logicalplan.Col(profile.ColumnPeriod),
+		).Alias(profile.ColumnValue)
+		resultType = m.PeriodType
+	}
+	valueSum := logicalplan.Sum(logicalplan.Col(profile.ColumnValue))
+	durationMin := logicalplan.Min(logicalplan.Col(profile.ColumnDuration))
+
+	sel := queryStatsFromContext(ctx).selection(table, filterExpr)
+	begin := time.Now()
+
+	records := []arrow.Record{}
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+	err := q.engine.ScanTable(table).
+		Filter(filterExpr).
+		Project(
+			logicalplan.Col(profile.ColumnTimestamp),
+			logicalplan.DynCol(profile.ColumnLabels),
+			logicalplan.Col(profile.ColumnDuration),
+			valueExpr,
+		).
+		Aggregate(
+			[]*logicalplan.AggregationFunction{
+				durationMin,
+				valueSum,
+			},
+			append([]logicalplan.Expr{
+				logicalplan.Col(profile.ColumnTimestamp),
+			}, getSumByAggregateExprs(sumBy)...),
+		).
+		Execute(ctx, func(ctx context.Context, r arrow.Record) error {
+			r.Retain()
+			records = append(records, r)
+			sel.record(r)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	newSeries := func(lset labels.Labels) *pb.MetricsSeries {
+		pbLabelSet := make([]*profilestorepb.Label, 0, len(lset))
+		for _, l := range lset {
+			pbLabelSet = append(pbLabelSet, &profilestorepb.Label{
+				Name:  l.Name,
+				Value: l.Value,
+			})
+		}
+		s := &pb.MetricsSeries{Labelset: &profilestorepb.LabelSet{Labels: pbLabelSet}}
+		if queryParts.Delta {
+			s.PeriodType = &pb.ValueType{Type: m.PeriodType.Type, Unit: m.PeriodType.Unit}
+			s.SampleType = &pb.ValueType{Type: resultType.Type, Unit: resultType.Unit}
+		}
+		return s
+	}
+
+	res := make([]*windowSeries, len(windows))
+	for i := range res {
+		res[i] = &windowSeries{index: map[string]int{}}
+	}
+	labelSet := labels.Labels{}
+	for _, ar := range records {
+		fields := ar.Schema().Fields()
+		timestampIndex, valueIndex, durationIndex := -1, -1, -1
+		labelColumnIndices := []int{}
+		for i, field := range fields {
+			switch field.Name {
+			case profile.ColumnTimestamp:
+				timestampIndex = i
+			case valueSum.Name():
+				valueIndex = i
+			case durationMin.Name():
+				durationIndex = i
+			default:
+				if strings.HasPrefix(field.Name, profile.ColumnLabelsPrefix) {
+					labelColumnIndices = append(labelColumnIndices, i)
+				}
+			}
+		}
+		if timestampIndex == -1 || valueIndex == -1 || durationIndex == -1 {
+			return nil, fmt.Errorf("expected timestamp, %s and %s columns", valueSum.Name(), durationMin.Name())
+		}
+
+		for i := 0; i < int(ar.NumRows()); i++ {
+			labelSet = labelSet[:0]
+			for _, labelColumnIndex := range labelColumnIndices {
+				col, ok := ar.Column(labelColumnIndex).(*array.Dictionary)
+				if !ok || col.IsNull(i) {
+					continue
+				}
+				if v := StringValueFromDictionary(col, i); len(v) > 0 {
+					labelSet = append(labelSet, labels.Label{Name: strings.TrimPrefix(fields[labelColumnIndex].Name, profile.ColumnLabelsPrefix), Value: v})
+				}
+			}
+			sort.Sort(labelSet)
+			key := labelSet.String()
+
+			ts := ar.Column(timestampIndex).(*array.Int64).Value(i)
+			value := ar.Column(valueIndex).(*array.Int64).Value(i)
+			duration := ar.Column(durationIndex).(*array.Int64).Value(i)
+
+			for j, w := range windows {
+				if ts <= timestamp.FromTime(w.Start) || ts >= timestamp.FromTime(w.End) {
+					continue
+				}
+				ws := res[j]
+				index := ws.get(key, labelSet, newSeries)
+
+				if !queryParts.Delta {
+					// Each step bucket only returns the first of its timestamps and its value.
+					tsBucket := ts / 1000 / int64(step.Seconds())
+					if _, found := ws.buckets[index][tsBucket]; found {
+						continue
+					}
+					ws.buckets[index][tsBucket] = &windowBucket{}
+					ws.series[index].Samples = append(ws.series[index].Samples, &pb.MetricsSample{
+						Timestamp:      timestamppb.New(timestamp.Time(ts)),
+						Value:          value,
+						ValuePerSecond: float64(value),
+					})
+					continue
+				}
+
+				tsBucket := ts / step.Milliseconds() * step.Milliseconds()
+				b, ok := ws.buckets[index][tsBucket]
+				if !ok {
+					b = &windowBucket{durationMin: duration}
+					ws.buckets[index][tsBucket] = b
+				}
+				b.value += value
+				b.durationMin = min(b.durationMin, duration)
+				b.timestamps++
+			}
+		}
+	}
+
+	series := make([][]*pb.MetricsSeries, len(windows))
+	total := 0
+	for i, ws := range res {
+		if queryParts.Delta {
+			for j, s := range ws.series {
+				for ts, b := range ws.buckets[j] {
+					s.Samples = append(s.Samples, deltaSample(ts, b, step, resultType))
+				}
+			}
+		}
+		for _, s := range ws.series {
+			sort.Slice(s.Samples, func(i, j int) bool {
+				return s.Samples[i].Timestamp.AsTime().Before(s.Samples[j].Timestamp.AsTime())
+			})
+		}
+		series[i] = ws.series
+		total += len(ws.series)
+	}
+	sel.finish(begin, total)
+
+	return series, nil
+}
+
+// deltaSample returns the sample of a bucket of delta profiles. Like in
+// queryRangeDelta, the duration of a bucket of a single timestamp is the one
+// of its profiles, otherwise the step.
+func deltaSample(ts int64, b *windowBucket, step time.Duration, resultType profile.ValueType) *pb.MetricsSample {
+	duration := b.durationMin
+	if b.timestamps > 1 {
+		duration = step.Nanoseconds()
+	}
+	perSecond := float64(b.value) / float64(duration)
+	if !isNanoseconds(resultType) {
+		perSecond = float64(b.value) / (float64(duration) / float64(time.Second.Nanoseconds()))
+	}
+	return &pb.MetricsSample{
+		Timestamp:      timestamppb.New(timestamp.Time(ts)),
+		Value:          b.value,
+		ValuePerSecond: perSecond,
+		Duration:       duration,
+	}
+}
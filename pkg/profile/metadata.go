@@ -0,0 +1,81 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+)
+
+const (
+	// MetadataTableName is the table storing the metadata written along
+	// with profiles, one row per written profile.
+	MetadataTableName = "profile_metadata"
+	// ColumnMetadata is the dynamic column holding the metadata by key.
+	ColumnMetadata = "metadata"
+)
+
+// MetadataSchemaDefinition is the schema of the metadata table. Rows are
+// identified by the profile name, labels and timestamp of the profile they
+// belong to.
+func MetadataSchemaDefinition() *schemapb.Schema {
+	return &schemapb.Schema{
+		Name: SchemaName + "_metadata",
+		Columns: []*schemapb.Column{
+			{
+				Name: ColumnLabels,
+				StorageLayout: &schemapb.StorageLayout{
+					Type:     schemapb.StorageLayout_TYPE_STRING,
+					Encoding: schemapb.StorageLayout_ENCODING_RLE_DICTIONARY,
+					Nullable: true,
+				},
+				Dynamic: true,
+			}, {
+				Name: ColumnMetadata,
+				StorageLayout: &schemapb.StorageLayout{
+					Type:     schemapb.StorageLayout_TYPE_STRING,
+					Encoding: schemapb.StorageLayout_ENCODING_RLE_DICTIONARY,
+					Nullable: true,
+				},
+				Dynamic: true,
+			}, {
+				Name: ColumnName,
+				StorageLayout: &schemapb.StorageLayout{
+					Type:     schemapb.StorageLayout_TYPE_STRING,
+					Encoding: schemapb.StorageLayout_ENCODING_RLE_DICTIONARY,
+				},
+				Dynamic: false,
+			}, {
+				Name: ColumnTimestamp,
+				StorageLayout: &schemapb.StorageLayout{
+					Type:        schemapb.StorageLayout_TYPE_INT64,
+					Encoding:    schemapb.StorageLayout_ENCODING_DELTA_BINARY_PACKED,
+					Compression: schemapb.StorageLayout_COMPRESSION_LZ4_RAW,
+				},
+				Dynamic: false,
+			},
+		},
+		SortingColumns: []*schemapb.SortingColumn{
+			{
+				Name:      ColumnName,
+				Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+			}, {
+				Name:      ColumnTimestamp,
+				Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+			},
+		},
+	}
+}
+
+// ColumnMetadataPrefix prefixes the names of the metadata columns.
+const ColumnMetadataPrefix = ColumnMetadata + "."
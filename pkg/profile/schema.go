@@ -14,6 +14,8 @@
 package profile
 
 import (
+	"fmt"
+
 	"github.com/polarsignals/frostdb/dynparquet"
 	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
 )
@@ -147,6 +149,36 @@ func SchemaDefinition() *schemapb.Schema {
 	}
 }
 
+// Compressions of the compressed columns, see SetCompression.
+const (
+	CompressionLZ4  = "lz4"
+	CompressionZSTD = "zstd"
+)
+
+// SetCompression sets the compression of the compressed columns of the schema
+// definition, eg. the stacktraces, timestamps and values, to lz4 or zstd. The
+// compression is applied when parts are compacted and blocks are persisted.
+// Every column chunk records its compression, so blocks written with either
+// are read alike.
+func SetCompression(def *schemapb.Schema, compression string) error {
+	var c schemapb.StorageLayout_Compression
+	switch compression {
+	case CompressionLZ4:
+		c = schemapb.StorageLayout_COMPRESSION_LZ4_RAW
+	case CompressionZSTD:
+		c = schemapb.StorageLayout_COMPRESSION_ZSTD
+	default:
+		return fmt.Errorf("unknown compression %q", compression)
+	}
+	for _, col := range def.Columns {
+		switch col.StorageLayout.Compression {
+		case schemapb.StorageLayout_COMPRESSION_LZ4_RAW, schemapb.StorageLayout_COMPRESSION_ZSTD:
+			col.StorageLayout.Compression = c
+		}
+	}
+	return nil
+}
+
 func Schema() (*dynparquet.Schema, error) {
 	return dynparquet.SchemaFromDefinition(SchemaDefinition())
 }
@@ -0,0 +1,43 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	"testing"
+
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSetCompression(t *testing.T) {
+	t.Parallel()
+
+	def := SchemaDefinition()
+	require.NoError(t, SetCompression(def, CompressionZSTD))
+	compressions := map[string]schemapb.StorageLayout_Compression{}
+	for _, col := range def.Columns {
+		compressions[col.Name] = col.StorageLayout.Compression
+	}
+	require.Equal(t, schemapb.StorageLayout_COMPRESSION_ZSTD, compressions[ColumnStacktrace])
+	require.Equal(t, schemapb.StorageLayout_COMPRESSION_ZSTD, compressions[ColumnTimestamp])
+	require.Equal(t, schemapb.StorageLayout_COMPRESSION_ZSTD, compressions[ColumnValue])
+	// Dictionary encoded columns stay uncompressed.
+	require.Equal(t, SchemaDefinition().Columns[0].StorageLayout.Compression, compressions[ColumnDuration])
+
+	require.NoError(t, SetCompression(def, CompressionLZ4))
+	require.True(t, proto.Equal(SchemaDefinition(), def))
+
+	require.Error(t, SetCompression(def, "snappy"))
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+var (
+	ErrTooFarInFuture = errors.New("profile timestamp is too far in the future")
+	ErrTooFarInPast   = errors.New("profile timestamp is too far in the past")
+)
+
+// OutOfBoundsError is returned when profiles of a write were rejected because
+// their timestamps are outside of the accepted bounds. The remaining profiles
+// of the write are still ingested.
+type OutOfBoundsError struct {
+	// Err is either ErrTooFarInFuture or ErrTooFarInPast, whichever was
+	// encountered first.
+	Err error
+	// Timestamp of the first rejected profile in milliseconds since epoch.
+	Timestamp int64
+	// Rejected is the number of rejected profiles.
+	Rejected int
+}
+
+func (e *OutOfBoundsError) Error() string {
+	return fmt.Sprintf("%d profile(s) rejected, first at %s: %v", e.Rejected, time.UnixMilli(e.Timestamp).UTC().Format(time.RFC3339), e.Err)
+}
+
+func (e *OutOfBoundsError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus makes the error an InvalidArgument error when returned from a
+// gRPC handler.
+func (e *OutOfBoundsError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// timeBounds rejects profiles with timestamps too far from the server's time.
+// A single client with a bad clock must not be able to write samples that
+// extend the time range of the stored data arbitrarily.
+type timeBounds struct {
+	maxFuture time.Duration
+	maxPast   time.Duration
+
+	rejected *prometheus.CounterVec
+}
+
+func newTimeBounds(reg prometheus.Registerer, maxFuture, maxPast time.Duration) *timeBounds {
+	return &timeBounds{
+		maxFuture: maxFuture,
+		maxPast:   maxPast,
+		rejected: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_out_of_bounds_profiles_total",
+			Help: "Total number of profiles rejected at ingest because their timestamp was out of bounds.",
+		}, []string{"reason"}),
+	}
+}
+
+// filter removes the profiles that are out of bounds from the request. The
+// current time is given in milliseconds since epoch. If any profiles were
+// removed, an *OutOfBoundsError is returned along with the remaining request.
+// The optional onReject is called for every removed profile.
+func (b *timeBounds) filter(
+	req normalizer.NormalizedWriteRawRequest,
+	now int64,
+	onReject func(s normalizer.Series, p *normalizer.NormalizedProfile, err error),
+) (normalizer.NormalizedWriteRawRequest, error) {
+	if b.maxFuture <= 0 && b.maxPast <= 0 {
+		return req, nil
+	}
+
+	var oobErr *OutOfBoundsError
+	reject := func(s normalizer.Series, p *normalizer.NormalizedProfile, err error) {
+		if oobErr == nil {
+			oobErr = &OutOfBoundsError{Err: err, Timestamp: p.Meta.Timestamp}
+		}
+		oobErr.Rejected++
+		if onReject != nil {
+			onReject(s, p, err)
+		}
+	}
+
+	series := req.Series[:0]
+	for _, s := range req.Series {
+		samples := s.Samples[:0]
+		for _, sample := range s.Samples {
+			profiles := sample[:0]
+			for _, p := range sample {
+				switch {
+				case b.maxFuture > 0 && p.Meta.Timestamp > now+b.maxFuture.Milliseconds():
+					b.rejected.WithLabelValues("too_far_in_future").Inc()
+					reject(s, p, ErrTooFarInFuture)
+				case b.maxPast > 0 && p.Meta.Timestamp < now-b.maxPast.Milliseconds():
+					b.rejected.WithLabelValues("too_far_in_past").Inc()
+					reject(s, p, ErrTooFarInPast)
+				default:
+					profiles = append(profiles, p)
+				}
+			}
+			if len(profiles) > 0 {
+				samples = append(samples, profiles)
+			}
+		}
+		if len(samples) > 0 {
+			s.Samples = samples
+			series = append(series, s)
+		}
+	}
+	req.Series = series
+
+	if oobErr != nil {
+		return req, oobErr
+	}
+	return req, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTimeBounds(t *testing.T) {
+	const now = 10_000_000
+
+	b := newTimeBounds(prometheus.NewRegistry(), time.Minute, time.Hour)
+
+	req, err := b.filter(normalizedRequest(now, now+2*60_000, now-2*3_600_000, now+60_000), now, nil)
+	require.Equal(t, []int64{now, now + 60_000}, timestamps(req))
+
+	var oobErr *OutOfBoundsError
+	require.True(t, errors.As(err, &oobErr))
+	require.Equal(t, 2, oobErr.Rejected)
+	require.Equal(t, int64(now+2*60_000), oobErr.Timestamp)
+	require.True(t, errors.Is(err, ErrTooFarInFuture))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	require.Equal(t, 1.0, testutil.ToFloat64(b.rejected.WithLabelValues("too_far_in_future")))
+	require.Equal(t, 1.0, testutil.ToFloat64(b.rejected.WithLabelValues("too_far_in_past")))
+
+	// Rejecting every profile removes the series.
+	req, err = b.filter(normalizedRequest(now-2*3_600_000), now, nil)
+	require.Empty(t, req.Series)
+	require.True(t, errors.Is(err, ErrTooFarInPast))
+}
+
+func TestTimeBoundsDisabled(t *testing.T) {
+	b := newTimeBounds(prometheus.NewRegistry(), 0, 0)
+
+	req, err := b.filter(normalizedRequest(0, 1<<60), 1_000, nil)
+	require.NoError(t, err)
+	require.Equal(t, []int64{0, 1 << 60}, timestamps(req))
+}
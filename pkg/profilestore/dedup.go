@@ -0,0 +1,142 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/cache"
+)
+
+type dedupKey struct {
+	series  uint64
+	content uint64
+}
+
+// deduplicator drops raw samples that were already ingested for the same
+// series within a time window. Agents retry writes that they believe failed,
+// so the same profile may be received more than once, which would double
+// count its samples. A raw profile contains its own timestamp, so two
+// profiles with identical content for the same series are the same profile.
+type deduplicator struct {
+	seen         *cache.LRUCacheWithTTL[dedupKey, struct{}]
+	deduplicated prometheus.Counter
+}
+
+// newDeduplicator creates a deduplicator that remembers the hashes of up to
+// maxEntries ingested profiles for the duration of the window.
+func newDeduplicator(reg prometheus.Registerer, window time.Duration, maxEntries int) *deduplicator {
+	return &deduplicator{
+		seen: cache.NewLRUCacheWithTTL[dedupKey, struct{}](
+			prometheus.WrapRegistererWith(prometheus.Labels{"cache": "ingest_dedup"}, reg),
+			maxEntries,
+			window,
+			cache.CacheWithTTLOptions{RemoveExpiredOnAdd: true},
+		),
+		deduplicated: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_deduplicated_samples_total",
+			Help: "Total number of raw samples dropped at ingest because an identical profile was already ingested for the series.",
+		}),
+	}
+}
+
+// filter returns a request without the samples that were already ingested
+// and the keys of the remaining samples. The keys must be passed to commit
+// once the request was ingested successfully, so that a retry of a failed
+// write is not dropped.
+func (d *deduplicator) filter(req *profilestorepb.WriteRawRequest) (*profilestorepb.WriteRawRequest, []dedupKey) {
+	if d == nil {
+		return req, nil
+	}
+
+	var (
+		keys    []dedupKey
+		dropped int
+		inReq   = map[dedupKey]struct{}{}
+	)
+	series := make([]*profilestorepb.RawProfileSeries, 0, len(req.Series))
+	for _, s := range req.Series {
+		seriesHash := hashSeries(req.Tenant, s.Labels)
+
+		samples := make([]*profilestorepb.RawSample, 0, len(s.Samples))
+		for _, sample := range s.Samples {
+			k := dedupKey{series: seriesHash, content: xxhash.Sum64(sample.RawProfile)}
+			if _, ok := inReq[k]; ok {
+				dropped++
+				continue
+			}
+			if _, ok := d.seen.Get(k); ok {
+				dropped++
+				continue
+			}
+			inReq[k] = struct{}{}
+			keys = append(keys, k)
+			samples = append(samples, sample)
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		series = append(series, &profilestorepb.RawProfileSeries{
+			Labels:  s.Labels,
+			Samples: samples,
+		})
+	}
+
+	if dropped == 0 {
+		return req, keys
+	}
+	d.deduplicated.Add(float64(dropped))
+
+	return &profilestorepb.WriteRawRequest{
+		Tenant:     req.Tenant,
+		Series:     series,
+		Normalized: req.Normalized,
+	}, keys
+}
+
+// commit records the samples identified by the keys as ingested.
+func (d *deduplicator) commit(keys []dedupKey) {
+	if d == nil {
+		return
+	}
+	for _, k := range keys {
+		d.seen.Add(k, struct{}{})
+	}
+}
+
+// hashSeries hashes the tenant and the label set independent of the order of
+// the labels.
+func hashSeries(tenant string, ls *profilestorepb.LabelSet) uint64 {
+	labels := make([]*profilestorepb.Label, len(ls.GetLabels()))
+	copy(labels, ls.GetLabels())
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+
+	h := xxhash.New()
+	_, _ = h.WriteString(tenant)
+	for _, l := range labels {
+		_, _ = h.Write([]byte{0xff})
+		_, _ = h.WriteString(l.Name)
+		_, _ = h.Write([]byte{0xfe})
+		_, _ = h.WriteString(l.Value)
+	}
+	return h.Sum64()
+}
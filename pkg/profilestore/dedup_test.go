@@ -0,0 +1,92 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+func rawSeries(profile string, labels ...string) *profilestorepb.RawProfileSeries {
+	ls := &profilestorepb.LabelSet{}
+	for i := 0; i < len(labels); i += 2 {
+		ls.Labels = append(ls.Labels, &profilestorepb.Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return &profilestorepb.RawProfileSeries{
+		Labels:  ls,
+		Samples: []*profilestorepb.RawSample{{RawProfile: []byte(profile)}},
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	d := newDeduplicator(prometheus.NewRegistry(), time.Minute, 100)
+
+	req := &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{
+			rawSeries("a", "job", "api", "instance", "1"),
+			rawSeries("a", "job", "api", "instance", "2"),
+			rawSeries("a", "instance", "1", "job", "api"),
+		},
+	}
+
+	// The third series is the first one with reordered labels.
+	res, keys := d.filter(req)
+	require.Len(t, res.Series, 2)
+	require.Len(t, keys, 2)
+	require.Equal(t, 1.0, testutil.ToFloat64(d.deduplicated))
+
+	// Nothing was committed, eg. because ingestion failed, so a retry must
+	// not be dropped.
+	res, keys = d.filter(req)
+	require.Len(t, res.Series, 2)
+	d.commit(keys)
+
+	res, keys = d.filter(req)
+	require.Empty(t, res.Series)
+	require.Empty(t, keys)
+	require.Equal(t, 5.0, testutil.ToFloat64(d.deduplicated))
+
+	// A different profile of the same series is not a duplicate.
+	res, _ = d.filter(&profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{rawSeries("b", "job", "api", "instance", "1")},
+	})
+	require.Len(t, res.Series, 1)
+
+	// Neither is the same profile of another tenant.
+	res, _ = d.filter(&profilestorepb.WriteRawRequest{
+		Tenant: "other",
+		Series: []*profilestorepb.RawProfileSeries{rawSeries("a", "job", "api", "instance", "1")},
+	})
+	require.Len(t, res.Series, 1)
+}
+
+func TestDeduplicatorDisabled(t *testing.T) {
+	var d *deduplicator
+
+	req := &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{
+			rawSeries("a", "job", "api"),
+			rawSeries("a", "job", "api"),
+		},
+	}
+	res, keys := d.filter(req)
+	require.Same(t, req, res)
+	d.commit(keys)
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+// EmptyProfileMode determines what is stored of empty profiles, ie.
+// profiles without any non-zero sample, as scraped of idle processes.
+type EmptyProfileMode string
+
+const (
+	// EmptyProfileStore stores empty profiles like any other.
+	EmptyProfileStore EmptyProfileMode = "store"
+	// EmptyProfileSkip doesn't store empty profiles. The series they were
+	// written to are still recorded, see ProfileColumnStore.SeriesStats.
+	EmptyProfileSkip EmptyProfileMode = "skip"
+	// EmptyProfileMarker stores a single sample without a stack and with a
+	// zero value instead of the samples of empty profiles, so that it is
+	// still queryable that the target reported.
+	EmptyProfileMarker EmptyProfileMode = "marker"
+)
+
+// emptyProfiles skips or replaces empty profiles before they are appended.
+type emptyProfiles struct {
+	mode EmptyProfileMode
+
+	empty *prometheus.CounterVec
+}
+
+func newEmptyProfiles(reg prometheus.Registerer, mode EmptyProfileMode) *emptyProfiles {
+	return &emptyProfiles{
+		mode: mode,
+		empty: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_empty_profiles_total",
+			Help: "Total number of written profiles without any non-zero sample, by what was stored of them.",
+		}, []string{"action"}),
+	}
+}
+
+// apply removes the empty profiles from the request or replaces their
+// samples by a marker, and returns the number of removed profiles.
+func (e *emptyProfiles) apply(req normalizer.NormalizedWriteRawRequest) (normalizer.NormalizedWriteRawRequest, int) {
+	if e.mode == "" || e.mode == EmptyProfileStore {
+		return req, 0
+	}
+
+	skipped := 0
+	series := req.Series[:0]
+	for _, ser := range req.Series {
+		samples := ser.Samples[:0]
+		for _, sample := range ser.Samples {
+			profiles := sample[:0]
+			for _, p := range sample {
+				if !isEmptyProfile(p) {
+					profiles = append(profiles, p)
+					continue
+				}
+
+				if e.mode == EmptyProfileSkip {
+					e.empty.WithLabelValues("skipped").Inc()
+					skipped++
+					continue
+				}
+				e.empty.WithLabelValues("marked").Inc()
+				p.Samples = []*normalizer.NormalizedSample{{}}
+				profiles = append(profiles, p)
+			}
+			if len(profiles) > 0 {
+				samples = append(samples, profiles)
+			}
+		}
+		if len(samples) > 0 {
+			ser.Samples = samples
+			series = append(series, ser)
+		}
+	}
+	req.Series = series
+	return req, skipped
+}
+
+func isEmptyProfile(p *normalizer.NormalizedProfile) bool {
+	for _, s := range p.Samples {
+		if s.Value != 0 || s.DiffValue != 0 {
+			return false
+		}
+	}
+	return true
+}
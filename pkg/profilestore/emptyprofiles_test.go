@@ -0,0 +1,62 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+func TestEmptyProfiles(t *testing.T) {
+	request := func() (normalizer.NormalizedWriteRawRequest, *normalizer.NormalizedProfile) {
+		idle := &normalizer.NormalizedProfile{Samples: []*normalizer.NormalizedSample{
+			{Locations: [][]byte{[]byte("a")}},
+			{Locations: [][]byte{[]byte("b")}},
+		}}
+		busy := &normalizer.NormalizedProfile{Samples: []*normalizer.NormalizedSample{
+			{Locations: [][]byte{[]byte("a")}, Value: 1},
+		}}
+		return normalizer.NormalizedWriteRawRequest{Series: []normalizer.Series{
+			{Labels: map[string]string{"job": "idle"}, Samples: [][]*normalizer.NormalizedProfile{{idle}}},
+			{Labels: map[string]string{"job": "busy"}, Samples: [][]*normalizer.NormalizedProfile{{busy}}},
+		}}, idle
+	}
+
+	e := newEmptyProfiles(prometheus.NewRegistry(), EmptyProfileStore)
+	req, _ := request()
+	req, skipped := e.apply(req)
+	require.Len(t, req.Series, 2)
+	require.Equal(t, 0, skipped)
+
+	e = newEmptyProfiles(prometheus.NewRegistry(), EmptyProfileSkip)
+	req, _ = request()
+	req, skipped = e.apply(req)
+	require.Len(t, req.Series, 1)
+	require.Equal(t, "busy", req.Series[0].Labels["job"])
+	require.Equal(t, 1, skipped)
+	require.Equal(t, 1.0, testutil.ToFloat64(e.empty.WithLabelValues("skipped")))
+
+	e = newEmptyProfiles(prometheus.NewRegistry(), EmptyProfileMarker)
+	req, idle := request()
+	req, skipped = e.apply(req)
+	require.Len(t, req.Series, 2)
+	require.Equal(t, 0, skipped)
+	require.Equal(t, []*normalizer.NormalizedSample{{}}, idle.Samples)
+	require.Equal(t, 1.0, testutil.ToFloat64(e.empty.WithLabelValues("marked")))
+}
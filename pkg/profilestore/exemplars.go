@@ -0,0 +1,352 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/thanos-io/objstore"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+// RawProfilesPath is the HTTP path of the endpoint serving the raw profiles
+// kept as exemplars, relative to the API root.
+const RawProfilesPath = "/profiles/raw"
+
+// rawExemplars keeps the raw pprof of the first profile written for a series
+// in every window, exactly as it was received. Stored samples are merged
+// into the columnar storage, the exemplars allow downloading the original
+// artifact.
+type rawExemplars struct {
+	logger log.Logger
+	bucket objstore.Bucket
+	window time.Duration
+
+	mtx sync.Mutex
+	// last is the start of the window the last exemplar of a series was
+	// kept for, by the fingerprint of the series.
+	last   map[uint64]int64
+	nextGC int64
+
+	stored prometheus.Counter
+	failed prometheus.Counter
+}
+
+func newRawExemplars(reg prometheus.Registerer, logger log.Logger, bucket objstore.Bucket, window time.Duration) *rawExemplars {
+	return &rawExemplars{
+		logger: logger,
+		bucket: bucket,
+		window: window,
+		last:   map[uint64]int64{},
+		stored: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_raw_exemplars_stored_total",
+			Help: "Total number of raw profiles kept as exemplars in object storage.",
+		}),
+		failed: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_raw_exemplars_failed_total",
+			Help: "Total number of raw profiles that failed to be kept as exemplars.",
+		}),
+	}
+}
+
+// rawExemplar is a raw profile that is kept if any of the profiles
+// normalized from it is stored.
+type rawExemplar struct {
+	fingerprint uint64
+	timestamp   int64
+	raw         []byte
+	stored      bool
+}
+
+// rawExemplarCandidates remembers the raw profiles of the request. They have
+// to be captured before normalizing, which replaces compressed raw profiles
+// with their decompressed content.
+type rawExemplarCandidates struct {
+	raw       [][][]byte
+	profiles  map[*normalizer.NormalizedProfile]*rawExemplar
+	exemplars []*rawExemplar
+}
+
+func (e *rawExemplars) capture(req *profilestorepb.WriteRawRequest) *rawExemplarCandidates {
+	if e == nil {
+		return nil
+	}
+
+	raw := make([][][]byte, len(req.Series))
+	for i, series := range req.Series {
+		raw[i] = make([][]byte, len(series.Samples))
+		for j, sample := range series.Samples {
+			raw[i][j] = sample.RawProfile
+		}
+	}
+	return &rawExemplarCandidates{raw: raw}
+}
+
+// match associates the normalized profiles with the raw profiles they were
+// normalized from. The normalized request must not have been filtered yet,
+// its series and samples correspond to the ones of the raw request.
+func (c *rawExemplarCandidates) match(tenant string, req *profilestorepb.WriteRawRequest, normalized normalizer.NormalizedWriteRawRequest) {
+	if c == nil {
+		return
+	}
+
+	c.profiles = map[*normalizer.NormalizedProfile]*rawExemplar{}
+	for i, series := range normalized.Series {
+		fp := rawExemplarFingerprint(tenant, labels.FromMap(labelSetMap(req.Series[i].Labels)))
+		for j, sample := range series.Samples {
+			if len(sample) == 0 {
+				continue
+			}
+			ex := &rawExemplar{
+				fingerprint: fp,
+				timestamp:   sample[0].Meta.Timestamp,
+				raw:         c.raw[i][j],
+			}
+			c.exemplars = append(c.exemplars, ex)
+			for _, p := range sample {
+				c.profiles[p] = ex
+			}
+		}
+	}
+}
+
+// store keeps the raw profiles of which any normalized profile remained in
+// the request, if no exemplar was kept for their series and window yet.
+func (e *rawExemplars) store(ctx context.Context, c *rawExemplarCandidates, req normalizer.NormalizedWriteRawRequest) {
+	if e == nil || c == nil {
+		return
+	}
+
+	for _, series := range req.Series {
+		for _, sample := range series.Samples {
+			for _, p := range sample {
+				if ex, ok := c.profiles[p]; ok {
+					ex.stored = true
+				}
+			}
+		}
+	}
+
+	for _, ex := range c.exemplars {
+		if !ex.stored || !e.claim(ex.fingerprint, ex.timestamp) {
+			continue
+		}
+		if err := e.bucket.Upload(ctx, rawExemplarKey(ex.fingerprint, ex.timestamp), bytes.NewReader(ex.raw)); err != nil {
+			e.failed.Inc()
+			e.release(ex.fingerprint, ex.timestamp)
+			level.Warn(e.logger).Log("msg", "failed to keep raw profile exemplar", "timestamp", ex.timestamp, "err", err)
+			continue
+		}
+		e.stored.Inc()
+	}
+}
+
+// claim reports whether an exemplar should be kept for the series at the
+// timestamp and marks its window as taken.
+func (e *rawExemplars) claim(fp uint64, ts int64) bool {
+	window := ts - ts%e.window.Milliseconds()
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	// Windows that ended long ago are forgotten, late profiles for them
+	// may be kept as additional exemplars.
+	if window > e.nextGC {
+		for k, w := range e.last {
+			if w < window-e.window.Milliseconds() {
+				delete(e.last, k)
+			}
+		}
+		e.nextGC = window + e.window.Milliseconds()
+	}
+
+	if last, ok := e.last[fp]; ok && window <= last {
+		return false
+	}
+	e.last[fp] = window
+	return true
+}
+
+// release makes the window of the timestamp available again after keeping
+// an exemplar failed.
+func (e *rawExemplars) release(fp uint64, ts int64) {
+	window := ts - ts%e.window.Milliseconds()
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if e.last[fp] == window {
+		delete(e.last, fp)
+	}
+}
+
+// nearest returns the key and timestamp of the exemplar of the series
+// closest to the timestamp, skipping the ones of deleted profiles.
+func (e *rawExemplars) nearest(ctx context.Context, tenant string, ls labels.Labels, ts int64, deleted func(labels.Labels, time.Time) bool) (string, int64, error) {
+	dir := fmt.Sprintf("%016x", rawExemplarFingerprint(tenant, ls))
+
+	var (
+		key   string
+		found int64
+		best  int64 = -1
+	)
+	err := e.bucket.Iter(ctx, dir+"/", func(name string) error {
+		t, err := strconv.ParseInt(path.Base(name), 10, 64)
+		if err != nil {
+			return nil
+		}
+		if deleted(ls, timestamp.Time(t)) {
+			return nil
+		}
+		d := t - ts
+		if d < 0 {
+			d = -d
+		}
+		if best < 0 || d < best {
+			key, found, best = name, t, d
+		}
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return key, found, nil
+}
+
+func rawExemplarFingerprint(tenant string, ls labels.Labels) uint64 {
+	return xxhash.Sum64String(tenant + ls.String())
+}
+
+func rawExemplarKey(fp uint64, ts int64) string {
+	return fmt.Sprintf("%016x/%020d", fp, ts)
+}
+
+// WithDeletedProfiles hides the raw profiles kept as exemplars or last
+// profiles of deleted profiles. deleted reports whether the profile of the
+// series, with its name as written, at the time is deleted.
+func WithDeletedProfiles(deleted func(ls labels.Labels, t time.Time) bool) Option {
+	return func(s *ProfileColumnStore) {
+		s.deleted = deleted
+	}
+}
+
+// isDeleted reports whether the profile of the series at the time is
+// deleted.
+func (s *ProfileColumnStore) isDeleted(ls labels.Labels, t time.Time) bool {
+	return s.deleted != nil && s.deleted(ls, t)
+}
+
+// WithRawProfileExemplars keeps the raw pprof of one profile per series in
+// every window in the bucket, so the original artifacts can be downloaded.
+// A window of zero disables keeping them.
+func WithRawProfileExemplars(bucket objstore.Bucket, window time.Duration) Option {
+	return func(s *ProfileColumnStore) {
+		s.exemplarBucket = bucket
+		s.exemplarWindow = window
+	}
+}
+
+// RawProfilesHandler serves the raw profile kept as exemplar that is closest
+// to a timestamp. The required query parameters are "series", the labels of
+// the series including its name as written, eg.
+// `parca_agent_cpu{job="api"}`, and "time" (RFC3339 or milliseconds since
+// epoch). The optional "tenant" parameter selects the tenant the profile was
+// written for.
+func (s *ProfileColumnStore) RawProfilesHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		if s.exemplars == nil {
+			http.Error(w, "raw profile exemplars are disabled", http.StatusNotFound)
+			return
+		}
+
+		params := r.URL.Query()
+		ls, err := parser.ParseMetric(params.Get("series"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid series: %v", err), http.StatusBadRequest)
+			return
+		}
+		ts, err := parseExemplarTime(params.Get("time"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid time: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		key, found, err := s.exemplars.nearest(r.Context(), params.Get("tenant"), ls, ts, s.isDeleted)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if key == "" {
+			http.Error(w, "no raw profile kept for series", http.StatusNotFound)
+			return
+		}
+
+		rc, err := s.exemplars.bucket.Get(r.Context(), key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rc.Close()
+
+		raw, err := io.ReadAll(rc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		filename := "profile.pb"
+		if len(raw) >= 2 && raw[0] == 0x1f && raw[1] == 0x8b {
+			filename += ".gz"
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("X-Parca-Profile-Timestamp", strconv.FormatInt(found, 10))
+		if _, err := w.Write(raw); err != nil {
+			level.Warn(s.logger).Log("msg", "failed to send raw profile", "key", key, "err", err)
+		}
+	}
+}
+
+func parseExemplarTime(v string) (int64, error) {
+	if v == "" {
+		return 0, fmt.Errorf("missing")
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMilli(), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func notDeleted(labels.Labels, time.Time) bool { return false }
+
+func TestRawExemplars(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	e := newRawExemplars(prometheus.NewRegistry(), nil, bucket, time.Minute)
+
+	write := func(raw string, ts int64) {
+		req := &profilestorepb.WriteRawRequest{
+			Series: []*profilestorepb.RawProfileSeries{rawSeries(raw, "__name__", "cpu", "job", "api")},
+		}
+		c := e.capture(req)
+		// Normalizing replaces the raw profile, eg. when decompressing it.
+		req.Series[0].Samples[0].RawProfile = []byte("decompressed")
+		normalized := normalizer.NormalizedWriteRawRequest{
+			Series: []normalizer.Series{{
+				Labels:  map[string]string{"job": "api"},
+				Samples: [][]*normalizer.NormalizedProfile{{{Meta: profile.Meta{Timestamp: ts}}}},
+			}},
+		}
+		c.match("", req, normalized)
+		e.store(ctx, c, normalized)
+	}
+
+	write("a", 60_000)
+	write("b", 90_000)  // Same window.
+	write("c", 120_000) // Next window.
+	require.Equal(t, 2.0, testutil.ToFloat64(e.stored))
+
+	ls := labels.FromStrings("__name__", "cpu", "job", "api")
+	key, ts, err := e.nearest(ctx, "", ls, 100_000, notDeleted)
+	require.NoError(t, err)
+	require.Equal(t, int64(120_000), ts)
+
+	rc, err := bucket.Get(ctx, key)
+	require.NoError(t, err)
+	defer rc.Close()
+	raw, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, "c", string(raw))
+
+	// Other tenants' series are separate.
+	key, _, err = e.nearest(ctx, "other", ls, 100_000, notDeleted)
+	require.NoError(t, err)
+	require.Empty(t, key)
+
+	// The exemplars of deleted profiles are skipped.
+	_, ts, err = e.nearest(ctx, "", ls, 100_000, func(_ labels.Labels, t time.Time) bool {
+		return t.UnixMilli() >= 120_000
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(60_000), ts)
+}
+
+func TestRawExemplarsFiltered(t *testing.T) {
+	e := newRawExemplars(prometheus.NewRegistry(), nil, objstore.NewInMemBucket(), time.Minute)
+
+	req := &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{rawSeries("a", "__name__", "cpu")},
+	}
+	c := e.capture(req)
+	c.match("", req, normalizer.NormalizedWriteRawRequest{
+		Series: []normalizer.Series{{
+			Samples: [][]*normalizer.NormalizedProfile{{{Meta: profile.Meta{Timestamp: 1}}}},
+		}},
+	})
+
+	// The profile was dropped before ingestion, eg. by the time bounds.
+	e.store(context.Background(), c, normalizer.NormalizedWriteRawRequest{})
+	require.Equal(t, 0.0, testutil.ToFloat64(e.stored))
+}
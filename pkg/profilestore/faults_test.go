@@ -0,0 +1,109 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build faultinjection
+
+package profilestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/faults"
+	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+type countingTable struct {
+	rows int64
+}
+
+func (t *countingTable) InsertRecord(_ context.Context, r arrow.Record) (uint64, error) {
+	t.rows += r.NumRows()
+	return 0, nil
+}
+
+func TestWriteRawIngestFailure(t *testing.T) {
+	faults.Setup(t)
+	ctx := context.Background()
+
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+
+	table := &countingTable{}
+	store := NewProfileColumnStore(
+		prometheus.NewRegistry(),
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		ingester.NewIngester(log.NewNopLogger(), table),
+		schema,
+		memory.DefaultAllocator,
+		WithDeduplication(time.Minute, 100),
+	)
+
+	content, err := os.ReadFile("../query/testdata/alloc_objects.pb.gz")
+	require.NoError(t, err)
+	// Every write gets a new request like a retrying client would send, the
+	// normalization decompresses the profiles of a request in place.
+	req := func() *profilestorepb.WriteRawRequest {
+		return &profilestorepb.WriteRawRequest{
+			Series: []*profilestorepb.RawProfileSeries{{
+				Labels: &profilestorepb.LabelSet{
+					Labels: []*profilestorepb.Label{
+						{Name: "__name__", Value: "memory"},
+						{Name: "job", Value: "test"},
+					},
+				},
+				Samples: []*profilestorepb.RawSample{{RawProfile: content}},
+			}},
+		}
+	}
+
+	var expectedRows int64
+	noPartialWrites := func() error {
+		if table.rows != expectedRows {
+			return fmt.Errorf("expected %d rows to be ingested, got %d", expectedRows, table.rows)
+		}
+		return nil
+	}
+
+	faults.FailNext(faults.Ingest, 1, nil)
+	_, err = store.WriteRaw(ctx, req())
+	require.ErrorIs(t, err, faults.ErrInjected)
+	faults.CheckInvariants(t, noPartialWrites)
+
+	// The failed write must not be remembered by the deduplication, so that
+	// the retry is ingested.
+	_, err = store.WriteRaw(ctx, req())
+	require.NoError(t, err)
+	require.Positive(t, table.rows)
+	expectedRows = table.rows
+	faults.CheckInvariants(t, noPartialWrites)
+
+	// A second retry is a duplicate.
+	_, err = store.WriteRaw(ctx, req())
+	require.NoError(t, err)
+	faults.CheckInvariants(t, noPartialWrites)
+	require.Equal(t, 2, faults.Hits(faults.Ingest))
+}
@@ -42,7 +42,7 @@ func NewGRPCForwarder(conn grpc.ClientConnInterface, logger log.Logger) *GRPCFor
 func (s *GRPCForwarder) WriteRaw(ctx context.Context, req *profilestorepb.WriteRawRequest) (*profilestorepb.WriteRawResponse, error) {
 	// TODO: Batch writes to only send a request every now and then.
 	// See https://github.com/parca-dev/parca-agent/blob/main/pkg/agent/write_client.go#L28
-	resp, err := s.client.WriteRaw(ctx, req)
+	resp, err := s.client.WriteRaw(outgoingScrapeStart(ctx), req)
 	if err != nil {
 		level.Warn(s.logger).Log("msg", "failed to forward profiles", "err", err)
 	}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kafka consumes profiles from Kafka topics.
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/parca-dev/parca/pkg/profilestore"
+)
+
+// fetchErrorBackoff is how long polling pauses after fetches that returned
+// only errors, eg. while the brokers are unreachable.
+const fetchErrorBackoff = time.Second
+
+// Client is the part of a franz-go client the source consumes with.
+type Client interface {
+	PollFetches(ctx context.Context) kgo.Fetches
+	CommitRecords(ctx context.Context, rs ...*kgo.Record) error
+}
+
+// NewClient creates a client consuming the topics as a member of the
+// consumer group. Offsets are not committed automatically, the source
+// commits the offset of a record once its profile was written.
+func NewClient(brokers, topics []string, group string) (*kgo.Client, error) {
+	return kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumeTopics(topics...),
+		kgo.ConsumerGroup(group),
+		kgo.DisableAutoCommit(),
+	)
+}
+
+// Source is a profilestore.MessageSource receiving the records of Kafka
+// topics. The value of a record is a pprof profile, its headers are the
+// labels of the series, including the profile name in the __name__ header.
+//
+// The offset of a record is committed when the message is acknowledged, so
+// records are consumed at least once: the records of a partition that are
+// not committed when it is assigned to another member of the group, or when
+// Parca restarts, are consumed again.
+type Source struct {
+	logger log.Logger
+	client Client
+
+	records []*kgo.Record
+}
+
+// NewSource creates a source receiving the records polled from the client.
+func NewSource(logger log.Logger, client Client) *Source {
+	return &Source{
+		logger: logger,
+		client: client,
+	}
+}
+
+// Receive returns the next record, polling for more once all records of
+// the previous poll were received.
+func (s *Source) Receive(ctx context.Context) (profilestore.Message, error) {
+	for len(s.records) == 0 {
+		fetches := s.client.PollFetches(ctx)
+		if fetches.IsClientClosed() {
+			return profilestore.Message{}, kgo.ErrClientClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return profilestore.Message{}, err
+		}
+
+		fetches.EachError(func(topic string, partition int32, err error) {
+			level.Warn(s.logger).Log("msg", "failed to fetch from Kafka", "topic", topic, "partition", partition, "err", err)
+		})
+		s.records = fetches.Records()
+		if len(s.records) == 0 && len(fetches.Errors()) > 0 {
+			select {
+			case <-ctx.Done():
+				return profilestore.Message{}, ctx.Err()
+			case <-time.After(fetchErrorBackoff):
+			}
+		}
+	}
+
+	r := s.records[0]
+	s.records = s.records[1:]
+	return message(r, s.client), nil
+}
+
+func message(r *kgo.Record, client Client) profilestore.Message {
+	labels := make(map[string]string, len(r.Headers))
+	for _, h := range r.Headers {
+		labels[h.Key] = string(h.Value)
+	}
+
+	return profilestore.Message{
+		ID:     fmt.Sprintf("%s/%d/%d", r.Topic, r.Partition, r.Offset),
+		Labels: labels,
+		Pprof:  r.Value,
+		Ack: func(ctx context.Context) error {
+			return client.CommitRecords(ctx, r)
+		},
+	}
+}
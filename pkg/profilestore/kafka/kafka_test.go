@@ -0,0 +1,162 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/parca-dev/parca/pkg/profile"
+	"github.com/parca-dev/parca/pkg/profilestore"
+)
+
+// fakeClient returns the records of a single poll, and then blocks until
+// the context is canceled.
+type fakeClient struct {
+	records []*kgo.Record
+	events  *[]string
+	polled  bool
+}
+
+func (c *fakeClient) PollFetches(ctx context.Context) kgo.Fetches {
+	if c.polled {
+		<-ctx.Done()
+		return kgo.NewErrFetch(ctx.Err())
+	}
+	c.polled = true
+	return kgo.Fetches{{Topics: []kgo.FetchTopic{{
+		Topic:      "profiles",
+		Partitions: []kgo.FetchPartition{{Partition: 0, Records: c.records}},
+	}}}}
+}
+
+func (c *fakeClient) CommitRecords(_ context.Context, rs ...*kgo.Record) error {
+	for _, r := range rs {
+		*c.events = append(*c.events, "commit "+string(r.Key))
+	}
+	return nil
+}
+
+// flakyIngester fails the first ingest, and cancels the context once all
+// records were ingested.
+type flakyIngester struct {
+	events *[]string
+	n      int
+	cancel context.CancelFunc
+}
+
+func (i *flakyIngester) Ingest(_ context.Context, _ arrow.Record) error {
+	i.n++
+	if i.n == 1 {
+		*i.events = append(*i.events, "ingest failed")
+		return errors.New("unavailable")
+	}
+	*i.events = append(*i.events, "ingest")
+	if i.n == 3 {
+		i.cancel()
+	}
+	return nil
+}
+
+func TestSourceCommitsAfterAppend(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+
+	var events []string
+	store := profilestore.NewProfileColumnStore(
+		prometheus.NewRegistry(),
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		&flakyIngester{events: &events, cancel: cancel},
+		schema,
+		memory.DefaultAllocator,
+	)
+
+	content, err := os.ReadFile("../../query/testdata/alloc_objects.pb.gz")
+	require.NoError(t, err)
+
+	record := func(key, job string, offset int64) *kgo.Record {
+		return &kgo.Record{
+			Key:       []byte(key),
+			Value:     content,
+			Topic:     "profiles",
+			Partition: 0,
+			Offset:    offset,
+			Headers: []kgo.RecordHeader{
+				{Key: "__name__", Value: []byte("memory")},
+				{Key: "job", Value: []byte(job)},
+			},
+		}
+	}
+	client := &fakeClient{
+		events:  &events,
+		records: []*kgo.Record{record("a", "a", 0), record("b", "b", 1)},
+	}
+
+	require.NoError(t, store.Consume(ctx, "kafka", NewSource(log.NewNopLogger(), client)))
+
+	// The offset of a record is only committed once its profile was
+	// ingested, after the failed ingest was retried.
+	require.Equal(t, []string{"ingest failed", "ingest", "commit a", "ingest"}, events)
+}
+
+func TestSourceMessage(t *testing.T) {
+	var events []string
+	client := &fakeClient{
+		events: &events,
+		records: []*kgo.Record{{
+			Key:       []byte("a"),
+			Value:     []byte("pprof"),
+			Topic:     "profiles",
+			Partition: 3,
+			Offset:    42,
+			Headers: []kgo.RecordHeader{
+				{Key: "__name__", Value: []byte("parca_agent")},
+				{Key: "node", Value: []byte("a")},
+			},
+		}},
+	}
+	src := NewSource(log.NewNopLogger(), client)
+
+	ctx := context.Background()
+	m, err := src.Receive(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "profiles/3/42", m.ID)
+	require.Equal(t, map[string]string{"__name__": "parca_agent", "node": "a"}, m.Labels)
+	require.Equal(t, []byte("pprof"), m.Pprof)
+	require.Empty(t, events)
+
+	require.NoError(t, m.Ack(ctx))
+	require.Equal(t, []string{"commit a"}, events)
+
+	// Receiving blocks until the context is canceled once all records were
+	// received.
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = src.Receive(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,335 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+// LastProfilesPath is the HTTP path of the endpoint serving the most recent
+// raw profiles written for every series, relative to the API root.
+const LastProfilesPath = "/profiles/last"
+
+// lastProfilesSeriesFile is the file in the directory of a series
+// describing it.
+const lastProfilesSeriesFile = "series.json"
+
+// lastProfiles keeps the most recent raw profiles written for every series
+// in a directory on disk, independently of the columnar storage. They
+// survive restarts, so the very last heap profiles of a target that ran out
+// of memory can be retrieved. Every series is a directory named by its
+// fingerprint holding one file per profile, named by the time it was
+// received.
+type lastProfiles struct {
+	logger log.Logger
+	dir    string
+	n      int
+
+	mtx sync.Mutex
+	// known are the series their directory was created for.
+	known map[uint64]struct{}
+
+	written prometheus.Counter
+	failed  prometheus.Counter
+}
+
+// LastProfilesSeries describes a series the last profiles are kept for.
+type LastProfilesSeries struct {
+	Tenant string `json:"tenant,omitempty"`
+	Series string `json:"series"`
+	// Timestamps are the times the kept profiles were received at in
+	// milliseconds since epoch, the most recent first.
+	Timestamps []int64 `json:"timestamps,omitempty"`
+}
+
+func newLastProfiles(reg prometheus.Registerer, logger log.Logger, dir string, n int) *lastProfiles {
+	return &lastProfiles{
+		logger: logger,
+		dir:    dir,
+		n:      n,
+		known:  map[uint64]struct{}{},
+		written: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_last_profiles_written_total",
+			Help: "Total number of raw profiles written to the last profiles kept per series.",
+		}),
+		failed: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_last_profiles_failed_total",
+			Help: "Total number of raw profiles that failed to be written to the last profiles kept per series.",
+		}),
+	}
+}
+
+// record keeps the raw profiles of the request, dropping the oldest
+// profiles of their series beyond the limit. Failures are logged, they
+// never fail the write.
+func (l *lastProfiles) record(req *profilestorepb.WriteRawRequest, now time.Time) {
+	if l == nil {
+		return
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for _, series := range req.Series {
+		ls := labels.FromMap(labelSetMap(series.Labels))
+		fp := rawExemplarFingerprint(req.Tenant, ls)
+		if err := l.recordSeries(fp, req.Tenant, ls, series.Samples, now); err != nil {
+			l.failed.Add(float64(len(series.Samples)))
+			level.Warn(l.logger).Log("msg", "failed to keep last profiles", "series", ls.String(), "err", err)
+		}
+	}
+}
+
+func (l *lastProfiles) recordSeries(fp uint64, tenant string, ls labels.Labels, samples []*profilestorepb.RawSample, now time.Time) error {
+	dir := filepath.Join(l.dir, fmt.Sprintf("%016x", fp))
+	if _, ok := l.known[fp]; !ok {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		b, err := json.Marshal(LastProfilesSeries{Tenant: tenant, Series: ls.String()})
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, lastProfilesSeriesFile), b, 0o644); err != nil {
+			return err
+		}
+		l.known[fp] = struct{}{}
+	}
+
+	// Samples of the same request are received at the same time, they are
+	// kept in their order at consecutive nanoseconds.
+	for i, sample := range samples {
+		name := fmt.Sprintf("%020d", now.UnixNano()+int64(i))
+		if err := os.WriteFile(filepath.Join(dir, name), sample.RawProfile, 0o644); err != nil {
+			return err
+		}
+		l.written.Inc()
+	}
+
+	names, err := profileFiles(dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > l.n {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// profileFiles returns the names of the profile files in the directory of a
+// series, the oldest first.
+func profileFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if _, err := strconv.ParseInt(e.Name(), 10, 64); err == nil {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// list returns the series profiles are kept for, without the profiles
+// received while they are deleted.
+func (l *lastProfiles) list(deleted func(labels.Labels, time.Time) bool) ([]*LastProfilesSeries, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	entries, err := os.ReadDir(l.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*LastProfilesSeries, 0, len(entries))
+	for _, e := range entries {
+		dir := filepath.Join(l.dir, e.Name())
+		b, err := os.ReadFile(filepath.Join(dir, lastProfilesSeriesFile))
+		if err != nil {
+			continue
+		}
+		series := &LastProfilesSeries{}
+		if err := json.Unmarshal(b, series); err != nil {
+			continue
+		}
+		ls, err := parser.ParseMetric(series.Series)
+		if err != nil {
+			continue
+		}
+		names, err := profileFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		names = visibleProfileFiles(names, ls, deleted)
+		if len(names) == 0 {
+			continue
+		}
+		for i := len(names) - 1; i >= 0; i-- {
+			ns, _ := strconv.ParseInt(names[i], 10, 64)
+			series.Timestamps = append(series.Timestamps, time.Unix(0, ns).UnixMilli())
+		}
+		res = append(res, series)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Tenant != res[j].Tenant {
+			return res[i].Tenant < res[j].Tenant
+		}
+		return res[i].Series < res[j].Series
+	})
+	return res, nil
+}
+
+// get returns the nth most recent profile kept for the series and the time
+// it was received at, skipping the profiles received while they are deleted.
+func (l *lastProfiles) get(tenant string, ls labels.Labels, nth int, deleted func(labels.Labels, time.Time) bool) ([]byte, time.Time, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	dir := filepath.Join(l.dir, fmt.Sprintf("%016x", rawExemplarFingerprint(tenant, ls)))
+	names, err := profileFiles(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, time.Time{}, err
+	}
+	names = visibleProfileFiles(names, ls, deleted)
+	if nth < 0 || nth >= len(names) {
+		return nil, time.Time{}, os.ErrNotExist
+	}
+
+	name := names[len(names)-1-nth]
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	ns, _ := strconv.ParseInt(name, 10, 64)
+	return raw, time.Unix(0, ns), nil
+}
+
+// visibleProfileFiles returns the names of the profile files that were not
+// received while the profiles of the series are deleted. The time a profile
+// was received at stands in for its timestamp, which is only known after
+// parsing it.
+func visibleProfileFiles(names []string, ls labels.Labels, deleted func(labels.Labels, time.Time) bool) []string {
+	visible := names[:0:0]
+	for _, name := range names {
+		ns, _ := strconv.ParseInt(name, 10, 64)
+		if !deleted(ls, time.Unix(0, ns)) {
+			visible = append(visible, name)
+		}
+	}
+	return visible
+}
+
+// WithLastProfiles keeps the n most recent raw profiles written for every
+// series in the directory, eg. to retrieve the last heap profiles of a
+// target that ran out of memory. Zero disables keeping them.
+func WithLastProfiles(dir string, n int) Option {
+	return func(s *ProfileColumnStore) {
+		s.lastProfilesDir = dir
+		s.lastProfilesN = n
+	}
+}
+
+// LastProfilesHandler serves the most recent raw profiles kept for the
+// series. Without parameters it lists the series and the times their
+// profiles were received at. With the "series" parameter, the labels of the
+// series including its name as written, it serves the most recent profile,
+// or the nth most recent one with the "n" parameter counting from zero. The
+// optional "tenant" parameter selects the tenant the profile was written
+// for.
+func (s *ProfileColumnStore) LastProfilesHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		if s.lastProfiles == nil {
+			http.Error(w, "last profiles are disabled", http.StatusNotFound)
+			return
+		}
+
+		params := r.URL.Query()
+		if params.Get("series") == "" {
+			series, err := s.lastProfiles.list(s.isDeleted)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(struct {
+				Series []*LastProfilesSeries `json:"series"`
+			}{series}); err != nil {
+				level.Warn(s.logger).Log("msg", "failed to send last profiles", "err", err)
+			}
+			return
+		}
+
+		ls, err := parser.ParseMetric(params.Get("series"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid series: %v", err), http.StatusBadRequest)
+			return
+		}
+		nth := 0
+		if v := params.Get("n"); v != "" {
+			if nth, err = strconv.Atoi(v); err != nil {
+				http.Error(w, fmt.Sprintf("invalid n: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		raw, received, err := s.lastProfiles.get(params.Get("tenant"), ls, nth, s.isDeleted)
+		if errors.Is(err, os.ErrNotExist) {
+			http.Error(w, "no profile kept for series", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		filename := "profile.pb"
+		if len(raw) >= 2 && raw[0] == 0x1f && raw[1] == 0x8b {
+			filename += ".gz"
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("X-Parca-Profile-Timestamp", strconv.FormatInt(received.UnixMilli(), 10))
+		if _, err := w.Write(raw); err != nil {
+			level.Warn(s.logger).Log("msg", "failed to send last profile", "series", ls.String(), "err", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+func TestLastProfiles(t *testing.T) {
+	dir := t.TempDir()
+	l := newLastProfiles(prometheus.NewRegistry(), log.NewNopLogger(), dir, 2)
+
+	now := time.Unix(1000, 0)
+	for _, raw := range []string{"a", "b", "c"} {
+		l.record(&profilestorepb.WriteRawRequest{
+			Series: []*profilestorepb.RawProfileSeries{rawSeries(raw, "__name__", "memory", "job", "api")},
+		}, now)
+		now = now.Add(time.Second)
+	}
+
+	// The profiles survive a restart, only the two most recent are kept.
+	l = newLastProfiles(prometheus.NewRegistry(), log.NewNopLogger(), dir, 2)
+	ls := labels.FromStrings("__name__", "memory", "job", "api")
+	raw, received, err := l.get("", ls, 0, notDeleted)
+	require.NoError(t, err)
+	require.Equal(t, "c", string(raw))
+	require.Equal(t, int64(1_002_000), received.UnixMilli())
+
+	raw, _, err = l.get("", ls, 1, notDeleted)
+	require.NoError(t, err)
+	require.Equal(t, "b", string(raw))
+
+	_, _, err = l.get("", ls, 2, notDeleted)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, _, err = l.get("other", ls, 0, notDeleted)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	series, err := l.list(notDeleted)
+	require.NoError(t, err)
+	require.Equal(t, []*LastProfilesSeries{{
+		Series:     ls.String(),
+		Timestamps: []int64{1_002_000, 1_001_000},
+	}}, series)
+
+	// The profiles received while deleted are skipped.
+	deleted := func(_ labels.Labels, t time.Time) bool {
+		return !t.Before(time.Unix(1002, 0))
+	}
+	raw, _, err = l.get("", ls, 0, deleted)
+	require.NoError(t, err)
+	require.Equal(t, "b", string(raw))
+	series, err = l.list(deleted)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1_001_000}, series[0].Timestamps)
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// memoryRelieveRatio is the fraction of the memory limit at which the
+	// store starts freeing memory.
+	memoryRelieveRatio = 0.8
+	// memoryRejectRatio is the fraction of the memory limit at which writes
+	// are rejected.
+	memoryRejectRatio = 0.95
+
+	// memorySampleInterval is how often the memory usage is read at most.
+	memorySampleInterval = 100 * time.Millisecond
+)
+
+// memoryLimiter keeps the memory used by the process below a soft limit.
+// Approaching the limit it frees memory, eg. by compacting the active
+// storage, and close to it writes are rejected until the usage drops, so
+// that the process is not killed for running out of memory. Tests replace
+// usage and relieve.
+type memoryLimiter struct {
+	logger  log.Logger
+	limit   int64
+	usage   func() int64
+	relieve func() error
+
+	mtx       sync.Mutex
+	used      int64
+	sampledAt time.Time
+	relieving atomic.Bool
+
+	usedGauge prometheus.Gauge
+	relieved  prometheus.Counter
+	rejected  prometheus.Counter
+}
+
+func newMemoryLimiter(reg prometheus.Registerer, logger log.Logger, limit int64, relieve func() error) *memoryLimiter {
+	l := &memoryLimiter{
+		logger:  logger,
+		limit:   limit,
+		usage:   memoryUsage,
+		relieve: relieve,
+		usedGauge: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "parca_profilestore_memory_used_bytes",
+			Help: "Memory used by the process as accounted against the memory limit.",
+		}),
+		relieved: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_memory_relieved_total",
+			Help: "Total number of times memory was freed because the memory limit was approached.",
+		}),
+		rejected: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_memory_limit_rejected_total",
+			Help: "Total number of writes rejected because the memory limit was approached.",
+		}),
+	}
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "parca_profilestore_memory_limit_bytes",
+		Help: "Soft limit of the memory used by the process.",
+	}, func() float64 { return float64(limit) })
+	return l
+}
+
+// memoryUsage returns the memory mapped by the Go runtime that was not
+// released to the operating system, which is what the runtime's own memory
+// limit accounts for.
+func memoryUsage() int64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	return int64(samples[0].Value.Uint64() - samples[1].Value.Uint64())
+}
+
+// admit returns a ResourceExhausted error if the memory used is close to the
+// limit. Approaching the limit, it starts freeing memory in the background.
+func (l *memoryLimiter) admit(now time.Time) error {
+	if l == nil {
+		return nil
+	}
+
+	used := l.sample(now)
+	if used >= int64(float64(l.limit)*memoryRelieveRatio) && l.relieving.CompareAndSwap(false, true) {
+		go l.free(used)
+	}
+	if used >= int64(float64(l.limit)*memoryRejectRatio) {
+		l.rejected.Inc()
+		return status.Errorf(codes.ResourceExhausted, "memory limit approached: %d of %d bytes used, retry later", used, l.limit)
+	}
+	return nil
+}
+
+func (l *memoryLimiter) sample(now time.Time) int64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if now.Sub(l.sampledAt) >= memorySampleInterval {
+		l.used = l.usage()
+		l.sampledAt = now
+		l.usedGauge.Set(float64(l.used))
+	}
+	return l.used
+}
+
+func (l *memoryLimiter) free(used int64) {
+	defer l.relieving.Store(false)
+
+	level.Warn(l.logger).Log("msg", "approaching memory limit, freeing memory", "used", used, "limit", l.limit)
+	l.relieved.Inc()
+	if l.relieve != nil {
+		if err := l.relieve(); err != nil {
+			level.Error(l.logger).Log("msg", "failed to free memory", "err", err)
+		}
+	}
+	// Return the memory to the operating system right away rather than
+	// waiting for the runtime to scavenge it.
+	debug.FreeOSMemory()
+
+	// Force the next write to read the memory usage after it was freed.
+	l.mtx.Lock()
+	l.sampledAt = time.Time{}
+	l.mtx.Unlock()
+}
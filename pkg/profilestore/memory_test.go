@@ -0,0 +1,64 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	var used int64
+	relieved := make(chan struct{}, 1)
+	l := newMemoryLimiter(prometheus.NewRegistry(), log.NewNopLogger(), 1000, func() error {
+		used = 500
+		relieved <- struct{}{}
+		return nil
+	})
+	l.usage = func() int64 { return used }
+
+	now := time.Now()
+	used = 100
+	require.NoError(t, l.admit(now))
+	require.Equal(t, 100.0, testutil.ToFloat64(l.usedGauge))
+
+	// The usage is only read once per sample interval.
+	used = 990
+	require.NoError(t, l.admit(now.Add(memorySampleInterval/2)))
+
+	// Close to the limit, writes are rejected and memory is freed.
+	err := l.admit(now.Add(memorySampleInterval))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, 1.0, testutil.ToFloat64(l.rejected))
+
+	<-relieved
+	require.Eventually(t, func() bool { return !l.relieving.Load() }, time.Second, time.Millisecond)
+	require.Equal(t, 1.0, testutil.ToFloat64(l.relieved))
+
+	// Freeing memory forces the usage to be read again.
+	require.NoError(t, l.admit(now.Add(memorySampleInterval)))
+	require.Equal(t, 500.0, testutil.ToFloat64(l.usedGauge))
+}
+
+func TestMemoryLimiterDisabled(t *testing.T) {
+	var l *memoryLimiter
+	require.NoError(t, l.admit(time.Now()))
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/pqarrow"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// ProfileMetadataKeyPrefix prefixes the gRPC metadata keys of a WriteRaw
+// request that are stored as metadata of its profiles, eg.
+// "parca-profile-meta-agent_version".
+const ProfileMetadataKeyPrefix = "parca-profile-meta-"
+
+// Well-known keys of profile metadata.
+const (
+	ProfileMetadataAgentVersion      = "agent_version"
+	ProfileMetadataDurationRequested = "duration_requested"
+	ProfileMetadataDurationActual    = "duration_actual"
+	ProfileMetadataTruncated         = "truncated"
+)
+
+type profileMetadataKey struct{}
+
+// ContextWithProfileMetadata returns a context that carries metadata to
+// store along with the profiles written with it, in addition to metadata
+// already carried by the context.
+func ContextWithProfileMetadata(ctx context.Context, md map[string]string) context.Context {
+	merged := maps.Clone(profileMetadataFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(md))
+	}
+	maps.Copy(merged, md)
+	return context.WithValue(ctx, profileMetadataKey{}, merged)
+}
+
+// profileMetadataFromContext returns the profile metadata set by
+// ContextWithProfileMetadata for in-process writes and sent as gRPC
+// metadata.
+func profileMetadataFromContext(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(profileMetadataKey{}).(map[string]string)
+
+	incoming, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return md
+	}
+	res := maps.Clone(md)
+	for k, v := range incoming {
+		key, ok := strings.CutPrefix(k, ProfileMetadataKeyPrefix)
+		if !ok || key == "" || len(v) == 0 {
+			continue
+		}
+		if res == nil {
+			res = map[string]string{}
+		}
+		// Metadata set in-process takes precedence.
+		if _, set := res[key]; !set {
+			res[key] = v[0]
+		}
+	}
+	return res
+}
+
+// WithProfileMetadata stores the metadata written along with profiles using
+// the ingester, which has to write to a table of the metadata schema, see
+// profile.MetadataSchemaDefinition.
+func WithProfileMetadata(ing ingester.Ingester, schema *dynparquet.Schema) Option {
+	return func(s *ProfileColumnStore) {
+		s.metadataIngester = ing
+		s.metadataSchema = schema
+	}
+}
+
+// writeProfileMetadata stores the metadata of the context for every raw
+// profile of the request.
+func (s *ProfileColumnStore) writeProfileMetadata(ctx context.Context, req normalizer.NormalizedWriteRawRequest) error {
+	if s.metadataIngester == nil {
+		return nil
+	}
+	md := profileMetadataFromContext(ctx)
+	if len(md) == 0 {
+		return nil
+	}
+
+	r, err := profileMetadataRecord(ctx, s.mem, s.metadataSchema, req, md)
+	if err != nil || r == nil {
+		return err
+	}
+	defer r.Release()
+
+	return s.metadataIngester.Ingest(ctx, r)
+}
+
+type profileMetadataRow struct {
+	labels    map[string]string
+	name      string
+	timestamp int64
+}
+
+// profileMetadataRecord returns a record with a row of the metadata for
+// every raw profile of the request, sorted by name and timestamp.
+func profileMetadataRecord(
+	ctx context.Context,
+	mem memory.Allocator,
+	schema *dynparquet.Schema,
+	req normalizer.NormalizedWriteRawRequest,
+	md map[string]string,
+) (arrow.Record, error) {
+	var rows []profileMetadataRow
+	for _, series := range req.Series {
+		for _, sample := range series.Samples {
+			if len(sample) == 0 {
+				continue
+			}
+			rows = append(rows, profileMetadataRow{
+				labels:    series.Labels,
+				name:      sample[0].Meta.Name,
+				timestamp: sample[0].Meta.Timestamp,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].name != rows[j].name {
+			return rows[i].name < rows[j].name
+		}
+		return rows[i].timestamp < rows[j].timestamp
+	})
+
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ps, err := schema.GetDynamicParquetSchema(map[string][]string{
+		profile.ColumnLabels:   req.AllLabelNames,
+		profile.ColumnMetadata: keys,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer schema.PutPooledParquetSchema(ps)
+
+	arrowSchema, err := pqarrow.ParquetSchemaToArrowSchema(ctx, ps.Schema, schema, logicalplan.IterOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	b := array.NewRecordBuilder(mem, arrowSchema)
+	defer b.Release()
+	b.Reserve(len(rows))
+
+	field := func(name string) array.Builder {
+		return b.Field(b.Schema().FieldIndices(name)[0])
+	}
+	for _, name := range req.AllLabelNames {
+		cBuilder := field(profile.ColumnLabels + "." + name).(*array.BinaryDictionaryBuilder)
+		for _, row := range rows {
+			val, ok := row.labels[name]
+			if !ok {
+				cBuilder.AppendNull()
+				continue
+			}
+			if err := cBuilder.AppendString(val); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, key := range keys {
+		cBuilder := field(profile.ColumnMetadata + "." + key).(*array.BinaryDictionaryBuilder)
+		for range rows {
+			if err := cBuilder.AppendString(md[key]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	nameBuilder := field(profile.ColumnName).(*array.BinaryDictionaryBuilder)
+	tsBuilder := field(profile.ColumnTimestamp).(*array.Int64Builder)
+	for _, row := range rows {
+		if err := nameBuilder.AppendString(row.name); err != nil {
+			return nil, err
+		}
+		tsBuilder.Append(row.timestamp)
+	}
+
+	return b.NewRecord(), nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func TestProfileMetadataFromContext(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		ProfileMetadataKeyPrefix+ProfileMetadataAgentVersion, "v1",
+		ProfileMetadataKeyPrefix+ProfileMetadataTruncated, "false",
+		"other", "x",
+	))
+	ctx = ContextWithProfileMetadata(ctx, map[string]string{ProfileMetadataTruncated: "true"})
+
+	require.Equal(t, map[string]string{
+		ProfileMetadataAgentVersion: "v1",
+		ProfileMetadataTruncated:    "true",
+	}, profileMetadataFromContext(ctx))
+	require.Nil(t, profileMetadataFromContext(context.Background()))
+}
+
+func TestProfileMetadataRecord(t *testing.T) {
+	schema, err := dynparquet.SchemaFromDefinition(profile.MetadataSchemaDefinition())
+	require.NoError(t, err)
+
+	sample := func(name string, ts int64) []*normalizer.NormalizedProfile {
+		return []*normalizer.NormalizedProfile{{Meta: profile.Meta{Name: name, Timestamp: ts}}}
+	}
+	req := normalizer.NormalizedWriteRawRequest{
+		Series: []normalizer.Series{
+			{Labels: map[string]string{"job": "a"}, Samples: [][]*normalizer.NormalizedProfile{sample("memory", 1), sample("cpu", 2)}},
+			{Labels: map[string]string{"instance": "b"}, Samples: [][]*normalizer.NormalizedProfile{sample("cpu", 1)}},
+		},
+		AllLabelNames: []string{"instance", "job"},
+	}
+
+	r, err := profileMetadataRecord(context.Background(), memory.NewGoAllocator(), schema, req, map[string]string{
+		ProfileMetadataAgentVersion: "v1",
+	})
+	require.NoError(t, err)
+	defer r.Release()
+
+	require.Equal(t, int64(3), r.NumRows())
+	col := func(name string) *array.Dictionary {
+		return r.Column(r.Schema().FieldIndices(name)[0]).(*array.Dictionary)
+	}
+	value := func(arr *array.Dictionary, i int) string {
+		return string(arr.Dictionary().(*array.Binary).Value(arr.GetValueIndex(i)))
+	}
+
+	// Rows are sorted by name and timestamp.
+	names := col(profile.ColumnName)
+	require.Equal(t, []string{"cpu", "cpu", "memory"}, []string{value(names, 0), value(names, 1), value(names, 2)})
+	instances := col(profile.ColumnLabelsPrefix + "instance")
+	require.Equal(t, "b", value(instances, 0))
+	require.True(t, instances.IsNull(1))
+	versions := col(profile.ColumnMetadataPrefix + ProfileMetadataAgentVersion)
+	require.Equal(t, "v1", value(versions, 2))
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+// IngestMiddleware inspects, mutates or rejects written profiles before they
+// are appended to the storage, eg. to enrich or scrub their labels.
+type IngestMiddleware interface {
+	// Ingest is called with the normalized profiles of a write. It may
+	// mutate the series, their labels and their profiles. Returning an error
+	// rejects the write, errors without a gRPC status are returned as
+	// InvalidArgument. Arrow and OTLP writes have no tenant.
+	Ingest(ctx context.Context, tenant string, req *normalizer.NormalizedWriteRawRequest) error
+}
+
+// IngestMiddlewareFunc is a function implementing IngestMiddleware.
+type IngestMiddlewareFunc func(ctx context.Context, tenant string, req *normalizer.NormalizedWriteRawRequest) error
+
+func (f IngestMiddlewareFunc) Ingest(ctx context.Context, tenant string, req *normalizer.NormalizedWriteRawRequest) error {
+	return f(ctx, tenant, req)
+}
+
+var (
+	ingestMiddlewaresMtx sync.Mutex
+	ingestMiddlewares    = map[string]IngestMiddleware{}
+)
+
+// RegisterIngestMiddleware makes an ingest middleware available by name, so
+// that it can be enabled by configuration. It is meant to be called from the
+// init function of an extension package linked into a custom build of Parca,
+// and panics if the name is already registered.
+func RegisterIngestMiddleware(name string, mw IngestMiddleware) {
+	ingestMiddlewaresMtx.Lock()
+	defer ingestMiddlewaresMtx.Unlock()
+
+	if _, ok := ingestMiddlewares[name]; ok {
+		panic(fmt.Sprintf("ingest middleware %q registered twice", name))
+	}
+	ingestMiddlewares[name] = mw
+}
+
+// IngestMiddlewares returns the registered ingest middlewares with the given
+// names, in order.
+func IngestMiddlewares(names ...string) ([]IngestMiddleware, error) {
+	ingestMiddlewaresMtx.Lock()
+	defer ingestMiddlewaresMtx.Unlock()
+
+	res := make([]IngestMiddleware, 0, len(names))
+	for _, name := range names {
+		mw, ok := ingestMiddlewares[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown ingest middleware %q", name)
+		}
+		res = append(res, mw)
+	}
+	return res, nil
+}
+
+// WithIngestMiddlewares runs the middlewares in order on the normalized
+// profiles of every write, after they were validated and before they are
+// appended.
+func WithIngestMiddlewares(mws ...IngestMiddleware) Option {
+	return func(s *ProfileColumnStore) {
+		s.middlewares = append(s.middlewares, mws...)
+	}
+}
+
+// runMiddlewares runs the ingest middlewares on the request. Label names
+// added by them are added to the label names of the request.
+func (s *ProfileColumnStore) runMiddlewares(ctx context.Context, tenant string, req *normalizer.NormalizedWriteRawRequest) error {
+	if len(s.middlewares) == 0 {
+		return nil
+	}
+
+	for _, mw := range s.middlewares {
+		if err := mw.Ingest(ctx, tenant, req); err != nil {
+			if _, ok := status.FromError(err); ok {
+				return err
+			}
+			return status.Errorf(codes.InvalidArgument, "rejected by ingest middleware: %v", err)
+		}
+	}
+
+	for _, series := range req.Series {
+		for name := range series.Labels {
+			if !slices.Contains(req.AllLabelNames, name) {
+				req.AllLabelNames = append(req.AllLabelNames, name)
+			}
+		}
+	}
+	sort.Strings(req.AllLabelNames)
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+func TestRunMiddlewares(t *testing.T) {
+	ctx := context.Background()
+	newRequest := func() *normalizer.NormalizedWriteRawRequest {
+		return &normalizer.NormalizedWriteRawRequest{
+			Series: []normalizer.Series{
+				{Labels: map[string]string{"job": "a", "secret": "x"}},
+				{Labels: map[string]string{"job": "b"}},
+			},
+			AllLabelNames: []string{"job", "secret"},
+		}
+	}
+
+	s := &ProfileColumnStore{middlewares: []IngestMiddleware{
+		IngestMiddlewareFunc(func(_ context.Context, tenant string, req *normalizer.NormalizedWriteRawRequest) error {
+			for _, series := range req.Series {
+				delete(series.Labels, "secret")
+				series.Labels["tenant"] = tenant
+			}
+			return nil
+		}),
+	}}
+	req := newRequest()
+	require.NoError(t, s.runMiddlewares(ctx, "t1", req))
+	require.Equal(t, map[string]string{"job": "a", "tenant": "t1"}, req.Series[0].Labels)
+	require.Equal(t, []string{"job", "secret", "tenant"}, req.AllLabelNames)
+
+	// Errors reject the write, as invalid unless they carry a status.
+	s.middlewares = append(s.middlewares, IngestMiddlewareFunc(func(_ context.Context, _ string, req *normalizer.NormalizedWriteRawRequest) error {
+		if req.Series[0].Labels["job"] == "quota" {
+			return status.Error(codes.ResourceExhausted, "quota exceeded")
+		}
+		return errors.New("denied")
+	}))
+	err := s.runMiddlewares(ctx, "t1", newRequest())
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	req = newRequest()
+	req.Series[0].Labels["job"] = "quota"
+	err = s.runMiddlewares(ctx, "t1", req)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestIngestMiddlewareRegistry(t *testing.T) {
+	mw := IngestMiddlewareFunc(func(context.Context, string, *normalizer.NormalizedWriteRawRequest) error {
+		return nil
+	})
+	RegisterIngestMiddleware("test-registry", mw)
+	require.Panics(t, func() { RegisterIngestMiddleware("test-registry", mw) })
+
+	mws, err := IngestMiddlewares("test-registry")
+	require.NoError(t, err)
+	require.Len(t, mws, 1)
+
+	_, err = IngestMiddlewares("test-registry", "unknown")
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nats consumes profiles from NATS JetStream streams.
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/parca-dev/parca/pkg/profilestore"
+)
+
+// Messages is the part of a JetStream message iterator the source consumes
+// with.
+type Messages interface {
+	Next() (jetstream.Msg, error)
+	Stop()
+}
+
+// Consumer is a durable JetStream consumer of a stream.
+type Consumer struct {
+	conn     *natsgo.Conn
+	messages jetstream.MessagesContext
+}
+
+// NewConsumer connects to the NATS server and creates or updates the durable
+// consumer of the stream. Messages have to be acknowledged explicitly, the
+// source acknowledges a message once its profile was written.
+func NewConsumer(ctx context.Context, url, stream, name string) (*Consumer, error) {
+	conn, err := natsgo.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("create JetStream context: %w", err)
+	}
+
+	cons, err := js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:   name,
+		AckPolicy: jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("create consumer %q of stream %q: %w", name, stream, err)
+	}
+
+	messages, err := cons.Messages()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("consume messages: %w", err)
+	}
+
+	return &Consumer{
+		conn:     conn,
+		messages: messages,
+	}, nil
+}
+
+// Messages returns the iterator of the messages of the consumer.
+func (c *Consumer) Messages() Messages {
+	return c.messages
+}
+
+// Close stops consuming and closes the connection.
+func (c *Consumer) Close() {
+	c.messages.Stop()
+	c.conn.Close()
+}
+
+// Source is a profilestore.MessageSource receiving the messages of a
+// JetStream consumer. The data of a message is a pprof profile, its headers
+// are the labels of the series, including the profile name in the __name__
+// header. Headers of NATS itself, prefixed with "Nats-", are not labels.
+//
+// Messages are acknowledged once their profile was written, so they are
+// consumed at least once: messages not acknowledged within the ack wait of
+// the consumer, eg. because Parca restarted, are redelivered. Redeliveries
+// are identified by the Nats-Msg-Id header set by the publisher, or the
+// stream sequence of the message if it has none.
+type Source struct {
+	messages Messages
+}
+
+// NewSource creates a source receiving the messages of the iterator.
+func NewSource(messages Messages) *Source {
+	return &Source{messages: messages}
+}
+
+// Receive returns the next message. The iterator is stopped if the context
+// is canceled while waiting for it.
+func (s *Source) Receive(ctx context.Context) (profilestore.Message, error) {
+	stop := context.AfterFunc(ctx, s.messages.Stop)
+	defer stop()
+
+	msg, err := s.messages.Next()
+	if ctx.Err() != nil {
+		return profilestore.Message{}, ctx.Err()
+	}
+	if err != nil {
+		return profilestore.Message{}, err
+	}
+
+	id, err := messageID(msg)
+	if err != nil {
+		return profilestore.Message{}, err
+	}
+
+	labels := map[string]string{}
+	for name, values := range msg.Headers() {
+		if strings.HasPrefix(name, "Nats-") || len(values) == 0 {
+			continue
+		}
+		labels[name] = values[0]
+	}
+
+	return profilestore.Message{
+		ID:     id,
+		Labels: labels,
+		Pprof:  msg.Data(),
+		Ack:    msg.DoubleAck,
+	}, nil
+}
+
+func messageID(msg jetstream.Msg) (string, error) {
+	if id := msg.Headers().Get(jetstream.MsgIDHeader); id != "" {
+		return id, nil
+	}
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		return "", fmt.Errorf("read message metadata: %w", err)
+	}
+	return fmt.Sprintf("%s/%d", meta.Stream, meta.Sequence.Stream), nil
+}
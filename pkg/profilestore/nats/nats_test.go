@@ -0,0 +1,176 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/parca-dev/parca/pkg/profile"
+	"github.com/parca-dev/parca/pkg/profilestore"
+)
+
+type fakeMsg struct {
+	jetstream.Msg
+
+	data    []byte
+	headers natsgo.Header
+	seq     uint64
+	events  *[]string
+	onAck   func()
+}
+
+func (m *fakeMsg) Data() []byte           { return m.data }
+func (m *fakeMsg) Headers() natsgo.Header { return m.headers }
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{Stream: "profiles", Sequence: jetstream.SequencePair{Stream: m.seq}}, nil
+}
+
+func (m *fakeMsg) DoubleAck(context.Context) error {
+	*m.events = append(*m.events, "ack "+m.headers.Get("job"))
+	if m.onAck != nil {
+		m.onAck()
+	}
+	return nil
+}
+
+// fakeMessages returns its messages, and then blocks until it is stopped.
+type fakeMessages struct {
+	msgs    []jetstream.Msg
+	stopped chan struct{}
+}
+
+func (m *fakeMessages) Next() (jetstream.Msg, error) {
+	if len(m.msgs) == 0 {
+		<-m.stopped
+		return nil, jetstream.ErrMsgIteratorClosed
+	}
+	msg := m.msgs[0]
+	m.msgs = m.msgs[1:]
+	return msg, nil
+}
+
+func (m *fakeMessages) Stop() {
+	close(m.stopped)
+}
+
+type recordingIngester struct {
+	events *[]string
+}
+
+func (i *recordingIngester) Ingest(_ context.Context, _ arrow.Record) error {
+	*i.events = append(*i.events, "ingest")
+	return nil
+}
+
+func TestSourceAcksAfterWrite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+
+	var events []string
+	store := profilestore.NewProfileColumnStore(
+		prometheus.NewRegistry(),
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		&recordingIngester{events: &events},
+		schema,
+		memory.DefaultAllocator,
+	)
+
+	content, err := os.ReadFile("../../query/testdata/alloc_objects.pb.gz")
+	require.NoError(t, err)
+
+	// Consuming stops once all messages were acknowledged.
+	acks := 0
+	onAck := func() {
+		acks++
+		if acks == 4 {
+			cancel()
+		}
+	}
+	msg := func(job, id string, seq uint64) *fakeMsg {
+		headers := natsgo.Header{}
+		headers.Set("__name__", "memory")
+		headers.Set("job", job)
+		if id != "" {
+			headers.Set(jetstream.MsgIDHeader, id)
+		}
+		return &fakeMsg{data: content, headers: headers, seq: seq, events: &events, onAck: onAck}
+	}
+	messages := &fakeMessages{
+		stopped: make(chan struct{}),
+		msgs: []jetstream.Msg{
+			msg("a", "", 1),
+			// Redelivered, eg. after its ack was lost.
+			msg("a", "", 1),
+			msg("b", "profile-b", 2),
+			// Published twice with the same message ID.
+			msg("b", "profile-b", 3),
+		},
+	}
+	require.NoError(t, store.Consume(ctx, "nats", NewSource(messages)))
+
+	// Every message is acknowledged after it was written, redeliveries are
+	// acknowledged without being written again.
+	require.Equal(t, []string{"ingest", "ack a", "ack a", "ingest", "ack b", "ack b"}, events)
+}
+
+func TestSourceMessage(t *testing.T) {
+	headers := natsgo.Header{}
+	headers.Set("__name__", "parca_agent")
+	headers.Set("job", "a")
+	headers.Set("Nats-Expected-Stream", "profiles")
+
+	var events []string
+	src := NewSource(&fakeMessages{
+		stopped: make(chan struct{}),
+		msgs: []jetstream.Msg{&fakeMsg{
+			data:    []byte("pprof"),
+			headers: headers,
+			seq:     42,
+			events:  &events,
+		}},
+	})
+
+	ctx := context.Background()
+	m, err := src.Receive(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "profiles/42", m.ID)
+	require.Equal(t, map[string]string{"__name__": "parca_agent", "job": "a"}, m.Labels)
+	require.Equal(t, []byte("pprof"), m.Pprof)
+	require.Empty(t, events)
+
+	require.NoError(t, m.Ack(ctx))
+	require.Equal(t, []string{"ack a"}, events)
+
+	// Receiving blocks until the context is canceled once all messages were
+	// received.
+	ctx, cancel := context.WithCancel(ctx)
+	go cancel()
+	_, err = src.Receive(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
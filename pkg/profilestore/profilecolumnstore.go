@@ -16,17 +16,25 @@ package profilestore
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/ipc"
 	"github.com/apache/arrow/go/v16/arrow/memory"
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/gogo/status"
 	"github.com/polarsignals/frostdb/dynparquet"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/thanos-io/objstore"
 	"go.opentelemetry.io/otel/trace"
 	otelgrpcprofilingpb "go.opentelemetry.io/proto/otlp/collector/profiles/v1experimental"
 	"google.golang.org/grpc/codes"
@@ -49,6 +57,7 @@ type agent struct {
 type ProfileColumnStore struct {
 	profilestorepb.UnimplementedProfileStoreServiceServer
 	profilestorepb.UnimplementedAgentsServiceServer
+	profilestorepb.UnimplementedSeriesStatsServiceServer
 
 	otelgrpcprofilingpb.UnimplementedProfilesServiceServer
 
@@ -65,6 +74,169 @@ type ProfileColumnStore struct {
 	schema *dynparquet.Schema
 
 	converterMetrics *normalizer.Metrics
+
+	dedupWindow     time.Duration
+	dedupMaxEntries int
+	dedup           *deduplicator
+
+	timestampPolicy TimestampPolicy
+	maxClockSkew    time.Duration
+	timestamps      *timestamper
+
+	maxFuture time.Duration
+	maxPast   time.Duration
+	bounds    *timeBounds
+
+	series              *seriesTracker
+	seriesCreationRate  float64
+	seriesCreationBurst int
+	seriesCreationQueue int
+	seriesCreation      *seriesCreationLimiter
+	maxSeries           int
+	seriesLimit         *seriesLimiter
+
+	seriesErrors *seriesErrors
+	seriesStats  *seriesStats
+
+	memoryLimit   int64
+	memoryRelieve func() error
+	memory        *memoryLimiter
+
+	sizeLimit ProfileSizeLimit
+	sizes     *profileSizeLimiter
+
+	maxStacks int
+	stacks    *stackLimiter
+
+	samplingRules []SamplingRule
+	sampling      *sampler
+
+	emptyProfileMode EmptyProfileMode
+	emptyProfiles    *emptyProfiles
+
+	middlewares []IngestMiddleware
+
+	metadataIngester ingester.Ingester
+	metadataSchema   *dynparquet.Schema
+
+	exemplarBucket objstore.Bucket
+	exemplarWindow time.Duration
+	exemplars      *rawExemplars
+
+	lastProfilesDir string
+	lastProfilesN   int
+	lastProfiles    *lastProfiles
+
+	// deleted reports whether the profile of a series at a time is deleted,
+	// the raw profiles kept of deleted profiles are not served.
+	deleted func(labels.Labels, time.Time) bool
+
+	stages  *writeStages
+	consume *consumeMetrics
+}
+
+// defaultSeriesTTL is how long a series is remembered after it was last
+// written to.
+const defaultSeriesTTL = time.Hour
+
+type Option func(*ProfileColumnStore)
+
+// WithDeduplication drops raw profiles that are identical to a profile that
+// was ingested for the same series within the window. At most maxEntries
+// profiles are remembered.
+func WithDeduplication(window time.Duration, maxEntries int) Option {
+	return func(s *ProfileColumnStore) {
+		s.dedupWindow = window
+		s.dedupMaxEntries = maxEntries
+	}
+}
+
+// WithTimestampPolicy sets which timestamp stored profiles get. Timestamps
+// more than maxClockSkew ahead of the server's time are clamped, unless it is
+// zero.
+func WithTimestampPolicy(policy TimestampPolicy, maxClockSkew time.Duration) Option {
+	return func(s *ProfileColumnStore) {
+		s.timestampPolicy = policy
+		s.maxClockSkew = maxClockSkew
+	}
+}
+
+// WithSeriesCreationLimit limits the rate at which new series are created
+// to perSecond with the given burst. At most maxQueue series creations wait
+// for the limit, writes that would exceed it are rejected. A rate of zero
+// disables the limit.
+func WithSeriesCreationLimit(perSecond float64, burst, maxQueue int) Option {
+	return func(s *ProfileColumnStore) {
+		s.seriesCreationRate = perSecond
+		s.seriesCreationBurst = burst
+		s.seriesCreationQueue = maxQueue
+	}
+}
+
+// WithSeriesLimit limits the number of active series, the series written
+// to within the last hour. Writes creating new series beyond the limit are
+// rejected with ErrSeriesLimit, the series of the write that are active are
+// still written. Zero disables the limit.
+func WithSeriesLimit(maxSeries int) Option {
+	return func(s *ProfileColumnStore) {
+		s.maxSeries = maxSeries
+	}
+}
+
+// WithTimeBounds rejects profiles with timestamps more than maxFuture ahead
+// of or more than maxPast behind the server's time. Zero disables the
+// respective bound.
+func WithTimeBounds(maxFuture, maxPast time.Duration) Option {
+	return func(s *ProfileColumnStore) {
+		s.maxFuture = maxFuture
+		s.maxPast = maxPast
+	}
+}
+
+// WithMemoryLimit rejects writes when the memory used by the process
+// approaches the limit in bytes. Before that relieve is called to free
+// memory, eg. by compacting the active storage. Zero disables the limit.
+func WithMemoryLimit(limit int64, relieve func() error) Option {
+	return func(s *ProfileColumnStore) {
+		s.memoryLimit = limit
+		s.memoryRelieve = relieve
+	}
+}
+
+// WithProfileSizeLimit limits the uncompressed size of written pprof
+// profiles. Profiles exceeding it are rejected or trimmed depending on the
+// mode of the limit. Writes can override the limit, see
+// ContextWithProfileSizeLimit.
+func WithProfileSizeLimit(limit ProfileSizeLimit) Option {
+	return func(s *ProfileColumnStore) {
+		s.sizeLimit = limit
+	}
+}
+
+// WithStackLimit limits the number of distinct stacks stored per profile.
+// The least significant samples of profiles exceeding it are aggregated
+// into a single sample with the "other" stack. Zero disables the limit.
+func WithStackLimit(maxStacks int) Option {
+	return func(s *ProfileColumnStore) {
+		s.maxStacks = maxStacks
+	}
+}
+
+// WithSampling keeps only a fraction of the profiles of the series matching
+// the rules. The first matching rule applies to a series. The rules can be
+// replaced later, see SetSamplingRules.
+func WithSampling(rules ...SamplingRule) Option {
+	return func(s *ProfileColumnStore) {
+		s.samplingRules = rules
+	}
+}
+
+// WithEmptyProfiles determines what is stored of profiles without any
+// non-zero sample. They are stored like any other by default.
+func WithEmptyProfiles(mode EmptyProfileMode) Option {
+	return func(s *ProfileColumnStore) {
+		s.emptyProfileMode = mode
+	}
 }
 
 var _ profilestorepb.ProfileStoreServiceServer = &ProfileColumnStore{}
@@ -76,9 +248,10 @@ func NewProfileColumnStore(
 	ingester ingester.Ingester,
 	schema *dynparquet.Schema,
 	mem memory.Allocator,
+	opts ...Option,
 ) *ProfileColumnStore {
 	normalizerMetrics := normalizer.NewMetrics(reg)
-	return &ProfileColumnStore{
+	s := &ProfileColumnStore{
 		logger:   logger,
 		tracer:   tracer,
 		ingester: ingester,
@@ -87,29 +260,189 @@ func NewProfileColumnStore(
 		agents:   make(map[string]agent),
 
 		converterMetrics: normalizerMetrics,
+
+		timestampPolicy: TimestampPolicyAgent,
+
+		series:  newSeriesTracker(defaultSeriesTTL),
+		stages:  newWriteStages(reg),
+		consume: newConsumeMetrics(reg),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.dedupWindow > 0 {
+		s.dedup = newDeduplicator(reg, s.dedupWindow, s.dedupMaxEntries)
 	}
+	s.timestamps = newTimestamper(reg, s.timestampPolicy, s.maxClockSkew)
+	s.bounds = newTimeBounds(reg, s.maxFuture, s.maxPast)
+	s.seriesErrors = newSeriesErrors(reg, defaultSeriesTTL)
+	s.seriesStats = newSeriesStats(defaultSeriesTTL)
+	s.sizes = newProfileSizeLimiter(reg, s.sizeLimit)
+	s.stacks = newStackLimiter(reg, s.maxStacks)
+	s.sampling = newSampler(reg, s.samplingRules)
+	s.emptyProfiles = newEmptyProfiles(reg, s.emptyProfileMode)
+	if s.maxSeries > 0 {
+		s.seriesLimit = newSeriesLimiter(reg, s.series, s.maxSeries)
+	}
+	if s.seriesCreationRate > 0 {
+		s.seriesCreation = newSeriesCreationLimiter(reg, s.series, s.seriesCreationRate, s.seriesCreationBurst, s.seriesCreationQueue)
+	}
+	if s.memoryLimit > 0 {
+		s.memory = newMemoryLimiter(reg, logger, s.memoryLimit, s.memoryRelieve)
+	}
+	if s.exemplarBucket != nil && s.exemplarWindow > 0 {
+		s.exemplars = newRawExemplars(reg, logger, s.exemplarBucket, s.exemplarWindow)
+	}
+	if s.lastProfilesDir != "" && s.lastProfilesN > 0 {
+		s.lastProfiles = newLastProfiles(reg, logger, s.lastProfilesDir, s.lastProfilesN)
+	}
+
+	return s
+}
+
+// SetSamplingRules replaces the sampling rules, eg. when the configuration
+// was reloaded.
+func (s *ProfileColumnStore) SetSamplingRules(rules []SamplingRule) {
+	s.sampling.setRules(rules)
 }
 
 func (s *ProfileColumnStore) writeSeries(ctx context.Context, req *profilestorepb.WriteRawRequest) error {
-	r, err := normalizer.WriteRawRequestToArrowRecord(
+	now := time.Now()
+	received := timestamp.FromTime(now)
+
+	// Invalid series are rejected before they are tracked as known series.
+	for _, series := range req.Series {
+		if err := normalizer.ValidateLabelSet(series.Labels); err != nil {
+			s.seriesErrors.record(req.Tenant, labelSetMap(series.Labels), AppendErrorInvalidLabels, err, now)
+			return err
+		}
+	}
+
+	timer := s.stages.start(req)
+
+	// The last profiles are kept even if they are rejected below, eg. when
+	// the memory limit is reached.
+	s.lastProfiles.record(req, now)
+
+	if err := s.memory.admit(now); err != nil {
+		s.seriesErrors.recordRequest(req, AppendErrorMemoryLimit, err, now)
+		return err
+	}
+
+	req, dedupKeys := s.dedup.filter(req)
+	if len(req.Series) == 0 {
+		return nil
+	}
+
+	// New series beyond the series limit are rejected, the remaining ones
+	// are ingested.
+	req, seriesErr := s.seriesLimit.filter(req, now, func(series *profilestorepb.RawProfileSeries, err error) {
+		s.seriesErrors.record(req.Tenant, labelSetMap(series.Labels), AppendErrorSeriesLimit, err, now)
+	})
+	if len(req.Series) == 0 {
+		return seriesErr
+	}
+
+	if err := s.seriesCreation.admit(ctx, req); err != nil {
+		s.seriesErrors.recordRequest(req, AppendErrorThrottled, err, now)
+		return err
+	}
+	s.seriesLimit.commit(req, now)
+
+	// Profiles exceeding the size limit are rejected or trimmed, the
+	// remaining ones are ingested.
+	req, sizeErr := s.sizes.apply(ctx, req, func(series *profilestorepb.RawProfileSeries, err error) {
+		s.seriesErrors.record(req.Tenant, labelSetMap(series.Labels), AppendErrorProfileTooLarge, err, now)
+	})
+	if sizeErr == nil {
+		sizeErr = seriesErr
+	}
+	if len(req.Series) == 0 {
+		return sizeErr
+	}
+
+	exemplars := s.exemplars.capture(req)
+	timer.done(StageAdmission)
+
+	normalizedRequest, err := normalizer.NormalizeWriteRawRequest(ctx, req)
+	if err != nil {
+		s.seriesErrors.recordRequest(req, AppendErrorInvalidProfile, err, now)
+		return err
+	}
+	timer.done(StageNormalize)
+
+	s.timestamps.apply(ctx, normalizedRequest, received)
+	exemplars.match(req.Tenant, req, normalizedRequest)
+
+	// Profiles out of bounds are rejected, the remaining ones are ingested.
+	normalizedRequest, boundsErr := s.bounds.filter(normalizedRequest, received, func(series normalizer.Series, p *normalizer.NormalizedProfile, err error) {
+		reason := AppendErrorTooFarInPast
+		if errors.Is(err, ErrTooFarInFuture) {
+			reason = AppendErrorTooFarInFuture
+		}
+		ls := maps.Clone(series.Labels)
+		ls[model.MetricNameLabel] = p.Meta.Name
+		s.seriesErrors.record(req.Tenant, ls, reason, err, now)
+	})
+	if boundsErr == nil {
+		boundsErr = sizeErr
+	}
+
+	normalizedRequest = s.sampling.apply(req.Tenant, normalizedRequest, now)
+	normalizedRequest, skippedEmpty := s.emptyProfiles.apply(normalizedRequest)
+	s.stacks.apply(normalizedRequest)
+	timer.done(StageFilter)
+
+	if skippedEmpty > 0 && len(normalizedRequest.Series) == 0 {
+		// Only empty profiles were written, the targets are still healthy.
+		s.seriesStats.record(req, now)
+		s.dedup.commit(dedupKeys)
+		return boundsErr
+	}
+
+	if err := s.runMiddlewares(ctx, req.Tenant, &normalizedRequest); err != nil {
+		s.seriesErrors.recordRequest(req, AppendErrorRejected, err, now)
+		return err
+	}
+	timer.done(StageMiddlewares)
+
+	r, err := normalizer.NormalizedWriteRawRequestToArrowRecord(
 		ctx,
 		s.mem,
-		req,
+		normalizedRequest,
 		s.schema,
 	)
 	if err != nil {
+		s.seriesErrors.recordRequest(req, AppendErrorInvalidProfile, err, now)
 		return err
 	}
 	if r == nil {
-		return nil
+		return boundsErr
 	}
 	defer r.Release()
 
 	if r.NumRows() == 0 {
-		return nil
+		return boundsErr
 	}
+	timer.done(StageConvert)
 
-	return s.ingester.Ingest(ctx, r)
+	if err := s.ingester.Ingest(ctx, r); err != nil {
+		s.seriesErrors.recordRequest(req, AppendErrorIngest, err, now)
+		return err
+	}
+	timer.done(StageIngest)
+
+	// The profiles are stored, missing metadata doesn't fail the write.
+	if err := s.writeProfileMetadata(ctx, normalizedRequest); err != nil {
+		level.Warn(s.logger).Log("msg", "failed to store profile metadata", "err", err)
+	}
+	s.exemplars.store(ctx, exemplars, normalizedRequest)
+	timer.done(StageMetadata)
+
+	s.seriesStats.record(req, now)
+	s.dedup.commit(dedupKeys)
+	return boundsErr
 }
 
 func (s *ProfileColumnStore) updateAgents(nodeNameAndIP string, ag agent) {
@@ -280,12 +613,16 @@ func (s *ProfileColumnStore) write(ctx context.Context, server profilestorepb.Pr
 	if err != nil {
 		return fmt.Errorf("new record: %w", err)
 	}
+	defer ir.Release()
 
 	if ir.NumRows() == 0 {
 		return nil
 	}
 
-	if err := s.ingester.Ingest(ctx, ir); err != nil {
+	if err := s.writeRecord(ctx, ir); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
 		return status.Errorf(codes.Internal, "failed to ingest record: %v", err)
 	}
 
@@ -311,13 +648,41 @@ func (s *ProfileColumnStore) Export(ctx context.Context, req *otelgrpcprofilingp
 		return &otelgrpcprofilingpb.ExportProfilesServiceResponse{}, nil
 	}
 
-	if err := s.ingester.Ingest(ctx, r); err != nil {
+	if err := s.writeRecord(ctx, r); err != nil {
 		return nil, err
 	}
 
 	return &otelgrpcprofilingpb.ExportProfilesServiceResponse{}, nil
 }
 
+// writeRecord ingests a record converted from an Arrow or OTLP write. The
+// conversion validates the record, so only the ingest middlewares run on its
+// profiles, and only if there are any, see writeSeries.
+func (s *ProfileColumnStore) writeRecord(ctx context.Context, r arrow.Record) error {
+	if len(s.middlewares) == 0 {
+		return s.ingester.Ingest(ctx, r)
+	}
+
+	req, err := normalizer.RecordToNormalizedWriteRawRequest(r)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to convert record: %v", err)
+	}
+	if err := s.runMiddlewares(ctx, "", &req); err != nil {
+		return err
+	}
+
+	mr, err := normalizer.NormalizedWriteRawRequestToArrowRecord(ctx, s.mem, req, s.schema)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to convert profiles: %v", err)
+	}
+	if mr == nil {
+		return nil
+	}
+	defer mr.Release()
+
+	return s.ingester.Ingest(ctx, mr)
+}
+
 func (s *ProfileColumnStore) Agents(ctx context.Context, req *profilestorepb.AgentsRequest) (*profilestorepb.AgentsResponse, error) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
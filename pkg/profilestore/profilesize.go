@@ -0,0 +1,282 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pprofpb "github.com/parca-dev/parca/gen/proto/go/google/pprof"
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+// ProfileSizeMode is what happens to profiles exceeding the size limit.
+type ProfileSizeMode string
+
+const (
+	// ProfileSizeModeReject rejects profiles exceeding the size limit.
+	ProfileSizeModeReject ProfileSizeMode = "reject"
+	// ProfileSizeModeTrim keeps the heaviest samples of profiles exceeding
+	// the size limit, and rejects them if they still exceed it.
+	ProfileSizeModeTrim ProfileSizeMode = "trim"
+)
+
+// ErrProfileTooLarge is returned for profiles exceeding the size limit.
+var ErrProfileTooLarge = errors.New("profile exceeds the maximum size")
+
+// ProfileSizeLimit limits the uncompressed size of written pprof profiles.
+type ProfileSizeLimit struct {
+	// MaxBytes is the maximum uncompressed size of a profile. Zero disables
+	// the limit.
+	MaxBytes int64
+	Mode     ProfileSizeMode
+	// TopK is the number of heaviest samples kept by ProfileSizeModeTrim.
+	TopK int
+}
+
+type profileSizeLimitKey struct{}
+
+// ContextWithProfileSizeLimit returns a context that overrides the profile
+// size limit of the store for the writes made with it, eg. with the limit
+// configured for a scrape target.
+func ContextWithProfileSizeLimit(ctx context.Context, limit ProfileSizeLimit) context.Context {
+	return context.WithValue(ctx, profileSizeLimitKey{}, limit)
+}
+
+// profileSizeLimiter enforces the profile size limit before profiles are
+// normalized.
+type profileSizeLimiter struct {
+	limit ProfileSizeLimit
+
+	violations *prometheus.CounterVec
+}
+
+func newProfileSizeLimiter(reg prometheus.Registerer, limit ProfileSizeLimit) *profileSizeLimiter {
+	return &profileSizeLimiter{
+		limit: limit,
+		violations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_profile_size_violations_total",
+			Help: "Total number of profiles exceeding the size limit by whether they were rejected or trimmed.",
+		}, []string{"action"}),
+	}
+}
+
+// apply rejects or trims the profiles of the request exceeding the size
+// limit, calling onReject for each rejected one. The remaining profiles are
+// returned along with an error if any was rejected.
+func (l *profileSizeLimiter) apply(
+	ctx context.Context,
+	req *profilestorepb.WriteRawRequest,
+	onReject func(series *profilestorepb.RawProfileSeries, err error),
+) (*profilestorepb.WriteRawRequest, error) {
+	limit := l.limit
+	if override, ok := ctx.Value(profileSizeLimitKey{}).(ProfileSizeLimit); ok {
+		limit = override
+	}
+	if limit.MaxBytes <= 0 {
+		return req, nil
+	}
+
+	var (
+		firstErr error
+		series   = make([]*profilestorepb.RawProfileSeries, 0, len(req.Series))
+	)
+	for _, s := range req.Series {
+		samples := make([]*profilestorepb.RawSample, 0, len(s.Samples))
+		for _, sample := range s.Samples {
+			err := l.limitSample(limit, sample)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				if onReject != nil {
+					onReject(s, err)
+				}
+				continue
+			}
+			samples = append(samples, sample)
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		series = append(series, &profilestorepb.RawProfileSeries{
+			Labels:  s.Labels,
+			Samples: samples,
+		})
+	}
+
+	if firstErr == nil {
+		return req, nil
+	}
+	return &profilestorepb.WriteRawRequest{
+		Tenant:     req.Tenant,
+		Normalized: req.Normalized,
+		Series:     series,
+	}, firstErr
+}
+
+// limitSample returns an error if the sample's profile exceeds the limit.
+// In trim mode the profile is trimmed in place instead if possible.
+func (l *profileSizeLimiter) limitSample(limit ProfileSizeLimit, sample *profilestorepb.RawSample) error {
+	raw, exceeded, err := uncompressedProfile(sample.RawProfile, limit.MaxBytes, limit.Mode == ProfileSizeModeTrim)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "decompressing profile: %v", err)
+	}
+	if !exceeded {
+		return nil
+	}
+
+	if limit.Mode == ProfileSizeModeTrim && limit.TopK > 0 {
+		trimmed, err := trimProfile(raw, limit.TopK)
+		if err == nil && int64(len(trimmed)) <= limit.MaxBytes {
+			l.violations.WithLabelValues("trimmed").Inc()
+			sample.RawProfile = trimmed
+			return nil
+		}
+	}
+
+	l.violations.WithLabelValues("rejected").Inc()
+	return profileTooLargeError{maxBytes: limit.MaxBytes}
+}
+
+// profileTooLargeError is an ErrProfileTooLarge with an InvalidArgument
+// status.
+type profileTooLargeError struct {
+	maxBytes int64
+}
+
+func (e profileTooLargeError) Error() string {
+	return fmt.Sprintf("%v of %d bytes", ErrProfileTooLarge, e.maxBytes)
+}
+
+func (e profileTooLargeError) Unwrap() error {
+	return ErrProfileTooLarge
+}
+
+func (e profileTooLargeError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// uncompressedProfile returns whether the uncompressed profile exceeds
+// maxBytes. If full is set the uncompressed profile is returned as well,
+// otherwise it is only read up to the limit.
+func uncompressedProfile(raw []byte, maxBytes int64, full bool) ([]byte, bool, error) {
+	if len(raw) < 2 || raw[0] != 0x1f || raw[1] != 0x8b {
+		return raw, int64(len(raw)) > maxBytes, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false, err
+	}
+	defer gz.Close()
+
+	readLimit := maxBytes + 1
+	if full {
+		readLimit = normalizer.MaxDecompressedProfileSize
+	}
+	b, err := io.ReadAll(io.LimitReader(gz, readLimit))
+	if err != nil {
+		return nil, false, err
+	}
+	return b, int64(len(b)) > maxBytes, nil
+}
+
+// trimProfile keeps the topK heaviest samples of the profile by the value
+// of its default sample type, and the locations and functions they
+// reference.
+func trimProfile(raw []byte, topK int) ([]byte, error) {
+	p := &pprofpb.Profile{}
+	if err := p.UnmarshalVT(raw); err != nil {
+		return nil, err
+	}
+	if len(p.SampleType) == 0 {
+		return nil, errors.New("profile has no sample types")
+	}
+
+	// Like pprof, the last sample type is the default one unless set.
+	valueIndex := len(p.SampleType) - 1
+	for i, st := range p.SampleType {
+		if p.DefaultSampleType != 0 && st.Type == p.DefaultSampleType {
+			valueIndex = i
+		}
+	}
+	weight := func(s *pprofpb.Sample) int64 {
+		if valueIndex >= len(s.Value) {
+			return 0
+		}
+		v := s.Value[valueIndex]
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+
+	if len(p.Sample) > topK {
+		slices.SortStableFunc(p.Sample, func(a, b *pprofpb.Sample) int {
+			wa, wb := weight(a), weight(b)
+			switch {
+			case wa > wb:
+				return -1
+			case wa < wb:
+				return 1
+			}
+			return 0
+		})
+		p.Sample = p.Sample[:topK]
+	}
+
+	locations := map[uint64]struct{}{}
+	for _, s := range p.Sample {
+		for _, id := range s.LocationId {
+			locations[id] = struct{}{}
+		}
+	}
+	functions := map[uint64]struct{}{}
+	p.Location = slices.DeleteFunc(p.Location, func(loc *pprofpb.Location) bool {
+		if _, ok := locations[loc.Id]; !ok {
+			return true
+		}
+		for _, line := range loc.Line {
+			functions[line.FunctionId] = struct{}{}
+		}
+		return false
+	})
+	p.Function = slices.DeleteFunc(p.Function, func(fn *pprofpb.Function) bool {
+		_, ok := functions[fn.Id]
+		return !ok
+	})
+
+	return p.MarshalVT()
+}
+
+func labelValue(ls *profilestorepb.LabelSet, name string) string {
+	for _, l := range ls.GetLabels() {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pprofpb "github.com/parca-dev/parca/gen/proto/go/google/pprof"
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+// pprofWithSamples returns a profile with a sample of each value, each with
+// its own location and function.
+func pprofWithSamples(t *testing.T, values ...int64) []byte {
+	t.Helper()
+
+	p := &pprofpb.Profile{
+		StringTable: []string{"", "cpu", "nanoseconds"},
+		SampleType:  []*pprofpb.ValueType{{Type: 1, Unit: 2}},
+	}
+	for i, v := range values {
+		id := uint64(i + 1)
+		p.Function = append(p.Function, &pprofpb.Function{Id: id})
+		p.Location = append(p.Location, &pprofpb.Location{Id: id, Line: []*pprofpb.Line{{FunctionId: id}}})
+		p.Sample = append(p.Sample, &pprofpb.Sample{LocationId: []uint64{id}, Value: []int64{v}})
+	}
+
+	b, err := p.MarshalVT()
+	require.NoError(t, err)
+	return b
+}
+
+func TestProfileSizeLimiter(t *testing.T) {
+	ctx := context.Background()
+	small := pprofWithSamples(t, 1)
+	large := pprofWithSamples(t, 5, 30, 10, 20, 1, 2)
+
+	newRequest := func() *profilestorepb.WriteRawRequest {
+		return &profilestorepb.WriteRawRequest{
+			Series: []*profilestorepb.RawProfileSeries{
+				rawSeries(string(small), "job", "a", "instance", "1"),
+				rawSeries(string(large), "job", "b", "instance", "2"),
+			},
+		}
+	}
+
+	l := newProfileSizeLimiter(prometheus.NewRegistry(), ProfileSizeLimit{
+		MaxBytes: int64(len(small)) + 10,
+		Mode:     ProfileSizeModeReject,
+	})
+	var rejected []string
+	req, err := l.apply(ctx, newRequest(), func(series *profilestorepb.RawProfileSeries, err error) {
+		rejected = append(rejected, labelValue(series.Labels, "job"))
+	})
+	require.ErrorIs(t, err, ErrProfileTooLarge)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, []string{"b"}, rejected)
+	require.Len(t, req.Series, 1)
+	require.Equal(t, 1.0, testutil.ToFloat64(l.violations.WithLabelValues("rejected")))
+
+	// A write can trim instead, keeping the heaviest samples.
+	ctx = ContextWithProfileSizeLimit(ctx, ProfileSizeLimit{
+		MaxBytes: int64(len(pprofWithSamples(t, 30, 20))),
+		Mode:     ProfileSizeModeTrim,
+		TopK:     2,
+	})
+	req, err = l.apply(ctx, newRequest(), nil)
+	require.NoError(t, err)
+	require.Len(t, req.Series, 2)
+	require.Equal(t, 1.0, testutil.ToFloat64(l.violations.WithLabelValues("trimmed")))
+
+	p := &pprofpb.Profile{}
+	require.NoError(t, p.UnmarshalVT(req.Series[1].Samples[0].RawProfile))
+	require.Len(t, p.Sample, 2)
+	require.Equal(t, []int64{30}, p.Sample[0].Value)
+	require.Equal(t, []int64{20}, p.Sample[1].Value)
+	// Only the locations and functions of the kept samples remain.
+	require.Len(t, p.Location, 2)
+	require.Len(t, p.Function, 2)
+
+	// Profiles still exceeding the limit after trimming are rejected.
+	ctx = ContextWithProfileSizeLimit(context.Background(), ProfileSizeLimit{
+		MaxBytes: int64(len(small)) + 10,
+		Mode:     ProfileSizeModeTrim,
+		TopK:     4,
+	})
+	req, err = l.apply(ctx, newRequest(), nil)
+	require.ErrorIs(t, err, ErrProfileTooLarge)
+	require.Len(t, req.Series, 1)
+}
+
+func TestProfileSizeLimiterDisabled(t *testing.T) {
+	l := newProfileSizeLimiter(prometheus.NewRegistry(), ProfileSizeLimit{})
+	req := &profilestorepb.WriteRawRequest{Series: []*profilestorepb.RawProfileSeries{rawSeries("profile")}}
+
+	res, err := l.apply(context.Background(), req, nil)
+	require.NoError(t, err)
+	require.Same(t, req, res)
+}
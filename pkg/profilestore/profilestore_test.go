@@ -16,6 +16,7 @@ package profilestore
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/apache/arrow/go/v16/arrow/memory"
@@ -29,6 +30,7 @@ import (
 
 	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
 	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/normalizer"
 	"github.com/parca-dev/parca/pkg/profile"
 )
 
@@ -70,6 +72,7 @@ func Test_LabelName_Error(t *testing.T) {
 	cases := []struct {
 		name   string
 		labels []*profilestorepb.Label
+		err    error
 	}{
 		{
 			name: "invalid label name",
@@ -79,6 +82,7 @@ func Test_LabelName_Error(t *testing.T) {
 					Value: "v0",
 				},
 			},
+			err: normalizer.ErrInvalidLabelName,
 		},
 		{
 			name: "duplicate label names",
@@ -92,6 +96,37 @@ func Test_LabelName_Error(t *testing.T) {
 					Value: "v0",
 				},
 			},
+			err: normalizer.ErrDuplicateLabelName,
+		},
+		{
+			name: "reserved label name",
+			labels: []*profilestorepb.Label{
+				{
+					Name:  "__address__",
+					Value: "localhost:7070",
+				},
+			},
+			err: normalizer.ErrReservedLabelName,
+		},
+		{
+			name: "invalid label value",
+			labels: []*profilestorepb.Label{
+				{
+					Name:  "n0",
+					Value: "\xff",
+				},
+			},
+			err: normalizer.ErrInvalidLabelValue,
+		},
+		{
+			name: "label value too long",
+			labels: []*profilestorepb.Label{
+				{
+					Name:  "n0",
+					Value: strings.Repeat("v", normalizer.MaxLabelValueLength+1),
+				},
+			},
+			err: normalizer.ErrLabelValueTooLong,
 		},
 	}
 
@@ -105,10 +140,11 @@ func Test_LabelName_Error(t *testing.T) {
 				}},
 			}
 
-			_, err = api.WriteRaw(ctx, req)
+			_, err := api.WriteRaw(ctx, req)
 			st, _ := status.FromError(err)
 
 			require.Equal(t, codes.InvalidArgument, st.Code())
+			require.ErrorIs(t, err, c.err)
 		})
 	}
 }
@@ -0,0 +1,192 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+// SamplingMode determines which profiles of a sampled series are kept.
+type SamplingMode string
+
+const (
+	// SamplingModeEveryNth keeps exactly every Nth profile of a series.
+	SamplingModeEveryNth SamplingMode = "every_nth"
+	// SamplingModeProbabilistic keeps each profile with a probability of
+	// 1/N.
+	SamplingModeProbabilistic SamplingMode = "probabilistic"
+)
+
+// SamplingRule keeps one of every Keep profiles of the series matching all
+// matchers. The values of kept delta profiles are multiplied by Keep, so that
+// merged results remain statistically correct. Non-delta profiles, eg. heap
+// snapshots, are kept as they are, a kept snapshot stands in for the dropped
+// ones instead of adding up with them.
+type SamplingRule struct {
+	Matchers []*labels.Matcher
+	Keep     int
+	Mode     SamplingMode
+}
+
+func (r SamplingRule) matches(ls map[string]string) bool {
+	for _, m := range r.Matchers {
+		if !m.Matches(ls[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sampler drops profiles of series matching a sampling rule before they are
+// appended. The first matching rule applies to a series.
+type sampler struct {
+	mtx   sync.Mutex
+	rules []SamplingRule
+	// seen counts the profiles of every-Nth sampled series, by the hash of
+	// the tenant and labels of the series. They are reset with the rules,
+	// series that were not written to for the TTL are forgotten.
+	seen   map[uint64]sampledSeries
+	ttl    time.Duration
+	nextGC time.Time
+	float  func() float64
+
+	sampled *prometheus.CounterVec
+}
+
+type sampledSeries struct {
+	n         uint64
+	lastWrite time.Time
+}
+
+func newSampler(reg prometheus.Registerer, rules []SamplingRule) *sampler {
+	return &sampler{
+		rules:  rules,
+		seen:   map[uint64]sampledSeries{},
+		ttl:    defaultSeriesTTL,
+		nextGC: time.Now().Add(defaultSeriesTTL),
+		float:  rand.Float64,
+		sampled: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_sampled_profiles_total",
+			Help: "Total number of profiles of series matching a sampling rule, by whether they were kept.",
+		}, []string{"action"}),
+	}
+}
+
+func (s *sampler) setRules(rules []SamplingRule) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.rules = rules
+	s.seen = map[uint64]sampledSeries{}
+}
+
+// apply removes the sampled out profiles from the request and scales the
+// values of the kept delta profiles of sampled series.
+func (s *sampler) apply(tenant string, req normalizer.NormalizedWriteRawRequest, now time.Time) normalizer.NormalizedWriteRawRequest {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if len(s.rules) == 0 {
+		return req
+	}
+	s.gc(now)
+
+	series := req.Series[:0]
+	for _, ser := range req.Series {
+		samples := ser.Samples[:0]
+		for _, sample := range ser.Samples {
+			rule, ok := s.match(ser.Labels, sample)
+			if !ok {
+				samples = append(samples, sample)
+				continue
+			}
+
+			if !s.keep(tenant, ser.Labels, rule, now) {
+				s.sampled.WithLabelValues("dropped").Add(float64(len(sample)))
+				continue
+			}
+			s.sampled.WithLabelValues("kept").Add(float64(len(sample)))
+			for _, p := range sample {
+				if p.Meta.Duration == 0 {
+					continue
+				}
+				for _, smpl := range p.Samples {
+					smpl.Value *= int64(rule.Keep)
+					smpl.DiffValue *= int64(rule.Keep)
+				}
+			}
+			samples = append(samples, sample)
+		}
+		if len(samples) > 0 {
+			ser.Samples = samples
+			series = append(series, ser)
+		}
+	}
+	req.Series = series
+	return req
+}
+
+// match returns the first rule matching the series, whose profile name is
+// matched as the __name__ label.
+func (s *sampler) match(ls map[string]string, sample []*normalizer.NormalizedProfile) (SamplingRule, bool) {
+	if len(sample) == 0 {
+		return SamplingRule{}, false
+	}
+
+	withName := make(map[string]string, len(ls)+1)
+	for k, v := range ls {
+		withName[k] = v
+	}
+	withName[labels.MetricName] = sample[0].Meta.Name
+
+	for _, r := range s.rules {
+		if r.Keep > 1 && r.matches(withName) {
+			return r, true
+		}
+	}
+	return SamplingRule{}, false
+}
+
+func (s *sampler) keep(tenant string, ls map[string]string, rule SamplingRule, now time.Time) bool {
+	if rule.Mode == SamplingModeProbabilistic {
+		return s.float() < 1/float64(rule.Keep)
+	}
+
+	h := labels.FromMap(ls).Hash() ^ xxhash.Sum64String(tenant)
+	n := s.seen[h].n
+	s.seen[h] = sampledSeries{n: n + 1, lastWrite: now}
+	return n%uint64(rule.Keep) == 0
+}
+
+// gc forgets the series that were not written to for the TTL.
+func (s *sampler) gc(now time.Time) {
+	if !now.After(s.nextGC) {
+		return
+	}
+	for h, ser := range s.seen {
+		if now.Sub(ser.lastWrite) >= s.ttl {
+			delete(s.seen, h)
+		}
+	}
+	s.nextGC = now.Add(s.ttl)
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func TestSampler(t *testing.T) {
+	newRequest := func() normalizer.NormalizedWriteRawRequest {
+		newSample := func() []*normalizer.NormalizedProfile {
+			return []*normalizer.NormalizedProfile{{
+				Meta:    profile.Meta{Name: "cpu", Duration: time.Second.Nanoseconds()},
+				Samples: []*normalizer.NormalizedSample{{Value: 3}},
+			}}
+		}
+		return normalizer.NormalizedWriteRawRequest{
+			Series: []normalizer.Series{
+				{Labels: map[string]string{"namespace": "dev"}, Samples: [][]*normalizer.NormalizedProfile{newSample()}},
+				{Labels: map[string]string{"namespace": "prod"}, Samples: [][]*normalizer.NormalizedProfile{newSample()}},
+			},
+		}
+	}
+
+	s := newSampler(prometheus.NewRegistry(), []SamplingRule{{
+		Matchers: []*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchEqual, "namespace", "dev"),
+			labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "cpu"),
+		},
+		Keep: 3,
+		Mode: SamplingModeEveryNth,
+	}})
+
+	now := time.Unix(1000, 0)
+	var kept []int64
+	for i := 0; i < 6; i++ {
+		req := s.apply("", newRequest(), now)
+		for _, ser := range req.Series {
+			if ser.Labels["namespace"] == "dev" {
+				kept = append(kept, ser.Samples[0][0].Samples[0].Value)
+			}
+		}
+		// Series not matching any rule are kept as they are.
+		require.Equal(t, "prod", req.Series[len(req.Series)-1].Labels["namespace"])
+		require.Equal(t, int64(3), req.Series[len(req.Series)-1].Samples[0][0].Samples[0].Value)
+	}
+	// Every 3rd profile is kept, with its value scaled by 3.
+	require.Equal(t, []int64{9, 9}, kept)
+	require.Equal(t, 4.0, testutil.ToFloat64(s.sampled.WithLabelValues("dropped")))
+
+	s.setRules([]SamplingRule{{
+		Matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "namespace", "prod")},
+		Keep:     2,
+		Mode:     SamplingModeProbabilistic,
+	}})
+	s.float = func() float64 { return 0.7 }
+	req := s.apply("", newRequest(), now)
+	require.Len(t, req.Series, 1)
+	require.Equal(t, "dev", req.Series[0].Labels["namespace"])
+
+	s.float = func() float64 { return 0.2 }
+	req = s.apply("", newRequest(), now)
+	require.Len(t, req.Series, 2)
+	require.Equal(t, int64(6), req.Series[1].Samples[0][0].Samples[0].Value)
+}
+
+func TestSamplerNonDelta(t *testing.T) {
+	s := newSampler(prometheus.NewRegistry(), []SamplingRule{{
+		Matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "memory")},
+		Keep:     2,
+		Mode:     SamplingModeEveryNth,
+	}})
+
+	// A kept heap snapshot stands in for the dropped one, it is not scaled.
+	req := s.apply("", normalizer.NormalizedWriteRawRequest{
+		Series: []normalizer.Series{{
+			Labels: map[string]string{"job": "api"},
+			Samples: [][]*normalizer.NormalizedProfile{{{
+				Meta:    profile.Meta{Name: "memory"},
+				Samples: []*normalizer.NormalizedSample{{Value: 3}},
+			}}},
+		}},
+	}, time.Unix(1000, 0))
+	require.Len(t, req.Series, 1)
+	require.Equal(t, int64(3), req.Series[0].Samples[0][0].Samples[0].Value)
+}
+
+func TestSamplerForgetsSeries(t *testing.T) {
+	s := newSampler(prometheus.NewRegistry(), []SamplingRule{{
+		Matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "cpu")},
+		Keep:     2,
+		Mode:     SamplingModeEveryNth,
+	}})
+	newRequest := func(job string) normalizer.NormalizedWriteRawRequest {
+		return normalizer.NormalizedWriteRawRequest{
+			Series: []normalizer.Series{{
+				Labels: map[string]string{"job": job},
+				Samples: [][]*normalizer.NormalizedProfile{{{
+					Meta:    profile.Meta{Name: "cpu", Duration: time.Second.Nanoseconds()},
+					Samples: []*normalizer.NormalizedSample{{Value: 3}},
+				}}},
+			}},
+		}
+	}
+
+	now := time.Now()
+	s.apply("", newRequest("old"), now)
+	s.apply("", newRequest("recent"), now.Add(s.ttl/2))
+	require.Len(t, s.seen, 2)
+
+	// Series that were not written to for the TTL are forgotten.
+	s.apply("", newRequest("recent"), now.Add(s.ttl+time.Second))
+	require.Len(t, s.seen, 1)
+	require.Contains(t, s.seen, labels.FromStrings("job", "recent").Hash()^xxhash.Sum64String(""))
+}
@@ -0,0 +1,321 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+// seriesTracker keeps track of the series written to recently, keyed by the
+// hash of their tenant and labels. Series that were not written to for the
+// TTL are forgotten.
+type seriesTracker struct {
+	ttl time.Duration
+
+	mtx    sync.Mutex
+	series map[uint64]time.Time
+	nextGC time.Time
+}
+
+func newSeriesTracker(ttl time.Duration) *seriesTracker {
+	return &seriesTracker{
+		ttl:    ttl,
+		series: map[uint64]time.Time{},
+		nextGC: time.Now().Add(ttl),
+	}
+}
+
+// known returns whether the series was written to within the TTL.
+func (t *seriesTracker) known(hash uint64) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	lastWrite, ok := t.series[hash]
+	return ok && time.Since(lastWrite) < t.ttl
+}
+
+// active returns the number of series written to within the TTL.
+func (t *seriesTracker) active(now time.Time) int {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	n := 0
+	for _, lastWrite := range t.series {
+		if now.Sub(lastWrite) < t.ttl {
+			n++
+		}
+	}
+	return n
+}
+
+// touch records a write to the series.
+func (t *seriesTracker) touch(hash uint64, now time.Time) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.series[hash] = now
+	if now.After(t.nextGC) {
+		for h, lastWrite := range t.series {
+			if now.Sub(lastWrite) >= t.ttl {
+				delete(t.series, h)
+			}
+		}
+		t.nextGC = now.Add(t.ttl)
+	}
+}
+
+// seriesCreationLimiter throttles the creation of new series. A burst of new
+// targets would otherwise create thousands of series at once. Writes that
+// create new series wait for the limiter, and are rejected if too many series
+// creations are already pending.
+type seriesCreationLimiter struct {
+	tracker  *seriesTracker
+	limiter  *rate.Limiter
+	maxQueue int
+
+	mtx     sync.Mutex
+	pending int
+
+	pendingGauge prometheus.Gauge
+	created      prometheus.Counter
+	rejected     prometheus.Counter
+}
+
+func newSeriesCreationLimiter(reg prometheus.Registerer, tracker *seriesTracker, perSecond float64, burst, maxQueue int) *seriesCreationLimiter {
+	return &seriesCreationLimiter{
+		tracker:  tracker,
+		limiter:  rate.NewLimiter(rate.Limit(perSecond), burst),
+		maxQueue: maxQueue,
+		pendingGauge: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "parca_profilestore_pending_series_creations",
+			Help: "Number of new series waiting to be created.",
+		}),
+		created: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_series_created_total",
+			Help: "Total number of series created.",
+		}),
+		rejected: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_series_creation_rejected_total",
+			Help: "Total number of writes rejected because too many series creations were pending.",
+		}),
+	}
+}
+
+// admit waits until the new series of the request may be created. It
+// returns a ResourceExhausted error if the queue of pending series creations
+// is full.
+func (l *seriesCreationLimiter) admit(ctx context.Context, req *profilestorepb.WriteRawRequest) error {
+	if l == nil {
+		return nil
+	}
+
+	var created []uint64
+	for _, s := range req.Series {
+		h := hashSeries(req.Tenant, s.Labels)
+		if !l.tracker.known(h) {
+			created = append(created, h)
+		}
+	}
+
+	if len(created) > 0 {
+		if !l.enqueue(len(created)) {
+			l.rejected.Inc()
+			return status.Errorf(codes.ResourceExhausted, "too many pending series creations, retry later")
+		}
+		defer l.dequeue(len(created))
+
+		for range created {
+			if err := l.limiter.Wait(ctx); err != nil {
+				return status.Errorf(codes.Unavailable, "waiting for series creation: %v", err)
+			}
+		}
+		l.created.Add(float64(len(created)))
+	}
+
+	now := time.Now()
+	for _, s := range req.Series {
+		l.tracker.touch(hashSeries(req.Tenant, s.Labels), now)
+	}
+	return nil
+}
+
+func (l *seriesCreationLimiter) enqueue(n int) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	// A single write is always admitted to the queue if it is empty, so that
+	// writes with more new series than the queue size are not starved.
+	if l.pending > 0 && l.pending+n > l.maxQueue {
+		return false
+	}
+	l.pending += n
+	l.pendingGauge.Set(float64(l.pending))
+	return true
+}
+
+func (l *seriesCreationLimiter) dequeue(n int) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.pending -= n
+	l.pendingGauge.Set(float64(l.pending))
+}
+
+// ErrSeriesLimit is returned for writes to new series exceeding the series
+// limit.
+var ErrSeriesLimit = errors.New("series limit exceeded")
+
+// seriesLimitError is an ErrSeriesLimit. If no series of the request was
+// admitted it has a ResourceExhausted status, so the request is retried once
+// other series became inactive. Otherwise the admitted series were ingested
+// and it has an InvalidArgument status like the other partially rejected
+// writes, retrying the request would write them again.
+type seriesLimitError struct {
+	max     int
+	partial bool
+}
+
+func (e seriesLimitError) Error() string {
+	if e.partial {
+		return fmt.Sprintf("%v: the limit of %d active series is reached, only the series that were already active were written", ErrSeriesLimit, e.max)
+	}
+	return fmt.Sprintf("%v: the limit of %d active series is reached", ErrSeriesLimit, e.max)
+}
+
+func (e seriesLimitError) Unwrap() error {
+	return ErrSeriesLimit
+}
+
+func (e seriesLimitError) GRPCStatus() *status.Status {
+	if e.partial {
+		return status.New(codes.InvalidArgument, e.Error())
+	}
+	return status.New(codes.ResourceExhausted, e.Error())
+}
+
+// seriesLimiter limits the number of active series, the series written to
+// within the TTL of the tracker, so that label churn, eg. from Kubernetes
+// service discovery, cannot exhaust the memory of the server. Writes to
+// known series are always admitted, new series beyond the limit are
+// rejected until other series become inactive.
+type seriesLimiter struct {
+	tracker *seriesTracker
+	max     int
+
+	mtx sync.Mutex
+	// activeCount is the number of active series as of the last count
+	// plus the series created since, the count is refreshed periodically
+	// as series become inactive.
+	activeCount int
+	nextCount   time.Time
+
+	activeGauge prometheus.Gauge
+	rejected    prometheus.Counter
+}
+
+// seriesLimitRecount is how often the active series are counted.
+const seriesLimitRecount = time.Minute
+
+func newSeriesLimiter(reg prometheus.Registerer, tracker *seriesTracker, max int) *seriesLimiter {
+	return &seriesLimiter{
+		tracker: tracker,
+		max:     max,
+		activeGauge: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "parca_profilestore_active_series",
+			Help: "Number of series written to recently, as counted for the series limit.",
+		}),
+		rejected: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_series_limit_rejected_total",
+			Help: "Total number of new series rejected because the series limit was reached.",
+		}),
+	}
+}
+
+// filter removes the new series exceeding the limit from the request,
+// calling onReject for each of them. It returns the remaining series along
+// with an error if any was rejected. The remaining new series are counted
+// as active, they become known once the write was committed.
+func (l *seriesLimiter) filter(
+	req *profilestorepb.WriteRawRequest,
+	now time.Time,
+	onReject func(series *profilestorepb.RawProfileSeries, err error),
+) (*profilestorepb.WriteRawRequest, error) {
+	if l == nil {
+		return req, nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if now.After(l.nextCount) {
+		l.activeCount = l.tracker.active(now)
+		l.nextCount = now.Add(seriesLimitRecount)
+	}
+
+	var (
+		rejected []*profilestorepb.RawProfileSeries
+		series   = make([]*profilestorepb.RawProfileSeries, 0, len(req.Series))
+	)
+	for _, s := range req.Series {
+		h := hashSeries(req.Tenant, s.Labels)
+		if !l.tracker.known(h) {
+			if l.activeCount >= l.max {
+				rejected = append(rejected, s)
+				continue
+			}
+			l.activeCount++
+		}
+		series = append(series, s)
+	}
+	l.activeGauge.Set(float64(l.activeCount))
+
+	if len(rejected) == 0 {
+		return req, nil
+	}
+	err := seriesLimitError{max: l.max, partial: len(series) > 0}
+	l.rejected.Add(float64(len(rejected)))
+	for _, s := range rejected {
+		if onReject != nil {
+			onReject(s, err)
+		}
+	}
+	return &profilestorepb.WriteRawRequest{
+		Tenant:     req.Tenant,
+		Normalized: req.Normalized,
+		Series:     series,
+	}, err
+}
+
+// commit records the writes to the series of the admitted request.
+func (l *seriesLimiter) commit(req *profilestorepb.WriteRawRequest, now time.Time) {
+	if l == nil {
+		return
+	}
+
+	for _, s := range req.Series {
+		l.tracker.touch(hashSeries(req.Tenant, s.Labels), now)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+func TestSeriesTracker(t *testing.T) {
+	tr := newSeriesTracker(time.Minute)
+	now := time.Now()
+
+	require.False(t, tr.known(1))
+	tr.touch(1, now)
+	require.True(t, tr.known(1))
+
+	tr.touch(2, now.Add(-2*time.Minute))
+	require.False(t, tr.known(2))
+
+	// Touching after the GC interval forgets stale series.
+	tr.touch(3, now.Add(2*time.Minute))
+	require.NotContains(t, tr.series, uint64(1))
+	require.NotContains(t, tr.series, uint64(2))
+	require.Contains(t, tr.series, uint64(3))
+}
+
+func TestSeriesCreationLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := newSeriesCreationLimiter(prometheus.NewRegistry(), newSeriesTracker(time.Hour), 1, 2, 2)
+
+	req := &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{
+			rawSeries("a", "job", "1"),
+			rawSeries("a", "job", "2"),
+		},
+	}
+	require.NoError(t, l.admit(ctx, req))
+	require.Equal(t, 2.0, testutil.ToFloat64(l.created))
+
+	// Known series are not throttled.
+	start := time.Now()
+	require.NoError(t, l.admit(ctx, req))
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+	require.Equal(t, 2.0, testutil.ToFloat64(l.created))
+
+	// The burst is used up, so a new series has to wait for the limiter.
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err := l.admit(ctx, &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{rawSeries("a", "job", "3")},
+	})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, 0.0, testutil.ToFloat64(l.pendingGauge))
+}
+
+func TestSeriesCreationLimiterQueueFull(t *testing.T) {
+	l := newSeriesCreationLimiter(prometheus.NewRegistry(), newSeriesTracker(time.Hour), 1, 1, 2)
+
+	require.True(t, l.enqueue(2))
+	err := l.admit(context.Background(), &profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{rawSeries("a", "job", "1")},
+	})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, 1.0, testutil.ToFloat64(l.rejected))
+}
+
+func TestSeriesLimiter(t *testing.T) {
+	l := newSeriesLimiter(prometheus.NewRegistry(), newSeriesTracker(time.Minute), 2)
+	now := time.Now()
+
+	req := &profilestorepb.WriteRawRequest{Series: []*profilestorepb.RawProfileSeries{
+		rawSeries("a", "__name__", "cpu", "job", "a"),
+		rawSeries("b", "__name__", "cpu", "job", "b"),
+	}}
+	admitted, err := l.filter(req, now, nil)
+	require.NoError(t, err)
+	l.commit(admitted, now)
+
+	var rejected []string
+	admitted, err = l.filter(&profilestorepb.WriteRawRequest{Series: []*profilestorepb.RawProfileSeries{
+		rawSeries("a", "__name__", "cpu", "job", "a"),
+		rawSeries("c", "__name__", "cpu", "job", "c"),
+	}}, now, func(series *profilestorepb.RawProfileSeries, _ error) {
+		rejected = append(rejected, labelValue(series.Labels, "job"))
+	})
+	require.ErrorIs(t, err, ErrSeriesLimit)
+	// The active series are written, retrying would write them again.
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, []string{"c"}, rejected)
+	require.Len(t, admitted.Series, 1)
+	require.Equal(t, 1.0, testutil.ToFloat64(l.rejected))
+
+	// Requests of only new series can be retried later.
+	admitted, err = l.filter(&profilestorepb.WriteRawRequest{Series: []*profilestorepb.RawProfileSeries{
+		rawSeries("c", "__name__", "cpu", "job", "c"),
+	}}, now, nil)
+	require.ErrorIs(t, err, ErrSeriesLimit)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Empty(t, admitted.Series)
+
+	// Once a series becomes inactive, new series are admitted again.
+	later := now.Add(2 * time.Minute)
+	l.tracker.touch(hashSeries("", req.Series[0].Labels), later)
+	_, err = l.filter(&profilestorepb.WriteRawRequest{Series: []*profilestorepb.RawProfileSeries{
+		rawSeries("c", "__name__", "cpu", "job", "c"),
+	}}, later, nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,166 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+// SeriesErrorsPath is the HTTP path of the endpoint listing the last append
+// error of series, relative to the API root.
+const SeriesErrorsPath = "/series/errors"
+
+// Reasons a write to a series failed.
+const (
+	AppendErrorInvalidLabels   = "invalid_labels"
+	AppendErrorInvalidProfile  = "invalid_profile"
+	AppendErrorThrottled       = "throttled"
+	AppendErrorTooFarInFuture  = "too_far_in_future"
+	AppendErrorTooFarInPast    = "too_far_in_past"
+	AppendErrorIngest          = "ingest"
+	AppendErrorMemoryLimit     = "memory_limit"
+	AppendErrorProfileTooLarge = "profile_too_large"
+	AppendErrorRejected        = "rejected"
+	AppendErrorSeriesLimit     = "series_limit"
+)
+
+// SeriesError is the last error of a write to a series.
+type SeriesError struct {
+	Tenant    string            `json:"tenant,omitempty"`
+	Labels    map[string]string `json:"labels"`
+	Reason    string            `json:"reason"`
+	Error     string            `json:"error"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// seriesErrors keeps the last append error of every series, so that gaps in
+// the data of a target can be explained. Errors are forgotten after the TTL.
+type seriesErrors struct {
+	ttl time.Duration
+
+	mtx    sync.Mutex
+	series map[string]*SeriesError
+	nextGC time.Time
+
+	errorsTotal *prometheus.CounterVec
+}
+
+func newSeriesErrors(reg prometheus.Registerer, ttl time.Duration) *seriesErrors {
+	return &seriesErrors{
+		ttl:    ttl,
+		series: map[string]*SeriesError{},
+		nextGC: time.Now().Add(ttl),
+		errorsTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_series_append_errors_total",
+			Help: "Total number of failed writes to series by reason.",
+		}, []string{"reason"}),
+	}
+}
+
+// record sets the last append error of the series with the given labels.
+func (e *seriesErrors) record(tenant string, ls map[string]string, reason string, err error, now time.Time) {
+	e.errorsTotal.WithLabelValues(reason).Inc()
+
+	key := tenant + labels.FromMap(ls).String()
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.series[key] = &SeriesError{
+		Tenant:    tenant,
+		Labels:    ls,
+		Reason:    reason,
+		Error:     err.Error(),
+		Timestamp: now,
+	}
+	if now.After(e.nextGC) {
+		e.gc(now)
+		e.nextGC = now.Add(e.ttl)
+	}
+}
+
+// recordRequest sets the last append error of all series of the request, for
+// errors that can't be attributed to a single series.
+func (e *seriesErrors) recordRequest(req *profilestorepb.WriteRawRequest, reason string, err error, now time.Time) {
+	for _, s := range req.Series {
+		e.record(req.Tenant, labelSetMap(s.Labels), reason, err, now)
+	}
+}
+
+// list returns the errors that are not expired yet, the most recent first.
+func (e *seriesErrors) list(now time.Time) []*SeriesError {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.gc(now)
+	res := make([]*SeriesError, 0, len(e.series))
+	for _, se := range e.series {
+		res = append(res, se)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Timestamp.After(res[j].Timestamp)
+	})
+	return res
+}
+
+func (e *seriesErrors) gc(now time.Time) {
+	for k, se := range e.series {
+		if now.Sub(se.Timestamp) >= e.ttl {
+			delete(e.series, k)
+		}
+	}
+}
+
+func labelSetMap(ls *profilestorepb.LabelSet) map[string]string {
+	m := make(map[string]string, len(ls.GetLabels()))
+	for _, l := range ls.GetLabels() {
+		m[l.Name] = l.Value
+	}
+	return m
+}
+
+// SeriesErrorsHandler lists the last append error of the series that failed
+// to be written to recently. The optional "reason" parameter filters the
+// errors by their reason.
+func (s *ProfileColumnStore) SeriesErrorsHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		reason := r.URL.Query().Get("reason")
+
+		res := []*SeriesError{}
+		for _, se := range s.seriesErrors.list(time.Now()) {
+			if reason == "" || strings.EqualFold(se.Reason, reason) {
+				res = append(res, se)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(struct {
+			Series []*SeriesError `json:"series"`
+		}{res}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
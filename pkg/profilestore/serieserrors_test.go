@@ -0,0 +1,64 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+func TestSeriesErrors(t *testing.T) {
+	e := newSeriesErrors(prometheus.NewRegistry(), time.Minute)
+	now := time.Now()
+
+	e.record("", map[string]string{"__name__": "cpu", "job": "a"}, AppendErrorTooFarInPast, ErrTooFarInPast, now.Add(-time.Second))
+	e.recordRequest(&profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{
+			rawSeries("a", "__name__", "cpu", "job", "a"),
+			rawSeries("a", "__name__", "cpu", "job", "b"),
+		},
+	}, AppendErrorIngest, errors.New("table closed"), now)
+	e.record("", map[string]string{"__name__": "cpu", "job": "c"}, AppendErrorThrottled, errors.New("throttled"), now.Add(-2*time.Minute))
+
+	require.Equal(t, 1.0, testutil.ToFloat64(e.errorsTotal.WithLabelValues(AppendErrorTooFarInPast)))
+	require.Equal(t, 2.0, testutil.ToFloat64(e.errorsTotal.WithLabelValues(AppendErrorIngest)))
+
+	// The last error of a series replaces the previous one and expired errors
+	// are not listed.
+	errs := e.list(now)
+	require.Len(t, errs, 2)
+	for _, se := range errs {
+		require.Equal(t, AppendErrorIngest, se.Reason)
+		require.Equal(t, "table closed", se.Error)
+	}
+
+	s := &ProfileColumnStore{seriesErrors: e}
+	rec := httptest.NewRecorder()
+	s.SeriesErrorsHandler()(rec, httptest.NewRequest("GET", SeriesErrorsPath+"?reason=ingest", nil), nil)
+
+	var res struct {
+		Series []*SeriesError `json:"series"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Len(t, res.Series, 2)
+}
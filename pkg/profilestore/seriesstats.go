@@ -0,0 +1,150 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+// defaultSeriesStatsLimit is the number of series served if the request has
+// no limit.
+const defaultSeriesStatsLimit = 100
+
+// seriesStat are the statistics of the profiles written to a series since it
+// became active.
+type seriesStat struct {
+	tenant string
+	labels map[string]string
+	// profiles is the number of profiles written.
+	profiles int64
+	// bytes is the size of the profiles written, as received.
+	bytes      int64
+	firstWrite time.Time
+	lastWrite  time.Time
+}
+
+// seriesStats keeps the statistics of the profiles written to every series,
+// so that the targets dominating the ingestion can be found. Series are
+// forgotten after they were not written to for the TTL.
+type seriesStats struct {
+	ttl time.Duration
+
+	mtx    sync.Mutex
+	series map[string]*seriesStat
+	nextGC time.Time
+}
+
+func newSeriesStats(ttl time.Duration) *seriesStats {
+	return &seriesStats{
+		ttl:    ttl,
+		series: map[string]*seriesStat{},
+		nextGC: time.Now().Add(ttl),
+	}
+}
+
+// record adds the profiles of the written request to the statistics of
+// their series.
+func (s *seriesStats) record(req *profilestorepb.WriteRawRequest, now time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, series := range req.Series {
+		ls := labelSetMap(series.Labels)
+		key := req.Tenant + labels.FromMap(ls).String()
+		st, ok := s.series[key]
+		if !ok {
+			st = &seriesStat{tenant: req.Tenant, labels: ls, firstWrite: now}
+			s.series[key] = st
+		}
+		for _, sample := range series.Samples {
+			st.profiles++
+			st.bytes += int64(len(sample.RawProfile))
+		}
+		st.lastWrite = now
+	}
+	if now.After(s.nextGC) {
+		s.gc(now)
+		s.nextGC = now.Add(s.ttl)
+	}
+}
+
+// summary returns the statistics of the active series, with the limit
+// series ordered by the given field first.
+func (s *seriesStats) summary(now time.Time, order profilestorepb.SeriesStatsRequest_Order, limit int) *profilestorepb.SeriesStatsResponse {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.gc(now)
+	top := make([]*seriesStat, 0, len(s.series))
+	res := &profilestorepb.SeriesStatsResponse{
+		Series: int64(len(s.series)),
+	}
+	for _, st := range s.series {
+		res.Profiles += st.profiles
+		res.Bytes += st.bytes
+		top = append(top, st)
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		a, b := top[i], top[j]
+		if order == profilestorepb.SeriesStatsRequest_ORDER_PROFILES && a.profiles != b.profiles {
+			return a.profiles > b.profiles
+		}
+		if a.bytes != b.bytes {
+			return a.bytes > b.bytes
+		}
+		return a.lastWrite.After(b.lastWrite)
+	})
+	if limit > 0 && len(top) > limit {
+		top = top[:limit]
+	}
+	res.Top = make([]*profilestorepb.SeriesStats, 0, len(top))
+	for _, st := range top {
+		res.Top = append(res.Top, &profilestorepb.SeriesStats{
+			Tenant:     st.tenant,
+			Labels:     messageLabelSet(st.labels),
+			Profiles:   st.profiles,
+			Bytes:      st.bytes,
+			FirstWrite: timestamppb.New(st.firstWrite),
+			LastWrite:  timestamppb.New(st.lastWrite),
+		})
+	}
+	return res
+}
+
+func (s *seriesStats) gc(now time.Time) {
+	for k, st := range s.series {
+		if now.Sub(st.lastWrite) >= s.ttl {
+			delete(s.series, k)
+		}
+	}
+}
+
+// SeriesStats returns the statistics of the series written to recently, the
+// ones written the most to first.
+func (s *ProfileColumnStore) SeriesStats(_ context.Context, req *profilestorepb.SeriesStatsRequest) (*profilestorepb.SeriesStatsResponse, error) {
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultSeriesStatsLimit
+	}
+	return s.seriesStats.summary(time.Now(), req.Order, limit), nil
+}
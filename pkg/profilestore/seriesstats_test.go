@@ -0,0 +1,66 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+func TestSeriesStats(t *testing.T) {
+	st := newSeriesStats(time.Minute)
+	now := time.Now()
+
+	st.record(&profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{
+			rawSeries("aaaa", "__name__", "cpu", "job", "a"),
+			rawSeries("bb", "__name__", "cpu", "job", "b"),
+		},
+	}, now.Add(-2*time.Second))
+	st.record(&profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{
+			rawSeries("bb", "__name__", "cpu", "job", "b"),
+			rawSeries("bb", "__name__", "cpu", "job", "b"),
+		},
+	}, now.Add(-time.Second))
+	st.record(&profilestorepb.WriteRawRequest{
+		Series: []*profilestorepb.RawProfileSeries{
+			rawSeries("cccccccc", "__name__", "cpu", "job", "c"),
+		},
+	}, now.Add(-2*time.Minute))
+
+	// Expired series are not counted.
+	summary := st.summary(now, profilestorepb.SeriesStatsRequest_ORDER_BYTES_UNSPECIFIED, 0)
+	require.Equal(t, int64(2), summary.Series)
+	require.Equal(t, int64(4), summary.Profiles)
+	require.Equal(t, int64(10), summary.Bytes)
+	require.Equal(t, "b", labelSetMap(summary.Top[0].Labels)["job"])
+	require.Equal(t, int64(3), summary.Top[0].Profiles)
+	require.True(t, now.Add(-2*time.Second).Equal(summary.Top[0].FirstWrite.AsTime()))
+	require.True(t, now.Add(-time.Second).Equal(summary.Top[0].LastWrite.AsTime()))
+
+	s := &ProfileColumnStore{seriesStats: st}
+	res, err := s.SeriesStats(context.Background(), &profilestorepb.SeriesStatsRequest{
+		Order: profilestorepb.SeriesStatsRequest_ORDER_PROFILES,
+		Limit: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Top, 1)
+	require.Equal(t, "b", labelSetMap(res.Top[0].Labels)["job"])
+}
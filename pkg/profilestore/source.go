@@ -0,0 +1,202 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+// Message is a profile received from a message broker.
+type Message struct {
+	// ID identifies the message across redeliveries, eg. its topic,
+	// partition and offset. Messages with an ID that was consumed recently
+	// are acknowledged without being written again. Empty disables this.
+	ID string
+	// Labels must contain the profile name in the "__name__" label.
+	Labels map[string]string
+	Pprof  []byte
+	// Ack acknowledges the message once it is stored or rejected for good,
+	// eg. by committing its offset.
+	Ack func(ctx context.Context) error
+}
+
+// MessageSource receives profiles from a message broker, eg. a Kafka topic
+// or a NATS JetStream consumer.
+type MessageSource interface {
+	// Receive blocks until the next message is available or the context is
+	// canceled.
+	Receive(ctx context.Context) (Message, error)
+}
+
+const (
+	minConsumeBackoff = 100 * time.Millisecond
+	maxConsumeBackoff = 30 * time.Second
+
+	// consumedIDs is the number of message IDs remembered per source to
+	// drop redeliveries of consumed messages.
+	consumedIDs = 10000
+)
+
+// consumeMetrics count the messages of every source. They are shared by the
+// sources, which are told apart by the source label.
+type consumeMetrics struct {
+	messages *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+}
+
+func newConsumeMetrics(reg prometheus.Registerer) *consumeMetrics {
+	return &consumeMetrics{
+		messages: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_source_messages_total",
+			Help: "Total number of messages consumed from message sources, by whether they were written, rejected or dropped as a redelivery.",
+		}, []string{"source", "result"}),
+		retries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_source_write_retries_total",
+			Help: "Total number of retried writes of messages consumed from message sources.",
+		}, []string{"source"}),
+	}
+}
+
+// Consume writes the profiles received from the source until the context is
+// canceled. A message is acknowledged only after it was written, so that
+// profiles are written at least once: writes failing temporarily, eg. while
+// the memory limit is exceeded, are retried with a backoff, and messages are
+// redelivered if the server stops before acknowledging them. Redeliveries of
+// recently consumed messages are recognized by their ID, other redelivered
+// profiles are dropped by the deduplication, see WithDeduplication. Invalid
+// profiles are acknowledged without being written, so that they don't block
+// the source.
+func (s *ProfileColumnStore) Consume(ctx context.Context, name string, src MessageSource) error {
+	if s.dedup == nil {
+		level.Warn(s.logger).Log("msg", "deduplication is disabled, redelivered messages are written more than once", "source", name)
+	}
+
+	consumed := newRecentIDs(consumedIDs)
+	for {
+		m, err := src.Receive(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result := "duplicate"
+		if m.ID == "" || !consumed.contains(m.ID) {
+			result, err = s.consumeMessage(ctx, name, m)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				level.Warn(s.logger).Log("msg", "rejected profile of message source", "source", name, "err", err)
+			}
+			if m.ID != "" {
+				consumed.add(m.ID)
+			}
+		}
+		s.consume.messages.WithLabelValues(name, result).Inc()
+
+		if m.Ack != nil {
+			if err := m.Ack(ctx); err != nil {
+				level.Warn(s.logger).Log("msg", "failed to acknowledge message", "source", name, "err", err)
+			}
+		}
+	}
+}
+
+// consumeMessage writes the message, retrying until the write succeeds, is
+// rejected for good or the context is canceled.
+func (s *ProfileColumnStore) consumeMessage(ctx context.Context, name string, m Message) (string, error) {
+	backoff := minConsumeBackoff
+	for {
+		// Every attempt gets a new request, the normalization decompresses
+		// the profiles of a request in place.
+		err := s.writeSeries(ctx, &profilestorepb.WriteRawRequest{
+			Series: []*profilestorepb.RawProfileSeries{{
+				Labels:  messageLabelSet(m.Labels),
+				Samples: []*profilestorepb.RawSample{{RawProfile: m.Pprof}},
+			}},
+		})
+		switch status.Code(err) {
+		case codes.OK:
+			return "written", nil
+		case codes.InvalidArgument, codes.OutOfRange:
+			return "rejected", err
+		}
+
+		s.consume.retries.WithLabelValues(name).Inc()
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConsumeBackoff)
+	}
+}
+
+func messageLabelSet(labels map[string]string) *profilestorepb.LabelSet {
+	ls := &profilestorepb.LabelSet{
+		Labels: make([]*profilestorepb.Label, 0, len(labels)),
+	}
+	for name, value := range labels {
+		ls.Labels = append(ls.Labels, &profilestorepb.Label{Name: name, Value: value})
+	}
+	sort.Slice(ls.Labels, func(i, j int) bool {
+		return ls.Labels[i].Name < ls.Labels[j].Name
+	})
+	return ls
+}
+
+// recentIDs remembers the most recently added IDs, up to a maximum.
+type recentIDs struct {
+	max   int
+	order *list.List
+	ids   map[string]*list.Element
+}
+
+func newRecentIDs(max int) *recentIDs {
+	return &recentIDs{
+		max:   max,
+		order: list.New(),
+		ids:   make(map[string]*list.Element, max),
+	}
+}
+
+func (r *recentIDs) contains(id string) bool {
+	_, ok := r.ids[id]
+	return ok
+}
+
+func (r *recentIDs) add(id string) {
+	if _, ok := r.ids[id]; ok {
+		return
+	}
+	r.ids[id] = r.order.PushBack(id)
+	if r.order.Len() > r.max {
+		oldest := r.order.Front()
+		r.order.Remove(oldest)
+		delete(r.ids, oldest.Value.(string))
+	}
+}
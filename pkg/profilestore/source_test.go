@@ -0,0 +1,130 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// flakyTable fails the first insert.
+type flakyTable struct {
+	inserts int
+}
+
+func (t *flakyTable) InsertRecord(_ context.Context, _ arrow.Record) (uint64, error) {
+	t.inserts++
+	if t.inserts == 1 {
+		return 0, errors.New("unavailable")
+	}
+	return 0, nil
+}
+
+type fakeSource struct {
+	messages []Message
+	cancel   context.CancelFunc
+}
+
+func (s *fakeSource) Receive(ctx context.Context) (Message, error) {
+	if len(s.messages) == 0 {
+		s.cancel()
+		return Message{}, ctx.Err()
+	}
+	m := s.messages[0]
+	s.messages = s.messages[1:]
+	return m, nil
+}
+
+func TestConsume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+
+	table := &flakyTable{}
+	store := NewProfileColumnStore(
+		prometheus.NewRegistry(),
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		ingester.NewIngester(log.NewNopLogger(), table),
+		schema,
+		memory.DefaultAllocator,
+	)
+
+	content, err := os.ReadFile("../query/testdata/alloc_objects.pb.gz")
+	require.NoError(t, err)
+
+	var acked []string
+	ack := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			acked = append(acked, name)
+			return nil
+		}
+	}
+	src := &fakeSource{cancel: cancel, messages: []Message{{
+		ID:     "1",
+		Labels: map[string]string{"__name__": "memory", "job": "test"},
+		Pprof:  content,
+		Ack:    ack("valid"),
+	}, {
+		ID:     "2",
+		Labels: map[string]string{"__name__": "memory", "1invalid": "test"},
+		Pprof:  content,
+		Ack:    ack("invalid"),
+	}, {
+		ID:     "1",
+		Labels: map[string]string{"__name__": "memory", "job": "test"},
+		Pprof:  content,
+		Ack:    ack("redelivered"),
+	}}}
+
+	require.NoError(t, store.Consume(ctx, "test", src))
+
+	// The failed write was retried before the message was acknowledged, the
+	// invalid and the redelivered message are acknowledged without being
+	// written.
+	require.Equal(t, 2, table.inserts)
+	require.Equal(t, []string{"valid", "invalid", "redelivered"}, acked)
+	require.Equal(t, 1.0, testutil.ToFloat64(store.consume.retries.WithLabelValues("test")))
+	require.Equal(t, 1.0, testutil.ToFloat64(store.consume.messages.WithLabelValues("test", "written")))
+	require.Equal(t, 1.0, testutil.ToFloat64(store.consume.messages.WithLabelValues("test", "rejected")))
+	require.Equal(t, 1.0, testutil.ToFloat64(store.consume.messages.WithLabelValues("test", "duplicate")))
+}
+
+func TestRecentIDs(t *testing.T) {
+	ids := newRecentIDs(2)
+	ids.add("a")
+	ids.add("b")
+	ids.add("a")
+	require.True(t, ids.contains("a"))
+
+	ids.add("c")
+	require.False(t, ids.contains("a"))
+	require.True(t, ids.contains("b"))
+	require.True(t, ids.contains("c"))
+}
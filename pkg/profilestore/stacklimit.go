@@ -0,0 +1,118 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	pprofpb "github.com/parca-dev/parca/gen/proto/go/google/pprof"
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// OtherStackFunction is the name of the function of the single frame
+// stack that the samples collapsed by the stack limit are aggregated into.
+const OtherStackFunction = "other"
+
+// otherStack is the stack the collapsed samples are aggregated into.
+var otherStack = [][]byte{profile.EncodePprofLocation(
+	&pprofpb.Location{Id: 1, Line: []*pprofpb.Line{{FunctionId: 1}}},
+	nil,
+	[]*pprofpb.Function{{Id: 1, Name: 1}},
+	[]string{"", OtherStackFunction},
+)}
+
+// stackLimiter limits the number of distinct stacks stored per profile, so
+// that a single target with a huge stack cardinality cannot blow up the
+// storage. The least significant samples of profiles exceeding the limit
+// are collapsed into one sample with the "other" stack, which keeps the
+// total of the profile.
+type stackLimiter struct {
+	maxStacks int
+
+	truncatedProfiles *prometheus.CounterVec
+	collapsedSamples  *prometheus.CounterVec
+}
+
+func newStackLimiter(reg prometheus.Registerer, maxStacks int) *stackLimiter {
+	return &stackLimiter{
+		maxStacks: maxStacks,
+		truncatedProfiles: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_stack_limit_truncated_profiles_total",
+			Help: "Total number of profiles exceeding the stack limit by target.",
+		}, []string{"job", "instance"}),
+		collapsedSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_profilestore_stack_limit_collapsed_samples_total",
+			Help: "Total number of samples collapsed into the other stack by target.",
+		}, []string{"job", "instance"}),
+	}
+}
+
+// apply collapses the least significant samples of the profiles exceeding
+// the limit, by the absolute value of the samples.
+func (l *stackLimiter) apply(req normalizer.NormalizedWriteRawRequest) {
+	if l.maxStacks <= 0 {
+		return
+	}
+
+	for _, series := range req.Series {
+		for _, sample := range series.Samples {
+			for _, p := range sample {
+				if len(p.Samples) <= l.maxStacks {
+					continue
+				}
+				collapsed := l.collapse(p)
+				job, instance := series.Labels["job"], series.Labels["instance"]
+				l.truncatedProfiles.WithLabelValues(job, instance).Inc()
+				l.collapsedSamples.WithLabelValues(job, instance).Add(float64(collapsed))
+			}
+		}
+	}
+}
+
+// collapse keeps the maxStacks-1 heaviest samples of the profile and
+// aggregates the remaining ones into the other stack. It returns the number
+// of collapsed samples.
+func (l *stackLimiter) collapse(p *normalizer.NormalizedProfile) int {
+	slices.SortStableFunc(p.Samples, func(a, b *normalizer.NormalizedSample) int {
+		wa, wb := abs(a.Value), abs(b.Value)
+		switch {
+		case wa > wb:
+			return -1
+		case wa < wb:
+			return 1
+		}
+		return 0
+	})
+
+	keep := max(l.maxStacks-1, 0)
+	other := &normalizer.NormalizedSample{Locations: otherStack}
+	for _, s := range p.Samples[keep:] {
+		other.Value += s.Value
+		other.DiffValue += s.DiffValue
+	}
+	collapsed := len(p.Samples) - keep
+	p.Samples = append(p.Samples[:keep], other)
+	return collapsed
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
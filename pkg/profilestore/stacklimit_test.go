@@ -0,0 +1,58 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func TestStackLimiter(t *testing.T) {
+	l := newStackLimiter(prometheus.NewRegistry(), 3)
+
+	stack := func(name string) [][]byte { return [][]byte{[]byte(name)} }
+	p := &normalizer.NormalizedProfile{Samples: []*normalizer.NormalizedSample{
+		{Locations: stack("a"), Value: 1},
+		{Locations: stack("b"), Value: -10},
+		{Locations: stack("c"), Value: 2},
+		{Locations: stack("d"), Value: 5},
+	}}
+	small := &normalizer.NormalizedProfile{Samples: []*normalizer.NormalizedSample{
+		{Locations: stack("a"), Value: 1},
+	}}
+	l.apply(normalizer.NormalizedWriteRawRequest{Series: []normalizer.Series{{
+		Labels:  map[string]string{"job": "api"},
+		Samples: [][]*normalizer.NormalizedProfile{{p, small}},
+	}}})
+
+	require.Len(t, p.Samples, 3)
+	require.Equal(t, stack("b"), p.Samples[0].Locations)
+	require.Equal(t, stack("d"), p.Samples[1].Locations)
+	require.Equal(t, int64(3), p.Samples[2].Value)
+	require.Len(t, small.Samples, 1)
+
+	_, lines := profile.DecodeSymbolizationInfo(p.Samples[2].Locations[0])
+	require.Equal(t, uint64(1), lines)
+	require.True(t, bytes.Contains(p.Samples[2].Locations[0], []byte(OtherStackFunction)))
+
+	require.Equal(t, 1.0, testutil.ToFloat64(l.truncatedProfiles.WithLabelValues("api", "")))
+	require.Equal(t, 2.0, testutil.ToFloat64(l.collapsedSamples.WithLabelValues("api", "")))
+}
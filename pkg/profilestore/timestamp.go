@@ -0,0 +1,163 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+)
+
+// TimestampPolicy determines which timestamp a stored profile gets.
+type TimestampPolicy string
+
+const (
+	// TimestampPolicyAgent uses the timestamp reported in the profile.
+	TimestampPolicyAgent TimestampPolicy = "agent"
+	// TimestampPolicyReceive uses the time the server received the profile.
+	TimestampPolicyReceive TimestampPolicy = "receive"
+	// TimestampPolicyScrapeStart uses the time the scrape of the profile
+	// started. Profiles that were pushed rather than scraped keep the
+	// timestamp reported in the profile.
+	TimestampPolicyScrapeStart TimestampPolicy = "scrape-start"
+	// TimestampPolicyCaptureStart uses the start of the capture window of
+	// the profile, which lasts the duration of the profile. Like the
+	// time_nanos of pprof profiles written by Go's runtime/pprof, the
+	// timestamp reported in the profile is the start of the window, so
+	// delays of the delivery don't shift it. Profiles without a reported
+	// timestamp are assumed to have ended when the server received them.
+	TimestampPolicyCaptureStart TimestampPolicy = "capture-start"
+	// TimestampPolicyCaptureMidpoint uses the midpoint of the capture window
+	// of the profile, see TimestampPolicyCaptureStart, eg. to line up a 30s
+	// CPU profile with metrics and traces of the same period. Profiles
+	// without a duration keep the start of the window.
+	TimestampPolicyCaptureMidpoint TimestampPolicy = "capture-midpoint"
+)
+
+// ScrapeStartMetadataKey is the gRPC metadata key that carries the scrape
+// start of the profiles of a WriteRaw request, in milliseconds since epoch.
+const ScrapeStartMetadataKey = "parca-scrape-start"
+
+type scrapeStartKey struct{}
+
+// ContextWithScrapeStart returns a context that carries the start of the
+// scrape the profiles written with it originate from.
+func ContextWithScrapeStart(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, scrapeStartKey{}, timestamp.FromTime(t))
+}
+
+// scrapeStartFromContext returns the scrape start in milliseconds since
+// epoch, either set by ContextWithScrapeStart for in-process writes or sent as
+// gRPC metadata.
+func scrapeStartFromContext(ctx context.Context) (int64, bool) {
+	if ts, ok := ctx.Value(scrapeStartKey{}).(int64); ok {
+		return ts, true
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	vals := md.Get(ScrapeStartMetadataKey)
+	if len(vals) == 0 {
+		return 0, false
+	}
+	ts, err := strconv.ParseInt(vals[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return ts, true
+}
+
+// outgoingScrapeStart forwards the scrape start of the context as gRPC
+// metadata.
+func outgoingScrapeStart(ctx context.Context) context.Context {
+	ts, ok := scrapeStartFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, ScrapeStartMetadataKey, strconv.FormatInt(ts, 10))
+}
+
+// timestamper assigns the timestamps of normalized profiles according to a
+// policy. Timestamps further in the future than the maximum skew relative to
+// the server's time are clamped, so that a client with a skewed clock can't
+// create samples far in the future.
+type timestamper struct {
+	policy  TimestampPolicy
+	maxSkew time.Duration
+
+	clamped prometheus.Counter
+}
+
+func newTimestamper(reg prometheus.Registerer, policy TimestampPolicy, maxSkew time.Duration) *timestamper {
+	return &timestamper{
+		policy:  policy,
+		maxSkew: maxSkew,
+		clamped: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_profilestore_clamped_timestamps_total",
+			Help: "Total number of profiles whose timestamp was clamped because it was too far in the future.",
+		}),
+	}
+}
+
+// captureStart returns the start of the capture window of a profile, the
+// timestamp reported in it, or the receive time less the duration of the
+// profile if it has none. Timestamps are in milliseconds and the duration in
+// nanoseconds.
+func captureStart(reported, received, duration int64) int64 {
+	if reported == 0 {
+		return received - time.Duration(duration).Milliseconds()
+	}
+	return reported
+}
+
+// apply sets the timestamps of the profiles of the request. The receive time
+// is given in milliseconds since epoch.
+func (t *timestamper) apply(ctx context.Context, req normalizer.NormalizedWriteRawRequest, received int64) {
+	var override int64
+	switch t.policy {
+	case TimestampPolicyReceive:
+		override = received
+	case TimestampPolicyScrapeStart:
+		override, _ = scrapeStartFromContext(ctx)
+	}
+
+	maxTimestamp := received + t.maxSkew.Milliseconds()
+	for _, series := range req.Series {
+		for _, sample := range series.Samples {
+			for _, p := range sample {
+				switch {
+				case override != 0:
+					p.Meta.Timestamp = override
+				case t.policy == TimestampPolicyCaptureStart:
+					p.Meta.Timestamp = captureStart(p.Meta.Timestamp, received, p.Meta.Duration)
+				case t.policy == TimestampPolicyCaptureMidpoint:
+					p.Meta.Timestamp = captureStart(p.Meta.Timestamp, received, p.Meta.Duration) + (time.Duration(p.Meta.Duration) / 2).Milliseconds()
+				}
+				if t.maxSkew > 0 && p.Meta.Timestamp > maxTimestamp {
+					p.Meta.Timestamp = maxTimestamp
+					t.clamped.Inc()
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func normalizedRequest(timestamps ...int64) normalizer.NormalizedWriteRawRequest {
+	profiles := make([]*normalizer.NormalizedProfile, 0, len(timestamps))
+	for _, ts := range timestamps {
+		profiles = append(profiles, &normalizer.NormalizedProfile{Meta: profile.Meta{Timestamp: ts}})
+	}
+	return normalizer.NormalizedWriteRawRequest{
+		Series: []normalizer.Series{{Samples: [][]*normalizer.NormalizedProfile{profiles}}},
+	}
+}
+
+func timestamps(req normalizer.NormalizedWriteRawRequest) []int64 {
+	var res []int64
+	for _, p := range req.Series[0].Samples[0] {
+		res = append(res, p.Meta.Timestamp)
+	}
+	return res
+}
+
+func TestTimestamper(t *testing.T) {
+	const received = 1_000_000
+
+	cases := []struct {
+		name     string
+		policy   TimestampPolicy
+		maxSkew  time.Duration
+		ctx      context.Context
+		expected []int64
+	}{{
+		name:     "agent",
+		policy:   TimestampPolicyAgent,
+		ctx:      context.Background(),
+		expected: []int64{900_000, 2_000_000},
+	}, {
+		name:     "agent with clamping",
+		policy:   TimestampPolicyAgent,
+		maxSkew:  time.Minute,
+		ctx:      context.Background(),
+		expected: []int64{900_000, 1_060_000},
+	}, {
+		name:     "receive",
+		policy:   TimestampPolicyReceive,
+		maxSkew:  time.Minute,
+		ctx:      context.Background(),
+		expected: []int64{received, received},
+	}, {
+		name:     "scrape start",
+		policy:   TimestampPolicyScrapeStart,
+		ctx:      ContextWithScrapeStart(context.Background(), time.UnixMilli(990_000)),
+		expected: []int64{990_000, 990_000},
+	}, {
+		name:     "scrape start from metadata",
+		policy:   TimestampPolicyScrapeStart,
+		ctx:      metadata.NewIncomingContext(context.Background(), metadata.Pairs(ScrapeStartMetadataKey, "980000")),
+		expected: []int64{980_000, 980_000},
+	}, {
+		name:     "scrape start of pushed profile",
+		policy:   TimestampPolicyScrapeStart,
+		maxSkew:  time.Minute,
+		ctx:      context.Background(),
+		expected: []int64{900_000, 1_060_000},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := newTimestamper(prometheus.NewRegistry(), c.policy, c.maxSkew)
+			req := normalizedRequest(900_000, 2_000_000)
+			ts.apply(c.ctx, req, received)
+			require.Equal(t, c.expected, timestamps(req))
+		})
+	}
+}
+
+func TestTimestamperCaptureWindow(t *testing.T) {
+	const received = 1_000_000
+
+	newRequest := func(reported ...int64) normalizer.NormalizedWriteRawRequest {
+		req := normalizedRequest(reported...)
+		req.Series[0].Samples[0][0].Meta.Duration = (30 * time.Second).Nanoseconds()
+		return req
+	}
+
+	// The window starts at the reported timestamp, however late the
+	// profile is received.
+	req := newRequest(900_000, 900_000)
+	ts := newTimestamper(prometheus.NewRegistry(), TimestampPolicyCaptureStart, 0)
+	ts.apply(context.Background(), req, received)
+	require.Equal(t, []int64{900_000, 900_000}, timestamps(req))
+
+	req = newRequest(900_000, 900_000)
+	ts = newTimestamper(prometheus.NewRegistry(), TimestampPolicyCaptureMidpoint, 0)
+	ts.apply(context.Background(), req, received)
+	require.Equal(t, []int64{915_000, 900_000}, timestamps(req))
+
+	// Without a reported timestamp the window ends when it was received.
+	req = newRequest(0, 0)
+	ts.apply(context.Background(), req, received)
+	require.Equal(t, []int64{985_000, received}, timestamps(req))
+
+	req = newRequest(0, 0)
+	ts = newTimestamper(prometheus.NewRegistry(), TimestampPolicyCaptureStart, 0)
+	ts.apply(context.Background(), req, received)
+	require.Equal(t, []int64{970_000, received}, timestamps(req))
+}
+
+func TestTimestamperCaptureWindowOfGoProfile(t *testing.T) {
+	// Go's runtime/pprof reports the start of a CPU profile as its time.
+	start := time.Now()
+	var buf bytes.Buffer
+	require.NoError(t, pprof.StartCPUProfile(&buf))
+	time.Sleep(200 * time.Millisecond)
+	pprof.StopCPUProfile()
+	end := time.Now()
+
+	p, err := pprofprofile.Parse(&buf)
+	require.NoError(t, err)
+	newRequest := func() normalizer.NormalizedWriteRawRequest {
+		req := normalizedRequest(p.TimeNanos / time.Millisecond.Nanoseconds())
+		req.Series[0].Samples[0][0].Meta.Duration = p.DurationNanos
+		return req
+	}
+
+	req := newRequest()
+	ts := newTimestamper(prometheus.NewRegistry(), TimestampPolicyCaptureStart, 0)
+	ts.apply(context.Background(), req, end.UnixMilli())
+	captureStart := time.UnixMilli(timestamps(req)[0])
+	require.False(t, captureStart.Before(start.Truncate(time.Millisecond)))
+	require.Less(t, captureStart.Sub(start), 100*time.Millisecond)
+
+	req = newRequest()
+	ts = newTimestamper(prometheus.NewRegistry(), TimestampPolicyCaptureMidpoint, 0)
+	ts.apply(context.Background(), req, end.UnixMilli())
+	midpoint := time.UnixMilli(timestamps(req)[0])
+	require.InDelta(t, start.Add(end.Sub(start)/2).UnixMilli(), midpoint.UnixMilli(), float64((50 * time.Millisecond).Milliseconds()))
+}
+
+func TestTimestamperClampedMetric(t *testing.T) {
+	ts := newTimestamper(prometheus.NewRegistry(), TimestampPolicyAgent, time.Second)
+	ts.apply(context.Background(), normalizedRequest(1, 2_000, 3_000), 1_000)
+	require.Equal(t, 1.0, testutil.ToFloat64(ts.clamped))
+}
+
+func TestOutgoingScrapeStart(t *testing.T) {
+	ctx := outgoingScrapeStart(ContextWithScrapeStart(context.Background(), time.UnixMilli(1234)))
+	md, ok := metadata.FromOutgoingContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, []string{"1234"}, md.Get(ScrapeStartMetadataKey))
+}
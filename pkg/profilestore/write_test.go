@@ -0,0 +1,331 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	otelgrpcprofilingpb "go.opentelemetry.io/proto/otlp/collector/profiles/v1experimental"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	otelprofilingpb "go.opentelemetry.io/proto/otlp/profiles/v1experimental"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/normalizer"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+type rowsTable struct {
+	rows   int64
+	labels map[string]struct{}
+}
+
+func (t *rowsTable) InsertRecord(_ context.Context, r arrow.Record) (uint64, error) {
+	t.rows += r.NumRows()
+	for _, f := range r.Schema().Fields() {
+		if strings.HasPrefix(f.Name, profile.ColumnLabelsPrefix) {
+			t.labels[strings.TrimPrefix(f.Name, profile.ColumnLabelsPrefix)] = struct{}{}
+		}
+	}
+	return 0, nil
+}
+
+// pipelineStore is a store that deduplicates profiles and counts the series
+// passed to its middleware, which labels them, to check which parts of the
+// ingest pipeline a write API goes through.
+type pipelineStore struct {
+	*ProfileColumnStore
+	table  *rowsTable
+	series int
+}
+
+func newPipelineStore(t *testing.T) *pipelineStore {
+	t.Helper()
+
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+
+	s := &pipelineStore{table: &rowsTable{labels: map[string]struct{}{}}}
+	s.ProfileColumnStore = NewProfileColumnStore(
+		prometheus.NewRegistry(),
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		ingester.NewIngester(log.NewNopLogger(), s.table),
+		schema,
+		memory.DefaultAllocator,
+		WithDeduplication(time.Minute, 100),
+		WithIngestMiddlewares(IngestMiddlewareFunc(func(_ context.Context, _ string, req *normalizer.NormalizedWriteRawRequest) error {
+			s.series += len(req.Series)
+			for _, series := range req.Series {
+				series.Labels["enriched"] = "true"
+			}
+			return nil
+		})),
+	)
+	return s
+}
+
+// requireDeduplicated checks that the first of two identical writes was
+// ingested and the second one was dropped.
+func (s *pipelineStore) requireDeduplicated(t *testing.T, write func() error) {
+	t.Helper()
+
+	require.NoError(t, write())
+	require.Equal(t, 1, s.series)
+	require.Positive(t, s.table.rows)
+
+	rows := s.table.rows
+	require.NoError(t, write())
+	require.Equal(t, 1, s.series)
+	require.Equal(t, rows, s.table.rows)
+}
+
+// requireMiddlewares checks that the profiles of a write were passed to the
+// middleware and ingested as it changed them. Identical writes are not
+// deduplicated, as only raw writes are admitted.
+func (s *pipelineStore) requireMiddlewares(t *testing.T, write func() error) {
+	t.Helper()
+
+	require.NoError(t, write())
+	require.Equal(t, 1, s.series)
+	require.Positive(t, s.table.rows)
+	require.Contains(t, s.table.labels, "enriched")
+
+	rows := s.table.rows
+	require.NoError(t, write())
+	require.Equal(t, 2, s.series)
+	require.Equal(t, 2*rows, s.table.rows)
+}
+
+func TestWriteRawPipeline(t *testing.T) {
+	ctx := context.Background()
+	s := newPipelineStore(t)
+
+	content, err := os.ReadFile("../query/testdata/alloc_objects.pb.gz")
+	require.NoError(t, err)
+
+	s.requireDeduplicated(t, func() error {
+		_, err := s.WriteRaw(ctx, &profilestorepb.WriteRawRequest{
+			Series: []*profilestorepb.RawProfileSeries{{
+				Labels: &profilestorepb.LabelSet{
+					Labels: []*profilestorepb.Label{
+						{Name: "__name__", Value: "memory"},
+						{Name: "job", Value: "test"},
+					},
+				},
+				Samples: []*profilestorepb.RawSample{{RawProfile: bytes.Clone(content)}},
+			}},
+		})
+		return err
+	})
+}
+
+type writeServer struct {
+	grpc.ServerStream
+	ctx  context.Context
+	reqs []*profilestorepb.WriteRequest
+}
+
+func (s *writeServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *writeServer) Recv() (*profilestorepb.WriteRequest, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *writeServer) Send(*profilestorepb.WriteResponse) error {
+	return nil
+}
+
+func ipcRecord(t *testing.T, r arrow.Record) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := ipc.NewWriter(buf, ipc.WithSchema(r.Schema()))
+	require.NoError(t, w.Write(r))
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// arrowWrite returns the requests of an Arrow write of a single sample with a
+// single unsymbolized location and label, the sample record followed by the
+// locations record of its stack.
+func arrowWrite(t *testing.T, ts time.Time, label string) []*profilestorepb.WriteRequest {
+	t.Helper()
+
+	mem := memory.DefaultAllocator
+	md := arrow.NewMetadata([]string{normalizer.MetadataSchemaVersion}, []string{normalizer.MetadataSchemaVersionV1})
+	dict := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.Binary}
+	reeDict := arrow.RunEndEncodedOf(arrow.PrimitiveTypes.Int32, dict)
+	reeInt64 := arrow.RunEndEncodedOf(arrow.PrimitiveTypes.Int32, arrow.PrimitiveTypes.Int64)
+	reeUint64 := arrow.RunEndEncodedOf(arrow.PrimitiveTypes.Int32, arrow.PrimitiveTypes.Uint64)
+
+	appendREE := func(b array.Builder, v any) {
+		ree := b.(*array.RunEndEncodedBuilder)
+		ree.Append(1)
+		switch vb := ree.ValueBuilder().(type) {
+		case *array.BinaryDictionaryBuilder:
+			require.NoError(t, vb.AppendString(v.(string)))
+		case *array.Int64Builder:
+			vb.Append(v.(int64))
+		case *array.Uint64Builder:
+			vb.Append(v.(uint64))
+		}
+	}
+
+	sb := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{
+		{Name: profile.ColumnLabelsPrefix + label, Type: reeDict},
+		{Name: "producer", Type: reeDict},
+		{Name: profile.ColumnSampleType, Type: reeDict},
+		{Name: profile.ColumnSampleUnit, Type: reeDict},
+		{Name: profile.ColumnPeriodType, Type: reeDict},
+		{Name: profile.ColumnPeriodUnit, Type: reeDict},
+		{Name: "stacktrace_id", Type: reeDict},
+		{Name: profile.ColumnTimestamp, Type: reeInt64},
+		{Name: profile.ColumnDuration, Type: reeInt64},
+		{Name: profile.ColumnPeriod, Type: reeInt64},
+		{Name: profile.ColumnValue, Type: arrow.PrimitiveTypes.Int64},
+	}, &md))
+	defer sb.Release()
+	for i, v := range []any{"test", "parca_agent", "samples", "count", "cpu", "nanoseconds", "stack", ts.UnixNano(), time.Second.Nanoseconds(), int64(52631578)} {
+		appendREE(sb.Field(i), v)
+	}
+	sb.Field(10).(*array.Int64Builder).Append(3)
+	samples := sb.NewRecord()
+	defer samples.Release()
+
+	lineType := arrow.StructOf(
+		arrow.Field{Name: "line", Type: arrow.PrimitiveTypes.Int64},
+		arrow.Field{Name: "function_name", Type: dict},
+		arrow.Field{Name: "function_system_name", Type: dict},
+		arrow.Field{Name: "function_filename", Type: reeDict},
+		arrow.Field{Name: "function_start_line", Type: arrow.PrimitiveTypes.Int64},
+	)
+	locationType := arrow.StructOf(
+		arrow.Field{Name: "address", Type: arrow.PrimitiveTypes.Uint64},
+		arrow.Field{Name: "frame_type", Type: reeDict},
+		arrow.Field{Name: "mapping_start", Type: reeUint64},
+		arrow.Field{Name: "mapping_limit", Type: reeUint64},
+		arrow.Field{Name: "mapping_offset", Type: reeUint64},
+		arrow.Field{Name: "mapping_file", Type: reeDict},
+		arrow.Field{Name: "mapping_build_id", Type: reeDict},
+		arrow.Field{Name: "lines", Type: arrow.ListOf(lineType)},
+	)
+	lb := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{
+		{Name: "stacktrace_id", Type: arrow.BinaryTypes.Binary},
+		{Name: "locations", Type: arrow.ListOf(locationType)},
+		{Name: "is_complete", Type: arrow.FixedWidthTypes.Boolean},
+	}, &md))
+	defer lb.Release()
+	lb.Field(0).(*array.BinaryBuilder).AppendString("stack")
+	locs := lb.Field(1).(*array.ListBuilder)
+	locs.Append(true)
+	loc := locs.ValueBuilder().(*array.StructBuilder)
+	loc.Append(true)
+	loc.FieldBuilder(0).(*array.Uint64Builder).Append(0x1234)
+	for i, v := range []any{"native", uint64(0x1000), uint64(0x2000), uint64(0), "/bin/test", "f0e1d2c3"} {
+		appendREE(loc.FieldBuilder(i+1), v)
+	}
+	loc.FieldBuilder(7).(*array.ListBuilder).Append(true)
+	lb.Field(2).(*array.BooleanBuilder).Append(true)
+	locations := lb.NewRecord()
+	defer locations.Release()
+
+	return []*profilestorepb.WriteRequest{
+		{Record: ipcRecord(t, samples)},
+		{Record: ipcRecord(t, locations)},
+	}
+}
+
+func TestArrowWritePipeline(t *testing.T) {
+	ctx := context.Background()
+	s := newPipelineStore(t)
+
+	ts := time.Now()
+	// Labels of Arrow writes are validated by their conversion only, which
+	// accepts reserved names.
+	s.requireMiddlewares(t, func() error {
+		return s.Write(&writeServer{ctx: ctx, reqs: arrowWrite(t, ts, "__job__")})
+	})
+	require.Contains(t, s.table.labels, "__job__")
+}
+
+func otlpExport(ts time.Time) *otelgrpcprofilingpb.ExportProfilesServiceRequest {
+	return &otelgrpcprofilingpb.ExportProfilesServiceRequest{
+		ResourceProfiles: []*otelprofilingpb.ResourceProfiles{{
+			Resource: &resourcev1.Resource{
+				Attributes: []*commonv1.KeyValue{{
+					Key:   "job",
+					Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "test"}},
+				}},
+			},
+			ScopeProfiles: []*otelprofilingpb.ScopeProfiles{{
+				Scope: &commonv1.InstrumentationScope{Name: "parca_agent"},
+				Profiles: []*otelprofilingpb.ProfileContainer{{
+					Profile: &otelprofilingpb.Profile{
+						StringTable: []string{"", "samples", "count", "cpu", "nanoseconds", "main"},
+						SampleType: []*otelprofilingpb.ValueType{{
+							Type:                   1,
+							Unit:                   2,
+							AggregationTemporality: otelprofilingpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+						}},
+						PeriodType:    &otelprofilingpb.ValueType{Type: 3, Unit: 4},
+						Period:        52631578,
+						TimeNanos:     ts.UnixNano(),
+						DurationNanos: time.Second.Nanoseconds(),
+						Function:      []*otelprofilingpb.Function{{Id: 1, Name: 5}},
+						Location: []*otelprofilingpb.Location{{
+							Id:   1,
+							Line: []*otelprofilingpb.Line{{FunctionIndex: 1, Line: 10}},
+						}},
+						Sample: []*otelprofilingpb.Sample{{LocationsLength: 1, Value: []int64{3}}},
+					},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestExportPipeline(t *testing.T) {
+	ctx := context.Background()
+	s := newPipelineStore(t)
+
+	ts := time.Now()
+	s.requireMiddlewares(t, func() error {
+		_, err := s.Export(ctx, otlpExport(ts))
+		return err
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+// Stages of the write path timed by the write stage histograms.
+const (
+	// StageAdmission admits the profiles, eg. by the memory and series
+	// creation limits, deduplication and the profile size limit.
+	StageAdmission = "admission"
+	// StageNormalize decodes the pprof profiles and normalizes them.
+	StageNormalize = "normalize"
+	// StageFilter assigns timestamps and applies the time bounds, sampling
+	// and the stack limit.
+	StageFilter = "filter"
+	// StageMiddlewares runs the ingest middlewares.
+	StageMiddlewares = "middlewares"
+	// StageConvert converts the profiles to an Arrow record.
+	StageConvert = "convert"
+	// StageIngest appends the record to the storage.
+	StageIngest = "ingest"
+	// StageMetadata stores the metadata and raw exemplars of the profiles.
+	StageMetadata = "metadata"
+)
+
+// mixedProfileTypes is the profile type label of writes of several
+// profile types.
+const mixedProfileTypes = "mixed"
+
+// otherProfileTypes is the profile type label of writes of a profile type
+// that isn't known. The profile name is chosen by the clients, so only the
+// known ones are used as label values to bound the number of series.
+const otherProfileTypes = "other"
+
+// knownProfileTypes are the profile names of the Parca Agent and of the
+// default pprof endpoints scraped by Parca.
+var knownProfileTypes = map[string]struct{}{
+	"parca_agent":     {},
+	"parca_agent_cpu": {},
+	"process_cpu":     {},
+	"memory":          {},
+	"block":           {},
+	"goroutine":       {},
+	"mutex":           {},
+	"fgprof":          {},
+}
+
+// writeStages times the stages of the write path by profile type, so that
+// regressions of the ingest latency can be attributed to a stage.
+type writeStages struct {
+	duration *prometheus.HistogramVec
+}
+
+func newWriteStages(reg prometheus.Registerer) *writeStages {
+	return &writeStages{
+		duration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "parca_profilestore_write_stage_duration_seconds",
+			Help:    "Duration of the stages of writing profiles by profile type. Writes of several profile types have the mixed type, writes of unknown profile types the other type.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"stage", "profile_type"}),
+	}
+}
+
+// stageTimer times consecutive stages of a write.
+type stageTimer struct {
+	stages      *writeStages
+	profileType string
+	last        time.Time
+}
+
+// start starts timing the first stage of the write.
+func (s *writeStages) start(req *profilestorepb.WriteRawRequest) *stageTimer {
+	return &stageTimer{
+		stages:      s,
+		profileType: requestProfileType(req),
+		last:        time.Now(),
+	}
+}
+
+// done observes the duration of the stage since the previous one was done
+// and starts timing the next stage.
+func (t *stageTimer) done(stage string) {
+	now := time.Now()
+	t.stages.duration.WithLabelValues(stage, t.profileType).Observe(now.Sub(t.last).Seconds())
+	t.last = now
+}
+
+// requestProfileType returns the profile name shared by the series of the
+// request, the mixed type if they differ or the other type if it isn't known.
+func requestProfileType(req *profilestorepb.WriteRawRequest) string {
+	name := ""
+	for _, series := range req.Series {
+		n := labelValue(series.Labels, model.MetricNameLabel)
+		if name != "" && n != name {
+			return mixedProfileTypes
+		}
+		name = n
+	}
+	if _, ok := knownProfileTypes[name]; !ok {
+		return otherProfileTypes
+	}
+	return name
+}
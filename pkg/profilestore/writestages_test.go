@@ -0,0 +1,51 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profilestore
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+)
+
+func TestWriteStages(t *testing.T) {
+	stages := newWriteStages(prometheus.NewRegistry())
+
+	timer := stages.start(&profilestorepb.WriteRawRequest{Series: []*profilestorepb.RawProfileSeries{
+		rawSeries("a", "__name__", "memory", "job", "a"),
+		rawSeries("b", "__name__", "memory", "job", "b"),
+	}})
+	timer.done(StageNormalize)
+	timer.done(StageIngest)
+
+	mixed := stages.start(&profilestorepb.WriteRawRequest{Series: []*profilestorepb.RawProfileSeries{
+		rawSeries("a", "__name__", "memory"),
+		rawSeries("b", "__name__", "cpu"),
+	}})
+	mixed.done(StageNormalize)
+
+	unknown := stages.start(&profilestorepb.WriteRawRequest{Series: []*profilestorepb.RawProfileSeries{
+		rawSeries("a", "__name__", "my_custom_profile"),
+	}})
+	unknown.done(StageNormalize)
+
+	require.Equal(t, 4, testutil.CollectAndCount(stages.duration))
+	require.Equal(t, "memory", timer.profileType)
+	require.Equal(t, mixedProfileTypes, mixed.profileType)
+	require.Equal(t, otherProfileTypes, unknown.profileType)
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ActiveQueriesFile is the name of the file in flight queries are
+	// tracked in.
+	ActiveQueriesFile = "queries.active"
+
+	// DefaultActiveQuerySlots is the default number of queries that can be
+	// tracked at once.
+	DefaultActiveQuerySlots = 64
+
+	// activeQuerySlotSize is the size of a slot of the active queries file.
+	// Requests that do not fit are truncated.
+	activeQuerySlotSize = 4096
+)
+
+// ActiveQuery is a query that was in flight.
+type ActiveQuery struct {
+	Method  string    `json:"method"`
+	Request string    `json:"request"`
+	Time    time.Time `json:"time"`
+}
+
+// ActiveQueryTracker records the queries in flight in a file of fixed size
+// slots. The file is written without syncing, as it only needs to survive a
+// crash of the process, eg. when it is killed for running out of memory,
+// not of the machine. When the tracker is opened again after a crash, the
+// queries left in the file are the ones that were running and likely caused
+// it.
+type ActiveQueryTracker struct {
+	logger log.Logger
+	file   *os.File
+
+	mtx  sync.Mutex
+	free []int
+
+	active    prometheus.Gauge
+	untracked prometheus.Counter
+}
+
+// NewActiveQueryTracker opens the active queries file in the directory,
+// logs the queries that were still in flight when the file was last used
+// and clears it to track up to slots queries at once.
+func NewActiveQueryTracker(logger log.Logger, reg prometheus.Registerer, dir string, slots int) (*ActiveQueryTracker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create active queries directory: %w", err)
+	}
+
+	path := filepath.Join(dir, ActiveQueriesFile)
+	leftover, err := ReadActiveQueries(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		level.Warn(logger).Log("msg", "failed to read queries active before last shutdown", "err", err)
+	}
+	for _, q := range leftover {
+		level.Warn(logger).Log(
+			"msg", "query was in flight when Parca last stopped, it may have caused a crash",
+			"method", q.Method,
+			"request", q.Request,
+			"started", q.Time,
+		)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open active queries file: %w", err)
+	}
+	if err := f.Truncate(int64(slots * activeQuerySlotSize)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("size active queries file: %w", err)
+	}
+
+	t := &ActiveQueryTracker{
+		logger: logger,
+		file:   f,
+		free:   make([]int, 0, slots),
+		active: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "parca_query_active_queries",
+			Help: "Number of queries in flight tracked in the active queries file.",
+		}),
+		untracked: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_query_untracked_queries_total",
+			Help: "Total number of queries that were not tracked because all slots of the active queries file were in use.",
+		}),
+	}
+	for i := slots - 1; i >= 0; i-- {
+		t.free = append(t.free, i)
+	}
+	return t, nil
+}
+
+// ReadActiveQueries returns the queries recorded in an active queries file.
+func ReadActiveQueries(path string) ([]ActiveQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		res  []ActiveQuery
+		slot = make([]byte, activeQuerySlotSize)
+	)
+	for {
+		_, err := io.ReadFull(f, slot)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return res, nil
+		}
+		if err != nil {
+			return res, fmt.Errorf("read active queries: %w", err)
+		}
+
+		b := bytes.TrimRight(slot, "\x00")
+		if len(b) == 0 {
+			continue
+		}
+		var q ActiveQuery
+		if err := json.Unmarshal(b, &q); err != nil {
+			// A slot may have been partially written when the process
+			// crashed.
+			continue
+		}
+		res = append(res, q)
+	}
+}
+
+// Insert records a query as in flight. It returns the slot to pass to
+// Delete once the query finished, or -1 if all slots are in use and the
+// query is not tracked.
+func (t *ActiveQueryTracker) Insert(q ActiveQuery) int {
+	t.mtx.Lock()
+	if len(t.free) == 0 {
+		t.mtx.Unlock()
+		t.untracked.Inc()
+		return -1
+	}
+	slot := t.free[len(t.free)-1]
+	t.free = t.free[:len(t.free)-1]
+	t.mtx.Unlock()
+
+	if err := t.write(slot, encodeActiveQuery(q)); err != nil {
+		level.Debug(t.logger).Log("msg", "failed to record active query", "err", err)
+	}
+	t.active.Inc()
+	return slot
+}
+
+// Delete clears the slot of a query that finished.
+func (t *ActiveQueryTracker) Delete(slot int) {
+	if slot < 0 {
+		return
+	}
+
+	if err := t.write(slot, nil); err != nil {
+		level.Debug(t.logger).Log("msg", "failed to clear active query", "err", err)
+	}
+	t.active.Dec()
+
+	t.mtx.Lock()
+	t.free = append(t.free, slot)
+	t.mtx.Unlock()
+}
+
+func (t *ActiveQueryTracker) write(slot int, b []byte) error {
+	buf := make([]byte, activeQuerySlotSize)
+	copy(buf, b)
+	_, err := t.file.WriteAt(buf, int64(slot*activeQuerySlotSize))
+	return err
+}
+
+// encodeActiveQuery encodes the query to fit a slot, truncating the request
+// if necessary.
+func encodeActiveQuery(q ActiveQuery) []byte {
+	for {
+		b, err := json.Marshal(q)
+		if err != nil {
+			return nil
+		}
+		if len(b) <= activeQuerySlotSize || q.Request == "" {
+			return b
+		}
+		// Escaping may grow the request, so it is cut by the excess and
+		// encoded again until it fits.
+		cut := len(q.Request) - (len(b) - activeQuerySlotSize) - len("...")
+		if cut <= 0 {
+			q.Request = ""
+			continue
+		}
+		q.Request = strings.ToValidUTF8(q.Request[:cut], "") + "..."
+	}
+}
+
+// Close closes the active queries file. The queries still recorded in it
+// are not reported when it is opened again.
+func (t *ActiveQueryTracker) Close() error {
+	if err := t.file.Truncate(0); err != nil {
+		t.file.Close()
+		return fmt.Errorf("clear active queries file: %w", err)
+	}
+	return t.file.Close()
+}
+
+// UnaryServerInterceptor tracks the query service requests while they are
+// in flight.
+func (t *ActiveQueryTracker) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, queryMethodPrefix) {
+			return handler(ctx, req)
+		}
+
+		q := ActiveQuery{
+			Method: info.FullMethod,
+			Time:   time.Now(),
+		}
+		if m, ok := req.(proto.Message); ok {
+			if b, err := protojson.Marshal(m); err == nil {
+				q.Request = string(b)
+			}
+		}
+
+		slot := t.Insert(q)
+		defer t.Delete(slot)
+
+		return handler(ctx, req)
+	}
+}
+
+// HandlerInterceptor tracks the requests of a query endpoint served over
+// HTTP while they are in flight.
+func (t *ActiveQueryTracker) HandlerInterceptor() HandlerInterceptor {
+	return func(method string, next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			slot := t.Insert(ActiveQuery{
+				Method:  method,
+				Request: string(httpRequestJSON(r)),
+				Time:    time.Now(),
+			})
+			defer t.Delete(slot)
+
+			next(w, r, pathParams)
+		}
+	}
+}
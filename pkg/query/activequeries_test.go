@@ -0,0 +1,57 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveQueryTracker(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ActiveQueriesFile)
+
+	tracker, err := NewActiveQueryTracker(log.NewNopLogger(), prometheus.NewRegistry(), dir, 2)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	a := tracker.Insert(ActiveQuery{Method: "QueryRange", Request: `{"query":"a"}`, Time: now})
+	tracker.Insert(ActiveQuery{Method: "Query", Request: strings.Repeat("b", 2*activeQuerySlotSize), Time: now})
+	require.Equal(t, -1, tracker.Insert(ActiveQuery{Method: "Query"}))
+	tracker.Delete(a)
+
+	// The file reflects the queries in flight, as it would after a crash.
+	queries, err := ReadActiveQueries(path)
+	require.NoError(t, err)
+	require.Len(t, queries, 1)
+	require.Equal(t, "Query", queries[0].Method)
+	require.True(t, strings.HasSuffix(queries[0].Request, "..."))
+	require.Equal(t, now, queries[0].Time)
+
+	// Opening the tracker again reports and clears the leftover queries.
+	require.NoError(t, tracker.file.Close())
+	tracker, err = NewActiveQueryTracker(log.NewNopLogger(), prometheus.NewRegistry(), dir, 2)
+	require.NoError(t, err)
+	queries, err = ReadActiveQueries(path)
+	require.NoError(t, err)
+	require.Empty(t, queries)
+
+	require.NoError(t, tracker.Close())
+}
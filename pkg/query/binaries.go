@@ -0,0 +1,149 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/debuginfo"
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+type BinaryQuerier interface {
+	Binaries(ctx context.Context, query string, start, end time.Time, serviceLabel string) ([]*parcacol.Binary, error)
+}
+
+type DebuginfoMetadata interface {
+	Fetch(ctx context.Context, buildID string, typ debuginfopb.DebuginfoType) (*debuginfopb.Debuginfo, error)
+}
+
+// BinaryCatalog lists the binaries observed in the mappings of stored profiles
+// together with the status of their debuginfo.
+// It implements the BinaryService of proto/parca/query/v1alpha1/query.proto.
+type BinaryCatalog struct {
+	pb.UnimplementedBinaryServiceServer
+
+	logger    log.Logger
+	querier   BinaryQuerier
+	debuginfo DebuginfoMetadata
+}
+
+func NewBinaryCatalog(logger log.Logger, querier BinaryQuerier, debuginfo DebuginfoMetadata) *BinaryCatalog {
+	return &BinaryCatalog{
+		logger:    logger,
+		querier:   querier,
+		debuginfo: debuginfo,
+	}
+}
+
+// Binaries returns the binaries observed in the profiles matching the query in
+// the requested time range, the last 24 hours by default.
+func (c *BinaryCatalog) Binaries(ctx context.Context, req *pb.BinariesRequest) (*pb.BinariesResponse, error) {
+	start, end := requestTimeRange(req.Start, req.End)
+	serviceLabel := req.ServiceLabel
+	if serviceLabel == "" {
+		serviceLabel = "job"
+	}
+
+	ctx, releaseSnapshot := readSnapshot(ctx, c.querier)
+	defer releaseSnapshot()
+
+	binaries, err := c.querier.Binaries(ctx, req.Query, start, end, serviceLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*pb.Binary, 0, len(binaries))
+	for _, b := range binaries {
+		res = append(res, &pb.Binary{
+			BuildId:         b.BuildID,
+			File:            b.File,
+			FirstSeen:       timestamppb.New(b.FirstSeen),
+			LastSeen:        timestamppb.New(b.LastSeen),
+			Services:        b.Services,
+			DebuginfoStatus: c.debuginfoStatus(ctx, b.BuildID),
+		})
+	}
+	return &pb.BinariesResponse{Binaries: res}, nil
+}
+
+func (c *BinaryCatalog) debuginfoStatus(ctx context.Context, buildID string) pb.Binary_DebuginfoStatus {
+	if buildID == "" || c.debuginfo == nil {
+		return pb.Binary_DEBUGINFO_STATUS_UNKNOWN_UNSPECIFIED
+	}
+
+	dbginfo, err := c.debuginfo.Fetch(ctx, buildID, debuginfopb.DebuginfoType_DEBUGINFO_TYPE_DEBUGINFO_UNSPECIFIED)
+	if err != nil {
+		if errors.Is(err, debuginfo.ErrMetadataNotFound) {
+			return pb.Binary_DEBUGINFO_STATUS_MISSING
+		}
+		level.Debug(c.logger).Log("msg", "failed to fetch debuginfo metadata", "build_id", buildID, "err", err)
+		return pb.Binary_DEBUGINFO_STATUS_UNKNOWN_UNSPECIFIED
+	}
+
+	if q := dbginfo.Quality; q != nil {
+		if q.NotValidElf || !(q.HasDwarf || q.HasGoPclntab || q.HasSymtab || q.HasDynsym) {
+			return pb.Binary_DEBUGINFO_STATUS_NO_SYMBOLS
+		}
+	}
+	if dbginfo.Source == debuginfopb.Debuginfo_SOURCE_UPLOAD && dbginfo.Upload.GetState() != debuginfopb.DebuginfoUpload_STATE_UPLOADED {
+		return pb.Binary_DEBUGINFO_STATUS_UPLOADING
+	}
+	return pb.Binary_DEBUGINFO_STATUS_AVAILABLE
+}
+
+// requestTimeRange returns the time range of a request with optional start
+// and end timestamps. The range defaults to the last 24 hours.
+func requestTimeRange(startTs, endTs *timestamppb.Timestamp) (time.Time, time.Time) {
+	end := time.Now()
+	if endTs != nil {
+		end = endTs.AsTime()
+	}
+	start := end.Add(-24 * time.Hour)
+	if startTs != nil {
+		start = startTs.AsTime()
+	}
+	return start, end
+}
+
+// parseTimeRangeParams parses optional RFC3339 start and end parameters. The
+// range defaults to the last 24 hours.
+func parseTimeRangeParams(startParam, endParam string) (time.Time, time.Time, error) {
+	end := time.Now()
+	if endParam != "" {
+		t, err := time.Parse(time.RFC3339, endParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end: %w", err)
+		}
+		end = t
+	}
+	start := end.Add(-24 * time.Hour)
+	if startParam != "" {
+		t, err := time.Parse(time.RFC3339, startParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start: %w", err)
+		}
+		start = t
+	}
+	return start, end, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/debuginfo"
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+type fakeBinaryQuerier struct {
+	binaries []*parcacol.Binary
+}
+
+func (q *fakeBinaryQuerier) Binaries(context.Context, string, time.Time, time.Time, string) ([]*parcacol.Binary, error) {
+	return q.binaries, nil
+}
+
+type fakeDebuginfoMetadata map[string]*debuginfopb.Debuginfo
+
+func (m fakeDebuginfoMetadata) Fetch(_ context.Context, buildID string, _ debuginfopb.DebuginfoType) (*debuginfopb.Debuginfo, error) {
+	d, ok := m[buildID]
+	if !ok {
+		return nil, debuginfo.ErrMetadataNotFound
+	}
+	return d, nil
+}
+
+func TestBinaryCatalog(t *testing.T) {
+	now := time.Unix(1700000000, 0).UTC()
+	catalog := NewBinaryCatalog(
+		log.NewNopLogger(),
+		&fakeBinaryQuerier{binaries: []*parcacol.Binary{
+			{BuildID: "a", File: "/usr/bin/a", FirstSeen: now.Add(-time.Hour), LastSeen: now, Services: []string{"api"}},
+			{BuildID: "b", File: "/usr/bin/b", FirstSeen: now, LastSeen: now},
+			{BuildID: "c", File: "/usr/bin/c", FirstSeen: now, LastSeen: now},
+			{BuildID: "d", File: "/usr/bin/d", FirstSeen: now, LastSeen: now},
+			{File: "[vdso]", FirstSeen: now, LastSeen: now},
+		}},
+		fakeDebuginfoMetadata{
+			"a": {
+				Source:  debuginfopb.Debuginfo_SOURCE_UPLOAD,
+				Upload:  &debuginfopb.DebuginfoUpload{State: debuginfopb.DebuginfoUpload_STATE_UPLOADED},
+				Quality: &debuginfopb.DebuginfoQuality{HasDwarf: true},
+			},
+			"b": {
+				Source: debuginfopb.Debuginfo_SOURCE_UPLOAD,
+				Upload: &debuginfopb.DebuginfoUpload{State: debuginfopb.DebuginfoUpload_STATE_UPLOADING},
+			},
+			"c": {
+				Source:  debuginfopb.Debuginfo_SOURCE_DEBUGINFOD,
+				Quality: &debuginfopb.DebuginfoQuality{NotValidElf: true},
+			},
+		},
+	)
+
+	res, err := catalog.Binaries(context.Background(), &pb.BinariesRequest{ServiceLabel: "job"})
+	require.NoError(t, err)
+	require.Len(t, res.Binaries, 5)
+
+	statuses := map[string]pb.Binary_DebuginfoStatus{}
+	for _, b := range res.Binaries {
+		statuses[b.File] = b.DebuginfoStatus
+	}
+	require.Equal(t, map[string]pb.Binary_DebuginfoStatus{
+		"/usr/bin/a": pb.Binary_DEBUGINFO_STATUS_AVAILABLE,
+		"/usr/bin/b": pb.Binary_DEBUGINFO_STATUS_UPLOADING,
+		"/usr/bin/c": pb.Binary_DEBUGINFO_STATUS_NO_SYMBOLS,
+		"/usr/bin/d": pb.Binary_DEBUGINFO_STATUS_MISSING,
+		"[vdso]":     pb.Binary_DEBUGINFO_STATUS_UNKNOWN_UNSPECIFIED,
+	}, statuses)
+	require.Equal(t, []string{"api"}, res.Binaries[0].Services)
+	require.Equal(t, now.Add(-time.Hour), res.Binaries[0].FirstSeen.AsTime())
+}
@@ -45,11 +45,30 @@ type Querier interface {
 	Labels(ctx context.Context, match []string, start, end time.Time, profileType string) ([]string, error)
 	Values(ctx context.Context, labelName string, match []string, start, end time.Time, profileType string) ([]string, error)
 	QueryRange(ctx context.Context, query string, startTime, endTime time.Time, step time.Duration, limit uint32, sumBy []string) ([]*pb.MetricsSeries, error)
+	QueryRangeWindows(ctx context.Context, query string, windows []parcacol.TimeRange, step time.Duration, limit uint32, sumBy []string) ([][]*pb.MetricsSeries, error)
 	ProfileTypes(ctx context.Context) ([]*pb.ProfileType, error)
 	QuerySingle(ctx context.Context, query string, time time.Time, invertCallStacks bool) (profile.Profile, error)
 	QueryMerge(ctx context.Context, query string, start, end time.Time, aggregateByLabels []string, invertCallStacks bool) (profile.Profile, error)
 	GetProfileMetadataMappings(ctx context.Context, query string, start, end time.Time) ([]string, error)
 	GetProfileMetadataLabels(ctx context.Context, query string, start, end time.Time) ([]string, error)
+	ProfileMetadata(ctx context.Context, query string, start, end time.Time) ([]*parcacol.ProfileMetadata, error)
+	TraceExemplars(ctx context.Context, query string, start, end time.Time, label string, limit int) ([]*parcacol.TraceExemplar, error)
+	ProfileTypeCounts(ctx context.Context, selector string, start, end time.Time) ([]*parcacol.ProfileTypeCount, error)
+}
+
+// readSnapshotter is implemented by queriers whose queries can read the same
+// snapshot of the storage, see parcacol.Querier.ContextWithReadSnapshot.
+type readSnapshotter interface {
+	ContextWithReadSnapshot(ctx context.Context) (context.Context, func())
+}
+
+// readSnapshot returns a context whose queries read the same snapshot of the
+// storage, if the querier supports it, and the func releasing it.
+func readSnapshot(ctx context.Context, querier any) (context.Context, func()) {
+	if s, ok := querier.(readSnapshotter); ok {
+		return s.ContextWithReadSnapshot(ctx)
+	}
+	return ctx, func() {}
 }
 
 var (
@@ -154,6 +173,8 @@ func (q *ColumnQueryAPI) QueryRange(ctx context.Context, req *pb.QueryRangeReque
 	if err := req.Validate(); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
 
 	res, err := q.querier.QueryRange(ctx, req.Query, req.Start.AsTime(), req.End.AsTime(), req.Step.AsDuration(), req.Limit, req.SumBy)
 	if err != nil {
@@ -190,6 +211,9 @@ func (q *ColumnQueryAPI) Query(ctx context.Context, req *pb.QueryRequest) (*pb.Q
 	if err := req.Validate(); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	// Both sides of a diff read the same snapshot.
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
 
 	var (
 		source string
@@ -512,6 +536,8 @@ func (q *ColumnQueryAPI) renderReport(
 	source string,
 	isDiff bool,
 ) (*pb.QueryResponse, error) {
+	defer parcacol.ObserveStage(ctx, parcacol.StageRender, time.Now())
+
 	return RenderReport(
 		ctx,
 		q.tracer,
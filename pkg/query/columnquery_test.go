@@ -229,6 +229,123 @@ func TestColumnQueryAPIQueryRange(t *testing.T) {
 	require.Equal(t, 10, len(res.Series[0].Samples))
 }
 
+func TestColumnQueryAPIQueryRangeWindows(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	reg := prometheus.NewRegistry()
+	tracer := noop.NewTracerProvider().Tracer("")
+	col, err := columnstore.New()
+	require.NoError(t, err)
+	colDB, err := col.DB(context.Background(), "parca")
+	require.NoError(t, err)
+
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+
+	table, err := colDB.Table(
+		"stacktraces",
+		columnstore.NewTableConfig(profile.SchemaDefinition()),
+	)
+	require.NoError(t, err)
+
+	dir := "./testdata/many/"
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	ingester := ingester.NewIngester(
+		logger,
+		table,
+	)
+	store := profilestore.NewProfileColumnStore(
+		reg,
+		logger,
+		tracer,
+		ingester,
+		schema,
+		memory.DefaultAllocator,
+	)
+
+	for _, f := range files {
+		fileContent, err := os.ReadFile(dir + f.Name())
+		require.NoError(t, err)
+
+		_, err = store.WriteRaw(ctx, &profilestorepb.WriteRawRequest{
+			Series: []*profilestorepb.RawProfileSeries{{
+				Labels: &profilestorepb.LabelSet{
+					Labels: []*profilestorepb.Label{
+						{
+							Name:  "__name__",
+							Value: "memory",
+						},
+						{
+							Name:  "job",
+							Value: "default",
+						},
+					},
+				},
+				Samples: []*profilestorepb.RawSample{{
+					RawProfile: fileContent,
+				}},
+			}},
+		})
+		require.NoError(t, err)
+	}
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	api := NewColumnQueryAPI(
+		logger,
+		tracer,
+		getShareServerConn(t),
+		parcacol.NewQuerier(
+			logger,
+			tracer,
+			query.NewEngine(
+				mem,
+				colDB.TableProvider(),
+			),
+			"stacktraces",
+			nil,
+			mem,
+		),
+		mem,
+		parcacol.NewArrowToProfileConverter(tracer, kv.NewKeyMaker()),
+		nil,
+	)
+	all := &pb.TimeWindow{Start: timestamppb.New(timestamp.Time(0)), End: timestamppb.New(timestamp.Time(9223372036854775807))}
+	res, err := api.QueryRangeWindows(ctx, &pb.QueryRangeWindowsRequest{
+		Query: `memory:alloc_objects:count:space:bytes{job="default"}`,
+		Windows: []*pb.TimeWindow{
+			all,
+			{Start: timestamppb.New(timestamp.Time(0)), End: timestamppb.New(timestamp.Time(1))},
+			all,
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Windows, 3)
+	require.Equal(t, 1, len(res.Windows[0].Series))
+	require.Equal(t, 10, len(res.Windows[0].Series[0].Samples))
+	require.Equal(t, 0, len(res.Windows[1].Series))
+	require.True(t, proto.Equal(res.Windows[0], res.Windows[2]))
+
+	// The windows are split from a single selection, so each matches the
+	// range query of the window.
+	single, err := api.QueryRange(ctx, &pb.QueryRangeRequest{
+		Query: `memory:alloc_objects:count:space:bytes{job="default"}`,
+		Start: all.Start,
+		End:   all.End,
+	})
+	require.NoError(t, err)
+	require.True(t, proto.Equal(single, res.Windows[0]))
+
+	_, err = api.QueryRangeWindows(ctx, &pb.QueryRangeWindowsRequest{
+		Query: `memory:alloc_objects:count:space:bytes{job="default"}`,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func TestColumnQueryAPIQuerySingle(t *testing.T) {
 	t.Parallel()
 
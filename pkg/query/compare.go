@@ -0,0 +1,179 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+
+	pprofprofile "github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+// regressionRule is a RegressionRule with its compiled function expression.
+type regressionRule struct {
+	function    *regexp.Regexp
+	maxIncrease float64
+}
+
+// CompareToBaseline merges the profiles matching the baseline query and
+// compares the cumulative share of the total every function matching one of
+// the rules has in the given profile against its share in the baseline.
+// Shares rather than absolute values are compared, so that profiles of runs
+// with different durations or iteration counts can be compared. The values
+// of the profile's sample type requested, or the one of the baseline query,
+// are compared.
+func (q *ColumnQueryAPI) CompareToBaseline(ctx context.Context, req *pb.CompareToBaselineRequest) (*pb.CompareToBaselineResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	rules := make([]regressionRule, 0, len(req.Rules))
+	for _, r := range req.Rules {
+		re, err := regexp.Compile(r.Function)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid function regexp %q: %v", r.Function, err)
+		}
+		rules = append(rules, regressionRule{function: re, maxIncrease: r.MaxIncrease})
+	}
+
+	qp, err := parcacol.ParseQuery(req.BaselineQuery)
+	if err != nil {
+		return nil, err
+	}
+	sampleType := req.SampleType
+	if sampleType == "" {
+		sampleType = qp.Meta.SampleType.Type
+	}
+
+	candidate, err := pprofprofile.ParseData(req.Profile)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid profile: %v", err)
+	}
+	index := -1
+	for i, st := range candidate.SampleType {
+		if st.Type == sampleType {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "profile has no sample type %q", sampleType)
+	}
+
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	baseline, err := q.querier.QueryMerge(ctx, req.BaselineQuery, req.Start.AsTime(), req.End.AsTime(), nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline profiles: %w", err)
+	}
+	baseShares := functionShares(baseline)
+	for _, r := range baseline.Samples {
+		r.Release()
+	}
+	if baseShares == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the baseline has no samples")
+	}
+
+	return compareFunctionShares(baseShares, pprofFunctionShares(candidate, index), rules), nil
+}
+
+// compareFunctionShares compares the share of every function of the
+// candidate matching one of the rules, ordered by the decreasing increase.
+func compareFunctionShares(baseline, candidate map[string]float64, rules []regressionRule) *pb.CompareToBaselineResponse {
+	res := &pb.CompareToBaselineResponse{}
+	for fn, cand := range candidate {
+		var rule *regressionRule
+		for i := range rules {
+			if rules[i].function.MatchString(fn) {
+				rule = &rules[i]
+				break
+			}
+		}
+		if rule == nil {
+			continue
+		}
+
+		base := baseline[fn]
+		var increase float64
+		switch {
+		case base > 0:
+			increase = cand/base - 1
+		case cand > 0:
+			increase = math.Inf(1)
+		}
+		c := &pb.FunctionComparison{
+			Function:    fn,
+			Baseline:    base,
+			Candidate:   cand,
+			Increase:    increase,
+			MaxIncrease: rule.maxIncrease,
+			Regressed:   increase > rule.maxIncrease,
+		}
+		res.Functions = append(res.Functions, c)
+		res.Regressed = res.Regressed || c.Regressed
+	}
+
+	sort.Slice(res.Functions, func(i, j int) bool {
+		if res.Functions[i].Increase != res.Functions[j].Increase {
+			return res.Functions[i].Increase > res.Functions[j].Increase
+		}
+		return res.Functions[i].Function < res.Functions[j].Function
+	})
+	return res
+}
+
+// pprofFunctionShares returns the cumulative value of the sample type at the
+// index of every function of the pprof profile as a share of the total
+// value. A function appearing multiple times in a stack counts once.
+func pprofFunctionShares(p *pprofprofile.Profile, index int) map[string]float64 {
+	var total int64
+	cumulative := map[string]int64{}
+	seen := map[string]struct{}{}
+	for _, s := range p.Sample {
+		v := s.Value[index]
+		total += v
+
+		clear(seen)
+		for _, loc := range s.Location {
+			for _, line := range loc.Line {
+				if line.Function == nil {
+					continue
+				}
+				if _, ok := seen[line.Function.Name]; ok {
+					continue
+				}
+				seen[line.Function.Name] = struct{}{}
+				cumulative[line.Function.Name] += v
+			}
+		}
+	}
+
+	shares := make(map[string]float64, len(cumulative))
+	if total == 0 {
+		return shares
+	}
+	for fn, v := range cumulative {
+		shares[fn] = float64(v) / float64(total)
+	}
+	return shares
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+func TestCompareToBaseline(t *testing.T) {
+	// In the baseline a and b each have half of the samples.
+	querier := &fakeWindowQuerier{values: map[int64][2]int64{0: {50, 50}}}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		querier,
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+
+	// The candidate has the same share of samples in a, but a spends 80% of
+	// the CPU time. c is new.
+	main := &pprofprofile.Function{ID: 1, Name: "main"}
+	a := &pprofprofile.Function{ID: 2, Name: "a"}
+	b := &pprofprofile.Function{ID: 3, Name: "b"}
+	c := &pprofprofile.Function{ID: 4, Name: "c"}
+	loc := func(fn *pprofprofile.Function) *pprofprofile.Location {
+		return &pprofprofile.Location{ID: fn.ID, Line: []pprofprofile.Line{{Function: fn}}}
+	}
+	candidate := &pprofprofile.Profile{
+		SampleType: []*pprofprofile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		Sample: []*pprofprofile.Sample{
+			{Location: []*pprofprofile.Location{loc(a), loc(main)}, Value: []int64{45, 720}},
+			{Location: []*pprofprofile.Location{loc(b), loc(main)}, Value: []int64{45, 80}},
+			{Location: []*pprofprofile.Location{loc(c), loc(main)}, Value: []int64{10, 200}},
+		},
+		Location: []*pprofprofile.Location{loc(main), loc(a), loc(b), loc(c)},
+		Function: []*pprofprofile.Function{main, a, b, c},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, candidate.Write(&buf))
+
+	req := &pb.CompareToBaselineRequest{
+		BaselineQuery: `parca_agent:samples:count:cpu:nanoseconds:delta{}`,
+		Start:         timestamppb.New(time.Unix(0, 0)),
+		End:           timestamppb.New(time.Unix(10, 0)),
+		Profile:       buf.Bytes(),
+		Rules:         []*pb.RegressionRule{{Function: "^(a|b)$", MaxIncrease: 0.1}},
+	}
+
+	// The sample type of the baseline query is compared by default.
+	res, err := api.CompareToBaseline(context.Background(), req)
+	require.NoError(t, err)
+	require.False(t, res.Regressed)
+	require.Len(t, res.Functions, 2)
+	require.Equal(t, "a", res.Functions[0].Function)
+	require.InDelta(t, 0.5, res.Functions[0].Baseline, 0.0001)
+	require.InDelta(t, 0.45, res.Functions[0].Candidate, 0.0001)
+
+	req.SampleType = "cpu"
+	res, err = api.CompareToBaseline(context.Background(), req)
+	require.NoError(t, err)
+	require.True(t, res.Regressed)
+	require.Equal(t, "a", res.Functions[0].Function)
+	require.InDelta(t, 0.72, res.Functions[0].Candidate, 0.0001)
+	require.InDelta(t, 0.44, res.Functions[0].Increase, 0.0001)
+	require.True(t, res.Functions[0].Regressed)
+	require.Equal(t, "b", res.Functions[1].Function)
+	require.False(t, res.Functions[1].Regressed)
+
+	// A function missing from the baseline regressed infinitely.
+	req.Rules = []*pb.RegressionRule{{Function: "^c$", MaxIncrease: 1}}
+	res, err = api.CompareToBaseline(context.Background(), req)
+	require.NoError(t, err)
+	require.True(t, res.Regressed)
+	require.True(t, math.IsInf(res.Functions[0].Increase, 1))
+
+	req.SampleType = "alloc_space"
+	_, err = api.CompareToBaseline(context.Background(), req)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	req.SampleType = ""
+	req.Rules = []*pb.RegressionRule{{Function: "(", MaxIncrease: 1}}
+	_, err = api.CompareToBaseline(context.Background(), req)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
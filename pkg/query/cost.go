@@ -0,0 +1,244 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+// CostReportPath is the HTTP path of the endpoint estimating the cost of
+// the CPU and memory used per group of profiles, relative to the API root.
+const CostReportPath = "/profiles/cost"
+
+const (
+	// DefaultCostCPUQuery selects the on-CPU profiles of the Parca agent.
+	DefaultCostCPUQuery = "parca_agent:samples:count:cpu:nanoseconds:delta{}"
+	// DefaultCostMemoryQuery selects the in-use heap profiles of Go
+	// programs.
+	DefaultCostMemoryQuery = "memory:inuse_space:bytes:space:bytes{}"
+)
+
+// CostRates are the prices used to turn resource usage into costs.
+type CostRates struct {
+	// CoreHour is the price of using one CPU core for an hour.
+	CoreHour float64 `json:"core_hour"`
+	// GiBHour is the price of using a GiB of memory for an hour.
+	GiBHour  float64 `json:"gib_hour"`
+	Currency string  `json:"currency"`
+}
+
+// CostGroup is the resource usage and its cost of the profiles with the
+// same values of the grouping labels.
+type CostGroup struct {
+	Labels     map[string]string `json:"labels"`
+	CoreHours  float64           `json:"core_hours"`
+	GiBHours   float64           `json:"gib_hours"`
+	CPUCost    float64           `json:"cpu_cost"`
+	MemoryCost float64           `json:"memory_cost"`
+	Cost       float64           `json:"cost"`
+}
+
+// CostReport is the cost of the profiled resource usage in a time range,
+// per group and in total.
+type CostReport struct {
+	Start   time.Time    `json:"start"`
+	End     time.Time    `json:"end"`
+	GroupBy []string     `json:"group_by"`
+	Rates   CostRates    `json:"rates"`
+	Groups  []*CostGroup `json:"groups"`
+	Total   float64      `json:"total"`
+}
+
+// CostReport estimates the cost of the resources used by the profiled
+// processes in the time range, grouped by the labels. The CPU query must
+// select a delta profile measured in nanoseconds, its average number of
+// cores used per step is accumulated to core hours. The memory query must
+// select a profile measured in bytes, its value per step is accumulated to
+// GiB hours. Either query may be empty to leave out the resource. The step
+// is truncated to whole seconds and is at least a second, like the steps of
+// range queries.
+func (q *ColumnQueryAPI) CostReport(ctx context.Context, cpuQuery, memoryQuery string, start, end time.Time, step time.Duration, groupBy []string, rates CostRates) (*CostReport, error) {
+	if !end.After(start) {
+		return nil, status.Error(codes.InvalidArgument, "end must be after start")
+	}
+	if step <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "step must be positive")
+	}
+	step = step.Truncate(time.Second)
+	if step < time.Second {
+		step = time.Second
+	}
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	var cpu, mem []*pb.MetricsSeries
+	g, ctx := errgroup.WithContext(ctx)
+	if cpuQuery != "" {
+		g.Go(func() error {
+			var err error
+			cpu, err = q.querier.QueryRange(ctx, cpuQuery, start, end, step, 0, groupBy)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return fmt.Errorf("query cpu usage: %w", err)
+			}
+			return nil
+		})
+	}
+	if memoryQuery != "" {
+		g.Go(func() error {
+			// The range of a non-delta profile type has a single value of
+			// a series per step, the one of its first profile. Summing by
+			// the labels would only keep the first profile of any of the
+			// series of a group, so every series is read and they are
+			// summed per group below.
+			var err error
+			mem, err = q.querier.QueryRange(ctx, memoryQuery, start, end, step, 0, nil)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return fmt.Errorf("query memory usage: %w", err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*CostGroup{}
+	group := func(s *pb.MetricsSeries) *CostGroup {
+		ls := make(map[string]string, len(s.GetLabelset().GetLabels()))
+		keys := make([]string, 0, len(ls))
+		for _, l := range s.GetLabelset().GetLabels() {
+			if len(groupBy) > 0 && !slices.Contains(groupBy, l.GetName()) {
+				continue
+			}
+			ls[l.GetName()] = l.GetValue()
+			keys = append(keys, l.GetName()+"="+l.GetValue())
+		}
+		sort.Strings(keys)
+		key := strings.Join(keys, ",")
+		cg, ok := groups[key]
+		if !ok {
+			cg = &CostGroup{Labels: ls}
+			groups[key] = cg
+		}
+		return cg
+	}
+
+	hoursPerStep := step.Hours()
+	for _, s := range cpu {
+		cg := group(s)
+		for _, sample := range s.GetSamples() {
+			// The value per second of CPU time is the number of cores.
+			cg.CoreHours += sample.GetValuePerSecond() * hoursPerStep
+		}
+	}
+	for _, s := range mem {
+		cg := group(s)
+		for _, sample := range s.GetSamples() {
+			cg.GiBHours += sample.GetValuePerSecond() / (1 << 30) * hoursPerStep
+		}
+	}
+
+	report := &CostReport{
+		Start:   start,
+		End:     end,
+		GroupBy: groupBy,
+		Rates:   rates,
+		Groups:  make([]*CostGroup, 0, len(groups)),
+	}
+	for _, cg := range groups {
+		cg.CPUCost = cg.CoreHours * rates.CoreHour
+		cg.MemoryCost = cg.GiBHours * rates.GiBHour
+		cg.Cost = cg.CPUCost + cg.MemoryCost
+		report.Total += cg.Cost
+		report.Groups = append(report.Groups, cg)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Cost > report.Groups[j].Cost
+	})
+	return report, nil
+}
+
+// CostReportHandler serves cost reports with the given default rates. The
+// query parameters are "group_by" (comma separated label names), and
+// optionally "start" and "end" (RFC3339, defaulting to the last 24 hours),
+// "step" (defaulting to 5m), "cpu_query" and "memory_query" (selectors
+// overriding the defaults, "none" to leave a resource out), and
+// "core_hour" and "gib_hour" overriding the rates.
+func (q *ColumnQueryAPI) CostReportHandler(defaults CostRates) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		rates := defaults
+		params := r.URL.Query()
+		start, end, err := parseTimeRangeParams(params.Get("start"), params.Get("end"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		step := 5 * time.Minute
+		if v := params.Get("step"); v != "" {
+			if step, err = time.ParseDuration(v); err != nil {
+				http.Error(w, fmt.Sprintf("invalid step: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var groupBy []string
+		if v := params.Get("group_by"); v != "" {
+			groupBy = strings.Split(v, ",")
+		}
+
+		queries := map[string]string{"cpu_query": DefaultCostCPUQuery, "memory_query": DefaultCostMemoryQuery}
+		for name := range queries {
+			switch v := params.Get(name); v {
+			case "":
+			case "none":
+				queries[name] = ""
+			default:
+				queries[name] = v
+			}
+		}
+
+		for name, rate := range map[string]*float64{"core_hour": &rates.CoreHour, "gib_hour": &rates.GiBHour} {
+			v := params.Get(name)
+			if v == "" {
+				continue
+			}
+			if *rate, err = strconv.ParseFloat(v, 64); err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %v", name, err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		report, err := q.CostReport(r.Context(), queries["cpu_query"], queries["memory_query"], start, end, step, groupBy, rates)
+		if err != nil {
+			http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+		writeJSON(w, report)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+type fakeRangeQuerier struct {
+	Querier
+	series map[string][]*pb.MetricsSeries
+}
+
+func (q *fakeRangeQuerier) QueryRange(_ context.Context, query string, _, _ time.Time, _ time.Duration, _ uint32, _ []string) ([]*pb.MetricsSeries, error) {
+	return q.series[query], nil
+}
+
+func costSeries(team string, values ...float64) *pb.MetricsSeries {
+	s := &pb.MetricsSeries{Labelset: &profilestorepb.LabelSet{Labels: []*profilestorepb.Label{{Name: "team", Value: team}}}}
+	for _, v := range values {
+		s.Samples = append(s.Samples, &pb.MetricsSample{ValuePerSecond: v})
+	}
+	return s
+}
+
+func withPod(s *pb.MetricsSeries, pod string) *pb.MetricsSeries {
+	s.Labelset.Labels = append(s.Labelset.Labels, &profilestorepb.Label{Name: "pod", Value: pod})
+	return s
+}
+
+func TestCostReport(t *testing.T) {
+	querier := &fakeRangeQuerier{series: map[string][]*pb.MetricsSeries{
+		// Two hours of 2 and 4 cores.
+		"cpu": {costSeries("a", 2, 2), costSeries("b", 4, 4)},
+		// Two hours of 1 GiB for each of two pods, which add up.
+		"mem": {withPod(costSeries("a", 1<<30, 1<<30), "a-1"), withPod(costSeries("a", 1<<30, 1<<30), "a-2")},
+	}}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		querier,
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+
+	report, err := api.CostReport(context.Background(), "cpu", "mem", time.Unix(0, 0), time.Unix(7200, 0), time.Hour, []string{"team"}, CostRates{
+		CoreHour: 0.5,
+		GiBHour:  0.25,
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Groups, 2)
+
+	require.Equal(t, map[string]string{"team": "b"}, report.Groups[0].Labels)
+	require.InDelta(t, 8, report.Groups[0].CoreHours, 1e-9)
+	require.InDelta(t, 4, report.Groups[0].Cost, 1e-9)
+
+	require.Equal(t, map[string]string{"team": "a"}, report.Groups[1].Labels)
+	require.InDelta(t, 4, report.Groups[1].CoreHours, 1e-9)
+	require.InDelta(t, 4, report.Groups[1].GiBHours, 1e-9)
+	require.InDelta(t, 3, report.Groups[1].Cost, 1e-9)
+	require.InDelta(t, 7, report.Total, 1e-9)
+
+	// Steps are at least a second, like the ones of range queries.
+	querier.series = map[string][]*pb.MetricsSeries{"cpu": {costSeries("a", 3600)}}
+	report, err = api.CostReport(context.Background(), "cpu", "", time.Unix(0, 0), time.Unix(7200, 0), time.Millisecond, []string{"team"}, CostRates{})
+	require.NoError(t, err)
+	require.InDelta(t, 1, report.Groups[0].CoreHours, 1e-9)
+}
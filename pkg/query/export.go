@@ -0,0 +1,235 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/jobs"
+)
+
+const (
+	// ExportJobKind is the kind of the export jobs.
+	ExportJobKind = "export"
+
+	// ExportsPath is the HTTP path of the export downloads, relative to the
+	// API root.
+	ExportsPath = "/exports"
+
+	defaultExportWindow = time.Hour
+	// maxExportParts bounds the number of parts, and with it the number of
+	// queries, of an export.
+	maxExportParts = 10_000
+)
+
+// ExportRequest is the spec of an export job.
+type ExportRequest struct {
+	Query string    `json:"query"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Window is the time range merged into each part of the export, eg.
+	// "1h". Parts are written as they are completed, so an interrupted export
+	// only redoes the window it was working on.
+	Window string `json:"window,omitempty"`
+}
+
+// ExportResult is the result of an export job.
+type ExportResult struct {
+	// Parts are the names of the pprof files of the windows that contained
+	// profiles.
+	Parts []string `json:"parts"`
+}
+
+type exportQuerier interface {
+	Query(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error)
+}
+
+// Exporter is the job kind exporting the merged pprof profiles of a time
+// range, window by window, to the object storage. The progress of an export
+// is the number of exported windows.
+type Exporter struct {
+	logger  log.Logger
+	bucket  objstore.Bucket
+	querier exportQuerier
+}
+
+var _ jobs.Kind = (*Exporter)(nil)
+
+func NewExporter(logger log.Logger, bucket objstore.Bucket, querier exportQuerier) *Exporter {
+	return &Exporter{
+		logger:  logger,
+		bucket:  bucket,
+		querier: querier,
+	}
+}
+
+func parseExportRequest(spec json.RawMessage) (ExportRequest, time.Duration, error) {
+	var req ExportRequest
+	if err := json.Unmarshal(spec, &req); err != nil {
+		return req, 0, fmt.Errorf("invalid spec: %w", err)
+	}
+
+	window := defaultExportWindow
+	if req.Window != "" {
+		var err error
+		window, err = time.ParseDuration(req.Window)
+		if err != nil {
+			return req, 0, fmt.Errorf("invalid window: %w", err)
+		}
+		if window <= 0 {
+			return req, 0, errors.New("window must be positive")
+		}
+	}
+	return req, window, nil
+}
+
+// Plan validates the export and returns the number of windows to export.
+func (e *Exporter) Plan(spec json.RawMessage) (int, error) {
+	req, window, err := parseExportRequest(spec)
+	if err != nil {
+		return 0, err
+	}
+	if req.Query == "" {
+		return 0, errors.New("query is required")
+	}
+	if !req.End.After(req.Start) {
+		return 0, errors.New("end must be after start")
+	}
+
+	total := int((req.End.Sub(req.Start) + window - 1) / window)
+	if total > maxExportParts {
+		return 0, fmt.Errorf("export of %d windows exceeds the maximum of %d, use a larger window", total, maxExportParts)
+	}
+	return total, nil
+}
+
+// Run exports the windows after the completed ones.
+func (e *Exporter) Run(ctx context.Context, id string, spec json.RawMessage, completed int, result json.RawMessage, checkpoint jobs.Checkpoint) error {
+	req, window, err := parseExportRequest(spec)
+	if err != nil {
+		return err
+	}
+
+	res := ExportResult{Parts: []string{}}
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &res); err != nil {
+			return fmt.Errorf("invalid result: %w", err)
+		}
+	}
+
+	for i := completed; req.Start.Add(time.Duration(i) * window).Before(req.End); i++ {
+		start := req.Start.Add(time.Duration(i) * window)
+		end := start.Add(window)
+		if end.After(req.End) {
+			end = req.End
+		}
+
+		part, err := e.exportWindow(ctx, id, i, req.Query, start, end)
+		if err != nil {
+			return fmt.Errorf("export window %d: %w", i, err)
+		}
+		if part != "" {
+			res.Parts = append(res.Parts, part)
+		}
+
+		b, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		if err := checkpoint(i+1, b); err != nil {
+			return fmt.Errorf("checkpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+// exportWindow writes the merged profile of the window to the bucket and
+// returns the name of the part, which is empty if the window contained no
+// profiles.
+func (e *Exporter) exportWindow(ctx context.Context, id string, i int, query string, start, end time.Time) (string, error) {
+	resp, err := e.querier.Query(ctx, &pb.QueryRequest{
+		Mode:       pb.QueryRequest_MODE_MERGE,
+		ReportType: pb.QueryRequest_REPORT_TYPE_PPROF,
+		Options: &pb.QueryRequest_Merge{
+			Merge: &pb.MergeProfile{
+				Query: query,
+				Start: timestamppb.New(start),
+				End:   timestamppb.New(end),
+			},
+		},
+	})
+	if status.Code(err) == codes.NotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	pprof := resp.GetPprof()
+	if len(pprof) == 0 {
+		return "", nil
+	}
+
+	part := fmt.Sprintf("part-%05d.pb.gz", i)
+	if err := e.bucket.Upload(ctx, path.Join(id, part), bytes.NewReader(pprof)); err != nil {
+		return "", fmt.Errorf("upload part: %w", err)
+	}
+	return part, nil
+}
+
+// PartHandler downloads the part given by the "part" path parameter of the
+// export job given by the "id" path parameter.
+func (e *Exporter) PartHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, part := params["id"], params["part"]
+		if id != path.Base(id) || part != path.Base(part) {
+			http.Error(w, "invalid export part", http.StatusBadRequest)
+			return
+		}
+
+		rc, err := e.bucket.Get(r.Context(), path.Join(id, part))
+		if e.bucket.IsObjNotFoundErr(err) {
+			http.Error(w, "export part not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rc.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", part))
+		if _, err := io.Copy(w, rc); err != nil {
+			level.Warn(e.logger).Log("msg", "failed to send export part", "id", id, "part", part, "err", err)
+		}
+	}
+}
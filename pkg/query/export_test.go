@@ -0,0 +1,88 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+type fakeExportQuerier struct {
+	starts []time.Time
+	empty  map[int64]bool
+}
+
+func (q *fakeExportQuerier) Query(_ context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	start := req.GetMerge().GetStart().AsTime()
+	q.starts = append(q.starts, start)
+	if q.empty[start.Unix()] {
+		return nil, status.Error(codes.NotFound, "no profiles")
+	}
+	return &pb.QueryResponse{
+		Report: &pb.QueryResponse_Pprof{Pprof: []byte(start.Format(time.RFC3339))},
+	}, nil
+}
+
+func TestExporter(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	start := time.Unix(0, 0).UTC()
+	q := &fakeExportQuerier{empty: map[int64]bool{start.Add(2 * time.Hour).Unix(): true}}
+	e := NewExporter(log.NewNopLogger(), bucket, q)
+
+	spec, err := json.Marshal(ExportRequest{Query: "cpu", Start: start, End: start})
+	require.NoError(t, err)
+	_, err = e.Plan(spec)
+	require.Error(t, err)
+
+	spec, err = json.Marshal(ExportRequest{Query: "cpu", Start: start, End: start.Add(210 * time.Minute)})
+	require.NoError(t, err)
+	total, err := e.Plan(spec)
+	require.NoError(t, err)
+	require.Equal(t, 4, total)
+
+	// Resume after the first window was exported.
+	var completed []int
+	var result json.RawMessage
+	require.NoError(t, e.Run(ctx, "export", spec, 1, []byte(`{"parts":["part-00000.pb.gz"]}`), func(c int, r json.RawMessage) error {
+		completed = append(completed, c)
+		result = r
+		return nil
+	}))
+	require.Equal(t, []int{2, 3, 4}, completed)
+	require.Equal(t, []time.Time{start.Add(time.Hour), start.Add(2 * time.Hour), start.Add(3 * time.Hour)}, q.starts)
+
+	// The empty third window doesn't produce a part.
+	var res ExportResult
+	require.NoError(t, json.Unmarshal(result, &res))
+	require.Equal(t, []string{"part-00000.pb.gz", "part-00001.pb.gz", "part-00003.pb.gz"}, res.Parts)
+
+	r, err := bucket.Get(ctx, "export/part-00003.pb.gz")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(r)
+	require.NoError(t, err)
+	require.Equal(t, start.Add(3*time.Hour).Format(time.RFC3339), buf.String())
+}
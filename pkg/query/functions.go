@@ -0,0 +1,176 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// QueryFunctionsPath is the HTTP path listing the registered query functions
+// and, suffixed with the name of a function, evaluating it, relative to the
+// API root.
+const QueryFunctionsPath = "/profiles/functions"
+
+// QueryFunction evaluates a profile selected by a query to a JSON encodable
+// result, eg. a custom scoring of its stacks. The args are the query
+// parameters of the request. The samples of the profile are released after
+// the function returned.
+type QueryFunction func(ctx context.Context, p profile.Profile, args map[string]string) (any, error)
+
+var (
+	queryFunctionsMtx sync.RWMutex
+	queryFunctions    = map[string]QueryFunction{}
+)
+
+// RegisterQueryFunction makes a query function available by name. It is
+// meant to be called from the init function of an extension package linked
+// into a custom build of Parca, and panics if the name is already
+// registered.
+func RegisterQueryFunction(name string, fn QueryFunction) {
+	queryFunctionsMtx.Lock()
+	defer queryFunctionsMtx.Unlock()
+
+	if _, ok := queryFunctions[name]; ok {
+		panic(fmt.Sprintf("query function %q registered twice", name))
+	}
+	queryFunctions[name] = fn
+}
+
+// QueryFunctions returns the names of the registered query functions, sorted.
+func QueryFunctions() []string {
+	queryFunctionsMtx.RLock()
+	defer queryFunctionsMtx.RUnlock()
+
+	names := make([]string, 0, len(queryFunctions))
+	for name := range queryFunctions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupQueryFunction(name string) (QueryFunction, error) {
+	queryFunctionsMtx.RLock()
+	defer queryFunctionsMtx.RUnlock()
+
+	fn, ok := queryFunctions[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown query function %q", name)
+	}
+	return fn, nil
+}
+
+// EvalQueryFunction selects the profile of the request, filtered like for
+// any other report, and evaluates the named query function on it.
+func (q *ColumnQueryAPI) EvalQueryFunction(ctx context.Context, name string, req *pb.QueryRequest, args map[string]string) (any, error) {
+	fn, err := lookupQueryFunction(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	isInvert := req.GetInvertCallStack()
+	var p profile.Profile
+	switch req.Mode {
+	case pb.QueryRequest_MODE_SINGLE_UNSPECIFIED:
+		p, err = q.selectSingle(ctx, req.GetSingle(), isInvert)
+	case pb.QueryRequest_MODE_MERGE:
+		p, err = q.selectMerge(ctx, req.GetMerge(), nil, isInvert)
+	case pb.QueryRequest_MODE_DIFF:
+		p, err = q.selectDiff(ctx, req.GetDiff(), false, isInvert)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "unknown query mode")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, r := range p.Samples {
+			r.Release()
+		}
+	}()
+
+	var functionToFilterBy string
+	binaryFrameFilter := map[string]struct{}{}
+	for _, filter := range req.GetFilter() {
+		if f := filter.GetStackFilter().GetFunctionNameStackFilter(); f != nil {
+			functionToFilterBy = f.GetFunctionToFilter()
+		}
+		for _, include := range filter.GetFrameFilter().GetBinaryFrameFilter().GetIncludeBinaries() {
+			binaryFrameFilter[include] = struct{}{}
+		}
+	}
+	p.Samples, _, err = FilterProfileData(ctx, q.tracer, q.mem, p.Samples, functionToFilterBy, binaryFrameFilter)
+	if err != nil {
+		return nil, fmt.Errorf("filtering profile: %w", err)
+	}
+
+	return fn(ctx, p, args)
+}
+
+// QueryFunctionsHandler serves the names of the registered query functions.
+func QueryFunctionsHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		writeJSON(w, struct {
+			Functions []string `json:"functions"`
+		}{Functions: QueryFunctions()})
+	}
+}
+
+// EvalQueryFunctionHandler evaluates the query function given by the "name"
+// path parameter. The request body is a QueryRequest in its JSON encoding,
+// its report type is ignored, and the query parameters are passed to the
+// function as its arguments.
+func (q *ColumnQueryAPI) EvalQueryFunctionHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		req := &pb.QueryRequest{}
+		if err := unmarshalBody(r.Body, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		args := map[string]string{}
+		for k, v := range r.URL.Query() {
+			if len(v) > 0 {
+				args[k] = v[0]
+			}
+		}
+
+		res, err := q.EvalQueryFunction(r.Context(), params["name"], req, args)
+		if err != nil {
+			http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+
+		writeJSON(w, struct {
+			Function string `json:"function"`
+			Result   any    `json:"result"`
+		}{Function: params["name"], Result: res})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+type fakeSingleQuerier struct {
+	Querier
+	query string
+}
+
+func (q *fakeSingleQuerier) QuerySingle(_ context.Context, query string, _ time.Time, _ bool) (profile.Profile, error) {
+	q.query = query
+	return profile.Profile{Meta: profile.Meta{Name: "cpu"}}, nil
+}
+
+func TestEvalQueryFunction(t *testing.T) {
+	RegisterQueryFunction("test-profile-name", func(_ context.Context, p profile.Profile, args map[string]string) (any, error) {
+		return p.Meta.Name + args["suffix"], nil
+	})
+	require.Panics(t, func() {
+		RegisterQueryFunction("test-profile-name", nil)
+	})
+	require.Contains(t, QueryFunctions(), "test-profile-name")
+
+	querier := &fakeSingleQuerier{}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		querier,
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+	req := &pb.QueryRequest{
+		Mode: pb.QueryRequest_MODE_SINGLE_UNSPECIFIED,
+		Options: &pb.QueryRequest_Single{Single: &pb.SingleProfile{
+			Query: `parca_agent:samples:count:cpu:nanoseconds:delta{job="a"}`,
+			Time:  timestamppb.New(time.Unix(1, 0)),
+		}},
+	}
+
+	res, err := api.EvalQueryFunction(context.Background(), "test-profile-name", req, map[string]string{"suffix": "!"})
+	require.NoError(t, err)
+	require.Equal(t, "cpu!", res)
+	require.Equal(t, req.GetSingle().Query, querier.query)
+
+	_, err = api.EvalQueryFunction(context.Background(), "unknown", req, nil)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// queryMethodPrefix is the prefix of the gRPC methods of the query services,
+// the ones the unary server interceptors of this package act on.
+const queryMethodPrefix = "/parca.query.v1alpha1."
+
+// HandlerInterceptor intercepts the HTTP handler of a query endpoint that is
+// served next to the query service, the counterpart of a unary server
+// interceptor. The method names the endpoint.
+type HandlerInterceptor func(method string, next runtime.HandlerFunc) runtime.HandlerFunc
+
+// InterceptHandler returns the handler of the method wrapped by the
+// interceptors, the first one being the outermost.
+func InterceptHandler(method string, h runtime.HandlerFunc, interceptors ...HandlerInterceptor) runtime.HandlerFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		h = interceptors[i](method, h)
+	}
+	return h
+}
+
+// httpRequestJSON describes the request as a JSON object of its URL and
+// body, for the slow query log and the active queries. The body is read and
+// replaced so the handler can still read it.
+func httpRequestJSON(r *http.Request) []byte {
+	req := struct {
+		URL  string          `json:"url"`
+		Body json.RawMessage `json:"body,omitempty"`
+	}{URL: r.URL.RequestURI()}
+
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err == nil && len(body) > 0 {
+			if json.Valid(body) {
+				req.Body = body
+			} else if b, err := json.Marshal(string(body)); err == nil {
+				req.Body = b
+			}
+		}
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// statusRecorder records the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+func TestInterceptHandler(t *testing.T) {
+	dir := t.TempDir()
+	slowQueries := NewSlowQueryLog(log.NewNopLogger(), prometheus.NewRegistry(), 10*time.Millisecond, 2)
+	metrics := NewQueryMetrics(prometheus.NewRegistry())
+	activeQueries, err := NewActiveQueryTracker(log.NewNopLogger(), prometheus.NewRegistry(), dir, 2)
+	require.NoError(t, err)
+	defer activeQueries.Close()
+
+	h := InterceptHandler(QueryNormalizedPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		// The request is tracked while in flight and its body is still
+		// readable.
+		queries, err := ReadActiveQueries(filepath.Join(dir, ActiveQueriesFile))
+		require.NoError(t, err)
+		require.Len(t, queries, 1)
+		require.Equal(t, QueryNormalizedPath, queries[0].Method)
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"query":"a"}`, string(body))
+
+		parcacol.ObserveStage(r.Context(), parcacol.StageRender, time.Now())
+		time.Sleep(20 * time.Millisecond)
+		http.Error(w, "not found", http.StatusNotFound)
+	}, slowQueries.HandlerInterceptor(), metrics.HandlerInterceptor(), activeQueries.HandlerInterceptor())
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, QueryNormalizedPath+"?divisor=2", strings.NewReader(`{"query":"a"}`)), nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	queries, err := ReadActiveQueries(filepath.Join(dir, ActiveQueriesFile))
+	require.NoError(t, err)
+	require.Empty(t, queries)
+
+	require.Equal(t, 1, testutil.CollectAndCount(metrics.stageDuration))
+
+	require.Eventually(t, func() bool {
+		return len(slowQueries.List()) == 1
+	}, time.Second, time.Millisecond)
+	q := slowQueries.List()[0]
+	require.Equal(t, QueryNormalizedPath, q.Method)
+	require.Equal(t, "Not Found", q.Code)
+
+	var req struct {
+		URL  string          `json:"url"`
+		Body json.RawMessage `json:"body"`
+	}
+	require.NoError(t, json.Unmarshal(q.Request, &req))
+	require.Equal(t, QueryNormalizedPath+"?divisor=2", req.URL)
+	require.JSONEq(t, `{"query":"a"}`, string(req.Body))
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+const (
+	// ImagesPath is the HTTP path of the container image endpoint, relative
+	// to the API root.
+	ImagesPath = "/images"
+
+	// ContainerImageLabel is the label agents and scrape configs are expected
+	// to attach the container image reference of a profiled process to.
+	ContainerImageLabel = "container_image"
+)
+
+// ImageInfo correlates a container image with the binaries observed in the
+// profiles labeled with it.
+type ImageInfo struct {
+	Image      string    `json:"image"`
+	Repository string    `json:"repository"`
+	Tag        string    `json:"tag"`
+	Digest     string    `json:"digest,omitempty"`
+	FirstSeen  time.Time `json:"firstSeen"`
+	LastSeen   time.Time `json:"lastSeen"`
+	BuildIDs   []string  `json:"buildIds"`
+}
+
+// ParseImageReference splits a container image reference such as
+// "registry:5000/app:v1.3@sha256:..." into its repository, tag and digest.
+// The tag defaults to "latest" if the reference has neither a tag nor a
+// digest.
+func ParseImageReference(image string) (repository, tag, digest string) {
+	repository = image
+	if i := strings.Index(repository, "@"); i >= 0 {
+		digest = repository[i+1:]
+		repository = repository[:i]
+	}
+	// A colon after the last slash separates the tag, others belong to the
+	// registry's port.
+	if i := strings.LastIndex(repository, ":"); i >= 0 && i > strings.LastIndex(repository, "/") {
+		tag = repository[i+1:]
+		repository = repository[:i]
+	}
+	if tag == "" && digest == "" {
+		tag = "latest"
+	}
+	return repository, tag, digest
+}
+
+// Images returns the container images found in the image label of the
+// profiles matching the query in the given time range, together with the
+// time range each image was observed in and the build IDs of its binaries.
+// The time ranges can be used to compare two versions of an image, eg. with a
+// diff of "v1.3" against "v1.4".
+func (c *BinaryCatalog) Images(ctx context.Context, query string, start, end time.Time, imageLabel string) ([]*ImageInfo, error) {
+	binaries, err := c.querier.Binaries(ctx, query, start, end, imageLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	images := map[string]*ImageInfo{}
+	buildIDs := map[string]map[string]struct{}{}
+	for _, b := range binaries {
+		for image, r := range b.ServiceRanges {
+			info, ok := images[image]
+			if !ok {
+				repository, tag, digest := ParseImageReference(image)
+				info = &ImageInfo{
+					Image:      image,
+					Repository: repository,
+					Tag:        tag,
+					Digest:     digest,
+					FirstSeen:  r.Start,
+					LastSeen:   r.End,
+				}
+				images[image] = info
+				buildIDs[image] = map[string]struct{}{}
+			}
+			if r.Start.Before(info.FirstSeen) {
+				info.FirstSeen = r.Start
+			}
+			if r.End.After(info.LastSeen) {
+				info.LastSeen = r.End
+			}
+			if b.BuildID != "" {
+				buildIDs[image][b.BuildID] = struct{}{}
+			}
+		}
+	}
+
+	res := make([]*ImageInfo, 0, len(images))
+	for image, info := range images {
+		info.BuildIDs = make([]string, 0, len(buildIDs[image]))
+		for id := range buildIDs[image] {
+			info.BuildIDs = append(info.BuildIDs, id)
+		}
+		sort.Strings(info.BuildIDs)
+		res = append(res, info)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Repository != res[j].Repository {
+			return res[i].Repository < res[j].Repository
+		}
+		return res[i].FirstSeen.Before(res[j].FirstSeen)
+	})
+
+	return res, nil
+}
+
+// ImagesHandler serves the container images over HTTP. The optional query
+// parameters are "query" (a profile selector), "start" and "end" (RFC3339,
+// defaulting to the last 24 hours) and "image_label" (defaulting to
+// "container_image").
+func (c *BinaryCatalog) ImagesHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		params := r.URL.Query()
+
+		start, end, err := parseTimeRangeParams(params.Get("start"), params.Get("end"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		imageLabel := params.Get("image_label")
+		if imageLabel == "" {
+			imageLabel = ContainerImageLabel
+		}
+
+		images, err := c.Images(r.Context(), params.Get("query"), start, end, imageLabel)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(struct {
+			Images []*ImageInfo `json:"images"`
+		}{images}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image, repository, tag, digest string
+	}{
+		{"app", "app", "latest", ""},
+		{"app:v1.3", "app", "v1.3", ""},
+		{"registry:5000/team/app:v1.4", "registry:5000/team/app", "v1.4", ""},
+		{"registry:5000/team/app", "registry:5000/team/app", "latest", ""},
+		{"app@sha256:abc", "app", "", "sha256:abc"},
+		{"app:v1@sha256:abc", "app", "v1", "sha256:abc"},
+	}
+	for _, tc := range tests {
+		repository, tag, digest := ParseImageReference(tc.image)
+		require.Equal(t, tc.repository, repository, tc.image)
+		require.Equal(t, tc.tag, tag, tc.image)
+		require.Equal(t, tc.digest, digest, tc.image)
+	}
+}
+
+func TestBinaryCatalogImages(t *testing.T) {
+	t0 := time.Unix(1700000000, 0).UTC()
+	catalog := NewBinaryCatalog(
+		log.NewNopLogger(),
+		&fakeBinaryQuerier{binaries: []*parcacol.Binary{{
+			BuildID: "old",
+			File:    "/app",
+			ServiceRanges: map[string]parcacol.TimeRange{
+				"app:v1.3": {Start: t0, End: t0.Add(time.Hour)},
+			},
+		}, {
+			BuildID: "new",
+			File:    "/app",
+			ServiceRanges: map[string]parcacol.TimeRange{
+				"app:v1.4": {Start: t0.Add(time.Hour), End: t0.Add(2 * time.Hour)},
+			},
+		}, {
+			BuildID: "libc",
+			File:    "/lib/libc.so.6",
+			ServiceRanges: map[string]parcacol.TimeRange{
+				"app:v1.3": {Start: t0.Add(-time.Minute), End: t0.Add(time.Hour)},
+				"app:v1.4": {Start: t0.Add(time.Hour), End: t0.Add(3 * time.Hour)},
+			},
+		}}},
+		nil,
+	)
+
+	images, err := catalog.Images(context.Background(), "", t0, t0.Add(3*time.Hour), ContainerImageLabel)
+	require.NoError(t, err)
+	require.Len(t, images, 2)
+
+	require.Equal(t, "v1.3", images[0].Tag)
+	require.Equal(t, t0.Add(-time.Minute), images[0].FirstSeen)
+	require.Equal(t, t0.Add(time.Hour), images[0].LastSeen)
+	require.Equal(t, []string{"libc", "old"}, images[0].BuildIDs)
+
+	require.Equal(t, "v1.4", images[1].Tag)
+	require.Equal(t, t0.Add(3*time.Hour), images[1].LastSeen)
+	require.Equal(t, []string{"libc", "new"}, images[1].BuildIDs)
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// CompareMatrixPath is the HTTP path of the endpoint comparing the functions
+// of a merged profile across the values of a label, relative to the API
+// root.
+const CompareMatrixPath = "/profiles/matrix"
+
+// CompareMatrix holds the cumulative value of functions for every value of
+// a label. The columns of the rows are ordered like Values, samples without
+// the label are in the column of the empty value.
+type CompareMatrix struct {
+	Label  string       `json:"label"`
+	Values []string     `json:"values"`
+	Totals []int64      `json:"totals"`
+	Rows   []*MatrixRow `json:"rows"`
+}
+
+// MatrixRow is the cumulative value of a function per label value, and its
+// share of the total of the value. Spread is the difference between the
+// largest and smallest share, rows are ordered by it, so the functions
+// deviating most between the label values come first.
+type MatrixRow struct {
+	Function   string    `json:"function"`
+	Cumulative []int64   `json:"cumulative"`
+	Shares     []float64 `json:"shares"`
+	Spread     float64   `json:"spread"`
+}
+
+// CompareMatrix merges the profiles selected by the query grouped by the
+// label and computes the matrix of the cumulative values of every function
+// per label value. At most limit rows are returned, if it is positive.
+func (q *ColumnQueryAPI) CompareMatrix(ctx context.Context, query string, start, end time.Time, label string, limit int) (*CompareMatrix, error) {
+	if label == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing label to compare by")
+	}
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	p, err := q.querier.QueryMerge(ctx, query, start, end, []string{FlamegraphFieldLabels + "." + label}, false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, r := range p.Samples {
+			r.Release()
+		}
+	}()
+
+	column := map[string]int{}
+	m := &CompareMatrix{Label: label}
+	cumulative := map[string]map[int]int64{}
+	seen := map[string]struct{}{}
+	for _, r := range profile.NewReader(p).RecordReaders {
+		var labelColumn *profile.LabelColumn
+		for i, f := range r.LabelFields {
+			if f.Name == profile.ColumnLabelsPrefix+label {
+				labelColumn = &r.LabelColumns[i]
+			}
+		}
+
+		for sampleRow := 0; sampleRow < int(r.Record.NumRows()); sampleRow++ {
+			value := ""
+			if labelColumn != nil && labelColumn.Col.IsValid(sampleRow) {
+				value = string(labelColumn.Dict.Value(int(labelColumn.Col.Value(sampleRow))))
+			}
+			c, ok := column[value]
+			if !ok {
+				c = len(m.Values)
+				column[value] = c
+				m.Values = append(m.Values, value)
+				m.Totals = append(m.Totals, 0)
+			}
+
+			v := r.Value.Value(sampleRow)
+			m.Totals[c] += v
+			forEachSampleFunction(r, sampleRow, seen, func(fn string) {
+				if cumulative[fn] == nil {
+					cumulative[fn] = map[int]int64{}
+				}
+				cumulative[fn][c] += v
+			})
+		}
+	}
+
+	// Columns are ordered by label value, which makes matrices of
+	// subsequent requests comparable.
+	order := make([]int, len(m.Values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return m.Values[order[i]] < m.Values[order[j]] })
+	values, totals := make([]string, len(order)), make([]int64, len(order))
+	for i, c := range order {
+		values[i], totals[i] = m.Values[c], m.Totals[c]
+	}
+	m.Values, m.Totals = values, totals
+
+	m.Rows = make([]*MatrixRow, 0, len(cumulative))
+	for fn, byColumn := range cumulative {
+		row := &MatrixRow{
+			Function:   fn,
+			Cumulative: make([]int64, len(order)),
+			Shares:     make([]float64, len(order)),
+		}
+		minShare, maxShare := 1.0, 0.0
+		for i, c := range order {
+			row.Cumulative[i] = byColumn[c]
+			if m.Totals[i] != 0 {
+				row.Shares[i] = float64(byColumn[c]) / float64(m.Totals[i])
+			}
+			minShare, maxShare = min(minShare, row.Shares[i]), max(maxShare, row.Shares[i])
+		}
+		row.Spread = maxShare - minShare
+		m.Rows = append(m.Rows, row)
+	}
+	sort.Slice(m.Rows, func(i, j int) bool {
+		if m.Rows[i].Spread != m.Rows[j].Spread {
+			return m.Rows[i].Spread > m.Rows[j].Spread
+		}
+		return m.Rows[i].Function < m.Rows[j].Function
+	})
+	if limit > 0 && len(m.Rows) > limit {
+		m.Rows = m.Rows[:limit]
+	}
+	return m, nil
+}
+
+// CompareMatrixHandler serves the compare matrix over HTTP. The query
+// parameters are "query" (a profile selector), "label", and optionally
+// "start" and "end" (RFC3339, defaulting to the last 24 hours) and "limit"
+// (defaulting to 100).
+func (q *ColumnQueryAPI) CompareMatrixHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		params := r.URL.Query()
+		start, end, err := parseTimeRangeParams(params.Get("start"), params.Get("end"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit := 100
+		if v := params.Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil {
+				http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		m, err := q.CompareMatrix(r.Context(), params.Get("query"), start, end, params.Get("label"), limit)
+		if err != nil {
+			http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+		writeJSON(w, m)
+	}
+}
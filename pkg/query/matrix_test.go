@@ -0,0 +1,88 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+type fakeGroupedQuerier struct {
+	Querier
+	groupBy []string
+}
+
+func (q *fakeGroupedQuerier) QueryMerge(_ context.Context, _ string, _, _ time.Time, groupBy []string, _ bool) (profile.Profile, error) {
+	q.groupBy = groupBy
+	main := &pprofprofile.Function{ID: 1, Name: "main"}
+	gc := &pprofprofile.Function{ID: 2, Name: "gc"}
+	mainLoc := &pprofprofile.Location{ID: 1, Line: []pprofprofile.Line{{Function: main}}}
+	gcLoc := &pprofprofile.Location{ID: 2, Line: []pprofprofile.Line{{Function: gc}}}
+	sample := func(value int64, version string, locs ...*pprofprofile.Location) *pprofprofile.Sample {
+		s := &pprofprofile.Sample{Location: locs, Value: []int64{value}}
+		if version != "" {
+			s.Label = map[string][]string{"version": {version}}
+		}
+		return s
+	}
+	return PprofToSymbolizedProfile(profile.Meta{}, &pprofprofile.Profile{
+		Sample: []*pprofprofile.Sample{
+			sample(90, "v2", mainLoc),
+			sample(10, "v2", gcLoc, mainLoc),
+			sample(50, "v1", mainLoc),
+			sample(50, "v1", gcLoc, mainLoc),
+			sample(5, "", mainLoc),
+		},
+	}, 0, nil)
+}
+
+func TestCompareMatrix(t *testing.T) {
+	querier := &fakeGroupedQuerier{}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		querier,
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+
+	m, err := api.CompareMatrix(context.Background(), `memory:alloc_space:bytes:space:bytes{}`, time.Unix(0, 0), time.Unix(60, 0), "version", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"labels.version"}, querier.groupBy)
+
+	require.Equal(t, []string{"", "v1", "v2"}, m.Values)
+	require.Equal(t, []int64{5, 100, 100}, m.Totals)
+	require.Len(t, m.Rows, 2)
+
+	// gc deviates between the versions, main is in every stack.
+	require.Equal(t, "gc", m.Rows[0].Function)
+	require.Equal(t, []int64{0, 50, 10}, m.Rows[0].Cumulative)
+	require.InDelta(t, 0.5, m.Rows[0].Spread, 0.001)
+	require.Equal(t, "main", m.Rows[1].Function)
+	require.Zero(t, m.Rows[1].Spread)
+
+	_, err = api.CompareMatrix(context.Background(), `memory:alloc_space:bytes:space:bytes{}`, time.Unix(0, 0), time.Unix(60, 0), "", 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+// ProfileCaptureMetadata returns the metadata written along with the profiles
+// matching the query in the requested time range, the last 24 hours by
+// default.
+func (q *ColumnQueryAPI) ProfileCaptureMetadata(ctx context.Context, req *pb.ProfileCaptureMetadataRequest) (*pb.ProfileCaptureMetadataResponse, error) {
+	start, end := requestTimeRange(req.Start, req.End)
+
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	res, err := q.querier.ProfileMetadata(ctx, req.Query, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]*pb.CaptureMetadata, 0, len(res))
+	for _, m := range res {
+		profiles = append(profiles, &pb.CaptureMetadata{
+			Labels:    labelSetFromMap(m.Labels),
+			Timestamp: timestamppb.New(m.Timestamp),
+			Metadata:  m.Metadata,
+		})
+	}
+	return &pb.ProfileCaptureMetadataResponse{Profiles: profiles}, nil
+}
+
+// labelSetFromMap returns the labels as a label set sorted by name.
+func labelSetFromMap(m map[string]string) *profilestorepb.LabelSet {
+	ls := labels.FromMap(m)
+	res := &profilestorepb.LabelSet{Labels: make([]*profilestorepb.Label, 0, ls.Len())}
+	ls.Range(func(l labels.Label) {
+		res.Labels = append(res.Labels, &profilestorepb.Label{Name: l.Name, Value: l.Value})
+	})
+	return res
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+type fakeMetadataQuerier struct {
+	Querier
+	start, end time.Time
+}
+
+func (q *fakeMetadataQuerier) ProfileMetadata(_ context.Context, _ string, start, end time.Time) ([]*parcacol.ProfileMetadata, error) {
+	q.start, q.end = start, end
+	return []*parcacol.ProfileMetadata{{
+		Labels:    map[string]string{"job": "api", "instance": "a"},
+		Timestamp: end,
+		Metadata:  map[string]string{"agent_version": "v1"},
+	}}, nil
+}
+
+func TestProfileCaptureMetadata(t *testing.T) {
+	querier := &fakeMetadataQuerier{}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		querier,
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+
+	end := time.Unix(1700000000, 0).UTC()
+	res, err := api.ProfileCaptureMetadata(context.Background(), &pb.ProfileCaptureMetadataRequest{
+		Query: "process_cpu:samples:count:cpu:nanoseconds:delta{}",
+		End:   timestamppb.New(end),
+	})
+	require.NoError(t, err)
+
+	// The start defaults to a day before the end.
+	require.Equal(t, end.Add(-24*time.Hour), querier.start)
+	require.Equal(t, end, querier.end)
+
+	require.Len(t, res.Profiles, 1)
+	require.Equal(t, []*profilestorepb.Label{
+		{Name: "instance", Value: "a"},
+		{Name: "job", Value: "api"},
+	}, res.Profiles[0].Labels.Labels)
+	require.Equal(t, end, res.Profiles[0].Timestamp.AsTime())
+	require.Equal(t, map[string]string{"agent_version": "v1"}, res.Profiles[0].Metadata)
+}
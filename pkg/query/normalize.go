@@ -0,0 +1,363 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// QueryNormalizedPath is the HTTP path of the query endpoint dividing the
+// values of the selected profiles by an external scalar, relative to the API
+// root.
+const QueryNormalizedPath = "/profiles/query_normalized"
+
+// NormalizedValueScale is the amount of the divisor the values of normalized
+// profiles are per. Values are integers, so they are reported per thousand
+// units of the divisor, eg. CPU nanoseconds per 1000 requests, which keeps
+// values smaller than the divisor from being rounded to zero.
+const NormalizedValueScale = 1000
+
+// ScalarSource evaluates an external time series to the total over a time
+// range, eg. the number of requests served, to normalize profiles by.
+type ScalarSource interface {
+	Total(ctx context.Context, query string, start, end time.Time) (float64, error)
+}
+
+// PrometheusScalarSource reads scalars from the HTTP API of Prometheus or a
+// compatible system. The query is expected to return a per-second rate, eg.
+// `sum(rate(http_requests_total{job="api"}[1m]))`, its total over a time
+// range is the rate integrated over the range.
+type PrometheusScalarSource struct {
+	client *http.Client
+	url    string
+	// points is the number of points the range is evaluated at.
+	points int
+}
+
+// NewPrometheusScalarSource returns a scalar source reading from the
+// Prometheus API at the URL.
+func NewPrometheusScalarSource(client *http.Client, url string) *PrometheusScalarSource {
+	return &PrometheusScalarSource{client: client, url: url, points: 100}
+}
+
+func (s *PrometheusScalarSource) Total(ctx context.Context, query string, start, end time.Time) (float64, error) {
+	step := end.Sub(start) / time.Duration(s.points)
+	if step < time.Second {
+		step = time.Second
+	}
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatFloat(float64(start.UnixMilli())/1e3, 'f', -1, 64))
+	params.Set("end", strconv.FormatFloat(float64(end.UnixMilli())/1e3, 'f', -1, 64))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/api/v1/query_range?"+params.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("query prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []struct {
+				Values [][2]any `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, fmt.Errorf("decode prometheus response: %w", err)
+	}
+	if res.Status != "success" {
+		return 0, fmt.Errorf("query prometheus: %s", res.Error)
+	}
+	if len(res.Data.Result) != 1 {
+		return 0, fmt.Errorf("query prometheus: expected a single series, got %d", len(res.Data.Result))
+	}
+
+	var total float64
+	for _, v := range res.Data.Result[0].Values {
+		s, ok := v[1].(string)
+		if !ok {
+			return 0, fmt.Errorf("query prometheus: unexpected sample value %v", v[1])
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("query prometheus: %w", err)
+		}
+		total += f * step.Seconds()
+	}
+	return total, nil
+}
+
+// selectionKey identifies the profiles selected by one side of a query.
+type selectionKey struct {
+	query      string
+	start, end int64
+}
+
+// scaledQuerier divides the values of the profiles it selects by the divisor
+// of their selection.
+type scaledQuerier struct {
+	Querier
+	mem      memory.Allocator
+	divisors map[selectionKey]float64
+}
+
+func (q *scaledQuerier) QuerySingle(ctx context.Context, query string, t time.Time, invertCallStacks bool) (profile.Profile, error) {
+	p, err := q.Querier.QuerySingle(ctx, query, t, invertCallStacks)
+	if err != nil {
+		return p, err
+	}
+	return scaleProfile(q.mem, p, q.divisors[selectionKey{query: query, start: t.UnixMilli(), end: t.UnixMilli()}]), nil
+}
+
+func (q *scaledQuerier) QueryMerge(ctx context.Context, query string, start, end time.Time, aggregateByLabels []string, invertCallStacks bool) (profile.Profile, error) {
+	p, err := q.Querier.QueryMerge(ctx, query, start, end, aggregateByLabels, invertCallStacks)
+	if err != nil {
+		return p, err
+	}
+	return scaleProfile(q.mem, p, q.divisors[selectionKey{query: query, start: start.UnixMilli(), end: end.UnixMilli()}]), nil
+}
+
+// scaleProfile divides the values of the profile by the divisor in units of
+// NormalizedValueScale, releasing the original samples. A divisor of zero
+// leaves the profile unchanged.
+func scaleProfile(mem memory.Allocator, p profile.Profile, divisor float64) profile.Profile {
+	if divisor == 0 {
+		return p
+	}
+
+	samples := make([]arrow.Record, 0, len(p.Samples))
+	for _, r := range p.Samples {
+		cols := make([]arrow.Array, len(r.Columns()))
+		copy(cols, r.Columns())
+		value := divideInt64(mem, cols[len(cols)-2].(*array.Int64), divisor)
+		diff := divideInt64(mem, cols[len(cols)-1].(*array.Int64), divisor)
+		cols[len(cols)-2], cols[len(cols)-1] = value, diff
+		samples = append(samples, array.NewRecord(r.Schema(), cols, r.NumRows()))
+		value.Release()
+		diff.Release()
+		r.Release()
+	}
+	p.Samples = samples
+	return p
+}
+
+func divideInt64(mem memory.Allocator, arr *array.Int64, divisor float64) arrow.Array {
+	b := array.NewInt64Builder(mem)
+	defer b.Release()
+
+	b.Reserve(arr.Len())
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		b.Append(int64(math.Round(float64(arr.Value(i)) * NormalizedValueScale / divisor)))
+	}
+	return b.NewArray()
+}
+
+// Divisors of the sides of a normalized query. A is the divisor of single
+// and merge queries and of the base of a diff, B the one of the compared
+// side of a diff.
+type Divisors struct {
+	A, B float64
+}
+
+// QueryNormalized runs the query with the values of its profiles divided by
+// the divisors, eg. the number of requests served in the selected time
+// ranges, which turns CPU time into CPU time per NormalizedValueScale
+// requests. Diffs of
+// normalized profiles compare absolute values, since normalizing replaces
+// scaling both sides to the same total.
+func (q *ColumnQueryAPI) QueryNormalized(ctx context.Context, req *pb.QueryRequest, divisors Divisors) (*pb.QueryResponse, error) {
+	keys := map[selectionKey]float64{}
+	add := func(k selectionKey, divisor float64) error {
+		if divisor < 0 {
+			return status.Error(codes.InvalidArgument, "divisors must not be negative")
+		}
+		if d, ok := keys[k]; ok && d != divisor {
+			return status.Error(codes.InvalidArgument, "both sides of the diff select the same profiles with different divisors")
+		}
+		keys[k] = divisor
+		return nil
+	}
+
+	switch req.Mode {
+	case pb.QueryRequest_MODE_SINGLE_UNSPECIFIED:
+		if err := add(singleKey(req.GetSingle()), divisors.A); err != nil {
+			return nil, err
+		}
+	case pb.QueryRequest_MODE_MERGE:
+		if err := add(mergeKey(req.GetMerge()), divisors.A); err != nil {
+			return nil, err
+		}
+	case pb.QueryRequest_MODE_DIFF:
+		for _, side := range []struct {
+			s       *pb.ProfileDiffSelection
+			divisor float64
+		}{{req.GetDiff().GetA(), divisors.A}, {req.GetDiff().GetB(), divisors.B}} {
+			k := singleKey(side.s.GetSingle())
+			if side.s.GetMode() == pb.ProfileDiffSelection_MODE_MERGE {
+				k = mergeKey(side.s.GetMerge())
+			}
+			if err := add(k, side.divisor); err != nil {
+				return nil, err
+			}
+		}
+		if req.GetDiff() != nil {
+			req = proto.Clone(req).(*pb.QueryRequest)
+			req.GetDiff().Absolute = proto.Bool(true)
+		}
+	}
+
+	nq := *q
+	nq.querier = &scaledQuerier{Querier: q.querier, mem: q.mem, divisors: keys}
+	return nq.Query(ctx, req)
+}
+
+func singleKey(s *pb.SingleProfile) selectionKey {
+	t := s.GetTime().AsTime().UnixMilli()
+	return selectionKey{query: s.GetQuery(), start: t, end: t}
+}
+
+func mergeKey(m *pb.MergeProfile) selectionKey {
+	return selectionKey{query: m.GetQuery(), start: m.GetStart().AsTime().UnixMilli(), end: m.GetEnd().AsTime().UnixMilli()}
+}
+
+// selectionRange returns the time range the selection covers, a single
+// profile covers its duration.
+func selectionRange(s *pb.ProfileDiffSelection) (time.Time, time.Time) {
+	if s.GetMode() == pb.ProfileDiffSelection_MODE_MERGE {
+		return s.GetMerge().GetStart().AsTime(), s.GetMerge().GetEnd().AsTime()
+	}
+	t := s.GetSingle().GetTime().AsTime()
+	return t, t
+}
+
+// QueryNormalizedHandler serves queries normalized by external scalars. The
+// request body is a QueryRequest in its JSON encoding and the response a
+// QueryResponse in its JSON encoding. The divisors are given inline by the
+// "divisor" and, for the compared side of diffs, "divisor_b" query
+// parameters, or as a "divisor_query" evaluated by the source over the time
+// range of each side. Single profiles can only be normalized by inline
+// divisors. The values are per NormalizedValueScale units of the divisors,
+// which is reported in the X-Parca-Normalized-Scale header.
+func (q *ColumnQueryAPI) QueryNormalizedHandler(source ScalarSource) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		req := &pb.QueryRequest{}
+		if err := unmarshalBody(r.Body, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params := r.URL.Query()
+		var divisors Divisors
+		if v := params.Get("divisor_query"); v != "" {
+			if source == nil {
+				http.Error(w, "no scalar source is configured for divisor queries", http.StatusBadRequest)
+				return
+			}
+			sides := []*pb.ProfileDiffSelection{{
+				Mode:    pb.ProfileDiffSelection_MODE_MERGE,
+				Options: &pb.ProfileDiffSelection_Merge{Merge: req.GetMerge()},
+			}}
+			switch req.Mode {
+			case pb.QueryRequest_MODE_MERGE:
+			case pb.QueryRequest_MODE_DIFF:
+				sides = []*pb.ProfileDiffSelection{req.GetDiff().GetA(), req.GetDiff().GetB()}
+			default:
+				http.Error(w, "divisor queries need merge or diff queries", http.StatusBadRequest)
+				return
+			}
+			totals := make([]float64, len(sides))
+			for i, side := range sides {
+				start, end := selectionRange(side)
+				if !end.After(start) {
+					http.Error(w, "divisor queries need merged time ranges", http.StatusBadRequest)
+					return
+				}
+				total, err := source.Total(r.Context(), v, start, end)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadGateway)
+					return
+				}
+				totals[i] = total
+			}
+			divisors.A = totals[0]
+			if len(totals) > 1 {
+				divisors.B = totals[1]
+			}
+		} else {
+			for name, d := range map[string]*float64{"divisor": &divisors.A, "divisor_b": &divisors.B} {
+				v := params.Get(name)
+				if v == "" {
+					continue
+				}
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("invalid %s: %v", name, err), http.StatusBadRequest)
+					return
+				}
+				*d = f
+			}
+			if divisors.B == 0 {
+				divisors.B = divisors.A
+			}
+		}
+
+		resp, err := q.QueryNormalized(r.Context(), req, divisors)
+		if err != nil {
+			http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+
+		b, err := protojson.Marshal(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Parca-Divisors", fmt.Sprintf("%g,%g", divisors.A, divisors.B))
+		w.Header().Set("X-Parca-Normalized-Scale", strconv.Itoa(NormalizedValueScale))
+		_, _ = w.Write(b)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func TestScaleProfile(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	fn := &pprofprofile.Function{ID: 1, Name: "main"}
+	loc := &pprofprofile.Location{ID: 1, Line: []pprofprofile.Line{{Function: fn}}}
+	p, err := PprofToSymbolizedProfile(profile.Meta{}, &pprofprofile.Profile{
+		Sample: []*pprofprofile.Sample{
+			{Location: []*pprofprofile.Location{loc}, Value: []int64{100}},
+			{Location: []*pprofprofile.Location{loc}, Value: []int64{55}},
+			// Values smaller than the divisor are kept.
+			{Location: []*pprofprofile.Location{loc}, Value: []int64{3}},
+		},
+	}, 0, nil)
+	require.NoError(t, err)
+
+	p = scaleProfile(mem, p, 7)
+	require.Len(t, p.Samples, 1)
+	defer p.Samples[0].Release()
+
+	// The values are per thousand units of the divisor, rounded.
+	cols := p.Samples[0].Columns()
+	require.Equal(t, []int64{14286, 7857, 429}, cols[len(cols)-2].(*array.Int64).Int64Values())
+	require.Equal(t, []int64{0, 0, 0}, cols[len(cols)-1].(*array.Int64).Int64Values())
+}
+
+func TestPrometheusScalarSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/query_range", r.URL.Path)
+		require.Equal(t, "sum(rate(requests[1m]))", r.URL.Query().Get("query"))
+		require.Equal(t, "2", r.URL.Query().Get("step"))
+
+		values := ""
+		for i := 0; i <= 100; i++ {
+			if i > 0 {
+				values += ","
+			}
+			values += fmt.Sprintf(`[%d,"3"]`, i*2)
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[%s]}]}}`, values)
+	}))
+	defer srv.Close()
+
+	s := NewPrometheusScalarSource(srv.Client(), srv.URL)
+	total, err := s.Total(context.Background(), "sum(rate(requests[1m]))", time.Unix(0, 0), time.Unix(200, 0))
+	require.NoError(t, err)
+	// 101 points of 3 requests per second, two seconds apart.
+	require.Equal(t, 606.0, total)
+}
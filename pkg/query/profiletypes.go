@@ -0,0 +1,52 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package query
+
+import (
+	"context"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+// ProfileTypeCounts returns the profile types stored for the series matching
+// the selector, a set of label matchers matching all series if empty, with
+// the number of samples stored in the requested time range, the last 24
+// hours by default.
+func (q *ColumnQueryAPI) ProfileTypeCounts(ctx context.Context, req *pb.ProfileTypeCountsRequest) (*pb.ProfileTypeCountsResponse, error) {
+	start, end := requestTimeRange(req.Start, req.End)
+
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	res, err := q.querier.ProfileTypeCounts(ctx, req.Selector, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*pb.ProfileTypeCount, 0, len(res))
+	for _, t := range res {
+		types = append(types, &pb.ProfileTypeCount{
+			ProfileType: t.ProfileType,
+			Type: &pb.ProfileType{
+				Name:       t.Name,
+				SampleType: t.SampleType,
+				SampleUnit: t.SampleUnit,
+				PeriodType: t.PeriodType,
+				PeriodUnit: t.PeriodUnit,
+				Delta:      t.Delta,
+			},
+			Samples: t.Samples,
+		})
+	}
+	return &pb.ProfileTypeCountsResponse{Types: types}, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+// stageSelect is the stage of selecting the data of a query from storage,
+// including its aggregation.
+const stageSelect = "select"
+
+// QueryMetrics continuously records how long the stages of the query
+// service requests take and how many rows they select. They complement the
+// slow query log, which explains individual queries.
+type QueryMetrics struct {
+	stageDuration *prometheus.HistogramVec
+	selectedRows  *prometheus.HistogramVec
+}
+
+// NewQueryMetrics returns the query metrics registered with reg.
+func NewQueryMetrics(reg prometheus.Registerer) *QueryMetrics {
+	return &QueryMetrics{
+		stageDuration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "parca_query_stage_duration_seconds",
+			Help:    "Duration of the stages of query service requests by method: selecting the data, symbolizing and rendering it.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"method", "stage"}),
+		selectedRows: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "parca_query_selected_rows",
+			Help:    "Number of rows selected from storage by query service requests by method.",
+			Buckets: prometheus.ExponentialBuckets(100, 4, 10),
+		}, []string{"method"}),
+	}
+}
+
+// UnaryServerInterceptor collects the statistics of the query service
+// requests and records them.
+func (m *QueryMetrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, queryMethodPrefix) {
+			return handler(ctx, req)
+		}
+
+		ctx, stats := parcacol.WithQueryStats(ctx)
+		resp, err := handler(ctx, req)
+		m.observe(path.Base(info.FullMethod), stats)
+		return resp, err
+	}
+}
+
+// HandlerInterceptor collects the statistics of the requests of a query
+// endpoint served over HTTP and records them.
+func (m *QueryMetrics) HandlerInterceptor() HandlerInterceptor {
+	return func(method string, next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			ctx, stats := parcacol.WithQueryStats(r.Context())
+			next(w, r.WithContext(ctx), pathParams)
+			m.observe(method, stats)
+		}
+	}
+}
+
+func (m *QueryMetrics) observe(method string, stats *parcacol.QueryStats) {
+	selections := stats.Selections()
+	if len(selections) > 0 {
+		var rows int
+		var selectDuration float64
+		for _, sel := range selections {
+			rows += sel.Rows
+			selectDuration += sel.Duration.Seconds()
+		}
+		m.selectedRows.WithLabelValues(method).Observe(float64(rows))
+		m.stageDuration.WithLabelValues(method, stageSelect).Observe(selectDuration)
+	}
+
+	for stage, d := range stats.Stages() {
+		m.stageDuration.WithLabelValues(method, stage).Observe(d.Seconds())
+	}
+}
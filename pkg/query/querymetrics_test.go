@@ -0,0 +1,52 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+func TestQueryMetrics(t *testing.T) {
+	m := NewQueryMetrics(prometheus.NewRegistry())
+	interceptor := m.UnaryServerInterceptor()
+
+	// Statistics collected by an outer interceptor are shared.
+	ctx, outer := parcacol.WithQueryStats(context.Background())
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/parca.query.v1alpha1.QueryService/Query"}, func(ctx context.Context, _ any) (any, error) {
+		parcacol.ObserveStage(ctx, parcacol.StageSymbolize, time.Now().Add(-time.Second))
+		parcacol.ObserveStage(ctx, parcacol.StageRender, time.Now())
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.InDelta(t, time.Second, outer.Stages()[parcacol.StageSymbolize], float64(100*time.Millisecond))
+
+	// Other services are not recorded.
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/parca.share.v1alpha1.ShareService/Upload"}, func(ctx context.Context, _ any) (any, error) {
+		parcacol.ObserveStage(ctx, parcacol.StageRender, time.Now())
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 2, testutil.CollectAndCount(m.stageDuration))
+	require.Equal(t, 0, testutil.CollectAndCount(m.selectedRows))
+}
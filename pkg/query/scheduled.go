@@ -0,0 +1,317 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+// ScheduledQuery periodically queries the top functions of the profiles
+// of the last Range and pushes them to the sinks.
+type ScheduledQuery struct {
+	Name     string
+	Query    string
+	Range    time.Duration
+	Interval time.Duration
+	// Limit is the number of functions pushed, by their flat value. Zero
+	// pushes all functions.
+	Limit int
+	// Diff compares the range against the preceding range of the same
+	// length.
+	Diff  bool
+	Sinks []ResultSink
+}
+
+// ScheduledResult is a result of a scheduled query.
+type ScheduledResult struct {
+	Name      string              `json:"name"`
+	Query     string              `json:"query"`
+	Start     time.Time           `json:"start"`
+	End       time.Time           `json:"end"`
+	Unit      string              `json:"unit"`
+	Total     int64               `json:"total"`
+	Functions []ScheduledFunction `json:"functions"`
+}
+
+// ScheduledFunction is the value of a function in a scheduled result. The
+// diff is only set for queries comparing against the preceding range.
+type ScheduledFunction struct {
+	Function   string `json:"function"`
+	Filename   string `json:"filename,omitempty"`
+	Cumulative int64  `json:"cumulative"`
+	Flat       int64  `json:"flat"`
+	Diff       int64  `json:"diff,omitempty"`
+}
+
+// ResultSink receives the results of scheduled queries.
+type ResultSink interface {
+	Push(ctx context.Context, res *ScheduledResult) error
+}
+
+// WebhookSink posts the results as JSON to a URL.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	return &WebhookSink{url: url, client: client}
+}
+
+func (s *WebhookSink) Push(ctx context.Context, res *ScheduledResult) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+// Formats of the results written by a BucketSink.
+const (
+	ResultFormatJSON    = "json"
+	ResultFormatParquet = "parquet"
+)
+
+// BucketSink writes every result to an object named by the query and the
+// end of its range, eg. "cpu/1700000000.json".
+type BucketSink struct {
+	bucket objstore.Bucket
+	format string
+}
+
+func NewBucketSink(bucket objstore.Bucket, format string) *BucketSink {
+	return &BucketSink{bucket: bucket, format: format}
+}
+
+// scheduledResultRow is a row of the results written as Parquet, one per
+// function.
+type scheduledResultRow struct {
+	Name       string `parquet:"name,dict"`
+	Start      int64  `parquet:"start"`
+	End        int64  `parquet:"end"`
+	Unit       string `parquet:"unit,dict"`
+	Function   string `parquet:"function"`
+	Filename   string `parquet:"filename,dict"`
+	Cumulative int64  `parquet:"cumulative"`
+	Flat       int64  `parquet:"flat"`
+	Diff       int64  `parquet:"diff"`
+}
+
+func (s *BucketSink) Push(ctx context.Context, res *ScheduledResult) error {
+	buf := &bytes.Buffer{}
+	switch s.format {
+	case ResultFormatParquet:
+		rows := make([]scheduledResultRow, 0, len(res.Functions))
+		for _, f := range res.Functions {
+			rows = append(rows, scheduledResultRow{
+				Name:       res.Name,
+				Start:      res.Start.UnixMilli(),
+				End:        res.End.UnixMilli(),
+				Unit:       res.Unit,
+				Function:   f.Function,
+				Filename:   f.Filename,
+				Cumulative: f.Cumulative,
+				Flat:       f.Flat,
+				Diff:       f.Diff,
+			})
+		}
+		if err := parquet.Write(buf, rows); err != nil {
+			return err
+		}
+	default:
+		if err := json.NewEncoder(buf).Encode(res); err != nil {
+			return err
+		}
+	}
+
+	name := path.Join(res.Name, fmt.Sprintf("%d.%s", res.End.Unix(), s.extension()))
+	return s.bucket.Upload(ctx, name, buf)
+}
+
+func (s *BucketSink) extension() string {
+	if s.format == ResultFormatParquet {
+		return ResultFormatParquet
+	}
+	return ResultFormatJSON
+}
+
+// Scheduler runs the scheduled queries and pushes their results to their
+// sinks.
+type Scheduler struct {
+	logger  log.Logger
+	querier exportQuerier
+	queries []ScheduledQuery
+
+	runs   *prometheus.CounterVec
+	errors *prometheus.CounterVec
+}
+
+func NewScheduler(logger log.Logger, reg prometheus.Registerer, querier exportQuerier, queries []ScheduledQuery) *Scheduler {
+	return &Scheduler{
+		logger:  logger,
+		querier: querier,
+		queries: queries,
+		runs: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_scheduled_query_runs_total",
+			Help: "Total number of runs of scheduled queries.",
+		}, []string{"query"}),
+		errors: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "parca_scheduled_query_errors_total",
+			Help: "Total number of failed runs of scheduled queries, by whether running the query or pushing the result failed.",
+		}, []string{"query", "stage"}),
+	}
+}
+
+// Run runs every query in its interval until the context is canceled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, q := range s.queries {
+		g.Go(func() error {
+			ticker := time.NewTicker(q.Interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case now := <-ticker.C:
+					s.run(ctx, q, now)
+				}
+			}
+		})
+	}
+	return g.Wait()
+}
+
+// run runs the query for the range ending at the given time and pushes the
+// result to every sink. Failures are logged, the next run is unaffected.
+func (s *Scheduler) run(ctx context.Context, q ScheduledQuery, end time.Time) {
+	s.runs.WithLabelValues(q.Name).Inc()
+
+	res, err := s.Evaluate(ctx, q, end)
+	if err != nil {
+		s.errors.WithLabelValues(q.Name, "query").Inc()
+		level.Warn(s.logger).Log("msg", "failed to run scheduled query", "query", q.Name, "err", err)
+		return
+	}
+
+	for _, sink := range q.Sinks {
+		if err := sink.Push(ctx, res); err != nil {
+			s.errors.WithLabelValues(q.Name, "push").Inc()
+			level.Warn(s.logger).Log("msg", "failed to push scheduled query result", "query", q.Name, "err", err)
+		}
+	}
+}
+
+// Evaluate queries the top functions of the range ending at the given time.
+func (s *Scheduler) Evaluate(ctx context.Context, q ScheduledQuery, end time.Time) (*ScheduledResult, error) {
+	start := end.Add(-q.Range)
+	merge := func(start, end time.Time) *pb.MergeProfile {
+		return &pb.MergeProfile{
+			Query: q.Query,
+			Start: timestamppb.New(start),
+			End:   timestamppb.New(end),
+		}
+	}
+
+	req := &pb.QueryRequest{
+		Mode:       pb.QueryRequest_MODE_MERGE,
+		ReportType: pb.QueryRequest_REPORT_TYPE_TOP,
+		Options:    &pb.QueryRequest_Merge{Merge: merge(start, end)},
+	}
+	if q.Diff {
+		req.Mode = pb.QueryRequest_MODE_DIFF
+		req.Options = &pb.QueryRequest_Diff{Diff: &pb.DiffProfile{
+			A: &pb.ProfileDiffSelection{
+				Mode:    pb.ProfileDiffSelection_MODE_MERGE,
+				Options: &pb.ProfileDiffSelection_Merge{Merge: merge(start.Add(-q.Range), start)},
+			},
+			B: &pb.ProfileDiffSelection{
+				Mode:    pb.ProfileDiffSelection_MODE_MERGE,
+				Options: &pb.ProfileDiffSelection_Merge{Merge: merge(start, end)},
+			},
+		}}
+	}
+
+	res := &ScheduledResult{
+		Name:      q.Name,
+		Query:     q.Query,
+		Start:     start,
+		End:       end,
+		Functions: []ScheduledFunction{},
+	}
+
+	resp, err := s.querier.Query(ctx, req)
+	if status.Code(err) == codes.NotFound {
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	top := resp.GetTop()
+	if top == nil {
+		return nil, errors.New("query returned no top report")
+	}
+	res.Unit = top.GetUnit()
+	res.Total = resp.Total
+	for _, n := range top.GetList() {
+		if q.Limit > 0 && len(res.Functions) == q.Limit {
+			break
+		}
+		res.Functions = append(res.Functions, ScheduledFunction{
+			Function:   n.GetMeta().GetFunction().GetName(),
+			Filename:   n.GetMeta().GetFunction().GetFilename(),
+			Cumulative: n.Cumulative,
+			Flat:       n.Flat,
+			Diff:       n.Diff,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	metastorev1alpha1 "github.com/parca-dev/parca/gen/proto/go/parca/metastore/v1alpha1"
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+type fakeTopQuerier struct {
+	req *pb.QueryRequest
+}
+
+func (q *fakeTopQuerier) Query(_ context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	q.req = req
+	node := func(name string, flat int64) *pb.TopNode {
+		return &pb.TopNode{
+			Meta: &pb.TopNodeMeta{Function: &metastorev1alpha1.Function{Name: name}},
+			Flat: flat, Cumulative: flat,
+		}
+	}
+	return &pb.QueryResponse{
+		Total: 6,
+		Report: &pb.QueryResponse_Top{Top: &pb.Top{
+			Unit: "nanoseconds",
+			List: []*pb.TopNode{node("main", 3), node("run", 2), node("gc", 1)},
+		}},
+	}, nil
+}
+
+func TestSchedulerEvaluate(t *testing.T) {
+	ctx := context.Background()
+	q := &fakeTopQuerier{}
+	s := NewScheduler(nil, nil, q, nil)
+
+	end := time.Unix(3600, 0).UTC()
+	res, err := s.Evaluate(ctx, ScheduledQuery{Name: "cpu", Query: "cpu{}", Range: time.Hour, Limit: 2}, end)
+	require.NoError(t, err)
+	require.Equal(t, pb.QueryRequest_REPORT_TYPE_TOP, q.req.ReportType)
+	require.Equal(t, time.Unix(0, 0).UTC(), res.Start)
+	require.Equal(t, "nanoseconds", res.Unit)
+	require.Equal(t, int64(6), res.Total)
+	require.Equal(t, []ScheduledFunction{
+		{Function: "main", Cumulative: 3, Flat: 3},
+		{Function: "run", Cumulative: 2, Flat: 2},
+	}, res.Functions)
+
+	// Diffs compare against the preceding range.
+	_, err = s.Evaluate(ctx, ScheduledQuery{Name: "cpu", Query: "cpu{}", Range: time.Hour, Diff: true}, end)
+	require.NoError(t, err)
+	require.Equal(t, pb.QueryRequest_MODE_DIFF, q.req.Mode)
+	require.Equal(t, time.Unix(-3600, 0).UTC(), q.req.GetDiff().GetA().GetMerge().GetStart().AsTime())
+	require.Equal(t, end, q.req.GetDiff().GetB().GetMerge().GetEnd().AsTime())
+
+	bucket := objstore.NewInMemBucket()
+	require.NoError(t, NewBucketSink(bucket, ResultFormatJSON).Push(ctx, res))
+	rc, err := bucket.Get(ctx, "cpu/3600.json")
+	require.NoError(t, err)
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+
+	var pushed ScheduledResult
+	require.NoError(t, json.Unmarshal(b, &pushed))
+	require.Equal(t, res.Functions, pushed.Functions)
+
+	require.NoError(t, NewBucketSink(bucket, ResultFormatParquet).Push(ctx, res))
+	exists, err := bucket.Exists(ctx, "cpu/3600.parquet")
+	require.NoError(t, err)
+	require.True(t, exists)
+}
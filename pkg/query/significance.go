@@ -0,0 +1,259 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// MaxSignificanceWindows is the maximum number of windows per side of a
+// significance test. Every window is merged separately, so the number of
+// windows bounds the number of merges a single request causes.
+const MaxSignificanceWindows = 100
+
+// DiffSignificance tests the significance of the per-function changes of the
+// requested diff. The number of windows defaults to 10, the confidence level
+// to 0.95 and the number of functions returned to 100.
+func (q *ColumnQueryAPI) DiffSignificance(ctx context.Context, req *pb.DiffSignificanceRequest) (*pb.DiffSignificanceResponse, error) {
+	windows, confidence, limit := 10, 0.95, 100
+	if req.Windows != 0 {
+		windows = int(req.Windows)
+	}
+	if req.Confidence != 0 {
+		confidence = req.Confidence
+	}
+	if req.Limit != 0 {
+		limit = int(req.Limit)
+	}
+
+	res, err := q.diffSignificance(ctx, req.Diff, windows, confidence)
+	if err != nil {
+		return nil, err
+	}
+	if req.SignificantOnly {
+		filtered := res[:0]
+		for _, f := range res {
+			if f.Significant {
+				filtered = append(filtered, f)
+			}
+		}
+		res = filtered
+	}
+	if len(res) > limit {
+		res = res[:limit]
+	}
+
+	return &pb.DiffSignificanceResponse{Functions: res}, nil
+}
+
+// diffSignificance splits both merge selections of the diff into the given
+// number of windows, merges each window separately and compares the
+// cumulative share every function has in the windows of both sides. Shares
+// rather than absolute values are compared, so that a change in traffic
+// alone doesn't make every function significant. A delta is significant if
+// its confidence interval, computed with Welch's approximation for the given
+// confidence level, doesn't contain zero.
+func (q *ColumnQueryAPI) diffSignificance(ctx context.Context, d *pb.DiffProfile, windows int, confidence float64) ([]*pb.FunctionSignificance, error) {
+	if d.GetA().GetMode() != pb.ProfileDiffSelection_MODE_MERGE || d.GetB().GetMode() != pb.ProfileDiffSelection_MODE_MERGE {
+		return nil, status.Error(codes.InvalidArgument, "significance testing needs merge selections on both sides of the diff")
+	}
+	if windows < 2 {
+		return nil, status.Error(codes.InvalidArgument, "significance testing needs at least two windows per side")
+	}
+	if windows > MaxSignificanceWindows {
+		return nil, status.Errorf(codes.InvalidArgument, "significance testing supports at most %d windows per side", MaxSignificanceWindows)
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, status.Error(codes.InvalidArgument, "confidence must be between 0 and 1")
+	}
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	var a, b []map[string]float64
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		a, err = q.windowShares(ctx, d.A.GetMerge(), windows)
+		if err != nil {
+			return fmt.Errorf("reading base profiles: %w", err)
+		}
+		return nil
+	})
+	g.Go(func() error {
+		var err error
+		b, err = q.windowShares(ctx, d.B.GetMerge(), windows)
+		if err != nil {
+			return fmt.Errorf("reading compared profiles: %w", err)
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if len(a) < 2 || len(b) < 2 {
+		return nil, status.Error(codes.FailedPrecondition, "not enough windows with samples on both sides of the diff")
+	}
+
+	return compareShares(a, b, confidence), nil
+}
+
+// windowShares merges the profiles of each window of the selection and
+// returns the cumulative share of every function in the windows that have
+// samples. Windows without any profiles are skipped like empty ones.
+func (q *ColumnQueryAPI) windowShares(ctx context.Context, m *pb.MergeProfile, windows int) ([]map[string]float64, error) {
+	start, end := m.GetStart().AsTime(), m.GetEnd().AsTime()
+	step := end.Sub(start) / time.Duration(windows)
+	if step <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "time range is too short for the number of windows")
+	}
+
+	res := make([]map[string]float64, 0, windows)
+	for i := 0; i < windows; i++ {
+		wStart := start.Add(time.Duration(i) * step)
+		p, err := q.querier.QueryMerge(ctx, m.GetQuery(), wStart, wStart.Add(step), nil, false)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		shares := functionShares(p)
+		for _, r := range p.Samples {
+			r.Release()
+		}
+		if shares != nil {
+			res = append(res, shares)
+		}
+	}
+	return res, nil
+}
+
+// functionShares returns the cumulative value of every function of the
+// profile as a share of the total value, or nil if the total is zero. A
+// function appearing multiple times in a stack counts once.
+func functionShares(p profile.Profile) map[string]float64 {
+	cumulative := map[string]int64{}
+	var total int64
+	seen := map[string]struct{}{}
+	for _, r := range profile.NewReader(p).RecordReaders {
+		for sampleRow := 0; sampleRow < int(r.Record.NumRows()); sampleRow++ {
+			value := r.Value.Value(sampleRow)
+			total += value
+			forEachSampleFunction(r, sampleRow, seen, func(fn string) {
+				cumulative[fn] += value
+			})
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	shares := make(map[string]float64, len(cumulative))
+	for fn, v := range cumulative {
+		shares[fn] = float64(v) / float64(total)
+	}
+	return shares
+}
+
+// forEachSampleFunction calls fn once for every function in the stack of
+// the sample. The seen set is reset and used to skip repeated functions,
+// eg. of recursive calls.
+func forEachSampleFunction(r *profile.RecordReader, sampleRow int, seen map[string]struct{}, fn func(name string)) {
+	clear(seen)
+	lStart, lEnd := r.Locations.ValueOffsets(sampleRow)
+	for locationRow := int(lStart); locationRow < int(lEnd); locationRow++ {
+		if r.Locations.ListValues().IsNull(locationRow) || r.Lines.IsNull(locationRow) {
+			continue
+		}
+		llStart, llEnd := r.Lines.ValueOffsets(locationRow)
+		for lineRow := int(llStart); lineRow < int(llEnd); lineRow++ {
+			if !r.Line.IsValid(lineRow) || !r.LineFunctionNameIndices.IsValid(lineRow) {
+				continue
+			}
+			name := r.LineFunctionNameDict.Value(int(r.LineFunctionNameIndices.Value(lineRow)))
+			if _, ok := seen[string(name)]; ok {
+				continue
+			}
+			seen[string(name)] = struct{}{}
+			fn(string(name))
+		}
+	}
+}
+
+// compareShares computes the significance of the change of every function's
+// share, ordered by the absolute delta.
+func compareShares(a, b []map[string]float64, confidence float64) []*pb.FunctionSignificance {
+	functions := map[string]struct{}{}
+	for _, windows := range [][]map[string]float64{a, b} {
+		for _, shares := range windows {
+			for fn := range shares {
+				functions[fn] = struct{}{}
+			}
+		}
+	}
+
+	z := math.Sqrt2 * math.Erfinv(confidence)
+	res := make([]*pb.FunctionSignificance, 0, len(functions))
+	for fn := range functions {
+		meanA, varA := meanVariance(a, fn)
+		meanB, varB := meanVariance(b, fn)
+		delta := meanB - meanA
+		margin := z * math.Sqrt(varA/float64(len(a))+varB/float64(len(b)))
+		res = append(res, &pb.FunctionSignificance{
+			Function:    fn,
+			ShareA:      meanA,
+			ShareB:      meanB,
+			Delta:       delta,
+			Low:         delta - margin,
+			High:        delta + margin,
+			Significant: delta-margin > 0 || delta+margin < 0,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		di, dj := math.Abs(res[i].Delta), math.Abs(res[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return res[i].Function < res[j].Function
+	})
+	return res
+}
+
+// meanVariance returns the mean and sample variance of the share of the
+// function over the windows, where it is zero if it doesn't appear.
+func meanVariance(windows []map[string]float64, fn string) (float64, float64) {
+	var sum float64
+	for _, shares := range windows {
+		sum += shares[fn]
+	}
+	mean := sum / float64(len(windows))
+
+	var sq float64
+	for _, shares := range windows {
+		d := shares[fn] - mean
+		sq += d * d
+	}
+	return mean, sq / float64(len(windows)-1)
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// fakeWindowQuerier returns a profile with the stacks main;a and main;b for
+// every merged window. The values of a window are looked up by its start,
+// windows without values are not found.
+type fakeWindowQuerier struct {
+	Querier
+	values map[int64][2]int64
+}
+
+func (q *fakeWindowQuerier) QueryMerge(_ context.Context, _ string, start, _ time.Time, _ []string, _ bool) (profile.Profile, error) {
+	v, ok := q.values[start.Unix()]
+	if !ok {
+		return profile.Profile{}, status.Error(codes.NotFound, "no profiles found")
+	}
+	main := &pprofprofile.Function{ID: 1, Name: "main"}
+	a := &pprofprofile.Function{ID: 2, Name: "a"}
+	b := &pprofprofile.Function{ID: 3, Name: "b"}
+	loc := func(id uint64, fn *pprofprofile.Function) *pprofprofile.Location {
+		return &pprofprofile.Location{ID: id, Line: []pprofprofile.Line{{Function: fn}}}
+	}
+	mainLoc := loc(1, main)
+	return PprofToSymbolizedProfile(profile.Meta{}, &pprofprofile.Profile{
+		Sample: []*pprofprofile.Sample{
+			{Location: []*pprofprofile.Location{loc(2, a), mainLoc}, Value: []int64{v[0]}},
+			{Location: []*pprofprofile.Location{loc(3, b), mainLoc}, Value: []int64{v[1]}},
+		},
+	}, 0, nil)
+}
+
+func TestDiffSignificance(t *testing.T) {
+	querier := &fakeWindowQuerier{values: map[int64][2]int64{
+		// Side A, a has about half of the samples.
+		0: {50, 50}, 10: {48, 52}, 20: {52, 48}, 30: {0, 0},
+		// Side B, a has about 80% with twice the traffic.
+		100: {160, 40}, 110: {164, 36}, 120: {156, 44}, 130: {162, 38},
+	}}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		querier,
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+
+	selection := func(start int64) *pb.ProfileDiffSelection {
+		return &pb.ProfileDiffSelection{
+			Mode: pb.ProfileDiffSelection_MODE_MERGE,
+			Options: &pb.ProfileDiffSelection_Merge{Merge: &pb.MergeProfile{
+				Query: `parca_agent:samples:count:cpu:nanoseconds:delta{}`,
+				Start: timestamppb.New(time.Unix(start, 0)),
+				End:   timestamppb.New(time.Unix(start+40, 0)),
+			}},
+		}
+	}
+	resp, err := api.DiffSignificance(context.Background(), &pb.DiffSignificanceRequest{
+		Diff:    &pb.DiffProfile{A: selection(0), B: selection(100)},
+		Windows: 4,
+	})
+	require.NoError(t, err)
+	res := resp.Functions
+	require.Len(t, res, 3)
+
+	// The window without samples is skipped.
+	require.Equal(t, "a", res[0].Function)
+	require.InDelta(t, 0.5, res[0].ShareA, 0.001)
+	require.InDelta(t, 0.8, res[0].ShareB, 0.01)
+	require.True(t, res[0].Significant)
+	require.Less(t, 0.0, res[0].Low)
+
+	require.Equal(t, "b", res[1].Function)
+	require.True(t, res[1].Significant)
+	require.Less(t, res[1].High, 0.0)
+
+	// main is in every stack, its share doesn't change.
+	require.Equal(t, "main", res[2].Function)
+	require.False(t, res[2].Significant)
+
+	resp, err = api.DiffSignificance(context.Background(), &pb.DiffSignificanceRequest{
+		Diff:            &pb.DiffProfile{A: selection(0), B: selection(100)},
+		Windows:         4,
+		SignificantOnly: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Functions, 2)
+
+	_, err = api.DiffSignificance(context.Background(), &pb.DiffSignificanceRequest{
+		Diff:    &pb.DiffProfile{A: selection(0), B: selection(100)},
+		Windows: 1,
+	})
+	require.Error(t, err)
+
+	_, err = api.DiffSignificance(context.Background(), &pb.DiffSignificanceRequest{
+		Diff:    &pb.DiffProfile{A: selection(0), B: selection(100)},
+		Windows: MaxSignificanceWindows + 1,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestDiffSignificanceGap(t *testing.T) {
+	querier := &fakeWindowQuerier{values: map[int64][2]int64{
+		// Side A has no profiles in its second window.
+		0: {50, 50}, 20: {52, 48}, 30: {48, 52},
+		100: {160, 40}, 110: {164, 36}, 120: {156, 44}, 130: {162, 38},
+	}}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		querier,
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+
+	selection := func(start int64) *pb.ProfileDiffSelection {
+		return &pb.ProfileDiffSelection{
+			Mode: pb.ProfileDiffSelection_MODE_MERGE,
+			Options: &pb.ProfileDiffSelection_Merge{Merge: &pb.MergeProfile{
+				Query: `parca_agent:samples:count:cpu:nanoseconds:delta{}`,
+				Start: timestamppb.New(time.Unix(start, 0)),
+				End:   timestamppb.New(time.Unix(start+40, 0)),
+			}},
+		}
+	}
+	resp, err := api.DiffSignificance(context.Background(), &pb.DiffSignificanceRequest{
+		Diff:    &pb.DiffProfile{A: selection(0), B: selection(100)},
+		Windows: 4,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Functions, 3)
+	require.Equal(t, "a", resp.Functions[0].Function)
+	require.InDelta(t, 0.5, resp.Functions[0].ShareA, 0.001)
+	require.True(t, resp.Functions[0].Significant)
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+const (
+	// SlowQueriesPath is the path of the admin endpoint listing the most
+	// recent slow queries.
+	SlowQueriesPath = "/slow_queries"
+
+	// explainTimeout bounds the queries issued to explain a slow query.
+	explainTimeout = 30 * time.Second
+)
+
+// SlowQuery is a query that took longer than the slow query threshold.
+type SlowQuery struct {
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Code     string          `json:"code"`
+	Duration time.Duration   `json:"duration"`
+	// Series is the number of series selected by all selections of the
+	// query, or -1 if they could not be counted.
+	Series     int                  `json:"series"`
+	Selections []parcacol.Selection `json:"selections"`
+}
+
+// SlowQueryLog keeps the most recent queries that exceeded a latency
+// threshold along with how their data was selected, to find the queries and
+// selectors that are systematically expensive.
+type SlowQueryLog struct {
+	logger    log.Logger
+	threshold time.Duration
+	size      int
+
+	slowQueries prometheus.Counter
+
+	mtx     sync.Mutex
+	entries []SlowQuery
+	next    int
+}
+
+// NewSlowQueryLog returns a log of the most recent size queries that took
+// longer than threshold.
+func NewSlowQueryLog(logger log.Logger, reg prometheus.Registerer, threshold time.Duration, size int) *SlowQueryLog {
+	return &SlowQueryLog{
+		logger:    logger,
+		threshold: threshold,
+		size:      size,
+		slowQueries: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "parca_query_slow_queries_total",
+			Help: "Total number of queries that took longer than the slow query threshold.",
+		}),
+	}
+}
+
+// UnaryServerInterceptor collects the statistics of the query service
+// requests and logs the ones that exceed the threshold.
+func (l *SlowQueryLog) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, queryMethodPrefix) {
+			return handler(ctx, req)
+		}
+
+		ctx, stats := parcacol.WithQueryStats(ctx)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+		if duration < l.threshold {
+			return resp, err
+		}
+
+		q := SlowQuery{
+			Time:     start,
+			Method:   info.FullMethod,
+			Code:     status.Code(err).String(),
+			Duration: duration,
+		}
+		if m, ok := req.(proto.Message); ok {
+			if b, err := protojson.Marshal(m); err == nil {
+				q.Request = b
+			}
+		}
+
+		// Counting the series issues further queries, so it is done in the
+		// background to not delay the already slow response.
+		go l.explain(context.WithoutCancel(ctx), q, stats)
+
+		return resp, err
+	}
+}
+
+// HandlerInterceptor collects the statistics of the requests of a query
+// endpoint served over HTTP and logs the ones that exceed the threshold.
+func (l *SlowQueryLog) HandlerInterceptor() HandlerInterceptor {
+	return func(method string, next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			ctx, stats := parcacol.WithQueryStats(r.Context())
+			request := httpRequestJSON(r)
+			rec := newStatusRecorder(w)
+			start := time.Now()
+			next(rec, r.WithContext(ctx), pathParams)
+			duration := time.Since(start)
+			if duration < l.threshold {
+				return
+			}
+
+			q := SlowQuery{
+				Time:     start,
+				Method:   method,
+				Request:  request,
+				Code:     http.StatusText(rec.code),
+				Duration: duration,
+			}
+			go l.explain(context.WithoutCancel(ctx), q, stats)
+		}
+	}
+}
+
+// explain completes the slow query with the statistics of its selections and
+// adds it to the log.
+func (l *SlowQueryLog) explain(ctx context.Context, q SlowQuery, stats *parcacol.QueryStats) {
+	ctx, cancel := context.WithTimeout(ctx, explainTimeout)
+	defer cancel()
+
+	countErr := stats.CountSeries(ctx)
+	if countErr != nil {
+		level.Debug(l.logger).Log("msg", "failed to count series of slow query", "err", countErr)
+	}
+
+	q.Selections = stats.Selections()
+	for _, sel := range q.Selections {
+		if sel.Series < 0 || countErr != nil {
+			q.Series = -1
+			break
+		}
+		q.Series += sel.Series
+	}
+
+	l.add(q)
+	l.slowQueries.Inc()
+	level.Info(l.logger).Log(
+		"msg", "slow query",
+		"method", q.Method,
+		"request", string(q.Request),
+		"code", q.Code,
+		"duration", q.Duration,
+		"series", q.Series,
+	)
+}
+
+func (l *SlowQueryLog) add(q SlowQuery) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.size <= 0 {
+		return
+	}
+	if len(l.entries) < l.size {
+		l.entries = append(l.entries, q)
+		return
+	}
+	l.entries[l.next] = q
+	l.next = (l.next + 1) % l.size
+}
+
+// List returns the logged slow queries, most recent first.
+func (l *SlowQueryLog) List() []SlowQuery {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	res := make([]SlowQuery, 0, len(l.entries))
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		res = append(res, l.entries[(l.next+i)%len(l.entries)])
+	}
+	return res
+}
+
+// SlowQueriesHandler lists the logged slow queries.
+func (l *SlowQueryLog) SlowQueriesHandler() runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		writeJSON(w, l.List())
+	}
+}
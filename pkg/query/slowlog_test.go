@@ -0,0 +1,85 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	l := NewSlowQueryLog(log.NewNopLogger(), prometheus.NewRegistry(), 10*time.Millisecond, 2)
+	interceptor := l.UnaryServerInterceptor()
+
+	call := func(method, query string, d time.Duration) {
+		_, err := interceptor(
+			context.Background(),
+			&pb.QueryRangeRequest{Query: query},
+			&grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req any) (any, error) {
+				time.Sleep(d)
+				return &pb.QueryRangeResponse{}, nil
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	// queries returns the logged queries of the slow query admin endpoint.
+	queries := func() []string {
+		rec := httptest.NewRecorder()
+		l.SlowQueriesHandler()(rec, httptest.NewRequest("GET", SlowQueriesPath, nil), nil)
+
+		var res []SlowQuery
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+
+		queries := make([]string, 0, len(res))
+		for _, q := range res {
+			require.Equal(t, "OK", q.Code)
+			require.GreaterOrEqual(t, q.Duration, 10*time.Millisecond)
+
+			req := &pb.QueryRangeRequest{}
+			require.NoError(t, protojson.Unmarshal(q.Request, req))
+			queries = append(queries, req.Query)
+		}
+		return queries
+	}
+
+	call("/parca.query.v1alpha1.QueryService/QueryRange", `fast:cpu:nanoseconds:cpu:nanoseconds:delta{}`, 0)
+	call("/parca.profilestore.v1alpha1.ProfileStoreService/WriteRaw", "", 20*time.Millisecond)
+	for _, q := range []string{"a", "b", "c"} {
+		call("/parca.query.v1alpha1.QueryService/QueryRange", q+`:cpu:nanoseconds:cpu:nanoseconds:delta{}`, 20*time.Millisecond)
+		// Slow queries are added once explained in the background.
+		require.Eventually(t, func() bool {
+			got := queries()
+			return len(got) > 0 && got[0] == q+`:cpu:nanoseconds:cpu:nanoseconds:delta{}`
+		}, time.Second, time.Millisecond)
+	}
+
+	// Most recent first, the oldest was evicted.
+	require.Equal(t, []string{
+		`c:cpu:nanoseconds:cpu:nanoseconds:delta{}`,
+		`b:cpu:nanoseconds:cpu:nanoseconds:delta{}`,
+	}, queries())
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package query
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+// TraceExemplars returns the traces the samples matching the query were
+// recorded in, in the requested time range, the last 24 hours by default.
+// Trace IDs are read from the requested sample label, trace_id by default.
+// At most the requested number of traces is returned, 100 by default, the
+// ones with the highest total value first.
+func (q *ColumnQueryAPI) TraceExemplars(ctx context.Context, req *pb.TraceExemplarsRequest) (*pb.TraceExemplarsResponse, error) {
+	start, end := requestTimeRange(req.Start, req.End)
+	label := req.Label
+	if label == "" {
+		label = parcacol.DefaultTraceIDLabel
+	}
+	limit := 100
+	if req.Limit != 0 {
+		limit = int(req.Limit)
+	}
+
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	res, err := q.querier.TraceExemplars(ctx, req.Query, start, end, label, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	traces := make([]*pb.TraceExemplar, 0, len(res))
+	for _, t := range res {
+		traces = append(traces, &pb.TraceExemplar{
+			TraceId:   t.TraceID,
+			Value:     t.Value,
+			Timestamp: timestamppb.New(t.Timestamp),
+		})
+	}
+	return &pb.TraceExemplarsResponse{Traces: traces}, nil
+}
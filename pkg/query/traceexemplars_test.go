@@ -0,0 +1,67 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+type fakeTraceQuerier struct {
+	Querier
+	label string
+	limit int
+}
+
+func (q *fakeTraceQuerier) TraceExemplars(_ context.Context, _ string, _, end time.Time, label string, limit int) ([]*parcacol.TraceExemplar, error) {
+	q.label, q.limit = label, limit
+	return []*parcacol.TraceExemplar{{TraceID: "abc", Value: 42, Timestamp: end}}, nil
+}
+
+func TestTraceExemplars(t *testing.T) {
+	querier := &fakeTraceQuerier{}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		querier,
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+
+	res, err := api.TraceExemplars(context.Background(), &pb.TraceExemplarsRequest{
+		Query: "parca_agent:samples:count:cpu:nanoseconds:delta{}",
+	})
+	require.NoError(t, err)
+	require.Equal(t, parcacol.DefaultTraceIDLabel, querier.label)
+	require.Equal(t, 100, querier.limit)
+	require.Len(t, res.Traces, 1)
+	require.Equal(t, "abc", res.Traces[0].TraceId)
+	require.Equal(t, int64(42), res.Traces[0].Value)
+
+	_, err = api.TraceExemplars(context.Background(), &pb.TraceExemplarsRequest{Label: "span_id", Limit: 5})
+	require.NoError(t, err)
+	require.Equal(t, "span_id", querier.label)
+	require.Equal(t, 5, querier.limit)
+}
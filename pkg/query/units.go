@@ -0,0 +1,222 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+// UnitAuto selects the largest unit of the same dimension in which the
+// largest value is still at least 1.
+const UnitAuto = "auto"
+
+type unitDimension struct {
+	units []string
+	// scales of the units relative to the first unit.
+	scales []float64
+}
+
+var (
+	timeDimension = unitDimension{
+		units:  []string{"nanoseconds", "microseconds", "milliseconds", "seconds", "minutes", "hours"},
+		scales: []float64{1, 1e3, 1e6, 1e9, 60e9, 3600e9},
+	}
+	bytesDimension = unitDimension{
+		units:  []string{"bytes", "kibibytes", "mebibytes", "gibibytes", "tebibytes"},
+		scales: []float64{1, 1 << 10, 1 << 20, 1 << 30, 1 << 40},
+	}
+
+	unitAliases = map[string]string{
+		"ns":  "nanoseconds",
+		"us":  "microseconds",
+		"ms":  "milliseconds",
+		"s":   "seconds",
+		"m":   "minutes",
+		"h":   "hours",
+		"B":   "bytes",
+		"KiB": "kibibytes",
+		"MiB": "mebibytes",
+		"GiB": "gibibytes",
+		"TiB": "tebibytes",
+	}
+)
+
+// lookupUnit returns the dimension of the unit and its index in it.
+func lookupUnit(unit string) (unitDimension, int, bool) {
+	if canonical, ok := unitAliases[unit]; ok {
+		unit = canonical
+	}
+	for _, d := range []unitDimension{timeDimension, bytesDimension} {
+		for i, u := range d.units {
+			if u == unit {
+				return d, i, true
+			}
+		}
+	}
+	return unitDimension{}, 0, false
+}
+
+// UnitScale returns the factor values in the from unit are multiplied with to
+// convert them to the to unit, and the canonical name of the to unit. If to
+// is UnitAuto, the unit is selected by the largest absolute value that is
+// going to be converted. Values of units that can't be converted, like
+// counts, are returned unchanged with a factor of 1.
+func UnitScale(from, to string, maxValue float64) (float64, string, error) {
+	if to == "" || to == from {
+		return 1, from, nil
+	}
+
+	dim, fromIdx, ok := lookupUnit(from)
+	if !ok {
+		if to == UnitAuto {
+			return 1, from, nil
+		}
+		return 0, "", fmt.Errorf("unit %q can't be converted", from)
+	}
+
+	toIdx := fromIdx
+	if to == UnitAuto {
+		for i, s := range dim.scales {
+			if math.Abs(maxValue)*dim.scales[fromIdx]/s >= 1 {
+				toIdx = i
+			}
+		}
+	} else {
+		toDim, i, ok := lookupUnit(to)
+		if !ok || toDim.units[0] != dim.units[0] {
+			return 0, "", fmt.Errorf("can't convert %q to %q", from, to)
+		}
+		toIdx = i
+	}
+
+	return dim.scales[fromIdx] / dim.scales[toIdx], dim.units[toIdx], nil
+}
+
+// QueryUnits serves the top report of the query with values converted to the
+// requested unit, which is either a unit like "seconds" or "MiB" or
+// UnitAuto. The report type of the query is overwritten.
+func (q *ColumnQueryAPI) QueryUnits(ctx context.Context, req *pb.QueryUnitsRequest) (*pb.QueryUnitsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	query := proto.Clone(req.Query).(*pb.QueryRequest)
+	query.ReportType = pb.QueryRequest_REPORT_TYPE_TOP
+
+	resp, err := q.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	top := resp.GetTop()
+	maxValue := float64(resp.Total)
+	for _, n := range top.GetList() {
+		maxValue = math.Max(maxValue, math.Abs(float64(n.Cumulative)))
+	}
+	scale, unit, err := UnitScale(top.GetUnit(), req.Unit, maxValue)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res := &pb.QueryUnitsResponse{
+		SourceUnit: top.GetUnit(),
+		Unit:       unit,
+		Total:      float64(resp.Total) * scale,
+		Reported:   top.GetReported(),
+		List:       make([]*pb.ConvertedTopNode, 0, len(top.GetList())),
+	}
+	for _, n := range top.GetList() {
+		res.List = append(res.List, &pb.ConvertedTopNode{
+			Meta:       n.Meta,
+			Cumulative: float64(n.Cumulative) * scale,
+			Flat:       float64(n.Flat) * scale,
+			Diff:       float64(n.Diff) * scale,
+		})
+	}
+	return res, nil
+}
+
+// QueryRangeUnits serves the range query with the sample values converted to
+// the requested unit. With UnitAuto the unit is selected per series.
+func (q *ColumnQueryAPI) QueryRangeUnits(ctx context.Context, req *pb.QueryRangeUnitsRequest) (*pb.QueryRangeUnitsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp, err := q.QueryRange(ctx, req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.QueryRangeUnitsResponse{
+		Series: make([]*pb.ConvertedMetricsSeries, 0, len(resp.Series)),
+	}
+	for _, s := range resp.Series {
+		var maxValue float64
+		for _, sample := range s.Samples {
+			maxValue = math.Max(maxValue, math.Abs(float64(sample.Value)))
+		}
+		scale, unit, err := UnitScale(s.GetSampleType().GetUnit(), req.Unit, maxValue)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		cs := &pb.ConvertedMetricsSeries{
+			Labelset:   s.Labelset,
+			PeriodType: s.PeriodType,
+			SampleType: s.SampleType,
+			Unit:       unit,
+			Samples:    make([]*pb.ConvertedMetricsSample, 0, len(s.Samples)),
+		}
+		for _, sample := range s.Samples {
+			cs.Samples = append(cs.Samples, &pb.ConvertedMetricsSample{
+				Timestamp:      sample.Timestamp,
+				Value:          float64(sample.Value) * scale,
+				ValuePerSecond: sample.ValuePerSecond * scale,
+				Duration:       sample.Duration,
+			})
+		}
+		res.Series = append(res.Series, cs)
+	}
+	return res, nil
+}
+
+func unmarshalBody(body io.Reader, m proto.Message) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("read request body: %w", err)
+	}
+	if err := protojson.Unmarshal(b, m); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
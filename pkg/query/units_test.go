@@ -0,0 +1,100 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+func TestUnitScale(t *testing.T) {
+	cases := []struct {
+		from, to string
+		maxValue float64
+		scale    float64
+		unit     string
+	}{
+		{from: "nanoseconds", to: "seconds", scale: 1e-9, unit: "seconds"},
+		{from: "nanoseconds", to: "ms", scale: 1e-6, unit: "milliseconds"},
+		{from: "bytes", to: "MiB", scale: 1.0 / (1 << 20), unit: "mebibytes"},
+		{from: "nanoseconds", to: "", scale: 1, unit: "nanoseconds"},
+		{from: "nanoseconds", to: UnitAuto, maxValue: 2.5e9, scale: 1e-9, unit: "seconds"},
+		{from: "nanoseconds", to: UnitAuto, maxValue: 500, scale: 1, unit: "nanoseconds"},
+		{from: "bytes", to: UnitAuto, maxValue: 3 << 30, scale: 1.0 / (1 << 30), unit: "gibibytes"},
+		{from: "count", to: UnitAuto, maxValue: 1e9, scale: 1, unit: "count"},
+	}
+	for _, c := range cases {
+		t.Run(c.from+"->"+c.to, func(t *testing.T) {
+			scale, unit, err := UnitScale(c.from, c.to, c.maxValue)
+			require.NoError(t, err)
+			require.InDelta(t, c.scale, scale, c.scale*1e-9)
+			require.Equal(t, c.unit, unit)
+		})
+	}
+
+	_, _, err := UnitScale("bytes", "seconds", 0)
+	require.Error(t, err)
+	_, _, err = UnitScale("count", "seconds", 0)
+	require.Error(t, err)
+}
+
+func TestQueryRangeUnits(t *testing.T) {
+	series := costSeries("a", 0)
+	series.SampleType = &pb.ValueType{Type: "alloc_space", Unit: "bytes"}
+	series.Samples = []*pb.MetricsSample{
+		{Timestamp: timestamppb.New(time.Unix(0, 0)), Value: 1 << 20, ValuePerSecond: 1 << 10, Duration: 10},
+		{Timestamp: timestamppb.New(time.Unix(10, 0)), Value: 3 << 20, ValuePerSecond: 3 << 10, Duration: 10},
+	}
+	api := NewColumnQueryAPI(
+		log.NewNopLogger(),
+		noop.NewTracerProvider().Tracer(""),
+		nil,
+		&fakeRangeQuerier{series: map[string][]*pb.MetricsSeries{"mem": {series}}},
+		memory.NewGoAllocator(),
+		nil,
+		nil,
+	)
+	query := &pb.QueryRangeRequest{
+		Query: "mem",
+		Start: timestamppb.New(time.Unix(0, 0)),
+		End:   timestamppb.New(time.Unix(20, 0)),
+	}
+
+	res, err := api.QueryRangeUnits(context.Background(), &pb.QueryRangeUnitsRequest{Query: query, Unit: UnitAuto})
+	require.NoError(t, err)
+	require.Len(t, res.Series, 1)
+	require.Equal(t, "mebibytes", res.Series[0].Unit)
+	require.Equal(t, "bytes", res.Series[0].SampleType.Unit)
+	require.Len(t, res.Series[0].Samples, 2)
+	require.InDelta(t, 3, res.Series[0].Samples[1].Value, 1e-9)
+	require.InDelta(t, 3.0/(1<<10), res.Series[0].Samples[1].ValuePerSecond, 1e-12)
+	require.Equal(t, int64(10), res.Series[0].Samples[1].Duration)
+
+	_, err = api.QueryRangeUnits(context.Background(), &pb.QueryRangeUnitsRequest{Query: query, Unit: "seconds"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = api.QueryRangeUnits(context.Background(), &pb.QueryRangeUnitsRequest{Unit: UnitAuto})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/parcacol"
+)
+
+// QueryRangeWindows issues the same range query for each of the windows in a
+// single round trip and returns one response per window, in the order of the
+// windows.
+func (q *ColumnQueryAPI) QueryRangeWindows(ctx context.Context, req *pb.QueryRangeWindowsRequest) (*pb.QueryRangeWindowsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	windows := make([]parcacol.TimeRange, 0, len(req.Windows))
+	for _, w := range req.Windows {
+		windows = append(windows, parcacol.TimeRange{Start: w.Start.AsTime(), End: w.End.AsTime()})
+	}
+
+	ctx, releaseSnapshot := readSnapshot(ctx, q.querier)
+	defer releaseSnapshot()
+
+	res, err := q.querier.QueryRangeWindows(ctx, req.Query, windows, req.Step.AsDuration(), req.Limit, req.SumBy)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.QueryRangeWindowsResponse{
+		Windows: make([]*pb.QueryRangeResponse, 0, len(res)),
+	}
+	for _, series := range res {
+		resp.Windows = append(resp.Windows, &pb.QueryRangeResponse{
+			Series: series,
+		})
+	}
+	return resp, nil
+}
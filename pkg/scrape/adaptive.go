@@ -0,0 +1,136 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scrape
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+
+	"github.com/parca-dev/parca/pkg/config"
+)
+
+// adaptiveInterval adapts the scrape interval of a target to the stability
+// of its profiles. The interval is doubled while consecutive profiles are
+// nearly identical, halved when they change rapidly and reset to the
+// configured interval when their totals spike.
+type adaptiveInterval struct {
+	minInterval time.Duration
+	maxInterval time.Duration
+	cfg         config.AdaptiveIntervalConfig
+
+	current time.Duration
+	shares  map[string]float64
+	total   int64
+}
+
+func newAdaptiveInterval(interval time.Duration, cfg config.AdaptiveIntervalConfig) *adaptiveInterval {
+	return &adaptiveInterval{
+		minInterval: interval,
+		maxInterval: time.Duration(cfg.MaxInterval),
+		cfg:         cfg,
+		current:     interval,
+	}
+}
+
+// observe returns the interval after which the target is scraped next,
+// given the profile of the latest scrape.
+func (a *adaptiveInterval) observe(p *profile.Profile) time.Duration {
+	shares, total := stackShares(p)
+	prevShares, prevTotal := a.shares, a.total
+	a.shares, a.total = shares, total
+	if prevShares == nil {
+		return a.current
+	}
+
+	if spiked(prevTotal, total, a.cfg.SpikeFactor) {
+		a.current = a.minInterval
+		return a.current
+	}
+
+	switch s := similarity(prevShares, shares); {
+	case s >= a.cfg.StableSimilarity:
+		a.current = min(2*a.current, a.maxInterval)
+	case s < a.cfg.ChangeSimilarity:
+		a.current = max(a.current/2, a.minInterval)
+	}
+	return a.current
+}
+
+func spiked(prev, cur int64, factor float64) bool {
+	p, c := math.Abs(float64(prev)), math.Abs(float64(cur))
+	return c > p*factor || p > c*factor
+}
+
+// stackShares returns the share of every stack in the total of the default
+// sample type of the profile, and the total.
+func stackShares(p *profile.Profile) (map[string]float64, int64) {
+	idx := len(p.SampleType) - 1
+	for i, st := range p.SampleType {
+		if st.Type == p.DefaultSampleType {
+			idx = i
+		}
+	}
+
+	values := map[string]int64{}
+	var total int64
+	if idx >= 0 {
+		var b strings.Builder
+		for _, s := range p.Sample {
+			b.Reset()
+			for _, l := range s.Location {
+				if len(l.Line) == 0 {
+					b.WriteString(strconv.FormatUint(l.Address, 16))
+				}
+				for _, line := range l.Line {
+					if line.Function != nil {
+						b.WriteString(line.Function.Name)
+					}
+				}
+				b.WriteByte(';')
+			}
+			v := s.Value[idx]
+			if v < 0 {
+				v = -v
+			}
+			values[b.String()] += v
+			total += v
+		}
+	}
+
+	shares := make(map[string]float64, len(values))
+	for k, v := range values {
+		if total > 0 {
+			shares[k] = float64(v) / float64(total)
+		}
+	}
+	return shares, total
+}
+
+// similarity is one minus the total variation distance of the shares.
+func similarity(a, b map[string]float64) float64 {
+	var d float64
+	for k, v := range a {
+		d += math.Abs(v - b[k])
+	}
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			d += v
+		}
+	}
+	return 1 - d/2
+}
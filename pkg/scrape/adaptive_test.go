@@ -0,0 +1,65 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scrape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/parca-dev/parca/pkg/config"
+)
+
+func testProfile(values map[string]int64) *profile.Profile {
+	p := &profile.Profile{SampleType: []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}}}
+	for name, v := range values {
+		fn := &profile.Function{Name: name}
+		p.Sample = append(p.Sample, &profile.Sample{
+			Location: []*profile.Location{{Line: []profile.Line{{Function: fn}}}},
+			Value:    []int64{v},
+		})
+	}
+	return p
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	a := newAdaptiveInterval(10*time.Second, config.AdaptiveIntervalConfig{
+		MaxInterval:      model.Duration(30 * time.Second),
+		StableSimilarity: config.DefaultAdaptiveStableSimilarity,
+		ChangeSimilarity: config.DefaultAdaptiveChangeSimilarity,
+		SpikeFactor:      config.DefaultAdaptiveSpikeFactor,
+	})
+
+	stable := map[string]int64{"a": 50, "b": 50}
+	require.Equal(t, 10*time.Second, a.observe(testProfile(stable)))
+	// Stable profiles double the interval up to the maximum.
+	require.Equal(t, 20*time.Second, a.observe(testProfile(stable)))
+	require.Equal(t, 30*time.Second, a.observe(testProfile(map[string]int64{"a": 51, "b": 49})))
+
+	// A changing profile halves it.
+	require.Equal(t, 15*time.Second, a.observe(testProfile(map[string]int64{"a": 20, "c": 80})))
+
+	// A spiking total resets it.
+	require.Equal(t, 30*time.Second, a.observe(testProfile(map[string]int64{"a": 20, "c": 80})))
+	require.Equal(t, 10*time.Second, a.observe(testProfile(map[string]int64{"a": 200, "c": 800})))
+}
+
+func TestSimilarity(t *testing.T) {
+	require.InDelta(t, 1, similarity(map[string]float64{"a": 1}, map[string]float64{"a": 1}), 1e-9)
+	require.InDelta(t, 0, similarity(map[string]float64{"a": 1}, map[string]float64{"b": 1}), 1e-9)
+	require.InDelta(t, 0.5, similarity(map[string]float64{"a": 0.5, "b": 0.5}, map[string]float64{"a": 1}), 1e-9)
+}
@@ -38,6 +38,7 @@ import (
 
 	profilepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
 	"github.com/parca-dev/parca/pkg/config"
+	"github.com/parca-dev/parca/pkg/profilestore"
 )
 
 // scrapePool manages scrapes for sets of targets.
@@ -102,7 +103,7 @@ func newScrapePool(
 		metrics:       metrics,
 	}
 	sp.newLoop = func(t *Target, s scraper) loop {
-		return newScrapeLoop(
+		sl := newScrapeLoop(
 			ctx,
 			t,
 			s,
@@ -113,6 +114,10 @@ func newScrapePool(
 			store,
 			cfg.NormalizedAddresses,
 		)
+		// The config may have been reloaded since the pool was created.
+		sl.sizeLimit = sp.config.ProfileSizeLimit
+		sl.adaptive = sp.config.AdaptiveInterval
+		return sl
 	}
 
 	return sp
@@ -374,6 +379,8 @@ type scrapeLoop struct {
 	externalLabels labels.Labels
 
 	normalizedAddresses bool
+	sizeLimit           *config.ProfileSizeLimitConfig
+	adaptive            *config.AdaptiveIntervalConfig
 
 	buffers *pool.Pool
 
@@ -426,7 +433,14 @@ func (sl *scrapeLoop) run(interval, timeout time.Duration, errc chan<- error) {
 		return
 	}
 
-	var last time.Time
+	var (
+		last     time.Time
+		adaptive *adaptiveInterval
+		current  = interval
+	)
+	if sl.adaptive != nil {
+		adaptive = newAdaptiveInterval(interval, *sl.adaptive)
+	}
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -502,6 +516,14 @@ mainLoop:
 				continue
 			}
 
+			if adaptive != nil {
+				if next := adaptive.observe(p); next != current {
+					level.Debug(sl.l).Log("msg", "adapting scrape interval", "from", current, "to", next)
+					current = next
+					ticker.Reset(current)
+				}
+			}
+
 			var executableInfo []*profilepb.ExecutableInfo
 			for _, comment := range p.Comments {
 				if strings.HasPrefix(comment, "executableInfo=") {
@@ -545,7 +567,18 @@ mainLoop:
 				b = newB // We want to make sure we return the new buffer to the pool further below.
 			}
 
-			_, err = sl.store.WriteRaw(sl.ctx, &profilepb.WriteRawRequest{
+			writeCtx := profilestore.ContextWithScrapeStart(sl.ctx, start)
+			if md := captureMetadata(sl.target, p); len(md) > 0 {
+				writeCtx = profilestore.ContextWithProfileMetadata(writeCtx, md)
+			}
+			if sl.sizeLimit != nil {
+				writeCtx = profilestore.ContextWithProfileSizeLimit(writeCtx, profilestore.ProfileSizeLimit{
+					MaxBytes: sl.sizeLimit.MaxBytes,
+					Mode:     profilestore.ProfileSizeMode(sl.sizeLimit.Mode),
+					TopK:     sl.sizeLimit.TopK,
+				})
+			}
+			_, err = sl.store.WriteRaw(writeCtx, &profilepb.WriteRawRequest{
 				Normalized: sl.normalizedAddresses,
 				Series: []*profilepb.RawProfileSeries{
 					{
@@ -600,6 +633,21 @@ mainLoop:
 	close(sl.stopped)
 }
 
+// captureMetadata returns the requested and actual capture duration of a
+// scraped profile, if known.
+func captureMetadata(t *Target, p *profile.Profile) map[string]string {
+	md := map[string]string{}
+	if s := t.Params().Get("seconds"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			md[profilestore.ProfileMetadataDurationRequested] = (time.Duration(secs) * time.Second).String()
+		}
+	}
+	if p.DurationNanos > 0 {
+		md[profilestore.ProfileMetadataDurationActual] = time.Duration(p.DurationNanos).String()
+	}
+	return md
+}
+
 // parseExecutableInfo parses the executableInfo string from the comment. It is in the format of: "executableInfo=elfType;offset;vaddr".
 func parseExecutableInfo(comment string) (*profilepb.ExecutableInfo, error) {
 	eiString := strings.TrimPrefix(comment, "executableInfo=")
@@ -0,0 +1,225 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	querypb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+)
+
+// accessLoggedServices are the services whose requests are access logged.
+var accessLoggedServices = []string{
+	"/parca.query.v1alpha1.QueryService/",
+	"/parca.query.v1alpha1.BinaryService/",
+	"/parca.profilestore.v1alpha1.ProfileStoreService/",
+}
+
+// AccessLog logs a structured line for every query and ingest request. It
+// can be enabled and disabled at runtime.
+type AccessLog struct {
+	logger  log.Logger
+	enabled atomic.Bool
+}
+
+func NewAccessLog(logger log.Logger, enabled bool) *AccessLog {
+	a := &AccessLog{logger: logger}
+	a.enabled.Store(enabled)
+	return a
+}
+
+func (a *AccessLog) SetEnabled(enabled bool) {
+	a.enabled.Store(enabled)
+}
+
+func (a *AccessLog) Enabled() bool {
+	return a.enabled.Load()
+}
+
+// UnaryServerInterceptor logs the requests of the access logged services.
+func (a *AccessLog) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !a.Enabled() || !accessLogged(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		keyvals := []any{
+			"method", info.FullMethod,
+			"code", status.Code(err).String(),
+			"duration", time.Since(start),
+		}
+		if t, ok := req.(interface{ GetTenant() string }); ok && t.GetTenant() != "" {
+			keyvals = append(keyvals, "tenant", t.GetTenant())
+		}
+		if queries := requestQueries(req); len(queries) > 0 {
+			fingerprints := make([]string, 0, len(queries))
+			for _, q := range queries {
+				fingerprints = append(fingerprints, QueryFingerprint(q))
+			}
+			keyvals = append(keyvals,
+				"query", strings.Join(queries, " "),
+				"fingerprint", strings.Join(fingerprints, ","),
+			)
+		}
+		if m, ok := req.(proto.Message); ok {
+			keyvals = append(keyvals, "request_size", proto.Size(m))
+		}
+		if m, ok := resp.(proto.Message); ok && err == nil {
+			keyvals = append(keyvals, "response_size", proto.Size(m))
+		}
+		if samples, ok := samplesTouched(req, resp); ok {
+			keyvals = append(keyvals, "samples", samples)
+		}
+
+		level.Info(a.logger).Log(keyvals...)
+		return resp, err
+	}
+}
+
+func accessLogged(method string) bool {
+	for _, s := range accessLoggedServices {
+		if strings.HasPrefix(method, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestQueries returns the label selectors a request queries.
+func requestQueries(req any) []string {
+	selection := func(s *querypb.ProfileDiffSelection) string {
+		if s.GetMerge() != nil {
+			return s.GetMerge().GetQuery()
+		}
+		return s.GetSingle().GetQuery()
+	}
+
+	var queries []string
+	switch r := req.(type) {
+	case *querypb.QueryRequest:
+		switch {
+		case r.GetMerge() != nil:
+			queries = append(queries, r.GetMerge().GetQuery())
+		case r.GetSingle() != nil:
+			queries = append(queries, r.GetSingle().GetQuery())
+		case r.GetDiff() != nil:
+			queries = append(queries, selection(r.GetDiff().GetA()), selection(r.GetDiff().GetB()))
+		}
+	case *querypb.QueryRangeRequest:
+		queries = append(queries, r.GetQuery())
+	case *querypb.LabelsRequest:
+		queries = append(queries, r.GetMatch()...)
+	case *querypb.ValuesRequest:
+		queries = append(queries, r.GetMatch()...)
+	}
+
+	res := queries[:0]
+	for _, q := range queries {
+		if q != "" {
+			res = append(res, q)
+		}
+	}
+	return res
+}
+
+// samplesTouched returns the number of samples written by an ingest request
+// or returned by a range query.
+func samplesTouched(req, resp any) (int, bool) {
+	switch r := req.(type) {
+	case *profilestorepb.WriteRawRequest:
+		n := 0
+		for _, s := range r.Series {
+			n += len(s.Samples)
+		}
+		return n, true
+	}
+
+	if r, ok := resp.(*querypb.QueryRangeResponse); ok {
+		n := 0
+		for _, s := range r.Series {
+			n += len(s.Samples)
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// QueryFingerprint returns a fingerprint of the query that is the same for
+// all queries that only differ in the values they match labels with, or in
+// the order of their matchers. It identifies the same query issued for
+// different targets, eg. by a dashboard.
+func QueryFingerprint(query string) string {
+	matchers, err := parser.ParseMetricSelector(query)
+	if err != nil {
+		return "invalid"
+	}
+
+	parts := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		if m.Name == labels.MetricName {
+			// The profile type is part of the fingerprint.
+			parts = append(parts, m.String())
+			continue
+		}
+		parts = append(parts, m.Name+m.Type.String()+"?")
+	}
+	sort.Strings(parts)
+
+	return strconv.FormatUint(xxhash.Sum64String(strings.Join(parts, ",")), 16)
+}
+
+// ServeHTTP reports whether the access log is enabled. PUT and POST requests
+// enable or disable it according to the "enabled" parameter.
+func (a *AccessLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid enabled parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+		a.SetEnabled(enabled)
+		level.Info(a.logger).Log("msg", "access log toggled", "enabled", enabled)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Enabled bool `json:"enabled"`
+	}{a.Enabled()})
+}
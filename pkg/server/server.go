@@ -65,14 +65,39 @@ type Server struct {
 	grpcProbe *prober.GRPCProbe
 	reg       *prometheus.Registry
 	version   string
+	accessLog *AccessLog
+
+	unaryInterceptors []grpc.UnaryServerInterceptor
+}
+
+type Option func(*Server)
+
+// WithAccessLog logs query and ingest requests with the access log, which
+// can be toggled at /debug/access-log.
+func WithAccessLog(a *AccessLog) Option {
+	return func(s *Server) {
+		s.accessLog = a
+	}
+}
+
+// WithUnaryInterceptor adds an interceptor to the unary gRPC requests. It
+// runs after the built-in interceptors.
+func WithUnaryInterceptor(i grpc.UnaryServerInterceptor) Option {
+	return func(s *Server) {
+		s.unaryInterceptors = append(s.unaryInterceptors, i)
+	}
 }
 
-func NewServer(reg *prometheus.Registry, version string) *Server {
-	return &Server{
+func NewServer(reg *prometheus.Registry, version string, opts ...Option) *Server {
+	s := &Server{
 		grpcProbe: prober.NewGRPC(),
 		reg:       reg,
 		version:   version,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // ListenAndServe starts the http grpc gateway server.
@@ -103,6 +128,15 @@ func (s *Server) ListenAndServe(
 		),
 	)
 
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		met.UnaryServerInterceptor(),
+		grpc_logging.UnaryServerInterceptor(InterceptorLogger(logger), logOpts...),
+	}
+	if s.accessLog != nil {
+		unaryInterceptors = append(unaryInterceptors, s.accessLog.UnaryServerInterceptor())
+	}
+	unaryInterceptors = append(unaryInterceptors, s.unaryInterceptors...)
+
 	// Start grpc server with API server registered
 	srv := grpc.NewServer(
 		// It is increased to 32MB to account for large protobuf messages (debug information uploads and downloads).
@@ -113,10 +147,7 @@ func (s *Server) ListenAndServe(
 			met.StreamServerInterceptor(),
 			grpc_logging.StreamServerInterceptor(InterceptorLogger(logger), logOpts...),
 		),
-		grpc.ChainUnaryInterceptor(
-			met.UnaryServerInterceptor(),
-			grpc_logging.UnaryServerInterceptor(InterceptorLogger(logger), logOpts...),
-		),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
 	)
 
 	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
@@ -143,6 +174,10 @@ func (s *Server) ListenAndServe(
 		r.HandleFunc("/debug/pprof/profile", pprof.Profile)
 		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 		r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+		if s.accessLog != nil {
+			r.Handle("/debug/access-log", s.accessLog)
+		}
 	})
 
 	uiHandler, err := s.uiHandler(uiFS, pathPrefix)
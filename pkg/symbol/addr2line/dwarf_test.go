@@ -51,3 +51,31 @@ func TestDwarfSymbolizer(t *testing.T) {
 		StartLine: 8,
 	}, gotLines[0].Function)
 }
+
+// BenchmarkDwarfPCToLines symbolizes addresses spread over the code of a Go
+// binary, as a batch of a single build ID does.
+func BenchmarkDwarfPCToLines(b *testing.B) {
+	logger := log.NewNopLogger()
+	demangler := demangle.NewDemangler("simple", true)
+	filename := "../../symbolizer/testdata/2d6912fd3dd64542f6f6294f4bf9cb6c265b3085/debuginfo"
+
+	elfFile, err := elf.Open(filename)
+	require.NoError(b, err)
+	defer elfFile.Close()
+
+	text := elfFile.Section(".text")
+	addrs := make([]uint64, 0, 1000)
+	for addr := text.Addr; addr < text.Addr+text.Size && len(addrs) < cap(addrs); addr += text.Size / uint64(cap(addrs)) {
+		addrs = append(addrs, addr)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dwarf, err := DWARF(logger, filename, elfFile, demangler)
+		require.NoError(b, err)
+		for _, addr := range addrs {
+			_, _ = dwarf.PCToLines(context.Background(), addr)
+		}
+	}
+}
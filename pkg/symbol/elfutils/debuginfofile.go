@@ -39,6 +39,8 @@ type debugInfoFile struct {
 	demangler *demangle.Demangler
 
 	debugData           *dwarf.Data
+	units               []unitRange
+	unitsBuilt          bool
 	lineEntries         map[dwarf.Offset][]dwarf.LineEntry
 	subprograms         map[dwarf.Offset][]*godwarf.Tree
 	abstractSubprograms map[dwarf.Offset]*dwarf.Entry
@@ -62,12 +64,7 @@ func NewDebugInfoFile(debugData *dwarf.Data, demangler *demangle.Demangler) (Deb
 // tries to find the name of the function that address belongs to.
 // After that it tries to find the corresponding source file and line information.
 func (f *debugInfoFile) SourceLines(addr uint64) ([]profile.LocationLine, error) {
-	// The reader is positioned at byte offset 0 in the DWARF “info” section.
-	// It allows reading Entry structures that are arranged in a tree.
-	er := f.debugData.Reader()
-	// SeekPC returns the Entry for the compilation unit that includes program counter,
-	// and positions the reader to read the children of that unit.
-	cu, err := er.SeekPC(addr)
+	cu, err := f.unit(addr)
 	if err != nil {
 		return nil, fmt.Errorf("seek to PC: %w", err)
 	}
@@ -143,6 +140,66 @@ func (f *debugInfoFile) SourceLines(addr uint64) ([]profile.LocationLine, error)
 	return lines, nil
 }
 
+// unitRange is an address range of a compile unit.
+type unitRange struct {
+	low, high uint64
+	cu        *dwarf.Entry
+}
+
+// unit returns the entry of the compile unit that includes the program
+// counter, or nil if there's none.
+//
+// Unlike dwarf.Reader.SeekPC, which reads the ranges of every compile unit
+// on each call, the ranges are read once and kept sorted.
+func (f *debugInfoFile) unit(addr uint64) (*dwarf.Entry, error) {
+	if !f.unitsBuilt {
+		if err := f.buildUnits(); err != nil {
+			return nil, err
+		}
+		f.unitsBuilt = true
+	}
+
+	i := sort.Search(len(f.units), func(i int) bool {
+		return f.units[i].high > addr
+	})
+	if i < len(f.units) && f.units[i].low <= addr {
+		return f.units[i].cu, nil
+	}
+	return nil, nil
+}
+
+func (f *debugInfoFile) buildUnits() error {
+	// The reader is positioned at byte offset 0 in the DWARF “info” section.
+	er := f.debugData.Reader()
+	for {
+		entry, err := er.Next()
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			break
+		}
+		// Only the compile unit entries are read, not their children.
+		er.SkipChildren()
+		if entry.Tag != dwarf.TagCompileUnit {
+			continue
+		}
+
+		ranges, err := f.debugData.Ranges(entry)
+		if err != nil {
+			return err
+		}
+		for _, r := range ranges {
+			f.units = append(f.units, unitRange{low: r[0], high: r[1], cu: entry})
+		}
+	}
+
+	sort.Slice(f.units, func(i, j int) bool {
+		return f.units[i].low < f.units[j].low
+	})
+	return nil
+}
+
 func (f *debugInfoFile) ensureLookUpTablesBuilt(cu *dwarf.Entry) error {
 	if _, ok := f.lineEntries[cu.Offset]; ok {
 		// Already created.
@@ -75,6 +75,30 @@ func HasGoPclntab(f *elf.File) bool {
 		return true
 	}
 
+	// PIE binaries place the table in a relocatable read-only data section.
+	if sec := f.Section(".data.rel.ro.gopclntab"); sec != nil {
+		return true
+	}
+
+	// Some linkers merge the section away, but the runtime still knows where
+	// the table starts and ends.
+	syms, err := f.Symbols()
+	if err != nil {
+		return false
+	}
+	var hasStart, hasEnd bool
+	for _, s := range syms {
+		switch s.Name {
+		case "runtime.pclntab":
+			hasStart = true
+		case "runtime.epclntab":
+			hasEnd = true
+		}
+		if hasStart && hasEnd {
+			return true
+		}
+	}
+
 	return false
 }
 
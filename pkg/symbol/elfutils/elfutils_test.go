@@ -26,3 +26,11 @@ func TestIsSymbolizableGoObjFile(t *testing.T) {
 
 	require.True(t, HasGoPclntab(f))
 }
+
+func TestHasGoPclntabNonGoBinary(t *testing.T) {
+	f, err := elf.Open("../addr2line/testdata/basic-cpp-no-fp-with-debuginfo")
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	require.False(t, HasGoPclntab(f))
+}
@@ -0,0 +1,406 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symbolizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/metastore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/config"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// FrameTransformer rewrites the lines of a symbolized location. Lines are
+// ordered as in pprof: the first line is the innermost inlined frame.
+// Implementations must not modify the functions of the given lines in place,
+// as they may be shared with the symbolizer cache.
+type FrameTransformer interface {
+	Transform(lines []profile.LocationLine) []profile.LocationLine
+}
+
+// FrameTransformerFunc adapts a function to the FrameTransformer interface.
+type FrameTransformerFunc func(lines []profile.LocationLine) []profile.LocationLine
+
+func (f FrameTransformerFunc) Transform(lines []profile.LocationLine) []profile.LocationLine {
+	return f(lines)
+}
+
+var languageExtensions = map[string][]string{
+	"c":    {".c", ".h"},
+	"cpp":  {".cc", ".cpp", ".cxx", ".c++", ".hh", ".hpp", ".hxx", ".h", ".tcc", ".ipp"},
+	"go":   {".go"},
+	"rust": {".rs"},
+}
+
+type frameRule struct {
+	mapping      *regexp.Regexp
+	extensions   []string
+	transformers []FrameTransformer
+	// foldAsync folds async state machine frames across the locations of a
+	// stack, see FoldStack.
+	foldAsync bool
+}
+
+func (r *frameRule) matches(mappingFile string, lines []profile.LocationLine) bool {
+	if r.mapping != nil && !r.mapping.MatchString(mappingFile) {
+		return false
+	}
+	if r.extensions == nil {
+		return true
+	}
+
+	for _, l := range lines {
+		if l.Function == nil || l.Function.Filename == "" {
+			continue
+		}
+		ext := filepath.Ext(l.Function.Filename)
+		for _, e := range r.extensions {
+			if ext == e {
+				return true
+			}
+		}
+		return false
+	}
+
+	return false
+}
+
+// FramePipeline applies the configured frame transformers to symbolized
+// locations.
+type FramePipeline struct {
+	rules []*frameRule
+}
+
+// NewFramePipeline creates a FramePipeline from the given configuration.
+func NewFramePipeline(rules []*config.FrameTransformRule) (*FramePipeline, error) {
+	p := &FramePipeline{}
+	for i, rule := range rules {
+		r := &frameRule{}
+		if rule.Mapping != "" {
+			re, err := regexp.Compile(rule.Mapping)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: compile mapping regexp: %w", i, err)
+			}
+			r.mapping = re
+		}
+		if rule.Language != "" {
+			exts, ok := languageExtensions[rule.Language]
+			if !ok {
+				return nil, fmt.Errorf("rule %d: unknown language %q", i, rule.Language)
+			}
+			r.extensions = exts
+		}
+		for j, t := range rule.Transforms {
+			if t.Type == config.FrameTransformFoldAsync {
+				r.foldAsync = true
+				continue
+			}
+			transformer, err := newFrameTransformer(t)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d, transform %d: %w", i, j, err)
+			}
+			r.transformers = append(r.transformers, transformer)
+		}
+		p.rules = append(p.rules, r)
+	}
+
+	return p, nil
+}
+
+// Apply runs all rules matching the mapping and language of the location on
+// its lines and returns the result.
+func (p *FramePipeline) Apply(mappingFile string, lines []profile.LocationLine) []profile.LocationLine {
+	if p == nil || len(lines) == 0 {
+		return lines
+	}
+
+	for _, r := range p.rules {
+		if !r.matches(mappingFile, lines) {
+			continue
+		}
+		for _, t := range r.transformers {
+			lines = t.Transform(lines)
+		}
+	}
+
+	return lines
+}
+
+func newFrameTransformer(t *config.FrameTransform) (FrameTransformer, error) {
+	switch t.Type {
+	case config.FrameTransformStripTemplateArgs:
+		return FrameTransformerFunc(stripTemplateArgs), nil
+	case config.FrameTransformCollapseStdInternals:
+		return FrameTransformerFunc(collapseStdInternals), nil
+	case config.FrameTransformLabelRuntime:
+		return FrameTransformerFunc(labelRuntimeFrames), nil
+	case config.FrameTransformRename:
+		re, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile rename pattern: %w", err)
+		}
+		return &renameTransformer{
+			field:       t.Field,
+			pattern:     re,
+			replacement: t.Replacement,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown frame transform %q", t.Type)
+	}
+}
+
+// withFunction returns a copy of the line with the function replaced by a
+// copy that has been modified by f.
+func withFunction(l profile.LocationLine, f func(fn *pb.Function)) profile.LocationLine {
+	fn := &pb.Function{
+		Id:         l.Function.Id,
+		StartLine:  l.Function.StartLine,
+		Name:       l.Function.Name,
+		SystemName: l.Function.SystemName,
+		Filename:   l.Function.Filename,
+	}
+	f(fn)
+	return profile.LocationLine{Line: l.Line, Function: fn}
+}
+
+func stripTemplateArgs(lines []profile.LocationLine) []profile.LocationLine {
+	res := make([]profile.LocationLine, 0, len(lines))
+	for _, l := range lines {
+		if l.Function == nil {
+			res = append(res, l)
+			continue
+		}
+		name := StripTemplateArgs(l.Function.Name)
+		if name == l.Function.Name {
+			res = append(res, l)
+			continue
+		}
+		res = append(res, withFunction(l, func(fn *pb.Function) { fn.Name = name }))
+	}
+	return res
+}
+
+// StripTemplateArgs removes all template and generic arguments enclosed in
+// angle brackets from a function name. Names of comparison and shift
+// operators are returned unchanged.
+func StripTemplateArgs(name string) string {
+	if strings.Contains(name, "operator<") || strings.Contains(name, "operator>") || strings.Contains(name, "operator->") {
+		return name
+	}
+
+	var (
+		b     strings.Builder
+		depth int
+	)
+	for _, r := range name {
+		switch {
+		case r == '<':
+			depth++
+		case r == '>' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	if depth != 0 {
+		// Unbalanced brackets, leave the name alone rather than guessing.
+		return name
+	}
+	return b.String()
+}
+
+var stdNamespaces = []string{"std::", "__gnu_cxx::", "__cxxabiv1::", "core::", "alloc::", "<core::", "<alloc::", "<std::"}
+
+func isStdFrame(l profile.LocationLine) bool {
+	if l.Function == nil {
+		return false
+	}
+	for _, ns := range stdNamespaces {
+		if strings.HasPrefix(l.Function.Name, ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseStdInternals keeps only the outermost frame of each run of
+// consecutive standard library frames, which is the one called from user
+// code.
+func collapseStdInternals(lines []profile.LocationLine) []profile.LocationLine {
+	res := make([]profile.LocationLine, 0, len(lines))
+	for i, l := range lines {
+		if isStdFrame(l) && i+1 < len(lines) && isStdFrame(lines[i+1]) {
+			continue
+		}
+		res = append(res, l)
+	}
+	return res
+}
+
+var (
+	// Rust async fns and blocks are compiled to closures implementing a
+	// generator, eg. "app::handler::{{closure}}" with legacy mangling or
+	// "app::handler::{closure#0}" with v0 mangling.
+	rustAsyncSuffix = regexp.MustCompile(`(::\{\{closure\}\}|::\{(closure|async_fn_env|async_block)#\d+\})+$`)
+	// C++ coroutines are split into ramp, resume, destroy and cleanup
+	// functions, eg. "task foo(int) [clone .resume]" with GCC or
+	// "foo(int) (.resume)" with Clang. GCC's older names use .actor.
+	cppCoroutineSuffix = regexp.MustCompile(`\s*(\[clone \.(resume|destroy|actor|cleanup)\]|\(\.(resume|destroy|cleanup)\))$`)
+	// Executor glue polling the generated state machine.
+	asyncPollFrame = regexp.MustCompile(`^(<)?(core|std)::future::(from_generator::GenFuture|future::Future)\b.*::poll$`)
+)
+
+// FoldAsyncFrameName returns the name of the logical function an async state
+// machine frame belongs to, and whether the frame was generated.
+func FoldAsyncFrameName(name string) (string, bool) {
+	if folded := rustAsyncSuffix.ReplaceAllString(name, ""); folded != name {
+		return folded, true
+	}
+	if folded := cppCoroutineSuffix.ReplaceAllString(name, ""); folded != name {
+		return folded, true
+	}
+	return name, false
+}
+
+// FoldsStacks returns true if any rule folds async state machine frames, so
+// that stacks have to be built with FoldStack.
+func (p *FramePipeline) FoldsStacks() bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.rules {
+		if r.foldAsync {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *FramePipeline) foldsAsync(loc *profile.Location) bool {
+	for _, r := range p.rules {
+		if r.foldAsync && r.matches(loc.Mapping.GetFile(), loc.Lines) {
+			return true
+		}
+	}
+	return false
+}
+
+// FoldStack folds the async state machine frames of a stack, ordered from the
+// leaf to the root, into their logical parent function while the stack is
+// built. Generated frames are renamed to their parent function, the executor
+// glue polling them is dropped and consecutive frames that end up belonging
+// to the same function are merged, also across locations, so that no node of
+// the state machine remains. Only the locations matching a rule with the
+// fold_async transform are folded, nil locations are kept in place. Changed
+// locations are copies, as locations are shared between stacks, and
+// locations left without frames are dropped.
+func (p *FramePipeline) FoldStack(stack []*profile.Location) []*profile.Location {
+	if !p.FoldsStacks() {
+		return stack
+	}
+
+	res := make([]*profile.Location, 0, len(stack))
+	// callee is the function of the last frame kept, which the next frame
+	// called.
+	var callee string
+	for _, loc := range stack {
+		if loc == nil || !p.foldsAsync(loc) {
+			res = append(res, loc)
+			callee = ""
+			continue
+		}
+
+		folded := &profile.Location{
+			ID:       loc.ID,
+			Address:  loc.Address,
+			IsFolded: loc.IsFolded,
+			Mapping:  loc.Mapping,
+			Lines:    make([]profile.LocationLine, 0, len(loc.Lines)),
+		}
+		for _, l := range loc.Lines {
+			if l.Function == nil {
+				folded.Lines = append(folded.Lines, l)
+				callee = ""
+				continue
+			}
+			if asyncPollFrame.MatchString(l.Function.Name) {
+				continue
+			}
+
+			name, generated := FoldAsyncFrameName(l.Function.Name)
+			if generated {
+				l = withFunction(l, func(fn *pb.Function) { fn.Name = name })
+			}
+			if name == callee {
+				// Keep the outer of the two frames, which has the line number
+				// of the call site in the parent.
+				if n := len(folded.Lines); n > 0 {
+					folded.Lines = folded.Lines[:n-1]
+				} else if last := res[len(res)-1]; len(last.Lines) > 1 {
+					last.Lines = last.Lines[:len(last.Lines)-1]
+				} else {
+					res = res[:len(res)-1]
+				}
+			}
+			folded.Lines = append(folded.Lines, l)
+			callee = name
+		}
+		if len(folded.Lines) > 0 {
+			res = append(res, folded)
+		}
+	}
+	if len(res) == 0 {
+		// Never leave a stack without any frames.
+		return stack
+	}
+	return res
+}
+
+type renameTransformer struct {
+	field       string
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+func (t *renameTransformer) Transform(lines []profile.LocationLine) []profile.LocationLine {
+	res := make([]profile.LocationLine, 0, len(lines))
+	for _, l := range lines {
+		if l.Function == nil {
+			res = append(res, l)
+			continue
+		}
+
+		value := l.Function.Name
+		if t.field == config.FrameTransformFieldFilename {
+			value = l.Function.Filename
+		}
+		if !t.pattern.MatchString(value) {
+			res = append(res, l)
+			continue
+		}
+
+		value = t.pattern.ReplaceAllString(value, t.replacement)
+		res = append(res, withFunction(l, func(fn *pb.Function) {
+			if t.field == config.FrameTransformFieldFilename {
+				fn.Filename = value
+			} else {
+				fn.Name = value
+			}
+		}))
+	}
+	return res
+}
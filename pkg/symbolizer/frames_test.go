@@ -0,0 +1,183 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symbolizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/metastore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/config"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+func TestStripTemplateArgs(t *testing.T) {
+	tests := map[string]string{
+		"main": "main",
+		"std::vector<int, std::allocator<int>>::push_back": "std::vector::push_back",
+		"foo<bar>::baz<qux>()":                             "foo::baz()",
+		"operator<<":                                       "operator<<",
+		"unbalanced<":                                      "unbalanced<",
+	}
+	for in, want := range tests {
+		require.Equal(t, want, StripTemplateArgs(in), in)
+	}
+}
+
+func lines(names ...string) []profile.LocationLine {
+	res := make([]profile.LocationLine, 0, len(names))
+	for i, n := range names {
+		res = append(res, profile.LocationLine{
+			Line:     int64(i + 1),
+			Function: &pb.Function{Name: n, Filename: "main.cpp"},
+		})
+	}
+	return res
+}
+
+func names(lines []profile.LocationLine) []string {
+	res := make([]string, 0, len(lines))
+	for _, l := range lines {
+		res = append(res, l.Function.Name)
+	}
+	return res
+}
+
+func TestFramePipeline(t *testing.T) {
+	p, err := NewFramePipeline([]*config.FrameTransformRule{{
+		Language: "cpp",
+		Transforms: []*config.FrameTransform{
+			{Type: config.FrameTransformCollapseStdInternals},
+			{Type: config.FrameTransformStripTemplateArgs},
+		},
+	}, {
+		Mapping: "^/usr/bin/app$",
+		Transforms: []*config.FrameTransform{{
+			Type:        config.FrameTransformRename,
+			Pattern:     "^generated_(.*)$",
+			Replacement: "$1",
+		}},
+	}})
+	require.NoError(t, err)
+
+	in := lines("std::__detail::inner", "std::sort<int*>", "generated_run<int>", "main")
+	out := p.Apply("/usr/bin/app", in)
+	require.Equal(t, []string{"std::sort", "run", "main"}, names(out))
+
+	// The input functions must not be modified as they may be cached.
+	require.Equal(t, "std::sort<int*>", in[1].Function.Name)
+
+	out = p.Apply("/usr/bin/other", lines("generated_run<int>"))
+	require.Equal(t, []string{"generated_run"}, names(out))
+
+	goLines := []profile.LocationLine{{Function: &pb.Function{Name: "std::x<y>", Filename: "main.go"}}}
+	require.Equal(t, []string{"std::x<y>"}, names(p.Apply("/usr/bin/other", goLines)))
+}
+
+func TestFramePipelineNil(t *testing.T) {
+	var p *FramePipeline
+	in := lines("a<b>")
+	require.Equal(t, in, p.Apply("", in))
+}
+
+func TestFoldAsyncFrames(t *testing.T) {
+	p, err := NewFramePipeline([]*config.FrameTransformRule{{
+		Transforms: []*config.FrameTransform{{Type: config.FrameTransformFoldAsync}},
+	}})
+	require.NoError(t, err)
+
+	require.True(t, p.FoldsStacks())
+
+	// Every frame of the stack is its own location, the state machine frames
+	// are folded across them.
+	stack := func(names ...string) []*profile.Location {
+		res := make([]*profile.Location, 0, len(names))
+		for i, l := range lines(names...) {
+			res = append(res, &profile.Location{Address: uint64(i + 1), Lines: []profile.LocationLine{l}})
+		}
+		return res
+	}
+	stackNames := func(stack []*profile.Location) []string {
+		var res []string
+		for _, loc := range stack {
+			res = append(res, names(loc.Lines)...)
+		}
+		return res
+	}
+
+	in := stack(
+		"app::handler::{{closure}}::{{closure}}",
+		"app::handler::{{closure}}",
+		"<core::future::from_generator::GenFuture<T> as core::future::future::Future>::poll",
+		"tokio::runtime::task::poll",
+	)
+	out := p.FoldStack(in)
+	require.Equal(t, []string{"app::handler", "tokio::runtime::task::poll"}, stackNames(out))
+	// The outermost of the folded frames is kept.
+	require.Equal(t, uint64(2), out[0].Address)
+	require.Equal(t, int64(2), out[0].Lines[0].Line)
+	// The shared locations are not modified.
+	require.Equal(t, "app::handler::{{closure}}", in[1].Lines[0].Function.Name)
+
+	// Inlined frames of a single location are folded too.
+	out = p.FoldStack([]*profile.Location{{Lines: lines("task<void> serve(int) [clone .resume]", "task<void> serve(int)", "main")}})
+	require.Equal(t, []string{"task<void> serve(int)", "main"}, stackNames(out))
+
+	// Locations that weren't symbolized separate the frames around them.
+	in = stack("app::run::{{closure}}", "app::run")
+	out = p.FoldStack([]*profile.Location{in[0], nil, in[1]})
+	require.Len(t, out, 3)
+	require.Equal(t, "app::run", out[0].Lines[0].Function.Name)
+	require.Nil(t, out[1])
+	require.Equal(t, "app::run", out[2].Lines[0].Function.Name)
+
+	// Folding only happens while stacks are built.
+	require.Equal(t, []string{"app::run::{{closure}}"}, names(p.Apply("", lines("app::run::{{closure}}"))))
+
+	name, generated := FoldAsyncFrameName("main")
+	require.False(t, generated)
+	require.Equal(t, "main", name)
+}
+
+func TestLabelRuntimeFrames(t *testing.T) {
+	p, err := NewFramePipeline([]*config.FrameTransformRule{{
+		Transforms: []*config.FrameTransform{{Type: config.FrameTransformLabelRuntime}},
+	}})
+	require.NoError(t, err)
+
+	in := lines(
+		"runtime.findRunnable",
+		"runtime.schedule",
+		"runtime.park_m",
+		"main.main",
+		"_PyEval_EvalFrameDefault",
+		"GCTaskThread::run()",
+	)
+	out := p.Apply("", in)
+	require.Equal(t, []string{
+		"[runtime] Go scheduler",
+		"main.main",
+		"[runtime] CPython eval loop",
+		"[runtime] JVM garbage collector",
+	}, names(out))
+	// The outermost of the merged frames is kept, with its original name.
+	require.Equal(t, int64(3), out[0].Line)
+	require.Equal(t, "runtime.park_m", out[0].Function.SystemName)
+	// The input is not modified.
+	require.Equal(t, "runtime.findRunnable", in[0].Function.Name)
+
+	_, ok := RuntimeFrameName("runtime.mallocgc")
+	require.False(t, ok)
+}
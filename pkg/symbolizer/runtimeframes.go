@@ -0,0 +1,92 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symbolizer
+
+import (
+	"regexp"
+
+	pb "github.com/parca-dev/parca/gen/proto/go/parca/metastore/v1alpha1"
+	"github.com/parca-dev/parca/pkg/profile"
+)
+
+// RuntimeFramePrefix prefixes the names of frames labeled as internal to a
+// language runtime, so they are told apart from user code in responses.
+const RuntimeFramePrefix = "[runtime] "
+
+type runtimeFrameRule struct {
+	pattern *regexp.Regexp
+	name    string
+}
+
+// runtimeFrameRules recognize the frames of well-known runtimes. C++ names
+// are matched as demangled, with or without parameters.
+var runtimeFrameRules = []runtimeFrameRule{{
+	pattern: regexp.MustCompile(`^runtime\.(schedule|findRunnable|findrunnable|park_m|mcall|goexit0|goexit1|gosched_m|gopreempt_m|stopm|startm|handoffp|mstart|mstart0|mstart1|sysmon|execute|runqsteal|stealWork|netpoll)$`),
+	name:    "Go scheduler",
+}, {
+	pattern: regexp.MustCompile(`^runtime\.(gcBgMarkWorker|gcDrain\w*|gcAssistAlloc\w*|markroot\w*|scanobject|scanblock|scanstack|scanframeworker|greyobject|bgsweep|bgscavenge|sweepone|gcStart|gcMarkDone|gcMarkTermination)$`),
+	name:    "Go garbage collector",
+}, {
+	pattern: regexp.MustCompile(`^(GCTaskThread::run|ConcurrentGCThread::run|G1\w*Thread::run\w*|ShenandoahControlThread::run_service|ZDirector::run_service|WorkerThread::run|GangWorker::loop)(\(.*)?$`),
+	name:    "JVM garbage collector",
+}, {
+	pattern: regexp.MustCompile(`^(CompileBroker::compiler_thread_loop|CompileBroker::invoke_compiler_on_method|C2Compiler::compile_method|Compilation::compile_method)(\(.*)?$`),
+	name:    "JVM JIT compiler",
+}, {
+	pattern: regexp.MustCompile(`^(_PyEval_EvalFrameDefault|_PyEval_EvalFrame|_PyEval_Vector|PyEval_EvalCode|PyEval_EvalCodeEx|_PyFunction_Vectorcall|_PyObject_Vectorcall\w*|PyObject_Vectorcall|_PyObject_MakeTpCall|PyObject_Call|method_vectorcall\w*|cfunction_vectorcall\w*)$`),
+	name:    "CPython eval loop",
+}}
+
+// RuntimeFrameName returns the display name of a frame internal to a
+// well-known language runtime, eg. "Go scheduler" for runtime.schedule, and
+// whether the frame is one.
+func RuntimeFrameName(name string) (string, bool) {
+	for _, r := range runtimeFrameRules {
+		if r.pattern.MatchString(name) {
+			return r.name, true
+		}
+	}
+	return name, false
+}
+
+// labelRuntimeFrames renames the frames internal to well-known runtimes to
+// their display name prefixed with RuntimeFramePrefix, keeping the original
+// name as the system name, and merges consecutive frames of the same
+// runtime component.
+func labelRuntimeFrames(lines []profile.LocationLine) []profile.LocationLine {
+	res := make([]profile.LocationLine, 0, len(lines))
+	for _, l := range lines {
+		if l.Function == nil {
+			res = append(res, l)
+			continue
+		}
+		name, ok := RuntimeFrameName(l.Function.Name)
+		if !ok {
+			res = append(res, l)
+			continue
+		}
+
+		l = withFunction(l, func(fn *pb.Function) {
+			fn.SystemName = fn.Name
+			fn.Name = RuntimeFramePrefix + name
+		})
+		// As when folding async frames, the outer frame of a run is kept.
+		if n := len(res); n > 0 && res[n-1].Function != nil && res[n-1].Function.Name == l.Function.Name {
+			res[n-1] = l
+			continue
+		}
+		res = append(res, l)
+	}
+	return res
+}
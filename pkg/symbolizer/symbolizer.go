@@ -60,14 +60,32 @@ func WithDemangleMode(mode string) Option {
 	}
 }
 
+// WithFramePipeline sets the pipeline applied to the lines of every
+// symbolized location.
+func WithFramePipeline(p *FramePipeline) Option {
+	return func(s *Symbolizer) {
+		s.frames = p
+	}
+}
+
+// WithDebuginfoSections fetches only the sections of the debuginfos needed
+// for symbolization if enabled, and if the DebuginfoFetcher supports it.
+func WithDebuginfoSections(enabled bool) Option {
+	return func(s *Symbolizer) {
+		s.sections = enabled
+	}
+}
+
 type Symbolizer struct {
 	logger log.Logger
 
 	debuginfo DebuginfoFetcher
+	sections  bool
 	cache     SymbolizerCache
 	metadata  DebuginfoMetadata
 
 	demangler *demangle.Demangler
+	frames    *FramePipeline
 
 	tmpDir string
 }
@@ -78,6 +96,14 @@ type DebuginfoFetcher interface {
 	FetchDebuginfo(ctx context.Context, dbginfo *debuginfopb.Debuginfo) (io.ReadCloser, error)
 }
 
+// DebuginfoSectionFetcher is implemented by DebuginfoFetchers that can fetch
+// only the sections of the debug info needed for symbolization.
+type DebuginfoSectionFetcher interface {
+	// FetchDebuginfoSections writes the sections of the debug info needed
+	// for symbolization to dst, at their offsets in the debug info.
+	FetchDebuginfoSections(ctx context.Context, dbginfo *debuginfopb.Debuginfo, dst *os.File) error
+}
+
 type SymbolizerCache interface {
 	Get(ctx context.Context, buildID string, addr uint64) ([]profile.LocationLine, bool, error)
 	Set(ctx context.Context, buildID string, addr uint64, lines []profile.LocationLine) error
@@ -171,6 +197,7 @@ func (s *Symbolizer) symbolize(
 			if err != nil {
 				level.Debug(s.logger).Log("msg", "failed to get lines", "err", err)
 			}
+			loc.Lines = s.frames.Apply(loc.Mapping.File, loc.Lines)
 		}
 	}
 
@@ -203,13 +230,6 @@ func (s *Symbolizer) getDebuginfo(ctx context.Context, buildID string) (string,
 		return "", nil, nil, debuginfo.ErrUnknownDebuginfoSource
 	}
 
-	// Fetch the debug info for the build ID.
-	rc, err := s.debuginfo.FetchDebuginfo(ctx, dbginfo)
-	if err != nil {
-		return "", nil, nil, fmt.Errorf("fetch debuginfo (BuildID: %q): %w", buildID, err)
-	}
-	defer rc.Close()
-
 	f, err := os.CreateTemp(s.tmpDir, "parca-symbolizer-*")
 	if err != nil {
 		return "", nil, nil, fmt.Errorf("create temp file: %w", err)
@@ -219,8 +239,9 @@ func (s *Symbolizer) getDebuginfo(ctx context.Context, buildID string) (string,
 		os.Remove(f.Name())
 	}()
 
-	if _, err := io.Copy(f, rc); err != nil {
-		return "", nil, nil, fmt.Errorf("copy debuginfo to temp file: %w", err)
+	// Fetch the debug info for the build ID.
+	if err := s.fetchDebuginfo(ctx, dbginfo, f); err != nil {
+		return "", nil, nil, fmt.Errorf("fetch debuginfo (BuildID: %q): %w", buildID, err)
 	}
 
 	if err := f.Close(); err != nil {
@@ -267,6 +288,25 @@ func (s *Symbolizer) getDebuginfo(ctx context.Context, buildID string) (string,
 	return targetPath, e, dbginfo.Quality, nil
 }
 
+// fetchDebuginfo writes the debug info to f, only the sections needed for
+// symbolization if enabled with WithDebuginfoSections.
+func (s *Symbolizer) fetchDebuginfo(ctx context.Context, dbginfo *debuginfopb.Debuginfo, f *os.File) error {
+	if sf, ok := s.debuginfo.(DebuginfoSectionFetcher); ok && s.sections {
+		return sf.FetchDebuginfoSections(ctx, dbginfo, f)
+	}
+
+	rc, err := s.debuginfo.FetchDebuginfo(ctx, dbginfo)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if _, err := io.Copy(f, rc); err != nil {
+		return fmt.Errorf("copy debuginfo to temp file: %w", err)
+	}
+	return nil
+}
+
 type cachedLiner struct {
 	logger    log.Logger
 	demangler *demangle.Demangler
@@ -338,31 +378,115 @@ func (c *cachedLiner) PCToLines(ctx context.Context, pc uint64) ([]profile.Locat
 	return lines, nil
 }
 
+// newConcreteLiner picks the best liner for the object file. Liners are tried
+// in order of the quality of their results: DWARF, then the Go symbol table,
+// then ELF symbols. If a liner fails to initialize (for example, because the
+// DWARF data is truncated) the next one is tried, so that stripped Go binaries
+// still get function names from their .gopclntab. The liners after the first
+// one are kept as fallbacks for addresses it has no entry for.
 func (c *cachedLiner) newConcreteLiner(filepath string, f *elf.File, quality *debuginfopb.DebuginfoQuality) (liner, error) {
-	switch {
-	case quality.HasDwarf:
-		lnr, err := addr2line.DWARF(c.logger, c.filepath, c.f, c.demangler)
+	var factories []linerFactory
+
+	if quality.HasDwarf {
+		factories = append(factories, linerFactory{name: "DWARF", new: func() (liner, error) {
+			// TODO CHECK plt
+			return addr2line.DWARF(c.logger, filepath, f, c.demangler)
+		}})
+	}
+
+	if quality.HasGoPclntab {
+		factories = append(factories, linerFactory{name: "Go", new: func() (liner, error) {
+			return addr2line.Go(c.logger, filepath, f)
+		}})
+	}
+
+	if quality.HasSymtab || quality.HasDynsym {
+		factories = append(factories, linerFactory{name: "Symtab", new: func() (liner, error) {
+			return addr2line.Symbols(c.logger, filepath, f, c.demangler)
+		}})
+	}
+
+	var errs error
+	for i, factory := range factories {
+		lnr, err := factory.new()
 		if err != nil {
-			return nil, fmt.Errorf("failed to create DWARF liner: %w", err)
+			errs = errors.Join(errs, fmt.Errorf("failed to create %s liner: %w", factory.name, err))
+			continue
 		}
 
-		return lnr, nil
-		// TODO CHECK plt
-	case quality.HasGoPclntab:
-		lnr, err := addr2line.Go(c.logger, c.filepath, c.f)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create Go liner: %w", err)
+		return &fallbackLiner{
+			logger:    c.logger,
+			liners:    []liner{lnr},
+			fallbacks: factories[i+1:],
+		}, nil
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return nil, ErrLinerFailed
+}
+
+type linerFactory struct {
+	name string
+	new  func() (liner, error)
+}
+
+// fallbackLiner resolves an address with the first liner that has an entry
+// for it. DWARF doesn't necessarily describe every function of a binary, for
+// example when parts of it were compiled without debug information, while the
+// Go symbol table always covers all Go functions. Fallback liners are only
+// created once an address misses in all the liners before them.
+type fallbackLiner struct {
+	logger log.Logger
+
+	liners    []liner
+	fallbacks []linerFactory
+}
+
+func (l *fallbackLiner) PCToLines(ctx context.Context, pc uint64) ([]profile.LocationLine, error) {
+	var (
+		found []profile.LocationLine
+		errs  error
+	)
+	for i := 0; i < len(l.liners) || len(l.fallbacks) > 0; i++ {
+		if i == len(l.liners) {
+			factory := l.fallbacks[0]
+			l.fallbacks = l.fallbacks[1:]
+
+			lnr, err := factory.new()
+			if err != nil {
+				level.Debug(l.logger).Log("msg", "failed to create fallback liner", "liner", factory.name, "err", err)
+				i--
+				continue
+			}
+			l.liners = append(l.liners, lnr)
 		}
 
-		return lnr, nil
-	case quality.HasSymtab || quality.HasDynsym:
-		lnr, err := addr2line.Symbols(c.logger, c.filepath, c.f, c.demangler)
+		lines, err := l.liners[i].PCToLines(ctx, pc)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Symtab liner: %w", err)
+			errs = errors.Join(errs, err)
+			continue
 		}
+		if len(lines) > 0 {
+			return lines, nil
+		}
+		found = lines
+	}
 
-		return lnr, nil
-	default:
-		return nil, ErrLinerFailed
+	if found == nil && errs != nil {
+		return nil, errs
+	}
+
+	return found, nil
+}
+
+func (l *fallbackLiner) Close() error {
+	var errs error
+	for _, lnr := range l.liners {
+		errs = errors.Join(errs, lnr.Close())
 	}
+
+	return errs
 }
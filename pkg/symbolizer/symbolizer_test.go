@@ -15,6 +15,7 @@ package symbolizer
 
 import (
 	"context"
+	"debug/elf"
 	"os"
 	"testing"
 
@@ -24,9 +25,11 @@ import (
 	"github.com/thanos-io/objstore/providers/filesystem"
 	"gopkg.in/yaml.v3"
 
+	debuginfopb "github.com/parca-dev/parca/gen/proto/go/parca/debuginfo/v1alpha1"
 	pb "github.com/parca-dev/parca/gen/proto/go/parca/metastore/v1alpha1"
 	"github.com/parca-dev/parca/pkg/debuginfo"
 	"github.com/parca-dev/parca/pkg/profile"
+	"github.com/parca-dev/parca/pkg/symbol/demangle"
 )
 
 type NoopSymbolizerCache struct{}
@@ -65,7 +68,7 @@ func TestSymbolizer(t *testing.T) {
 		logger,
 		metadata,
 		&NoopSymbolizerCache{},
-		debuginfo.NewFetcher(debuginfodClient, bucket),
+		debuginfo.NewFetcher(debuginfodClient, bucket, nil),
 		symbolizerCacheDir,
 	)
 
@@ -105,3 +108,41 @@ func TestSymbolizer(t *testing.T) {
 	require.Equal(t, "main.iteratePerTenant", location.Lines[2].Function.Name)
 	require.Equal(t, int64(23), location.Lines[2].Line)
 }
+
+func TestSymbolizerGoSymtabFallback(t *testing.T) {
+	// The DWARF data of this binary has no entry for main.main, see
+	// testdata/nodwarf/Makefile.
+	filename := "testdata/nodwarf/main"
+	f, err := elf.Open(filename)
+	require.NoError(t, err)
+
+	c := &cachedLiner{
+		logger:    log.NewNopLogger(),
+		demangler: demangle.NewDemangler("simple", true),
+	}
+	lnr, err := c.newConcreteLiner(filename, f, &debuginfopb.DebuginfoQuality{
+		HasDwarf:     true,
+		HasGoPclntab: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		lnr.Close()
+	})
+
+	ctx := context.Background()
+
+	// Covered by DWARF.
+	lines, err := lnr.PCToLines(ctx, 0x494880)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	require.Equal(t, "fmt.Fprintln", lines[0].Function.Name)
+	require.NotZero(t, lines[0].Function.StartLine)
+
+	// Not covered by DWARF, resolved from the Go symbol table.
+	lines, err = lnr.PCToLines(ctx, 0x499de0)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	require.Equal(t, "main.main", lines[0].Function.Name)
+	require.Equal(t, "./main.go", lines[0].Function.Filename)
+	require.Equal(t, int64(18), lines[0].Line)
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+)
+
+// UpdateGoldenEnv is the environment variable that makes AssertGolden
+// overwrite the golden files with the actual output instead of comparing
+// them, e.g.:
+//
+//	PARCA_UPDATE_GOLDEN=1 go test ./pkg/testutil/...
+const UpdateGoldenEnv = "PARCA_UPDATE_GOLDEN"
+
+// AssertGolden compares the output byte-for-byte with the golden file.
+func AssertGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "run the test with %s=1 to create the golden file", UpdateGoldenEnv)
+	require.Equal(t, string(want), string(got), "output differs from golden file %s", path)
+}
+
+// Folded renders the profile in the folded format: one line per stack with
+// its frames joined by semicolons from the root to the leaf, followed by the
+// value of the last sample type. The lines are sorted, so the output only
+// depends on the stacks and their values and not on how the server laid out
+// the profile.
+func Folded(p *pprofprofile.Profile) []byte {
+	stacks := map[string]int64{}
+	for _, s := range p.Sample {
+		frames := []string{}
+		for i := len(s.Location) - 1; i >= 0; i-- {
+			loc := s.Location[i]
+			if len(loc.Line) == 0 {
+				frames = append(frames, fmt.Sprintf("0x%x", loc.Address))
+				continue
+			}
+			for j := len(loc.Line) - 1; j >= 0; j-- {
+				if fn := loc.Line[j].Function; fn != nil {
+					frames = append(frames, fn.Name)
+				} else {
+					frames = append(frames, fmt.Sprintf("0x%x", loc.Address))
+				}
+			}
+		}
+		if len(s.Value) > 0 {
+			stacks[strings.Join(frames, ";")] += s.Value[len(s.Value)-1]
+		}
+	}
+
+	keys := make([]string, 0, len(stacks))
+	for k := range stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s %d\n", k, stacks[k])
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"time"
+
+	pprofprofile "github.com/google/pprof/profile"
+)
+
+// Stack is a stacktrace with its value. The frames are ordered from the root
+// to the leaf, like in the folded format.
+type Stack struct {
+	Frames []string
+	Value  int64
+}
+
+// NewProfile builds a pprof profile with a single sample type out of the
+// stacks. Functions with the same name share their function and location.
+func NewProfile(t time.Time, duration time.Duration, sampleType, sampleUnit string, stacks ...Stack) *pprofprofile.Profile {
+	p := &pprofprofile.Profile{
+		SampleType:    []*pprofprofile.ValueType{{Type: sampleType, Unit: sampleUnit}},
+		TimeNanos:     t.UnixNano(),
+		DurationNanos: duration.Nanoseconds(),
+	}
+
+	locations := map[string]*pprofprofile.Location{}
+	location := func(name string) *pprofprofile.Location {
+		if l, ok := locations[name]; ok {
+			return l
+		}
+
+		id := uint64(len(locations) + 1)
+		fn := &pprofprofile.Function{ID: id, Name: name}
+		l := &pprofprofile.Location{
+			ID:      id,
+			Address: 0x1000 * id,
+			Line:    []pprofprofile.Line{{Function: fn, Line: 1}},
+		}
+		p.Function = append(p.Function, fn)
+		p.Location = append(p.Location, l)
+		locations[name] = l
+		return l
+	}
+
+	for _, s := range stacks {
+		sample := &pprofprofile.Sample{Value: []int64{s.Value}}
+		for i := len(s.Frames) - 1; i >= 0; i-- {
+			sample.Location = append(sample.Location, location(s.Frames[i]))
+		}
+		p.Sample = append(p.Sample, sample)
+	}
+
+	return p
+}
+
+// NewCPUProfile builds a delta CPU profile sampled every 10ms, as sent by
+// the agent.
+func NewCPUProfile(t time.Time, duration time.Duration, stacks ...Stack) *pprofprofile.Profile {
+	p := NewProfile(t, duration, "samples", "count", stacks...)
+	p.PeriodType = &pprofprofile.ValueType{Type: "cpu", Unit: "nanoseconds"}
+	p.Period = (10 * time.Millisecond).Nanoseconds()
+	return p
+}
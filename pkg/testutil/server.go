@@ -0,0 +1,165 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testutil provides a harness to test the storage and query path
+// end-to-end. It runs the profile store and query services in-process behind
+// a real gRPC server, pushes synthetic profiles and compares query results
+// with golden files.
+package testutil
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/go-kit/log"
+	pprofprofile "github.com/google/pprof/profile"
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	profilestorepb "github.com/parca-dev/parca/gen/proto/go/parca/profilestore/v1alpha1"
+	querypb "github.com/parca-dev/parca/gen/proto/go/parca/query/v1alpha1"
+	"github.com/parca-dev/parca/pkg/client"
+	"github.com/parca-dev/parca/pkg/ingester"
+	"github.com/parca-dev/parca/pkg/kv"
+	"github.com/parca-dev/parca/pkg/parcacol"
+	"github.com/parca-dev/parca/pkg/profile"
+	"github.com/parca-dev/parca/pkg/profilestore"
+	queryservice "github.com/parca-dev/parca/pkg/query"
+)
+
+const tableName = "stacktraces"
+
+type options struct {
+	storeOpts []profilestore.Option
+}
+
+type Option func(*options)
+
+// WithStoreOptions configures the profile store of the server, for example
+// to test the ingestion with deduplication enabled.
+func WithStoreOptions(opts ...profilestore.Option) Option {
+	return func(o *options) {
+		o.storeOpts = append(o.storeOpts, opts...)
+	}
+}
+
+// Server is an in-process Parca server backed by an in-memory database.
+type Server struct {
+	// Client is connected to the server through gRPC, so requests and
+	// responses go through the same serialization as in production.
+	Client *client.Client
+	// Registry contains the metrics of the server.
+	Registry *prometheus.Registry
+
+	table *frostdb.Table
+}
+
+// NewServer starts a server that is stopped when the test finishes.
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	logger := log.NewNopLogger()
+	reg := prometheus.NewRegistry()
+	tracer := noop.NewTracerProvider().Tracer("")
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	t.Cleanup(func() { mem.AssertSize(t, 0) })
+
+	col, err := frostdb.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { col.Close() })
+
+	colDB, err := col.DB(context.Background(), "parca")
+	require.NoError(t, err)
+
+	schema, err := profile.Schema()
+	require.NoError(t, err)
+
+	table, err := colDB.Table(tableName, frostdb.NewTableConfig(profile.SchemaDefinition()))
+	require.NoError(t, err)
+
+	store := profilestore.NewProfileColumnStore(
+		reg,
+		logger,
+		tracer,
+		ingester.NewIngester(logger, table),
+		schema,
+		memory.DefaultAllocator,
+		o.storeOpts...,
+	)
+
+	api := queryservice.NewColumnQueryAPI(
+		logger,
+		tracer,
+		nil,
+		parcacol.NewQuerier(
+			logger,
+			tracer,
+			query.NewEngine(mem, colDB.TableProvider()),
+			tableName,
+			nil,
+			mem,
+		),
+		mem,
+		parcacol.NewArrowToProfileConverter(tracer, kv.NewKeyMaker()),
+		nil,
+	)
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	profilestorepb.RegisterProfileStoreServiceServer(srv, store)
+	querypb.RegisterQueryServiceServer(srv, api)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	c, err := client.New(
+		"passthrough:///bufnet",
+		client.WithInsecure(),
+		client.WithDialOptions(
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	return &Server{
+		Client:   c,
+		Registry: reg,
+		table:    table,
+	}
+}
+
+// Push writes the profile with the given labels and makes it visible to
+// queries.
+func (s *Server) Push(t testing.TB, labels map[string]string, p *pprofprofile.Profile) {
+	t.Helper()
+
+	require.NoError(t, s.Client.WriteProfile(context.Background(), labels, p))
+	require.NoError(t, s.table.EnsureCompaction())
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 The Parca Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer(t)
+
+	ts := time.Unix(1_700_000_000, 0)
+	s.Push(t, map[string]string{"__name__": "cpu", "job": "api"}, NewCPUProfile(ts, 10*time.Second,
+		Stack{Frames: []string{"main", "handle", "encode"}, Value: 30},
+		Stack{Frames: []string{"main", "handle"}, Value: 10},
+	))
+	s.Push(t, map[string]string{"__name__": "cpu", "job": "db"}, NewCPUProfile(ts, 10*time.Second,
+		Stack{Frames: []string{"main", "handle", "encode"}, Value: 5},
+		Stack{Frames: []string{"main", "compact"}, Value: 20},
+	))
+
+	start, end := ts.Add(-time.Minute), ts.Add(time.Minute)
+
+	values, err := s.Client.LabelValues(ctx, "job", nil, start, end)
+	require.NoError(t, err)
+	require.Equal(t, []string{"api", "db"}, values)
+
+	p, err := s.Client.QueryMerge(ctx, `cpu:samples:count:cpu:nanoseconds:delta`, start, end)
+	require.NoError(t, err)
+	AssertGolden(t, "testdata/merge.golden", Folded(p))
+
+	p, err = s.Client.QueryMerge(ctx, `cpu:samples:count:cpu:nanoseconds:delta{job="api"}`, start, end)
+	require.NoError(t, err)
+	AssertGolden(t, "testdata/merge_api.golden", Folded(p))
+}
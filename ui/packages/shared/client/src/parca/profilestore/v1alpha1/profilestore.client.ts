@@ -1,6 +1,9 @@
 // @generated by protobuf-ts 2.9.4 with parameter generate_dependencies
 // @generated from protobuf file "parca/profilestore/v1alpha1/profilestore.proto" (package "parca.profilestore.v1alpha1", syntax proto3)
 // tslint:disable
+import type { SeriesStatsResponse } from "./profilestore";
+import type { SeriesStatsRequest } from "./profilestore";
+import { SeriesStatsService } from "./profilestore";
 import { AgentsService } from "./profilestore";
 import type { AgentsResponse } from "./profilestore";
 import type { AgentsRequest } from "./profilestore";
@@ -106,3 +109,39 @@ export class AgentsServiceClient implements IAgentsServiceClient, ServiceInfo {
         return stackIntercept<AgentsRequest, AgentsResponse>("unary", this._transport, method, opt, input);
     }
 }
+/**
+ * SeriesStatsService serves the write statistics of the series, so that
+ * operators can find the targets dominating the ingestion.
+ *
+ * @generated from protobuf service parca.profilestore.v1alpha1.SeriesStatsService
+ */
+export interface ISeriesStatsServiceClient {
+    /**
+     * SeriesStats returns the statistics of the series written to recently
+     *
+     * @generated from protobuf rpc: SeriesStats(parca.profilestore.v1alpha1.SeriesStatsRequest) returns (parca.profilestore.v1alpha1.SeriesStatsResponse);
+     */
+    seriesStats(input: SeriesStatsRequest, options?: RpcOptions): UnaryCall<SeriesStatsRequest, SeriesStatsResponse>;
+}
+/**
+ * SeriesStatsService serves the write statistics of the series, so that
+ * operators can find the targets dominating the ingestion.
+ *
+ * @generated from protobuf service parca.profilestore.v1alpha1.SeriesStatsService
+ */
+export class SeriesStatsServiceClient implements ISeriesStatsServiceClient, ServiceInfo {
+    typeName = SeriesStatsService.typeName;
+    methods = SeriesStatsService.methods;
+    options = SeriesStatsService.options;
+    constructor(private readonly _transport: RpcTransport) {
+    }
+    /**
+     * SeriesStats returns the statistics of the series written to recently
+     *
+     * @generated from protobuf rpc: SeriesStats(parca.profilestore.v1alpha1.SeriesStatsRequest) returns (parca.profilestore.v1alpha1.SeriesStatsResponse);
+     */
+    seriesStats(input: SeriesStatsRequest, options?: RpcOptions): UnaryCall<SeriesStatsRequest, SeriesStatsResponse> {
+        const method = this.methods[0], opt = this._transport.mergeOptions(options);
+        return stackIntercept<SeriesStatsRequest, SeriesStatsResponse>("unary", this._transport, method, opt, input);
+    }
+}
@@ -241,6 +241,120 @@ export interface Agent {
      */
     lastPushDuration?: Duration;
 }
+/**
+ * SeriesStatsRequest is the request to retrieve the statistics of the series
+ *
+ * @generated from protobuf message parca.profilestore.v1alpha1.SeriesStatsRequest
+ */
+export interface SeriesStatsRequest {
+    /**
+     * order is the field the series are ordered by, the most written first
+     *
+     * @generated from protobuf field: parca.profilestore.v1alpha1.SeriesStatsRequest.Order order = 1;
+     */
+    order: SeriesStatsRequest_Order;
+    /**
+     * limit is the number of series returned, 100 if unset
+     *
+     * @generated from protobuf field: uint32 limit = 2;
+     */
+    limit: number;
+}
+/**
+ * Order is the field the series are ordered by
+ *
+ * @generated from protobuf enum parca.profilestore.v1alpha1.SeriesStatsRequest.Order
+ */
+export enum SeriesStatsRequest_Order {
+    /**
+     * ORDER_BYTES_UNSPECIFIED orders the series by the bytes written
+     *
+     * @generated from protobuf enum value: ORDER_BYTES_UNSPECIFIED = 0;
+     */
+    BYTES_UNSPECIFIED = 0,
+    /**
+     * ORDER_PROFILES orders the series by the number of profiles written
+     *
+     * @generated from protobuf enum value: ORDER_PROFILES = 1;
+     */
+    PROFILES = 1
+}
+/**
+ * SeriesStatsResponse is the statistics of all active series along with the
+ * series that were written the most to
+ *
+ * @generated from protobuf message parca.profilestore.v1alpha1.SeriesStatsResponse
+ */
+export interface SeriesStatsResponse {
+    /**
+     * series is the number of active series
+     *
+     * @generated from protobuf field: int64 series = 1;
+     */
+    series: bigint;
+    /**
+     * profiles is the number of profiles written to the active series
+     *
+     * @generated from protobuf field: int64 profiles = 2;
+     */
+    profiles: bigint;
+    /**
+     * bytes is the size of the profiles written to the active series
+     *
+     * @generated from protobuf field: int64 bytes = 3;
+     */
+    bytes: bigint;
+    /**
+     * top are the series written the most to, ordered by the requested field
+     *
+     * @generated from protobuf field: repeated parca.profilestore.v1alpha1.SeriesStats top = 4;
+     */
+    top: SeriesStats[];
+}
+/**
+ * SeriesStats is the statistics of the profiles written to a series since
+ * it became active
+ *
+ * @generated from protobuf message parca.profilestore.v1alpha1.SeriesStats
+ */
+export interface SeriesStats {
+    /**
+     * tenant is the tenant the series was written by, if any
+     *
+     * @generated from protobuf field: string tenant = 1;
+     */
+    tenant: string;
+    /**
+     * labels are the labels of the series
+     *
+     * @generated from protobuf field: parca.profilestore.v1alpha1.LabelSet labels = 2;
+     */
+    labels?: LabelSet;
+    /**
+     * profiles is the number of profiles written
+     *
+     * @generated from protobuf field: int64 profiles = 3;
+     */
+    profiles: bigint;
+    /**
+     * bytes is the size of the profiles written, as received
+     *
+     * @generated from protobuf field: int64 bytes = 4;
+     */
+    bytes: bigint;
+    /**
+     * first_write is the time the series was first written to
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp first_write = 5;
+     */
+    firstWrite?: Timestamp;
+    /**
+     * last_write is the time the series was last written to
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp last_write = 6;
+     */
+    lastWrite?: Timestamp;
+}
 // @generated message type with reflection information, may provide speed optimized methods
 class WriteRequest$Type extends MessageType<WriteRequest> {
     constructor() {
@@ -884,6 +998,216 @@ class Agent$Type extends MessageType<Agent> {
  * @generated MessageType for protobuf message parca.profilestore.v1alpha1.Agent
  */
 export const Agent = new Agent$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class SeriesStatsRequest$Type extends MessageType<SeriesStatsRequest> {
+    constructor() {
+        super("parca.profilestore.v1alpha1.SeriesStatsRequest", [
+            { no: 1, name: "order", kind: "enum", T: () => ["parca.profilestore.v1alpha1.SeriesStatsRequest.Order", SeriesStatsRequest_Order, "ORDER_"] },
+            { no: 2, name: "limit", kind: "scalar", T: 13 /*ScalarType.UINT32*/ }
+        ]);
+    }
+    create(value?: PartialMessage<SeriesStatsRequest>): SeriesStatsRequest {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.order = 0;
+        message.limit = 0;
+        if (value !== undefined)
+            reflectionMergePartial<SeriesStatsRequest>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SeriesStatsRequest): SeriesStatsRequest {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.profilestore.v1alpha1.SeriesStatsRequest.Order order */ 1:
+                    message.order = reader.int32();
+                    break;
+                case /* uint32 limit */ 2:
+                    message.limit = reader.uint32();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: SeriesStatsRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.profilestore.v1alpha1.SeriesStatsRequest.Order order = 1; */
+        if (message.order !== 0)
+            writer.tag(1, WireType.Varint).int32(message.order);
+        /* uint32 limit = 2; */
+        if (message.limit !== 0)
+            writer.tag(2, WireType.Varint).uint32(message.limit);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.profilestore.v1alpha1.SeriesStatsRequest
+ */
+export const SeriesStatsRequest = new SeriesStatsRequest$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class SeriesStatsResponse$Type extends MessageType<SeriesStatsResponse> {
+    constructor() {
+        super("parca.profilestore.v1alpha1.SeriesStatsResponse", [
+            { no: 1, name: "series", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 2, name: "profiles", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 3, name: "bytes", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 4, name: "top", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => SeriesStats }
+        ]);
+    }
+    create(value?: PartialMessage<SeriesStatsResponse>): SeriesStatsResponse {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.series = 0n;
+        message.profiles = 0n;
+        message.bytes = 0n;
+        message.top = [];
+        if (value !== undefined)
+            reflectionMergePartial<SeriesStatsResponse>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SeriesStatsResponse): SeriesStatsResponse {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* int64 series */ 1:
+                    message.series = reader.int64().toBigInt();
+                    break;
+                case /* int64 profiles */ 2:
+                    message.profiles = reader.int64().toBigInt();
+                    break;
+                case /* int64 bytes */ 3:
+                    message.bytes = reader.int64().toBigInt();
+                    break;
+                case /* repeated parca.profilestore.v1alpha1.SeriesStats top */ 4:
+                    message.top.push(SeriesStats.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: SeriesStatsResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* int64 series = 1; */
+        if (message.series !== 0n)
+            writer.tag(1, WireType.Varint).int64(message.series);
+        /* int64 profiles = 2; */
+        if (message.profiles !== 0n)
+            writer.tag(2, WireType.Varint).int64(message.profiles);
+        /* int64 bytes = 3; */
+        if (message.bytes !== 0n)
+            writer.tag(3, WireType.Varint).int64(message.bytes);
+        /* repeated parca.profilestore.v1alpha1.SeriesStats top = 4; */
+        for (let i = 0; i < message.top.length; i++)
+            SeriesStats.internalBinaryWrite(message.top[i], writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.profilestore.v1alpha1.SeriesStatsResponse
+ */
+export const SeriesStatsResponse = new SeriesStatsResponse$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class SeriesStats$Type extends MessageType<SeriesStats> {
+    constructor() {
+        super("parca.profilestore.v1alpha1.SeriesStats", [
+            { no: 1, name: "tenant", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "labels", kind: "message", T: () => LabelSet },
+            { no: 3, name: "profiles", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 4, name: "bytes", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 5, name: "first_write", kind: "message", T: () => Timestamp },
+            { no: 6, name: "last_write", kind: "message", T: () => Timestamp }
+        ]);
+    }
+    create(value?: PartialMessage<SeriesStats>): SeriesStats {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.tenant = "";
+        message.profiles = 0n;
+        message.bytes = 0n;
+        if (value !== undefined)
+            reflectionMergePartial<SeriesStats>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SeriesStats): SeriesStats {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string tenant */ 1:
+                    message.tenant = reader.string();
+                    break;
+                case /* parca.profilestore.v1alpha1.LabelSet labels */ 2:
+                    message.labels = LabelSet.internalBinaryRead(reader, reader.uint32(), options, message.labels);
+                    break;
+                case /* int64 profiles */ 3:
+                    message.profiles = reader.int64().toBigInt();
+                    break;
+                case /* int64 bytes */ 4:
+                    message.bytes = reader.int64().toBigInt();
+                    break;
+                case /* google.protobuf.Timestamp first_write */ 5:
+                    message.firstWrite = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.firstWrite);
+                    break;
+                case /* google.protobuf.Timestamp last_write */ 6:
+                    message.lastWrite = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.lastWrite);
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: SeriesStats, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string tenant = 1; */
+        if (message.tenant !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.tenant);
+        /* parca.profilestore.v1alpha1.LabelSet labels = 2; */
+        if (message.labels)
+            LabelSet.internalBinaryWrite(message.labels, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* int64 profiles = 3; */
+        if (message.profiles !== 0n)
+            writer.tag(3, WireType.Varint).int64(message.profiles);
+        /* int64 bytes = 4; */
+        if (message.bytes !== 0n)
+            writer.tag(4, WireType.Varint).int64(message.bytes);
+        /* google.protobuf.Timestamp first_write = 5; */
+        if (message.firstWrite)
+            Timestamp.internalBinaryWrite(message.firstWrite, writer.tag(5, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp last_write = 6; */
+        if (message.lastWrite)
+            Timestamp.internalBinaryWrite(message.lastWrite, writer.tag(6, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.profilestore.v1alpha1.SeriesStats
+ */
+export const SeriesStats = new SeriesStats$Type();
 /**
  * @generated ServiceType for protobuf service parca.profilestore.v1alpha1.ProfileStoreService
  */
@@ -897,3 +1221,9 @@ export const ProfileStoreService = new ServiceType("parca.profilestore.v1alpha1.
 export const AgentsService = new ServiceType("parca.profilestore.v1alpha1.AgentsService", [
     { name: "Agents", options: { "google.api.http": { get: "/agents" } }, I: AgentsRequest, O: AgentsResponse }
 ]);
+/**
+ * @generated ServiceType for protobuf service parca.profilestore.v1alpha1.SeriesStatsService
+ */
+export const SeriesStatsService = new ServiceType("parca.profilestore.v1alpha1.SeriesStatsService", [
+    { name: "SeriesStats", options: { "google.api.http": { get: "/series/stats" } }, I: SeriesStatsRequest, O: SeriesStatsResponse }
+]);
@@ -1,9 +1,28 @@
 // @generated by protobuf-ts 2.9.4 with parameter generate_dependencies
 // @generated from protobuf file "parca/query/v1alpha1/query.proto" (package "parca.query.v1alpha1", syntax proto3)
 // tslint:disable
+import type { ProfileTypeCountsResponse } from "./query";
+import type { ProfileTypeCountsRequest } from "./query";
+import type { TraceExemplarsResponse } from "./query";
+import type { TraceExemplarsRequest } from "./query";
+import type { DiffSignificanceResponse } from "./query";
+import type { DiffSignificanceRequest } from "./query";
+import type { ProfileCaptureMetadataResponse } from "./query";
+import type { ProfileCaptureMetadataRequest } from "./query";
+import type { QueryUnitsResponse } from "./query";
+import type { QueryUnitsRequest } from "./query";
+import type { QueryRangeUnitsResponse } from "./query";
+import type { QueryRangeUnitsRequest } from "./query";
+import type { BinariesResponse } from "./query";
+import type { BinariesRequest } from "./query";
+import { BinaryService } from "./query";
 import type { RpcTransport } from "@protobuf-ts/runtime-rpc";
 import type { ServiceInfo } from "@protobuf-ts/runtime-rpc";
 import { QueryService } from "./query";
+import type { CompareToBaselineResponse } from "./query";
+import type { CompareToBaselineRequest } from "./query";
+import type { QueryRangeWindowsResponse } from "./query";
+import type { QueryRangeWindowsRequest } from "./query";
 import type { ShareProfileResponse } from "./query";
 import type { ShareProfileRequest } from "./query";
 import type { ValuesResponse } from "./query";
@@ -69,6 +88,54 @@ export interface IQueryServiceClient {
      * @generated from protobuf rpc: ShareProfile(parca.query.v1alpha1.ShareProfileRequest) returns (parca.query.v1alpha1.ShareProfileResponse);
      */
     shareProfile(input: ShareProfileRequest, options?: RpcOptions): UnaryCall<ShareProfileRequest, ShareProfileResponse>;
+    /**
+     * QueryRangeWindows performs the same profile query over multiple time ranges
+     *
+     * @generated from protobuf rpc: QueryRangeWindows(parca.query.v1alpha1.QueryRangeWindowsRequest) returns (parca.query.v1alpha1.QueryRangeWindowsResponse);
+     */
+    queryRangeWindows(input: QueryRangeWindowsRequest, options?: RpcOptions): UnaryCall<QueryRangeWindowsRequest, QueryRangeWindowsResponse>;
+    /**
+     * CompareToBaseline compares a profile against the merge of the baseline profiles and reports the functions that regressed
+     *
+     * @generated from protobuf rpc: CompareToBaseline(parca.query.v1alpha1.CompareToBaselineRequest) returns (parca.query.v1alpha1.CompareToBaselineResponse);
+     */
+    compareToBaseline(input: CompareToBaselineRequest, options?: RpcOptions): UnaryCall<CompareToBaselineRequest, CompareToBaselineResponse>;
+    /**
+     * QueryUnits performs a top query and converts the values of the report to another unit
+     *
+     * @generated from protobuf rpc: QueryUnits(parca.query.v1alpha1.QueryUnitsRequest) returns (parca.query.v1alpha1.QueryUnitsResponse);
+     */
+    queryUnits(input: QueryUnitsRequest, options?: RpcOptions): UnaryCall<QueryUnitsRequest, QueryUnitsResponse>;
+    /**
+     * QueryRangeUnits performs a range query and converts the sample values of the series to another unit
+     *
+     * @generated from protobuf rpc: QueryRangeUnits(parca.query.v1alpha1.QueryRangeUnitsRequest) returns (parca.query.v1alpha1.QueryRangeUnitsResponse);
+     */
+    queryRangeUnits(input: QueryRangeUnitsRequest, options?: RpcOptions): UnaryCall<QueryRangeUnitsRequest, QueryRangeUnitsResponse>;
+    /**
+     * ProfileCaptureMetadata returns the metadata written along with the profiles matching a query, eg. the agent version
+     *
+     * @generated from protobuf rpc: ProfileCaptureMetadata(parca.query.v1alpha1.ProfileCaptureMetadataRequest) returns (parca.query.v1alpha1.ProfileCaptureMetadataResponse);
+     */
+    profileCaptureMetadata(input: ProfileCaptureMetadataRequest, options?: RpcOptions): UnaryCall<ProfileCaptureMetadataRequest, ProfileCaptureMetadataResponse>;
+    /**
+     * DiffSignificance tests whether the changes of the cumulative share every function has of the total between the two sides of a diff are significant
+     *
+     * @generated from protobuf rpc: DiffSignificance(parca.query.v1alpha1.DiffSignificanceRequest) returns (parca.query.v1alpha1.DiffSignificanceResponse);
+     */
+    diffSignificance(input: DiffSignificanceRequest, options?: RpcOptions): UnaryCall<DiffSignificanceRequest, DiffSignificanceResponse>;
+    /**
+     * TraceExemplars returns the traces the samples matching a query were recorded in, the ones with the highest total value first
+     *
+     * @generated from protobuf rpc: TraceExemplars(parca.query.v1alpha1.TraceExemplarsRequest) returns (parca.query.v1alpha1.TraceExemplarsResponse);
+     */
+    traceExemplars(input: TraceExemplarsRequest, options?: RpcOptions): UnaryCall<TraceExemplarsRequest, TraceExemplarsResponse>;
+    /**
+     * ProfileTypeCounts returns the profile types stored for the series matching a selector with the number of samples stored
+     *
+     * @generated from protobuf rpc: ProfileTypeCounts(parca.query.v1alpha1.ProfileTypeCountsRequest) returns (parca.query.v1alpha1.ProfileTypeCountsResponse);
+     */
+    profileTypeCounts(input: ProfileTypeCountsRequest, options?: RpcOptions): UnaryCall<ProfileTypeCountsRequest, ProfileTypeCountsResponse>;
 }
 /**
  * QueryService is the service that provides APIs to retrieve and inspect profiles
@@ -144,4 +211,110 @@ export class QueryServiceClient implements IQueryServiceClient, ServiceInfo {
         const method = this.methods[6], opt = this._transport.mergeOptions(options);
         return stackIntercept<ShareProfileRequest, ShareProfileResponse>("unary", this._transport, method, opt, input);
     }
+    /**
+     * QueryRangeWindows performs the same profile query over multiple time ranges
+     *
+     * @generated from protobuf rpc: QueryRangeWindows(parca.query.v1alpha1.QueryRangeWindowsRequest) returns (parca.query.v1alpha1.QueryRangeWindowsResponse);
+     */
+    queryRangeWindows(input: QueryRangeWindowsRequest, options?: RpcOptions): UnaryCall<QueryRangeWindowsRequest, QueryRangeWindowsResponse> {
+        const method = this.methods[7], opt = this._transport.mergeOptions(options);
+        return stackIntercept<QueryRangeWindowsRequest, QueryRangeWindowsResponse>("unary", this._transport, method, opt, input);
+    }
+    /**
+     * CompareToBaseline compares a profile against the merge of the baseline profiles and reports the functions that regressed
+     *
+     * @generated from protobuf rpc: CompareToBaseline(parca.query.v1alpha1.CompareToBaselineRequest) returns (parca.query.v1alpha1.CompareToBaselineResponse);
+     */
+    compareToBaseline(input: CompareToBaselineRequest, options?: RpcOptions): UnaryCall<CompareToBaselineRequest, CompareToBaselineResponse> {
+        const method = this.methods[8], opt = this._transport.mergeOptions(options);
+        return stackIntercept<CompareToBaselineRequest, CompareToBaselineResponse>("unary", this._transport, method, opt, input);
+    }
+    /**
+     * QueryUnits performs a top query and converts the values of the report to another unit
+     *
+     * @generated from protobuf rpc: QueryUnits(parca.query.v1alpha1.QueryUnitsRequest) returns (parca.query.v1alpha1.QueryUnitsResponse);
+     */
+    queryUnits(input: QueryUnitsRequest, options?: RpcOptions): UnaryCall<QueryUnitsRequest, QueryUnitsResponse> {
+        const method = this.methods[9], opt = this._transport.mergeOptions(options);
+        return stackIntercept<QueryUnitsRequest, QueryUnitsResponse>("unary", this._transport, method, opt, input);
+    }
+    /**
+     * QueryRangeUnits performs a range query and converts the sample values of the series to another unit
+     *
+     * @generated from protobuf rpc: QueryRangeUnits(parca.query.v1alpha1.QueryRangeUnitsRequest) returns (parca.query.v1alpha1.QueryRangeUnitsResponse);
+     */
+    queryRangeUnits(input: QueryRangeUnitsRequest, options?: RpcOptions): UnaryCall<QueryRangeUnitsRequest, QueryRangeUnitsResponse> {
+        const method = this.methods[10], opt = this._transport.mergeOptions(options);
+        return stackIntercept<QueryRangeUnitsRequest, QueryRangeUnitsResponse>("unary", this._transport, method, opt, input);
+    }
+    /**
+     * ProfileCaptureMetadata returns the metadata written along with the profiles matching a query, eg. the agent version
+     *
+     * @generated from protobuf rpc: ProfileCaptureMetadata(parca.query.v1alpha1.ProfileCaptureMetadataRequest) returns (parca.query.v1alpha1.ProfileCaptureMetadataResponse);
+     */
+    profileCaptureMetadata(input: ProfileCaptureMetadataRequest, options?: RpcOptions): UnaryCall<ProfileCaptureMetadataRequest, ProfileCaptureMetadataResponse> {
+        const method = this.methods[11], opt = this._transport.mergeOptions(options);
+        return stackIntercept<ProfileCaptureMetadataRequest, ProfileCaptureMetadataResponse>("unary", this._transport, method, opt, input);
+    }
+    /**
+     * DiffSignificance tests whether the changes of the cumulative share every function has of the total between the two sides of a diff are significant
+     *
+     * @generated from protobuf rpc: DiffSignificance(parca.query.v1alpha1.DiffSignificanceRequest) returns (parca.query.v1alpha1.DiffSignificanceResponse);
+     */
+    diffSignificance(input: DiffSignificanceRequest, options?: RpcOptions): UnaryCall<DiffSignificanceRequest, DiffSignificanceResponse> {
+        const method = this.methods[12], opt = this._transport.mergeOptions(options);
+        return stackIntercept<DiffSignificanceRequest, DiffSignificanceResponse>("unary", this._transport, method, opt, input);
+    }
+    /**
+     * TraceExemplars returns the traces the samples matching a query were recorded in, the ones with the highest total value first
+     *
+     * @generated from protobuf rpc: TraceExemplars(parca.query.v1alpha1.TraceExemplarsRequest) returns (parca.query.v1alpha1.TraceExemplarsResponse);
+     */
+    traceExemplars(input: TraceExemplarsRequest, options?: RpcOptions): UnaryCall<TraceExemplarsRequest, TraceExemplarsResponse> {
+        const method = this.methods[13], opt = this._transport.mergeOptions(options);
+        return stackIntercept<TraceExemplarsRequest, TraceExemplarsResponse>("unary", this._transport, method, opt, input);
+    }
+    /**
+     * ProfileTypeCounts returns the profile types stored for the series matching a selector with the number of samples stored
+     *
+     * @generated from protobuf rpc: ProfileTypeCounts(parca.query.v1alpha1.ProfileTypeCountsRequest) returns (parca.query.v1alpha1.ProfileTypeCountsResponse);
+     */
+    profileTypeCounts(input: ProfileTypeCountsRequest, options?: RpcOptions): UnaryCall<ProfileTypeCountsRequest, ProfileTypeCountsResponse> {
+        const method = this.methods[14], opt = this._transport.mergeOptions(options);
+        return stackIntercept<ProfileTypeCountsRequest, ProfileTypeCountsResponse>("unary", this._transport, method, opt, input);
+    }
+}
+/**
+ * BinaryService lists the binaries observed in the mappings of stored profiles
+ *
+ * @generated from protobuf service parca.query.v1alpha1.BinaryService
+ */
+export interface IBinaryServiceClient {
+    /**
+     * Binaries returns the binaries observed in the profiles matching a query together with the status of their debuginfo
+     *
+     * @generated from protobuf rpc: Binaries(parca.query.v1alpha1.BinariesRequest) returns (parca.query.v1alpha1.BinariesResponse);
+     */
+    binaries(input: BinariesRequest, options?: RpcOptions): UnaryCall<BinariesRequest, BinariesResponse>;
+}
+/**
+ * BinaryService lists the binaries observed in the mappings of stored profiles
+ *
+ * @generated from protobuf service parca.query.v1alpha1.BinaryService
+ */
+export class BinaryServiceClient implements IBinaryServiceClient, ServiceInfo {
+    typeName = BinaryService.typeName;
+    methods = BinaryService.methods;
+    options = BinaryService.options;
+    constructor(private readonly _transport: RpcTransport) {
+    }
+    /**
+     * Binaries returns the binaries observed in the profiles matching a query together with the status of their debuginfo
+     *
+     * @generated from protobuf rpc: Binaries(parca.query.v1alpha1.BinariesRequest) returns (parca.query.v1alpha1.BinariesResponse);
+     */
+    binaries(input: BinariesRequest, options?: RpcOptions): UnaryCall<BinariesRequest, BinariesResponse> {
+        const method = this.methods[0], opt = this._transport.mergeOptions(options);
+        return stackIntercept<BinariesRequest, BinariesResponse>("unary", this._transport, method, opt, input);
+    }
 }
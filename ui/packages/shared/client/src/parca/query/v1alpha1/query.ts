@@ -137,6 +137,75 @@ export interface QueryRangeResponse {
      */
     series: MetricsSeries[];
 }
+/**
+ * QueryRangeWindowsRequest is the request for a set of profile values over multiple time windows
+ *
+ * @generated from protobuf message parca.query.v1alpha1.QueryRangeWindowsRequest
+ */
+export interface QueryRangeWindowsRequest {
+    /**
+     * query is the query string to match profiles against
+     *
+     * @generated from protobuf field: string query = 1;
+     */
+    query: string;
+    /**
+     * windows are the time windows to query
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.TimeWindow windows = 2;
+     */
+    windows: TimeWindow[];
+    /**
+     * limit is the max number of profiles to include in the response
+     *
+     * @generated from protobuf field: uint32 limit = 3;
+     */
+    limit: number;
+    /**
+     * step is the duration of each sample returned.
+     *
+     * @generated from protobuf field: google.protobuf.Duration step = 4;
+     */
+    step?: Duration;
+    /**
+     * sum_by is the set of labels to sum by
+     *
+     * @generated from protobuf field: repeated string sum_by = 5;
+     */
+    sumBy: string[];
+}
+/**
+ * TimeWindow is a time window of a query
+ *
+ * @generated from protobuf message parca.query.v1alpha1.TimeWindow
+ */
+export interface TimeWindow {
+    /**
+     * start is the start of the time window
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp start = 1;
+     */
+    start?: Timestamp;
+    /**
+     * end is the end of the time window
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp end = 2;
+     */
+    end?: Timestamp;
+}
+/**
+ * QueryRangeWindowsResponse is the set of matching profile values of each window
+ *
+ * @generated from protobuf message parca.query.v1alpha1.QueryRangeWindowsResponse
+ */
+export interface QueryRangeWindowsResponse {
+    /**
+     * windows are the responses of the windows, in the order of the request
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.QueryRangeResponse windows = 1;
+     */
+    windows: QueryRangeResponse[];
+}
 /**
  * MetricsSeries is a set of labels and corresponding sample values
  *
@@ -1426,208 +1495,2790 @@ export interface ProfileMetadata {
      */
     labels: string[];
 }
-// @generated message type with reflection information, may provide speed optimized methods
-class ProfileTypesRequest$Type extends MessageType<ProfileTypesRequest> {
-    constructor() {
-        super("parca.query.v1alpha1.ProfileTypesRequest", []);
-    }
-    create(value?: PartialMessage<ProfileTypesRequest>): ProfileTypesRequest {
-        const message = globalThis.Object.create((this.messagePrototype!));
-        if (value !== undefined)
-            reflectionMergePartial<ProfileTypesRequest>(this, message, value);
-        return message;
-    }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileTypesRequest): ProfileTypesRequest {
-        return target ?? this.create();
-    }
-    internalBinaryWrite(message: ProfileTypesRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        let u = options.writeUnknownFields;
-        if (u !== false)
-            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
-        return writer;
-    }
+/**
+ * CompareToBaselineRequest is the request to compare a profile, eg. of a benchmark run, against a baseline
+ *
+ * @generated from protobuf message parca.query.v1alpha1.CompareToBaselineRequest
+ */
+export interface CompareToBaselineRequest {
+    /**
+     * baseline_query is the query of the baseline profiles, they are merged
+     *
+     * @generated from protobuf field: string baseline_query = 1;
+     */
+    baselineQuery: string;
+    /**
+     * start is the start of the time range of the baseline profiles
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp start = 2;
+     */
+    start?: Timestamp;
+    /**
+     * end is the end of the time range of the baseline profiles
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp end = 3;
+     */
+    end?: Timestamp;
+    /**
+     * profile is the pprof profile to compare, optionally gzipped
+     *
+     * @generated from protobuf field: bytes profile = 4;
+     */
+    profile: Uint8Array;
+    /**
+     * sample_type is the type of the sample values of the profile to compare, eg. cpu. It defaults to the sample type of the baseline query
+     *
+     * @generated from protobuf field: string sample_type = 5;
+     */
+    sampleType: string;
+    /**
+     * rules are the regression thresholds of the functions to compare
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.RegressionRule rules = 6;
+     */
+    rules: RegressionRule[];
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileTypesRequest
+ * RegressionRule limits how much the functions matching a regular expression may regress compared to a baseline
+ *
+ * @generated from protobuf message parca.query.v1alpha1.RegressionRule
  */
-export const ProfileTypesRequest = new ProfileTypesRequest$Type();
-// @generated message type with reflection information, may provide speed optimized methods
-class ProfileTypesResponse$Type extends MessageType<ProfileTypesResponse> {
-    constructor() {
-        super("parca.query.v1alpha1.ProfileTypesResponse", [
-            { no: 1, name: "types", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => ProfileType }
-        ]);
-    }
-    create(value?: PartialMessage<ProfileTypesResponse>): ProfileTypesResponse {
-        const message = globalThis.Object.create((this.messagePrototype!));
-        message.types = [];
-        if (value !== undefined)
-            reflectionMergePartial<ProfileTypesResponse>(this, message, value);
-        return message;
-    }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileTypesResponse): ProfileTypesResponse {
-        let message = target ?? this.create(), end = reader.pos + length;
-        while (reader.pos < end) {
-            let [fieldNo, wireType] = reader.tag();
-            switch (fieldNo) {
-                case /* repeated parca.query.v1alpha1.ProfileType types */ 1:
-                    message.types.push(ProfileType.internalBinaryRead(reader, reader.uint32(), options));
-                    break;
-                default:
-                    let u = options.readUnknownField;
-                    if (u === "throw")
-                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
-                    let d = reader.skip(wireType);
-                    if (u !== false)
-                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
-            }
-        }
-        return message;
-    }
-    internalBinaryWrite(message: ProfileTypesResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated parca.query.v1alpha1.ProfileType types = 1; */
-        for (let i = 0; i < message.types.length; i++)
-            ProfileType.internalBinaryWrite(message.types[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        let u = options.writeUnknownFields;
-        if (u !== false)
-            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
-        return writer;
-    }
+export interface RegressionRule {
+    /**
+     * function is the regular expression matching the names of the functions
+     *
+     * @generated from protobuf field: string function = 1;
+     */
+    function: string;
+    /**
+     * max_increase is the maximum allowed relative increase of the share of the total spent in the function, eg. 0.05 for 5%
+     *
+     * @generated from protobuf field: double max_increase = 2;
+     */
+    maxIncrease: number;
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileTypesResponse
+ * CompareToBaselineResponse is the result of comparing a profile against a baseline
+ *
+ * @generated from protobuf message parca.query.v1alpha1.CompareToBaselineResponse
  */
-export const ProfileTypesResponse = new ProfileTypesResponse$Type();
-// @generated message type with reflection information, may provide speed optimized methods
-class ProfileType$Type extends MessageType<ProfileType> {
-    constructor() {
-        super("parca.query.v1alpha1.ProfileType", [
-            { no: 1, name: "name", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "sample_type", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 3, name: "sample_unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 4, name: "period_type", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 5, name: "period_unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 6, name: "delta", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
-        ]);
-    }
-    create(value?: PartialMessage<ProfileType>): ProfileType {
-        const message = globalThis.Object.create((this.messagePrototype!));
-        message.name = "";
-        message.sampleType = "";
-        message.sampleUnit = "";
-        message.periodType = "";
-        message.periodUnit = "";
-        message.delta = false;
-        if (value !== undefined)
-            reflectionMergePartial<ProfileType>(this, message, value);
-        return message;
-    }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileType): ProfileType {
-        let message = target ?? this.create(), end = reader.pos + length;
-        while (reader.pos < end) {
-            let [fieldNo, wireType] = reader.tag();
-            switch (fieldNo) {
-                case /* string name */ 1:
-                    message.name = reader.string();
-                    break;
-                case /* string sample_type */ 2:
-                    message.sampleType = reader.string();
-                    break;
-                case /* string sample_unit */ 3:
-                    message.sampleUnit = reader.string();
-                    break;
-                case /* string period_type */ 4:
-                    message.periodType = reader.string();
-                    break;
-                case /* string period_unit */ 5:
-                    message.periodUnit = reader.string();
-                    break;
-                case /* bool delta */ 6:
-                    message.delta = reader.bool();
-                    break;
-                default:
-                    let u = options.readUnknownField;
-                    if (u === "throw")
-                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
-                    let d = reader.skip(wireType);
-                    if (u !== false)
-                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
-            }
-        }
-        return message;
-    }
-    internalBinaryWrite(message: ProfileType, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string name = 1; */
-        if (message.name !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.name);
-        /* string sample_type = 2; */
-        if (message.sampleType !== "")
-            writer.tag(2, WireType.LengthDelimited).string(message.sampleType);
-        /* string sample_unit = 3; */
-        if (message.sampleUnit !== "")
-            writer.tag(3, WireType.LengthDelimited).string(message.sampleUnit);
-        /* string period_type = 4; */
-        if (message.periodType !== "")
-            writer.tag(4, WireType.LengthDelimited).string(message.periodType);
-        /* string period_unit = 5; */
-        if (message.periodUnit !== "")
-            writer.tag(5, WireType.LengthDelimited).string(message.periodUnit);
-        /* bool delta = 6; */
-        if (message.delta !== false)
-            writer.tag(6, WireType.Varint).bool(message.delta);
-        let u = options.writeUnknownFields;
-        if (u !== false)
-            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
-        return writer;
-    }
+export interface CompareToBaselineResponse {
+    /**
+     * functions are the compared functions, ordered by decreasing increase
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.FunctionComparison functions = 1;
+     */
+    functions: FunctionComparison[];
+    /**
+     * regressed is true if any function regressed beyond its threshold
+     *
+     * @generated from protobuf field: bool regressed = 2;
+     */
+    regressed: boolean;
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileType
+ * FunctionComparison is the result of comparing a function matched by a rule between a baseline and a profile
+ *
+ * @generated from protobuf message parca.query.v1alpha1.FunctionComparison
  */
-export const ProfileType = new ProfileType$Type();
-// @generated message type with reflection information, may provide speed optimized methods
-class QueryRangeRequest$Type extends MessageType<QueryRangeRequest> {
-    constructor() {
-        super("parca.query.v1alpha1.QueryRangeRequest", [
-            { no: 1, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "start", kind: "message", T: () => Timestamp },
-            { no: 3, name: "end", kind: "message", T: () => Timestamp },
-            { no: 4, name: "limit", kind: "scalar", T: 13 /*ScalarType.UINT32*/ },
-            { no: 5, name: "step", kind: "message", T: () => Duration },
-            { no: 6, name: "sum_by", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
-        ]);
-    }
-    create(value?: PartialMessage<QueryRangeRequest>): QueryRangeRequest {
-        const message = globalThis.Object.create((this.messagePrototype!));
-        message.query = "";
-        message.limit = 0;
-        message.sumBy = [];
-        if (value !== undefined)
+export interface FunctionComparison {
+    /**
+     * function is the name of the function
+     *
+     * @generated from protobuf field: string function = 1;
+     */
+    function: string;
+    /**
+     * baseline is the cumulative share of the total of the baseline spent in the function, between 0 and 1
+     *
+     * @generated from protobuf field: double baseline = 2;
+     */
+    baseline: number;
+    /**
+     * candidate is the cumulative share of the total of the compared profile spent in the function, between 0 and 1
+     *
+     * @generated from protobuf field: double candidate = 3;
+     */
+    candidate: number;
+    /**
+     * increase is the relative change of the share, eg. 0.1 if the share grew by 10%. It is infinite if the function does not appear in the baseline
+     *
+     * @generated from protobuf field: double increase = 4;
+     */
+    increase: number;
+    /**
+     * max_increase is the threshold of the rule matching the function
+     *
+     * @generated from protobuf field: double max_increase = 5;
+     */
+    maxIncrease: number;
+    /**
+     * regressed is true if the increase exceeds the threshold
+     *
+     * @generated from protobuf field: bool regressed = 6;
+     */
+    regressed: boolean;
+}
+/**
+ * QueryUnitsRequest is the request for a top report with values converted to another unit
+ *
+ * @generated from protobuf message parca.query.v1alpha1.QueryUnitsRequest
+ */
+export interface QueryUnitsRequest {
+    /**
+     * query is the query of the report, its report type is overwritten with top
+     *
+     * @generated from protobuf field: parca.query.v1alpha1.QueryRequest query = 1;
+     */
+    query?: QueryRequest;
+    /**
+     * unit is the unit to convert the values to, eg. seconds or MiB, or auto to select the largest unit in which the largest value is still at least 1. The values are not converted if empty
+     *
+     * @generated from protobuf field: string unit = 2;
+     */
+    unit: string;
+}
+/**
+ * QueryUnitsResponse is a top report with values converted to another unit
+ *
+ * @generated from protobuf message parca.query.v1alpha1.QueryUnitsResponse
+ */
+export interface QueryUnitsResponse {
+    /**
+     * source_unit is the unit of the values of the profiles
+     *
+     * @generated from protobuf field: string source_unit = 1;
+     */
+    sourceUnit: string;
+    /**
+     * unit is the unit the values were converted to
+     *
+     * @generated from protobuf field: string unit = 2;
+     */
+    unit: string;
+    /**
+     * total is the converted total of the profiles
+     *
+     * @generated from protobuf field: double total = 3;
+     */
+    total: number;
+    /**
+     * reported is the number of lines reported
+     *
+     * @generated from protobuf field: int32 reported = 4;
+     */
+    reported: number;
+    /**
+     * list is the list of ordered elements of the table
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.ConvertedTopNode list = 5;
+     */
+    list: ConvertedTopNode[];
+}
+/**
+ * ConvertedTopNode is a node entry in a top list with values converted to another unit
+ *
+ * @generated from protobuf message parca.query.v1alpha1.ConvertedTopNode
+ */
+export interface ConvertedTopNode {
+    /**
+     * meta is the metadata about the node
+     *
+     * @generated from protobuf field: parca.query.v1alpha1.TopNodeMeta meta = 1;
+     */
+    meta?: TopNodeMeta;
+    /**
+     * cumulative is the converted cumulative value of the node
+     *
+     * @generated from protobuf field: double cumulative = 2;
+     */
+    cumulative: number;
+    /**
+     * flat is the converted flat value of the node
+     *
+     * @generated from protobuf field: double flat = 3;
+     */
+    flat: number;
+    /**
+     * diff is the converted diff value between two profiles
+     *
+     * @generated from protobuf field: double diff = 4;
+     */
+    diff: number;
+}
+/**
+ * QueryRangeUnitsRequest is the request for a set of series with sample values converted to another unit
+ *
+ * @generated from protobuf message parca.query.v1alpha1.QueryRangeUnitsRequest
+ */
+export interface QueryRangeUnitsRequest {
+    /**
+     * query is the range query
+     *
+     * @generated from protobuf field: parca.query.v1alpha1.QueryRangeRequest query = 1;
+     */
+    query?: QueryRangeRequest;
+    /**
+     * unit is the unit to convert the values to, eg. seconds or MiB, or auto to select the unit per series. The values are not converted if empty
+     *
+     * @generated from protobuf field: string unit = 2;
+     */
+    unit: string;
+}
+/**
+ * QueryRangeUnitsResponse is the set of matching series with converted sample values
+ *
+ * @generated from protobuf message parca.query.v1alpha1.QueryRangeUnitsResponse
+ */
+export interface QueryRangeUnitsResponse {
+    /**
+     * series is the set of series that satisfy the range query
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.ConvertedMetricsSeries series = 1;
+     */
+    series: ConvertedMetricsSeries[];
+}
+/**
+ * ConvertedMetricsSeries is a set of labels and corresponding sample values converted to another unit
+ *
+ * @generated from protobuf message parca.query.v1alpha1.ConvertedMetricsSeries
+ */
+export interface ConvertedMetricsSeries {
+    /**
+     * labelset is the set of key value pairs
+     *
+     * @generated from protobuf field: parca.profilestore.v1alpha1.LabelSet labelset = 1;
+     */
+    labelset?: LabelSet;
+    /**
+     * samples is the set of converted top-level cumulative values of the corresponding profiles
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.ConvertedMetricsSample samples = 2;
+     */
+    samples: ConvertedMetricsSample[];
+    /**
+     * period_type is the value type of profile period
+     *
+     * @generated from protobuf field: parca.query.v1alpha1.ValueType period_type = 3;
+     */
+    periodType?: ValueType;
+    /**
+     * sample_type is the value type of profile sample
+     *
+     * @generated from protobuf field: parca.query.v1alpha1.ValueType sample_type = 4;
+     */
+    sampleType?: ValueType;
+    /**
+     * unit is the unit the sample values were converted to
+     *
+     * @generated from protobuf field: string unit = 5;
+     */
+    unit: string;
+}
+/**
+ * ConvertedMetricsSample is a cumulative value converted to another unit and timestamp of a profile
+ *
+ * @generated from protobuf message parca.query.v1alpha1.ConvertedMetricsSample
+ */
+export interface ConvertedMetricsSample {
+    /**
+     * timestamp is the time the profile was ingested
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp timestamp = 1;
+     */
+    timestamp?: Timestamp;
+    /**
+     * value is the converted cumulative value for the profile
+     *
+     * @generated from protobuf field: double value = 2;
+     */
+    value: number;
+    /**
+     * value_per_second is the converted per second average in the steps duration
+     *
+     * @generated from protobuf field: double value_per_second = 3;
+     */
+    valuePerSecond: number;
+    /**
+     * duration is the normalized aggregated duration the metric samples has been observed over.
+     *
+     * @generated from protobuf field: int64 duration = 4;
+     */
+    duration: bigint;
+}
+/**
+ * ProfileCaptureMetadataRequest is the request for the metadata written along with profiles
+ *
+ * @generated from protobuf message parca.query.v1alpha1.ProfileCaptureMetadataRequest
+ */
+export interface ProfileCaptureMetadataRequest {
+    /**
+     * query is the profile selector of the profiles, only its profile name is matched so the metadata is shared by all sample types of a profile
+     *
+     * @generated from protobuf field: string query = 1;
+     */
+    query: string;
+    /**
+     * start is the start of the time range, 24 hours before end if unset
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp start = 2;
+     */
+    start?: Timestamp;
+    /**
+     * end is the end of the time range, now if unset
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp end = 3;
+     */
+    end?: Timestamp;
+}
+/**
+ * ProfileCaptureMetadataResponse is the metadata of the matching profiles
+ *
+ * @generated from protobuf message parca.query.v1alpha1.ProfileCaptureMetadataResponse
+ */
+export interface ProfileCaptureMetadataResponse {
+    /**
+     * profiles is the metadata of the matching profiles, ordered by time
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.CaptureMetadata profiles = 1;
+     */
+    profiles: CaptureMetadata[];
+}
+/**
+ * CaptureMetadata is the metadata written along with a profile
+ *
+ * @generated from protobuf message parca.query.v1alpha1.CaptureMetadata
+ */
+export interface CaptureMetadata {
+    /**
+     * labels is the label set of the profile
+     *
+     * @generated from protobuf field: parca.profilestore.v1alpha1.LabelSet labels = 1;
+     */
+    labels?: LabelSet;
+    /**
+     * timestamp is the time of the profile
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp timestamp = 2;
+     */
+    timestamp?: Timestamp;
+    /**
+     * metadata is the metadata of the profile, eg. the agent version or whether the profile was truncated
+     *
+     * @generated from protobuf field: map<string, string> metadata = 3;
+     */
+    metadata: {
+        [key: string]: string;
+    };
+}
+/**
+ * DiffSignificanceRequest is the request to test the significance of the per-function changes of a diff
+ *
+ * @generated from protobuf message parca.query.v1alpha1.DiffSignificanceRequest
+ */
+export interface DiffSignificanceRequest {
+    /**
+     * diff is the diff to test, both of its sides must be merge selections
+     *
+     * @generated from protobuf field: parca.query.v1alpha1.DiffProfile diff = 1;
+     */
+    diff?: DiffProfile;
+    /**
+     * windows is the number of windows each side is split into and merged separately, 10 if zero and at most 100
+     *
+     * @generated from protobuf field: uint32 windows = 2;
+     */
+    windows: number;
+    /**
+     * confidence is the confidence level of the intervals of the deltas, 0.95 if zero
+     *
+     * @generated from protobuf field: double confidence = 3;
+     */
+    confidence: number;
+    /**
+     * limit is the maximum number of functions returned, 100 if zero
+     *
+     * @generated from protobuf field: uint32 limit = 4;
+     */
+    limit: number;
+    /**
+     * significant_only limits the functions returned to the ones whose change is significant
+     *
+     * @generated from protobuf field: bool significant_only = 5;
+     */
+    significantOnly: boolean;
+}
+/**
+ * DiffSignificanceResponse is the result of testing the significance of the per-function changes of a diff
+ *
+ * @generated from protobuf message parca.query.v1alpha1.DiffSignificanceResponse
+ */
+export interface DiffSignificanceResponse {
+    /**
+     * functions are the tested functions, ordered by decreasing absolute delta
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.FunctionSignificance functions = 1;
+     */
+    functions: FunctionSignificance[];
+}
+/**
+ * FunctionSignificance is the change of the cumulative share a function has of the total between the two sides of a diff
+ *
+ * @generated from protobuf message parca.query.v1alpha1.FunctionSignificance
+ */
+export interface FunctionSignificance {
+    /**
+     * function is the name of the function
+     *
+     * @generated from protobuf field: string function = 1;
+     */
+    function: string;
+    /**
+     * share_a is the share of the function averaged over the windows of the base side, between 0 and 1
+     *
+     * @generated from protobuf field: double share_a = 2;
+     */
+    shareA: number;
+    /**
+     * share_b is the share of the function averaged over the windows of the compared side, between 0 and 1
+     *
+     * @generated from protobuf field: double share_b = 3;
+     */
+    shareB: number;
+    /**
+     * delta is the difference of the shares of the compared and the base side
+     *
+     * @generated from protobuf field: double delta = 4;
+     */
+    delta: number;
+    /**
+     * low is the lower bound of the confidence interval of the delta
+     *
+     * @generated from protobuf field: double low = 5;
+     */
+    low: number;
+    /**
+     * high is the upper bound of the confidence interval of the delta
+     *
+     * @generated from protobuf field: double high = 6;
+     */
+    high: number;
+    /**
+     * significant is true if the confidence interval of the delta doesn't contain zero
+     *
+     * @generated from protobuf field: bool significant = 7;
+     */
+    significant: boolean;
+}
+/**
+ * TraceExemplarsRequest is the request for the traces samples were recorded in
+ *
+ * @generated from protobuf message parca.query.v1alpha1.TraceExemplarsRequest
+ */
+export interface TraceExemplarsRequest {
+    /**
+     * query is the profile selector of the samples
+     *
+     * @generated from protobuf field: string query = 1;
+     */
+    query: string;
+    /**
+     * start is the start of the time range, 24 hours before end if unset
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp start = 2;
+     */
+    start?: Timestamp;
+    /**
+     * end is the end of the time range, now if unset
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp end = 3;
+     */
+    end?: Timestamp;
+    /**
+     * label is the sample label the trace IDs are read from, trace_id if empty
+     *
+     * @generated from protobuf field: string label = 4;
+     */
+    label: string;
+    /**
+     * limit is the maximum number of traces returned, 100 if zero
+     *
+     * @generated from protobuf field: uint32 limit = 5;
+     */
+    limit: number;
+}
+/**
+ * TraceExemplarsResponse is the list of traces samples were recorded in
+ *
+ * @generated from protobuf message parca.query.v1alpha1.TraceExemplarsResponse
+ */
+export interface TraceExemplarsResponse {
+    /**
+     * traces are the traces, ordered by decreasing value
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.TraceExemplar traces = 1;
+     */
+    traces: TraceExemplar[];
+}
+/**
+ * TraceExemplar is a trace samples were recorded in
+ *
+ * @generated from protobuf message parca.query.v1alpha1.TraceExemplar
+ */
+export interface TraceExemplar {
+    /**
+     * trace_id is the ID of the trace
+     *
+     * @generated from protobuf field: string trace_id = 1;
+     */
+    traceId: string;
+    /**
+     * value is the total value of the samples recorded in the trace
+     *
+     * @generated from protobuf field: int64 value = 2;
+     */
+    value: bigint;
+    /**
+     * timestamp is the time of the last profile with samples of the trace
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp timestamp = 3;
+     */
+    timestamp?: Timestamp;
+}
+/**
+ * ProfileTypeCountsRequest is the request for the profile types stored for a set of series
+ *
+ * @generated from protobuf message parca.query.v1alpha1.ProfileTypeCountsRequest
+ */
+export interface ProfileTypeCountsRequest {
+    /**
+     * selector is the set of label matchers of the series, eg. {job="api"}, all series if empty
+     *
+     * @generated from protobuf field: string selector = 1;
+     */
+    selector: string;
+    /**
+     * start is the start of the time range, 24 hours before end if unset
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp start = 2;
+     */
+    start?: Timestamp;
+    /**
+     * end is the end of the time range, now if unset
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp end = 3;
+     */
+    end?: Timestamp;
+}
+/**
+ * ProfileTypeCountsResponse is the list of profile types stored for a set of series
+ *
+ * @generated from protobuf message parca.query.v1alpha1.ProfileTypeCountsResponse
+ */
+export interface ProfileTypeCountsResponse {
+    /**
+     * types are the stored profile types, ordered by profile type
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.ProfileTypeCount types = 1;
+     */
+    types: ProfileTypeCount[];
+}
+/**
+ * ProfileTypeCount is a profile type stored for a set of series with the number of samples stored
+ *
+ * @generated from protobuf message parca.query.v1alpha1.ProfileTypeCount
+ */
+export interface ProfileTypeCount {
+    /**
+     * profile_type is the profile type as used in queries, eg. parca_agent:samples:count:cpu:nanoseconds:delta
+     *
+     * @generated from protobuf field: string profile_type = 1;
+     */
+    profileType: string;
+    /**
+     * type is the profile type
+     *
+     * @generated from protobuf field: parca.query.v1alpha1.ProfileType type = 2;
+     */
+    type?: ProfileType;
+    /**
+     * samples is the number of samples stored
+     *
+     * @generated from protobuf field: int64 samples = 3;
+     */
+    samples: bigint;
+}
+/**
+ * BinariesRequest is the request to list the binaries observed in stored profiles
+ *
+ * @generated from protobuf message parca.query.v1alpha1.BinariesRequest
+ */
+export interface BinariesRequest {
+    /**
+     * query is the profile selector of the profiles the binaries are read from, all profiles if empty
+     *
+     * @generated from protobuf field: string query = 1;
+     */
+    query: string;
+    /**
+     * start is the start of the time range, 24 hours before end if unset
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp start = 2;
+     */
+    start?: Timestamp;
+    /**
+     * end is the end of the time range, now if unset
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp end = 3;
+     */
+    end?: Timestamp;
+    /**
+     * service_label is the label whose values name the services a binary ran in, job if empty
+     *
+     * @generated from protobuf field: string service_label = 4;
+     */
+    serviceLabel: string;
+}
+/**
+ * BinariesResponse is the list of binaries observed in stored profiles
+ *
+ * @generated from protobuf message parca.query.v1alpha1.BinariesResponse
+ */
+export interface BinariesResponse {
+    /**
+     * binaries are the observed binaries
+     *
+     * @generated from protobuf field: repeated parca.query.v1alpha1.Binary binaries = 1;
+     */
+    binaries: Binary[];
+}
+/**
+ * Binary is a binary observed in the mappings of stored profiles
+ *
+ * @generated from protobuf message parca.query.v1alpha1.Binary
+ */
+export interface Binary {
+    /**
+     * build_id is the build ID of the binary
+     *
+     * @generated from protobuf field: string build_id = 1;
+     */
+    buildId: string;
+    /**
+     * file is the path of the binary
+     *
+     * @generated from protobuf field: string file = 2;
+     */
+    file: string;
+    /**
+     * first_seen is the time of the first profile the binary was observed in
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp first_seen = 3;
+     */
+    firstSeen?: Timestamp;
+    /**
+     * last_seen is the time of the last profile the binary was observed in
+     *
+     * @generated from protobuf field: google.protobuf.Timestamp last_seen = 4;
+     */
+    lastSeen?: Timestamp;
+    /**
+     * services are the values of the service label of the profiles the binary was observed in
+     *
+     * @generated from protobuf field: repeated string services = 5;
+     */
+    services: string[];
+    /**
+     * debuginfo_status is the status of the debuginfo of the binary
+     *
+     * @generated from protobuf field: parca.query.v1alpha1.Binary.DebuginfoStatus debuginfo_status = 6;
+     */
+    debuginfoStatus: Binary_DebuginfoStatus;
+}
+/**
+ * DebuginfoStatus is the status of the debuginfo of a binary
+ *
+ * @generated from protobuf enum parca.query.v1alpha1.Binary.DebuginfoStatus
+ */
+export enum Binary_DebuginfoStatus {
+    /**
+     * DEBUGINFO_STATUS_UNKNOWN_UNSPECIFIED means the status could not be determined, eg. because the binary has no build ID
+     *
+     * @generated from protobuf enum value: DEBUGINFO_STATUS_UNKNOWN_UNSPECIFIED = 0;
+     */
+    UNKNOWN_UNSPECIFIED = 0,
+    /**
+     * DEBUGINFO_STATUS_MISSING means no debuginfo is known for the binary
+     *
+     * @generated from protobuf enum value: DEBUGINFO_STATUS_MISSING = 1;
+     */
+    MISSING = 1,
+    /**
+     * DEBUGINFO_STATUS_UPLOADING means the debuginfo of the binary is being uploaded
+     *
+     * @generated from protobuf enum value: DEBUGINFO_STATUS_UPLOADING = 2;
+     */
+    UPLOADING = 2,
+    /**
+     * DEBUGINFO_STATUS_AVAILABLE means the debuginfo of the binary can be used for symbolization
+     *
+     * @generated from protobuf enum value: DEBUGINFO_STATUS_AVAILABLE = 3;
+     */
+    AVAILABLE = 3,
+    /**
+     * DEBUGINFO_STATUS_NO_SYMBOLS means the debuginfo of the binary has no symbols
+     *
+     * @generated from protobuf enum value: DEBUGINFO_STATUS_NO_SYMBOLS = 4;
+     */
+    NO_SYMBOLS = 4
+}
+// @generated message type with reflection information, may provide speed optimized methods
+class ProfileTypesRequest$Type extends MessageType<ProfileTypesRequest> {
+    constructor() {
+        super("parca.query.v1alpha1.ProfileTypesRequest", []);
+    }
+    create(value?: PartialMessage<ProfileTypesRequest>): ProfileTypesRequest {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        if (value !== undefined)
+            reflectionMergePartial<ProfileTypesRequest>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileTypesRequest): ProfileTypesRequest {
+        return target ?? this.create();
+    }
+    internalBinaryWrite(message: ProfileTypesRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileTypesRequest
+ */
+export const ProfileTypesRequest = new ProfileTypesRequest$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class ProfileTypesResponse$Type extends MessageType<ProfileTypesResponse> {
+    constructor() {
+        super("parca.query.v1alpha1.ProfileTypesResponse", [
+            { no: 1, name: "types", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => ProfileType }
+        ]);
+    }
+    create(value?: PartialMessage<ProfileTypesResponse>): ProfileTypesResponse {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.types = [];
+        if (value !== undefined)
+            reflectionMergePartial<ProfileTypesResponse>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileTypesResponse): ProfileTypesResponse {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* repeated parca.query.v1alpha1.ProfileType types */ 1:
+                    message.types.push(ProfileType.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: ProfileTypesResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.ProfileType types = 1; */
+        for (let i = 0; i < message.types.length; i++)
+            ProfileType.internalBinaryWrite(message.types[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileTypesResponse
+ */
+export const ProfileTypesResponse = new ProfileTypesResponse$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class ProfileType$Type extends MessageType<ProfileType> {
+    constructor() {
+        super("parca.query.v1alpha1.ProfileType", [
+            { no: 1, name: "name", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "sample_type", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 3, name: "sample_unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 4, name: "period_type", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 5, name: "period_unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 6, name: "delta", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
+        ]);
+    }
+    create(value?: PartialMessage<ProfileType>): ProfileType {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.name = "";
+        message.sampleType = "";
+        message.sampleUnit = "";
+        message.periodType = "";
+        message.periodUnit = "";
+        message.delta = false;
+        if (value !== undefined)
+            reflectionMergePartial<ProfileType>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileType): ProfileType {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string name */ 1:
+                    message.name = reader.string();
+                    break;
+                case /* string sample_type */ 2:
+                    message.sampleType = reader.string();
+                    break;
+                case /* string sample_unit */ 3:
+                    message.sampleUnit = reader.string();
+                    break;
+                case /* string period_type */ 4:
+                    message.periodType = reader.string();
+                    break;
+                case /* string period_unit */ 5:
+                    message.periodUnit = reader.string();
+                    break;
+                case /* bool delta */ 6:
+                    message.delta = reader.bool();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: ProfileType, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string name = 1; */
+        if (message.name !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.name);
+        /* string sample_type = 2; */
+        if (message.sampleType !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.sampleType);
+        /* string sample_unit = 3; */
+        if (message.sampleUnit !== "")
+            writer.tag(3, WireType.LengthDelimited).string(message.sampleUnit);
+        /* string period_type = 4; */
+        if (message.periodType !== "")
+            writer.tag(4, WireType.LengthDelimited).string(message.periodType);
+        /* string period_unit = 5; */
+        if (message.periodUnit !== "")
+            writer.tag(5, WireType.LengthDelimited).string(message.periodUnit);
+        /* bool delta = 6; */
+        if (message.delta !== false)
+            writer.tag(6, WireType.Varint).bool(message.delta);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileType
+ */
+export const ProfileType = new ProfileType$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class QueryRangeRequest$Type extends MessageType<QueryRangeRequest> {
+    constructor() {
+        super("parca.query.v1alpha1.QueryRangeRequest", [
+            { no: 1, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "start", kind: "message", T: () => Timestamp },
+            { no: 3, name: "end", kind: "message", T: () => Timestamp },
+            { no: 4, name: "limit", kind: "scalar", T: 13 /*ScalarType.UINT32*/ },
+            { no: 5, name: "step", kind: "message", T: () => Duration },
+            { no: 6, name: "sum_by", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        ]);
+    }
+    create(value?: PartialMessage<QueryRangeRequest>): QueryRangeRequest {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.query = "";
+        message.limit = 0;
+        message.sumBy = [];
+        if (value !== undefined)
             reflectionMergePartial<QueryRangeRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRangeRequest): QueryRangeRequest {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRangeRequest): QueryRangeRequest {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string query */ 1:
+                    message.query = reader.string();
+                    break;
+                case /* google.protobuf.Timestamp start */ 2:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                    break;
+                case /* google.protobuf.Timestamp end */ 3:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                    break;
+                case /* uint32 limit */ 4:
+                    message.limit = reader.uint32();
+                    break;
+                case /* google.protobuf.Duration step */ 5:
+                    message.step = Duration.internalBinaryRead(reader, reader.uint32(), options, message.step);
+                    break;
+                case /* repeated string sum_by */ 6:
+                    message.sumBy.push(reader.string());
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: QueryRangeRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string query = 1; */
+        if (message.query !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.query);
+        /* google.protobuf.Timestamp start = 2; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 3; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* uint32 limit = 4; */
+        if (message.limit !== 0)
+            writer.tag(4, WireType.Varint).uint32(message.limit);
+        /* google.protobuf.Duration step = 5; */
+        if (message.step)
+            Duration.internalBinaryWrite(message.step, writer.tag(5, WireType.LengthDelimited).fork(), options).join();
+        /* repeated string sum_by = 6; */
+        for (let i = 0; i < message.sumBy.length; i++)
+            writer.tag(6, WireType.LengthDelimited).string(message.sumBy[i]);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRangeRequest
+ */
+export const QueryRangeRequest = new QueryRangeRequest$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class QueryRangeResponse$Type extends MessageType<QueryRangeResponse> {
+    constructor() {
+        super("parca.query.v1alpha1.QueryRangeResponse", [
+            { no: 1, name: "series", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => MetricsSeries }
+        ]);
+    }
+    create(value?: PartialMessage<QueryRangeResponse>): QueryRangeResponse {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.series = [];
+        if (value !== undefined)
+            reflectionMergePartial<QueryRangeResponse>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRangeResponse): QueryRangeResponse {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* repeated parca.query.v1alpha1.MetricsSeries series */ 1:
+                    message.series.push(MetricsSeries.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: QueryRangeResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.MetricsSeries series = 1; */
+        for (let i = 0; i < message.series.length; i++)
+            MetricsSeries.internalBinaryWrite(message.series[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRangeResponse
+ */
+export const QueryRangeResponse = new QueryRangeResponse$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class QueryRangeWindowsRequest$Type extends MessageType<QueryRangeWindowsRequest> {
+    constructor() {
+        super("parca.query.v1alpha1.QueryRangeWindowsRequest", [
+            { no: 1, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "windows", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => TimeWindow },
+            { no: 3, name: "limit", kind: "scalar", T: 13 /*ScalarType.UINT32*/ },
+            { no: 4, name: "step", kind: "message", T: () => Duration },
+            { no: 5, name: "sum_by", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        ]);
+    }
+    create(value?: PartialMessage<QueryRangeWindowsRequest>): QueryRangeWindowsRequest {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.query = "";
+        message.windows = [];
+        message.limit = 0;
+        message.sumBy = [];
+        if (value !== undefined)
+            reflectionMergePartial<QueryRangeWindowsRequest>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRangeWindowsRequest): QueryRangeWindowsRequest {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string query */ 1:
+                    message.query = reader.string();
+                    break;
+                case /* repeated parca.query.v1alpha1.TimeWindow windows */ 2:
+                    message.windows.push(TimeWindow.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                case /* uint32 limit */ 3:
+                    message.limit = reader.uint32();
+                    break;
+                case /* google.protobuf.Duration step */ 4:
+                    message.step = Duration.internalBinaryRead(reader, reader.uint32(), options, message.step);
+                    break;
+                case /* repeated string sum_by */ 5:
+                    message.sumBy.push(reader.string());
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: QueryRangeWindowsRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string query = 1; */
+        if (message.query !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.query);
+        /* repeated parca.query.v1alpha1.TimeWindow windows = 2; */
+        for (let i = 0; i < message.windows.length; i++)
+            TimeWindow.internalBinaryWrite(message.windows[i], writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* uint32 limit = 3; */
+        if (message.limit !== 0)
+            writer.tag(3, WireType.Varint).uint32(message.limit);
+        /* google.protobuf.Duration step = 4; */
+        if (message.step)
+            Duration.internalBinaryWrite(message.step, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        /* repeated string sum_by = 5; */
+        for (let i = 0; i < message.sumBy.length; i++)
+            writer.tag(5, WireType.LengthDelimited).string(message.sumBy[i]);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRangeWindowsRequest
+ */
+export const QueryRangeWindowsRequest = new QueryRangeWindowsRequest$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class TimeWindow$Type extends MessageType<TimeWindow> {
+    constructor() {
+        super("parca.query.v1alpha1.TimeWindow", [
+            { no: 1, name: "start", kind: "message", T: () => Timestamp },
+            { no: 2, name: "end", kind: "message", T: () => Timestamp }
+        ]);
+    }
+    create(value?: PartialMessage<TimeWindow>): TimeWindow {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        if (value !== undefined)
+            reflectionMergePartial<TimeWindow>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TimeWindow): TimeWindow {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* google.protobuf.Timestamp start */ 1:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                    break;
+                case /* google.protobuf.Timestamp end */ 2:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: TimeWindow, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* google.protobuf.Timestamp start = 1; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 2; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.TimeWindow
+ */
+export const TimeWindow = new TimeWindow$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class QueryRangeWindowsResponse$Type extends MessageType<QueryRangeWindowsResponse> {
+    constructor() {
+        super("parca.query.v1alpha1.QueryRangeWindowsResponse", [
+            { no: 1, name: "windows", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => QueryRangeResponse }
+        ]);
+    }
+    create(value?: PartialMessage<QueryRangeWindowsResponse>): QueryRangeWindowsResponse {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.windows = [];
+        if (value !== undefined)
+            reflectionMergePartial<QueryRangeWindowsResponse>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRangeWindowsResponse): QueryRangeWindowsResponse {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* repeated parca.query.v1alpha1.QueryRangeResponse windows */ 1:
+                    message.windows.push(QueryRangeResponse.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: QueryRangeWindowsResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.QueryRangeResponse windows = 1; */
+        for (let i = 0; i < message.windows.length; i++)
+            QueryRangeResponse.internalBinaryWrite(message.windows[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRangeWindowsResponse
+ */
+export const QueryRangeWindowsResponse = new QueryRangeWindowsResponse$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class MetricsSeries$Type extends MessageType<MetricsSeries> {
+    constructor() {
+        super("parca.query.v1alpha1.MetricsSeries", [
+            { no: 1, name: "labelset", kind: "message", T: () => LabelSet },
+            { no: 2, name: "samples", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => MetricsSample },
+            { no: 3, name: "period_type", kind: "message", T: () => ValueType },
+            { no: 4, name: "sample_type", kind: "message", T: () => ValueType }
+        ]);
+    }
+    create(value?: PartialMessage<MetricsSeries>): MetricsSeries {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.samples = [];
+        if (value !== undefined)
+            reflectionMergePartial<MetricsSeries>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: MetricsSeries): MetricsSeries {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.profilestore.v1alpha1.LabelSet labelset */ 1:
+                    message.labelset = LabelSet.internalBinaryRead(reader, reader.uint32(), options, message.labelset);
+                    break;
+                case /* repeated parca.query.v1alpha1.MetricsSample samples */ 2:
+                    message.samples.push(MetricsSample.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                case /* parca.query.v1alpha1.ValueType period_type */ 3:
+                    message.periodType = ValueType.internalBinaryRead(reader, reader.uint32(), options, message.periodType);
+                    break;
+                case /* parca.query.v1alpha1.ValueType sample_type */ 4:
+                    message.sampleType = ValueType.internalBinaryRead(reader, reader.uint32(), options, message.sampleType);
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: MetricsSeries, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.profilestore.v1alpha1.LabelSet labelset = 1; */
+        if (message.labelset)
+            LabelSet.internalBinaryWrite(message.labelset, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* repeated parca.query.v1alpha1.MetricsSample samples = 2; */
+        for (let i = 0; i < message.samples.length; i++)
+            MetricsSample.internalBinaryWrite(message.samples[i], writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.ValueType period_type = 3; */
+        if (message.periodType)
+            ValueType.internalBinaryWrite(message.periodType, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.ValueType sample_type = 4; */
+        if (message.sampleType)
+            ValueType.internalBinaryWrite(message.sampleType, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.MetricsSeries
+ */
+export const MetricsSeries = new MetricsSeries$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class MetricsSample$Type extends MessageType<MetricsSample> {
+    constructor() {
+        super("parca.query.v1alpha1.MetricsSample", [
+            { no: 1, name: "timestamp", kind: "message", T: () => Timestamp },
+            { no: 2, name: "value", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 3, name: "value_per_second", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 4, name: "duration", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        ]);
+    }
+    create(value?: PartialMessage<MetricsSample>): MetricsSample {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.value = 0n;
+        message.valuePerSecond = 0;
+        message.duration = 0n;
+        if (value !== undefined)
+            reflectionMergePartial<MetricsSample>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: MetricsSample): MetricsSample {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* google.protobuf.Timestamp timestamp */ 1:
+                    message.timestamp = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.timestamp);
+                    break;
+                case /* int64 value */ 2:
+                    message.value = reader.int64().toBigInt();
+                    break;
+                case /* double value_per_second */ 3:
+                    message.valuePerSecond = reader.double();
+                    break;
+                case /* int64 duration */ 4:
+                    message.duration = reader.int64().toBigInt();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: MetricsSample, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* google.protobuf.Timestamp timestamp = 1; */
+        if (message.timestamp)
+            Timestamp.internalBinaryWrite(message.timestamp, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* int64 value = 2; */
+        if (message.value !== 0n)
+            writer.tag(2, WireType.Varint).int64(message.value);
+        /* double value_per_second = 3; */
+        if (message.valuePerSecond !== 0)
+            writer.tag(3, WireType.Bit64).double(message.valuePerSecond);
+        /* int64 duration = 4; */
+        if (message.duration !== 0n)
+            writer.tag(4, WireType.Varint).int64(message.duration);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.MetricsSample
+ */
+export const MetricsSample = new MetricsSample$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class MergeProfile$Type extends MessageType<MergeProfile> {
+    constructor() {
+        super("parca.query.v1alpha1.MergeProfile", [
+            { no: 1, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "start", kind: "message", T: () => Timestamp },
+            { no: 3, name: "end", kind: "message", T: () => Timestamp }
+        ]);
+    }
+    create(value?: PartialMessage<MergeProfile>): MergeProfile {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.query = "";
+        if (value !== undefined)
+            reflectionMergePartial<MergeProfile>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: MergeProfile): MergeProfile {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string query */ 1:
+                    message.query = reader.string();
+                    break;
+                case /* google.protobuf.Timestamp start */ 2:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                    break;
+                case /* google.protobuf.Timestamp end */ 3:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: MergeProfile, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string query = 1; */
+        if (message.query !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.query);
+        /* google.protobuf.Timestamp start = 2; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 3; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.MergeProfile
+ */
+export const MergeProfile = new MergeProfile$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class SingleProfile$Type extends MessageType<SingleProfile> {
+    constructor() {
+        super("parca.query.v1alpha1.SingleProfile", [
+            { no: 1, name: "time", kind: "message", T: () => Timestamp },
+            { no: 2, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        ]);
+    }
+    create(value?: PartialMessage<SingleProfile>): SingleProfile {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.query = "";
+        if (value !== undefined)
+            reflectionMergePartial<SingleProfile>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SingleProfile): SingleProfile {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* google.protobuf.Timestamp time */ 1:
+                    message.time = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.time);
+                    break;
+                case /* string query */ 2:
+                    message.query = reader.string();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: SingleProfile, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* google.protobuf.Timestamp time = 1; */
+        if (message.time)
+            Timestamp.internalBinaryWrite(message.time, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* string query = 2; */
+        if (message.query !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.query);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.SingleProfile
+ */
+export const SingleProfile = new SingleProfile$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class DiffProfile$Type extends MessageType<DiffProfile> {
+    constructor() {
+        super("parca.query.v1alpha1.DiffProfile", [
+            { no: 1, name: "a", kind: "message", T: () => ProfileDiffSelection },
+            { no: 2, name: "b", kind: "message", T: () => ProfileDiffSelection },
+            { no: 3, name: "absolute", kind: "scalar", opt: true, T: 8 /*ScalarType.BOOL*/ }
+        ]);
+    }
+    create(value?: PartialMessage<DiffProfile>): DiffProfile {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        if (value !== undefined)
+            reflectionMergePartial<DiffProfile>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: DiffProfile): DiffProfile {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.query.v1alpha1.ProfileDiffSelection a */ 1:
+                    message.a = ProfileDiffSelection.internalBinaryRead(reader, reader.uint32(), options, message.a);
+                    break;
+                case /* parca.query.v1alpha1.ProfileDiffSelection b */ 2:
+                    message.b = ProfileDiffSelection.internalBinaryRead(reader, reader.uint32(), options, message.b);
+                    break;
+                case /* optional bool absolute */ 3:
+                    message.absolute = reader.bool();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: DiffProfile, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.ProfileDiffSelection a = 1; */
+        if (message.a)
+            ProfileDiffSelection.internalBinaryWrite(message.a, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.ProfileDiffSelection b = 2; */
+        if (message.b)
+            ProfileDiffSelection.internalBinaryWrite(message.b, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* optional bool absolute = 3; */
+        if (message.absolute !== undefined)
+            writer.tag(3, WireType.Varint).bool(message.absolute);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.DiffProfile
+ */
+export const DiffProfile = new DiffProfile$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class ProfileDiffSelection$Type extends MessageType<ProfileDiffSelection> {
+    constructor() {
+        super("parca.query.v1alpha1.ProfileDiffSelection", [
+            { no: 1, name: "mode", kind: "enum", T: () => ["parca.query.v1alpha1.ProfileDiffSelection.Mode", ProfileDiffSelection_Mode, "MODE_"] },
+            { no: 2, name: "merge", kind: "message", oneof: "options", T: () => MergeProfile },
+            { no: 3, name: "single", kind: "message", oneof: "options", T: () => SingleProfile }
+        ]);
+    }
+    create(value?: PartialMessage<ProfileDiffSelection>): ProfileDiffSelection {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.mode = 0;
+        message.options = { oneofKind: undefined };
+        if (value !== undefined)
+            reflectionMergePartial<ProfileDiffSelection>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileDiffSelection): ProfileDiffSelection {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.query.v1alpha1.ProfileDiffSelection.Mode mode */ 1:
+                    message.mode = reader.int32();
+                    break;
+                case /* parca.query.v1alpha1.MergeProfile merge */ 2:
+                    message.options = {
+                        oneofKind: "merge",
+                        merge: MergeProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).merge)
+                    };
+                    break;
+                case /* parca.query.v1alpha1.SingleProfile single */ 3:
+                    message.options = {
+                        oneofKind: "single",
+                        single: SingleProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).single)
+                    };
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: ProfileDiffSelection, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.ProfileDiffSelection.Mode mode = 1; */
+        if (message.mode !== 0)
+            writer.tag(1, WireType.Varint).int32(message.mode);
+        /* parca.query.v1alpha1.MergeProfile merge = 2; */
+        if (message.options.oneofKind === "merge")
+            MergeProfile.internalBinaryWrite(message.options.merge, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.SingleProfile single = 3; */
+        if (message.options.oneofKind === "single")
+            SingleProfile.internalBinaryWrite(message.options.single, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileDiffSelection
+ */
+export const ProfileDiffSelection = new ProfileDiffSelection$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class QueryRequest$Type extends MessageType<QueryRequest> {
+    constructor() {
+        super("parca.query.v1alpha1.QueryRequest", [
+            { no: 1, name: "mode", kind: "enum", T: () => ["parca.query.v1alpha1.QueryRequest.Mode", QueryRequest_Mode, "MODE_"] },
+            { no: 2, name: "diff", kind: "message", oneof: "options", T: () => DiffProfile },
+            { no: 3, name: "merge", kind: "message", oneof: "options", T: () => MergeProfile },
+            { no: 4, name: "single", kind: "message", oneof: "options", T: () => SingleProfile },
+            { no: 5, name: "report_type", kind: "enum", T: () => ["parca.query.v1alpha1.QueryRequest.ReportType", QueryRequest_ReportType, "REPORT_TYPE_"] },
+            { no: 6, name: "filter_query", kind: "scalar", opt: true, T: 9 /*ScalarType.STRING*/ },
+            { no: 7, name: "node_trim_threshold", kind: "scalar", opt: true, T: 2 /*ScalarType.FLOAT*/ },
+            { no: 8, name: "group_by", kind: "message", T: () => GroupBy },
+            { no: 9, name: "source_reference", kind: "message", T: () => SourceReference },
+            { no: 10, name: "runtime_filter", kind: "message", T: () => RuntimeFilter },
+            { no: 11, name: "invert_call_stack", kind: "scalar", opt: true, T: 8 /*ScalarType.BOOL*/ },
+            { no: 12, name: "filter", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Filter }
+        ]);
+    }
+    create(value?: PartialMessage<QueryRequest>): QueryRequest {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.mode = 0;
+        message.options = { oneofKind: undefined };
+        message.reportType = 0;
+        message.filter = [];
+        if (value !== undefined)
+            reflectionMergePartial<QueryRequest>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRequest): QueryRequest {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.query.v1alpha1.QueryRequest.Mode mode */ 1:
+                    message.mode = reader.int32();
+                    break;
+                case /* parca.query.v1alpha1.DiffProfile diff */ 2:
+                    message.options = {
+                        oneofKind: "diff",
+                        diff: DiffProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).diff)
+                    };
+                    break;
+                case /* parca.query.v1alpha1.MergeProfile merge */ 3:
+                    message.options = {
+                        oneofKind: "merge",
+                        merge: MergeProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).merge)
+                    };
+                    break;
+                case /* parca.query.v1alpha1.SingleProfile single */ 4:
+                    message.options = {
+                        oneofKind: "single",
+                        single: SingleProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).single)
+                    };
+                    break;
+                case /* parca.query.v1alpha1.QueryRequest.ReportType report_type */ 5:
+                    message.reportType = reader.int32();
+                    break;
+                case /* optional string filter_query = 6 [deprecated = true];*/ 6:
+                    message.filterQuery = reader.string();
+                    break;
+                case /* optional float node_trim_threshold */ 7:
+                    message.nodeTrimThreshold = reader.float();
+                    break;
+                case /* optional parca.query.v1alpha1.GroupBy group_by */ 8:
+                    message.groupBy = GroupBy.internalBinaryRead(reader, reader.uint32(), options, message.groupBy);
+                    break;
+                case /* optional parca.query.v1alpha1.SourceReference source_reference */ 9:
+                    message.sourceReference = SourceReference.internalBinaryRead(reader, reader.uint32(), options, message.sourceReference);
+                    break;
+                case /* optional parca.query.v1alpha1.RuntimeFilter runtime_filter = 10 [deprecated = true];*/ 10:
+                    message.runtimeFilter = RuntimeFilter.internalBinaryRead(reader, reader.uint32(), options, message.runtimeFilter);
+                    break;
+                case /* optional bool invert_call_stack */ 11:
+                    message.invertCallStack = reader.bool();
+                    break;
+                case /* repeated parca.query.v1alpha1.Filter filter */ 12:
+                    message.filter.push(Filter.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: QueryRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.QueryRequest.Mode mode = 1; */
+        if (message.mode !== 0)
+            writer.tag(1, WireType.Varint).int32(message.mode);
+        /* parca.query.v1alpha1.DiffProfile diff = 2; */
+        if (message.options.oneofKind === "diff")
+            DiffProfile.internalBinaryWrite(message.options.diff, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.MergeProfile merge = 3; */
+        if (message.options.oneofKind === "merge")
+            MergeProfile.internalBinaryWrite(message.options.merge, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.SingleProfile single = 4; */
+        if (message.options.oneofKind === "single")
+            SingleProfile.internalBinaryWrite(message.options.single, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.QueryRequest.ReportType report_type = 5; */
+        if (message.reportType !== 0)
+            writer.tag(5, WireType.Varint).int32(message.reportType);
+        /* optional string filter_query = 6 [deprecated = true]; */
+        if (message.filterQuery !== undefined)
+            writer.tag(6, WireType.LengthDelimited).string(message.filterQuery);
+        /* optional float node_trim_threshold = 7; */
+        if (message.nodeTrimThreshold !== undefined)
+            writer.tag(7, WireType.Bit32).float(message.nodeTrimThreshold);
+        /* optional parca.query.v1alpha1.GroupBy group_by = 8; */
+        if (message.groupBy)
+            GroupBy.internalBinaryWrite(message.groupBy, writer.tag(8, WireType.LengthDelimited).fork(), options).join();
+        /* optional parca.query.v1alpha1.SourceReference source_reference = 9; */
+        if (message.sourceReference)
+            SourceReference.internalBinaryWrite(message.sourceReference, writer.tag(9, WireType.LengthDelimited).fork(), options).join();
+        /* optional parca.query.v1alpha1.RuntimeFilter runtime_filter = 10 [deprecated = true]; */
+        if (message.runtimeFilter)
+            RuntimeFilter.internalBinaryWrite(message.runtimeFilter, writer.tag(10, WireType.LengthDelimited).fork(), options).join();
+        /* optional bool invert_call_stack = 11; */
+        if (message.invertCallStack !== undefined)
+            writer.tag(11, WireType.Varint).bool(message.invertCallStack);
+        /* repeated parca.query.v1alpha1.Filter filter = 12; */
+        for (let i = 0; i < message.filter.length; i++)
+            Filter.internalBinaryWrite(message.filter[i], writer.tag(12, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRequest
+ */
+export const QueryRequest = new QueryRequest$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class Filter$Type extends MessageType<Filter> {
+    constructor() {
+        super("parca.query.v1alpha1.Filter", [
+            { no: 1, name: "stack_filter", kind: "message", oneof: "filter", T: () => StackFilter },
+            { no: 2, name: "frame_filter", kind: "message", oneof: "filter", T: () => FrameFilter }
+        ]);
+    }
+    create(value?: PartialMessage<Filter>): Filter {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.filter = { oneofKind: undefined };
+        if (value !== undefined)
+            reflectionMergePartial<Filter>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Filter): Filter {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.query.v1alpha1.StackFilter stack_filter */ 1:
+                    message.filter = {
+                        oneofKind: "stackFilter",
+                        stackFilter: StackFilter.internalBinaryRead(reader, reader.uint32(), options, (message.filter as any).stackFilter)
+                    };
+                    break;
+                case /* parca.query.v1alpha1.FrameFilter frame_filter */ 2:
+                    message.filter = {
+                        oneofKind: "frameFilter",
+                        frameFilter: FrameFilter.internalBinaryRead(reader, reader.uint32(), options, (message.filter as any).frameFilter)
+                    };
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: Filter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.StackFilter stack_filter = 1; */
+        if (message.filter.oneofKind === "stackFilter")
+            StackFilter.internalBinaryWrite(message.filter.stackFilter, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.FrameFilter frame_filter = 2; */
+        if (message.filter.oneofKind === "frameFilter")
+            FrameFilter.internalBinaryWrite(message.filter.frameFilter, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.Filter
+ */
+export const Filter = new Filter$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class StackFilter$Type extends MessageType<StackFilter> {
+    constructor() {
+        super("parca.query.v1alpha1.StackFilter", [
+            { no: 1, name: "function_name_stack_filter", kind: "message", oneof: "filter", T: () => FunctionNameStackFilter }
+        ]);
+    }
+    create(value?: PartialMessage<StackFilter>): StackFilter {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.filter = { oneofKind: undefined };
+        if (value !== undefined)
+            reflectionMergePartial<StackFilter>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: StackFilter): StackFilter {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.query.v1alpha1.FunctionNameStackFilter function_name_stack_filter */ 1:
+                    message.filter = {
+                        oneofKind: "functionNameStackFilter",
+                        functionNameStackFilter: FunctionNameStackFilter.internalBinaryRead(reader, reader.uint32(), options, (message.filter as any).functionNameStackFilter)
+                    };
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: StackFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.FunctionNameStackFilter function_name_stack_filter = 1; */
+        if (message.filter.oneofKind === "functionNameStackFilter")
+            FunctionNameStackFilter.internalBinaryWrite(message.filter.functionNameStackFilter, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.StackFilter
+ */
+export const StackFilter = new StackFilter$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class FunctionNameStackFilter$Type extends MessageType<FunctionNameStackFilter> {
+    constructor() {
+        super("parca.query.v1alpha1.FunctionNameStackFilter", [
+            { no: 1, name: "function_to_filter", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        ]);
+    }
+    create(value?: PartialMessage<FunctionNameStackFilter>): FunctionNameStackFilter {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.functionToFilter = "";
+        if (value !== undefined)
+            reflectionMergePartial<FunctionNameStackFilter>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FunctionNameStackFilter): FunctionNameStackFilter {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string function_to_filter */ 1:
+                    message.functionToFilter = reader.string();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: FunctionNameStackFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string function_to_filter = 1; */
+        if (message.functionToFilter !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.functionToFilter);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.FunctionNameStackFilter
+ */
+export const FunctionNameStackFilter = new FunctionNameStackFilter$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class FrameFilter$Type extends MessageType<FrameFilter> {
+    constructor() {
+        super("parca.query.v1alpha1.FrameFilter", [
+            { no: 1, name: "binary_frame_filter", kind: "message", oneof: "filter", T: () => BinaryFrameFilter }
+        ]);
+    }
+    create(value?: PartialMessage<FrameFilter>): FrameFilter {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.filter = { oneofKind: undefined };
+        if (value !== undefined)
+            reflectionMergePartial<FrameFilter>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FrameFilter): FrameFilter {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.query.v1alpha1.BinaryFrameFilter binary_frame_filter */ 1:
+                    message.filter = {
+                        oneofKind: "binaryFrameFilter",
+                        binaryFrameFilter: BinaryFrameFilter.internalBinaryRead(reader, reader.uint32(), options, (message.filter as any).binaryFrameFilter)
+                    };
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: FrameFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.BinaryFrameFilter binary_frame_filter = 1; */
+        if (message.filter.oneofKind === "binaryFrameFilter")
+            BinaryFrameFilter.internalBinaryWrite(message.filter.binaryFrameFilter, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.FrameFilter
+ */
+export const FrameFilter = new FrameFilter$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class BinaryFrameFilter$Type extends MessageType<BinaryFrameFilter> {
+    constructor() {
+        super("parca.query.v1alpha1.BinaryFrameFilter", [
+            { no: 1, name: "include_binaries", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        ]);
+    }
+    create(value?: PartialMessage<BinaryFrameFilter>): BinaryFrameFilter {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.includeBinaries = [];
+        if (value !== undefined)
+            reflectionMergePartial<BinaryFrameFilter>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: BinaryFrameFilter): BinaryFrameFilter {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* repeated string include_binaries */ 1:
+                    message.includeBinaries.push(reader.string());
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: BinaryFrameFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated string include_binaries = 1; */
+        for (let i = 0; i < message.includeBinaries.length; i++)
+            writer.tag(1, WireType.LengthDelimited).string(message.includeBinaries[i]);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.BinaryFrameFilter
+ */
+export const BinaryFrameFilter = new BinaryFrameFilter$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class RuntimeFilter$Type extends MessageType<RuntimeFilter> {
+    constructor() {
+        super("parca.query.v1alpha1.RuntimeFilter", [
+            { no: 1, name: "show_python", kind: "scalar", T: 8 /*ScalarType.BOOL*/ },
+            { no: 2, name: "show_ruby", kind: "scalar", T: 8 /*ScalarType.BOOL*/ },
+            { no: 3, name: "show_interpreted_only", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
+        ]);
+    }
+    create(value?: PartialMessage<RuntimeFilter>): RuntimeFilter {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.showPython = false;
+        message.showRuby = false;
+        message.showInterpretedOnly = false;
+        if (value !== undefined)
+            reflectionMergePartial<RuntimeFilter>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: RuntimeFilter): RuntimeFilter {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* bool show_python */ 1:
+                    message.showPython = reader.bool();
+                    break;
+                case /* bool show_ruby */ 2:
+                    message.showRuby = reader.bool();
+                    break;
+                case /* bool show_interpreted_only */ 3:
+                    message.showInterpretedOnly = reader.bool();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: RuntimeFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* bool show_python = 1; */
+        if (message.showPython !== false)
+            writer.tag(1, WireType.Varint).bool(message.showPython);
+        /* bool show_ruby = 2; */
+        if (message.showRuby !== false)
+            writer.tag(2, WireType.Varint).bool(message.showRuby);
+        /* bool show_interpreted_only = 3; */
+        if (message.showInterpretedOnly !== false)
+            writer.tag(3, WireType.Varint).bool(message.showInterpretedOnly);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.RuntimeFilter
+ */
+export const RuntimeFilter = new RuntimeFilter$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class SourceReference$Type extends MessageType<SourceReference> {
+    constructor() {
+        super("parca.query.v1alpha1.SourceReference", [
+            { no: 1, name: "build_id", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "filename", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 3, name: "source_only", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
+        ]);
+    }
+    create(value?: PartialMessage<SourceReference>): SourceReference {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.buildId = "";
+        message.filename = "";
+        message.sourceOnly = false;
+        if (value !== undefined)
+            reflectionMergePartial<SourceReference>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SourceReference): SourceReference {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string build_id */ 1:
+                    message.buildId = reader.string();
+                    break;
+                case /* string filename */ 2:
+                    message.filename = reader.string();
+                    break;
+                case /* bool source_only */ 3:
+                    message.sourceOnly = reader.bool();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: SourceReference, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string build_id = 1; */
+        if (message.buildId !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.buildId);
+        /* string filename = 2; */
+        if (message.filename !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.filename);
+        /* bool source_only = 3; */
+        if (message.sourceOnly !== false)
+            writer.tag(3, WireType.Varint).bool(message.sourceOnly);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.SourceReference
+ */
+export const SourceReference = new SourceReference$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class GroupBy$Type extends MessageType<GroupBy> {
+    constructor() {
+        super("parca.query.v1alpha1.GroupBy", [
+            { no: 1, name: "fields", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        ]);
+    }
+    create(value?: PartialMessage<GroupBy>): GroupBy {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.fields = [];
+        if (value !== undefined)
+            reflectionMergePartial<GroupBy>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: GroupBy): GroupBy {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* repeated string fields */ 1:
+                    message.fields.push(reader.string());
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: GroupBy, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated string fields = 1; */
+        for (let i = 0; i < message.fields.length; i++)
+            writer.tag(1, WireType.LengthDelimited).string(message.fields[i]);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.GroupBy
+ */
+export const GroupBy = new GroupBy$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class Top$Type extends MessageType<Top> {
+    constructor() {
+        super("parca.query.v1alpha1.Top", [
+            { no: 1, name: "list", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => TopNode },
+            { no: 2, name: "reported", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
+            { no: 3, name: "total", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
+            { no: 4, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        ]);
+    }
+    create(value?: PartialMessage<Top>): Top {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.list = [];
+        message.reported = 0;
+        message.total = 0;
+        message.unit = "";
+        if (value !== undefined)
+            reflectionMergePartial<Top>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Top): Top {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* repeated parca.query.v1alpha1.TopNode list */ 1:
+                    message.list.push(TopNode.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                case /* int32 reported */ 2:
+                    message.reported = reader.int32();
+                    break;
+                case /* int32 total = 3 [deprecated = true];*/ 3:
+                    message.total = reader.int32();
+                    break;
+                case /* string unit */ 4:
+                    message.unit = reader.string();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: Top, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.TopNode list = 1; */
+        for (let i = 0; i < message.list.length; i++)
+            TopNode.internalBinaryWrite(message.list[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* int32 reported = 2; */
+        if (message.reported !== 0)
+            writer.tag(2, WireType.Varint).int32(message.reported);
+        /* int32 total = 3 [deprecated = true]; */
+        if (message.total !== 0)
+            writer.tag(3, WireType.Varint).int32(message.total);
+        /* string unit = 4; */
+        if (message.unit !== "")
+            writer.tag(4, WireType.LengthDelimited).string(message.unit);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.Top
+ */
+export const Top = new Top$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class TopNode$Type extends MessageType<TopNode> {
+    constructor() {
+        super("parca.query.v1alpha1.TopNode", [
+            { no: 1, name: "meta", kind: "message", T: () => TopNodeMeta },
+            { no: 2, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 3, name: "flat", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 4, name: "diff", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        ]);
+    }
+    create(value?: PartialMessage<TopNode>): TopNode {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.cumulative = 0n;
+        message.flat = 0n;
+        message.diff = 0n;
+        if (value !== undefined)
+            reflectionMergePartial<TopNode>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TopNode): TopNode {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.query.v1alpha1.TopNodeMeta meta */ 1:
+                    message.meta = TopNodeMeta.internalBinaryRead(reader, reader.uint32(), options, message.meta);
+                    break;
+                case /* int64 cumulative */ 2:
+                    message.cumulative = reader.int64().toBigInt();
+                    break;
+                case /* int64 flat */ 3:
+                    message.flat = reader.int64().toBigInt();
+                    break;
+                case /* int64 diff */ 4:
+                    message.diff = reader.int64().toBigInt();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: TopNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.TopNodeMeta meta = 1; */
+        if (message.meta)
+            TopNodeMeta.internalBinaryWrite(message.meta, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* int64 cumulative = 2; */
+        if (message.cumulative !== 0n)
+            writer.tag(2, WireType.Varint).int64(message.cumulative);
+        /* int64 flat = 3; */
+        if (message.flat !== 0n)
+            writer.tag(3, WireType.Varint).int64(message.flat);
+        /* int64 diff = 4; */
+        if (message.diff !== 0n)
+            writer.tag(4, WireType.Varint).int64(message.diff);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.TopNode
+ */
+export const TopNode = new TopNode$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class TopNodeMeta$Type extends MessageType<TopNodeMeta> {
+    constructor() {
+        super("parca.query.v1alpha1.TopNodeMeta", [
+            { no: 1, name: "location", kind: "message", T: () => Location },
+            { no: 2, name: "mapping", kind: "message", T: () => Mapping },
+            { no: 3, name: "function", kind: "message", T: () => Function },
+            { no: 4, name: "line", kind: "message", T: () => Line }
+        ]);
+    }
+    create(value?: PartialMessage<TopNodeMeta>): TopNodeMeta {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        if (value !== undefined)
+            reflectionMergePartial<TopNodeMeta>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TopNodeMeta): TopNodeMeta {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.metastore.v1alpha1.Location location */ 1:
+                    message.location = Location.internalBinaryRead(reader, reader.uint32(), options, message.location);
+                    break;
+                case /* parca.metastore.v1alpha1.Mapping mapping */ 2:
+                    message.mapping = Mapping.internalBinaryRead(reader, reader.uint32(), options, message.mapping);
+                    break;
+                case /* parca.metastore.v1alpha1.Function function */ 3:
+                    message.function = Function.internalBinaryRead(reader, reader.uint32(), options, message.function);
+                    break;
+                case /* parca.metastore.v1alpha1.Line line */ 4:
+                    message.line = Line.internalBinaryRead(reader, reader.uint32(), options, message.line);
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: TopNodeMeta, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.metastore.v1alpha1.Location location = 1; */
+        if (message.location)
+            Location.internalBinaryWrite(message.location, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Mapping mapping = 2; */
+        if (message.mapping)
+            Mapping.internalBinaryWrite(message.mapping, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Function function = 3; */
+        if (message.function)
+            Function.internalBinaryWrite(message.function, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Line line = 4; */
+        if (message.line)
+            Line.internalBinaryWrite(message.line, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.TopNodeMeta
+ */
+export const TopNodeMeta = new TopNodeMeta$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class Flamegraph$Type extends MessageType<Flamegraph> {
+    constructor() {
+        super("parca.query.v1alpha1.Flamegraph", [
+            { no: 1, name: "root", kind: "message", T: () => FlamegraphRootNode },
+            { no: 2, name: "total", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 3, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 4, name: "height", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
+            { no: 5, name: "string_table", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+            { no: 6, name: "locations", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Location },
+            { no: 7, name: "mapping", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Mapping },
+            { no: 8, name: "function", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Function },
+            { no: 9, name: "untrimmed_total", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 10, name: "trimmed", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        ]);
+    }
+    create(value?: PartialMessage<Flamegraph>): Flamegraph {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.total = 0n;
+        message.unit = "";
+        message.height = 0;
+        message.stringTable = [];
+        message.locations = [];
+        message.mapping = [];
+        message.function = [];
+        message.untrimmedTotal = 0n;
+        message.trimmed = 0n;
+        if (value !== undefined)
+            reflectionMergePartial<Flamegraph>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Flamegraph): Flamegraph {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.query.v1alpha1.FlamegraphRootNode root */ 1:
+                    message.root = FlamegraphRootNode.internalBinaryRead(reader, reader.uint32(), options, message.root);
+                    break;
+                case /* int64 total = 2 [deprecated = true];*/ 2:
+                    message.total = reader.int64().toBigInt();
+                    break;
+                case /* string unit */ 3:
+                    message.unit = reader.string();
+                    break;
+                case /* int32 height */ 4:
+                    message.height = reader.int32();
+                    break;
+                case /* repeated string string_table */ 5:
+                    message.stringTable.push(reader.string());
+                    break;
+                case /* repeated parca.metastore.v1alpha1.Location locations */ 6:
+                    message.locations.push(Location.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                case /* repeated parca.metastore.v1alpha1.Mapping mapping */ 7:
+                    message.mapping.push(Mapping.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                case /* repeated parca.metastore.v1alpha1.Function function */ 8:
+                    message.function.push(Function.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                case /* int64 untrimmed_total = 9 [deprecated = true];*/ 9:
+                    message.untrimmedTotal = reader.int64().toBigInt();
+                    break;
+                case /* int64 trimmed */ 10:
+                    message.trimmed = reader.int64().toBigInt();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: Flamegraph, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.FlamegraphRootNode root = 1; */
+        if (message.root)
+            FlamegraphRootNode.internalBinaryWrite(message.root, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* int64 total = 2 [deprecated = true]; */
+        if (message.total !== 0n)
+            writer.tag(2, WireType.Varint).int64(message.total);
+        /* string unit = 3; */
+        if (message.unit !== "")
+            writer.tag(3, WireType.LengthDelimited).string(message.unit);
+        /* int32 height = 4; */
+        if (message.height !== 0)
+            writer.tag(4, WireType.Varint).int32(message.height);
+        /* repeated string string_table = 5; */
+        for (let i = 0; i < message.stringTable.length; i++)
+            writer.tag(5, WireType.LengthDelimited).string(message.stringTable[i]);
+        /* repeated parca.metastore.v1alpha1.Location locations = 6; */
+        for (let i = 0; i < message.locations.length; i++)
+            Location.internalBinaryWrite(message.locations[i], writer.tag(6, WireType.LengthDelimited).fork(), options).join();
+        /* repeated parca.metastore.v1alpha1.Mapping mapping = 7; */
+        for (let i = 0; i < message.mapping.length; i++)
+            Mapping.internalBinaryWrite(message.mapping[i], writer.tag(7, WireType.LengthDelimited).fork(), options).join();
+        /* repeated parca.metastore.v1alpha1.Function function = 8; */
+        for (let i = 0; i < message.function.length; i++)
+            Function.internalBinaryWrite(message.function[i], writer.tag(8, WireType.LengthDelimited).fork(), options).join();
+        /* int64 untrimmed_total = 9 [deprecated = true]; */
+        if (message.untrimmedTotal !== 0n)
+            writer.tag(9, WireType.Varint).int64(message.untrimmedTotal);
+        /* int64 trimmed = 10; */
+        if (message.trimmed !== 0n)
+            writer.tag(10, WireType.Varint).int64(message.trimmed);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.Flamegraph
+ */
+export const Flamegraph = new Flamegraph$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class FlamegraphArrow$Type extends MessageType<FlamegraphArrow> {
+    constructor() {
+        super("parca.query.v1alpha1.FlamegraphArrow", [
+            { no: 1, name: "record", kind: "scalar", T: 12 /*ScalarType.BYTES*/ },
+            { no: 2, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 3, name: "height", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
+            { no: 4, name: "trimmed", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        ]);
+    }
+    create(value?: PartialMessage<FlamegraphArrow>): FlamegraphArrow {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.record = new Uint8Array(0);
+        message.unit = "";
+        message.height = 0;
+        message.trimmed = 0n;
+        if (value !== undefined)
+            reflectionMergePartial<FlamegraphArrow>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FlamegraphArrow): FlamegraphArrow {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* bytes record */ 1:
+                    message.record = reader.bytes();
+                    break;
+                case /* string unit */ 2:
+                    message.unit = reader.string();
+                    break;
+                case /* int32 height */ 3:
+                    message.height = reader.int32();
+                    break;
+                case /* int64 trimmed */ 4:
+                    message.trimmed = reader.int64().toBigInt();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: FlamegraphArrow, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* bytes record = 1; */
+        if (message.record.length)
+            writer.tag(1, WireType.LengthDelimited).bytes(message.record);
+        /* string unit = 2; */
+        if (message.unit !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.unit);
+        /* int32 height = 3; */
+        if (message.height !== 0)
+            writer.tag(3, WireType.Varint).int32(message.height);
+        /* int64 trimmed = 4; */
+        if (message.trimmed !== 0n)
+            writer.tag(4, WireType.Varint).int64(message.trimmed);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.FlamegraphArrow
+ */
+export const FlamegraphArrow = new FlamegraphArrow$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class Source$Type extends MessageType<Source> {
+    constructor() {
+        super("parca.query.v1alpha1.Source", [
+            { no: 1, name: "record", kind: "scalar", T: 12 /*ScalarType.BYTES*/ },
+            { no: 2, name: "source", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 3, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        ]);
+    }
+    create(value?: PartialMessage<Source>): Source {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.record = new Uint8Array(0);
+        message.source = "";
+        message.unit = "";
+        if (value !== undefined)
+            reflectionMergePartial<Source>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Source): Source {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* bytes record */ 1:
+                    message.record = reader.bytes();
+                    break;
+                case /* string source */ 2:
+                    message.source = reader.string();
+                    break;
+                case /* string unit */ 3:
+                    message.unit = reader.string();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: Source, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* bytes record = 1; */
+        if (message.record.length)
+            writer.tag(1, WireType.LengthDelimited).bytes(message.record);
+        /* string source = 2; */
+        if (message.source !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.source);
+        /* string unit = 3; */
+        if (message.unit !== "")
+            writer.tag(3, WireType.LengthDelimited).string(message.unit);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.Source
+ */
+export const Source = new Source$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class FlamegraphRootNode$Type extends MessageType<FlamegraphRootNode> {
+    constructor() {
+        super("parca.query.v1alpha1.FlamegraphRootNode", [
+            { no: 1, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 2, name: "diff", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 3, name: "children", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => FlamegraphNode }
+        ]);
+    }
+    create(value?: PartialMessage<FlamegraphRootNode>): FlamegraphRootNode {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.cumulative = 0n;
+        message.diff = 0n;
+        message.children = [];
+        if (value !== undefined)
+            reflectionMergePartial<FlamegraphRootNode>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FlamegraphRootNode): FlamegraphRootNode {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* int64 cumulative */ 1:
+                    message.cumulative = reader.int64().toBigInt();
+                    break;
+                case /* int64 diff */ 2:
+                    message.diff = reader.int64().toBigInt();
+                    break;
+                case /* repeated parca.query.v1alpha1.FlamegraphNode children */ 3:
+                    message.children.push(FlamegraphNode.internalBinaryRead(reader, reader.uint32(), options));
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: FlamegraphRootNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* int64 cumulative = 1; */
+        if (message.cumulative !== 0n)
+            writer.tag(1, WireType.Varint).int64(message.cumulative);
+        /* int64 diff = 2; */
+        if (message.diff !== 0n)
+            writer.tag(2, WireType.Varint).int64(message.diff);
+        /* repeated parca.query.v1alpha1.FlamegraphNode children = 3; */
+        for (let i = 0; i < message.children.length; i++)
+            FlamegraphNode.internalBinaryWrite(message.children[i], writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.FlamegraphRootNode
+ */
+export const FlamegraphRootNode = new FlamegraphRootNode$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class FlamegraphNode$Type extends MessageType<FlamegraphNode> {
+    constructor() {
+        super("parca.query.v1alpha1.FlamegraphNode", [
+            { no: 1, name: "meta", kind: "message", T: () => FlamegraphNodeMeta },
+            { no: 2, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 3, name: "diff", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 4, name: "children", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => FlamegraphNode }
+        ]);
+    }
+    create(value?: PartialMessage<FlamegraphNode>): FlamegraphNode {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.cumulative = 0n;
+        message.diff = 0n;
+        message.children = [];
+        if (value !== undefined)
+            reflectionMergePartial<FlamegraphNode>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FlamegraphNode): FlamegraphNode {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* string query */ 1:
-                    message.query = reader.string();
+                case /* parca.query.v1alpha1.FlamegraphNodeMeta meta */ 1:
+                    message.meta = FlamegraphNodeMeta.internalBinaryRead(reader, reader.uint32(), options, message.meta);
                     break;
-                case /* google.protobuf.Timestamp start */ 2:
-                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                case /* int64 cumulative */ 2:
+                    message.cumulative = reader.int64().toBigInt();
                     break;
-                case /* google.protobuf.Timestamp end */ 3:
-                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                case /* int64 diff */ 3:
+                    message.diff = reader.int64().toBigInt();
                     break;
-                case /* uint32 limit */ 4:
-                    message.limit = reader.uint32();
+                case /* repeated parca.query.v1alpha1.FlamegraphNode children */ 4:
+                    message.children.push(FlamegraphNode.internalBinaryRead(reader, reader.uint32(), options));
                     break;
-                case /* google.protobuf.Duration step */ 5:
-                    message.step = Duration.internalBinaryRead(reader, reader.uint32(), options, message.step);
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: FlamegraphNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.FlamegraphNodeMeta meta = 1; */
+        if (message.meta)
+            FlamegraphNodeMeta.internalBinaryWrite(message.meta, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* int64 cumulative = 2; */
+        if (message.cumulative !== 0n)
+            writer.tag(2, WireType.Varint).int64(message.cumulative);
+        /* int64 diff = 3; */
+        if (message.diff !== 0n)
+            writer.tag(3, WireType.Varint).int64(message.diff);
+        /* repeated parca.query.v1alpha1.FlamegraphNode children = 4; */
+        for (let i = 0; i < message.children.length; i++)
+            FlamegraphNode.internalBinaryWrite(message.children[i], writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.FlamegraphNode
+ */
+export const FlamegraphNode = new FlamegraphNode$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class FlamegraphNodeMeta$Type extends MessageType<FlamegraphNodeMeta> {
+    constructor() {
+        super("parca.query.v1alpha1.FlamegraphNodeMeta", [
+            { no: 1, name: "location", kind: "message", T: () => Location },
+            { no: 2, name: "mapping", kind: "message", T: () => Mapping },
+            { no: 3, name: "function", kind: "message", T: () => Function },
+            { no: 4, name: "line", kind: "message", T: () => Line },
+            { no: 5, name: "location_index", kind: "scalar", T: 13 /*ScalarType.UINT32*/ },
+            { no: 6, name: "line_index", kind: "scalar", T: 13 /*ScalarType.UINT32*/ }
+        ]);
+    }
+    create(value?: PartialMessage<FlamegraphNodeMeta>): FlamegraphNodeMeta {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.locationIndex = 0;
+        message.lineIndex = 0;
+        if (value !== undefined)
+            reflectionMergePartial<FlamegraphNodeMeta>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FlamegraphNodeMeta): FlamegraphNodeMeta {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* parca.metastore.v1alpha1.Location location */ 1:
+                    message.location = Location.internalBinaryRead(reader, reader.uint32(), options, message.location);
                     break;
-                case /* repeated string sum_by */ 6:
-                    message.sumBy.push(reader.string());
+                case /* parca.metastore.v1alpha1.Mapping mapping */ 2:
+                    message.mapping = Mapping.internalBinaryRead(reader, reader.uint32(), options, message.mapping);
+                    break;
+                case /* parca.metastore.v1alpha1.Function function */ 3:
+                    message.function = Function.internalBinaryRead(reader, reader.uint32(), options, message.function);
+                    break;
+                case /* parca.metastore.v1alpha1.Line line */ 4:
+                    message.line = Line.internalBinaryRead(reader, reader.uint32(), options, message.line);
+                    break;
+                case /* uint32 location_index */ 5:
+                    message.locationIndex = reader.uint32();
+                    break;
+                case /* uint32 line_index */ 6:
+                    message.lineIndex = reader.uint32();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -1640,25 +4291,25 @@ class QueryRangeRequest$Type extends MessageType<QueryRangeRequest> {
         }
         return message;
     }
-    internalBinaryWrite(message: QueryRangeRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string query = 1; */
-        if (message.query !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.query);
-        /* google.protobuf.Timestamp start = 2; */
-        if (message.start)
-            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* google.protobuf.Timestamp end = 3; */
-        if (message.end)
-            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
-        /* uint32 limit = 4; */
-        if (message.limit !== 0)
-            writer.tag(4, WireType.Varint).uint32(message.limit);
-        /* google.protobuf.Duration step = 5; */
-        if (message.step)
-            Duration.internalBinaryWrite(message.step, writer.tag(5, WireType.LengthDelimited).fork(), options).join();
-        /* repeated string sum_by = 6; */
-        for (let i = 0; i < message.sumBy.length; i++)
-            writer.tag(6, WireType.LengthDelimited).string(message.sumBy[i]);
+    internalBinaryWrite(message: FlamegraphNodeMeta, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.metastore.v1alpha1.Location location = 1; */
+        if (message.location)
+            Location.internalBinaryWrite(message.location, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Mapping mapping = 2; */
+        if (message.mapping)
+            Mapping.internalBinaryWrite(message.mapping, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Function function = 3; */
+        if (message.function)
+            Function.internalBinaryWrite(message.function, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Line line = 4; */
+        if (message.line)
+            Line.internalBinaryWrite(message.line, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        /* uint32 location_index = 5; */
+        if (message.locationIndex !== 0)
+            writer.tag(5, WireType.Varint).uint32(message.locationIndex);
+        /* uint32 line_index = 6; */
+        if (message.lineIndex !== 0)
+            writer.tag(6, WireType.Varint).uint32(message.lineIndex);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -1666,30 +4317,111 @@ class QueryRangeRequest$Type extends MessageType<QueryRangeRequest> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRangeRequest
+ * @generated MessageType for protobuf message parca.query.v1alpha1.FlamegraphNodeMeta
+ */
+export const FlamegraphNodeMeta = new FlamegraphNodeMeta$Type();
+// @generated message type with reflection information, may provide speed optimized methods
+class CallgraphNode$Type extends MessageType<CallgraphNode> {
+    constructor() {
+        super("parca.query.v1alpha1.CallgraphNode", [
+            { no: 1, name: "id", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "meta", kind: "message", T: () => CallgraphNodeMeta },
+            { no: 3, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 4, name: "flat", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        ]);
+    }
+    create(value?: PartialMessage<CallgraphNode>): CallgraphNode {
+        const message = globalThis.Object.create((this.messagePrototype!));
+        message.id = "";
+        message.cumulative = 0n;
+        message.flat = 0n;
+        if (value !== undefined)
+            reflectionMergePartial<CallgraphNode>(this, message, value);
+        return message;
+    }
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CallgraphNode): CallgraphNode {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string id */ 1:
+                    message.id = reader.string();
+                    break;
+                case /* parca.query.v1alpha1.CallgraphNodeMeta meta */ 2:
+                    message.meta = CallgraphNodeMeta.internalBinaryRead(reader, reader.uint32(), options, message.meta);
+                    break;
+                case /* int64 cumulative */ 3:
+                    message.cumulative = reader.int64().toBigInt();
+                    break;
+                case /* int64 flat */ 4:
+                    message.flat = reader.int64().toBigInt();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: CallgraphNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string id = 1; */
+        if (message.id !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.id);
+        /* parca.query.v1alpha1.CallgraphNodeMeta meta = 2; */
+        if (message.meta)
+            CallgraphNodeMeta.internalBinaryWrite(message.meta, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* int64 cumulative = 3; */
+        if (message.cumulative !== 0n)
+            writer.tag(3, WireType.Varint).int64(message.cumulative);
+        /* int64 flat = 4; */
+        if (message.flat !== 0n)
+            writer.tag(4, WireType.Varint).int64(message.flat);
+        let u = options.writeUnknownFields;
+        if (u !== false)
+            (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
+        return writer;
+    }
+}
+/**
+ * @generated MessageType for protobuf message parca.query.v1alpha1.CallgraphNode
  */
-export const QueryRangeRequest = new QueryRangeRequest$Type();
+export const CallgraphNode = new CallgraphNode$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class QueryRangeResponse$Type extends MessageType<QueryRangeResponse> {
+class CallgraphNodeMeta$Type extends MessageType<CallgraphNodeMeta> {
     constructor() {
-        super("parca.query.v1alpha1.QueryRangeResponse", [
-            { no: 1, name: "series", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => MetricsSeries }
+        super("parca.query.v1alpha1.CallgraphNodeMeta", [
+            { no: 1, name: "location", kind: "message", T: () => Location },
+            { no: 2, name: "mapping", kind: "message", T: () => Mapping },
+            { no: 3, name: "function", kind: "message", T: () => Function },
+            { no: 4, name: "line", kind: "message", T: () => Line }
         ]);
     }
-    create(value?: PartialMessage<QueryRangeResponse>): QueryRangeResponse {
+    create(value?: PartialMessage<CallgraphNodeMeta>): CallgraphNodeMeta {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.series = [];
         if (value !== undefined)
-            reflectionMergePartial<QueryRangeResponse>(this, message, value);
+            reflectionMergePartial<CallgraphNodeMeta>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRangeResponse): QueryRangeResponse {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CallgraphNodeMeta): CallgraphNodeMeta {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated parca.query.v1alpha1.MetricsSeries series */ 1:
-                    message.series.push(MetricsSeries.internalBinaryRead(reader, reader.uint32(), options));
+                case /* parca.metastore.v1alpha1.Location location */ 1:
+                    message.location = Location.internalBinaryRead(reader, reader.uint32(), options, message.location);
+                    break;
+                case /* parca.metastore.v1alpha1.Mapping mapping */ 2:
+                    message.mapping = Mapping.internalBinaryRead(reader, reader.uint32(), options, message.mapping);
+                    break;
+                case /* parca.metastore.v1alpha1.Function function */ 3:
+                    message.function = Function.internalBinaryRead(reader, reader.uint32(), options, message.function);
+                    break;
+                case /* parca.metastore.v1alpha1.Line line */ 4:
+                    message.line = Line.internalBinaryRead(reader, reader.uint32(), options, message.line);
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -1702,10 +4434,19 @@ class QueryRangeResponse$Type extends MessageType<QueryRangeResponse> {
         }
         return message;
     }
-    internalBinaryWrite(message: QueryRangeResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated parca.query.v1alpha1.MetricsSeries series = 1; */
-        for (let i = 0; i < message.series.length; i++)
-            MetricsSeries.internalBinaryWrite(message.series[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: CallgraphNodeMeta, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.metastore.v1alpha1.Location location = 1; */
+        if (message.location)
+            Location.internalBinaryWrite(message.location, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Mapping mapping = 2; */
+        if (message.mapping)
+            Mapping.internalBinaryWrite(message.mapping, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Function function = 3; */
+        if (message.function)
+            Function.internalBinaryWrite(message.function, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* parca.metastore.v1alpha1.Line line = 4; */
+        if (message.line)
+            Line.internalBinaryWrite(message.line, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -1713,42 +4454,50 @@ class QueryRangeResponse$Type extends MessageType<QueryRangeResponse> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRangeResponse
+ * @generated MessageType for protobuf message parca.query.v1alpha1.CallgraphNodeMeta
  */
-export const QueryRangeResponse = new QueryRangeResponse$Type();
+export const CallgraphNodeMeta = new CallgraphNodeMeta$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class MetricsSeries$Type extends MessageType<MetricsSeries> {
+class CallgraphEdge$Type extends MessageType<CallgraphEdge> {
     constructor() {
-        super("parca.query.v1alpha1.MetricsSeries", [
-            { no: 1, name: "labelset", kind: "message", T: () => LabelSet },
-            { no: 2, name: "samples", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => MetricsSample },
-            { no: 3, name: "period_type", kind: "message", T: () => ValueType },
-            { no: 4, name: "sample_type", kind: "message", T: () => ValueType }
+        super("parca.query.v1alpha1.CallgraphEdge", [
+            { no: 1, name: "id", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "source", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 3, name: "target", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 4, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 5, name: "is_collapsed", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
         ]);
     }
-    create(value?: PartialMessage<MetricsSeries>): MetricsSeries {
+    create(value?: PartialMessage<CallgraphEdge>): CallgraphEdge {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.samples = [];
+        message.id = "";
+        message.source = "";
+        message.target = "";
+        message.cumulative = 0n;
+        message.isCollapsed = false;
         if (value !== undefined)
-            reflectionMergePartial<MetricsSeries>(this, message, value);
+            reflectionMergePartial<CallgraphEdge>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: MetricsSeries): MetricsSeries {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CallgraphEdge): CallgraphEdge {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.profilestore.v1alpha1.LabelSet labelset */ 1:
-                    message.labelset = LabelSet.internalBinaryRead(reader, reader.uint32(), options, message.labelset);
+                case /* string id */ 1:
+                    message.id = reader.string();
                     break;
-                case /* repeated parca.query.v1alpha1.MetricsSample samples */ 2:
-                    message.samples.push(MetricsSample.internalBinaryRead(reader, reader.uint32(), options));
+                case /* string source */ 2:
+                    message.source = reader.string();
                     break;
-                case /* parca.query.v1alpha1.ValueType period_type */ 3:
-                    message.periodType = ValueType.internalBinaryRead(reader, reader.uint32(), options, message.periodType);
+                case /* string target */ 3:
+                    message.target = reader.string();
                     break;
-                case /* parca.query.v1alpha1.ValueType sample_type */ 4:
-                    message.sampleType = ValueType.internalBinaryRead(reader, reader.uint32(), options, message.sampleType);
+                case /* int64 cumulative */ 4:
+                    message.cumulative = reader.int64().toBigInt();
+                    break;
+                case /* bool is_collapsed */ 5:
+                    message.isCollapsed = reader.bool();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -1761,19 +4510,22 @@ class MetricsSeries$Type extends MessageType<MetricsSeries> {
         }
         return message;
     }
-    internalBinaryWrite(message: MetricsSeries, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.profilestore.v1alpha1.LabelSet labelset = 1; */
-        if (message.labelset)
-            LabelSet.internalBinaryWrite(message.labelset, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* repeated parca.query.v1alpha1.MetricsSample samples = 2; */
-        for (let i = 0; i < message.samples.length; i++)
-            MetricsSample.internalBinaryWrite(message.samples[i], writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.ValueType period_type = 3; */
-        if (message.periodType)
-            ValueType.internalBinaryWrite(message.periodType, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.ValueType sample_type = 4; */
-        if (message.sampleType)
-            ValueType.internalBinaryWrite(message.sampleType, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: CallgraphEdge, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string id = 1; */
+        if (message.id !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.id);
+        /* string source = 2; */
+        if (message.source !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.source);
+        /* string target = 3; */
+        if (message.target !== "")
+            writer.tag(3, WireType.LengthDelimited).string(message.target);
+        /* int64 cumulative = 4; */
+        if (message.cumulative !== 0n)
+            writer.tag(4, WireType.Varint).int64(message.cumulative);
+        /* bool is_collapsed = 5; */
+        if (message.isCollapsed !== false)
+            writer.tag(5, WireType.Varint).bool(message.isCollapsed);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -1781,44 +4533,40 @@ class MetricsSeries$Type extends MessageType<MetricsSeries> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.MetricsSeries
+ * @generated MessageType for protobuf message parca.query.v1alpha1.CallgraphEdge
  */
-export const MetricsSeries = new MetricsSeries$Type();
+export const CallgraphEdge = new CallgraphEdge$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class MetricsSample$Type extends MessageType<MetricsSample> {
+class Callgraph$Type extends MessageType<Callgraph> {
     constructor() {
-        super("parca.query.v1alpha1.MetricsSample", [
-            { no: 1, name: "timestamp", kind: "message", T: () => Timestamp },
-            { no: 2, name: "value", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 3, name: "value_per_second", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
-            { no: 4, name: "duration", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        super("parca.query.v1alpha1.Callgraph", [
+            { no: 1, name: "nodes", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => CallgraphNode },
+            { no: 2, name: "edges", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => CallgraphEdge },
+            { no: 3, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
         ]);
     }
-    create(value?: PartialMessage<MetricsSample>): MetricsSample {
+    create(value?: PartialMessage<Callgraph>): Callgraph {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.value = 0n;
-        message.valuePerSecond = 0;
-        message.duration = 0n;
+        message.nodes = [];
+        message.edges = [];
+        message.cumulative = 0n;
         if (value !== undefined)
-            reflectionMergePartial<MetricsSample>(this, message, value);
+            reflectionMergePartial<Callgraph>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: MetricsSample): MetricsSample {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Callgraph): Callgraph {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* google.protobuf.Timestamp timestamp */ 1:
-                    message.timestamp = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.timestamp);
-                    break;
-                case /* int64 value */ 2:
-                    message.value = reader.int64().toBigInt();
+                case /* repeated parca.query.v1alpha1.CallgraphNode nodes */ 1:
+                    message.nodes.push(CallgraphNode.internalBinaryRead(reader, reader.uint32(), options));
                     break;
-                case /* double value_per_second */ 3:
-                    message.valuePerSecond = reader.double();
+                case /* repeated parca.query.v1alpha1.CallgraphEdge edges */ 2:
+                    message.edges.push(CallgraphEdge.internalBinaryRead(reader, reader.uint32(), options));
                     break;
-                case /* int64 duration */ 4:
-                    message.duration = reader.int64().toBigInt();
+                case /* int64 cumulative = 3 [deprecated = true];*/ 3:
+                    message.cumulative = reader.int64().toBigInt();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -1831,19 +4579,16 @@ class MetricsSample$Type extends MessageType<MetricsSample> {
         }
         return message;
     }
-    internalBinaryWrite(message: MetricsSample, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* google.protobuf.Timestamp timestamp = 1; */
-        if (message.timestamp)
-            Timestamp.internalBinaryWrite(message.timestamp, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* int64 value = 2; */
-        if (message.value !== 0n)
-            writer.tag(2, WireType.Varint).int64(message.value);
-        /* double value_per_second = 3; */
-        if (message.valuePerSecond !== 0)
-            writer.tag(3, WireType.Bit64).double(message.valuePerSecond);
-        /* int64 duration = 4; */
-        if (message.duration !== 0n)
-            writer.tag(4, WireType.Varint).int64(message.duration);
+    internalBinaryWrite(message: Callgraph, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.CallgraphNode nodes = 1; */
+        for (let i = 0; i < message.nodes.length; i++)
+            CallgraphNode.internalBinaryWrite(message.nodes[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* repeated parca.query.v1alpha1.CallgraphEdge edges = 2; */
+        for (let i = 0; i < message.edges.length; i++)
+            CallgraphEdge.internalBinaryWrite(message.edges[i], writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* int64 cumulative = 3 [deprecated = true]; */
+        if (message.cumulative !== 0n)
+            writer.tag(3, WireType.Varint).int64(message.cumulative);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -1851,38 +4596,92 @@ class MetricsSample$Type extends MessageType<MetricsSample> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.MetricsSample
+ * @generated MessageType for protobuf message parca.query.v1alpha1.Callgraph
  */
-export const MetricsSample = new MetricsSample$Type();
+export const Callgraph = new Callgraph$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class MergeProfile$Type extends MessageType<MergeProfile> {
+class QueryResponse$Type extends MessageType<QueryResponse> {
     constructor() {
-        super("parca.query.v1alpha1.MergeProfile", [
-            { no: 1, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "start", kind: "message", T: () => Timestamp },
-            { no: 3, name: "end", kind: "message", T: () => Timestamp }
+        super("parca.query.v1alpha1.QueryResponse", [
+            { no: 5, name: "flamegraph", kind: "message", oneof: "report", T: () => Flamegraph },
+            { no: 6, name: "pprof", kind: "scalar", oneof: "report", T: 12 /*ScalarType.BYTES*/ },
+            { no: 7, name: "top", kind: "message", oneof: "report", T: () => Top },
+            { no: 8, name: "callgraph", kind: "message", oneof: "report", T: () => Callgraph },
+            { no: 11, name: "flamegraph_arrow", kind: "message", oneof: "report", T: () => FlamegraphArrow },
+            { no: 12, name: "source", kind: "message", oneof: "report", T: () => Source },
+            { no: 13, name: "table_arrow", kind: "message", oneof: "report", T: () => TableArrow },
+            { no: 14, name: "profile_metadata", kind: "message", oneof: "report", T: () => ProfileMetadata },
+            { no: 9, name: "total", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 10, name: "filtered", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
         ]);
     }
-    create(value?: PartialMessage<MergeProfile>): MergeProfile {
+    create(value?: PartialMessage<QueryResponse>): QueryResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.query = "";
+        message.report = { oneofKind: undefined };
+        message.total = 0n;
+        message.filtered = 0n;
         if (value !== undefined)
-            reflectionMergePartial<MergeProfile>(this, message, value);
+            reflectionMergePartial<QueryResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: MergeProfile): MergeProfile {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryResponse): QueryResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* string query */ 1:
-                    message.query = reader.string();
+                case /* parca.query.v1alpha1.Flamegraph flamegraph */ 5:
+                    message.report = {
+                        oneofKind: "flamegraph",
+                        flamegraph: Flamegraph.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).flamegraph)
+                    };
+                    break;
+                case /* bytes pprof */ 6:
+                    message.report = {
+                        oneofKind: "pprof",
+                        pprof: reader.bytes()
+                    };
+                    break;
+                case /* parca.query.v1alpha1.Top top */ 7:
+                    message.report = {
+                        oneofKind: "top",
+                        top: Top.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).top)
+                    };
+                    break;
+                case /* parca.query.v1alpha1.Callgraph callgraph */ 8:
+                    message.report = {
+                        oneofKind: "callgraph",
+                        callgraph: Callgraph.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).callgraph)
+                    };
+                    break;
+                case /* parca.query.v1alpha1.FlamegraphArrow flamegraph_arrow */ 11:
+                    message.report = {
+                        oneofKind: "flamegraphArrow",
+                        flamegraphArrow: FlamegraphArrow.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).flamegraphArrow)
+                    };
+                    break;
+                case /* parca.query.v1alpha1.Source source */ 12:
+                    message.report = {
+                        oneofKind: "source",
+                        source: Source.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).source)
+                    };
                     break;
-                case /* google.protobuf.Timestamp start */ 2:
-                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                case /* parca.query.v1alpha1.TableArrow table_arrow */ 13:
+                    message.report = {
+                        oneofKind: "tableArrow",
+                        tableArrow: TableArrow.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).tableArrow)
+                    };
                     break;
-                case /* google.protobuf.Timestamp end */ 3:
-                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                case /* parca.query.v1alpha1.ProfileMetadata profile_metadata */ 14:
+                    message.report = {
+                        oneofKind: "profileMetadata",
+                        profileMetadata: ProfileMetadata.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).profileMetadata)
+                    };
+                    break;
+                case /* int64 total */ 9:
+                    message.total = reader.int64().toBigInt();
+                    break;
+                case /* int64 filtered */ 10:
+                    message.filtered = reader.int64().toBigInt();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -1895,16 +4694,37 @@ class MergeProfile$Type extends MessageType<MergeProfile> {
         }
         return message;
     }
-    internalBinaryWrite(message: MergeProfile, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string query = 1; */
-        if (message.query !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.query);
-        /* google.protobuf.Timestamp start = 2; */
-        if (message.start)
-            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* google.protobuf.Timestamp end = 3; */
-        if (message.end)
-            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: QueryResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.Flamegraph flamegraph = 5; */
+        if (message.report.oneofKind === "flamegraph")
+            Flamegraph.internalBinaryWrite(message.report.flamegraph, writer.tag(5, WireType.LengthDelimited).fork(), options).join();
+        /* bytes pprof = 6; */
+        if (message.report.oneofKind === "pprof")
+            writer.tag(6, WireType.LengthDelimited).bytes(message.report.pprof);
+        /* parca.query.v1alpha1.Top top = 7; */
+        if (message.report.oneofKind === "top")
+            Top.internalBinaryWrite(message.report.top, writer.tag(7, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.Callgraph callgraph = 8; */
+        if (message.report.oneofKind === "callgraph")
+            Callgraph.internalBinaryWrite(message.report.callgraph, writer.tag(8, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.FlamegraphArrow flamegraph_arrow = 11; */
+        if (message.report.oneofKind === "flamegraphArrow")
+            FlamegraphArrow.internalBinaryWrite(message.report.flamegraphArrow, writer.tag(11, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.Source source = 12; */
+        if (message.report.oneofKind === "source")
+            Source.internalBinaryWrite(message.report.source, writer.tag(12, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.TableArrow table_arrow = 13; */
+        if (message.report.oneofKind === "tableArrow")
+            TableArrow.internalBinaryWrite(message.report.tableArrow, writer.tag(13, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.ProfileMetadata profile_metadata = 14; */
+        if (message.report.oneofKind === "profileMetadata")
+            ProfileMetadata.internalBinaryWrite(message.report.profileMetadata, writer.tag(14, WireType.LengthDelimited).fork(), options).join();
+        /* int64 total = 9; */
+        if (message.total !== 0n)
+            writer.tag(9, WireType.Varint).int64(message.total);
+        /* int64 filtered = 10; */
+        if (message.filtered !== 0n)
+            writer.tag(10, WireType.Varint).int64(message.filtered);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -1912,34 +4732,38 @@ class MergeProfile$Type extends MessageType<MergeProfile> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.MergeProfile
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryResponse
  */
-export const MergeProfile = new MergeProfile$Type();
+export const QueryResponse = new QueryResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class SingleProfile$Type extends MessageType<SingleProfile> {
+class SeriesRequest$Type extends MessageType<SeriesRequest> {
     constructor() {
-        super("parca.query.v1alpha1.SingleProfile", [
-            { no: 1, name: "time", kind: "message", T: () => Timestamp },
-            { no: 2, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.SeriesRequest", [
+            { no: 1, name: "match", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "start", kind: "message", T: () => Timestamp },
+            { no: 3, name: "end", kind: "message", T: () => Timestamp }
         ]);
     }
-    create(value?: PartialMessage<SingleProfile>): SingleProfile {
+    create(value?: PartialMessage<SeriesRequest>): SeriesRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.query = "";
+        message.match = [];
         if (value !== undefined)
-            reflectionMergePartial<SingleProfile>(this, message, value);
+            reflectionMergePartial<SeriesRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SingleProfile): SingleProfile {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SeriesRequest): SeriesRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* google.protobuf.Timestamp time */ 1:
-                    message.time = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.time);
+                case /* repeated string match */ 1:
+                    message.match.push(reader.string());
                     break;
-                case /* string query */ 2:
-                    message.query = reader.string();
+                case /* google.protobuf.Timestamp start */ 2:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                    break;
+                case /* google.protobuf.Timestamp end */ 3:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -1952,13 +4776,16 @@ class SingleProfile$Type extends MessageType<SingleProfile> {
         }
         return message;
     }
-    internalBinaryWrite(message: SingleProfile, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* google.protobuf.Timestamp time = 1; */
-        if (message.time)
-            Timestamp.internalBinaryWrite(message.time, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* string query = 2; */
-        if (message.query !== "")
-            writer.tag(2, WireType.LengthDelimited).string(message.query);
+    internalBinaryWrite(message: SeriesRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated string match = 1; */
+        for (let i = 0; i < message.match.length; i++)
+            writer.tag(1, WireType.LengthDelimited).string(message.match[i]);
+        /* google.protobuf.Timestamp start = 2; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 3; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -1966,59 +4793,24 @@ class SingleProfile$Type extends MessageType<SingleProfile> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.SingleProfile
+ * @generated MessageType for protobuf message parca.query.v1alpha1.SeriesRequest
  */
-export const SingleProfile = new SingleProfile$Type();
+export const SeriesRequest = new SeriesRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class DiffProfile$Type extends MessageType<DiffProfile> {
+class SeriesResponse$Type extends MessageType<SeriesResponse> {
     constructor() {
-        super("parca.query.v1alpha1.DiffProfile", [
-            { no: 1, name: "a", kind: "message", T: () => ProfileDiffSelection },
-            { no: 2, name: "b", kind: "message", T: () => ProfileDiffSelection },
-            { no: 3, name: "absolute", kind: "scalar", opt: true, T: 8 /*ScalarType.BOOL*/ }
-        ]);
+        super("parca.query.v1alpha1.SeriesResponse", []);
     }
-    create(value?: PartialMessage<DiffProfile>): DiffProfile {
+    create(value?: PartialMessage<SeriesResponse>): SeriesResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
         if (value !== undefined)
-            reflectionMergePartial<DiffProfile>(this, message, value);
+            reflectionMergePartial<SeriesResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: DiffProfile): DiffProfile {
-        let message = target ?? this.create(), end = reader.pos + length;
-        while (reader.pos < end) {
-            let [fieldNo, wireType] = reader.tag();
-            switch (fieldNo) {
-                case /* parca.query.v1alpha1.ProfileDiffSelection a */ 1:
-                    message.a = ProfileDiffSelection.internalBinaryRead(reader, reader.uint32(), options, message.a);
-                    break;
-                case /* parca.query.v1alpha1.ProfileDiffSelection b */ 2:
-                    message.b = ProfileDiffSelection.internalBinaryRead(reader, reader.uint32(), options, message.b);
-                    break;
-                case /* optional bool absolute */ 3:
-                    message.absolute = reader.bool();
-                    break;
-                default:
-                    let u = options.readUnknownField;
-                    if (u === "throw")
-                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
-                    let d = reader.skip(wireType);
-                    if (u !== false)
-                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
-            }
-        }
-        return message;
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SeriesResponse): SeriesResponse {
+        return target ?? this.create();
     }
-    internalBinaryWrite(message: DiffProfile, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.ProfileDiffSelection a = 1; */
-        if (message.a)
-            ProfileDiffSelection.internalBinaryWrite(message.a, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.ProfileDiffSelection b = 2; */
-        if (message.b)
-            ProfileDiffSelection.internalBinaryWrite(message.b, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* optional bool absolute = 3; */
-        if (message.absolute !== undefined)
-            writer.tag(3, WireType.Varint).bool(message.absolute);
+    internalBinaryWrite(message: SeriesResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2026,45 +4818,42 @@ class DiffProfile$Type extends MessageType<DiffProfile> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.DiffProfile
+ * @generated MessageType for protobuf message parca.query.v1alpha1.SeriesResponse
  */
-export const DiffProfile = new DiffProfile$Type();
+export const SeriesResponse = new SeriesResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class ProfileDiffSelection$Type extends MessageType<ProfileDiffSelection> {
+class LabelsRequest$Type extends MessageType<LabelsRequest> {
     constructor() {
-        super("parca.query.v1alpha1.ProfileDiffSelection", [
-            { no: 1, name: "mode", kind: "enum", T: () => ["parca.query.v1alpha1.ProfileDiffSelection.Mode", ProfileDiffSelection_Mode, "MODE_"] },
-            { no: 2, name: "merge", kind: "message", oneof: "options", T: () => MergeProfile },
-            { no: 3, name: "single", kind: "message", oneof: "options", T: () => SingleProfile }
+        super("parca.query.v1alpha1.LabelsRequest", [
+            { no: 1, name: "match", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "start", kind: "message", T: () => Timestamp },
+            { no: 3, name: "end", kind: "message", T: () => Timestamp },
+            { no: 4, name: "profile_type", kind: "scalar", opt: true, T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<ProfileDiffSelection>): ProfileDiffSelection {
+    create(value?: PartialMessage<LabelsRequest>): LabelsRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.mode = 0;
-        message.options = { oneofKind: undefined };
+        message.match = [];
         if (value !== undefined)
-            reflectionMergePartial<ProfileDiffSelection>(this, message, value);
+            reflectionMergePartial<LabelsRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileDiffSelection): ProfileDiffSelection {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: LabelsRequest): LabelsRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.ProfileDiffSelection.Mode mode */ 1:
-                    message.mode = reader.int32();
+                case /* repeated string match */ 1:
+                    message.match.push(reader.string());
                     break;
-                case /* parca.query.v1alpha1.MergeProfile merge */ 2:
-                    message.options = {
-                        oneofKind: "merge",
-                        merge: MergeProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).merge)
-                    };
+                case /* google.protobuf.Timestamp start */ 2:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
                     break;
-                case /* parca.query.v1alpha1.SingleProfile single */ 3:
-                    message.options = {
-                        oneofKind: "single",
-                        single: SingleProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).single)
-                    };
+                case /* google.protobuf.Timestamp end */ 3:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                    break;
+                case /* optional string profile_type */ 4:
+                    message.profileType = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2077,16 +4866,19 @@ class ProfileDiffSelection$Type extends MessageType<ProfileDiffSelection> {
         }
         return message;
     }
-    internalBinaryWrite(message: ProfileDiffSelection, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.ProfileDiffSelection.Mode mode = 1; */
-        if (message.mode !== 0)
-            writer.tag(1, WireType.Varint).int32(message.mode);
-        /* parca.query.v1alpha1.MergeProfile merge = 2; */
-        if (message.options.oneofKind === "merge")
-            MergeProfile.internalBinaryWrite(message.options.merge, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.SingleProfile single = 3; */
-        if (message.options.oneofKind === "single")
-            SingleProfile.internalBinaryWrite(message.options.single, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: LabelsRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated string match = 1; */
+        for (let i = 0; i < message.match.length; i++)
+            writer.tag(1, WireType.LengthDelimited).string(message.match[i]);
+        /* google.protobuf.Timestamp start = 2; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 3; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* optional string profile_type = 4; */
+        if (message.profileType !== undefined)
+            writer.tag(4, WireType.LengthDelimited).string(message.profileType);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2094,86 +4886,35 @@ class ProfileDiffSelection$Type extends MessageType<ProfileDiffSelection> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileDiffSelection
+ * @generated MessageType for protobuf message parca.query.v1alpha1.LabelsRequest
  */
-export const ProfileDiffSelection = new ProfileDiffSelection$Type();
+export const LabelsRequest = new LabelsRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class QueryRequest$Type extends MessageType<QueryRequest> {
+class LabelsResponse$Type extends MessageType<LabelsResponse> {
     constructor() {
-        super("parca.query.v1alpha1.QueryRequest", [
-            { no: 1, name: "mode", kind: "enum", T: () => ["parca.query.v1alpha1.QueryRequest.Mode", QueryRequest_Mode, "MODE_"] },
-            { no: 2, name: "diff", kind: "message", oneof: "options", T: () => DiffProfile },
-            { no: 3, name: "merge", kind: "message", oneof: "options", T: () => MergeProfile },
-            { no: 4, name: "single", kind: "message", oneof: "options", T: () => SingleProfile },
-            { no: 5, name: "report_type", kind: "enum", T: () => ["parca.query.v1alpha1.QueryRequest.ReportType", QueryRequest_ReportType, "REPORT_TYPE_"] },
-            { no: 6, name: "filter_query", kind: "scalar", opt: true, T: 9 /*ScalarType.STRING*/ },
-            { no: 7, name: "node_trim_threshold", kind: "scalar", opt: true, T: 2 /*ScalarType.FLOAT*/ },
-            { no: 8, name: "group_by", kind: "message", T: () => GroupBy },
-            { no: 9, name: "source_reference", kind: "message", T: () => SourceReference },
-            { no: 10, name: "runtime_filter", kind: "message", T: () => RuntimeFilter },
-            { no: 11, name: "invert_call_stack", kind: "scalar", opt: true, T: 8 /*ScalarType.BOOL*/ },
-            { no: 12, name: "filter", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Filter }
+        super("parca.query.v1alpha1.LabelsResponse", [
+            { no: 1, name: "label_names", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "warnings", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<QueryRequest>): QueryRequest {
+    create(value?: PartialMessage<LabelsResponse>): LabelsResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.mode = 0;
-        message.options = { oneofKind: undefined };
-        message.reportType = 0;
-        message.filter = [];
+        message.labelNames = [];
+        message.warnings = [];
         if (value !== undefined)
-            reflectionMergePartial<QueryRequest>(this, message, value);
+            reflectionMergePartial<LabelsResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRequest): QueryRequest {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: LabelsResponse): LabelsResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.QueryRequest.Mode mode */ 1:
-                    message.mode = reader.int32();
-                    break;
-                case /* parca.query.v1alpha1.DiffProfile diff */ 2:
-                    message.options = {
-                        oneofKind: "diff",
-                        diff: DiffProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).diff)
-                    };
-                    break;
-                case /* parca.query.v1alpha1.MergeProfile merge */ 3:
-                    message.options = {
-                        oneofKind: "merge",
-                        merge: MergeProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).merge)
-                    };
-                    break;
-                case /* parca.query.v1alpha1.SingleProfile single */ 4:
-                    message.options = {
-                        oneofKind: "single",
-                        single: SingleProfile.internalBinaryRead(reader, reader.uint32(), options, (message.options as any).single)
-                    };
-                    break;
-                case /* parca.query.v1alpha1.QueryRequest.ReportType report_type */ 5:
-                    message.reportType = reader.int32();
-                    break;
-                case /* optional string filter_query = 6 [deprecated = true];*/ 6:
-                    message.filterQuery = reader.string();
-                    break;
-                case /* optional float node_trim_threshold */ 7:
-                    message.nodeTrimThreshold = reader.float();
-                    break;
-                case /* optional parca.query.v1alpha1.GroupBy group_by */ 8:
-                    message.groupBy = GroupBy.internalBinaryRead(reader, reader.uint32(), options, message.groupBy);
-                    break;
-                case /* optional parca.query.v1alpha1.SourceReference source_reference */ 9:
-                    message.sourceReference = SourceReference.internalBinaryRead(reader, reader.uint32(), options, message.sourceReference);
-                    break;
-                case /* optional parca.query.v1alpha1.RuntimeFilter runtime_filter = 10 [deprecated = true];*/ 10:
-                    message.runtimeFilter = RuntimeFilter.internalBinaryRead(reader, reader.uint32(), options, message.runtimeFilter);
-                    break;
-                case /* optional bool invert_call_stack */ 11:
-                    message.invertCallStack = reader.bool();
+                case /* repeated string label_names */ 1:
+                    message.labelNames.push(reader.string());
                     break;
-                case /* repeated parca.query.v1alpha1.Filter filter */ 12:
-                    message.filter.push(Filter.internalBinaryRead(reader, reader.uint32(), options));
+                case /* repeated string warnings */ 2:
+                    message.warnings.push(reader.string());
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2186,43 +4927,13 @@ class QueryRequest$Type extends MessageType<QueryRequest> {
         }
         return message;
     }
-    internalBinaryWrite(message: QueryRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.QueryRequest.Mode mode = 1; */
-        if (message.mode !== 0)
-            writer.tag(1, WireType.Varint).int32(message.mode);
-        /* parca.query.v1alpha1.DiffProfile diff = 2; */
-        if (message.options.oneofKind === "diff")
-            DiffProfile.internalBinaryWrite(message.options.diff, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.MergeProfile merge = 3; */
-        if (message.options.oneofKind === "merge")
-            MergeProfile.internalBinaryWrite(message.options.merge, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.SingleProfile single = 4; */
-        if (message.options.oneofKind === "single")
-            SingleProfile.internalBinaryWrite(message.options.single, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.QueryRequest.ReportType report_type = 5; */
-        if (message.reportType !== 0)
-            writer.tag(5, WireType.Varint).int32(message.reportType);
-        /* optional string filter_query = 6 [deprecated = true]; */
-        if (message.filterQuery !== undefined)
-            writer.tag(6, WireType.LengthDelimited).string(message.filterQuery);
-        /* optional float node_trim_threshold = 7; */
-        if (message.nodeTrimThreshold !== undefined)
-            writer.tag(7, WireType.Bit32).float(message.nodeTrimThreshold);
-        /* optional parca.query.v1alpha1.GroupBy group_by = 8; */
-        if (message.groupBy)
-            GroupBy.internalBinaryWrite(message.groupBy, writer.tag(8, WireType.LengthDelimited).fork(), options).join();
-        /* optional parca.query.v1alpha1.SourceReference source_reference = 9; */
-        if (message.sourceReference)
-            SourceReference.internalBinaryWrite(message.sourceReference, writer.tag(9, WireType.LengthDelimited).fork(), options).join();
-        /* optional parca.query.v1alpha1.RuntimeFilter runtime_filter = 10 [deprecated = true]; */
-        if (message.runtimeFilter)
-            RuntimeFilter.internalBinaryWrite(message.runtimeFilter, writer.tag(10, WireType.LengthDelimited).fork(), options).join();
-        /* optional bool invert_call_stack = 11; */
-        if (message.invertCallStack !== undefined)
-            writer.tag(11, WireType.Varint).bool(message.invertCallStack);
-        /* repeated parca.query.v1alpha1.Filter filter = 12; */
-        for (let i = 0; i < message.filter.length; i++)
-            Filter.internalBinaryWrite(message.filter[i], writer.tag(12, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: LabelsResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated string label_names = 1; */
+        for (let i = 0; i < message.labelNames.length; i++)
+            writer.tag(1, WireType.LengthDelimited).string(message.labelNames[i]);
+        /* repeated string warnings = 2; */
+        for (let i = 0; i < message.warnings.length; i++)
+            writer.tag(2, WireType.LengthDelimited).string(message.warnings[i]);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2230,40 +4941,47 @@ class QueryRequest$Type extends MessageType<QueryRequest> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRequest
+ * @generated MessageType for protobuf message parca.query.v1alpha1.LabelsResponse
  */
-export const QueryRequest = new QueryRequest$Type();
+export const LabelsResponse = new LabelsResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class Filter$Type extends MessageType<Filter> {
+class ValuesRequest$Type extends MessageType<ValuesRequest> {
     constructor() {
-        super("parca.query.v1alpha1.Filter", [
-            { no: 1, name: "stack_filter", kind: "message", oneof: "filter", T: () => StackFilter },
-            { no: 2, name: "frame_filter", kind: "message", oneof: "filter", T: () => FrameFilter }
+        super("parca.query.v1alpha1.ValuesRequest", [
+            { no: 1, name: "label_name", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "match", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+            { no: 3, name: "start", kind: "message", T: () => Timestamp },
+            { no: 4, name: "end", kind: "message", T: () => Timestamp },
+            { no: 5, name: "profile_type", kind: "scalar", opt: true, T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<Filter>): Filter {
+    create(value?: PartialMessage<ValuesRequest>): ValuesRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.filter = { oneofKind: undefined };
+        message.labelName = "";
+        message.match = [];
         if (value !== undefined)
-            reflectionMergePartial<Filter>(this, message, value);
+            reflectionMergePartial<ValuesRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Filter): Filter {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ValuesRequest): ValuesRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.StackFilter stack_filter */ 1:
-                    message.filter = {
-                        oneofKind: "stackFilter",
-                        stackFilter: StackFilter.internalBinaryRead(reader, reader.uint32(), options, (message.filter as any).stackFilter)
-                    };
+                case /* string label_name */ 1:
+                    message.labelName = reader.string();
                     break;
-                case /* parca.query.v1alpha1.FrameFilter frame_filter */ 2:
-                    message.filter = {
-                        oneofKind: "frameFilter",
-                        frameFilter: FrameFilter.internalBinaryRead(reader, reader.uint32(), options, (message.filter as any).frameFilter)
-                    };
+                case /* repeated string match */ 2:
+                    message.match.push(reader.string());
+                    break;
+                case /* google.protobuf.Timestamp start */ 3:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                    break;
+                case /* google.protobuf.Timestamp end */ 4:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                    break;
+                case /* optional string profile_type */ 5:
+                    message.profileType = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2276,13 +4994,22 @@ class Filter$Type extends MessageType<Filter> {
         }
         return message;
     }
-    internalBinaryWrite(message: Filter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.StackFilter stack_filter = 1; */
-        if (message.filter.oneofKind === "stackFilter")
-            StackFilter.internalBinaryWrite(message.filter.stackFilter, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.FrameFilter frame_filter = 2; */
-        if (message.filter.oneofKind === "frameFilter")
-            FrameFilter.internalBinaryWrite(message.filter.frameFilter, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: ValuesRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string label_name = 1; */
+        if (message.labelName !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.labelName);
+        /* repeated string match = 2; */
+        for (let i = 0; i < message.match.length; i++)
+            writer.tag(2, WireType.LengthDelimited).string(message.match[i]);
+        /* google.protobuf.Timestamp start = 3; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 4; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        /* optional string profile_type = 5; */
+        if (message.profileType !== undefined)
+            writer.tag(5, WireType.LengthDelimited).string(message.profileType);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2290,33 +5017,35 @@ class Filter$Type extends MessageType<Filter> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.Filter
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ValuesRequest
  */
-export const Filter = new Filter$Type();
+export const ValuesRequest = new ValuesRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class StackFilter$Type extends MessageType<StackFilter> {
+class ValuesResponse$Type extends MessageType<ValuesResponse> {
     constructor() {
-        super("parca.query.v1alpha1.StackFilter", [
-            { no: 1, name: "function_name_stack_filter", kind: "message", oneof: "filter", T: () => FunctionNameStackFilter }
+        super("parca.query.v1alpha1.ValuesResponse", [
+            { no: 1, name: "label_values", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "warnings", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<StackFilter>): StackFilter {
+    create(value?: PartialMessage<ValuesResponse>): ValuesResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.filter = { oneofKind: undefined };
+        message.labelValues = [];
+        message.warnings = [];
         if (value !== undefined)
-            reflectionMergePartial<StackFilter>(this, message, value);
+            reflectionMergePartial<ValuesResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: StackFilter): StackFilter {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ValuesResponse): ValuesResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.FunctionNameStackFilter function_name_stack_filter */ 1:
-                    message.filter = {
-                        oneofKind: "functionNameStackFilter",
-                        functionNameStackFilter: FunctionNameStackFilter.internalBinaryRead(reader, reader.uint32(), options, (message.filter as any).functionNameStackFilter)
-                    };
+                case /* repeated string label_values */ 1:
+                    message.labelValues.push(reader.string());
+                    break;
+                case /* repeated string warnings */ 2:
+                    message.warnings.push(reader.string());
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2329,10 +5058,13 @@ class StackFilter$Type extends MessageType<StackFilter> {
         }
         return message;
     }
-    internalBinaryWrite(message: StackFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.FunctionNameStackFilter function_name_stack_filter = 1; */
-        if (message.filter.oneofKind === "functionNameStackFilter")
-            FunctionNameStackFilter.internalBinaryWrite(message.filter.functionNameStackFilter, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: ValuesResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated string label_values = 1; */
+        for (let i = 0; i < message.labelValues.length; i++)
+            writer.tag(1, WireType.LengthDelimited).string(message.labelValues[i]);
+        /* repeated string warnings = 2; */
+        for (let i = 0; i < message.warnings.length; i++)
+            writer.tag(2, WireType.LengthDelimited).string(message.warnings[i]);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2340,30 +5072,35 @@ class StackFilter$Type extends MessageType<StackFilter> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.StackFilter
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ValuesResponse
  */
-export const StackFilter = new StackFilter$Type();
+export const ValuesResponse = new ValuesResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class FunctionNameStackFilter$Type extends MessageType<FunctionNameStackFilter> {
+class ValueType$Type extends MessageType<ValueType> {
     constructor() {
-        super("parca.query.v1alpha1.FunctionNameStackFilter", [
-            { no: 1, name: "function_to_filter", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.ValueType", [
+            { no: 1, name: "type", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<FunctionNameStackFilter>): FunctionNameStackFilter {
+    create(value?: PartialMessage<ValueType>): ValueType {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.functionToFilter = "";
+        message.type = "";
+        message.unit = "";
         if (value !== undefined)
-            reflectionMergePartial<FunctionNameStackFilter>(this, message, value);
+            reflectionMergePartial<ValueType>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FunctionNameStackFilter): FunctionNameStackFilter {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ValueType): ValueType {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* string function_to_filter */ 1:
-                    message.functionToFilter = reader.string();
+                case /* string type */ 1:
+                    message.type = reader.string();
+                    break;
+                case /* string unit */ 2:
+                    message.unit = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2376,10 +5113,13 @@ class FunctionNameStackFilter$Type extends MessageType<FunctionNameStackFilter>
         }
         return message;
     }
-    internalBinaryWrite(message: FunctionNameStackFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string function_to_filter = 1; */
-        if (message.functionToFilter !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.functionToFilter);
+    internalBinaryWrite(message: ValueType, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string type = 1; */
+        if (message.type !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.type);
+        /* string unit = 2; */
+        if (message.unit !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.unit);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2387,33 +5127,33 @@ class FunctionNameStackFilter$Type extends MessageType<FunctionNameStackFilter>
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.FunctionNameStackFilter
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ValueType
  */
-export const FunctionNameStackFilter = new FunctionNameStackFilter$Type();
+export const ValueType = new ValueType$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class FrameFilter$Type extends MessageType<FrameFilter> {
+class ShareProfileRequest$Type extends MessageType<ShareProfileRequest> {
     constructor() {
-        super("parca.query.v1alpha1.FrameFilter", [
-            { no: 1, name: "binary_frame_filter", kind: "message", oneof: "filter", T: () => BinaryFrameFilter }
+        super("parca.query.v1alpha1.ShareProfileRequest", [
+            { no: 1, name: "query_request", kind: "message", T: () => QueryRequest },
+            { no: 2, name: "description", kind: "scalar", opt: true, T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<FrameFilter>): FrameFilter {
+    create(value?: PartialMessage<ShareProfileRequest>): ShareProfileRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.filter = { oneofKind: undefined };
         if (value !== undefined)
-            reflectionMergePartial<FrameFilter>(this, message, value);
+            reflectionMergePartial<ShareProfileRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FrameFilter): FrameFilter {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ShareProfileRequest): ShareProfileRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.BinaryFrameFilter binary_frame_filter */ 1:
-                    message.filter = {
-                        oneofKind: "binaryFrameFilter",
-                        binaryFrameFilter: BinaryFrameFilter.internalBinaryRead(reader, reader.uint32(), options, (message.filter as any).binaryFrameFilter)
-                    };
+                case /* parca.query.v1alpha1.QueryRequest query_request */ 1:
+                    message.queryRequest = QueryRequest.internalBinaryRead(reader, reader.uint32(), options, message.queryRequest);
+                    break;
+                case /* optional string description */ 2:
+                    message.description = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2426,10 +5166,13 @@ class FrameFilter$Type extends MessageType<FrameFilter> {
         }
         return message;
     }
-    internalBinaryWrite(message: FrameFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.BinaryFrameFilter binary_frame_filter = 1; */
-        if (message.filter.oneofKind === "binaryFrameFilter")
-            BinaryFrameFilter.internalBinaryWrite(message.filter.binaryFrameFilter, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: ShareProfileRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.QueryRequest query_request = 1; */
+        if (message.queryRequest)
+            QueryRequest.internalBinaryWrite(message.queryRequest, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* optional string description = 2; */
+        if (message.description !== undefined)
+            writer.tag(2, WireType.LengthDelimited).string(message.description);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2437,30 +5180,30 @@ class FrameFilter$Type extends MessageType<FrameFilter> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.FrameFilter
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ShareProfileRequest
  */
-export const FrameFilter = new FrameFilter$Type();
+export const ShareProfileRequest = new ShareProfileRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class BinaryFrameFilter$Type extends MessageType<BinaryFrameFilter> {
+class ShareProfileResponse$Type extends MessageType<ShareProfileResponse> {
     constructor() {
-        super("parca.query.v1alpha1.BinaryFrameFilter", [
-            { no: 1, name: "include_binaries", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.ShareProfileResponse", [
+            { no: 1, name: "link", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<BinaryFrameFilter>): BinaryFrameFilter {
+    create(value?: PartialMessage<ShareProfileResponse>): ShareProfileResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.includeBinaries = [];
+        message.link = "";
         if (value !== undefined)
-            reflectionMergePartial<BinaryFrameFilter>(this, message, value);
+            reflectionMergePartial<ShareProfileResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: BinaryFrameFilter): BinaryFrameFilter {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ShareProfileResponse): ShareProfileResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated string include_binaries */ 1:
-                    message.includeBinaries.push(reader.string());
+                case /* string link */ 1:
+                    message.link = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2473,10 +5216,10 @@ class BinaryFrameFilter$Type extends MessageType<BinaryFrameFilter> {
         }
         return message;
     }
-    internalBinaryWrite(message: BinaryFrameFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated string include_binaries = 1; */
-        for (let i = 0; i < message.includeBinaries.length; i++)
-            writer.tag(1, WireType.LengthDelimited).string(message.includeBinaries[i]);
+    internalBinaryWrite(message: ShareProfileResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string link = 1; */
+        if (message.link !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.link);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2484,40 +5227,35 @@ class BinaryFrameFilter$Type extends MessageType<BinaryFrameFilter> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.BinaryFrameFilter
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ShareProfileResponse
  */
-export const BinaryFrameFilter = new BinaryFrameFilter$Type();
+export const ShareProfileResponse = new ShareProfileResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class RuntimeFilter$Type extends MessageType<RuntimeFilter> {
+class TableArrow$Type extends MessageType<TableArrow> {
     constructor() {
-        super("parca.query.v1alpha1.RuntimeFilter", [
-            { no: 1, name: "show_python", kind: "scalar", T: 8 /*ScalarType.BOOL*/ },
-            { no: 2, name: "show_ruby", kind: "scalar", T: 8 /*ScalarType.BOOL*/ },
-            { no: 3, name: "show_interpreted_only", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
+        super("parca.query.v1alpha1.TableArrow", [
+            { no: 1, name: "record", kind: "scalar", T: 12 /*ScalarType.BYTES*/ },
+            { no: 2, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<RuntimeFilter>): RuntimeFilter {
+    create(value?: PartialMessage<TableArrow>): TableArrow {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.showPython = false;
-        message.showRuby = false;
-        message.showInterpretedOnly = false;
+        message.record = new Uint8Array(0);
+        message.unit = "";
         if (value !== undefined)
-            reflectionMergePartial<RuntimeFilter>(this, message, value);
+            reflectionMergePartial<TableArrow>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: RuntimeFilter): RuntimeFilter {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TableArrow): TableArrow {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* bool show_python */ 1:
-                    message.showPython = reader.bool();
-                    break;
-                case /* bool show_ruby */ 2:
-                    message.showRuby = reader.bool();
+                case /* bytes record */ 1:
+                    message.record = reader.bytes();
                     break;
-                case /* bool show_interpreted_only */ 3:
-                    message.showInterpretedOnly = reader.bool();
+                case /* string unit */ 2:
+                    message.unit = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2530,16 +5268,13 @@ class RuntimeFilter$Type extends MessageType<RuntimeFilter> {
         }
         return message;
     }
-    internalBinaryWrite(message: RuntimeFilter, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* bool show_python = 1; */
-        if (message.showPython !== false)
-            writer.tag(1, WireType.Varint).bool(message.showPython);
-        /* bool show_ruby = 2; */
-        if (message.showRuby !== false)
-            writer.tag(2, WireType.Varint).bool(message.showRuby);
-        /* bool show_interpreted_only = 3; */
-        if (message.showInterpretedOnly !== false)
-            writer.tag(3, WireType.Varint).bool(message.showInterpretedOnly);
+    internalBinaryWrite(message: TableArrow, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* bytes record = 1; */
+        if (message.record.length)
+            writer.tag(1, WireType.LengthDelimited).bytes(message.record);
+        /* string unit = 2; */
+        if (message.unit !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.unit);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2547,40 +5282,35 @@ class RuntimeFilter$Type extends MessageType<RuntimeFilter> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.RuntimeFilter
+ * @generated MessageType for protobuf message parca.query.v1alpha1.TableArrow
  */
-export const RuntimeFilter = new RuntimeFilter$Type();
+export const TableArrow = new TableArrow$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class SourceReference$Type extends MessageType<SourceReference> {
+class ProfileMetadata$Type extends MessageType<ProfileMetadata> {
     constructor() {
-        super("parca.query.v1alpha1.SourceReference", [
-            { no: 1, name: "build_id", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "filename", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 3, name: "source_only", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
+        super("parca.query.v1alpha1.ProfileMetadata", [
+            { no: 1, name: "mapping_files", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "labels", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<SourceReference>): SourceReference {
+    create(value?: PartialMessage<ProfileMetadata>): ProfileMetadata {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.buildId = "";
-        message.filename = "";
-        message.sourceOnly = false;
+        message.mappingFiles = [];
+        message.labels = [];
         if (value !== undefined)
-            reflectionMergePartial<SourceReference>(this, message, value);
+            reflectionMergePartial<ProfileMetadata>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SourceReference): SourceReference {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileMetadata): ProfileMetadata {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* string build_id */ 1:
-                    message.buildId = reader.string();
-                    break;
-                case /* string filename */ 2:
-                    message.filename = reader.string();
+                case /* repeated string mapping_files */ 1:
+                    message.mappingFiles.push(reader.string());
                     break;
-                case /* bool source_only */ 3:
-                    message.sourceOnly = reader.bool();
+                case /* repeated string labels */ 2:
+                    message.labels.push(reader.string());
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2593,16 +5323,13 @@ class SourceReference$Type extends MessageType<SourceReference> {
         }
         return message;
     }
-    internalBinaryWrite(message: SourceReference, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string build_id = 1; */
-        if (message.buildId !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.buildId);
-        /* string filename = 2; */
-        if (message.filename !== "")
-            writer.tag(2, WireType.LengthDelimited).string(message.filename);
-        /* bool source_only = 3; */
-        if (message.sourceOnly !== false)
-            writer.tag(3, WireType.Varint).bool(message.sourceOnly);
+    internalBinaryWrite(message: ProfileMetadata, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated string mapping_files = 1; */
+        for (let i = 0; i < message.mappingFiles.length; i++)
+            writer.tag(1, WireType.LengthDelimited).string(message.mappingFiles[i]);
+        /* repeated string labels = 2; */
+        for (let i = 0; i < message.labels.length; i++)
+            writer.tag(2, WireType.LengthDelimited).string(message.labels[i]);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2610,30 +5337,53 @@ class SourceReference$Type extends MessageType<SourceReference> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.SourceReference
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileMetadata
  */
-export const SourceReference = new SourceReference$Type();
+export const ProfileMetadata = new ProfileMetadata$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class GroupBy$Type extends MessageType<GroupBy> {
+class CompareToBaselineRequest$Type extends MessageType<CompareToBaselineRequest> {
     constructor() {
-        super("parca.query.v1alpha1.GroupBy", [
-            { no: 1, name: "fields", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.CompareToBaselineRequest", [
+            { no: 1, name: "baseline_query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "start", kind: "message", T: () => Timestamp },
+            { no: 3, name: "end", kind: "message", T: () => Timestamp },
+            { no: 4, name: "profile", kind: "scalar", T: 12 /*ScalarType.BYTES*/ },
+            { no: 5, name: "sample_type", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 6, name: "rules", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => RegressionRule }
         ]);
     }
-    create(value?: PartialMessage<GroupBy>): GroupBy {
+    create(value?: PartialMessage<CompareToBaselineRequest>): CompareToBaselineRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.fields = [];
+        message.baselineQuery = "";
+        message.profile = new Uint8Array(0);
+        message.sampleType = "";
+        message.rules = [];
         if (value !== undefined)
-            reflectionMergePartial<GroupBy>(this, message, value);
+            reflectionMergePartial<CompareToBaselineRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: GroupBy): GroupBy {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CompareToBaselineRequest): CompareToBaselineRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated string fields */ 1:
-                    message.fields.push(reader.string());
+                case /* string baseline_query */ 1:
+                    message.baselineQuery = reader.string();
+                    break;
+                case /* google.protobuf.Timestamp start */ 2:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                    break;
+                case /* google.protobuf.Timestamp end */ 3:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                    break;
+                case /* bytes profile */ 4:
+                    message.profile = reader.bytes();
+                    break;
+                case /* string sample_type */ 5:
+                    message.sampleType = reader.string();
+                    break;
+                case /* repeated parca.query.v1alpha1.RegressionRule rules */ 6:
+                    message.rules.push(RegressionRule.internalBinaryRead(reader, reader.uint32(), options));
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2646,10 +5396,25 @@ class GroupBy$Type extends MessageType<GroupBy> {
         }
         return message;
     }
-    internalBinaryWrite(message: GroupBy, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated string fields = 1; */
-        for (let i = 0; i < message.fields.length; i++)
-            writer.tag(1, WireType.LengthDelimited).string(message.fields[i]);
+    internalBinaryWrite(message: CompareToBaselineRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string baseline_query = 1; */
+        if (message.baselineQuery !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.baselineQuery);
+        /* google.protobuf.Timestamp start = 2; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 3; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* bytes profile = 4; */
+        if (message.profile.length)
+            writer.tag(4, WireType.LengthDelimited).bytes(message.profile);
+        /* string sample_type = 5; */
+        if (message.sampleType !== "")
+            writer.tag(5, WireType.LengthDelimited).string(message.sampleType);
+        /* repeated parca.query.v1alpha1.RegressionRule rules = 6; */
+        for (let i = 0; i < message.rules.length; i++)
+            RegressionRule.internalBinaryWrite(message.rules[i], writer.tag(6, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2657,45 +5422,35 @@ class GroupBy$Type extends MessageType<GroupBy> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.GroupBy
+ * @generated MessageType for protobuf message parca.query.v1alpha1.CompareToBaselineRequest
  */
-export const GroupBy = new GroupBy$Type();
+export const CompareToBaselineRequest = new CompareToBaselineRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class Top$Type extends MessageType<Top> {
+class RegressionRule$Type extends MessageType<RegressionRule> {
     constructor() {
-        super("parca.query.v1alpha1.Top", [
-            { no: 1, name: "list", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => TopNode },
-            { no: 2, name: "reported", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
-            { no: 3, name: "total", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
-            { no: 4, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.RegressionRule", [
+            { no: 1, name: "function", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "max_increase", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ }
         ]);
     }
-    create(value?: PartialMessage<Top>): Top {
+    create(value?: PartialMessage<RegressionRule>): RegressionRule {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.list = [];
-        message.reported = 0;
-        message.total = 0;
-        message.unit = "";
+        message.function = "";
+        message.maxIncrease = 0;
         if (value !== undefined)
-            reflectionMergePartial<Top>(this, message, value);
+            reflectionMergePartial<RegressionRule>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Top): Top {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: RegressionRule): RegressionRule {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated parca.query.v1alpha1.TopNode list */ 1:
-                    message.list.push(TopNode.internalBinaryRead(reader, reader.uint32(), options));
-                    break;
-                case /* int32 reported */ 2:
-                    message.reported = reader.int32();
-                    break;
-                case /* int32 total = 3 [deprecated = true];*/ 3:
-                    message.total = reader.int32();
+                case /* string function */ 1:
+                    message.function = reader.string();
                     break;
-                case /* string unit */ 4:
-                    message.unit = reader.string();
+                case /* double max_increase */ 2:
+                    message.maxIncrease = reader.double();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2708,19 +5463,13 @@ class Top$Type extends MessageType<Top> {
         }
         return message;
     }
-    internalBinaryWrite(message: Top, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated parca.query.v1alpha1.TopNode list = 1; */
-        for (let i = 0; i < message.list.length; i++)
-            TopNode.internalBinaryWrite(message.list[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* int32 reported = 2; */
-        if (message.reported !== 0)
-            writer.tag(2, WireType.Varint).int32(message.reported);
-        /* int32 total = 3 [deprecated = true]; */
-        if (message.total !== 0)
-            writer.tag(3, WireType.Varint).int32(message.total);
-        /* string unit = 4; */
-        if (message.unit !== "")
-            writer.tag(4, WireType.LengthDelimited).string(message.unit);
+    internalBinaryWrite(message: RegressionRule, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string function = 1; */
+        if (message.function !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.function);
+        /* double max_increase = 2; */
+        if (message.maxIncrease !== 0)
+            writer.tag(2, WireType.Bit64).double(message.maxIncrease);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2728,44 +5477,35 @@ class Top$Type extends MessageType<Top> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.Top
+ * @generated MessageType for protobuf message parca.query.v1alpha1.RegressionRule
  */
-export const Top = new Top$Type();
+export const RegressionRule = new RegressionRule$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class TopNode$Type extends MessageType<TopNode> {
+class CompareToBaselineResponse$Type extends MessageType<CompareToBaselineResponse> {
     constructor() {
-        super("parca.query.v1alpha1.TopNode", [
-            { no: 1, name: "meta", kind: "message", T: () => TopNodeMeta },
-            { no: 2, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 3, name: "flat", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 4, name: "diff", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        super("parca.query.v1alpha1.CompareToBaselineResponse", [
+            { no: 1, name: "functions", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => FunctionComparison },
+            { no: 2, name: "regressed", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
         ]);
     }
-    create(value?: PartialMessage<TopNode>): TopNode {
+    create(value?: PartialMessage<CompareToBaselineResponse>): CompareToBaselineResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.cumulative = 0n;
-        message.flat = 0n;
-        message.diff = 0n;
+        message.functions = [];
+        message.regressed = false;
         if (value !== undefined)
-            reflectionMergePartial<TopNode>(this, message, value);
+            reflectionMergePartial<CompareToBaselineResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TopNode): TopNode {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CompareToBaselineResponse): CompareToBaselineResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.TopNodeMeta meta */ 1:
-                    message.meta = TopNodeMeta.internalBinaryRead(reader, reader.uint32(), options, message.meta);
-                    break;
-                case /* int64 cumulative */ 2:
-                    message.cumulative = reader.int64().toBigInt();
-                    break;
-                case /* int64 flat */ 3:
-                    message.flat = reader.int64().toBigInt();
+                case /* repeated parca.query.v1alpha1.FunctionComparison functions */ 1:
+                    message.functions.push(FunctionComparison.internalBinaryRead(reader, reader.uint32(), options));
                     break;
-                case /* int64 diff */ 4:
-                    message.diff = reader.int64().toBigInt();
+                case /* bool regressed */ 2:
+                    message.regressed = reader.bool();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2778,19 +5518,13 @@ class TopNode$Type extends MessageType<TopNode> {
         }
         return message;
     }
-    internalBinaryWrite(message: TopNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.TopNodeMeta meta = 1; */
-        if (message.meta)
-            TopNodeMeta.internalBinaryWrite(message.meta, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* int64 cumulative = 2; */
-        if (message.cumulative !== 0n)
-            writer.tag(2, WireType.Varint).int64(message.cumulative);
-        /* int64 flat = 3; */
-        if (message.flat !== 0n)
-            writer.tag(3, WireType.Varint).int64(message.flat);
-        /* int64 diff = 4; */
-        if (message.diff !== 0n)
-            writer.tag(4, WireType.Varint).int64(message.diff);
+    internalBinaryWrite(message: CompareToBaselineResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.FunctionComparison functions = 1; */
+        for (let i = 0; i < message.functions.length; i++)
+            FunctionComparison.internalBinaryWrite(message.functions[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* bool regressed = 2; */
+        if (message.regressed !== false)
+            writer.tag(2, WireType.Varint).bool(message.regressed);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2798,41 +5532,55 @@ class TopNode$Type extends MessageType<TopNode> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.TopNode
+ * @generated MessageType for protobuf message parca.query.v1alpha1.CompareToBaselineResponse
  */
-export const TopNode = new TopNode$Type();
+export const CompareToBaselineResponse = new CompareToBaselineResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class TopNodeMeta$Type extends MessageType<TopNodeMeta> {
+class FunctionComparison$Type extends MessageType<FunctionComparison> {
     constructor() {
-        super("parca.query.v1alpha1.TopNodeMeta", [
-            { no: 1, name: "location", kind: "message", T: () => Location },
-            { no: 2, name: "mapping", kind: "message", T: () => Mapping },
-            { no: 3, name: "function", kind: "message", T: () => Function },
-            { no: 4, name: "line", kind: "message", T: () => Line }
+        super("parca.query.v1alpha1.FunctionComparison", [
+            { no: 1, name: "function", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "baseline", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 3, name: "candidate", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 4, name: "increase", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 5, name: "max_increase", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 6, name: "regressed", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
         ]);
     }
-    create(value?: PartialMessage<TopNodeMeta>): TopNodeMeta {
+    create(value?: PartialMessage<FunctionComparison>): FunctionComparison {
         const message = globalThis.Object.create((this.messagePrototype!));
+        message.function = "";
+        message.baseline = 0;
+        message.candidate = 0;
+        message.increase = 0;
+        message.maxIncrease = 0;
+        message.regressed = false;
         if (value !== undefined)
-            reflectionMergePartial<TopNodeMeta>(this, message, value);
+            reflectionMergePartial<FunctionComparison>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TopNodeMeta): TopNodeMeta {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FunctionComparison): FunctionComparison {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.metastore.v1alpha1.Location location */ 1:
-                    message.location = Location.internalBinaryRead(reader, reader.uint32(), options, message.location);
+                case /* string function */ 1:
+                    message.function = reader.string();
                     break;
-                case /* parca.metastore.v1alpha1.Mapping mapping */ 2:
-                    message.mapping = Mapping.internalBinaryRead(reader, reader.uint32(), options, message.mapping);
+                case /* double baseline */ 2:
+                    message.baseline = reader.double();
                     break;
-                case /* parca.metastore.v1alpha1.Function function */ 3:
-                    message.function = Function.internalBinaryRead(reader, reader.uint32(), options, message.function);
+                case /* double candidate */ 3:
+                    message.candidate = reader.double();
                     break;
-                case /* parca.metastore.v1alpha1.Line line */ 4:
-                    message.line = Line.internalBinaryRead(reader, reader.uint32(), options, message.line);
+                case /* double increase */ 4:
+                    message.increase = reader.double();
+                    break;
+                case /* double max_increase */ 5:
+                    message.maxIncrease = reader.double();
+                    break;
+                case /* bool regressed */ 6:
+                    message.regressed = reader.bool();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -2845,19 +5593,25 @@ class TopNodeMeta$Type extends MessageType<TopNodeMeta> {
         }
         return message;
     }
-    internalBinaryWrite(message: TopNodeMeta, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.metastore.v1alpha1.Location location = 1; */
-        if (message.location)
-            Location.internalBinaryWrite(message.location, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Mapping mapping = 2; */
-        if (message.mapping)
-            Mapping.internalBinaryWrite(message.mapping, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Function function = 3; */
-        if (message.function)
-            Function.internalBinaryWrite(message.function, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Line line = 4; */
-        if (message.line)
-            Line.internalBinaryWrite(message.line, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: FunctionComparison, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string function = 1; */
+        if (message.function !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.function);
+        /* double baseline = 2; */
+        if (message.baseline !== 0)
+            writer.tag(2, WireType.Bit64).double(message.baseline);
+        /* double candidate = 3; */
+        if (message.candidate !== 0)
+            writer.tag(3, WireType.Bit64).double(message.candidate);
+        /* double increase = 4; */
+        if (message.increase !== 0)
+            writer.tag(4, WireType.Bit64).double(message.increase);
+        /* double max_increase = 5; */
+        if (message.maxIncrease !== 0)
+            writer.tag(5, WireType.Bit64).double(message.maxIncrease);
+        /* bool regressed = 6; */
+        if (message.regressed !== false)
+            writer.tag(6, WireType.Varint).bool(message.regressed);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2865,75 +5619,35 @@ class TopNodeMeta$Type extends MessageType<TopNodeMeta> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.TopNodeMeta
+ * @generated MessageType for protobuf message parca.query.v1alpha1.FunctionComparison
  */
-export const TopNodeMeta = new TopNodeMeta$Type();
+export const FunctionComparison = new FunctionComparison$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class Flamegraph$Type extends MessageType<Flamegraph> {
+class QueryUnitsRequest$Type extends MessageType<QueryUnitsRequest> {
     constructor() {
-        super("parca.query.v1alpha1.Flamegraph", [
-            { no: 1, name: "root", kind: "message", T: () => FlamegraphRootNode },
-            { no: 2, name: "total", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 3, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 4, name: "height", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
-            { no: 5, name: "string_table", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
-            { no: 6, name: "locations", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Location },
-            { no: 7, name: "mapping", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Mapping },
-            { no: 8, name: "function", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Function },
-            { no: 9, name: "untrimmed_total", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 10, name: "trimmed", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        super("parca.query.v1alpha1.QueryUnitsRequest", [
+            { no: 1, name: "query", kind: "message", T: () => QueryRequest },
+            { no: 2, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<Flamegraph>): Flamegraph {
+    create(value?: PartialMessage<QueryUnitsRequest>): QueryUnitsRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.total = 0n;
         message.unit = "";
-        message.height = 0;
-        message.stringTable = [];
-        message.locations = [];
-        message.mapping = [];
-        message.function = [];
-        message.untrimmedTotal = 0n;
-        message.trimmed = 0n;
         if (value !== undefined)
-            reflectionMergePartial<Flamegraph>(this, message, value);
+            reflectionMergePartial<QueryUnitsRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Flamegraph): Flamegraph {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryUnitsRequest): QueryUnitsRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.FlamegraphRootNode root */ 1:
-                    message.root = FlamegraphRootNode.internalBinaryRead(reader, reader.uint32(), options, message.root);
-                    break;
-                case /* int64 total = 2 [deprecated = true];*/ 2:
-                    message.total = reader.int64().toBigInt();
+                case /* parca.query.v1alpha1.QueryRequest query */ 1:
+                    message.query = QueryRequest.internalBinaryRead(reader, reader.uint32(), options, message.query);
                     break;
-                case /* string unit */ 3:
+                case /* string unit */ 2:
                     message.unit = reader.string();
                     break;
-                case /* int32 height */ 4:
-                    message.height = reader.int32();
-                    break;
-                case /* repeated string string_table */ 5:
-                    message.stringTable.push(reader.string());
-                    break;
-                case /* repeated parca.metastore.v1alpha1.Location locations */ 6:
-                    message.locations.push(Location.internalBinaryRead(reader, reader.uint32(), options));
-                    break;
-                case /* repeated parca.metastore.v1alpha1.Mapping mapping */ 7:
-                    message.mapping.push(Mapping.internalBinaryRead(reader, reader.uint32(), options));
-                    break;
-                case /* repeated parca.metastore.v1alpha1.Function function */ 8:
-                    message.function.push(Function.internalBinaryRead(reader, reader.uint32(), options));
-                    break;
-                case /* int64 untrimmed_total = 9 [deprecated = true];*/ 9:
-                    message.untrimmedTotal = reader.int64().toBigInt();
-                    break;
-                case /* int64 trimmed */ 10:
-                    message.trimmed = reader.int64().toBigInt();
-                    break;
                 default:
                     let u = options.readUnknownField;
                     if (u === "throw")
@@ -2945,37 +5659,13 @@ class Flamegraph$Type extends MessageType<Flamegraph> {
         }
         return message;
     }
-    internalBinaryWrite(message: Flamegraph, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.FlamegraphRootNode root = 1; */
-        if (message.root)
-            FlamegraphRootNode.internalBinaryWrite(message.root, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* int64 total = 2 [deprecated = true]; */
-        if (message.total !== 0n)
-            writer.tag(2, WireType.Varint).int64(message.total);
-        /* string unit = 3; */
+    internalBinaryWrite(message: QueryUnitsRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.QueryRequest query = 1; */
+        if (message.query)
+            QueryRequest.internalBinaryWrite(message.query, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* string unit = 2; */
         if (message.unit !== "")
-            writer.tag(3, WireType.LengthDelimited).string(message.unit);
-        /* int32 height = 4; */
-        if (message.height !== 0)
-            writer.tag(4, WireType.Varint).int32(message.height);
-        /* repeated string string_table = 5; */
-        for (let i = 0; i < message.stringTable.length; i++)
-            writer.tag(5, WireType.LengthDelimited).string(message.stringTable[i]);
-        /* repeated parca.metastore.v1alpha1.Location locations = 6; */
-        for (let i = 0; i < message.locations.length; i++)
-            Location.internalBinaryWrite(message.locations[i], writer.tag(6, WireType.LengthDelimited).fork(), options).join();
-        /* repeated parca.metastore.v1alpha1.Mapping mapping = 7; */
-        for (let i = 0; i < message.mapping.length; i++)
-            Mapping.internalBinaryWrite(message.mapping[i], writer.tag(7, WireType.LengthDelimited).fork(), options).join();
-        /* repeated parca.metastore.v1alpha1.Function function = 8; */
-        for (let i = 0; i < message.function.length; i++)
-            Function.internalBinaryWrite(message.function[i], writer.tag(8, WireType.LengthDelimited).fork(), options).join();
-        /* int64 untrimmed_total = 9 [deprecated = true]; */
-        if (message.untrimmedTotal !== 0n)
-            writer.tag(9, WireType.Varint).int64(message.untrimmedTotal);
-        /* int64 trimmed = 10; */
-        if (message.trimmed !== 0n)
-            writer.tag(10, WireType.Varint).int64(message.trimmed);
+            writer.tag(2, WireType.LengthDelimited).string(message.unit);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -2983,45 +5673,50 @@ class Flamegraph$Type extends MessageType<Flamegraph> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.Flamegraph
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryUnitsRequest
  */
-export const Flamegraph = new Flamegraph$Type();
+export const QueryUnitsRequest = new QueryUnitsRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class FlamegraphArrow$Type extends MessageType<FlamegraphArrow> {
+class QueryUnitsResponse$Type extends MessageType<QueryUnitsResponse> {
     constructor() {
-        super("parca.query.v1alpha1.FlamegraphArrow", [
-            { no: 1, name: "record", kind: "scalar", T: 12 /*ScalarType.BYTES*/ },
+        super("parca.query.v1alpha1.QueryUnitsResponse", [
+            { no: 1, name: "source_unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
             { no: 2, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 3, name: "height", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
-            { no: 4, name: "trimmed", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+            { no: 3, name: "total", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 4, name: "reported", kind: "scalar", T: 5 /*ScalarType.INT32*/ },
+            { no: 5, name: "list", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => ConvertedTopNode }
         ]);
     }
-    create(value?: PartialMessage<FlamegraphArrow>): FlamegraphArrow {
+    create(value?: PartialMessage<QueryUnitsResponse>): QueryUnitsResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.record = new Uint8Array(0);
+        message.sourceUnit = "";
         message.unit = "";
-        message.height = 0;
-        message.trimmed = 0n;
+        message.total = 0;
+        message.reported = 0;
+        message.list = [];
         if (value !== undefined)
-            reflectionMergePartial<FlamegraphArrow>(this, message, value);
+            reflectionMergePartial<QueryUnitsResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FlamegraphArrow): FlamegraphArrow {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryUnitsResponse): QueryUnitsResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* bytes record */ 1:
-                    message.record = reader.bytes();
+                case /* string source_unit */ 1:
+                    message.sourceUnit = reader.string();
                     break;
                 case /* string unit */ 2:
                     message.unit = reader.string();
                     break;
-                case /* int32 height */ 3:
-                    message.height = reader.int32();
+                case /* double total */ 3:
+                    message.total = reader.double();
                     break;
-                case /* int64 trimmed */ 4:
-                    message.trimmed = reader.int64().toBigInt();
+                case /* int32 reported */ 4:
+                    message.reported = reader.int32();
+                    break;
+                case /* repeated parca.query.v1alpha1.ConvertedTopNode list */ 5:
+                    message.list.push(ConvertedTopNode.internalBinaryRead(reader, reader.uint32(), options));
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3034,19 +5729,22 @@ class FlamegraphArrow$Type extends MessageType<FlamegraphArrow> {
         }
         return message;
     }
-    internalBinaryWrite(message: FlamegraphArrow, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* bytes record = 1; */
-        if (message.record.length)
-            writer.tag(1, WireType.LengthDelimited).bytes(message.record);
+    internalBinaryWrite(message: QueryUnitsResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string source_unit = 1; */
+        if (message.sourceUnit !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.sourceUnit);
         /* string unit = 2; */
         if (message.unit !== "")
             writer.tag(2, WireType.LengthDelimited).string(message.unit);
-        /* int32 height = 3; */
-        if (message.height !== 0)
-            writer.tag(3, WireType.Varint).int32(message.height);
-        /* int64 trimmed = 4; */
-        if (message.trimmed !== 0n)
-            writer.tag(4, WireType.Varint).int64(message.trimmed);
+        /* double total = 3; */
+        if (message.total !== 0)
+            writer.tag(3, WireType.Bit64).double(message.total);
+        /* int32 reported = 4; */
+        if (message.reported !== 0)
+            writer.tag(4, WireType.Varint).int32(message.reported);
+        /* repeated parca.query.v1alpha1.ConvertedTopNode list = 5; */
+        for (let i = 0; i < message.list.length; i++)
+            ConvertedTopNode.internalBinaryWrite(message.list[i], writer.tag(5, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3054,40 +5752,44 @@ class FlamegraphArrow$Type extends MessageType<FlamegraphArrow> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.FlamegraphArrow
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryUnitsResponse
  */
-export const FlamegraphArrow = new FlamegraphArrow$Type();
+export const QueryUnitsResponse = new QueryUnitsResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class Source$Type extends MessageType<Source> {
+class ConvertedTopNode$Type extends MessageType<ConvertedTopNode> {
     constructor() {
-        super("parca.query.v1alpha1.Source", [
-            { no: 1, name: "record", kind: "scalar", T: 12 /*ScalarType.BYTES*/ },
-            { no: 2, name: "source", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 3, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.ConvertedTopNode", [
+            { no: 1, name: "meta", kind: "message", T: () => TopNodeMeta },
+            { no: 2, name: "cumulative", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 3, name: "flat", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 4, name: "diff", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ }
         ]);
     }
-    create(value?: PartialMessage<Source>): Source {
+    create(value?: PartialMessage<ConvertedTopNode>): ConvertedTopNode {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.record = new Uint8Array(0);
-        message.source = "";
-        message.unit = "";
+        message.cumulative = 0;
+        message.flat = 0;
+        message.diff = 0;
         if (value !== undefined)
-            reflectionMergePartial<Source>(this, message, value);
+            reflectionMergePartial<ConvertedTopNode>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Source): Source {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ConvertedTopNode): ConvertedTopNode {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* bytes record */ 1:
-                    message.record = reader.bytes();
+                case /* parca.query.v1alpha1.TopNodeMeta meta */ 1:
+                    message.meta = TopNodeMeta.internalBinaryRead(reader, reader.uint32(), options, message.meta);
                     break;
-                case /* string source */ 2:
-                    message.source = reader.string();
+                case /* double cumulative */ 2:
+                    message.cumulative = reader.double();
                     break;
-                case /* string unit */ 3:
-                    message.unit = reader.string();
+                case /* double flat */ 3:
+                    message.flat = reader.double();
+                    break;
+                case /* double diff */ 4:
+                    message.diff = reader.double();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3100,16 +5802,19 @@ class Source$Type extends MessageType<Source> {
         }
         return message;
     }
-    internalBinaryWrite(message: Source, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* bytes record = 1; */
-        if (message.record.length)
-            writer.tag(1, WireType.LengthDelimited).bytes(message.record);
-        /* string source = 2; */
-        if (message.source !== "")
-            writer.tag(2, WireType.LengthDelimited).string(message.source);
-        /* string unit = 3; */
-        if (message.unit !== "")
-            writer.tag(3, WireType.LengthDelimited).string(message.unit);
+    internalBinaryWrite(message: ConvertedTopNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.TopNodeMeta meta = 1; */
+        if (message.meta)
+            TopNodeMeta.internalBinaryWrite(message.meta, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* double cumulative = 2; */
+        if (message.cumulative !== 0)
+            writer.tag(2, WireType.Bit64).double(message.cumulative);
+        /* double flat = 3; */
+        if (message.flat !== 0)
+            writer.tag(3, WireType.Bit64).double(message.flat);
+        /* double diff = 4; */
+        if (message.diff !== 0)
+            writer.tag(4, WireType.Bit64).double(message.diff);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3117,40 +5822,34 @@ class Source$Type extends MessageType<Source> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.Source
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ConvertedTopNode
  */
-export const Source = new Source$Type();
+export const ConvertedTopNode = new ConvertedTopNode$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class FlamegraphRootNode$Type extends MessageType<FlamegraphRootNode> {
+class QueryRangeUnitsRequest$Type extends MessageType<QueryRangeUnitsRequest> {
     constructor() {
-        super("parca.query.v1alpha1.FlamegraphRootNode", [
-            { no: 1, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 2, name: "diff", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 3, name: "children", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => FlamegraphNode }
+        super("parca.query.v1alpha1.QueryRangeUnitsRequest", [
+            { no: 1, name: "query", kind: "message", T: () => QueryRangeRequest },
+            { no: 2, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<FlamegraphRootNode>): FlamegraphRootNode {
+    create(value?: PartialMessage<QueryRangeUnitsRequest>): QueryRangeUnitsRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.cumulative = 0n;
-        message.diff = 0n;
-        message.children = [];
+        message.unit = "";
         if (value !== undefined)
-            reflectionMergePartial<FlamegraphRootNode>(this, message, value);
+            reflectionMergePartial<QueryRangeUnitsRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FlamegraphRootNode): FlamegraphRootNode {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRangeUnitsRequest): QueryRangeUnitsRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* int64 cumulative */ 1:
-                    message.cumulative = reader.int64().toBigInt();
-                    break;
-                case /* int64 diff */ 2:
-                    message.diff = reader.int64().toBigInt();
+                case /* parca.query.v1alpha1.QueryRangeRequest query */ 1:
+                    message.query = QueryRangeRequest.internalBinaryRead(reader, reader.uint32(), options, message.query);
                     break;
-                case /* repeated parca.query.v1alpha1.FlamegraphNode children */ 3:
-                    message.children.push(FlamegraphNode.internalBinaryRead(reader, reader.uint32(), options));
+                case /* string unit */ 2:
+                    message.unit = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3159,20 +5858,17 @@ class FlamegraphRootNode$Type extends MessageType<FlamegraphRootNode> {
                     let d = reader.skip(wireType);
                     if (u !== false)
                         (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
-            }
-        }
-        return message;
-    }
-    internalBinaryWrite(message: FlamegraphRootNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* int64 cumulative = 1; */
-        if (message.cumulative !== 0n)
-            writer.tag(1, WireType.Varint).int64(message.cumulative);
-        /* int64 diff = 2; */
-        if (message.diff !== 0n)
-            writer.tag(2, WireType.Varint).int64(message.diff);
-        /* repeated parca.query.v1alpha1.FlamegraphNode children = 3; */
-        for (let i = 0; i < message.children.length; i++)
-            FlamegraphNode.internalBinaryWrite(message.children[i], writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+            }
+        }
+        return message;
+    }
+    internalBinaryWrite(message: QueryRangeUnitsRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.QueryRangeRequest query = 1; */
+        if (message.query)
+            QueryRangeRequest.internalBinaryWrite(message.query, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* string unit = 2; */
+        if (message.unit !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.unit);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3180,44 +5876,30 @@ class FlamegraphRootNode$Type extends MessageType<FlamegraphRootNode> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.FlamegraphRootNode
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRangeUnitsRequest
  */
-export const FlamegraphRootNode = new FlamegraphRootNode$Type();
+export const QueryRangeUnitsRequest = new QueryRangeUnitsRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class FlamegraphNode$Type extends MessageType<FlamegraphNode> {
+class QueryRangeUnitsResponse$Type extends MessageType<QueryRangeUnitsResponse> {
     constructor() {
-        super("parca.query.v1alpha1.FlamegraphNode", [
-            { no: 1, name: "meta", kind: "message", T: () => FlamegraphNodeMeta },
-            { no: 2, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 3, name: "diff", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 4, name: "children", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => FlamegraphNode }
+        super("parca.query.v1alpha1.QueryRangeUnitsResponse", [
+            { no: 1, name: "series", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => ConvertedMetricsSeries }
         ]);
     }
-    create(value?: PartialMessage<FlamegraphNode>): FlamegraphNode {
+    create(value?: PartialMessage<QueryRangeUnitsResponse>): QueryRangeUnitsResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.cumulative = 0n;
-        message.diff = 0n;
-        message.children = [];
+        message.series = [];
         if (value !== undefined)
-            reflectionMergePartial<FlamegraphNode>(this, message, value);
+            reflectionMergePartial<QueryRangeUnitsResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FlamegraphNode): FlamegraphNode {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryRangeUnitsResponse): QueryRangeUnitsResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.FlamegraphNodeMeta meta */ 1:
-                    message.meta = FlamegraphNodeMeta.internalBinaryRead(reader, reader.uint32(), options, message.meta);
-                    break;
-                case /* int64 cumulative */ 2:
-                    message.cumulative = reader.int64().toBigInt();
-                    break;
-                case /* int64 diff */ 3:
-                    message.diff = reader.int64().toBigInt();
-                    break;
-                case /* repeated parca.query.v1alpha1.FlamegraphNode children */ 4:
-                    message.children.push(FlamegraphNode.internalBinaryRead(reader, reader.uint32(), options));
+                case /* repeated parca.query.v1alpha1.ConvertedMetricsSeries series */ 1:
+                    message.series.push(ConvertedMetricsSeries.internalBinaryRead(reader, reader.uint32(), options));
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3230,19 +5912,10 @@ class FlamegraphNode$Type extends MessageType<FlamegraphNode> {
         }
         return message;
     }
-    internalBinaryWrite(message: FlamegraphNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.FlamegraphNodeMeta meta = 1; */
-        if (message.meta)
-            FlamegraphNodeMeta.internalBinaryWrite(message.meta, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* int64 cumulative = 2; */
-        if (message.cumulative !== 0n)
-            writer.tag(2, WireType.Varint).int64(message.cumulative);
-        /* int64 diff = 3; */
-        if (message.diff !== 0n)
-            writer.tag(3, WireType.Varint).int64(message.diff);
-        /* repeated parca.query.v1alpha1.FlamegraphNode children = 4; */
-        for (let i = 0; i < message.children.length; i++)
-            FlamegraphNode.internalBinaryWrite(message.children[i], writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: QueryRangeUnitsResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.ConvertedMetricsSeries series = 1; */
+        for (let i = 0; i < message.series.length; i++)
+            ConvertedMetricsSeries.internalBinaryWrite(message.series[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3250,51 +5923,47 @@ class FlamegraphNode$Type extends MessageType<FlamegraphNode> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.FlamegraphNode
+ * @generated MessageType for protobuf message parca.query.v1alpha1.QueryRangeUnitsResponse
  */
-export const FlamegraphNode = new FlamegraphNode$Type();
+export const QueryRangeUnitsResponse = new QueryRangeUnitsResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class FlamegraphNodeMeta$Type extends MessageType<FlamegraphNodeMeta> {
+class ConvertedMetricsSeries$Type extends MessageType<ConvertedMetricsSeries> {
     constructor() {
-        super("parca.query.v1alpha1.FlamegraphNodeMeta", [
-            { no: 1, name: "location", kind: "message", T: () => Location },
-            { no: 2, name: "mapping", kind: "message", T: () => Mapping },
-            { no: 3, name: "function", kind: "message", T: () => Function },
-            { no: 4, name: "line", kind: "message", T: () => Line },
-            { no: 5, name: "location_index", kind: "scalar", T: 13 /*ScalarType.UINT32*/ },
-            { no: 6, name: "line_index", kind: "scalar", T: 13 /*ScalarType.UINT32*/ }
+        super("parca.query.v1alpha1.ConvertedMetricsSeries", [
+            { no: 1, name: "labelset", kind: "message", T: () => LabelSet },
+            { no: 2, name: "samples", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => ConvertedMetricsSample },
+            { no: 3, name: "period_type", kind: "message", T: () => ValueType },
+            { no: 4, name: "sample_type", kind: "message", T: () => ValueType },
+            { no: 5, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<FlamegraphNodeMeta>): FlamegraphNodeMeta {
+    create(value?: PartialMessage<ConvertedMetricsSeries>): ConvertedMetricsSeries {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.locationIndex = 0;
-        message.lineIndex = 0;
+        message.samples = [];
+        message.unit = "";
         if (value !== undefined)
-            reflectionMergePartial<FlamegraphNodeMeta>(this, message, value);
+            reflectionMergePartial<ConvertedMetricsSeries>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FlamegraphNodeMeta): FlamegraphNodeMeta {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ConvertedMetricsSeries): ConvertedMetricsSeries {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.metastore.v1alpha1.Location location */ 1:
-                    message.location = Location.internalBinaryRead(reader, reader.uint32(), options, message.location);
-                    break;
-                case /* parca.metastore.v1alpha1.Mapping mapping */ 2:
-                    message.mapping = Mapping.internalBinaryRead(reader, reader.uint32(), options, message.mapping);
+                case /* parca.profilestore.v1alpha1.LabelSet labelset */ 1:
+                    message.labelset = LabelSet.internalBinaryRead(reader, reader.uint32(), options, message.labelset);
                     break;
-                case /* parca.metastore.v1alpha1.Function function */ 3:
-                    message.function = Function.internalBinaryRead(reader, reader.uint32(), options, message.function);
+                case /* repeated parca.query.v1alpha1.ConvertedMetricsSample samples */ 2:
+                    message.samples.push(ConvertedMetricsSample.internalBinaryRead(reader, reader.uint32(), options));
                     break;
-                case /* parca.metastore.v1alpha1.Line line */ 4:
-                    message.line = Line.internalBinaryRead(reader, reader.uint32(), options, message.line);
+                case /* parca.query.v1alpha1.ValueType period_type */ 3:
+                    message.periodType = ValueType.internalBinaryRead(reader, reader.uint32(), options, message.periodType);
                     break;
-                case /* uint32 location_index */ 5:
-                    message.locationIndex = reader.uint32();
+                case /* parca.query.v1alpha1.ValueType sample_type */ 4:
+                    message.sampleType = ValueType.internalBinaryRead(reader, reader.uint32(), options, message.sampleType);
                     break;
-                case /* uint32 line_index */ 6:
-                    message.lineIndex = reader.uint32();
+                case /* string unit */ 5:
+                    message.unit = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3307,25 +5976,22 @@ class FlamegraphNodeMeta$Type extends MessageType<FlamegraphNodeMeta> {
         }
         return message;
     }
-    internalBinaryWrite(message: FlamegraphNodeMeta, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.metastore.v1alpha1.Location location = 1; */
-        if (message.location)
-            Location.internalBinaryWrite(message.location, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Mapping mapping = 2; */
-        if (message.mapping)
-            Mapping.internalBinaryWrite(message.mapping, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Function function = 3; */
-        if (message.function)
-            Function.internalBinaryWrite(message.function, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Line line = 4; */
-        if (message.line)
-            Line.internalBinaryWrite(message.line, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
-        /* uint32 location_index = 5; */
-        if (message.locationIndex !== 0)
-            writer.tag(5, WireType.Varint).uint32(message.locationIndex);
-        /* uint32 line_index = 6; */
-        if (message.lineIndex !== 0)
-            writer.tag(6, WireType.Varint).uint32(message.lineIndex);
+    internalBinaryWrite(message: ConvertedMetricsSeries, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.profilestore.v1alpha1.LabelSet labelset = 1; */
+        if (message.labelset)
+            LabelSet.internalBinaryWrite(message.labelset, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* repeated parca.query.v1alpha1.ConvertedMetricsSample samples = 2; */
+        for (let i = 0; i < message.samples.length; i++)
+            ConvertedMetricsSample.internalBinaryWrite(message.samples[i], writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.ValueType period_type = 3; */
+        if (message.periodType)
+            ValueType.internalBinaryWrite(message.periodType, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* parca.query.v1alpha1.ValueType sample_type = 4; */
+        if (message.sampleType)
+            ValueType.internalBinaryWrite(message.sampleType, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        /* string unit = 5; */
+        if (message.unit !== "")
+            writer.tag(5, WireType.LengthDelimited).string(message.unit);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3333,44 +5999,44 @@ class FlamegraphNodeMeta$Type extends MessageType<FlamegraphNodeMeta> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.FlamegraphNodeMeta
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ConvertedMetricsSeries
  */
-export const FlamegraphNodeMeta = new FlamegraphNodeMeta$Type();
+export const ConvertedMetricsSeries = new ConvertedMetricsSeries$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class CallgraphNode$Type extends MessageType<CallgraphNode> {
+class ConvertedMetricsSample$Type extends MessageType<ConvertedMetricsSample> {
     constructor() {
-        super("parca.query.v1alpha1.CallgraphNode", [
-            { no: 1, name: "id", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "meta", kind: "message", T: () => CallgraphNodeMeta },
-            { no: 3, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 4, name: "flat", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        super("parca.query.v1alpha1.ConvertedMetricsSample", [
+            { no: 1, name: "timestamp", kind: "message", T: () => Timestamp },
+            { no: 2, name: "value", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 3, name: "value_per_second", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 4, name: "duration", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
         ]);
     }
-    create(value?: PartialMessage<CallgraphNode>): CallgraphNode {
+    create(value?: PartialMessage<ConvertedMetricsSample>): ConvertedMetricsSample {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.id = "";
-        message.cumulative = 0n;
-        message.flat = 0n;
+        message.value = 0;
+        message.valuePerSecond = 0;
+        message.duration = 0n;
         if (value !== undefined)
-            reflectionMergePartial<CallgraphNode>(this, message, value);
+            reflectionMergePartial<ConvertedMetricsSample>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CallgraphNode): CallgraphNode {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ConvertedMetricsSample): ConvertedMetricsSample {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* string id */ 1:
-                    message.id = reader.string();
+                case /* google.protobuf.Timestamp timestamp */ 1:
+                    message.timestamp = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.timestamp);
                     break;
-                case /* parca.query.v1alpha1.CallgraphNodeMeta meta */ 2:
-                    message.meta = CallgraphNodeMeta.internalBinaryRead(reader, reader.uint32(), options, message.meta);
+                case /* double value */ 2:
+                    message.value = reader.double();
                     break;
-                case /* int64 cumulative */ 3:
-                    message.cumulative = reader.int64().toBigInt();
+                case /* double value_per_second */ 3:
+                    message.valuePerSecond = reader.double();
                     break;
-                case /* int64 flat */ 4:
-                    message.flat = reader.int64().toBigInt();
+                case /* int64 duration */ 4:
+                    message.duration = reader.int64().toBigInt();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3383,19 +6049,19 @@ class CallgraphNode$Type extends MessageType<CallgraphNode> {
         }
         return message;
     }
-    internalBinaryWrite(message: CallgraphNode, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string id = 1; */
-        if (message.id !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.id);
-        /* parca.query.v1alpha1.CallgraphNodeMeta meta = 2; */
-        if (message.meta)
-            CallgraphNodeMeta.internalBinaryWrite(message.meta, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* int64 cumulative = 3; */
-        if (message.cumulative !== 0n)
-            writer.tag(3, WireType.Varint).int64(message.cumulative);
-        /* int64 flat = 4; */
-        if (message.flat !== 0n)
-            writer.tag(4, WireType.Varint).int64(message.flat);
+    internalBinaryWrite(message: ConvertedMetricsSample, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* google.protobuf.Timestamp timestamp = 1; */
+        if (message.timestamp)
+            Timestamp.internalBinaryWrite(message.timestamp, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* double value = 2; */
+        if (message.value !== 0)
+            writer.tag(2, WireType.Bit64).double(message.value);
+        /* double value_per_second = 3; */
+        if (message.valuePerSecond !== 0)
+            writer.tag(3, WireType.Bit64).double(message.valuePerSecond);
+        /* int64 duration = 4; */
+        if (message.duration !== 0n)
+            writer.tag(4, WireType.Varint).int64(message.duration);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3403,41 +6069,38 @@ class CallgraphNode$Type extends MessageType<CallgraphNode> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.CallgraphNode
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ConvertedMetricsSample
  */
-export const CallgraphNode = new CallgraphNode$Type();
+export const ConvertedMetricsSample = new ConvertedMetricsSample$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class CallgraphNodeMeta$Type extends MessageType<CallgraphNodeMeta> {
+class ProfileCaptureMetadataRequest$Type extends MessageType<ProfileCaptureMetadataRequest> {
     constructor() {
-        super("parca.query.v1alpha1.CallgraphNodeMeta", [
-            { no: 1, name: "location", kind: "message", T: () => Location },
-            { no: 2, name: "mapping", kind: "message", T: () => Mapping },
-            { no: 3, name: "function", kind: "message", T: () => Function },
-            { no: 4, name: "line", kind: "message", T: () => Line }
+        super("parca.query.v1alpha1.ProfileCaptureMetadataRequest", [
+            { no: 1, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "start", kind: "message", T: () => Timestamp },
+            { no: 3, name: "end", kind: "message", T: () => Timestamp }
         ]);
     }
-    create(value?: PartialMessage<CallgraphNodeMeta>): CallgraphNodeMeta {
+    create(value?: PartialMessage<ProfileCaptureMetadataRequest>): ProfileCaptureMetadataRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
+        message.query = "";
         if (value !== undefined)
-            reflectionMergePartial<CallgraphNodeMeta>(this, message, value);
+            reflectionMergePartial<ProfileCaptureMetadataRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CallgraphNodeMeta): CallgraphNodeMeta {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileCaptureMetadataRequest): ProfileCaptureMetadataRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.metastore.v1alpha1.Location location */ 1:
-                    message.location = Location.internalBinaryRead(reader, reader.uint32(), options, message.location);
-                    break;
-                case /* parca.metastore.v1alpha1.Mapping mapping */ 2:
-                    message.mapping = Mapping.internalBinaryRead(reader, reader.uint32(), options, message.mapping);
+                case /* string query */ 1:
+                    message.query = reader.string();
                     break;
-                case /* parca.metastore.v1alpha1.Function function */ 3:
-                    message.function = Function.internalBinaryRead(reader, reader.uint32(), options, message.function);
+                case /* google.protobuf.Timestamp start */ 2:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
                     break;
-                case /* parca.metastore.v1alpha1.Line line */ 4:
-                    message.line = Line.internalBinaryRead(reader, reader.uint32(), options, message.line);
+                case /* google.protobuf.Timestamp end */ 3:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3450,19 +6113,16 @@ class CallgraphNodeMeta$Type extends MessageType<CallgraphNodeMeta> {
         }
         return message;
     }
-    internalBinaryWrite(message: CallgraphNodeMeta, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.metastore.v1alpha1.Location location = 1; */
-        if (message.location)
-            Location.internalBinaryWrite(message.location, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Mapping mapping = 2; */
-        if (message.mapping)
-            Mapping.internalBinaryWrite(message.mapping, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Function function = 3; */
-        if (message.function)
-            Function.internalBinaryWrite(message.function, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
-        /* parca.metastore.v1alpha1.Line line = 4; */
-        if (message.line)
-            Line.internalBinaryWrite(message.line, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: ProfileCaptureMetadataRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string query = 1; */
+        if (message.query !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.query);
+        /* google.protobuf.Timestamp start = 2; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 3; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3470,50 +6130,30 @@ class CallgraphNodeMeta$Type extends MessageType<CallgraphNodeMeta> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.CallgraphNodeMeta
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileCaptureMetadataRequest
  */
-export const CallgraphNodeMeta = new CallgraphNodeMeta$Type();
+export const ProfileCaptureMetadataRequest = new ProfileCaptureMetadataRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class CallgraphEdge$Type extends MessageType<CallgraphEdge> {
+class ProfileCaptureMetadataResponse$Type extends MessageType<ProfileCaptureMetadataResponse> {
     constructor() {
-        super("parca.query.v1alpha1.CallgraphEdge", [
-            { no: 1, name: "id", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "source", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 3, name: "target", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 4, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 5, name: "is_collapsed", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
+        super("parca.query.v1alpha1.ProfileCaptureMetadataResponse", [
+            { no: 1, name: "profiles", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => CaptureMetadata }
         ]);
     }
-    create(value?: PartialMessage<CallgraphEdge>): CallgraphEdge {
+    create(value?: PartialMessage<ProfileCaptureMetadataResponse>): ProfileCaptureMetadataResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.id = "";
-        message.source = "";
-        message.target = "";
-        message.cumulative = 0n;
-        message.isCollapsed = false;
+        message.profiles = [];
         if (value !== undefined)
-            reflectionMergePartial<CallgraphEdge>(this, message, value);
+            reflectionMergePartial<ProfileCaptureMetadataResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CallgraphEdge): CallgraphEdge {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileCaptureMetadataResponse): ProfileCaptureMetadataResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
-            let [fieldNo, wireType] = reader.tag();
-            switch (fieldNo) {
-                case /* string id */ 1:
-                    message.id = reader.string();
-                    break;
-                case /* string source */ 2:
-                    message.source = reader.string();
-                    break;
-                case /* string target */ 3:
-                    message.target = reader.string();
-                    break;
-                case /* int64 cumulative */ 4:
-                    message.cumulative = reader.int64().toBigInt();
-                    break;
-                case /* bool is_collapsed */ 5:
-                    message.isCollapsed = reader.bool();
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* repeated parca.query.v1alpha1.CaptureMetadata profiles */ 1:
+                    message.profiles.push(CaptureMetadata.internalBinaryRead(reader, reader.uint32(), options));
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3526,22 +6166,10 @@ class CallgraphEdge$Type extends MessageType<CallgraphEdge> {
         }
         return message;
     }
-    internalBinaryWrite(message: CallgraphEdge, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string id = 1; */
-        if (message.id !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.id);
-        /* string source = 2; */
-        if (message.source !== "")
-            writer.tag(2, WireType.LengthDelimited).string(message.source);
-        /* string target = 3; */
-        if (message.target !== "")
-            writer.tag(3, WireType.LengthDelimited).string(message.target);
-        /* int64 cumulative = 4; */
-        if (message.cumulative !== 0n)
-            writer.tag(4, WireType.Varint).int64(message.cumulative);
-        /* bool is_collapsed = 5; */
-        if (message.isCollapsed !== false)
-            writer.tag(5, WireType.Varint).bool(message.isCollapsed);
+    internalBinaryWrite(message: ProfileCaptureMetadataResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.CaptureMetadata profiles = 1; */
+        for (let i = 0; i < message.profiles.length; i++)
+            CaptureMetadata.internalBinaryWrite(message.profiles[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3549,40 +6177,38 @@ class CallgraphEdge$Type extends MessageType<CallgraphEdge> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.CallgraphEdge
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileCaptureMetadataResponse
  */
-export const CallgraphEdge = new CallgraphEdge$Type();
+export const ProfileCaptureMetadataResponse = new ProfileCaptureMetadataResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class Callgraph$Type extends MessageType<Callgraph> {
+class CaptureMetadata$Type extends MessageType<CaptureMetadata> {
     constructor() {
-        super("parca.query.v1alpha1.Callgraph", [
-            { no: 1, name: "nodes", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => CallgraphNode },
-            { no: 2, name: "edges", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => CallgraphEdge },
-            { no: 3, name: "cumulative", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        super("parca.query.v1alpha1.CaptureMetadata", [
+            { no: 1, name: "labels", kind: "message", T: () => LabelSet },
+            { no: 2, name: "timestamp", kind: "message", T: () => Timestamp },
+            { no: 3, name: "metadata", kind: "map", K: 9 /*ScalarType.STRING*/, V: { kind: "scalar", T: 9 /*ScalarType.STRING*/ } }
         ]);
     }
-    create(value?: PartialMessage<Callgraph>): Callgraph {
+    create(value?: PartialMessage<CaptureMetadata>): CaptureMetadata {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.nodes = [];
-        message.edges = [];
-        message.cumulative = 0n;
+        message.metadata = {};
         if (value !== undefined)
-            reflectionMergePartial<Callgraph>(this, message, value);
+            reflectionMergePartial<CaptureMetadata>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Callgraph): Callgraph {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: CaptureMetadata): CaptureMetadata {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated parca.query.v1alpha1.CallgraphNode nodes */ 1:
-                    message.nodes.push(CallgraphNode.internalBinaryRead(reader, reader.uint32(), options));
+                case /* parca.profilestore.v1alpha1.LabelSet labels */ 1:
+                    message.labels = LabelSet.internalBinaryRead(reader, reader.uint32(), options, message.labels);
                     break;
-                case /* repeated parca.query.v1alpha1.CallgraphEdge edges */ 2:
-                    message.edges.push(CallgraphEdge.internalBinaryRead(reader, reader.uint32(), options));
+                case /* google.protobuf.Timestamp timestamp */ 2:
+                    message.timestamp = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.timestamp);
                     break;
-                case /* int64 cumulative = 3 [deprecated = true];*/ 3:
-                    message.cumulative = reader.int64().toBigInt();
+                case /* map<string, string> metadata */ 3:
+                    this.binaryReadMap3(message.metadata, reader, options);
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3595,16 +6221,32 @@ class Callgraph$Type extends MessageType<Callgraph> {
         }
         return message;
     }
-    internalBinaryWrite(message: Callgraph, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated parca.query.v1alpha1.CallgraphNode nodes = 1; */
-        for (let i = 0; i < message.nodes.length; i++)
-            CallgraphNode.internalBinaryWrite(message.nodes[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* repeated parca.query.v1alpha1.CallgraphEdge edges = 2; */
-        for (let i = 0; i < message.edges.length; i++)
-            CallgraphEdge.internalBinaryWrite(message.edges[i], writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* int64 cumulative = 3 [deprecated = true]; */
-        if (message.cumulative !== 0n)
-            writer.tag(3, WireType.Varint).int64(message.cumulative);
+    private binaryReadMap3(map: CaptureMetadata["metadata"], reader: IBinaryReader, options: BinaryReadOptions): void {
+        let len = reader.uint32(), end = reader.pos + len, key: keyof CaptureMetadata["metadata"] | undefined, val: CaptureMetadata["metadata"][any] | undefined;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case 1:
+                    key = reader.string();
+                    break;
+                case 2:
+                    val = reader.string();
+                    break;
+                default: throw new globalThis.Error("unknown map entry field for field parca.query.v1alpha1.CaptureMetadata.metadata");
+            }
+        }
+        map[key ?? ""] = val ?? "";
+    }
+    internalBinaryWrite(message: CaptureMetadata, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.profilestore.v1alpha1.LabelSet labels = 1; */
+        if (message.labels)
+            LabelSet.internalBinaryWrite(message.labels, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp timestamp = 2; */
+        if (message.timestamp)
+            Timestamp.internalBinaryWrite(message.timestamp, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* map<string, string> metadata = 3; */
+        for (let k of globalThis.Object.keys(message.metadata))
+            writer.tag(3, WireType.LengthDelimited).fork().tag(1, WireType.LengthDelimited).string(k).tag(2, WireType.LengthDelimited).string(message.metadata[k]).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3612,92 +6254,49 @@ class Callgraph$Type extends MessageType<Callgraph> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.Callgraph
+ * @generated MessageType for protobuf message parca.query.v1alpha1.CaptureMetadata
  */
-export const Callgraph = new Callgraph$Type();
+export const CaptureMetadata = new CaptureMetadata$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class QueryResponse$Type extends MessageType<QueryResponse> {
+class DiffSignificanceRequest$Type extends MessageType<DiffSignificanceRequest> {
     constructor() {
-        super("parca.query.v1alpha1.QueryResponse", [
-            { no: 5, name: "flamegraph", kind: "message", oneof: "report", T: () => Flamegraph },
-            { no: 6, name: "pprof", kind: "scalar", oneof: "report", T: 12 /*ScalarType.BYTES*/ },
-            { no: 7, name: "top", kind: "message", oneof: "report", T: () => Top },
-            { no: 8, name: "callgraph", kind: "message", oneof: "report", T: () => Callgraph },
-            { no: 11, name: "flamegraph_arrow", kind: "message", oneof: "report", T: () => FlamegraphArrow },
-            { no: 12, name: "source", kind: "message", oneof: "report", T: () => Source },
-            { no: 13, name: "table_arrow", kind: "message", oneof: "report", T: () => TableArrow },
-            { no: 14, name: "profile_metadata", kind: "message", oneof: "report", T: () => ProfileMetadata },
-            { no: 9, name: "total", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
-            { no: 10, name: "filtered", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
+        super("parca.query.v1alpha1.DiffSignificanceRequest", [
+            { no: 1, name: "diff", kind: "message", T: () => DiffProfile },
+            { no: 2, name: "windows", kind: "scalar", T: 13 /*ScalarType.UINT32*/ },
+            { no: 3, name: "confidence", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 4, name: "limit", kind: "scalar", T: 13 /*ScalarType.UINT32*/ },
+            { no: 5, name: "significant_only", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
         ]);
     }
-    create(value?: PartialMessage<QueryResponse>): QueryResponse {
+    create(value?: PartialMessage<DiffSignificanceRequest>): DiffSignificanceRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.report = { oneofKind: undefined };
-        message.total = 0n;
-        message.filtered = 0n;
+        message.windows = 0;
+        message.confidence = 0;
+        message.limit = 0;
+        message.significantOnly = false;
         if (value !== undefined)
-            reflectionMergePartial<QueryResponse>(this, message, value);
+            reflectionMergePartial<DiffSignificanceRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: QueryResponse): QueryResponse {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: DiffSignificanceRequest): DiffSignificanceRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.Flamegraph flamegraph */ 5:
-                    message.report = {
-                        oneofKind: "flamegraph",
-                        flamegraph: Flamegraph.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).flamegraph)
-                    };
-                    break;
-                case /* bytes pprof */ 6:
-                    message.report = {
-                        oneofKind: "pprof",
-                        pprof: reader.bytes()
-                    };
-                    break;
-                case /* parca.query.v1alpha1.Top top */ 7:
-                    message.report = {
-                        oneofKind: "top",
-                        top: Top.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).top)
-                    };
+                case /* parca.query.v1alpha1.DiffProfile diff */ 1:
+                    message.diff = DiffProfile.internalBinaryRead(reader, reader.uint32(), options, message.diff);
                     break;
-                case /* parca.query.v1alpha1.Callgraph callgraph */ 8:
-                    message.report = {
-                        oneofKind: "callgraph",
-                        callgraph: Callgraph.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).callgraph)
-                    };
-                    break;
-                case /* parca.query.v1alpha1.FlamegraphArrow flamegraph_arrow */ 11:
-                    message.report = {
-                        oneofKind: "flamegraphArrow",
-                        flamegraphArrow: FlamegraphArrow.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).flamegraphArrow)
-                    };
-                    break;
-                case /* parca.query.v1alpha1.Source source */ 12:
-                    message.report = {
-                        oneofKind: "source",
-                        source: Source.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).source)
-                    };
-                    break;
-                case /* parca.query.v1alpha1.TableArrow table_arrow */ 13:
-                    message.report = {
-                        oneofKind: "tableArrow",
-                        tableArrow: TableArrow.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).tableArrow)
-                    };
+                case /* uint32 windows */ 2:
+                    message.windows = reader.uint32();
                     break;
-                case /* parca.query.v1alpha1.ProfileMetadata profile_metadata */ 14:
-                    message.report = {
-                        oneofKind: "profileMetadata",
-                        profileMetadata: ProfileMetadata.internalBinaryRead(reader, reader.uint32(), options, (message.report as any).profileMetadata)
-                    };
+                case /* double confidence */ 3:
+                    message.confidence = reader.double();
                     break;
-                case /* int64 total */ 9:
-                    message.total = reader.int64().toBigInt();
+                case /* uint32 limit */ 4:
+                    message.limit = reader.uint32();
                     break;
-                case /* int64 filtered */ 10:
-                    message.filtered = reader.int64().toBigInt();
+                case /* bool significant_only */ 5:
+                    message.significantOnly = reader.bool();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3710,37 +6309,22 @@ class QueryResponse$Type extends MessageType<QueryResponse> {
         }
         return message;
     }
-    internalBinaryWrite(message: QueryResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.Flamegraph flamegraph = 5; */
-        if (message.report.oneofKind === "flamegraph")
-            Flamegraph.internalBinaryWrite(message.report.flamegraph, writer.tag(5, WireType.LengthDelimited).fork(), options).join();
-        /* bytes pprof = 6; */
-        if (message.report.oneofKind === "pprof")
-            writer.tag(6, WireType.LengthDelimited).bytes(message.report.pprof);
-        /* parca.query.v1alpha1.Top top = 7; */
-        if (message.report.oneofKind === "top")
-            Top.internalBinaryWrite(message.report.top, writer.tag(7, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.Callgraph callgraph = 8; */
-        if (message.report.oneofKind === "callgraph")
-            Callgraph.internalBinaryWrite(message.report.callgraph, writer.tag(8, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.FlamegraphArrow flamegraph_arrow = 11; */
-        if (message.report.oneofKind === "flamegraphArrow")
-            FlamegraphArrow.internalBinaryWrite(message.report.flamegraphArrow, writer.tag(11, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.Source source = 12; */
-        if (message.report.oneofKind === "source")
-            Source.internalBinaryWrite(message.report.source, writer.tag(12, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.TableArrow table_arrow = 13; */
-        if (message.report.oneofKind === "tableArrow")
-            TableArrow.internalBinaryWrite(message.report.tableArrow, writer.tag(13, WireType.LengthDelimited).fork(), options).join();
-        /* parca.query.v1alpha1.ProfileMetadata profile_metadata = 14; */
-        if (message.report.oneofKind === "profileMetadata")
-            ProfileMetadata.internalBinaryWrite(message.report.profileMetadata, writer.tag(14, WireType.LengthDelimited).fork(), options).join();
-        /* int64 total = 9; */
-        if (message.total !== 0n)
-            writer.tag(9, WireType.Varint).int64(message.total);
-        /* int64 filtered = 10; */
-        if (message.filtered !== 0n)
-            writer.tag(10, WireType.Varint).int64(message.filtered);
+    internalBinaryWrite(message: DiffSignificanceRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* parca.query.v1alpha1.DiffProfile diff = 1; */
+        if (message.diff)
+            DiffProfile.internalBinaryWrite(message.diff, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
+        /* uint32 windows = 2; */
+        if (message.windows !== 0)
+            writer.tag(2, WireType.Varint).uint32(message.windows);
+        /* double confidence = 3; */
+        if (message.confidence !== 0)
+            writer.tag(3, WireType.Bit64).double(message.confidence);
+        /* uint32 limit = 4; */
+        if (message.limit !== 0)
+            writer.tag(4, WireType.Varint).uint32(message.limit);
+        /* bool significant_only = 5; */
+        if (message.significantOnly !== false)
+            writer.tag(5, WireType.Varint).bool(message.significantOnly);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3748,38 +6332,30 @@ class QueryResponse$Type extends MessageType<QueryResponse> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.QueryResponse
+ * @generated MessageType for protobuf message parca.query.v1alpha1.DiffSignificanceRequest
  */
-export const QueryResponse = new QueryResponse$Type();
+export const DiffSignificanceRequest = new DiffSignificanceRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class SeriesRequest$Type extends MessageType<SeriesRequest> {
+class DiffSignificanceResponse$Type extends MessageType<DiffSignificanceResponse> {
     constructor() {
-        super("parca.query.v1alpha1.SeriesRequest", [
-            { no: 1, name: "match", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "start", kind: "message", T: () => Timestamp },
-            { no: 3, name: "end", kind: "message", T: () => Timestamp }
+        super("parca.query.v1alpha1.DiffSignificanceResponse", [
+            { no: 1, name: "functions", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => FunctionSignificance }
         ]);
     }
-    create(value?: PartialMessage<SeriesRequest>): SeriesRequest {
+    create(value?: PartialMessage<DiffSignificanceResponse>): DiffSignificanceResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.match = [];
+        message.functions = [];
         if (value !== undefined)
-            reflectionMergePartial<SeriesRequest>(this, message, value);
+            reflectionMergePartial<DiffSignificanceResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SeriesRequest): SeriesRequest {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: DiffSignificanceResponse): DiffSignificanceResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated string match */ 1:
-                    message.match.push(reader.string());
-                    break;
-                case /* google.protobuf.Timestamp start */ 2:
-                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
-                    break;
-                case /* google.protobuf.Timestamp end */ 3:
-                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                case /* repeated parca.query.v1alpha1.FunctionSignificance functions */ 1:
+                    message.functions.push(FunctionSignificance.internalBinaryRead(reader, reader.uint32(), options));
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3792,16 +6368,10 @@ class SeriesRequest$Type extends MessageType<SeriesRequest> {
         }
         return message;
     }
-    internalBinaryWrite(message: SeriesRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated string match = 1; */
-        for (let i = 0; i < message.match.length; i++)
-            writer.tag(1, WireType.LengthDelimited).string(message.match[i]);
-        /* google.protobuf.Timestamp start = 2; */
-        if (message.start)
-            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
-        /* google.protobuf.Timestamp end = 3; */
-        if (message.end)
-            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+    internalBinaryWrite(message: DiffSignificanceResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.FunctionSignificance functions = 1; */
+        for (let i = 0; i < message.functions.length; i++)
+            FunctionSignificance.internalBinaryWrite(message.functions[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3809,24 +6379,94 @@ class SeriesRequest$Type extends MessageType<SeriesRequest> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.SeriesRequest
+ * @generated MessageType for protobuf message parca.query.v1alpha1.DiffSignificanceResponse
  */
-export const SeriesRequest = new SeriesRequest$Type();
+export const DiffSignificanceResponse = new DiffSignificanceResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class SeriesResponse$Type extends MessageType<SeriesResponse> {
+class FunctionSignificance$Type extends MessageType<FunctionSignificance> {
     constructor() {
-        super("parca.query.v1alpha1.SeriesResponse", []);
+        super("parca.query.v1alpha1.FunctionSignificance", [
+            { no: 1, name: "function", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "share_a", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 3, name: "share_b", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 4, name: "delta", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 5, name: "low", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 6, name: "high", kind: "scalar", T: 1 /*ScalarType.DOUBLE*/ },
+            { no: 7, name: "significant", kind: "scalar", T: 8 /*ScalarType.BOOL*/ }
+        ]);
     }
-    create(value?: PartialMessage<SeriesResponse>): SeriesResponse {
+    create(value?: PartialMessage<FunctionSignificance>): FunctionSignificance {
         const message = globalThis.Object.create((this.messagePrototype!));
+        message.function = "";
+        message.shareA = 0;
+        message.shareB = 0;
+        message.delta = 0;
+        message.low = 0;
+        message.high = 0;
+        message.significant = false;
         if (value !== undefined)
-            reflectionMergePartial<SeriesResponse>(this, message, value);
+            reflectionMergePartial<FunctionSignificance>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: SeriesResponse): SeriesResponse {
-        return target ?? this.create();
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: FunctionSignificance): FunctionSignificance {
+        let message = target ?? this.create(), end = reader.pos + length;
+        while (reader.pos < end) {
+            let [fieldNo, wireType] = reader.tag();
+            switch (fieldNo) {
+                case /* string function */ 1:
+                    message.function = reader.string();
+                    break;
+                case /* double share_a */ 2:
+                    message.shareA = reader.double();
+                    break;
+                case /* double share_b */ 3:
+                    message.shareB = reader.double();
+                    break;
+                case /* double delta */ 4:
+                    message.delta = reader.double();
+                    break;
+                case /* double low */ 5:
+                    message.low = reader.double();
+                    break;
+                case /* double high */ 6:
+                    message.high = reader.double();
+                    break;
+                case /* bool significant */ 7:
+                    message.significant = reader.bool();
+                    break;
+                default:
+                    let u = options.readUnknownField;
+                    if (u === "throw")
+                        throw new globalThis.Error(`Unknown field ${fieldNo} (wire type ${wireType}) for ${this.typeName}`);
+                    let d = reader.skip(wireType);
+                    if (u !== false)
+                        (u === true ? UnknownFieldHandler.onRead : u)(this.typeName, message, fieldNo, wireType, d);
+            }
+        }
+        return message;
     }
-    internalBinaryWrite(message: SeriesResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+    internalBinaryWrite(message: FunctionSignificance, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string function = 1; */
+        if (message.function !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.function);
+        /* double share_a = 2; */
+        if (message.shareA !== 0)
+            writer.tag(2, WireType.Bit64).double(message.shareA);
+        /* double share_b = 3; */
+        if (message.shareB !== 0)
+            writer.tag(3, WireType.Bit64).double(message.shareB);
+        /* double delta = 4; */
+        if (message.delta !== 0)
+            writer.tag(4, WireType.Bit64).double(message.delta);
+        /* double low = 5; */
+        if (message.low !== 0)
+            writer.tag(5, WireType.Bit64).double(message.low);
+        /* double high = 6; */
+        if (message.high !== 0)
+            writer.tag(6, WireType.Bit64).double(message.high);
+        /* bool significant = 7; */
+        if (message.significant !== false)
+            writer.tag(7, WireType.Varint).bool(message.significant);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3834,33 +6474,36 @@ class SeriesResponse$Type extends MessageType<SeriesResponse> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.SeriesResponse
+ * @generated MessageType for protobuf message parca.query.v1alpha1.FunctionSignificance
  */
-export const SeriesResponse = new SeriesResponse$Type();
+export const FunctionSignificance = new FunctionSignificance$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class LabelsRequest$Type extends MessageType<LabelsRequest> {
+class TraceExemplarsRequest$Type extends MessageType<TraceExemplarsRequest> {
     constructor() {
-        super("parca.query.v1alpha1.LabelsRequest", [
-            { no: 1, name: "match", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+        super("parca.query.v1alpha1.TraceExemplarsRequest", [
+            { no: 1, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
             { no: 2, name: "start", kind: "message", T: () => Timestamp },
             { no: 3, name: "end", kind: "message", T: () => Timestamp },
-            { no: 4, name: "profile_type", kind: "scalar", opt: true, T: 9 /*ScalarType.STRING*/ }
+            { no: 4, name: "label", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 5, name: "limit", kind: "scalar", T: 13 /*ScalarType.UINT32*/ }
         ]);
     }
-    create(value?: PartialMessage<LabelsRequest>): LabelsRequest {
+    create(value?: PartialMessage<TraceExemplarsRequest>): TraceExemplarsRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.match = [];
+        message.query = "";
+        message.label = "";
+        message.limit = 0;
         if (value !== undefined)
-            reflectionMergePartial<LabelsRequest>(this, message, value);
+            reflectionMergePartial<TraceExemplarsRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: LabelsRequest): LabelsRequest {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TraceExemplarsRequest): TraceExemplarsRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated string match */ 1:
-                    message.match.push(reader.string());
+                case /* string query */ 1:
+                    message.query = reader.string();
                     break;
                 case /* google.protobuf.Timestamp start */ 2:
                     message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
@@ -3868,8 +6511,11 @@ class LabelsRequest$Type extends MessageType<LabelsRequest> {
                 case /* google.protobuf.Timestamp end */ 3:
                     message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
                     break;
-                case /* optional string profile_type */ 4:
-                    message.profileType = reader.string();
+                case /* string label */ 4:
+                    message.label = reader.string();
+                    break;
+                case /* uint32 limit */ 5:
+                    message.limit = reader.uint32();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3882,19 +6528,22 @@ class LabelsRequest$Type extends MessageType<LabelsRequest> {
         }
         return message;
     }
-    internalBinaryWrite(message: LabelsRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated string match = 1; */
-        for (let i = 0; i < message.match.length; i++)
-            writer.tag(1, WireType.LengthDelimited).string(message.match[i]);
+    internalBinaryWrite(message: TraceExemplarsRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string query = 1; */
+        if (message.query !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.query);
         /* google.protobuf.Timestamp start = 2; */
         if (message.start)
             Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
         /* google.protobuf.Timestamp end = 3; */
         if (message.end)
-            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
-        /* optional string profile_type = 4; */
-        if (message.profileType !== undefined)
-            writer.tag(4, WireType.LengthDelimited).string(message.profileType);
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* string label = 4; */
+        if (message.label !== "")
+            writer.tag(4, WireType.LengthDelimited).string(message.label);
+        /* uint32 limit = 5; */
+        if (message.limit !== 0)
+            writer.tag(5, WireType.Varint).uint32(message.limit);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3902,35 +6551,30 @@ class LabelsRequest$Type extends MessageType<LabelsRequest> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.LabelsRequest
+ * @generated MessageType for protobuf message parca.query.v1alpha1.TraceExemplarsRequest
  */
-export const LabelsRequest = new LabelsRequest$Type();
+export const TraceExemplarsRequest = new TraceExemplarsRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class LabelsResponse$Type extends MessageType<LabelsResponse> {
+class TraceExemplarsResponse$Type extends MessageType<TraceExemplarsResponse> {
     constructor() {
-        super("parca.query.v1alpha1.LabelsResponse", [
-            { no: 1, name: "label_names", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "warnings", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.TraceExemplarsResponse", [
+            { no: 1, name: "traces", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => TraceExemplar }
         ]);
     }
-    create(value?: PartialMessage<LabelsResponse>): LabelsResponse {
+    create(value?: PartialMessage<TraceExemplarsResponse>): TraceExemplarsResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.labelNames = [];
-        message.warnings = [];
+        message.traces = [];
         if (value !== undefined)
-            reflectionMergePartial<LabelsResponse>(this, message, value);
+            reflectionMergePartial<TraceExemplarsResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: LabelsResponse): LabelsResponse {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TraceExemplarsResponse): TraceExemplarsResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated string label_names */ 1:
-                    message.labelNames.push(reader.string());
-                    break;
-                case /* repeated string warnings */ 2:
-                    message.warnings.push(reader.string());
+                case /* repeated parca.query.v1alpha1.TraceExemplar traces */ 1:
+                    message.traces.push(TraceExemplar.internalBinaryRead(reader, reader.uint32(), options));
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -3943,13 +6587,10 @@ class LabelsResponse$Type extends MessageType<LabelsResponse> {
         }
         return message;
     }
-    internalBinaryWrite(message: LabelsResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated string label_names = 1; */
-        for (let i = 0; i < message.labelNames.length; i++)
-            writer.tag(1, WireType.LengthDelimited).string(message.labelNames[i]);
-        /* repeated string warnings = 2; */
-        for (let i = 0; i < message.warnings.length; i++)
-            writer.tag(2, WireType.LengthDelimited).string(message.warnings[i]);
+    internalBinaryWrite(message: TraceExemplarsResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.TraceExemplar traces = 1; */
+        for (let i = 0; i < message.traces.length; i++)
+            TraceExemplar.internalBinaryWrite(message.traces[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -3957,47 +6598,39 @@ class LabelsResponse$Type extends MessageType<LabelsResponse> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.LabelsResponse
+ * @generated MessageType for protobuf message parca.query.v1alpha1.TraceExemplarsResponse
  */
-export const LabelsResponse = new LabelsResponse$Type();
+export const TraceExemplarsResponse = new TraceExemplarsResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class ValuesRequest$Type extends MessageType<ValuesRequest> {
+class TraceExemplar$Type extends MessageType<TraceExemplar> {
     constructor() {
-        super("parca.query.v1alpha1.ValuesRequest", [
-            { no: 1, name: "label_name", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "match", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
-            { no: 3, name: "start", kind: "message", T: () => Timestamp },
-            { no: 4, name: "end", kind: "message", T: () => Timestamp },
-            { no: 5, name: "profile_type", kind: "scalar", opt: true, T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.TraceExemplar", [
+            { no: 1, name: "trace_id", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "value", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ },
+            { no: 3, name: "timestamp", kind: "message", T: () => Timestamp }
         ]);
     }
-    create(value?: PartialMessage<ValuesRequest>): ValuesRequest {
+    create(value?: PartialMessage<TraceExemplar>): TraceExemplar {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.labelName = "";
-        message.match = [];
+        message.traceId = "";
+        message.value = 0n;
         if (value !== undefined)
-            reflectionMergePartial<ValuesRequest>(this, message, value);
+            reflectionMergePartial<TraceExemplar>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ValuesRequest): ValuesRequest {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TraceExemplar): TraceExemplar {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* string label_name */ 1:
-                    message.labelName = reader.string();
-                    break;
-                case /* repeated string match */ 2:
-                    message.match.push(reader.string());
-                    break;
-                case /* google.protobuf.Timestamp start */ 3:
-                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                case /* string trace_id */ 1:
+                    message.traceId = reader.string();
                     break;
-                case /* google.protobuf.Timestamp end */ 4:
-                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                case /* int64 value */ 2:
+                    message.value = reader.int64().toBigInt();
                     break;
-                case /* optional string profile_type */ 5:
-                    message.profileType = reader.string();
+                case /* google.protobuf.Timestamp timestamp */ 3:
+                    message.timestamp = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.timestamp);
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -4010,22 +6643,16 @@ class ValuesRequest$Type extends MessageType<ValuesRequest> {
         }
         return message;
     }
-    internalBinaryWrite(message: ValuesRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string label_name = 1; */
-        if (message.labelName !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.labelName);
-        /* repeated string match = 2; */
-        for (let i = 0; i < message.match.length; i++)
-            writer.tag(2, WireType.LengthDelimited).string(message.match[i]);
-        /* google.protobuf.Timestamp start = 3; */
-        if (message.start)
-            Timestamp.internalBinaryWrite(message.start, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
-        /* google.protobuf.Timestamp end = 4; */
-        if (message.end)
-            Timestamp.internalBinaryWrite(message.end, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
-        /* optional string profile_type = 5; */
-        if (message.profileType !== undefined)
-            writer.tag(5, WireType.LengthDelimited).string(message.profileType);
+    internalBinaryWrite(message: TraceExemplar, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string trace_id = 1; */
+        if (message.traceId !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.traceId);
+        /* int64 value = 2; */
+        if (message.value !== 0n)
+            writer.tag(2, WireType.Varint).int64(message.value);
+        /* google.protobuf.Timestamp timestamp = 3; */
+        if (message.timestamp)
+            Timestamp.internalBinaryWrite(message.timestamp, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -4033,35 +6660,38 @@ class ValuesRequest$Type extends MessageType<ValuesRequest> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ValuesRequest
+ * @generated MessageType for protobuf message parca.query.v1alpha1.TraceExemplar
  */
-export const ValuesRequest = new ValuesRequest$Type();
+export const TraceExemplar = new TraceExemplar$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class ValuesResponse$Type extends MessageType<ValuesResponse> {
+class ProfileTypeCountsRequest$Type extends MessageType<ProfileTypeCountsRequest> {
     constructor() {
-        super("parca.query.v1alpha1.ValuesResponse", [
-            { no: 1, name: "label_values", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "warnings", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.ProfileTypeCountsRequest", [
+            { no: 1, name: "selector", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "start", kind: "message", T: () => Timestamp },
+            { no: 3, name: "end", kind: "message", T: () => Timestamp }
         ]);
     }
-    create(value?: PartialMessage<ValuesResponse>): ValuesResponse {
+    create(value?: PartialMessage<ProfileTypeCountsRequest>): ProfileTypeCountsRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.labelValues = [];
-        message.warnings = [];
+        message.selector = "";
         if (value !== undefined)
-            reflectionMergePartial<ValuesResponse>(this, message, value);
+            reflectionMergePartial<ProfileTypeCountsRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ValuesResponse): ValuesResponse {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileTypeCountsRequest): ProfileTypeCountsRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated string label_values */ 1:
-                    message.labelValues.push(reader.string());
+                case /* string selector */ 1:
+                    message.selector = reader.string();
                     break;
-                case /* repeated string warnings */ 2:
-                    message.warnings.push(reader.string());
+                case /* google.protobuf.Timestamp start */ 2:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                    break;
+                case /* google.protobuf.Timestamp end */ 3:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -4074,13 +6704,16 @@ class ValuesResponse$Type extends MessageType<ValuesResponse> {
         }
         return message;
     }
-    internalBinaryWrite(message: ValuesResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated string label_values = 1; */
-        for (let i = 0; i < message.labelValues.length; i++)
-            writer.tag(1, WireType.LengthDelimited).string(message.labelValues[i]);
-        /* repeated string warnings = 2; */
-        for (let i = 0; i < message.warnings.length; i++)
-            writer.tag(2, WireType.LengthDelimited).string(message.warnings[i]);
+    internalBinaryWrite(message: ProfileTypeCountsRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string selector = 1; */
+        if (message.selector !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.selector);
+        /* google.protobuf.Timestamp start = 2; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 3; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -4088,35 +6721,30 @@ class ValuesResponse$Type extends MessageType<ValuesResponse> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ValuesResponse
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileTypeCountsRequest
  */
-export const ValuesResponse = new ValuesResponse$Type();
+export const ProfileTypeCountsRequest = new ProfileTypeCountsRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class ValueType$Type extends MessageType<ValueType> {
+class ProfileTypeCountsResponse$Type extends MessageType<ProfileTypeCountsResponse> {
     constructor() {
-        super("parca.query.v1alpha1.ValueType", [
-            { no: 1, name: "type", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.ProfileTypeCountsResponse", [
+            { no: 1, name: "types", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => ProfileTypeCount }
         ]);
     }
-    create(value?: PartialMessage<ValueType>): ValueType {
+    create(value?: PartialMessage<ProfileTypeCountsResponse>): ProfileTypeCountsResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.type = "";
-        message.unit = "";
+        message.types = [];
         if (value !== undefined)
-            reflectionMergePartial<ValueType>(this, message, value);
+            reflectionMergePartial<ProfileTypeCountsResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ValueType): ValueType {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileTypeCountsResponse): ProfileTypeCountsResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* string type */ 1:
-                    message.type = reader.string();
-                    break;
-                case /* string unit */ 2:
-                    message.unit = reader.string();
+                case /* repeated parca.query.v1alpha1.ProfileTypeCount types */ 1:
+                    message.types.push(ProfileTypeCount.internalBinaryRead(reader, reader.uint32(), options));
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -4129,13 +6757,10 @@ class ValueType$Type extends MessageType<ValueType> {
         }
         return message;
     }
-    internalBinaryWrite(message: ValueType, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string type = 1; */
-        if (message.type !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.type);
-        /* string unit = 2; */
-        if (message.unit !== "")
-            writer.tag(2, WireType.LengthDelimited).string(message.unit);
+    internalBinaryWrite(message: ProfileTypeCountsResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.ProfileTypeCount types = 1; */
+        for (let i = 0; i < message.types.length; i++)
+            ProfileTypeCount.internalBinaryWrite(message.types[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -4143,33 +6768,39 @@ class ValueType$Type extends MessageType<ValueType> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ValueType
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileTypeCountsResponse
  */
-export const ValueType = new ValueType$Type();
+export const ProfileTypeCountsResponse = new ProfileTypeCountsResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class ShareProfileRequest$Type extends MessageType<ShareProfileRequest> {
+class ProfileTypeCount$Type extends MessageType<ProfileTypeCount> {
     constructor() {
-        super("parca.query.v1alpha1.ShareProfileRequest", [
-            { no: 1, name: "query_request", kind: "message", T: () => QueryRequest },
-            { no: 2, name: "description", kind: "scalar", opt: true, T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.ProfileTypeCount", [
+            { no: 1, name: "profile_type", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "type", kind: "message", T: () => ProfileType },
+            { no: 3, name: "samples", kind: "scalar", T: 3 /*ScalarType.INT64*/, L: 0 /*LongType.BIGINT*/ }
         ]);
     }
-    create(value?: PartialMessage<ShareProfileRequest>): ShareProfileRequest {
+    create(value?: PartialMessage<ProfileTypeCount>): ProfileTypeCount {
         const message = globalThis.Object.create((this.messagePrototype!));
+        message.profileType = "";
+        message.samples = 0n;
         if (value !== undefined)
-            reflectionMergePartial<ShareProfileRequest>(this, message, value);
+            reflectionMergePartial<ProfileTypeCount>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ShareProfileRequest): ShareProfileRequest {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileTypeCount): ProfileTypeCount {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* parca.query.v1alpha1.QueryRequest query_request */ 1:
-                    message.queryRequest = QueryRequest.internalBinaryRead(reader, reader.uint32(), options, message.queryRequest);
+                case /* string profile_type */ 1:
+                    message.profileType = reader.string();
                     break;
-                case /* optional string description */ 2:
-                    message.description = reader.string();
+                case /* parca.query.v1alpha1.ProfileType type */ 2:
+                    message.type = ProfileType.internalBinaryRead(reader, reader.uint32(), options, message.type);
+                    break;
+                case /* int64 samples */ 3:
+                    message.samples = reader.int64().toBigInt();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -4182,13 +6813,16 @@ class ShareProfileRequest$Type extends MessageType<ShareProfileRequest> {
         }
         return message;
     }
-    internalBinaryWrite(message: ShareProfileRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* parca.query.v1alpha1.QueryRequest query_request = 1; */
-        if (message.queryRequest)
-            QueryRequest.internalBinaryWrite(message.queryRequest, writer.tag(1, WireType.LengthDelimited).fork(), options).join();
-        /* optional string description = 2; */
-        if (message.description !== undefined)
-            writer.tag(2, WireType.LengthDelimited).string(message.description);
+    internalBinaryWrite(message: ProfileTypeCount, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string profile_type = 1; */
+        if (message.profileType !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.profileType);
+        /* parca.query.v1alpha1.ProfileType type = 2; */
+        if (message.type)
+            ProfileType.internalBinaryWrite(message.type, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* int64 samples = 3; */
+        if (message.samples !== 0n)
+            writer.tag(3, WireType.Varint).int64(message.samples);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -4196,30 +6830,43 @@ class ShareProfileRequest$Type extends MessageType<ShareProfileRequest> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ShareProfileRequest
+ * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileTypeCount
  */
-export const ShareProfileRequest = new ShareProfileRequest$Type();
+export const ProfileTypeCount = new ProfileTypeCount$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class ShareProfileResponse$Type extends MessageType<ShareProfileResponse> {
+class BinariesRequest$Type extends MessageType<BinariesRequest> {
     constructor() {
-        super("parca.query.v1alpha1.ShareProfileResponse", [
-            { no: 1, name: "link", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.BinariesRequest", [
+            { no: 1, name: "query", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "start", kind: "message", T: () => Timestamp },
+            { no: 3, name: "end", kind: "message", T: () => Timestamp },
+            { no: 4, name: "service_label", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
         ]);
     }
-    create(value?: PartialMessage<ShareProfileResponse>): ShareProfileResponse {
+    create(value?: PartialMessage<BinariesRequest>): BinariesRequest {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.link = "";
+        message.query = "";
+        message.serviceLabel = "";
         if (value !== undefined)
-            reflectionMergePartial<ShareProfileResponse>(this, message, value);
+            reflectionMergePartial<BinariesRequest>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ShareProfileResponse): ShareProfileResponse {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: BinariesRequest): BinariesRequest {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* string link */ 1:
-                    message.link = reader.string();
+                case /* string query */ 1:
+                    message.query = reader.string();
+                    break;
+                case /* google.protobuf.Timestamp start */ 2:
+                    message.start = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.start);
+                    break;
+                case /* google.protobuf.Timestamp end */ 3:
+                    message.end = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.end);
+                    break;
+                case /* string service_label */ 4:
+                    message.serviceLabel = reader.string();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -4232,10 +6879,19 @@ class ShareProfileResponse$Type extends MessageType<ShareProfileResponse> {
         }
         return message;
     }
-    internalBinaryWrite(message: ShareProfileResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* string link = 1; */
-        if (message.link !== "")
-            writer.tag(1, WireType.LengthDelimited).string(message.link);
+    internalBinaryWrite(message: BinariesRequest, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string query = 1; */
+        if (message.query !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.query);
+        /* google.protobuf.Timestamp start = 2; */
+        if (message.start)
+            Timestamp.internalBinaryWrite(message.start, writer.tag(2, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp end = 3; */
+        if (message.end)
+            Timestamp.internalBinaryWrite(message.end, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* string service_label = 4; */
+        if (message.serviceLabel !== "")
+            writer.tag(4, WireType.LengthDelimited).string(message.serviceLabel);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -4243,35 +6899,30 @@ class ShareProfileResponse$Type extends MessageType<ShareProfileResponse> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ShareProfileResponse
+ * @generated MessageType for protobuf message parca.query.v1alpha1.BinariesRequest
  */
-export const ShareProfileResponse = new ShareProfileResponse$Type();
+export const BinariesRequest = new BinariesRequest$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class TableArrow$Type extends MessageType<TableArrow> {
+class BinariesResponse$Type extends MessageType<BinariesResponse> {
     constructor() {
-        super("parca.query.v1alpha1.TableArrow", [
-            { no: 1, name: "record", kind: "scalar", T: 12 /*ScalarType.BYTES*/ },
-            { no: 2, name: "unit", kind: "scalar", T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.BinariesResponse", [
+            { no: 1, name: "binaries", kind: "message", repeat: 1 /*RepeatType.PACKED*/, T: () => Binary }
         ]);
     }
-    create(value?: PartialMessage<TableArrow>): TableArrow {
+    create(value?: PartialMessage<BinariesResponse>): BinariesResponse {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.record = new Uint8Array(0);
-        message.unit = "";
+        message.binaries = [];
         if (value !== undefined)
-            reflectionMergePartial<TableArrow>(this, message, value);
+            reflectionMergePartial<BinariesResponse>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: TableArrow): TableArrow {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: BinariesResponse): BinariesResponse {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* bytes record */ 1:
-                    message.record = reader.bytes();
-                    break;
-                case /* string unit */ 2:
-                    message.unit = reader.string();
+                case /* repeated parca.query.v1alpha1.Binary binaries */ 1:
+                    message.binaries.push(Binary.internalBinaryRead(reader, reader.uint32(), options));
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -4284,13 +6935,10 @@ class TableArrow$Type extends MessageType<TableArrow> {
         }
         return message;
     }
-    internalBinaryWrite(message: TableArrow, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* bytes record = 1; */
-        if (message.record.length)
-            writer.tag(1, WireType.LengthDelimited).bytes(message.record);
-        /* string unit = 2; */
-        if (message.unit !== "")
-            writer.tag(2, WireType.LengthDelimited).string(message.unit);
+    internalBinaryWrite(message: BinariesResponse, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* repeated parca.query.v1alpha1.Binary binaries = 1; */
+        for (let i = 0; i < message.binaries.length; i++)
+            Binary.internalBinaryWrite(message.binaries[i], writer.tag(1, WireType.LengthDelimited).fork(), options).join();
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -4298,35 +6946,53 @@ class TableArrow$Type extends MessageType<TableArrow> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.TableArrow
+ * @generated MessageType for protobuf message parca.query.v1alpha1.BinariesResponse
  */
-export const TableArrow = new TableArrow$Type();
+export const BinariesResponse = new BinariesResponse$Type();
 // @generated message type with reflection information, may provide speed optimized methods
-class ProfileMetadata$Type extends MessageType<ProfileMetadata> {
+class Binary$Type extends MessageType<Binary> {
     constructor() {
-        super("parca.query.v1alpha1.ProfileMetadata", [
-            { no: 1, name: "mapping_files", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
-            { no: 2, name: "labels", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ }
+        super("parca.query.v1alpha1.Binary", [
+            { no: 1, name: "build_id", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 2, name: "file", kind: "scalar", T: 9 /*ScalarType.STRING*/ },
+            { no: 3, name: "first_seen", kind: "message", T: () => Timestamp },
+            { no: 4, name: "last_seen", kind: "message", T: () => Timestamp },
+            { no: 5, name: "services", kind: "scalar", repeat: 2 /*RepeatType.UNPACKED*/, T: 9 /*ScalarType.STRING*/ },
+            { no: 6, name: "debuginfo_status", kind: "enum", T: () => ["parca.query.v1alpha1.Binary.DebuginfoStatus", Binary_DebuginfoStatus, "DEBUGINFO_STATUS_"] }
         ]);
     }
-    create(value?: PartialMessage<ProfileMetadata>): ProfileMetadata {
+    create(value?: PartialMessage<Binary>): Binary {
         const message = globalThis.Object.create((this.messagePrototype!));
-        message.mappingFiles = [];
-        message.labels = [];
+        message.buildId = "";
+        message.file = "";
+        message.services = [];
+        message.debuginfoStatus = 0;
         if (value !== undefined)
-            reflectionMergePartial<ProfileMetadata>(this, message, value);
+            reflectionMergePartial<Binary>(this, message, value);
         return message;
     }
-    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: ProfileMetadata): ProfileMetadata {
+    internalBinaryRead(reader: IBinaryReader, length: number, options: BinaryReadOptions, target?: Binary): Binary {
         let message = target ?? this.create(), end = reader.pos + length;
         while (reader.pos < end) {
             let [fieldNo, wireType] = reader.tag();
             switch (fieldNo) {
-                case /* repeated string mapping_files */ 1:
-                    message.mappingFiles.push(reader.string());
+                case /* string build_id */ 1:
+                    message.buildId = reader.string();
                     break;
-                case /* repeated string labels */ 2:
-                    message.labels.push(reader.string());
+                case /* string file */ 2:
+                    message.file = reader.string();
+                    break;
+                case /* google.protobuf.Timestamp first_seen */ 3:
+                    message.firstSeen = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.firstSeen);
+                    break;
+                case /* google.protobuf.Timestamp last_seen */ 4:
+                    message.lastSeen = Timestamp.internalBinaryRead(reader, reader.uint32(), options, message.lastSeen);
+                    break;
+                case /* repeated string services */ 5:
+                    message.services.push(reader.string());
+                    break;
+                case /* parca.query.v1alpha1.Binary.DebuginfoStatus debuginfo_status */ 6:
+                    message.debuginfoStatus = reader.int32();
                     break;
                 default:
                     let u = options.readUnknownField;
@@ -4339,13 +7005,25 @@ class ProfileMetadata$Type extends MessageType<ProfileMetadata> {
         }
         return message;
     }
-    internalBinaryWrite(message: ProfileMetadata, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
-        /* repeated string mapping_files = 1; */
-        for (let i = 0; i < message.mappingFiles.length; i++)
-            writer.tag(1, WireType.LengthDelimited).string(message.mappingFiles[i]);
-        /* repeated string labels = 2; */
-        for (let i = 0; i < message.labels.length; i++)
-            writer.tag(2, WireType.LengthDelimited).string(message.labels[i]);
+    internalBinaryWrite(message: Binary, writer: IBinaryWriter, options: BinaryWriteOptions): IBinaryWriter {
+        /* string build_id = 1; */
+        if (message.buildId !== "")
+            writer.tag(1, WireType.LengthDelimited).string(message.buildId);
+        /* string file = 2; */
+        if (message.file !== "")
+            writer.tag(2, WireType.LengthDelimited).string(message.file);
+        /* google.protobuf.Timestamp first_seen = 3; */
+        if (message.firstSeen)
+            Timestamp.internalBinaryWrite(message.firstSeen, writer.tag(3, WireType.LengthDelimited).fork(), options).join();
+        /* google.protobuf.Timestamp last_seen = 4; */
+        if (message.lastSeen)
+            Timestamp.internalBinaryWrite(message.lastSeen, writer.tag(4, WireType.LengthDelimited).fork(), options).join();
+        /* repeated string services = 5; */
+        for (let i = 0; i < message.services.length; i++)
+            writer.tag(5, WireType.LengthDelimited).string(message.services[i]);
+        /* parca.query.v1alpha1.Binary.DebuginfoStatus debuginfo_status = 6; */
+        if (message.debuginfoStatus !== 0)
+            writer.tag(6, WireType.Varint).int32(message.debuginfoStatus);
         let u = options.writeUnknownFields;
         if (u !== false)
             (u == true ? UnknownFieldHandler.onWrite : u)(this.typeName, message, writer);
@@ -4353,9 +7031,9 @@ class ProfileMetadata$Type extends MessageType<ProfileMetadata> {
     }
 }
 /**
- * @generated MessageType for protobuf message parca.query.v1alpha1.ProfileMetadata
+ * @generated MessageType for protobuf message parca.query.v1alpha1.Binary
  */
-export const ProfileMetadata = new ProfileMetadata$Type();
+export const Binary = new Binary$Type();
 /**
  * @generated ServiceType for protobuf service parca.query.v1alpha1.QueryService
  */
@@ -4366,5 +7044,19 @@ export const QueryService = new ServiceType("parca.query.v1alpha1.QueryService",
     { name: "ProfileTypes", options: { "google.api.http": { get: "/profiles/types" } }, I: ProfileTypesRequest, O: ProfileTypesResponse },
     { name: "Labels", options: { "google.api.http": { get: "/profiles/labels" } }, I: LabelsRequest, O: LabelsResponse },
     { name: "Values", options: { "google.api.http": { get: "/profiles/labels/{label_name}/values" } }, I: ValuesRequest, O: ValuesResponse },
-    { name: "ShareProfile", options: { "google.api.http": { post: "/profiles/share", body: "*" } }, I: ShareProfileRequest, O: ShareProfileResponse }
+    { name: "ShareProfile", options: { "google.api.http": { post: "/profiles/share", body: "*" } }, I: ShareProfileRequest, O: ShareProfileResponse },
+    { name: "QueryRangeWindows", options: { "google.api.http": { post: "/profiles/query_range_windows", body: "*" } }, I: QueryRangeWindowsRequest, O: QueryRangeWindowsResponse },
+    { name: "CompareToBaseline", options: { "google.api.http": { post: "/profiles/compare", body: "*" } }, I: CompareToBaselineRequest, O: CompareToBaselineResponse },
+    { name: "QueryUnits", options: { "google.api.http": { post: "/profiles/query_units", body: "*" } }, I: QueryUnitsRequest, O: QueryUnitsResponse },
+    { name: "QueryRangeUnits", options: { "google.api.http": { post: "/profiles/query_range_units", body: "*" } }, I: QueryRangeUnitsRequest, O: QueryRangeUnitsResponse },
+    { name: "ProfileCaptureMetadata", options: { "google.api.http": { get: "/profiles/metadata" } }, I: ProfileCaptureMetadataRequest, O: ProfileCaptureMetadataResponse },
+    { name: "DiffSignificance", options: { "google.api.http": { post: "/profiles/diff/significance", body: "*" } }, I: DiffSignificanceRequest, O: DiffSignificanceResponse },
+    { name: "TraceExemplars", options: { "google.api.http": { get: "/profiles/traces" } }, I: TraceExemplarsRequest, O: TraceExemplarsResponse },
+    { name: "ProfileTypeCounts", options: { "google.api.http": { get: "/profiles/types/counts" } }, I: ProfileTypeCountsRequest, O: ProfileTypeCountsResponse }
+]);
+/**
+ * @generated ServiceType for protobuf service parca.query.v1alpha1.BinaryService
+ */
+export const BinaryService = new ServiceType("parca.query.v1alpha1.BinaryService", [
+    { name: "Binaries", options: { "google.api.http": { get: "/binaries" } }, I: BinariesRequest, O: BinariesResponse }
 ]);